@@ -0,0 +1,85 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"flag"
+	"fmt"
+	"log/slog"
+	"os"
+
+	"github.com/zizouhuweidi/maktaba/internal/auth"
+	"github.com/zizouhuweidi/maktaba/internal/config"
+	"github.com/zizouhuweidi/maktaba/internal/db"
+	"github.com/zizouhuweidi/maktaba/internal/importer"
+	"github.com/zizouhuweidi/maktaba/internal/library"
+	"github.com/zizouhuweidi/maktaba/internal/reviews"
+	"github.com/zizouhuweidi/maktaba/internal/sources"
+)
+
+func main() {
+	user := flag.String("user", "", "email or username of the account to import into")
+	path := flag.String("file", "", "path to a Goodreads or StoryGraph CSV export")
+	format := flag.String("format", "", "export format: goodreads or storygraph (detected when empty)")
+	visibility := flag.String("visibility", string(library.VisibilityPrivate), "visibility for imported library items")
+	publicReviews := flag.Bool("public-reviews", false, "publish imported ratings and reviews")
+	flag.Parse()
+
+	if *user == "" || *path == "" {
+		fatal("usage: go run ./cmd/import -user <email|username> -file <export.csv> [-format goodreads|storygraph]")
+	}
+
+	cfg, err := config.Load()
+	if err != nil {
+		fatal("load config: %v", err)
+	}
+	database, err := db.NewDB(cfg.Database.URL, cfg.Database.MaxOpenConns, cfg.Database.MaxIdleConns, cfg.Database.ConnMaxLifetime)
+	if err != nil {
+		fatal("connect database: %v", err)
+	}
+	defer database.Close()
+
+	ctx := context.Background()
+	account, err := auth.NewPostgresRepository(database).GetUserByEmailOrUsername(ctx, *user)
+	if err != nil {
+		fatal("load user: %v", err)
+	}
+	if account == nil {
+		fatal("user %q not found", *user)
+	}
+
+	file, err := os.Open(*path)
+	if err != nil {
+		fatal("open file: %v", err)
+	}
+	defer file.Close()
+
+	logger := slog.New(slog.NewTextHandler(os.Stderr, &slog.HandlerOptions{Level: slog.LevelWarn}))
+	service := importer.NewService(
+		sources.NewService(sources.NewPostgresRepository(database), nil, logger),
+		library.NewService(library.NewPostgresRepository(database), logger),
+		reviews.NewService(reviews.NewPostgresRepository(database), logger),
+		logger,
+	)
+	report, err := service.Import(ctx, file, importer.Options{
+		UserID:        account.ID,
+		Format:        importer.Format(*format),
+		Visibility:    library.Visibility(*visibility),
+		PublicReviews: *publicReviews,
+	})
+	if err != nil {
+		fatal("import: %v", err)
+	}
+
+	encoder := json.NewEncoder(os.Stdout)
+	encoder.SetIndent("", "  ")
+	if err := encoder.Encode(report); err != nil {
+		fatal("write report: %v", err)
+	}
+	fmt.Fprintf(os.Stderr, "imported %s export: %d created, %d matched, %d skipped, %d failed\n", report.Format, report.Created, report.Matched, report.Skipped, report.Failed)
+}
+
+func fatal(format string, args ...any) {
+	fmt.Fprintf(os.Stderr, format+"\n", args...)
+	os.Exit(1)
+}
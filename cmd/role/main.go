@@ -0,0 +1,55 @@
+package main
+
+import (
+	"context"
+	"flag"
+	"fmt"
+	"log/slog"
+	"os"
+
+	"github.com/zizouhuweidi/maktaba/internal/auth"
+	"github.com/zizouhuweidi/maktaba/internal/config"
+	"github.com/zizouhuweidi/maktaba/internal/db"
+)
+
+func main() {
+	user := flag.String("user", "", "email or username of the account")
+	role := flag.String("role", "", "role to grant: user, editor or admin")
+	flag.Parse()
+
+	if *user == "" || *role == "" {
+		fatal("usage: go run ./cmd/role -user <email|username> -role user|editor|admin")
+	}
+
+	cfg, err := config.Load()
+	if err != nil {
+		fatal("load config: %v", err)
+	}
+	database, err := db.NewDB(cfg.Database.URL, cfg.Database.MaxOpenConns, cfg.Database.MaxIdleConns, cfg.Database.ConnMaxLifetime)
+	if err != nil {
+		fatal("connect database: %v", err)
+	}
+	defer database.Close()
+
+	ctx := context.Background()
+	repo := auth.NewPostgresRepository(database)
+	account, err := repo.GetUserByEmailOrUsername(ctx, *user)
+	if err != nil {
+		fatal("load user: %v", err)
+	}
+	if account == nil {
+		fatal("user %q not found", *user)
+	}
+
+	logger := slog.New(slog.NewTextHandler(os.Stderr, &slog.HandlerOptions{Level: slog.LevelWarn}))
+	service := auth.NewService(repo, nil, nil, nil, nil, cfg.Auth.RefreshTokenLifetime, auth.EmailSettings{}, logger)
+	if err := service.SetRole(ctx, account.ID, auth.Role(*role)); err != nil {
+		fatal("set role: %v", err)
+	}
+	fmt.Printf("%s is now %s; the change applies from their next token refresh\n", account.Username, *role)
+}
+
+func fatal(format string, args ...any) {
+	fmt.Fprintf(os.Stderr, format+"\n", args...)
+	os.Exit(1)
+}
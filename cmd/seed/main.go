@@ -2,7 +2,6 @@ package main
 
 import (
 	"context"
-	"encoding/json"
 	"errors"
 	"fmt"
 	"os"
@@ -11,6 +10,7 @@ import (
 	"github.com/jackc/pgx/v5"
 	"github.com/jackc/pgx/v5/pgxpool"
 	"github.com/zizouhuweidi/maktaba/internal/auth"
+	"github.com/zizouhuweidi/maktaba/internal/tags"
 )
 
 type bookSeed struct {
@@ -42,9 +42,10 @@ func main() {
 		fatal("hash password: %v", err)
 	}
 	if _, err := db.Exec(ctx, `
-		INSERT INTO users (id, email, username, password_hash)
-		VALUES ($1, 'demo@example.com', 'demo_reader', $2)
-		ON CONFLICT (email) DO UPDATE SET username = EXCLUDED.username, password_hash = EXCLUDED.password_hash
+		INSERT INTO users (id, email, username, password_hash, email_verified_at)
+		VALUES ($1, 'demo@example.com', 'demo_reader', $2, NOW())
+		ON CONFLICT (email) DO UPDATE SET username = EXCLUDED.username, password_hash = EXCLUDED.password_hash,
+			email_verified_at = COALESCE(users.email_verified_at, EXCLUDED.email_verified_at)
 	`, userID.String(), passwordHash); err != nil {
 		fatal("seed user: %v", err)
 	}
@@ -84,7 +85,7 @@ func main() {
 
 	var sourceIDs []uuid.UUID
 	for _, book := range books {
-		sourceID := seedBook(ctx, db, book)
+		sourceID := seedBook(ctx, db, userID, book)
 		sourceIDs = append(sourceIDs, sourceID)
 		seedLibraryAndActivity(ctx, db, userID, sourceID, book.Title)
 	}
@@ -94,7 +95,7 @@ func main() {
 	fmt.Println("login: demo@example.com / password12345")
 }
 
-func seedBook(ctx context.Context, db *pgxpool.Pool, book bookSeed) uuid.UUID {
+func seedBook(ctx context.Context, db *pgxpool.Pool, userID uuid.UUID, book bookSeed) uuid.UUID {
 	var existingID uuid.UUID
 	err := db.QueryRow(ctx, `SELECT id FROM sources WHERE isbn = $1 AND type = 'book' ORDER BY created_at LIMIT 1`, book.ISBN13).Scan(&existingID)
 	if err == nil {
@@ -105,14 +106,31 @@ func seedBook(ctx context.Context, db *pgxpool.Pool, book bookSeed) uuid.UUID {
 	}
 
 	sourceID := mustUUID()
-	tags, _ := json.Marshal(book.Tags)
 	if _, err := db.Exec(ctx, `
-		INSERT INTO sources (id, title, subtitle, type, description, publisher, isbn, tags)
+		INSERT INTO sources (id, title, subtitle, type, description, publisher, isbn, created_by)
 		VALUES ($1, $2, $3, 'book', $4, $5, $6, $7)
 		ON CONFLICT (id) DO NOTHING
-	`, sourceID.String(), book.Title, book.Subtitle, book.Description, book.Publisher, book.ISBN13, tags); err != nil {
+	`, sourceID.String(), book.Title, book.Subtitle, book.Description, book.Publisher, book.ISBN13, userID.String()); err != nil {
 		fatal("seed source: %v", err)
 	}
+	for position, tag := range book.Tags {
+		tagID := mustUUID()
+		if err := db.QueryRow(ctx, `
+			INSERT INTO tags (id, name, slug)
+			VALUES ($1, $2, $3)
+			ON CONFLICT (slug) DO UPDATE SET slug = EXCLUDED.slug
+			RETURNING id
+		`, tagID.String(), tag, tags.Slug(tag)).Scan(&tagID); err != nil {
+			fatal("seed tag: %v", err)
+		}
+		if _, err := db.Exec(ctx, `
+			INSERT INTO source_tags (source_id, tag_id, position)
+			VALUES ($1, $2, $3)
+			ON CONFLICT DO NOTHING
+		`, sourceID.String(), tagID.String(), position); err != nil {
+			fatal("seed source tag: %v", err)
+		}
+	}
 	if _, err := db.Exec(ctx, `
 		INSERT INTO book_metadata (source_id, isbn_13, publisher, language)
 		VALUES ($1, $2, $3, 'en')
@@ -170,16 +188,30 @@ func seedLibraryAndActivity(ctx context.Context, db *pgxpool.Pool, userID, sourc
 }
 
 func seedCollection(ctx context.Context, db *pgxpool.Pool, userID uuid.UUID, sourceIDs []uuid.UUID) {
-	encoded, _ := json.Marshal(sourceIDs)
 	if _, err := db.Exec(ctx, `DELETE FROM collections WHERE user_id = $1 AND name = 'Demo Reading List'`, userID.String()); err != nil {
 		fatal("reset collection: %v", err)
 	}
+	collectionID := mustUUID()
 	if _, err := db.Exec(ctx, `
-		INSERT INTO collections (id, user_id, name, description, is_public, source_ids)
-		VALUES ($1, $2, 'Demo Reading List', 'A seeded public collection.', true, $3)
-	`, mustUUID().String(), userID.String(), encoded); err != nil {
+		INSERT INTO collections (id, user_id, name, description, is_public)
+		VALUES ($1, $2, 'Demo Reading List', 'A seeded public collection.', true)
+	`, collectionID.String(), userID.String()); err != nil {
 		fatal("seed collection: %v", err)
 	}
+	if _, err := db.Exec(ctx, `
+		INSERT INTO collection_members (collection_id, user_id, role)
+		VALUES ($1, $2, 'owner')
+	`, collectionID.String(), userID.String()); err != nil {
+		fatal("seed collection owner: %v", err)
+	}
+	for position, sourceID := range sourceIDs {
+		if _, err := db.Exec(ctx, `
+			INSERT INTO collection_items (collection_id, source_id, position, added_by)
+			VALUES ($1, $2, $3, $4)
+		`, collectionID.String(), sourceID.String(), position, userID.String()); err != nil {
+			fatal("seed collection item: %v", err)
+		}
+	}
 }
 
 func mustUUID() uuid.UUID {
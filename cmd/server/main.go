@@ -2,15 +2,21 @@ package main
 
 import (
 	"context"
+	"errors"
+	"fmt"
 	"log/slog"
 	"net/http"
 	"os"
 	"os/signal"
+	"strings"
 	"syscall"
 	"time"
 
 	"github.com/zizouhuweidi/maktaba/internal/config"
 	"github.com/zizouhuweidi/maktaba/internal/db"
+	"github.com/zizouhuweidi/maktaba/internal/embeddings"
+	"github.com/zizouhuweidi/maktaba/internal/goals"
+	"github.com/zizouhuweidi/maktaba/internal/outbox"
 	"github.com/zizouhuweidi/maktaba/internal/server"
 )
 
@@ -30,6 +36,19 @@ func main() {
 		}
 		logger.Warn("AUTH_ED25519_PRIVATE_KEY is not set; generated JWT keys will be ephemeral")
 	}
+	if cfg.Auth.TOTPEncryptionKey == "" {
+		if cfg.Environment == "production" {
+			logger.Error("AUTH_TOTP_ENCRYPTION_KEY is required in production")
+			os.Exit(1)
+		}
+		logger.Warn("AUTH_TOTP_ENCRYPTION_KEY is not set; two-factor secrets will be unreadable after a restart")
+	}
+	if cfg.Environment == "production" && cfg.Mail.Provider != "smtp" {
+		logger.Warn("MAIL_PROVIDER is not smtp; verification and password reset emails will not be delivered", "provider", cfg.Mail.Provider)
+	}
+	if cfg.Environment == "production" && len(cfg.Auth.OIDCProviders) > 0 && !strings.HasPrefix(cfg.Auth.APIURL, "https://") {
+		logger.Warn("API_URL is not https; identity providers will refuse to redirect back to it", "api_url", cfg.Auth.APIURL)
+	}
 
 	database, err := db.NewDB(cfg.Database.URL, cfg.Database.MaxOpenConns, cfg.Database.MaxIdleConns, cfg.Database.ConnMaxLifetime)
 	if err != nil {
@@ -39,15 +58,31 @@ func main() {
 	defer database.Close()
 	logger.Info("connected to database")
 
-	httpServer, err := server.New(cfg, database, logger)
+	srv, err := server.New(cfg, database, logger)
 	if err != nil {
 		logger.Error("failed to build server", "error", err)
 		os.Exit(1)
 	}
 
+	workerCtx, stopWorkers := context.WithCancel(context.Background())
+	defer stopWorkers()
+	if cfg.Outbox.Enabled {
+		relay := outbox.NewRelay(outbox.NewPostgresStore(database), buildOutboxSink(cfg.Outbox, database, logger), cfg.Outbox.BatchSize, cfg.Outbox.PollInterval, cfg.Outbox.MaxAttempts, logger)
+		go relay.Run(workerCtx)
+	}
+	embedder, err := buildEmbedder(cfg.Embeddings)
+	if err != nil {
+		logger.Error("failed to configure embeddings", "error", err)
+		os.Exit(1)
+	}
+	if embedder != nil {
+		indexer := embeddings.NewIndexer(embeddings.NewPostgresStore(database), embedder, cfg.Embeddings.BatchSize, cfg.Embeddings.BackfillInterval, logger)
+		go indexer.Run(workerCtx)
+	}
+
 	go func() {
 		logger.Info("server starting", "port", cfg.Server.Port)
-		if err := httpServer.ListenAndServe(); err != nil && err != http.ErrServerClosed {
+		if err := srv.HTTP.ListenAndServe(); err != nil && err != http.ErrServerClosed {
 			logger.Error("server failed", "error", err)
 			os.Exit(1)
 		}
@@ -58,11 +93,46 @@ func main() {
 	<-quit
 
 	logger.Info("server shutting down")
+	stopWorkers()
 	ctx, cancel := context.WithTimeout(context.Background(), 30*time.Second)
 	defer cancel()
-	if err := httpServer.Shutdown(ctx); err != nil {
+	if err := srv.HTTP.Shutdown(ctx); err != nil {
 		logger.Error("server forced to shutdown", "error", err)
 		os.Exit(1)
 	}
+	if err := srv.Wait(ctx); err != nil {
+		logger.Error("background jobs did not finish", "error", err)
+		os.Exit(1)
+	}
 	logger.Info("server exited")
 }
+
+func buildOutboxSink(cfg config.OutboxConfig, database *db.DB, logger *slog.Logger) outbox.Sink {
+	subscribers := outbox.NewSubscribers()
+	goals.NewService(goals.NewPostgresRepository(database), logger).Subscribe(subscribers)
+
+	sinks := outbox.MultiSink{subscribers}
+	if cfg.LogEvents {
+		sinks = append(sinks, outbox.NewLogSink(logger))
+	}
+	if cfg.WebhookURL != "" {
+		sinks = append(sinks, outbox.NewWebhookSink(cfg.WebhookURL, cfg.WebhookSecret, cfg.WebhookTimeout))
+	}
+	return sinks
+}
+
+func buildEmbedder(cfg config.EmbeddingsConfig) (embeddings.Embedder, error) {
+	switch cfg.Provider {
+	case "", "none":
+		return nil, nil
+	case "hash":
+		return embeddings.NewHashEmbedder(), nil
+	case "http":
+		if cfg.URL == "" || cfg.Model == "" {
+			return nil, errors.New("EMBEDDINGS_URL and EMBEDDINGS_MODEL are required for the http provider")
+		}
+		return embeddings.NewHTTPEmbedder(cfg.URL, cfg.Model, cfg.APIKey, cfg.Timeout), nil
+	default:
+		return nil, fmt.Errorf("unknown embeddings provider %q", cfg.Provider)
+	}
+}
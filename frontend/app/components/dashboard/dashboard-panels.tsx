@@ -14,7 +14,14 @@ import { Link } from "react-router";
 import { Button } from "~/components/ui/button";
 import { Card, CardContent, CardDescription, CardHeader, CardTitle } from "~/components/ui/card";
 import { Input } from "~/components/ui/input";
-import type { Collection, LibraryItemWithSource, Note, Review, Source } from "~/lib/api";
+import {
+  libraryStatusTransitions,
+  type Collection,
+  type LibraryItemWithSource,
+  type Note,
+  type Review,
+  type Source,
+} from "~/lib/api";
 
 export type DashboardData = {
   sources: Source[];
@@ -24,6 +31,14 @@ export type DashboardData = {
   collections: Collection[];
 };
 
+const libraryStatusOptions = [
+  ["to_consume", "To consume"],
+  ["in_progress", "In progress"],
+  ["completed", "Completed"],
+  ["paused", "Paused"],
+  ["abandoned", "Abandoned"],
+] as const;
+
 export type RunAction = (action: () => Promise<unknown>, fallback: string) => Promise<void>;
 
 export function DashboardHeader({
@@ -538,11 +553,17 @@ function LibraryItemRow({
             value={item.status}
             onChange={(event) => accessToken && onUpdate(item.id, { status: event.target.value })}
           >
-            <option value="to_consume">To consume</option>
-            <option value="in_progress">In progress</option>
-            <option value="completed">Completed</option>
-            <option value="paused">Paused</option>
-            <option value="abandoned">Abandoned</option>
+            {libraryStatusOptions.map(([value, label]) => (
+              <option
+                key={value}
+                value={value}
+                disabled={
+                  value !== item.status && !libraryStatusTransitions[item.status]?.includes(value)
+                }
+              >
+                {value === "in_progress" && item.status === "completed" ? "Read again" : label}
+              </option>
+            ))}
           </select>
           <div className="flex gap-2">
             <Button
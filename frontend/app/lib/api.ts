@@ -6,6 +6,52 @@ export type User = {
   username?: string;
   firstName?: string;
   lastName?: string;
+  role?: "user" | "editor" | "admin";
+  email_verified_at?: string | null;
+};
+
+export type Session = {
+  id: string;
+  user_agent?: string;
+  ip_address?: string;
+  created_at: string;
+  last_used_at: string;
+  expires_at: string;
+  current: boolean;
+};
+
+export type MFAStatus = {
+  enabled: boolean;
+  enabled_at?: string;
+  recovery_codes_remaining: number;
+};
+
+export type TOTPEnrollment = {
+  secret: string;
+  provisioning_uri: string;
+};
+
+export type LinkedIdentity = {
+  provider: string;
+  email?: string;
+  created_at: string;
+  last_used_at: string;
+};
+
+export type PersonalAccessToken = {
+  id: string;
+  name: string;
+  scopes: string[];
+  expires_at?: string;
+  last_used_at?: string;
+  last_used_ip?: string;
+  created_at: string;
+};
+
+export type Page<T> = {
+  items: T[];
+  next_cursor: string | null;
+  total?: number;
 };
 
 export type Source = {
@@ -18,6 +64,7 @@ export type Source = {
   isbn?: string;
   tags?: string[];
   created_at: string;
+  created_by?: string;
 };
 
 export type Book = {
@@ -46,10 +93,22 @@ export type LibraryItem = {
   progress_value?: number;
   progress_unit?: string;
   visibility: string;
+  started_at?: string;
+  completed_at?: string;
+  reread_count: number;
   created_at: string;
   updated_at: string;
 };
 
+// Statuses a library item may move to from each status; mirrors the server.
+export const libraryStatusTransitions: Record<string, string[]> = {
+  to_consume: ["in_progress", "completed", "abandoned"],
+  in_progress: ["to_consume", "completed", "paused", "abandoned"],
+  paused: ["in_progress", "completed", "abandoned"],
+  abandoned: ["to_consume", "in_progress"],
+  completed: ["in_progress"],
+};
+
 export type LibraryItemWithSource = LibraryItem & {
   source: Source;
 };
@@ -92,6 +151,7 @@ export type Profile = {
   display_name?: string;
   bio?: string;
   public_profile: boolean;
+  public_year_in_review: boolean;
   created_at: string;
   updated_at: string;
 };
@@ -100,6 +160,10 @@ type RequestOptions = RequestInit & {
   accessToken?: string | null;
 };
 
+function withCursor(path: string, cursor?: string) {
+  return cursor ? `${path}&cursor=${encodeURIComponent(cursor)}` : path;
+}
+
 export async function apiRequest<T>(path: string, options: RequestOptions = {}) {
   const headers = new Headers(options.headers);
   if (options.body && !headers.has("Content-Type")) {
@@ -137,24 +201,154 @@ export function getMe(accessToken: string) {
   return apiRequest<User>("/api/me", { accessToken });
 }
 
-export function listSources() {
-  return apiRequest<Source[]>("/sources?type=book&limit=100");
+export function verifyEmail(token: string) {
+  return apiRequest<void>("/auth/verify-email", {
+    method: "POST",
+    body: JSON.stringify({ token }),
+  });
 }
 
-export function createBook(accessToken: string, payload: unknown) {
-  return apiRequest<Book>("/api/sources/books", {
+export function resendVerificationEmail(accessToken: string) {
+  return apiRequest<void>("/api/me/verification-email", { method: "POST", accessToken });
+}
+
+export function forgotPassword(email: string) {
+  return apiRequest<void>("/auth/forgot-password", {
+    method: "POST",
+    body: JSON.stringify({ email }),
+  });
+}
+
+export function resetPassword(token: string, password: string) {
+  return apiRequest<void>("/auth/reset-password", {
+    method: "POST",
+    body: JSON.stringify({ token, password }),
+  });
+}
+
+export function listSessions(accessToken: string) {
+  return apiRequest<Session[]>("/api/me/sessions", { accessToken });
+}
+
+export function revokeSession(accessToken: string, sessionID: string) {
+  return apiRequest<void>(`/api/me/sessions/${sessionID}`, { method: "DELETE", accessToken });
+}
+
+export function revokeOtherSessions(accessToken: string) {
+  return apiRequest<{ revoked: number }>("/api/me/sessions/revoke-others", {
+    method: "POST",
+    accessToken,
+  });
+}
+
+export function completeMFALogin(challengeToken: string, code: string) {
+  return apiRequest<unknown>("/auth/login/mfa", {
+    method: "POST",
+    body: JSON.stringify({ challenge_token: challengeToken, code }),
+  });
+}
+
+export function getMFAStatus(accessToken: string) {
+  return apiRequest<MFAStatus>("/api/me/mfa", { accessToken });
+}
+
+export function startTOTPEnrollment(accessToken: string) {
+  return apiRequest<TOTPEnrollment>("/api/me/mfa/totp", { method: "POST", accessToken });
+}
+
+export function confirmTOTPEnrollment(accessToken: string, code: string) {
+  return apiRequest<{ recovery_codes: string[] }>("/api/me/mfa/totp/confirm", {
+    method: "POST",
+    accessToken,
+    body: JSON.stringify({ code }),
+  });
+}
+
+export function disableTOTP(accessToken: string, password: string) {
+  return apiRequest<void>("/api/me/mfa/totp", {
+    method: "DELETE",
+    accessToken,
+    body: JSON.stringify({ password }),
+  });
+}
+
+export function regenerateRecoveryCodes(accessToken: string, password: string) {
+  return apiRequest<{ recovery_codes: string[] }>("/api/me/mfa/recovery-codes", {
+    method: "POST",
+    accessToken,
+    body: JSON.stringify({ password }),
+  });
+}
+
+export function listIdentityProviders() {
+  return apiRequest<{ providers: string[] }>("/auth/oidc/providers");
+}
+
+// externalLoginURL is where the browser goes to sign in with a provider; the
+// API sends it back to the /callback page.
+export function externalLoginURL(provider: string) {
+  return `${API_URL}/auth/oidc/${encodeURIComponent(provider)}/start`;
+}
+
+export function listIdentities(accessToken: string) {
+  return apiRequest<LinkedIdentity[]>("/api/me/identities", { accessToken });
+}
+
+export function linkIdentity(accessToken: string, provider: string) {
+  return apiRequest<{ authorization_url: string }>(`/api/me/identities/${encodeURIComponent(provider)}`, {
+    method: "POST",
+    accessToken,
+  });
+}
+
+export function unlinkIdentity(accessToken: string, provider: string) {
+  return apiRequest<void>(`/api/me/identities/${encodeURIComponent(provider)}`, {
+    method: "DELETE",
+    accessToken,
+  });
+}
+
+export function listTokens(accessToken: string) {
+  return apiRequest<PersonalAccessToken[]>("/api/me/tokens", { accessToken });
+}
+
+export function createToken(
+  accessToken: string,
+  payload: { name: string; scopes: string[]; expires_in_days?: number }
+) {
+  return apiRequest<PersonalAccessToken & { token: string }>("/api/me/tokens", {
     method: "POST",
     accessToken,
     body: JSON.stringify(payload),
   });
 }
 
-export function listLibrary(accessToken: string) {
-  return apiRequest<LibraryItemWithSource[]>("/api/library/items/with-sources?limit=50", {
+export function revokeToken(accessToken: string, tokenID: string) {
+  return apiRequest<void>(`/api/me/tokens/${tokenID}`, {
+    method: "DELETE",
+    accessToken,
+  });
+}
+
+export function listSources(cursor?: string) {
+  return apiRequest<Page<Source>>(withCursor("/sources?type=book&limit=100", cursor));
+}
+
+export function createBook(accessToken: string, payload: unknown) {
+  return apiRequest<Book>("/api/sources/books", {
+    method: "POST",
     accessToken,
+    body: JSON.stringify(payload),
   });
 }
 
+export function listLibrary(accessToken: string, cursor?: string) {
+  return apiRequest<Page<LibraryItemWithSource>>(
+    withCursor("/api/library/items/with-sources?limit=50", cursor),
+    { accessToken }
+  );
+}
+
 export function addLibraryItem(accessToken: string, sourceID: string) {
   return apiRequest<LibraryItem>("/api/library/items", {
     method: "POST",
@@ -182,16 +376,18 @@ export function deleteLibraryItem(accessToken: string, itemID: string) {
   });
 }
 
-export function listNotes(accessToken: string) {
-  return apiRequest<Note[]>("/api/notes?limit=50", { accessToken });
+export function listNotes(accessToken: string, cursor?: string) {
+  return apiRequest<Page<Note>>(withCursor("/api/notes?limit=50", cursor), { accessToken });
 }
 
-export function listReviews(accessToken: string) {
-  return apiRequest<Review[]>("/api/reviews?limit=50", { accessToken });
+export function listReviews(accessToken: string, cursor?: string) {
+  return apiRequest<Page<Review>>(withCursor("/api/reviews?limit=50", cursor), { accessToken });
 }
 
-export function listCollections(accessToken: string) {
-  return apiRequest<Collection[]>("/api/collections?limit=50", { accessToken });
+export function listCollections(accessToken: string, cursor?: string) {
+  return apiRequest<Page<Collection>>(withCursor("/api/collections?limit=50", cursor), {
+    accessToken,
+  });
 }
 
 export function createNote(accessToken: string, payload: unknown) {
@@ -255,9 +451,9 @@ export function getPublicProfile(username: string) {
   return apiRequest<Profile>(`/users/${encodeURIComponent(username)}/profile`);
 }
 
-export function listPublicLibrary(username: string) {
-  return apiRequest<LibraryItemWithSource[]>(
-    `/users/${encodeURIComponent(username)}/library/with-sources?limit=50`
+export function listPublicLibrary(username: string, cursor?: string) {
+  return apiRequest<Page<LibraryItemWithSource>>(
+    withCursor(`/users/${encodeURIComponent(username)}/library/with-sources?limit=50`, cursor)
   );
 }
 
@@ -265,14 +461,20 @@ export function getBook(sourceID: string) {
   return apiRequest<Book>(`/sources/books/${encodeURIComponent(sourceID)}`);
 }
 
-export function listPublicNotesByUser(userID: string) {
-  return apiRequest<Note[]>(`/notes?user_id=${encodeURIComponent(userID)}&limit=50`);
+export function listPublicNotesByUser(userID: string, cursor?: string) {
+  return apiRequest<Page<Note>>(
+    withCursor(`/notes?user_id=${encodeURIComponent(userID)}&limit=50`, cursor)
+  );
 }
 
-export function listPublicReviewsByUser(userID: string) {
-  return apiRequest<Review[]>(`/reviews?user_id=${encodeURIComponent(userID)}&limit=50`);
+export function listPublicReviewsByUser(userID: string, cursor?: string) {
+  return apiRequest<Page<Review>>(
+    withCursor(`/reviews?user_id=${encodeURIComponent(userID)}&limit=50`, cursor)
+  );
 }
 
-export function listPublicCollectionsByUser(userID: string) {
-  return apiRequest<Collection[]>(`/collections?user_id=${encodeURIComponent(userID)}&limit=50`);
+export function listPublicCollectionsByUser(userID: string, cursor?: string) {
+  return apiRequest<Page<Collection>>(
+    withCursor(`/collections?user_id=${encodeURIComponent(userID)}&limit=50`, cursor)
+  );
 }
@@ -1,6 +1,6 @@
 import { create } from "zustand";
 import { persist } from "zustand/middleware";
-import { API_URL, getMe, type User } from "~/lib/api";
+import { API_URL, completeMFALogin, getMe, type User } from "~/lib/api";
 
 type AuthState = {
   accessToken: string | null;
@@ -9,7 +9,10 @@ type AuthState = {
   isLoading: boolean;
   setAccessToken: (token: string | null) => void;
   refreshSession: () => Promise<void>;
-  login: (login: string, password: string) => Promise<void>;
+  // login resolves to a challenge token when the account needs a second
+  // factor, which completeLogin then takes with the code.
+  login: (login: string, password: string) => Promise<string | null>;
+  completeLogin: (challengeToken: string, code: string) => Promise<void>;
   register: (email: string, username: string, password: string) => Promise<void>;
   logout: () => void;
 };
@@ -102,6 +105,23 @@ export const useAuthStore = create<AuthState>()(
           throw new Error("invalid credentials");
         }
         const data = await response.json();
+        if (data?.mfa_required && typeof data.challenge_token === "string") {
+          return data.challenge_token;
+        }
+        const accessToken = accessTokenFromAuthResponse(data);
+        if (!accessToken) {
+          throw new Error("login response missing access token");
+        }
+        set({
+          accessToken,
+          user: userFromResponse(data),
+          isAuthenticated: true,
+          isLoading: false,
+        });
+        return null;
+      },
+      completeLogin: async (challengeToken, code) => {
+        const data = await completeMFALogin(challengeToken, code);
         const accessToken = accessTokenFromAuthResponse(data);
         if (!accessToken) {
           throw new Error("login response missing access token");
@@ -6,6 +6,7 @@ export default [
   route("registration", "routes/registration.tsx"),
   route("recovery", "routes/recovery.tsx"),
   route("verification", "routes/verification.tsx"),
+  route("callback", "routes/callback.tsx"),
   route("dashboard", "routes/dashboard.tsx"),
   route("settings", "routes/settings.tsx"),
   route("users/:username/profile", "routes/users.$username.profile.tsx"),
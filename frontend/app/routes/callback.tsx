@@ -0,0 +1,79 @@
+import { Library, XCircle } from "lucide-react";
+import { useEffect, useRef } from "react";
+import { Link, useNavigate, useSearchParams } from "react-router";
+import { Button } from "~/components/ui/button";
+import { Card, CardContent } from "~/components/ui/card";
+import { useAuthStore } from "~/lib/auth";
+
+const errorMessages: Record<string, string> = {
+  cancelled: "Sign-in was cancelled at the provider.",
+  expired: "This sign-in has expired. Please try again.",
+  account_exists:
+    "An account already uses this email address. Sign in with your password, then link the provider from your settings.",
+  identity_in_use: "That account is already linked to another user.",
+  already_linked: "You already have an account at this provider linked.",
+};
+
+// The API ends every provider sign-in here: with an error, with a linked
+// provider, with a challenge token in the fragment when a second factor is
+// needed, or with a refresh cookie.
+export default function CallbackPage() {
+  const navigate = useNavigate();
+  const [searchParams] = useSearchParams();
+  const error = searchParams.get("error");
+  const linked = searchParams.get("linked");
+  const isAuthenticated = useAuthStore((state) => state.isAuthenticated);
+  const setAccessToken = useAuthStore((state) => state.setAccessToken);
+  const refreshSession = useAuthStore((state) => state.refreshSession);
+  const handled = useRef(false);
+
+  useEffect(() => {
+    if (error || handled.current) {
+      return;
+    }
+    handled.current = true;
+    if (linked) {
+      navigate("/settings", { replace: true });
+      return;
+    }
+    const challengeToken = new URLSearchParams(window.location.hash.slice(1)).get("challenge_token");
+    if (challengeToken) {
+      navigate("/login", { replace: true, state: { challengeToken } });
+      return;
+    }
+    // Drop any token from an earlier sign-in so the new refresh cookie is used.
+    setAccessToken(null);
+    refreshSession().then(() => {
+      navigate(useAuthStore.getState().isAuthenticated ? "/dashboard" : "/login", { replace: true });
+    });
+  }, [error, linked, navigate, refreshSession, setAccessToken]);
+
+  if (!error) {
+    return null;
+  }
+
+  return (
+    <div className="flex min-h-screen items-center justify-center bg-gradient-to-b from-slate-50 to-slate-100 px-4">
+      <div className="w-full max-w-md">
+        <div className="mb-8 flex flex-col items-center">
+          <div className="mb-4 rounded-xl bg-emerald-600 p-3">
+            <Library className="h-8 w-8 text-white" />
+          </div>
+          <h1 className="text-3xl font-bold text-slate-900">Bayt al Hikmah</h1>
+        </div>
+        <Card>
+          <CardContent className="pt-6">
+            <div className="flex flex-col items-center text-center">
+              <XCircle className="mb-4 h-16 w-16 text-red-500" />
+              <h2 className="mb-2 text-xl font-semibold text-slate-900">Sign-in Failed</h2>
+              <p className="mb-6 text-slate-600">{errorMessages[error] ?? "Could not sign you in with the provider."}</p>
+              <Link to={isAuthenticated ? "/settings" : "/login"}>
+                <Button>{isAuthenticated ? "Back to Settings" : "Back to Sign In"}</Button>
+              </Link>
+            </div>
+          </CardContent>
+        </Card>
+      </div>
+    </div>
+  );
+}
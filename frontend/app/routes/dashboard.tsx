@@ -87,7 +87,13 @@ export default function DashboardPage() {
         listReviews(token),
         listCollections(token),
       ]);
-      return normalizeDashboardData({ sources, library, notes, reviews, collections });
+      return normalizeDashboardData({
+        sources: sources.items,
+        library: library.items,
+        notes: notes.items,
+        reviews: reviews.items,
+        collections: collections.items,
+      });
     },
   });
 
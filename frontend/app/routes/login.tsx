@@ -1,29 +1,57 @@
 import { ArrowRight, Library } from "lucide-react";
-import { useState } from "react";
-import { Link, useNavigate } from "react-router";
+import { useEffect, useState } from "react";
+import { Link, useLocation, useNavigate } from "react-router";
 import { Button } from "~/components/ui/button";
 import { Card, CardContent, CardDescription, CardHeader, CardTitle } from "~/components/ui/card";
 import { Input } from "~/components/ui/input";
 import { Label } from "~/components/ui/label";
+import { externalLoginURL, listIdentityProviders } from "~/lib/api";
 import { useAuthStore } from "~/lib/auth";
 
 export default function LoginPage() {
   const navigate = useNavigate();
+  const location = useLocation();
   const login = useAuthStore((state) => state.login);
+  const completeLogin = useAuthStore((state) => state.completeLogin);
   const [loginValue, setLoginValue] = useState("");
   const [password, setPassword] = useState("");
+  // A provider sign-in that needs a second factor arrives with its challenge.
+  const [challengeToken, setChallengeToken] = useState<string | null>(
+    (location.state as { challengeToken?: string } | null)?.challengeToken ?? null
+  );
+  const [code, setCode] = useState("");
   const [error, setError] = useState("");
   const [isSubmitting, setIsSubmitting] = useState(false);
+  const [providers, setProviders] = useState<string[]>([]);
+
+  useEffect(() => {
+    listIdentityProviders()
+      .then((data) => setProviders(data.providers))
+      .catch(() => setProviders([]));
+  }, []);
 
   const handleSubmit = async (event: React.FormEvent) => {
     event.preventDefault();
     setError("");
     setIsSubmitting(true);
     try {
-      await login(loginValue, password);
+      if (challengeToken) {
+        await completeLogin(challengeToken, code);
+        navigate("/dashboard");
+        return;
+      }
+      const challenge = await login(loginValue, password);
+      if (challenge) {
+        setChallengeToken(challenge);
+        return;
+      }
       navigate("/dashboard");
-    } catch {
-      setError("Invalid email/username or password.");
+    } catch (err) {
+      if (challengeToken) {
+        setError(err instanceof Error ? err.message : "Invalid authentication code.");
+      } else {
+        setError("Invalid email/username or password.");
+      }
     } finally {
       setIsSubmitting(false);
     }
@@ -42,40 +70,93 @@ export default function LoginPage() {
         <Card>
           <CardHeader className="text-center">
             <CardTitle>Sign In</CardTitle>
-            <CardDescription>Sign in with your email or username.</CardDescription>
+            <CardDescription>
+              {challengeToken
+                ? "Enter the code from your authenticator app, or one of your recovery codes."
+                : "Sign in with your email or username."}
+            </CardDescription>
           </CardHeader>
           <CardContent>
-            <form className="space-y-4" onSubmit={handleSubmit}>
-              <div className="space-y-2">
-                <Label htmlFor="login">Email or username</Label>
-                <Input
-                  id="login"
-                  value={loginValue}
-                  onChange={(event) => setLoginValue(event.target.value)}
-                  required
-                />
-              </div>
-              <div className="space-y-2">
-                <Label htmlFor="password">Password</Label>
-                <Input
-                  id="password"
-                  type="password"
-                  value={password}
-                  onChange={(event) => setPassword(event.target.value)}
-                  required
-                />
-              </div>
-              {error && <p className="text-sm text-red-600">{error}</p>}
-              <Button className="w-full" size="lg" disabled={isSubmitting}>
-                Sign In
-                <ArrowRight className="ml-2 h-4 w-4" />
-              </Button>
-              <p className="text-center text-sm text-slate-500">
-                <Link to="/recovery" className="text-emerald-700 hover:underline">
-                  Forgot password?
-                </Link>
-              </p>
-            </form>
+            {challengeToken ? (
+              <form className="space-y-4" onSubmit={handleSubmit}>
+                <div className="space-y-2">
+                  <Label htmlFor="code">Authentication code</Label>
+                  <Input
+                    id="code"
+                    autoComplete="one-time-code"
+                    autoFocus
+                    value={code}
+                    onChange={(event) => setCode(event.target.value)}
+                    required
+                  />
+                </div>
+                {error && <p className="text-sm text-red-600">{error}</p>}
+                <Button className="w-full" size="lg" disabled={isSubmitting}>
+                  Verify
+                  <ArrowRight className="ml-2 h-4 w-4" />
+                </Button>
+                <p className="text-center text-sm text-slate-500">
+                  <button
+                    type="button"
+                    className="text-emerald-700 hover:underline"
+                    onClick={() => {
+                      setChallengeToken(null);
+                      setCode("");
+                      setError("");
+                    }}
+                  >
+                    Start over
+                  </button>
+                </p>
+              </form>
+            ) : (
+              <form className="space-y-4" onSubmit={handleSubmit}>
+                <div className="space-y-2">
+                  <Label htmlFor="login">Email or username</Label>
+                  <Input
+                    id="login"
+                    value={loginValue}
+                    onChange={(event) => setLoginValue(event.target.value)}
+                    required
+                  />
+                </div>
+                <div className="space-y-2">
+                  <Label htmlFor="password">Password</Label>
+                  <Input
+                    id="password"
+                    type="password"
+                    value={password}
+                    onChange={(event) => setPassword(event.target.value)}
+                    required
+                  />
+                </div>
+                {error && <p className="text-sm text-red-600">{error}</p>}
+                <Button className="w-full" size="lg" disabled={isSubmitting}>
+                  Sign In
+                  <ArrowRight className="ml-2 h-4 w-4" />
+                </Button>
+                <p className="text-center text-sm text-slate-500">
+                  <Link to="/recovery" className="text-emerald-700 hover:underline">
+                    Forgot password?
+                  </Link>
+                </p>
+                {providers.length > 0 && (
+                  <div className="space-y-2 border-t pt-4">
+                    {providers.map((provider) => (
+                      <Button
+                        key={provider}
+                        type="button"
+                        variant="outline"
+                        className="w-full"
+                        onClick={() => window.location.assign(externalLoginURL(provider))}
+                      >
+                        Continue with {provider}
+                      </Button>
+                    ))}
+                  </div>
+                )}
+              </form>
+            )}
           </CardContent>
         </Card>
       </div>
@@ -1,9 +1,16 @@
-import { Library } from "lucide-react";
-import { Link } from "react-router";
+import { ArrowRight, Library } from "lucide-react";
+import { useState } from "react";
+import { Link, useSearchParams } from "react-router";
 import { Button } from "~/components/ui/button";
 import { Card, CardContent, CardDescription, CardHeader, CardTitle } from "~/components/ui/card";
+import { Input } from "~/components/ui/input";
+import { Label } from "~/components/ui/label";
+import { forgotPassword, resetPassword } from "~/lib/api";
 
 export default function RecoveryPage() {
+  const [searchParams] = useSearchParams();
+  const token = searchParams.get("token");
+
   return (
     <div className="flex min-h-screen items-center justify-center bg-gradient-to-b from-slate-50 to-slate-100 px-4">
       <div className="w-full max-w-md">
@@ -13,18 +20,132 @@ export default function RecoveryPage() {
           </div>
           <h1 className="text-3xl font-bold text-slate-900">Bayt al Hikmah</h1>
         </div>
-        <Card>
-          <CardHeader className="text-center">
-            <CardTitle>Account Recovery</CardTitle>
-            <CardDescription>Password recovery is not implemented yet.</CardDescription>
-          </CardHeader>
-          <CardContent>
-            <Link to="/login">
-              <Button className="w-full">Back to Sign In</Button>
-            </Link>
-          </CardContent>
-        </Card>
+        {token ? <ResetPasswordCard token={token} /> : <ForgotPasswordCard />}
       </div>
     </div>
   );
 }
+
+function ForgotPasswordCard() {
+  const [email, setEmail] = useState("");
+  const [sent, setSent] = useState(false);
+  const [error, setError] = useState("");
+  const [isSubmitting, setIsSubmitting] = useState(false);
+
+  const handleSubmit = async (event: React.FormEvent) => {
+    event.preventDefault();
+    setError("");
+    setIsSubmitting(true);
+    try {
+      await forgotPassword(email);
+      setSent(true);
+    } catch (err) {
+      setError(err instanceof Error ? err.message : "Could not send the reset link.");
+    } finally {
+      setIsSubmitting(false);
+    }
+  };
+
+  return (
+    <Card>
+      <CardHeader className="text-center">
+        <CardTitle>Account Recovery</CardTitle>
+        <CardDescription>
+          {sent
+            ? "If that email belongs to an account, a reset link is on its way."
+            : "Enter your email and we will send you a link to choose a new password."}
+        </CardDescription>
+      </CardHeader>
+      <CardContent>
+        {sent ? (
+          <Link to="/login">
+            <Button className="w-full">Back to Sign In</Button>
+          </Link>
+        ) : (
+          <form className="space-y-4" onSubmit={handleSubmit}>
+            <div className="space-y-2">
+              <Label htmlFor="email">Email</Label>
+              <Input
+                id="email"
+                type="email"
+                value={email}
+                onChange={(event) => setEmail(event.target.value)}
+                required
+              />
+            </div>
+            {error && <p className="text-sm text-red-600">{error}</p>}
+            <Button className="w-full" size="lg" disabled={isSubmitting}>
+              Send Reset Link
+              <ArrowRight className="ml-2 h-4 w-4" />
+            </Button>
+            <p className="text-center text-sm text-slate-500">
+              <Link to="/login" className="text-emerald-700 hover:underline">
+                Back to Sign In
+              </Link>
+            </p>
+          </form>
+        )}
+      </CardContent>
+    </Card>
+  );
+}
+
+function ResetPasswordCard({ token }: { token: string }) {
+  const [password, setPassword] = useState("");
+  const [done, setDone] = useState(false);
+  const [error, setError] = useState("");
+  const [isSubmitting, setIsSubmitting] = useState(false);
+
+  const handleSubmit = async (event: React.FormEvent) => {
+    event.preventDefault();
+    setError("");
+    setIsSubmitting(true);
+    try {
+      await resetPassword(token, password);
+      setDone(true);
+    } catch (err) {
+      setError(err instanceof Error ? err.message : "Could not reset your password.");
+    } finally {
+      setIsSubmitting(false);
+    }
+  };
+
+  return (
+    <Card>
+      <CardHeader className="text-center">
+        <CardTitle>Choose a New Password</CardTitle>
+        <CardDescription>
+          {done
+            ? "Your password has been changed. Sign in again on each of your devices."
+            : "Your new password must be at least 12 characters."}
+        </CardDescription>
+      </CardHeader>
+      <CardContent>
+        {done ? (
+          <Link to="/login">
+            <Button className="w-full">Sign In</Button>
+          </Link>
+        ) : (
+          <form className="space-y-4" onSubmit={handleSubmit}>
+            <div className="space-y-2">
+              <Label htmlFor="password">New password</Label>
+              <Input
+                id="password"
+                type="password"
+                minLength={12}
+                value={password}
+                onChange={(event) => setPassword(event.target.value)}
+                required
+              />
+            </div>
+            {error && <p className="text-sm text-red-600">{error}</p>}
+            <Button className="w-full" size="lg" disabled={isSubmitting}>
+              Reset Password
+              <ArrowRight className="ml-2 h-4 w-4" />
+            </Button>
+          </form>
+        )}
+      </CardContent>
+    </Card>
+  );
+}
@@ -1,12 +1,32 @@
 import { useMutation, useQuery, useQueryClient } from "@tanstack/react-query";
-import { Library, Loader2, Mail, Shield, User } from "lucide-react";
+import { Key, KeyRound, Library, Link2, Loader2, Mail, Monitor, Shield, User } from "lucide-react";
 import type { FormEvent } from "react";
 import { useEffect, useState } from "react";
 import { Link, useNavigate } from "react-router";
 import { Button } from "~/components/ui/button";
 import { Card, CardContent } from "~/components/ui/card";
 import { Input } from "~/components/ui/input";
-import { getProfile, updateProfile } from "~/lib/api";
+import {
+  confirmTOTPEnrollment,
+  createToken,
+  disableTOTP,
+  getMFAStatus,
+  getProfile,
+  linkIdentity,
+  listIdentities,
+  listIdentityProviders,
+  listSessions,
+  listTokens,
+  regenerateRecoveryCodes,
+  resendVerificationEmail,
+  revokeOtherSessions,
+  revokeSession,
+  revokeToken,
+  startTOTPEnrollment,
+  type TOTPEnrollment,
+  unlinkIdentity,
+  updateProfile,
+} from "~/lib/api";
 import { useAuthStore } from "~/lib/auth";
 
 export default function SettingsPage() {
@@ -50,6 +70,37 @@ export default function SettingsPage() {
     onError: (err) => setError(err instanceof Error ? err.message : "Failed to save profile"),
   });
 
+  const sessionsQuery = useQuery({
+    queryKey: ["sessions", accessToken],
+    enabled: Boolean(isAuthenticated && accessToken),
+    queryFn: () => listSessions(accessToken as string),
+  });
+
+  const revokeMutation = useMutation({
+    mutationFn: (sessionID: string) => revokeSession(accessToken as string, sessionID),
+    onSuccess: async () => {
+      setMessage("Session signed out");
+      await queryClient.invalidateQueries({ queryKey: ["sessions"] });
+    },
+    onError: (err) => setError(err instanceof Error ? err.message : "Failed to sign out session"),
+  });
+
+  const revokeOthersMutation = useMutation({
+    mutationFn: () => revokeOtherSessions(accessToken as string),
+    onSuccess: async ({ revoked }) => {
+      setMessage(revoked === 1 ? "Signed out 1 other session" : `Signed out ${revoked} other sessions`);
+      await queryClient.invalidateQueries({ queryKey: ["sessions"] });
+    },
+    onError: (err) => setError(err instanceof Error ? err.message : "Failed to sign out sessions"),
+  });
+
+  const resendMutation = useMutation({
+    mutationFn: () => resendVerificationEmail(accessToken as string),
+    onSuccess: () => setMessage("Verification email sent"),
+    onError: (err) =>
+      setError(err instanceof Error ? err.message : "Failed to send verification email"),
+  });
+
   const handleSave = (event: FormEvent<HTMLFormElement>) => {
     event.preventDefault();
     if (!accessToken) return;
@@ -113,18 +164,33 @@ export default function SettingsPage() {
               <div className="flex h-12 w-12 items-center justify-center rounded-full bg-emerald-100">
                 <Mail className="h-6 w-6 text-emerald-600" />
               </div>
-              <div>
+              <div className="flex-1">
                 <p className="font-medium text-slate-900">{user.email || "No email"}</p>
-                <p className="text-sm text-slate-500">Email address</p>
+                <p className="text-sm text-slate-500">
+                  {user.email_verified_at ? "Verified email address" : "Email address not verified"}
+                </p>
               </div>
+              {user.email && !user.email_verified_at && (
+                <Button
+                  variant="outline"
+                  onClick={() => {
+                    setError(null);
+                    setMessage(null);
+                    resendMutation.mutate();
+                  }}
+                  disabled={resendMutation.isPending}
+                >
+                  Resend link
+                </Button>
+              )}
             </div>
             <div className="flex items-center gap-4">
               <div className="flex h-12 w-12 items-center justify-center rounded-full bg-emerald-100">
                 <Shield className="h-6 w-6 text-emerald-600" />
               </div>
               <div>
-                <p className="font-medium text-slate-900">Password auth enabled</p>
-                <p className="text-sm text-slate-500">OAuth support can be added later.</p>
+                <p className="font-medium text-slate-900">Password and linked accounts</p>
+                <p className="text-sm text-slate-500">Sign in with your password or any account linked below.</p>
               </div>
             </div>
 
@@ -180,7 +246,525 @@ export default function SettingsPage() {
             </form>
           </CardContent>
         </Card>
+
+        <Card className="mt-6">
+          <CardContent className="space-y-4 pt-6">
+            <div className="flex items-center justify-between gap-3">
+              <h2 className="text-lg font-semibold text-slate-900">Signed-in devices</h2>
+              <Button
+                variant="outline"
+                onClick={() => {
+                  setError(null);
+                  setMessage(null);
+                  revokeOthersMutation.mutate();
+                }}
+                disabled={revokeOthersMutation.isPending || (sessionsQuery.data?.length ?? 0) < 2}
+              >
+                Sign out everywhere else
+              </Button>
+            </div>
+            {sessionsQuery.isLoading && <Loader2 className="h-5 w-5 animate-spin text-emerald-600" />}
+            {sessionsQuery.data?.map((session) => (
+              <div key={session.id} className="flex items-center gap-4 border-t border-slate-200 pt-4">
+                <div className="flex h-10 w-10 items-center justify-center rounded-full bg-slate-100">
+                  <Monitor className="h-5 w-5 text-slate-600" />
+                </div>
+                <div className="min-w-0 flex-1">
+                  <p className="truncate text-sm font-medium text-slate-900">
+                    {session.user_agent || "Unknown device"}
+                    {session.current && <span className="ml-2 text-emerald-700">This device</span>}
+                  </p>
+                  <p className="text-sm text-slate-500">
+                    {session.ip_address ? `${session.ip_address} · ` : ""}
+                    Last active {new Date(session.last_used_at).toLocaleString()}
+                  </p>
+                </div>
+                {!session.current && (
+                  <Button
+                    variant="outline"
+                    onClick={() => {
+                      setError(null);
+                      setMessage(null);
+                      revokeMutation.mutate(session.id);
+                    }}
+                    disabled={revokeMutation.isPending}
+                  >
+                    Sign out
+                  </Button>
+                )}
+              </div>
+            ))}
+          </CardContent>
+        </Card>
+
+        {accessToken && (
+          <LinkedAccountsCard
+            accessToken={accessToken}
+            onMessage={(text) => {
+              setError(null);
+              setMessage(text);
+            }}
+            onError={(text) => {
+              setMessage(null);
+              setError(text);
+            }}
+          />
+        )}
+
+        {accessToken && (
+          <AccessTokensCard
+            accessToken={accessToken}
+            onMessage={(text) => {
+              setError(null);
+              setMessage(text);
+            }}
+            onError={(text) => {
+              setMessage(null);
+              setError(text);
+            }}
+          />
+        )}
+
+        {accessToken && (
+          <TwoFactorCard
+            accessToken={accessToken}
+            onMessage={(text) => {
+              setError(null);
+              setMessage(text);
+            }}
+            onError={(text) => {
+              setMessage(null);
+              setError(text);
+            }}
+          />
+        )}
       </div>
     </div>
   );
 }
+
+function TwoFactorCard({
+  accessToken,
+  onMessage,
+  onError,
+}: {
+  accessToken: string;
+  onMessage: (message: string) => void;
+  onError: (error: string) => void;
+}) {
+  const queryClient = useQueryClient();
+  const [enrollment, setEnrollment] = useState<TOTPEnrollment | null>(null);
+  const [code, setCode] = useState("");
+  const [password, setPassword] = useState("");
+  const [recoveryCodes, setRecoveryCodes] = useState<string[] | null>(null);
+
+  const statusQuery = useQuery({
+    queryKey: ["mfa", accessToken],
+    queryFn: () => getMFAStatus(accessToken),
+  });
+  const refreshStatus = () => queryClient.invalidateQueries({ queryKey: ["mfa"] });
+  const failed = (fallback: string) => (err: unknown) =>
+    onError(err instanceof Error ? err.message : fallback);
+
+  const startMutation = useMutation({
+    mutationFn: () => startTOTPEnrollment(accessToken),
+    onSuccess: (data) => {
+      setEnrollment(data);
+      setCode("");
+    },
+    onError: failed("Failed to start two-factor setup"),
+  });
+
+  const confirmMutation = useMutation({
+    mutationFn: () => confirmTOTPEnrollment(accessToken, code.trim()),
+    onSuccess: async ({ recovery_codes }) => {
+      setEnrollment(null);
+      setRecoveryCodes(recovery_codes);
+      onMessage("Two-factor authentication is on");
+      await refreshStatus();
+    },
+    onError: failed("Failed to enable two-factor authentication"),
+  });
+
+  const regenerateMutation = useMutation({
+    mutationFn: () => regenerateRecoveryCodes(accessToken, password),
+    onSuccess: async ({ recovery_codes }) => {
+      setPassword("");
+      setRecoveryCodes(recovery_codes);
+      onMessage("New recovery codes created");
+      await refreshStatus();
+    },
+    onError: failed("Failed to create recovery codes"),
+  });
+
+  const disableMutation = useMutation({
+    mutationFn: () => disableTOTP(accessToken, password),
+    onSuccess: async () => {
+      setPassword("");
+      setRecoveryCodes(null);
+      onMessage("Two-factor authentication is off");
+      await refreshStatus();
+    },
+    onError: failed("Failed to disable two-factor authentication"),
+  });
+
+  const status = statusQuery.data;
+
+  return (
+    <Card className="mt-6">
+      <CardContent className="space-y-4 pt-6">
+        <div className="flex items-center gap-4">
+          <div className="flex h-12 w-12 items-center justify-center rounded-full bg-emerald-100">
+            <KeyRound className="h-6 w-6 text-emerald-600" />
+          </div>
+          <div className="flex-1">
+            <h2 className="text-lg font-semibold text-slate-900">Two-factor authentication</h2>
+            <p className="text-sm text-slate-500">
+              {status?.enabled
+                ? `On · ${status.recovery_codes_remaining} recovery codes left`
+                : "Ask for a code from an authenticator app when signing in."}
+            </p>
+          </div>
+          {status && !status.enabled && !enrollment && (
+            <Button variant="outline" onClick={() => startMutation.mutate()} disabled={startMutation.isPending}>
+              Set up
+            </Button>
+          )}
+        </div>
+
+        {enrollment && (
+          <form
+            className="space-y-3 border-t border-slate-200 pt-4"
+            onSubmit={(event) => {
+              event.preventDefault();
+              confirmMutation.mutate();
+            }}
+          >
+            <p className="text-sm text-slate-700">
+              Add this account to your authenticator app by opening the{" "}
+              <a href={enrollment.provisioning_uri} className="text-emerald-700 underline-offset-4 hover:underline">
+                setup link
+              </a>{" "}
+              on your phone or entering the key below, then type the code it shows.
+            </p>
+            <code className="block break-all rounded-md bg-slate-100 px-3 py-2 text-sm">{enrollment.secret}</code>
+            <div className="flex gap-3">
+              <Input
+                autoComplete="one-time-code"
+                inputMode="numeric"
+                placeholder="123456"
+                value={code}
+                onChange={(event) => setCode(event.target.value)}
+                required
+              />
+              <Button type="submit" disabled={confirmMutation.isPending}>
+                Turn on
+              </Button>
+            </div>
+          </form>
+        )}
+
+        {recoveryCodes && (
+          <div className="space-y-2 border-t border-slate-200 pt-4">
+            <p className="text-sm text-slate-700">
+              Save these recovery codes somewhere safe. Each signs you in once without your authenticator
+              app, and they will not be shown again.
+            </p>
+            <ul className="grid grid-cols-2 gap-2 font-mono text-sm">
+              {recoveryCodes.map((recoveryCode) => (
+                <li key={recoveryCode} className="rounded-md bg-slate-100 px-3 py-1">
+                  {recoveryCode}
+                </li>
+              ))}
+            </ul>
+          </div>
+        )}
+
+        {status?.enabled && (
+          <div className="space-y-3 border-t border-slate-200 pt-4">
+            <Input
+              type="password"
+              placeholder="Confirm your password"
+              value={password}
+              onChange={(event) => setPassword(event.target.value)}
+            />
+            <div className="flex gap-3">
+              <Button
+                variant="outline"
+                onClick={() => regenerateMutation.mutate()}
+                disabled={!password || regenerateMutation.isPending}
+              >
+                New recovery codes
+              </Button>
+              <Button
+                variant="outline"
+                onClick={() => disableMutation.mutate()}
+                disabled={!password || disableMutation.isPending}
+              >
+                Turn off
+              </Button>
+            </div>
+          </div>
+        )}
+      </CardContent>
+    </Card>
+  );
+}
+
+function LinkedAccountsCard({
+  accessToken,
+  onMessage,
+  onError,
+}: {
+  accessToken: string;
+  onMessage: (message: string) => void;
+  onError: (error: string) => void;
+}) {
+  const queryClient = useQueryClient();
+
+  const providersQuery = useQuery({
+    queryKey: ["identity-providers"],
+    queryFn: () => listIdentityProviders(),
+  });
+  const identitiesQuery = useQuery({
+    queryKey: ["identities", accessToken],
+    queryFn: () => listIdentities(accessToken),
+  });
+  const failed = (fallback: string) => (err: unknown) =>
+    onError(err instanceof Error ? err.message : fallback);
+
+  // Linking leaves the app for the provider, which sends the browser back
+  // through /callback to this page.
+  const linkMutation = useMutation({
+    mutationFn: (provider: string) => linkIdentity(accessToken, provider),
+    onSuccess: ({ authorization_url }) => window.location.assign(authorization_url),
+    onError: failed("Failed to link account"),
+  });
+
+  const unlinkMutation = useMutation({
+    mutationFn: (provider: string) => unlinkIdentity(accessToken, provider),
+    onSuccess: async () => {
+      onMessage("Account unlinked");
+      await queryClient.invalidateQueries({ queryKey: ["identities"] });
+    },
+    onError: failed("Failed to unlink account"),
+  });
+
+  const providers = providersQuery.data?.providers ?? [];
+  const identities = identitiesQuery.data ?? [];
+  if (providers.length === 0 && identities.length === 0) {
+    return null;
+  }
+
+  return (
+    <Card className="mt-6">
+      <CardContent className="space-y-4 pt-6">
+        <h2 className="text-lg font-semibold text-slate-900">Linked accounts</h2>
+        {identitiesQuery.isLoading && <Loader2 className="h-5 w-5 animate-spin text-emerald-600" />}
+        {Array.from(new Set([...providers, ...identities.map((identity) => identity.provider)])).map(
+          (provider) => {
+            const identity = identities.find((linked) => linked.provider === provider);
+            return (
+              <div key={provider} className="flex items-center gap-4 border-t border-slate-200 pt-4">
+                <div className="flex h-10 w-10 items-center justify-center rounded-full bg-slate-100">
+                  <Link2 className="h-5 w-5 text-slate-600" />
+                </div>
+                <div className="min-w-0 flex-1">
+                  <p className="truncate text-sm font-medium text-slate-900">{provider}</p>
+                  <p className="text-sm text-slate-500">
+                    {identity
+                      ? `${identity.email ? `${identity.email} · ` : ""}Last used ${new Date(identity.last_used_at).toLocaleString()}`
+                      : "Not linked"}
+                  </p>
+                </div>
+                {identity ? (
+                  <Button
+                    variant="outline"
+                    onClick={() => unlinkMutation.mutate(provider)}
+                    disabled={unlinkMutation.isPending}
+                  >
+                    Unlink
+                  </Button>
+                ) : (
+                  <Button
+                    variant="outline"
+                    onClick={() => linkMutation.mutate(provider)}
+                    disabled={linkMutation.isPending}
+                  >
+                    Link
+                  </Button>
+                )}
+              </div>
+            );
+          }
+        )}
+      </CardContent>
+    </Card>
+  );
+}
+
+const tokenResources = [
+  "library",
+  "notes",
+  "reviews",
+  "collections",
+  "sources",
+  "tags",
+  "goals",
+  "profile",
+  "account",
+];
+
+function AccessTokensCard({
+  accessToken,
+  onMessage,
+  onError,
+}: {
+  accessToken: string;
+  onMessage: (message: string) => void;
+  onError: (error: string) => void;
+}) {
+  const queryClient = useQueryClient();
+  const [name, setName] = useState("");
+  const [access, setAccess] = useState<Record<string, string>>({});
+  const [expiresInDays, setExpiresInDays] = useState("90");
+  const [createdToken, setCreatedToken] = useState<string | null>(null);
+
+  const tokensQuery = useQuery({
+    queryKey: ["tokens", accessToken],
+    queryFn: () => listTokens(accessToken),
+  });
+  const refreshTokens = () => queryClient.invalidateQueries({ queryKey: ["tokens"] });
+  const failed = (fallback: string) => (err: unknown) =>
+    onError(err instanceof Error ? err.message : fallback);
+  const scopes = Object.entries(access)
+    .filter(([, level]) => level)
+    .map(([resource, level]) => `${resource}:${level}`);
+
+  const createMutation = useMutation({
+    mutationFn: () =>
+      createToken(accessToken, {
+        name: name.trim(),
+        scopes,
+        expires_in_days: expiresInDays ? Number(expiresInDays) : undefined,
+      }),
+    onSuccess: async ({ token }) => {
+      setCreatedToken(token);
+      setName("");
+      setAccess({});
+      onMessage("Access token created");
+      await refreshTokens();
+    },
+    onError: failed("Failed to create access token"),
+  });
+
+  const revokeMutation = useMutation({
+    mutationFn: (tokenID: string) => revokeToken(accessToken, tokenID),
+    onSuccess: async () => {
+      onMessage("Access token revoked");
+      await refreshTokens();
+    },
+    onError: failed("Failed to revoke access token"),
+  });
+
+  return (
+    <Card className="mt-6">
+      <CardContent className="space-y-4 pt-6">
+        <div className="flex items-center gap-4">
+          <div className="flex h-12 w-12 items-center justify-center rounded-full bg-emerald-100">
+            <Key className="h-6 w-6 text-emerald-600" />
+          </div>
+          <div className="flex-1">
+            <h2 className="text-lg font-semibold text-slate-900">Personal access tokens</h2>
+            <p className="text-sm text-slate-500">
+              Let scripts use the API as you, limited to the resources you choose.
+            </p>
+          </div>
+        </div>
+
+        {createdToken && (
+          <div className="space-y-2 border-t border-slate-200 pt-4">
+            <p className="text-sm text-slate-700">
+              Copy this token now. It will not be shown again.
+            </p>
+            <code className="block break-all rounded-md bg-slate-100 px-3 py-2 text-sm">{createdToken}</code>
+          </div>
+        )}
+
+        <form
+          className="space-y-3 border-t border-slate-200 pt-4"
+          onSubmit={(event) => {
+            event.preventDefault();
+            setCreatedToken(null);
+            createMutation.mutate();
+          }}
+        >
+          <Input
+            placeholder="Token name"
+            maxLength={100}
+            value={name}
+            onChange={(event) => setName(event.target.value)}
+            required
+          />
+          <div className="grid grid-cols-3 gap-2 text-sm text-slate-700">
+            {tokenResources.map((resource) => (
+              <label key={resource} className="flex flex-col gap-1">
+                {resource}
+                <select
+                  className="rounded-md border border-slate-300 bg-white px-2 py-1"
+                  value={access[resource] ?? ""}
+                  onChange={(event) => setAccess({ ...access, [resource]: event.target.value })}
+                >
+                  <option value="">No access</option>
+                  <option value="read">Read</option>
+                  <option value="write">Read and write</option>
+                </select>
+              </label>
+            ))}
+          </div>
+          <div className="flex gap-3">
+            <select
+              className="rounded-md border border-slate-300 bg-white px-3 py-2 text-sm"
+              value={expiresInDays}
+              onChange={(event) => setExpiresInDays(event.target.value)}
+            >
+              <option value="30">Expires in 30 days</option>
+              <option value="90">Expires in 90 days</option>
+              <option value="365">Expires in a year</option>
+              <option value="">Never expires</option>
+            </select>
+            <Button type="submit" disabled={!name.trim() || scopes.length === 0 || createMutation.isPending}>
+              Create token
+            </Button>
+          </div>
+        </form>
+
+        {tokensQuery.isLoading && <Loader2 className="h-5 w-5 animate-spin text-emerald-600" />}
+        {tokensQuery.data?.map((token) => (
+          <div key={token.id} className="flex items-center gap-4 border-t border-slate-200 pt-4">
+            <div className="min-w-0 flex-1">
+              <p className="truncate text-sm font-medium text-slate-900">{token.name}</p>
+              <p className="text-sm text-slate-500">{token.scopes.join(", ")}</p>
+              <p className="text-sm text-slate-500">
+                {token.last_used_at
+                  ? `Last used ${new Date(token.last_used_at).toLocaleString()}${token.last_used_ip ? ` from ${token.last_used_ip}` : ""}`
+                  : "Never used"}
+                {" · "}
+                {token.expires_at
+                  ? `${new Date(token.expires_at) < new Date() ? "Expired" : "Expires"} ${new Date(token.expires_at).toLocaleDateString()}`
+                  : "No expiry"}
+              </p>
+            </div>
+            <Button
+              variant="outline"
+              onClick={() => revokeMutation.mutate(token.id)}
+              disabled={revokeMutation.isPending}
+            >
+              Revoke
+            </Button>
+          </div>
+        ))}
+      </CardContent>
+    </Card>
+  );
+}
@@ -77,10 +77,10 @@ export default function PublicProfilePage() {
       </main>
     );
 
-  const library = libraryQuery.data || [];
-  const notes = notesQuery.data || [];
-  const reviews = reviewsQuery.data || [];
-  const collections = collectionsQuery.data || [];
+  const library = libraryQuery.data?.items || [];
+  const notes = notesQuery.data?.items || [];
+  const reviews = reviewsQuery.data?.items || [];
+  const collections = collectionsQuery.data?.items || [];
 
   return (
     <main className="min-h-screen bg-slate-50 px-4 py-10">
@@ -1,9 +1,33 @@
-import { CheckCircle, Library } from "lucide-react";
-import { Link } from "react-router";
+import { CheckCircle, Library, XCircle } from "lucide-react";
+import { useEffect, useRef, useState } from "react";
+import { Link, useSearchParams } from "react-router";
 import { Button } from "~/components/ui/button";
 import { Card, CardContent } from "~/components/ui/card";
+import { verifyEmail } from "~/lib/api";
+
+type Status = "verifying" | "verified" | "failed";
 
 export default function VerificationPage() {
+  const [searchParams] = useSearchParams();
+  const token = searchParams.get("token");
+  const [status, setStatus] = useState<Status>(token ? "verifying" : "failed");
+  const [message, setMessage] = useState(token ? "" : "This verification link is missing its token.");
+  const submitted = useRef(false);
+
+  useEffect(() => {
+    // Tokens work once, so the request must not be repeated on re-render.
+    if (!token || submitted.current) {
+      return;
+    }
+    submitted.current = true;
+    verifyEmail(token)
+      .then(() => setStatus("verified"))
+      .catch((err) => {
+        setStatus("failed");
+        setMessage(err instanceof Error ? err.message : "Could not verify your email.");
+      });
+  }, [token]);
+
   return (
     <div className="flex min-h-screen items-center justify-center bg-gradient-to-b from-slate-50 to-slate-100 px-4">
       <div className="w-full max-w-md">
@@ -16,9 +40,17 @@ export default function VerificationPage() {
         <Card>
           <CardContent className="pt-6">
             <div className="flex flex-col items-center text-center">
-              <CheckCircle className="mb-4 h-16 w-16 text-emerald-500" />
+              {status === "failed" ? (
+                <XCircle className="mb-4 h-16 w-16 text-red-500" />
+              ) : (
+                <CheckCircle className="mb-4 h-16 w-16 text-emerald-500" />
+              )}
               <h2 className="mb-2 text-xl font-semibold text-slate-900">Email Verification</h2>
-              <p className="mb-6 text-slate-600">Email verification is not implemented yet.</p>
+              <p className="mb-6 text-slate-600">
+                {status === "verifying" && "Verifying your email address..."}
+                {status === "verified" && "Your email address is verified."}
+                {status === "failed" && `${message} You can ask for a new link from your settings.`}
+              </p>
               <Link to="/dashboard">
                 <Button>Go to Dashboard</Button>
               </Link>
@@ -0,0 +1,71 @@
+// Package account assembles a user's personal data into a downloadable
+// archive.
+package account
+
+import (
+	"context"
+	"time"
+
+	"github.com/gofrs/uuid/v5"
+	"github.com/zizouhuweidi/maktaba/internal/auth"
+	"github.com/zizouhuweidi/maktaba/internal/collections"
+	"github.com/zizouhuweidi/maktaba/internal/goals"
+	"github.com/zizouhuweidi/maktaba/internal/library"
+	"github.com/zizouhuweidi/maktaba/internal/notes"
+	"github.com/zizouhuweidi/maktaba/internal/profiles"
+	"github.com/zizouhuweidi/maktaba/internal/reviews"
+)
+
+// Users loads the account being exported. auth.Service satisfies it.
+type Users interface {
+	GetUser(ctx context.Context, userID uuid.UUID) (*auth.User, error)
+}
+
+// Profiles loads the user's profile. profiles.Service satisfies it.
+type Profiles interface {
+	GetOwn(ctx context.Context, userID uuid.UUID) (*profiles.Profile, error)
+}
+
+// Library lists the user's library items, reading sessions and earlier
+// completions.
+// library.Service satisfies it.
+type Library interface {
+	ListByUserWithSources(ctx context.Context, userID uuid.UUID, limit, offset int) ([]*library.ItemWithSource, error)
+	ListSessionsByUser(ctx context.Context, userID uuid.UUID, limit, offset int) ([]*library.Session, error)
+	ListCompletionsByUser(ctx context.Context, userID uuid.UUID, limit, offset int) ([]*library.Completion, error)
+}
+
+// Notes lists the user's notes. notes.Service satisfies it.
+type Notes interface {
+	ListByUser(ctx context.Context, userID uuid.UUID, limit, offset int) ([]*notes.Note, error)
+}
+
+// Reviews lists the user's reviews. reviews.Service satisfies it.
+type Reviews interface {
+	ListByUser(ctx context.Context, userID uuid.UUID, limit, offset int) ([]*reviews.Review, error)
+}
+
+// Collections lists the user's collections. collections.Service satisfies it.
+type Collections interface {
+	ListByUser(ctx context.Context, userID uuid.UUID, limit, offset int) ([]*collections.Collection, error)
+}
+
+// Goals lists the goals the user set or joined, with their achievements.
+// goals.Service satisfies it.
+type Goals interface {
+	ListParticipations(ctx context.Context, userID uuid.UUID, limit, offset int) ([]*goals.Participation, error)
+}
+
+// Export is every piece of personal data held for a user.
+type Export struct {
+	ExportedAt      time.Time                 `json:"exported_at"`
+	User            *auth.User                `json:"user"`
+	Profile         *profiles.Profile         `json:"profile"`
+	Library         []*library.ItemWithSource `json:"library"`
+	ReadingSessions []*library.Session        `json:"reading_sessions"`
+	Completions     []*library.Completion     `json:"completions"`
+	Notes           []*notes.Note             `json:"notes"`
+	Reviews         []*reviews.Review         `json:"reviews"`
+	Collections     []*collections.Collection `json:"collections"`
+	Goals           []*goals.Participation    `json:"goals"`
+}
@@ -0,0 +1,200 @@
+package account
+
+import (
+	"archive/zip"
+	"bytes"
+	"context"
+	"encoding/json"
+	"io"
+	"log/slog"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/gofrs/uuid/v5"
+	"github.com/zizouhuweidi/maktaba/internal/auth"
+	"github.com/zizouhuweidi/maktaba/internal/collections"
+	"github.com/zizouhuweidi/maktaba/internal/goals"
+	"github.com/zizouhuweidi/maktaba/internal/library"
+	"github.com/zizouhuweidi/maktaba/internal/notes"
+	"github.com/zizouhuweidi/maktaba/internal/profiles"
+	"github.com/zizouhuweidi/maktaba/internal/reviews"
+)
+
+type fakeUsers struct{ user *auth.User }
+
+func (f fakeUsers) GetUser(ctx context.Context, userID uuid.UUID) (*auth.User, error) {
+	return f.user, nil
+}
+
+type fakeProfiles struct{}
+
+func (fakeProfiles) GetOwn(ctx context.Context, userID uuid.UUID) (*profiles.Profile, error) {
+	return &profiles.Profile{UserID: userID}, nil
+}
+
+type fakeLibrary struct{}
+
+func (fakeLibrary) ListByUserWithSources(ctx context.Context, userID uuid.UUID, limit, offset int) ([]*library.ItemWithSource, error) {
+	return nil, nil
+}
+
+func (fakeLibrary) ListSessionsByUser(ctx context.Context, userID uuid.UUID, limit, offset int) ([]*library.Session, error) {
+	return nil, nil
+}
+
+func (fakeLibrary) ListCompletionsByUser(ctx context.Context, userID uuid.UUID, limit, offset int) ([]*library.Completion, error) {
+	return nil, nil
+}
+
+type fakeNotes struct{ notes []*notes.Note }
+
+func (f fakeNotes) ListByUser(ctx context.Context, userID uuid.UUID, limit, offset int) ([]*notes.Note, error) {
+	if offset >= len(f.notes) {
+		return nil, nil
+	}
+	return f.notes[offset:min(offset+limit, len(f.notes))], nil
+}
+
+type fakeReviews struct{}
+
+func (fakeReviews) ListByUser(ctx context.Context, userID uuid.UUID, limit, offset int) ([]*reviews.Review, error) {
+	return []*reviews.Review{{ID: uuid.Must(uuid.NewV7()), UserID: userID, Rating: 5}}, nil
+}
+
+type fakeCollections struct{}
+
+func (fakeCollections) ListByUser(ctx context.Context, userID uuid.UUID, limit, offset int) ([]*collections.Collection, error) {
+	return nil, nil
+}
+
+type fakeGoals struct{}
+
+func (fakeGoals) ListParticipations(ctx context.Context, userID uuid.UUID, limit, offset int) ([]*goals.Participation, error) {
+	if offset > 0 {
+		return nil, nil
+	}
+	goal := &goals.Goal{ID: uuid.Must(uuid.NewV7()), OwnerID: uuid.Must(uuid.NewV7()), Title: "Read 24 books", Target: 24, Period: goals.PeriodTotal, Shared: true}
+	return []*goals.Participation{{
+		Goal:     goal,
+		JoinedAt: time.Date(2024, 1, 3, 0, 0, 0, 0, time.UTC),
+		Achievements: []*goals.Achievement{{
+			PeriodStart: time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC),
+			AchievedAt:  time.Date(2024, 11, 30, 12, 0, 0, 0, time.UTC),
+		}},
+	}}, nil
+}
+
+func testService(t *testing.T, noteCount int) (*Service, uuid.UUID) {
+	t.Helper()
+	userID := uuid.Must(uuid.NewV7())
+	sourceID := uuid.Must(uuid.NewV7())
+	userNotes := make([]*notes.Note, noteCount)
+	for i := range userNotes {
+		userNotes[i] = &notes.Note{
+			ID:          uuid.Must(uuid.NewV7()),
+			UserID:      userID,
+			SourceID:    &sourceID,
+			Content:     "On asabiyyah",
+			ContentType: notes.ContentTypeNote,
+			Tags:        []string{"history"},
+			CreatedAt:   time.Date(2024, 1, 2, 3, 4, 5, 0, time.UTC),
+			UpdatedAt:   time.Date(2024, 1, 2, 3, 4, 5, 0, time.UTC),
+		}
+	}
+
+	service := NewService(
+		fakeUsers{user: &auth.User{ID: userID, Email: "reader@example.com", Username: "reader", PasswordHash: "secret-hash"}},
+		fakeProfiles{},
+		fakeLibrary{},
+		fakeNotes{notes: userNotes},
+		fakeReviews{},
+		fakeCollections{},
+		fakeGoals{},
+		slog.New(slog.NewTextHandler(io.Discard, nil)),
+	)
+	return service, userID
+}
+
+func TestCollectPagesThroughAllNotes(t *testing.T) {
+	service, userID := testService(t, pageSize+20)
+
+	export, err := service.Collect(context.Background(), userID)
+	if err != nil {
+		t.Fatalf("Collect() error = %v", err)
+	}
+	if len(export.Notes) != pageSize+20 {
+		t.Fatalf("notes = %d, want %d", len(export.Notes), pageSize+20)
+	}
+	if len(export.Reviews) != 1 || export.Library == nil || export.Collections == nil {
+		t.Fatalf("export = %+v", export)
+	}
+}
+
+func TestCollectIncludesGoalsAndAchievements(t *testing.T) {
+	service, userID := testService(t, 0)
+
+	export, err := service.Collect(context.Background(), userID)
+	if err != nil {
+		t.Fatalf("Collect() error = %v", err)
+	}
+	if len(export.Goals) != 1 {
+		t.Fatalf("goals = %d, want 1", len(export.Goals))
+	}
+	participation := export.Goals[0]
+	if participation.Goal.Title != "Read 24 books" || participation.JoinedAt.IsZero() || len(participation.Achievements) != 1 {
+		t.Fatalf("goal = %+v", participation)
+	}
+}
+
+func TestWriteZipIncludesJSONAndMarkdown(t *testing.T) {
+	service, userID := testService(t, 2)
+	export, err := service.Collect(context.Background(), userID)
+	if err != nil {
+		t.Fatalf("Collect() error = %v", err)
+	}
+
+	var buf bytes.Buffer
+	if err := export.WriteZip(&buf); err != nil {
+		t.Fatalf("WriteZip() error = %v", err)
+	}
+	archive, err := zip.NewReader(bytes.NewReader(buf.Bytes()), int64(buf.Len()))
+	if err != nil {
+		t.Fatalf("zip.NewReader() error = %v", err)
+	}
+
+	files := make(map[string]string)
+	for _, file := range archive.File {
+		r, err := file.Open()
+		if err != nil {
+			t.Fatalf("open %s: %v", file.Name, err)
+		}
+		body, err := io.ReadAll(r)
+		r.Close()
+		if err != nil {
+			t.Fatalf("read %s: %v", file.Name, err)
+		}
+		files[file.Name] = string(body)
+	}
+
+	for _, name := range []string{"account.json", "library.json", "reading_sessions.json", "completions.json", "notes.json", "reviews.json", "collections.json", "goals.json"} {
+		if !json.Valid([]byte(files[name])) {
+			t.Fatalf("%s is not valid JSON: %q", name, files[name])
+		}
+	}
+	if !strings.Contains(files["goals.json"], `"achievements"`) {
+		t.Fatalf("goals.json = %q, want the achievements", files["goals.json"])
+	}
+	if strings.Contains(files["account.json"], "secret-hash") {
+		t.Fatal("account.json leaks the password hash")
+	}
+
+	note := export.Notes[0]
+	markdown, ok := files["notes/"+note.ID.String()+".md"]
+	if !ok {
+		t.Fatalf("missing markdown for note %s", note.ID)
+	}
+	if !strings.HasPrefix(markdown, "---\nid: "+note.ID.String()) || !strings.Contains(markdown, "  - \"history\"\n") || !strings.HasSuffix(markdown, "---\n\nOn asabiyyah\n") {
+		t.Fatalf("markdown = %q", markdown)
+	}
+}
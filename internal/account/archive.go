@@ -0,0 +1,108 @@
+package account
+
+import (
+	"archive/zip"
+	"encoding/json"
+	"fmt"
+	"io"
+	"strings"
+	"time"
+
+	"github.com/zizouhuweidi/maktaba/internal/notes"
+)
+
+// WriteZip writes the export as a ZIP archive: one JSON file per data set and
+// a Markdown file per note under notes/.
+func (e *Export) WriteZip(w io.Writer) error {
+	archive := zip.NewWriter(w)
+
+	files := []struct {
+		name  string
+		value any
+	}{
+		{"account.json", struct {
+			ExportedAt time.Time `json:"exported_at"`
+			User       any       `json:"user"`
+			Profile    any       `json:"profile"`
+		}{e.ExportedAt, e.User, e.Profile}},
+		{"library.json", e.Library},
+		{"reading_sessions.json", e.ReadingSessions},
+		{"completions.json", e.Completions},
+		{"notes.json", e.Notes},
+		{"reviews.json", e.Reviews},
+		{"collections.json", e.Collections},
+		{"goals.json", e.Goals},
+	}
+	for _, file := range files {
+		if err := writeJSON(archive, file.name, e.ExportedAt, file.value); err != nil {
+			return err
+		}
+	}
+
+	for _, note := range e.Notes {
+		out, err := archive.CreateHeader(&zip.FileHeader{Name: "notes/" + note.ID.String() + ".md", Method: zip.Deflate, Modified: note.UpdatedAt})
+		if err != nil {
+			return err
+		}
+		if _, err := io.WriteString(out, noteMarkdown(note)); err != nil {
+			return err
+		}
+	}
+
+	return archive.Close()
+}
+
+func writeJSON(archive *zip.Writer, name string, modified time.Time, value any) error {
+	out, err := archive.CreateHeader(&zip.FileHeader{Name: name, Method: zip.Deflate, Modified: modified})
+	if err != nil {
+		return err
+	}
+	encoder := json.NewEncoder(out)
+	encoder.SetIndent("", "  ")
+	return encoder.Encode(value)
+}
+
+// noteMarkdown renders a note with YAML front matter so it can be dropped
+// into a Markdown-based notes tool.
+func noteMarkdown(note *notes.Note) string {
+	var b strings.Builder
+	b.WriteString("---\n")
+	fmt.Fprintf(&b, "id: %s\n", note.ID)
+	if note.SourceID != nil {
+		fmt.Fprintf(&b, "source_id: %s\n", note.SourceID)
+	}
+	fmt.Fprintf(&b, "content_type: %s\n", note.ContentType)
+	fmt.Fprintf(&b, "public: %t\n", note.IsPublic)
+	if anchor := note.Anchor; anchor != nil {
+		if anchor.Chapter != nil {
+			fmt.Fprintf(&b, "chapter: %q\n", *anchor.Chapter)
+		}
+		if anchor.PageStart != nil && anchor.PageEnd != nil && *anchor.PageEnd != *anchor.PageStart {
+			fmt.Fprintf(&b, "pages: %d-%d\n", *anchor.PageStart, *anchor.PageEnd)
+		} else if anchor.PageStart != nil {
+			fmt.Fprintf(&b, "page: %d\n", *anchor.PageStart)
+		}
+		if anchor.StartSeconds != nil {
+			fmt.Fprintf(&b, "start_seconds: %d\n", *anchor.StartSeconds)
+		}
+	}
+	if len(note.Tags) > 0 {
+		b.WriteString("tags:\n")
+		for _, tag := range note.Tags {
+			fmt.Fprintf(&b, "  - %q\n", tag)
+		}
+	}
+	fmt.Fprintf(&b, "created_at: %s\n", note.CreatedAt.UTC().Format(time.RFC3339))
+	fmt.Fprintf(&b, "updated_at: %s\n", note.UpdatedAt.UTC().Format(time.RFC3339))
+	b.WriteString("---\n\n")
+	b.WriteString(note.Content)
+	if !strings.HasSuffix(note.Content, "\n") {
+		b.WriteString("\n")
+	}
+	for _, annotation := range note.Annotations {
+		b.WriteString("\n> ")
+		b.WriteString(strings.ReplaceAll(annotation, "\n", "\n> "))
+		b.WriteString("\n")
+	}
+	return b.String()
+}
@@ -0,0 +1,54 @@
+package account
+
+import (
+	"errors"
+	"fmt"
+	"log/slog"
+	"net/http"
+
+	"github.com/labstack/echo/v5"
+	"github.com/zizouhuweidi/maktaba/internal/auth"
+)
+
+type Handler struct {
+	service *Service
+	logger  *slog.Logger
+}
+
+func NewHandler(service *Service, logger *slog.Logger) *Handler {
+	return &Handler{service: service, logger: logger}
+}
+
+func (h *Handler) RegisterProtectedRoutes(g *echo.Group) {
+	g.GET("/me/export", h.Export)
+}
+
+// Export streams the user's data as a ZIP attachment.
+func (h *Handler) Export(c *echo.Context) error {
+	userID, ok := auth.UserID(c)
+	if !ok {
+		return echo.NewHTTPError(http.StatusUnauthorized, "authentication required")
+	}
+
+	export, err := h.service.Collect(c.Request().Context(), userID)
+	if errors.Is(err, auth.ErrUserNotFound) {
+		return echo.NewHTTPError(http.StatusNotFound, "user not found")
+	}
+	if err != nil {
+		h.logger.Error("failed to collect account export", "error", err, "user_id", userID)
+		return echo.NewHTTPError(http.StatusInternalServerError, "failed to export account")
+	}
+
+	filename := fmt.Sprintf("maktaba-export-%s-%s.zip", export.User.Username, export.ExportedAt.Format("20060102"))
+	header := c.Response().Header()
+	header.Set(echo.HeaderContentType, "application/zip")
+	header.Set(echo.HeaderContentDisposition, fmt.Sprintf("attachment; filename=%q", filename))
+	header.Set("Cache-Control", "no-store")
+	c.Response().WriteHeader(http.StatusOK)
+
+	if err := export.WriteZip(c.Response()); err != nil {
+		// Headers are already sent, so the client sees a truncated archive.
+		h.logger.Error("failed to write account export", "error", err, "user_id", userID)
+	}
+	return nil
+}
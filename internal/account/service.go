@@ -0,0 +1,90 @@
+package account
+
+import (
+	"context"
+	"log/slog"
+	"time"
+
+	"github.com/gofrs/uuid/v5"
+)
+
+// pageSize matches the largest page the domain services return.
+const pageSize = 100
+
+type Service struct {
+	users       Users
+	profiles    Profiles
+	library     Library
+	notes       Notes
+	reviews     Reviews
+	collections Collections
+	goals       Goals
+	logger      *slog.Logger
+}
+
+func NewService(users Users, profiles Profiles, library Library, notes Notes, reviews Reviews, collections Collections, goals Goals, logger *slog.Logger) *Service {
+	return &Service{
+		users:       users,
+		profiles:    profiles,
+		library:     library,
+		notes:       notes,
+		reviews:     reviews,
+		collections: collections,
+		goals:       goals,
+		logger:      logger,
+	}
+}
+
+// Collect loads all of the user's data. Everything is read before the archive
+// is written so a failure can still be reported with a proper status code.
+func (s *Service) Collect(ctx context.Context, userID uuid.UUID) (*Export, error) {
+	user, err := s.users.GetUser(ctx, userID)
+	if err != nil {
+		return nil, err
+	}
+	profile, err := s.profiles.GetOwn(ctx, userID)
+	if err != nil {
+		return nil, err
+	}
+
+	export := &Export{ExportedAt: time.Now().UTC(), User: user, Profile: profile}
+	if export.Library, err = listAll(ctx, userID, s.library.ListByUserWithSources); err != nil {
+		return nil, err
+	}
+	if export.ReadingSessions, err = listAll(ctx, userID, s.library.ListSessionsByUser); err != nil {
+		return nil, err
+	}
+	if export.Completions, err = listAll(ctx, userID, s.library.ListCompletionsByUser); err != nil {
+		return nil, err
+	}
+	if export.Notes, err = listAll(ctx, userID, s.notes.ListByUser); err != nil {
+		return nil, err
+	}
+	if export.Reviews, err = listAll(ctx, userID, s.reviews.ListByUser); err != nil {
+		return nil, err
+	}
+	if export.Collections, err = listAll(ctx, userID, s.collections.ListByUser); err != nil {
+		return nil, err
+	}
+	if export.Goals, err = listAll(ctx, userID, s.goals.ListParticipations); err != nil {
+		return nil, err
+	}
+
+	s.logger.Info("account export collected", "user_id", userID, "library_items", len(export.Library), "reading_sessions", len(export.ReadingSessions), "completions", len(export.Completions), "notes", len(export.Notes), "reviews", len(export.Reviews), "collections", len(export.Collections), "goals", len(export.Goals))
+	return export, nil
+}
+
+// listAll pages through a user-scoped list until a short page is returned.
+func listAll[T any](ctx context.Context, userID uuid.UUID, list func(context.Context, uuid.UUID, int, int) ([]T, error)) ([]T, error) {
+	all := make([]T, 0)
+	for offset := 0; ; offset += pageSize {
+		page, err := list(ctx, userID, pageSize, offset)
+		if err != nil {
+			return nil, err
+		}
+		all = append(all, page...)
+		if len(page) < pageSize {
+			return all, nil
+		}
+	}
+}
@@ -1,21 +1,112 @@
 package auth
 
 import (
+	"context"
+	"net/http"
+	"slices"
+	"strings"
 	"time"
 
 	"github.com/gofrs/uuid/v5"
 	"github.com/labstack/echo/v5"
+	"github.com/zizouhuweidi/maktaba/internal/oidc"
 )
 
-const userIDContextKey = "user_id"
+const (
+	userIDContextKey    = "user_id"
+	roleContextKey      = "user_role"
+	sessionIDContextKey = "session_id"
+	scopesContextKey    = "token_scopes"
+)
+
+// Outbox aggregate and event types published by this context.
+const (
+	AggregateUser    = "user"
+	EventUserDeleted = "user.deleted"
+)
+
+// Role controls what a user may do to shared data such as the source catalog.
+type Role string
+
+const (
+	RoleUser   Role = "user"
+	RoleEditor Role = "editor"
+	RoleAdmin  Role = "admin"
+)
+
+// CanModerate reports whether the role may edit any source directly and
+// review change proposals.
+func (r Role) CanModerate() bool {
+	return r == RoleEditor || r == RoleAdmin
+}
+
+func validRole(role Role) bool {
+	switch role {
+	case RoleUser, RoleEditor, RoleAdmin:
+		return true
+	default:
+		return false
+	}
+}
+
+// Resources a personal access token can be scoped to. A "<resource>:read"
+// scope allows reading the resource and "<resource>:write" allows changing it
+// as well.
+const (
+	ResourceAccount     = "account"
+	ResourceCollections = "collections"
+	ResourceGoals       = "goals"
+	ResourceLibrary     = "library"
+	ResourceNotes       = "notes"
+	ResourceProfile     = "profile"
+	ResourceReviews     = "reviews"
+	ResourceSources     = "sources"
+	ResourceTags        = "tags"
+)
+
+var scopeResources = []string{
+	ResourceAccount,
+	ResourceCollections,
+	ResourceGoals,
+	ResourceLibrary,
+	ResourceNotes,
+	ResourceProfile,
+	ResourceReviews,
+	ResourceSources,
+	ResourceTags,
+}
+
+func validScope(scope string) bool {
+	resource, access, ok := strings.Cut(scope, ":")
+	return ok && slices.Contains(scopeResources, resource) && (access == "read" || access == "write")
+}
+
+// grantsScope reports whether scopes allow a request to the resource. Write
+// access includes read access.
+func grantsScope(scopes []string, resource string, write bool) bool {
+	for _, scope := range scopes {
+		r, access, _ := strings.Cut(scope, ":")
+		if r == resource && (access == "write" || (access == "read" && !write)) {
+			return true
+		}
+	}
+	return false
+}
+
+// writeMethod reports whether a request with the method may change data.
+func writeMethod(method string) bool {
+	return method != http.MethodGet && method != http.MethodHead
+}
 
 type User struct {
-	ID           uuid.UUID `json:"id" db:"id"`
-	Email        string    `json:"email" db:"email"`
-	Username     string    `json:"username" db:"username"`
-	PasswordHash string    `json:"-" db:"password_hash"`
-	CreatedAt    time.Time `json:"created_at" db:"created_at"`
-	UpdatedAt    time.Time `json:"updated_at" db:"updated_at"`
+	ID              uuid.UUID  `json:"id" db:"id"`
+	Email           string     `json:"email" db:"email"`
+	Username        string     `json:"username" db:"username"`
+	PasswordHash    string     `json:"-" db:"password_hash"`
+	Role            Role       `json:"role" db:"role"`
+	EmailVerifiedAt *time.Time `json:"email_verified_at" db:"email_verified_at"`
+	CreatedAt       time.Time  `json:"created_at" db:"created_at"`
+	UpdatedAt       time.Time  `json:"updated_at" db:"updated_at"`
 }
 
 type RefreshToken struct {
@@ -28,10 +119,154 @@ type RefreshToken struct {
 	CreatedAt time.Time  `db:"created_at"`
 }
 
+// TokenPurpose says what a user token is good for. A token only works for its
+// own purpose.
+type TokenPurpose string
+
+const (
+	PurposeVerifyEmail    TokenPurpose = "verify_email"
+	PurposeResetPassword  TokenPurpose = "reset_password"
+	PurposeMFALogin       TokenPurpose = "mfa_login"
+	PurposeReauthenticate TokenPurpose = "reauthenticate"
+)
+
+// UserToken is a single-use token handed to a user, by email or as a sign-in
+// challenge. Only its hash is stored.
+type UserToken struct {
+	ID        uuid.UUID    `db:"id"`
+	UserID    uuid.UUID    `db:"user_id"`
+	Purpose   TokenPurpose `db:"purpose"`
+	TokenHash []byte       `db:"token_hash"`
+	ExpiresAt time.Time    `db:"expires_at"`
+}
+
+// TOTPCredential is a user's authenticator app enrollment. Secret is
+// encrypted, and ConfirmedAt is nil until the user has entered a code from the
+// app.
+type TOTPCredential struct {
+	UserID       uuid.UUID
+	Secret       []byte
+	ConfirmedAt  *time.Time
+	LastUsedStep int64
+}
+
+// TOTPEnrollment is what the user adds to their authenticator app, either by
+// scanning the provisioning URI as a QR code or by typing the secret.
+type TOTPEnrollment struct {
+	Secret          string `json:"secret"`
+	ProvisioningURI string `json:"provisioning_uri"`
+}
+
+type MFAStatus struct {
+	Enabled                bool       `json:"enabled"`
+	EnabledAt              *time.Time `json:"enabled_at,omitempty"`
+	RecoveryCodesRemaining int64      `json:"recovery_codes_remaining"`
+}
+
+// MFAChallenge is returned by a password login when the account has a second
+// factor. The token completes the sign-in together with a code.
+type MFAChallenge struct {
+	Token     string `json:"challenge_token"`
+	ExpiresIn int64  `json:"expires_in"`
+}
+
+// IdentityProvider signs users in at an external OpenID provider with the
+// authorization code flow. *oidc.Provider implements it.
+type IdentityProvider interface {
+	AuthCodeURL(ctx context.Context, state, nonce, codeVerifier string) (string, error)
+	Exchange(ctx context.Context, code, codeVerifier, nonce string) (*oidc.Identity, error)
+}
+
+// ExternalIdentity is an account at an OpenID provider that the user can sign
+// in with. Email is the address the provider last shared.
+type ExternalIdentity struct {
+	Provider   string    `json:"provider"`
+	Email      *string   `json:"email,omitempty"`
+	CreatedAt  time.Time `json:"created_at"`
+	LastUsedAt time.Time `json:"last_used_at"`
+}
+
+// ExternalLogin is a sign-in waiting for the provider to redirect back. Only
+// the hash of its state is stored. UserID is set when a signed-in user is
+// linking the provider rather than signing in, or, with Reauthenticate, is
+// proving it is still them before a sensitive change.
+type ExternalLogin struct {
+	StateHash      []byte
+	Provider       string
+	Nonce          string
+	CodeVerifier   string
+	UserID         *uuid.UUID
+	Reauthenticate bool
+	ExpiresAt      time.Time
+}
+
+// ExternalLoginResult is the outcome of a provider's callback: tokens for the
+// user, a challenge when they have a second factor, when linking just the
+// user the provider was linked to, or when re-authenticating a token that
+// confirms one sensitive change.
+type ExternalLoginResult struct {
+	User        *User
+	Tokens      AuthTokens
+	Challenge   *MFAChallenge
+	Linked      bool
+	ReauthToken string
+}
+
+// Confirmation re-authenticates a signed-in user before a sensitive change:
+// their password, or the token a fresh sign-in at a linked provider returned,
+// which is how a user without a password confirms.
+type Confirmation struct {
+	Password    string
+	ReauthToken string
+}
+
+// PersonalAccessToken is a long-lived token a user created for scripts. Only
+// its hash is stored, so the token itself is shown once, when it is created.
+// It works until ExpiresAt, or until it is revoked when ExpiresAt is nil.
+type PersonalAccessToken struct {
+	ID         uuid.UUID  `json:"id"`
+	UserID     uuid.UUID  `json:"-"`
+	Name       string     `json:"name"`
+	TokenHash  []byte     `json:"-"`
+	Scopes     []string   `json:"scopes"`
+	ExpiresAt  *time.Time `json:"expires_at,omitempty"`
+	LastUsedAt *time.Time `json:"last_used_at,omitempty"`
+	LastUsedIP *string    `json:"last_used_ip,omitempty"`
+	CreatedAt  time.Time  `json:"created_at"`
+}
+
+// TokenGrant is what a live personal access token lets a request do: act as
+// the user, with their current role, within the token's scopes.
+type TokenGrant struct {
+	TokenID uuid.UUID
+	UserID  uuid.UUID
+	Role    Role
+	Scopes  []string
+}
+
 type RefreshTokenRotation struct {
 	CurrentTokenID uuid.UUID
 	NewToken       RefreshToken
 	ReplacedByID   uuid.UUID
+	Client         Client
+}
+
+// Session is one sign-in: the refresh token family a login or registration
+// starts, which every refresh continues. ID is the family ID.
+type Session struct {
+	ID         uuid.UUID `json:"id"`
+	UserAgent  *string   `json:"user_agent,omitempty"`
+	IPAddress  *string   `json:"ip_address,omitempty"`
+	CreatedAt  time.Time `json:"created_at"`
+	LastUsedAt time.Time `json:"last_used_at"`
+	ExpiresAt  time.Time `json:"expires_at"`
+	Current    bool      `json:"current"`
+}
+
+// Client describes the device behind a request, as recorded on its session.
+type Client struct {
+	UserAgent string
+	IPAddress string
 }
 
 func SetUserID(c *echo.Context, userID uuid.UUID) {
@@ -42,3 +277,41 @@ func UserID(c *echo.Context) (uuid.UUID, bool) {
 	userID, ok := c.Get(userIDContextKey).(uuid.UUID)
 	return userID, ok
 }
+
+// SetSessionID records the session the access token was issued to.
+func SetSessionID(c *echo.Context, sessionID uuid.UUID) {
+	c.Set(sessionIDContextKey, sessionID)
+}
+
+// SessionID returns the session the request's access token belongs to. Tokens
+// issued before sessions were recorded have none.
+func SessionID(c *echo.Context) (uuid.UUID, bool) {
+	sessionID, ok := c.Get(sessionIDContextKey).(uuid.UUID)
+	return sessionID, ok
+}
+
+// SetScopes records the scopes of the personal access token that
+// authenticated the request.
+func SetScopes(c *echo.Context, scopes []string) {
+	c.Set(scopesContextKey, scopes)
+}
+
+// Scopes returns the scopes the request is limited to. Requests signed in
+// with an access token are not limited and have none.
+func Scopes(c *echo.Context) ([]string, bool) {
+	scopes, ok := c.Get(scopesContextKey).([]string)
+	return scopes, ok
+}
+
+func SetRole(c *echo.Context, role Role) {
+	c.Set(roleContextKey, role)
+}
+
+// UserRole returns the authenticated user's role, defaulting to RoleUser for
+// tokens issued before roles existed.
+func UserRole(c *echo.Context) Role {
+	if role, ok := c.Get(roleContextKey).(Role); ok && validRole(role) {
+		return role
+	}
+	return RoleUser
+}
@@ -0,0 +1,61 @@
+package auth
+
+import (
+	"fmt"
+	"net/url"
+	"strings"
+	"time"
+
+	"github.com/zizouhuweidi/maktaba/internal/mail"
+)
+
+// EmailSettings control the verification and password reset emails: the app
+// the links open, and how long each kind of link works.
+type EmailSettings struct {
+	AppURL           string
+	VerifyEmailTTL   time.Duration
+	ResetPasswordTTL time.Duration
+}
+
+// link points at a page of the app that reads the token from the query
+// string and posts it back to the API.
+func (e EmailSettings) link(path, token string) string {
+	return strings.TrimRight(e.AppURL, "/") + path + "?token=" + url.QueryEscape(token)
+}
+
+func verifyEmailMessage(user User, link string, ttl time.Duration) mail.Message {
+	return mail.Message{
+		To:      user.Email,
+		Subject: "Verify your email address",
+		Body: fmt.Sprintf("Hello %s,\n\nConfirm this is your email address by opening the link below:\n\n%s\n\nThe link works once and expires in %s. If you did not create an account, you can ignore this email.\n",
+			user.Username, link, humanDuration(ttl)),
+	}
+}
+
+func resetPasswordMessage(user User, link string, ttl time.Duration) mail.Message {
+	return mail.Message{
+		To:      user.Email,
+		Subject: "Reset your password",
+		Body: fmt.Sprintf("Hello %s,\n\nSomeone asked to reset the password for your account. Choose a new one by opening the link below:\n\n%s\n\nThe link works once and expires in %s. Resetting signs you out on every device. If you did not ask for this, you can ignore this email and your password stays the same.\n",
+			user.Username, link, humanDuration(ttl)),
+	}
+}
+
+// humanDuration renders a link lifetime in whole days, hours or minutes.
+func humanDuration(d time.Duration) string {
+	switch {
+	case d >= 24*time.Hour && d%(24*time.Hour) == 0:
+		return plural(int(d/(24*time.Hour)), "day")
+	case d >= time.Hour && d%time.Hour == 0:
+		return plural(int(d/time.Hour), "hour")
+	default:
+		return plural(max(int(d/time.Minute), 1), "minute")
+	}
+}
+
+func plural(n int, unit string) string {
+	if n == 1 {
+		return "1 " + unit
+	}
+	return fmt.Sprintf("%d %ss", n, unit)
+}
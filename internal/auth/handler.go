@@ -1,10 +1,13 @@
 package auth
 
 import (
+	"crypto/subtle"
 	"errors"
 	"log/slog"
 	"net"
 	"net/http"
+	"net/url"
+	"strings"
 	"sync"
 	"time"
 
@@ -13,11 +16,21 @@ import (
 	"github.com/zizouhuweidi/maktaba/internal/echox"
 )
 
-const refreshCookieName = "bh_refresh_token"
+const (
+	refreshCookieName  = "bh_refresh_token"
+	stateCookieName    = "bh_oidc_state"
+	maxUserAgentLength = 512
+)
+
+// externalCallbackPath is the app page that provider sign-ins end on. It
+// reads the outcome from the query string, or a challenge token from the
+// fragment.
+const externalCallbackPath = "/callback"
 
 type Handler struct {
 	service      *Service
 	cookieSecure bool
+	appURL       string
 	logger       *slog.Logger
 	limiter      *rateLimiter
 }
@@ -33,6 +46,49 @@ type loginRequest struct {
 	Password string `json:"password" validate:"required"`
 }
 
+type updateRoleRequest struct {
+	Role string `json:"role" validate:"required"`
+}
+
+type verifyEmailRequest struct {
+	Token string `json:"token" validate:"required"`
+}
+
+type forgotPasswordRequest struct {
+	Email string `json:"email" validate:"required,email"`
+}
+
+type resetPasswordRequest struct {
+	Token    string `json:"token" validate:"required"`
+	Password string `json:"password" validate:"required,min=12"`
+}
+
+type mfaLoginRequest struct {
+	ChallengeToken string `json:"challenge_token" validate:"required"`
+	Code           string `json:"code" validate:"required"`
+}
+
+type mfaCodeRequest struct {
+	Code string `json:"code" validate:"required"`
+}
+
+// confirmationRequest re-authenticates the caller with their password or,
+// when they have none, the token a provider re-authentication returned.
+type confirmationRequest struct {
+	Password    string `json:"password" validate:"required_without=ReauthToken"`
+	ReauthToken string `json:"reauth_token"`
+}
+
+func (r confirmationRequest) confirmation() Confirmation {
+	return Confirmation{Password: r.Password, ReauthToken: r.ReauthToken}
+}
+
+type createTokenRequest struct {
+	Name          string   `json:"name" validate:"required,max=100"`
+	Scopes        []string `json:"scopes" validate:"required,min=1"`
+	ExpiresInDays int      `json:"expires_in_days" validate:"omitempty,min=1,max=365"`
+}
+
 type refreshRequest struct {
 	RefreshToken string `json:"refresh_token"`
 }
@@ -42,19 +98,72 @@ type authResponse struct {
 	Tokens AuthTokens `json:"tokens"`
 }
 
-func NewHandler(service *Service, cookieSecure bool, logger *slog.Logger) *Handler {
-	return &Handler{service: service, cookieSecure: cookieSecure, logger: logger, limiter: newRateLimiter(10, time.Minute)}
+// mfaChallengeResponse answers a correct password when a second factor is
+// still needed.
+type mfaChallengeResponse struct {
+	MFARequired bool `json:"mfa_required"`
+	MFAChallenge
+}
+
+type recoveryCodesResponse struct {
+	RecoveryCodes []string `json:"recovery_codes"`
+}
+
+// createdTokenResponse is the only response that includes a personal access
+// token itself.
+type createdTokenResponse struct {
+	Token string `json:"token"`
+	*PersonalAccessToken
+}
+
+type providersResponse struct {
+	Providers []string `json:"providers"`
+}
+
+type authorizationResponse struct {
+	AuthorizationURL string `json:"authorization_url"`
+}
+
+// NewHandler sends browsers back to the app at appURL once they finish
+// signing in with an identity provider.
+func NewHandler(service *Service, cookieSecure bool, appURL string, logger *slog.Logger) *Handler {
+	return &Handler{service: service, cookieSecure: cookieSecure, appURL: strings.TrimRight(appURL, "/"), logger: logger, limiter: newRateLimiter(10, time.Minute)}
 }
 
 func (h *Handler) RegisterRoutes(e *echo.Echo) {
 	e.POST("/auth/register", h.Register)
 	e.POST("/auth/login", h.Login)
+	e.POST("/auth/login/mfa", h.LoginMFA)
 	e.POST("/auth/refresh", h.Refresh)
 	e.POST("/auth/logout", h.Logout)
+	e.POST("/auth/verify-email", h.VerifyEmail)
+	e.POST("/auth/forgot-password", h.ForgotPassword)
+	e.POST("/auth/reset-password", h.ResetPassword)
+	e.GET("/auth/oidc/providers", h.ExternalProviders)
+	e.GET("/auth/oidc/:provider/start", h.StartExternalLogin)
+	e.GET("/auth/oidc/:provider/callback", h.ExternalLoginCallback)
 }
 
 func (h *Handler) RegisterProtectedRoutes(g *echo.Group) {
 	g.GET("/me", h.Me)
+	g.DELETE("/me", h.DeleteMe)
+	g.POST("/me/verification-email", h.ResendVerification)
+	g.GET("/me/sessions", h.ListSessions)
+	g.DELETE("/me/sessions/:id", h.RevokeSession)
+	g.POST("/me/sessions/revoke-others", h.RevokeOtherSessions)
+	g.GET("/me/mfa", h.MFAStatus)
+	g.POST("/me/mfa/totp", h.StartTOTPEnrollment)
+	g.POST("/me/mfa/totp/confirm", h.ConfirmTOTPEnrollment)
+	g.DELETE("/me/mfa/totp", h.DisableTOTP)
+	g.POST("/me/mfa/recovery-codes", h.RegenerateRecoveryCodes)
+	g.GET("/me/identities", h.ListIdentities)
+	g.POST("/me/identities/:provider", h.LinkIdentity)
+	g.DELETE("/me/identities/:provider", h.UnlinkIdentity)
+	g.POST("/me/reauthenticate/:provider", h.StartReauthentication)
+	g.GET("/me/tokens", h.ListTokens)
+	g.POST("/me/tokens", h.CreateToken)
+	g.DELETE("/me/tokens/:id", h.RevokeToken)
+	g.PUT("/users/:id/role", h.UpdateRole, RequireRole(RoleAdmin))
 }
 
 func (h *Handler) Register(c *echo.Context) error {
@@ -67,7 +176,7 @@ func (h *Handler) Register(c *echo.Context) error {
 		return err
 	}
 
-	user, tokens, err := h.service.Register(c.Request().Context(), req.Email, req.Username, req.Password)
+	user, tokens, err := h.service.Register(c.Request().Context(), req.Email, req.Username, req.Password, client(c))
 	if errors.Is(err, ErrInvalidSignup) {
 		return echo.NewHTTPError(http.StatusBadRequest, "email, username, and password are required; password must be at least 12 characters")
 	}
@@ -90,7 +199,7 @@ func (h *Handler) Login(c *echo.Context) error {
 		return err
 	}
 
-	user, tokens, err := h.service.Login(c.Request().Context(), req.Login, req.Password)
+	user, tokens, challenge, err := h.service.Login(c.Request().Context(), req.Login, req.Password, client(c))
 	if errors.Is(err, ErrInvalidCredentials) {
 		return echo.NewHTTPError(http.StatusUnauthorized, "invalid credentials")
 	}
@@ -98,6 +207,40 @@ func (h *Handler) Login(c *echo.Context) error {
 		h.logger.Error("login failed", "error", err)
 		return echo.NewHTTPError(http.StatusInternalServerError, "failed to login")
 	}
+	if challenge != nil {
+		return c.JSON(http.StatusOK, mfaChallengeResponse{MFARequired: true, MFAChallenge: *challenge})
+	}
+
+	h.setRefreshCookie(c, tokens.RefreshToken)
+	return c.JSON(http.StatusOK, authResponse{User: user, Tokens: tokens})
+}
+
+// LoginMFA is the second step of a login for accounts with two-factor
+// authentication.
+func (h *Handler) LoginMFA(c *echo.Context) error {
+	if err := h.allowAuthAttempt(c, "login-mfa"); err != nil {
+		return err
+	}
+
+	var req mfaLoginRequest
+	if err := echox.BindAndValidate(c, &req); err != nil {
+		return err
+	}
+
+	user, tokens, err := h.service.CompleteMFALogin(c.Request().Context(), req.ChallengeToken, req.Code, client(c))
+	if errors.Is(err, ErrInvalidToken) {
+		return echo.NewHTTPError(http.StatusUnauthorized, "sign-in has expired; log in again")
+	}
+	if errors.Is(err, ErrInvalidMFACode) {
+		return echo.NewHTTPError(http.StatusUnauthorized, "invalid authentication code")
+	}
+	if errors.Is(err, ErrTooManyAttempts) {
+		return echo.NewHTTPError(http.StatusTooManyRequests, "too many attempts")
+	}
+	if err != nil {
+		h.logger.Error("two-factor login failed", "error", err)
+		return echo.NewHTTPError(http.StatusInternalServerError, "failed to login")
+	}
 
 	h.setRefreshCookie(c, tokens.RefreshToken)
 	return c.JSON(http.StatusOK, authResponse{User: user, Tokens: tokens})
@@ -123,7 +266,7 @@ func (h *Handler) Refresh(c *echo.Context) error {
 		token = cookie.Value
 	}
 
-	result, err := h.service.Refresh(c.Request().Context(), token)
+	result, err := h.service.Refresh(c.Request().Context(), token, client(c))
 	if errors.Is(err, ErrInvalidRefresh) {
 		h.clearRefreshCookie(c)
 		return echo.NewHTTPError(http.StatusUnauthorized, "invalid refresh token")
@@ -160,6 +303,485 @@ func (h *Handler) Logout(c *echo.Context) error {
 	return c.NoContent(http.StatusNoContent)
 }
 
+func (h *Handler) VerifyEmail(c *echo.Context) error {
+	if err := h.allowAuthAttempt(c, "verify-email"); err != nil {
+		return err
+	}
+
+	var req verifyEmailRequest
+	if err := echox.BindAndValidate(c, &req); err != nil {
+		return err
+	}
+
+	err := h.service.VerifyEmail(c.Request().Context(), req.Token)
+	if errors.Is(err, ErrInvalidToken) {
+		return echo.NewHTTPError(http.StatusBadRequest, "verification link is invalid or has expired")
+	}
+	if err != nil {
+		h.logger.Error("email verification failed", "error", err)
+		return echo.NewHTTPError(http.StatusInternalServerError, "failed to verify email")
+	}
+	return c.NoContent(http.StatusNoContent)
+}
+
+// ForgotPassword answers 202 whether or not the email has an account, so it
+// cannot be used to find out who is registered.
+func (h *Handler) ForgotPassword(c *echo.Context) error {
+	if err := h.allowAuthAttempt(c, "forgot-password"); err != nil {
+		return err
+	}
+
+	var req forgotPasswordRequest
+	if err := echox.BindAndValidate(c, &req); err != nil {
+		return err
+	}
+
+	h.service.ForgotPassword(c.Request().Context(), req.Email)
+	return c.NoContent(http.StatusAccepted)
+}
+
+func (h *Handler) ResetPassword(c *echo.Context) error {
+	if err := h.allowAuthAttempt(c, "reset-password"); err != nil {
+		return err
+	}
+
+	var req resetPasswordRequest
+	if err := echox.BindAndValidate(c, &req); err != nil {
+		return err
+	}
+
+	err := h.service.ResetPassword(c.Request().Context(), req.Token, req.Password)
+	if errors.Is(err, ErrInvalidPassword) {
+		return echo.NewHTTPError(http.StatusBadRequest, "password must be at least 12 characters")
+	}
+	if errors.Is(err, ErrInvalidToken) {
+		return echo.NewHTTPError(http.StatusBadRequest, "reset link is invalid or has expired")
+	}
+	if err != nil {
+		h.logger.Error("password reset failed", "error", err)
+		return echo.NewHTTPError(http.StatusInternalServerError, "failed to reset password")
+	}
+
+	h.clearRefreshCookie(c)
+	return c.NoContent(http.StatusNoContent)
+}
+
+func (h *Handler) ResendVerification(c *echo.Context) error {
+	userID, ok := UserID(c)
+	if !ok {
+		return echo.NewHTTPError(http.StatusUnauthorized, "authentication required")
+	}
+	if err := h.allowAuthAttempt(c, "resend-verification"); err != nil {
+		return err
+	}
+
+	err := h.service.SendVerificationEmail(c.Request().Context(), userID)
+	if errors.Is(err, ErrAlreadyVerified) {
+		return echo.NewHTTPError(http.StatusConflict, "email is already verified")
+	}
+	if errors.Is(err, ErrUserNotFound) {
+		return echo.NewHTTPError(http.StatusNotFound, "user not found")
+	}
+	if err != nil {
+		h.logger.Error("failed to send verification email", "error", err)
+		return echo.NewHTTPError(http.StatusInternalServerError, "failed to send verification email")
+	}
+	return c.NoContent(http.StatusAccepted)
+}
+
+// ListSessions lists the caller's signed-in devices, marking the current one.
+func (h *Handler) ListSessions(c *echo.Context) error {
+	userID, ok := UserID(c)
+	if !ok {
+		return echo.NewHTTPError(http.StatusUnauthorized, "authentication required")
+	}
+
+	sessions, err := h.service.ListSessions(c.Request().Context(), userID, currentSession(c))
+	if err != nil {
+		return echo.NewHTTPError(http.StatusInternalServerError, "failed to list sessions")
+	}
+	return c.JSON(http.StatusOK, sessions)
+}
+
+func (h *Handler) RevokeSession(c *echo.Context) error {
+	userID, ok := UserID(c)
+	if !ok {
+		return echo.NewHTTPError(http.StatusUnauthorized, "authentication required")
+	}
+	sessionID, err := echox.ParamUUID(c, "id", "session ID")
+	if err != nil {
+		return err
+	}
+
+	err = h.service.RevokeSession(c.Request().Context(), userID, sessionID)
+	if errors.Is(err, ErrSessionNotFound) {
+		return echo.NewHTTPError(http.StatusNotFound, "session not found")
+	}
+	if err != nil {
+		return echo.NewHTTPError(http.StatusInternalServerError, "failed to revoke session")
+	}
+
+	if current := currentSession(c); current != nil && *current == sessionID {
+		h.clearRefreshCookie(c)
+	}
+	return c.NoContent(http.StatusNoContent)
+}
+
+// RevokeOtherSessions signs the caller out on every device but this one.
+func (h *Handler) RevokeOtherSessions(c *echo.Context) error {
+	userID, ok := UserID(c)
+	if !ok {
+		return echo.NewHTTPError(http.StatusUnauthorized, "authentication required")
+	}
+
+	revoked, err := h.service.RevokeOtherSessions(c.Request().Context(), userID, currentSession(c))
+	if err != nil {
+		return echo.NewHTTPError(http.StatusInternalServerError, "failed to revoke sessions")
+	}
+	return c.JSON(http.StatusOK, map[string]int64{"revoked": revoked})
+}
+
+func (h *Handler) MFAStatus(c *echo.Context) error {
+	userID, ok := UserID(c)
+	if !ok {
+		return echo.NewHTTPError(http.StatusUnauthorized, "authentication required")
+	}
+
+	status, err := h.service.MFAStatus(c.Request().Context(), userID)
+	if err != nil {
+		return echo.NewHTTPError(http.StatusInternalServerError, "failed to get two-factor status")
+	}
+	return c.JSON(http.StatusOK, status)
+}
+
+// StartTOTPEnrollment returns a new secret for the caller's authenticator app.
+// Two-factor authentication stays off until the enrollment is confirmed.
+func (h *Handler) StartTOTPEnrollment(c *echo.Context) error {
+	userID, ok := UserID(c)
+	if !ok {
+		return echo.NewHTTPError(http.StatusUnauthorized, "authentication required")
+	}
+
+	enrollment, err := h.service.StartTOTPEnrollment(c.Request().Context(), userID)
+	if errors.Is(err, ErrMFAAlreadyEnabled) {
+		return echo.NewHTTPError(http.StatusConflict, "two-factor authentication is already enabled")
+	}
+	if errors.Is(err, ErrUserNotFound) {
+		return echo.NewHTTPError(http.StatusNotFound, "user not found")
+	}
+	if err != nil {
+		h.logger.Error("failed to start two-factor enrollment", "error", err)
+		return echo.NewHTTPError(http.StatusInternalServerError, "failed to start two-factor enrollment")
+	}
+	return c.JSON(http.StatusOK, enrollment)
+}
+
+func (h *Handler) ConfirmTOTPEnrollment(c *echo.Context) error {
+	userID, ok := UserID(c)
+	if !ok {
+		return echo.NewHTTPError(http.StatusUnauthorized, "authentication required")
+	}
+
+	var req mfaCodeRequest
+	if err := echox.BindAndValidate(c, &req); err != nil {
+		return err
+	}
+
+	codes, err := h.service.ConfirmTOTPEnrollment(c.Request().Context(), userID, req.Code)
+	if errors.Is(err, ErrInvalidMFACode) {
+		return echo.NewHTTPError(http.StatusBadRequest, "invalid authentication code")
+	}
+	if errors.Is(err, ErrTooManyAttempts) {
+		return echo.NewHTTPError(http.StatusTooManyRequests, "too many attempts")
+	}
+	if errors.Is(err, ErrMFANotEnabled) {
+		return echo.NewHTTPError(http.StatusConflict, "start two-factor enrollment first")
+	}
+	if errors.Is(err, ErrMFAAlreadyEnabled) {
+		return echo.NewHTTPError(http.StatusConflict, "two-factor authentication is already enabled")
+	}
+	if err != nil {
+		h.logger.Error("failed to confirm two-factor enrollment", "error", err)
+		return echo.NewHTTPError(http.StatusInternalServerError, "failed to enable two-factor authentication")
+	}
+	return c.JSON(http.StatusOK, recoveryCodesResponse{RecoveryCodes: codes})
+}
+
+func (h *Handler) DisableTOTP(c *echo.Context) error {
+	userID, ok := UserID(c)
+	if !ok {
+		return echo.NewHTTPError(http.StatusUnauthorized, "authentication required")
+	}
+	if err := h.allowAuthAttempt(c, "disable-mfa"); err != nil {
+		return err
+	}
+
+	var req confirmationRequest
+	if err := echox.BindAndValidate(c, &req); err != nil {
+		return err
+	}
+
+	err := h.service.DisableTOTP(c.Request().Context(), userID, req.confirmation())
+	if errors.Is(err, ErrInvalidCredentials) {
+		return echo.NewHTTPError(http.StatusForbidden, "confirmation failed")
+	}
+	if errors.Is(err, ErrMFANotEnabled) {
+		return echo.NewHTTPError(http.StatusConflict, "two-factor authentication is not enabled")
+	}
+	if errors.Is(err, ErrUserNotFound) {
+		return echo.NewHTTPError(http.StatusNotFound, "user not found")
+	}
+	if err != nil {
+		return echo.NewHTTPError(http.StatusInternalServerError, "failed to disable two-factor authentication")
+	}
+	return c.NoContent(http.StatusNoContent)
+}
+
+func (h *Handler) RegenerateRecoveryCodes(c *echo.Context) error {
+	userID, ok := UserID(c)
+	if !ok {
+		return echo.NewHTTPError(http.StatusUnauthorized, "authentication required")
+	}
+	if err := h.allowAuthAttempt(c, "recovery-codes"); err != nil {
+		return err
+	}
+
+	var req confirmationRequest
+	if err := echox.BindAndValidate(c, &req); err != nil {
+		return err
+	}
+
+	codes, err := h.service.RegenerateRecoveryCodes(c.Request().Context(), userID, req.confirmation())
+	if errors.Is(err, ErrInvalidCredentials) {
+		return echo.NewHTTPError(http.StatusForbidden, "confirmation failed")
+	}
+	if errors.Is(err, ErrMFANotEnabled) {
+		return echo.NewHTTPError(http.StatusConflict, "two-factor authentication is not enabled")
+	}
+	if errors.Is(err, ErrUserNotFound) {
+		return echo.NewHTTPError(http.StatusNotFound, "user not found")
+	}
+	if err != nil {
+		return echo.NewHTTPError(http.StatusInternalServerError, "failed to regenerate recovery codes")
+	}
+	return c.JSON(http.StatusOK, recoveryCodesResponse{RecoveryCodes: codes})
+}
+
+// ExternalProviders lists the identity providers users can sign in with.
+func (h *Handler) ExternalProviders(c *echo.Context) error {
+	return c.JSON(http.StatusOK, providersResponse{Providers: h.service.ExternalProviders()})
+}
+
+// StartExternalLogin sends the browser to the provider to sign in. The state
+// cookie ties the callback to this browser.
+func (h *Handler) StartExternalLogin(c *echo.Context) error {
+	if err := h.allowAuthAttempt(c, "oidc-start"); err != nil {
+		return err
+	}
+
+	authURL, state, err := h.service.StartExternalLogin(c.Request().Context(), c.Param("provider"), nil)
+	if errors.Is(err, ErrUnknownProvider) {
+		return echo.NewHTTPError(http.StatusNotFound, "identity provider not found")
+	}
+	if err != nil {
+		h.logger.Error("failed to start external login", "error", err, "provider", c.Param("provider"))
+		return h.redirectToApp(c, url.Values{"error": {"failed"}}, "")
+	}
+
+	h.setStateCookie(c, state)
+	return c.Redirect(http.StatusFound, authURL)
+}
+
+// ExternalLoginCallback is where the provider sends the browser back. It
+// always redirects to the app's callback page, with a refresh cookie when
+// the user is signed in.
+func (h *Handler) ExternalLoginCallback(c *echo.Context) error {
+	if err := h.allowAuthAttempt(c, "oidc-callback"); err != nil {
+		return err
+	}
+
+	state := c.QueryParam("state")
+	cookie, cookieErr := c.Cookie(stateCookieName)
+	h.clearStateCookie(c)
+	if c.QueryParam("error") != "" {
+		return h.redirectToApp(c, url.Values{"error": {"cancelled"}}, "")
+	}
+	if cookieErr != nil || state == "" || subtle.ConstantTimeCompare([]byte(cookie.Value), []byte(state)) != 1 {
+		return h.redirectToApp(c, url.Values{"error": {"expired"}}, "")
+	}
+
+	provider := c.Param("provider")
+	result, err := h.service.CompleteExternalLogin(c.Request().Context(), provider, state, c.QueryParam("code"), client(c))
+	if err != nil {
+		return h.redirectToApp(c, url.Values{"error": {h.externalLoginError(err, provider)}}, "")
+	}
+	if result.Linked {
+		return h.redirectToApp(c, url.Values{"linked": {provider}}, "")
+	}
+	if result.ReauthToken != "" {
+		return h.redirectToApp(c, nil, url.Values{"reauth_token": {result.ReauthToken}}.Encode())
+	}
+	if result.Challenge != nil {
+		return h.redirectToApp(c, nil, url.Values{"challenge_token": {result.Challenge.Token}}.Encode())
+	}
+
+	h.setRefreshCookie(c, result.Tokens.RefreshToken)
+	return h.redirectToApp(c, nil, "")
+}
+
+func (h *Handler) ListIdentities(c *echo.Context) error {
+	userID, ok := UserID(c)
+	if !ok {
+		return echo.NewHTTPError(http.StatusUnauthorized, "authentication required")
+	}
+
+	identities, err := h.service.ListIdentities(c.Request().Context(), userID)
+	if err != nil {
+		return echo.NewHTTPError(http.StatusInternalServerError, "failed to list linked accounts")
+	}
+	return c.JSON(http.StatusOK, identities)
+}
+
+// LinkIdentity starts linking a provider account to the caller. The app
+// sends the browser to the returned URL, and the callback finishes the link.
+func (h *Handler) LinkIdentity(c *echo.Context) error {
+	userID, ok := UserID(c)
+	if !ok {
+		return echo.NewHTTPError(http.StatusUnauthorized, "authentication required")
+	}
+	if err := h.allowAuthAttempt(c, "oidc-link"); err != nil {
+		return err
+	}
+
+	authURL, state, err := h.service.StartExternalLogin(c.Request().Context(), c.Param("provider"), &userID)
+	if errors.Is(err, ErrUnknownProvider) {
+		return echo.NewHTTPError(http.StatusNotFound, "identity provider not found")
+	}
+	if errors.Is(err, ErrProviderAlreadyLinked) {
+		return echo.NewHTTPError(http.StatusConflict, "an account at this provider is already linked")
+	}
+	if err != nil {
+		h.logger.Error("failed to start linking", "error", err, "provider", c.Param("provider"))
+		return echo.NewHTTPError(http.StatusBadGateway, "failed to reach identity provider")
+	}
+
+	h.setStateCookie(c, state)
+	return c.JSON(http.StatusOK, authorizationResponse{AuthorizationURL: authURL})
+}
+
+// StartReauthentication sends the caller to a provider they linked to
+// confirm it is still them. The callback returns a token that stands in for
+// their password when deleting the account or changing two-factor settings.
+func (h *Handler) StartReauthentication(c *echo.Context) error {
+	userID, ok := UserID(c)
+	if !ok {
+		return echo.NewHTTPError(http.StatusUnauthorized, "authentication required")
+	}
+	if err := h.allowAuthAttempt(c, "oidc-reauth"); err != nil {
+		return err
+	}
+
+	authURL, state, err := h.service.StartReauthentication(c.Request().Context(), c.Param("provider"), userID)
+	if errors.Is(err, ErrUnknownProvider) {
+		return echo.NewHTTPError(http.StatusNotFound, "identity provider not found")
+	}
+	if errors.Is(err, ErrIdentityNotFound) {
+		return echo.NewHTTPError(http.StatusNotFound, "linked account not found")
+	}
+	if err != nil {
+		h.logger.Error("failed to start re-authentication", "error", err, "provider", c.Param("provider"))
+		return echo.NewHTTPError(http.StatusBadGateway, "failed to reach identity provider")
+	}
+
+	h.setStateCookie(c, state)
+	return c.JSON(http.StatusOK, authorizationResponse{AuthorizationURL: authURL})
+}
+
+func (h *Handler) UnlinkIdentity(c *echo.Context) error {
+	userID, ok := UserID(c)
+	if !ok {
+		return echo.NewHTTPError(http.StatusUnauthorized, "authentication required")
+	}
+
+	err := h.service.UnlinkIdentity(c.Request().Context(), userID, c.Param("provider"))
+	if errors.Is(err, ErrIdentityNotFound) {
+		return echo.NewHTTPError(http.StatusNotFound, "linked account not found")
+	}
+	if errors.Is(err, ErrLastSignInMethod) {
+		return echo.NewHTTPError(http.StatusConflict, "set a password before unlinking your only sign-in method")
+	}
+	if errors.Is(err, ErrUserNotFound) {
+		return echo.NewHTTPError(http.StatusNotFound, "user not found")
+	}
+	if err != nil {
+		return echo.NewHTTPError(http.StatusInternalServerError, "failed to unlink account")
+	}
+	return c.NoContent(http.StatusNoContent)
+}
+
+func (h *Handler) ListTokens(c *echo.Context) error {
+	userID, ok := UserID(c)
+	if !ok {
+		return echo.NewHTTPError(http.StatusUnauthorized, "authentication required")
+	}
+
+	tokens, err := h.service.ListPersonalAccessTokens(c.Request().Context(), userID)
+	if err != nil {
+		return echo.NewHTTPError(http.StatusInternalServerError, "failed to list tokens")
+	}
+	return c.JSON(http.StatusOK, tokens)
+}
+
+// CreateToken creates a personal access token. The response carries the
+// token itself, which cannot be retrieved later.
+func (h *Handler) CreateToken(c *echo.Context) error {
+	userID, ok := UserID(c)
+	if !ok {
+		return echo.NewHTTPError(http.StatusUnauthorized, "authentication required")
+	}
+
+	var req createTokenRequest
+	if err := echox.BindAndValidate(c, &req); err != nil {
+		return err
+	}
+
+	lifetime := time.Duration(req.ExpiresInDays) * 24 * time.Hour
+	token, rawToken, err := h.service.CreatePersonalAccessToken(c.Request().Context(), userID, req.Name, req.Scopes, lifetime)
+	if errors.Is(err, ErrInvalidTokenName) {
+		return echo.NewHTTPError(http.StatusBadRequest, "token name must be 1 to 100 characters")
+	}
+	if errors.Is(err, ErrInvalidScope) {
+		return echo.NewHTTPError(http.StatusBadRequest, "scopes must be <resource>:read or <resource>:write for "+strings.Join(scopeResources, ", "))
+	}
+	if errors.Is(err, ErrInvalidTokenLifetime) {
+		return echo.NewHTTPError(http.StatusBadRequest, "expires_in_days must be between 1 and 365")
+	}
+	if err != nil {
+		return echo.NewHTTPError(http.StatusInternalServerError, "failed to create token")
+	}
+	return c.JSON(http.StatusCreated, createdTokenResponse{Token: rawToken, PersonalAccessToken: token})
+}
+
+func (h *Handler) RevokeToken(c *echo.Context) error {
+	userID, ok := UserID(c)
+	if !ok {
+		return echo.NewHTTPError(http.StatusUnauthorized, "authentication required")
+	}
+	tokenID, err := echox.ParamUUID(c, "id", "token ID")
+	if err != nil {
+		return err
+	}
+
+	err = h.service.RevokePersonalAccessToken(c.Request().Context(), userID, tokenID)
+	if errors.Is(err, ErrTokenNotFound) {
+		return echo.NewHTTPError(http.StatusNotFound, "token not found")
+	}
+	if err != nil {
+		return echo.NewHTTPError(http.StatusInternalServerError, "failed to revoke token")
+	}
+	return c.NoContent(http.StatusNoContent)
+}
+
 func (h *Handler) Me(c *echo.Context) error {
 	userID, ok := UserID(c)
 	if !ok {
@@ -173,6 +795,62 @@ func (h *Handler) Me(c *echo.Context) error {
 	return c.JSON(http.StatusOK, user)
 }
 
+func (h *Handler) DeleteMe(c *echo.Context) error {
+	userID, ok := UserID(c)
+	if !ok {
+		return echo.NewHTTPError(http.StatusUnauthorized, "authentication required")
+	}
+	if err := h.allowAuthAttempt(c, "delete-account"); err != nil {
+		return err
+	}
+
+	var req confirmationRequest
+	if err := echox.BindAndValidate(c, &req); err != nil {
+		return err
+	}
+
+	err := h.service.DeleteAccount(c.Request().Context(), userID, req.confirmation())
+	if errors.Is(err, ErrInvalidCredentials) {
+		return echo.NewHTTPError(http.StatusForbidden, "confirmation failed")
+	}
+	if errors.Is(err, ErrUserNotFound) {
+		return echo.NewHTTPError(http.StatusNotFound, "user not found")
+	}
+	if err != nil {
+		return echo.NewHTTPError(http.StatusInternalServerError, "failed to delete account")
+	}
+
+	h.clearRefreshCookie(c)
+	return c.NoContent(http.StatusNoContent)
+}
+
+func (h *Handler) UpdateRole(c *echo.Context) error {
+	userID, err := echox.ParamUUID(c, "id", "user ID")
+	if err != nil {
+		return err
+	}
+
+	var req updateRoleRequest
+	if err := echox.BindAndValidate(c, &req); err != nil {
+		return err
+	}
+
+	err = h.service.SetRole(c.Request().Context(), userID, Role(req.Role))
+	if errors.Is(err, ErrInvalidRole) {
+		return echo.NewHTTPError(http.StatusBadRequest, "role must be user, editor, or admin")
+	}
+	if errors.Is(err, ErrUserNotFound) {
+		return echo.NewHTTPError(http.StatusNotFound, "user not found")
+	}
+	if err != nil {
+		return echo.NewHTTPError(http.StatusInternalServerError, "failed to update role")
+	}
+	return c.NoContent(http.StatusNoContent)
+}
+
+// Middleware authenticates a request by its bearer token, which is either an
+// access token or a personal access token. Requests with a personal access
+// token are limited to its scopes by RequireScope and RequireSession.
 func (h *Handler) Middleware(next echo.HandlerFunc) echo.HandlerFunc {
 	return func(c *echo.Context) error {
 		const prefix = "Bearer "
@@ -182,6 +860,21 @@ func (h *Handler) Middleware(next echo.HandlerFunc) echo.HandlerFunc {
 		}
 		token := header[len(prefix):]
 
+		if strings.HasPrefix(token, personalAccessTokenPrefix) {
+			grant, err := h.service.AuthenticatePersonalAccessToken(c.Request().Context(), token, client(c))
+			if errors.Is(err, ErrInvalidToken) {
+				return echo.NewHTTPError(http.StatusUnauthorized, "invalid access token")
+			}
+			if err != nil {
+				h.logger.Error("failed to verify personal access token", "error", err)
+				return echo.NewHTTPError(http.StatusInternalServerError, "failed to verify access token")
+			}
+			SetUserID(c, grant.UserID)
+			SetRole(c, grant.Role)
+			SetScopes(c, grant.Scopes)
+			return next(c)
+		}
+
 		claims, err := h.service.VerifyAccessToken(token)
 		if err != nil {
 			return echo.NewHTTPError(http.StatusUnauthorized, "invalid access token")
@@ -192,6 +885,62 @@ func (h *Handler) Middleware(next echo.HandlerFunc) echo.HandlerFunc {
 		}
 
 		SetUserID(c, userID)
+		SetRole(c, Role(claims.Role))
+		if sessionID, err := uuid.FromString(claims.SessionID); err == nil {
+			SetSessionID(c, sessionID)
+		}
+		return next(c)
+	}
+}
+
+// RequireRole rejects requests from users whose role is not one of roles. It
+// must run after Middleware.
+func RequireRole(roles ...Role) echo.MiddlewareFunc {
+	return func(next echo.HandlerFunc) echo.HandlerFunc {
+		return func(c *echo.Context) error {
+			role := UserRole(c)
+			for _, allowed := range roles {
+				if role == allowed {
+					return next(c)
+				}
+			}
+			return echo.NewHTTPError(http.StatusForbidden, "insufficient role")
+		}
+	}
+}
+
+// RequireScope limits requests made with a personal access token to tokens
+// with a scope for resource: "<resource>:read" for GET and HEAD requests and
+// "<resource>:write" for the rest. Requests signed in with an access token
+// pass. It must run after Middleware.
+func RequireScope(resource string) echo.MiddlewareFunc {
+	return func(next echo.HandlerFunc) echo.HandlerFunc {
+		return func(c *echo.Context) error {
+			scopes, ok := Scopes(c)
+			if !ok {
+				return next(c)
+			}
+			write := writeMethod(c.Request().Method)
+			if grantsScope(scopes, resource, write) {
+				return next(c)
+			}
+			access := "read"
+			if write {
+				access = "write"
+			}
+			return echo.NewHTTPError(http.StatusForbidden, "token needs the "+resource+":"+access+" scope")
+		}
+	}
+}
+
+// RequireSession rejects requests made with a personal access token, keeping
+// credentials and the account itself out of reach of scripts. It must run
+// after Middleware.
+func RequireSession(next echo.HandlerFunc) echo.HandlerFunc {
+	return func(c *echo.Context) error {
+		if _, ok := Scopes(c); ok {
+			return echo.NewHTTPError(http.StatusForbidden, "personal access tokens cannot be used here")
+		}
 		return next(c)
 	}
 }
@@ -212,14 +961,84 @@ func (h *Handler) clearRefreshCookie(c *echo.Context) {
 	c.SetCookie(&http.Cookie{Name: refreshCookieName, Path: "/auth/refresh", MaxAge: -1, HttpOnly: true, Secure: h.cookieSecure, SameSite: http.SameSiteStrictMode})
 }
 
+// setStateCookie keeps the state of a provider sign-in until the callback.
+// It has to be Lax, as the callback is a navigation from the provider's site.
+func (h *Handler) setStateCookie(c *echo.Context, state string) {
+	c.SetCookie(&http.Cookie{
+		Name:     stateCookieName,
+		Value:    state,
+		Path:     "/auth/oidc",
+		HttpOnly: true,
+		Secure:   h.cookieSecure,
+		SameSite: http.SameSiteLaxMode,
+		MaxAge:   int(externalLoginTTL.Seconds()),
+	})
+}
+
+func (h *Handler) clearStateCookie(c *echo.Context) {
+	c.SetCookie(&http.Cookie{Name: stateCookieName, Path: "/auth/oidc", MaxAge: -1, HttpOnly: true, Secure: h.cookieSecure, SameSite: http.SameSiteLaxMode})
+}
+
+// redirectToApp ends a provider sign-in on the app's callback page. The
+// challenge token goes in the fragment so it stays out of server logs.
+func (h *Handler) redirectToApp(c *echo.Context, query url.Values, fragment string) error {
+	target := h.appURL + externalCallbackPath
+	if len(query) > 0 {
+		target += "?" + query.Encode()
+	}
+	if fragment != "" {
+		target += "#" + fragment
+	}
+	return c.Redirect(http.StatusFound, target)
+}
+
+// externalLoginError names the failure for the app's callback page.
+func (h *Handler) externalLoginError(err error, provider string) string {
+	switch {
+	case errors.Is(err, ErrInvalidToken):
+		return "expired"
+	case errors.Is(err, ErrIdentityConflict):
+		return "account_exists"
+	case errors.Is(err, ErrIdentityInUse):
+		return "identity_in_use"
+	case errors.Is(err, ErrProviderAlreadyLinked):
+		return "already_linked"
+	case errors.Is(err, ErrWrongIdentity):
+		return "wrong_account"
+	case errors.Is(err, ErrExternalLoginFailed):
+		h.logger.Warn("external login failed", "error", err, "provider", provider)
+		return "failed"
+	default:
+		h.logger.Error("external login failed", "error", err, "provider", provider)
+		return "failed"
+	}
+}
+
 func (h *Handler) allowAuthAttempt(c *echo.Context, action string) error {
 	key := clientIP(c) + ":" + action
-	if h.limiter.allow(key) {
+	if h.limiter.allow(key, time.Now()) {
 		return nil
 	}
 	return echo.NewHTTPError(http.StatusTooManyRequests, "too many attempts")
 }
 
+// client describes the device making the request for its session record
+func client(c *echo.Context) Client {
+	userAgent := c.Request().UserAgent()
+	if len(userAgent) > maxUserAgentLength {
+		userAgent = userAgent[:maxUserAgentLength]
+	}
+	return Client{UserAgent: strings.ToValidUTF8(userAgent, ""), IPAddress: clientIP(c)}
+}
+
+func currentSession(c *echo.Context) *uuid.UUID {
+	sessionID, ok := SessionID(c)
+	if !ok {
+		return nil
+	}
+	return &sessionID
+}
+
 func clientIP(c *echo.Context) string {
 	r := c.Request()
 	if forwardedFor := r.Header.Get("X-Forwarded-For"); forwardedFor != "" {
@@ -251,11 +1070,12 @@ func newRateLimiter(limit int, window time.Duration) *rateLimiter {
 	return &rateLimiter{limit: limit, window: window, attempts: make(map[string]rateLimitEntry)}
 }
 
-func (l *rateLimiter) allow(key string) bool {
+// allow counts an attempt for key at now. Callers pass the time so tests can
+// use a fake clock.
+func (l *rateLimiter) allow(key string, now time.Time) bool {
 	l.mu.Lock()
 	defer l.mu.Unlock()
 
-	now := time.Now()
 	entry := l.attempts[key]
 	if now.After(entry.resetTime) {
 		l.attempts[key] = rateLimitEntry{count: 1, resetTime: now.Add(l.window)}
@@ -0,0 +1,114 @@
+package auth
+
+import (
+	"context"
+	"io"
+	"log/slog"
+	"net/http"
+	"net/http/httptest"
+	"slices"
+	"testing"
+
+	"github.com/labstack/echo/v5"
+)
+
+func TestMiddlewareAcceptsPersonalAccessToken(t *testing.T) {
+	repo := &fakeAuthRepository{}
+	service := newTestService(t, repo, &recordingMailer{})
+	handler := NewHandler(service, false, "https://maktaba.example", slog.New(slog.NewTextHandler(io.Discard, nil)))
+	userID := mustTestUUID(t)
+	_, rawToken, err := service.CreatePersonalAccessToken(context.Background(), userID, "script", []string{"notes:read"}, 0)
+	if err != nil {
+		t.Fatalf("CreatePersonalAccessToken() error = %v", err)
+	}
+
+	c := testContext(http.MethodGet, "/api/notes", rawToken)
+	err = handler.Middleware(func(c *echo.Context) error {
+		if id, _ := UserID(c); id != userID {
+			t.Fatalf("user = %v, want %v", id, userID)
+		}
+		if scopes, ok := Scopes(c); !ok || !slices.Equal(scopes, []string{"notes:read"}) {
+			t.Fatalf("scopes = %v, want the token's scopes", scopes)
+		}
+		return c.NoContent(http.StatusNoContent)
+	})(c)
+	if err != nil {
+		t.Fatalf("Middleware() error = %v", err)
+	}
+
+	c = testContext(http.MethodGet, "/api/notes", personalAccessTokenPrefix+"unknown")
+	err = handler.Middleware(okHandler)(c)
+	if code := statusCode(t, err); code != http.StatusUnauthorized {
+		t.Fatalf("unknown token: status = %d, want %d", code, http.StatusUnauthorized)
+	}
+}
+
+func TestRequireScope(t *testing.T) {
+	tests := []struct {
+		name   string
+		method string
+		scopes []string
+		want   int
+	}{
+		{name: "session", method: http.MethodDelete, want: http.StatusOK},
+		{name: "read", method: http.MethodGet, scopes: []string{"notes:read"}, want: http.StatusOK},
+		{name: "write includes read", method: http.MethodGet, scopes: []string{"notes:write"}, want: http.StatusOK},
+		{name: "write", method: http.MethodPost, scopes: []string{"notes:write"}, want: http.StatusOK},
+		{name: "read cannot write", method: http.MethodPost, scopes: []string{"notes:read"}, want: http.StatusForbidden},
+		{name: "other resource", method: http.MethodGet, scopes: []string{"library:write"}, want: http.StatusForbidden},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			c := testContext(tt.method, "/api/notes", "")
+			if tt.scopes != nil {
+				SetScopes(c, tt.scopes)
+			}
+
+			err := RequireScope(ResourceNotes)(okHandler)(c)
+
+			code := http.StatusOK
+			if err != nil {
+				code = statusCode(t, err)
+			}
+			if code != tt.want {
+				t.Fatalf("status = %d, want %d", code, tt.want)
+			}
+		})
+	}
+}
+
+func TestRequireSessionRejectsPersonalAccessTokens(t *testing.T) {
+	c := testContext(http.MethodGet, "/api/me/tokens", "")
+	if err := RequireSession(okHandler)(c); err != nil {
+		t.Fatalf("session request: error = %v", err)
+	}
+
+	SetScopes(c, []string{"account:read"})
+	if code := statusCode(t, RequireSession(okHandler)(c)); code != http.StatusForbidden {
+		t.Fatalf("status = %d, want %d", code, http.StatusForbidden)
+	}
+}
+
+func okHandler(c *echo.Context) error {
+	return c.NoContent(http.StatusOK)
+}
+
+func testContext(method, target, bearer string) *echo.Context {
+	req := httptest.NewRequest(method, target, nil)
+	if bearer != "" {
+		req.Header.Set(echo.HeaderAuthorization, "Bearer "+bearer)
+	}
+	return echo.New().NewContext(req, httptest.NewRecorder())
+}
+
+func statusCode(t *testing.T, err error) int {
+	t.Helper()
+	if err == nil {
+		t.Fatal("expected error")
+	}
+	httpErr, ok := err.(*echo.HTTPError)
+	if !ok {
+		t.Fatalf("expected echo HTTPError, got %T", err)
+	}
+	return httpErr.Code
+}
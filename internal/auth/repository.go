@@ -7,18 +7,51 @@ import (
 
 	"github.com/gofrs/uuid/v5"
 	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgconn"
 	"github.com/zizouhuweidi/maktaba/internal/db"
 	"github.com/zizouhuweidi/maktaba/internal/db/dbgen"
+	"github.com/zizouhuweidi/maktaba/internal/oidc"
+	"github.com/zizouhuweidi/maktaba/internal/outbox"
 )
 
 type Repository interface {
 	CreateUser(ctx context.Context, user User) (*User, error)
 	GetUserByEmailOrUsername(ctx context.Context, login string) (*User, error)
 	GetUserByID(ctx context.Context, id uuid.UUID) (*User, error)
-	CreateRefreshToken(ctx context.Context, token RefreshToken) error
+	CreateSession(ctx context.Context, client Client, token RefreshToken) error
 	GetRefreshToken(ctx context.Context, tokenHash []byte) (*RefreshToken, error)
 	RotateRefreshToken(ctx context.Context, rotation RefreshTokenRotation) error
 	RevokeRefreshTokenFamily(ctx context.Context, familyID uuid.UUID) error
+	DeleteUser(ctx context.Context, id uuid.UUID) error
+	UpdateUserRole(ctx context.Context, id uuid.UUID, role Role) error
+	CreateUserToken(ctx context.Context, token UserToken) error
+	VerifyEmail(ctx context.Context, tokenHash []byte) (*uuid.UUID, error)
+	ResetPassword(ctx context.Context, tokenHash []byte, passwordHash string) (*uuid.UUID, error)
+	ListSessions(ctx context.Context, userID uuid.UUID) ([]*Session, error)
+	RevokeSession(ctx context.Context, userID, sessionID uuid.UUID) error
+	RevokeOtherSessions(ctx context.Context, userID uuid.UUID, keep *uuid.UUID) (int64, error)
+	FindUserToken(ctx context.Context, tokenHash []byte, purpose TokenPurpose) (*uuid.UUID, error)
+	ConsumeUserToken(ctx context.Context, tokenHash []byte, purpose TokenPurpose) (*uuid.UUID, error)
+	GetTOTP(ctx context.Context, userID uuid.UUID) (*TOTPCredential, error)
+	SavePendingTOTP(ctx context.Context, userID uuid.UUID, secret []byte) error
+	ConfirmTOTP(ctx context.Context, userID uuid.UUID, step int64, recoveryCodeHashes [][]byte) error
+	UseTOTPStep(ctx context.Context, userID uuid.UUID, step int64) (bool, error)
+	DisableTOTP(ctx context.Context, userID uuid.UUID) error
+	ReplaceRecoveryCodes(ctx context.Context, userID uuid.UUID, codeHashes [][]byte) error
+	UseRecoveryCode(ctx context.Context, userID uuid.UUID, codeHash []byte) (bool, error)
+	CountRecoveryCodes(ctx context.Context, userID uuid.UUID) (int64, error)
+	CreateExternalLogin(ctx context.Context, login ExternalLogin) error
+	ConsumeExternalLogin(ctx context.Context, stateHash []byte) (*ExternalLogin, error)
+	UseIdentity(ctx context.Context, provider string, identity oidc.Identity) (*uuid.UUID, error)
+	LinkIdentity(ctx context.Context, userID uuid.UUID, provider string, identity oidc.Identity) error
+	CreateUserWithIdentity(ctx context.Context, user User, provider string, identity oidc.Identity) (*User, error)
+	ListIdentities(ctx context.Context, userID uuid.UUID) ([]*ExternalIdentity, error)
+	DeleteIdentity(ctx context.Context, userID uuid.UUID, provider string) error
+	CreatePersonalAccessToken(ctx context.Context, token PersonalAccessToken) (*PersonalAccessToken, error)
+	FindPersonalAccessToken(ctx context.Context, tokenHash []byte) (*TokenGrant, error)
+	TouchPersonalAccessToken(ctx context.Context, tokenID uuid.UUID, ipAddress string) error
+	ListPersonalAccessTokens(ctx context.Context, userID uuid.UUID) ([]*PersonalAccessToken, error)
+	RevokePersonalAccessToken(ctx context.Context, userID, tokenID uuid.UUID) error
 }
 
 type postgresRepository struct {
@@ -65,14 +98,34 @@ func (r *postgresRepository) GetUserByID(ctx context.Context, id uuid.UUID) (*Us
 	return mapGetUserByIDRow(row), nil
 }
 
-func (r *postgresRepository) CreateRefreshToken(ctx context.Context, token RefreshToken) error {
-	return r.queries.CreateRefreshToken(ctx, dbgen.CreateRefreshTokenParams{
+// CreateSession records a new session and the first refresh token of its
+// family.
+func (r *postgresRepository) CreateSession(ctx context.Context, client Client, token RefreshToken) error {
+	tx, err := r.db.Begin(ctx)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback(ctx)
+	qtx := r.queries.WithTx(tx)
+
+	if err := qtx.CreateSession(ctx, dbgen.CreateSessionParams{
+		FamilyID:  db.PGUUID(token.FamilyID),
+		UserID:    db.PGUUID(token.UserID),
+		UserAgent: db.PGTextString(client.UserAgent),
+		IpAddress: db.PGTextString(client.IPAddress),
+	}); err != nil {
+		return err
+	}
+	if err := qtx.CreateRefreshToken(ctx, dbgen.CreateRefreshTokenParams{
 		ID:        db.PGUUID(token.ID),
 		UserID:    db.PGUUID(token.UserID),
 		TokenHash: token.TokenHash,
 		FamilyID:  db.PGUUID(token.FamilyID),
 		ExpiresAt: db.PGTimestamptz(token.ExpiresAt),
-	})
+	}); err != nil {
+		return err
+	}
+	return tx.Commit(ctx)
 }
 
 func (r *postgresRepository) GetRefreshToken(ctx context.Context, tokenHash []byte) (*RefreshToken, error) {
@@ -115,6 +168,13 @@ func (r *postgresRepository) RotateRefreshToken(ctx context.Context, rotation Re
 	if rowsAffected != 1 {
 		return ErrInvalidRefresh
 	}
+	if err := qtx.TouchSession(ctx, dbgen.TouchSessionParams{
+		UserAgent: db.PGTextString(rotation.Client.UserAgent),
+		IpAddress: db.PGTextString(rotation.Client.IPAddress),
+		FamilyID:  db.PGUUID(rotation.NewToken.FamilyID),
+	}); err != nil {
+		return err
+	}
 
 	return tx.Commit(ctx)
 }
@@ -126,36 +186,609 @@ func (r *postgresRepository) RevokeRefreshTokenFamily(ctx context.Context, famil
 	})
 }
 
+// DeleteUser revokes every refresh token family for the user and deletes the
+// account. Owned profiles, library items, notes, reviews and collections are
+// removed by ON DELETE CASCADE.
+func (r *postgresRepository) DeleteUser(ctx context.Context, id uuid.UUID) error {
+	tx, err := r.db.Begin(ctx)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback(ctx)
+	qtx := r.queries.WithTx(tx)
+
+	if err := qtx.RevokeUserRefreshTokens(ctx, dbgen.RevokeUserRefreshTokensParams{
+		UserID:    db.PGUUID(id),
+		RevokedAt: db.PGTimestamptz(time.Now().UTC()),
+	}); err != nil {
+		return err
+	}
+	deleted, err := qtx.DeleteUser(ctx, db.PGUUID(id))
+	if err != nil {
+		return err
+	}
+	if deleted == 0 {
+		return ErrUserNotFound
+	}
+	if err := outbox.Record(ctx, qtx, AggregateUser, id, EventUserDeleted, outbox.DeletedPayload{ID: id}); err != nil {
+		return err
+	}
+	return tx.Commit(ctx)
+}
+
+func (r *postgresRepository) UpdateUserRole(ctx context.Context, id uuid.UUID, role Role) error {
+	updated, err := r.queries.UpdateUserRole(ctx, dbgen.UpdateUserRoleParams{ID: db.PGUUID(id), Role: string(role)})
+	if err != nil {
+		return err
+	}
+	if updated == 0 {
+		return ErrUserNotFound
+	}
+	return nil
+}
+
+// CreateUserToken stores a new token, retiring any unused token the user
+// already has for the same purpose so only the latest link works.
+func (r *postgresRepository) CreateUserToken(ctx context.Context, token UserToken) error {
+	tx, err := r.db.Begin(ctx)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback(ctx)
+	qtx := r.queries.WithTx(tx)
+
+	if err := qtx.InvalidateUserTokens(ctx, dbgen.InvalidateUserTokensParams{
+		UserID:  db.PGUUID(token.UserID),
+		Purpose: string(token.Purpose),
+	}); err != nil {
+		return err
+	}
+	if err := qtx.CreateUserToken(ctx, dbgen.CreateUserTokenParams{
+		ID:        db.PGUUID(token.ID),
+		UserID:    db.PGUUID(token.UserID),
+		Purpose:   string(token.Purpose),
+		TokenHash: token.TokenHash,
+		ExpiresAt: db.PGTimestamptz(token.ExpiresAt),
+	}); err != nil {
+		return err
+	}
+	return tx.Commit(ctx)
+}
+
+// VerifyEmail uses up a verification token and marks its user's email as
+// verified. It returns nil when the token is unknown, used or expired.
+func (r *postgresRepository) VerifyEmail(ctx context.Context, tokenHash []byte) (*uuid.UUID, error) {
+	tx, err := r.db.Begin(ctx)
+	if err != nil {
+		return nil, err
+	}
+	defer tx.Rollback(ctx)
+	qtx := r.queries.WithTx(tx)
+
+	userID, err := consumeUserToken(ctx, qtx, tokenHash, PurposeVerifyEmail)
+	if userID == nil || err != nil {
+		return nil, err
+	}
+	if _, err := qtx.MarkUserEmailVerified(ctx, db.PGUUID(*userID)); err != nil {
+		return nil, err
+	}
+	return userID, tx.Commit(ctx)
+}
+
+// ResetPassword uses up a reset token, sets the new password hash and
+// revokes every refresh token family of the user, so sessions started with
+// the old password end. Following the emailed link also proves the address,
+// so the email is marked verified. It returns nil when the token is unknown,
+// used or expired.
+func (r *postgresRepository) ResetPassword(ctx context.Context, tokenHash []byte, passwordHash string) (*uuid.UUID, error) {
+	tx, err := r.db.Begin(ctx)
+	if err != nil {
+		return nil, err
+	}
+	defer tx.Rollback(ctx)
+	qtx := r.queries.WithTx(tx)
+
+	userID, err := consumeUserToken(ctx, qtx, tokenHash, PurposeResetPassword)
+	if userID == nil || err != nil {
+		return nil, err
+	}
+	if _, err := qtx.UpdateUserPassword(ctx, dbgen.UpdateUserPasswordParams{ID: db.PGUUID(*userID), PasswordHash: passwordHash}); err != nil {
+		return nil, err
+	}
+	if err := qtx.InvalidateUserTokens(ctx, dbgen.InvalidateUserTokensParams{
+		UserID:  db.PGUUID(*userID),
+		Purpose: string(PurposeResetPassword),
+	}); err != nil {
+		return nil, err
+	}
+	revokedAt := db.PGTimestamptz(time.Now().UTC())
+	if err := qtx.RevokeUserRefreshTokens(ctx, dbgen.RevokeUserRefreshTokensParams{
+		UserID:    db.PGUUID(*userID),
+		RevokedAt: revokedAt,
+	}); err != nil {
+		return nil, err
+	}
+	if err := qtx.RevokeUserPersonalAccessTokens(ctx, dbgen.RevokeUserPersonalAccessTokensParams{
+		UserID:    db.PGUUID(*userID),
+		RevokedAt: revokedAt,
+	}); err != nil {
+		return nil, err
+	}
+	return userID, tx.Commit(ctx)
+}
+
+// ListSessions returns the sessions whose refresh token family still has a
+// live token. Rotation revokes each token as it is replaced, so an active
+// session has exactly one.
+func (r *postgresRepository) ListSessions(ctx context.Context, userID uuid.UUID) ([]*Session, error) {
+	rows, err := r.queries.ListActiveSessions(ctx, db.PGUUID(userID))
+	if err != nil {
+		return nil, err
+	}
+	sessions := make([]*Session, 0, len(rows))
+	for _, row := range rows {
+		sessions = append(sessions, &Session{
+			ID:         db.UUID(row.FamilyID),
+			UserAgent:  db.StringPtr(row.UserAgent),
+			IPAddress:  db.StringPtr(row.IpAddress),
+			CreatedAt:  db.Time(row.CreatedAt),
+			LastUsedAt: db.Time(row.LastUsedAt),
+			ExpiresAt:  db.Time(row.ExpiresAt),
+		})
+	}
+	return sessions, nil
+}
+
+func (r *postgresRepository) RevokeSession(ctx context.Context, userID, sessionID uuid.UUID) error {
+	revoked, err := r.queries.RevokeUserSession(ctx, dbgen.RevokeUserSessionParams{
+		UserID:    db.PGUUID(userID),
+		FamilyID:  db.PGUUID(sessionID),
+		RevokedAt: db.PGTimestamptz(time.Now().UTC()),
+	})
+	if err != nil {
+		return err
+	}
+	if revoked == 0 {
+		return ErrSessionNotFound
+	}
+	return nil
+}
+
+// RevokeOtherSessions counts revoked refresh tokens, which is one per active
+// session.
+func (r *postgresRepository) RevokeOtherSessions(ctx context.Context, userID uuid.UUID, keep *uuid.UUID) (int64, error) {
+	return r.queries.RevokeOtherUserSessions(ctx, dbgen.RevokeOtherUserSessionsParams{
+		RevokedAt:    db.PGTimestamptz(time.Now().UTC()),
+		UserID:       db.PGUUID(userID),
+		KeepFamilyID: db.PGUUIDPtr(keep),
+	})
+}
+
+// FindUserToken returns the user of a live token without using it up, or nil
+// when the token is unknown, used or expired.
+func (r *postgresRepository) FindUserToken(ctx context.Context, tokenHash []byte, purpose TokenPurpose) (*uuid.UUID, error) {
+	row, err := r.queries.GetActiveUserToken(ctx, dbgen.GetActiveUserTokenParams{TokenHash: tokenHash, Purpose: string(purpose)})
+	if errors.Is(err, pgx.ErrNoRows) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	userID := db.UUID(row)
+	return &userID, nil
+}
+
+func (r *postgresRepository) ConsumeUserToken(ctx context.Context, tokenHash []byte, purpose TokenPurpose) (*uuid.UUID, error) {
+	return consumeUserToken(ctx, r.queries, tokenHash, purpose)
+}
+
+func (r *postgresRepository) GetTOTP(ctx context.Context, userID uuid.UUID) (*TOTPCredential, error) {
+	row, err := r.queries.GetUserTOTP(ctx, db.PGUUID(userID))
+	if errors.Is(err, pgx.ErrNoRows) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	return &TOTPCredential{
+		UserID:       db.UUID(row.UserID),
+		Secret:       row.SecretCiphertext,
+		ConfirmedAt:  db.TimePtr(row.ConfirmedAt),
+		LastUsedStep: row.LastUsedStep,
+	}, nil
+}
+
+// SavePendingTOTP stores a secret awaiting confirmation, replacing an earlier
+// unconfirmed one. A confirmed enrollment is never replaced.
+func (r *postgresRepository) SavePendingTOTP(ctx context.Context, userID uuid.UUID, secret []byte) error {
+	saved, err := r.queries.UpsertPendingUserTOTP(ctx, dbgen.UpsertPendingUserTOTPParams{
+		UserID:           db.PGUUID(userID),
+		SecretCiphertext: secret,
+	})
+	if err != nil {
+		return err
+	}
+	if saved == 0 {
+		return ErrMFAAlreadyEnabled
+	}
+	return nil
+}
+
+// ConfirmTOTP turns the pending enrollment on, recording the step of the code
+// that confirmed it, and replaces the user's recovery codes.
+func (r *postgresRepository) ConfirmTOTP(ctx context.Context, userID uuid.UUID, step int64, recoveryCodeHashes [][]byte) error {
+	tx, err := r.db.Begin(ctx)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback(ctx)
+	qtx := r.queries.WithTx(tx)
+
+	confirmed, err := qtx.ConfirmUserTOTP(ctx, dbgen.ConfirmUserTOTPParams{UserID: db.PGUUID(userID), LastUsedStep: step})
+	if err != nil {
+		return err
+	}
+	if confirmed == 0 {
+		return ErrMFAAlreadyEnabled
+	}
+	if err := replaceRecoveryCodes(ctx, qtx, userID, recoveryCodeHashes); err != nil {
+		return err
+	}
+	return tx.Commit(ctx)
+}
+
+// UseTOTPStep records that a code was used. It reports false when the step
+// is not newer than the last one used, so each code works once.
+func (r *postgresRepository) UseTOTPStep(ctx context.Context, userID uuid.UUID, step int64) (bool, error) {
+	used, err := r.queries.UseUserTOTPStep(ctx, dbgen.UseUserTOTPStepParams{UserID: db.PGUUID(userID), LastUsedStep: step})
+	return used == 1, err
+}
+
+// DisableTOTP removes the enrollment, pending or confirmed, together with the
+// recovery codes.
+func (r *postgresRepository) DisableTOTP(ctx context.Context, userID uuid.UUID) error {
+	tx, err := r.db.Begin(ctx)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback(ctx)
+	qtx := r.queries.WithTx(tx)
+
+	deleted, err := qtx.DeleteUserTOTP(ctx, db.PGUUID(userID))
+	if err != nil {
+		return err
+	}
+	if deleted == 0 {
+		return ErrMFANotEnabled
+	}
+	if err := qtx.DeleteRecoveryCodes(ctx, db.PGUUID(userID)); err != nil {
+		return err
+	}
+	return tx.Commit(ctx)
+}
+
+func (r *postgresRepository) ReplaceRecoveryCodes(ctx context.Context, userID uuid.UUID, codeHashes [][]byte) error {
+	tx, err := r.db.Begin(ctx)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback(ctx)
+
+	if err := replaceRecoveryCodes(ctx, r.queries.WithTx(tx), userID, codeHashes); err != nil {
+		return err
+	}
+	return tx.Commit(ctx)
+}
+
+func (r *postgresRepository) UseRecoveryCode(ctx context.Context, userID uuid.UUID, codeHash []byte) (bool, error) {
+	used, err := r.queries.UseRecoveryCode(ctx, dbgen.UseRecoveryCodeParams{UserID: db.PGUUID(userID), CodeHash: codeHash})
+	return used == 1, err
+}
+
+func (r *postgresRepository) CountRecoveryCodes(ctx context.Context, userID uuid.UUID) (int64, error) {
+	return r.queries.CountUnusedRecoveryCodes(ctx, db.PGUUID(userID))
+}
+
+// CreateExternalLogin stores a sign-in started at a provider and clears out
+// the ones that were never finished.
+func (r *postgresRepository) CreateExternalLogin(ctx context.Context, login ExternalLogin) error {
+	if err := r.queries.DeleteExpiredOIDCLogins(ctx); err != nil {
+		return err
+	}
+	return r.queries.CreateOIDCLogin(ctx, dbgen.CreateOIDCLoginParams{
+		StateHash:      login.StateHash,
+		Provider:       login.Provider,
+		Nonce:          login.Nonce,
+		CodeVerifier:   login.CodeVerifier,
+		UserID:         db.PGUUIDPtr(login.UserID),
+		Reauthenticate: login.Reauthenticate,
+		ExpiresAt:      db.PGTimestamptz(login.ExpiresAt),
+	})
+}
+
+// ConsumeExternalLogin deletes and returns the sign-in with the state, or nil
+// when it is unknown, already finished or expired.
+func (r *postgresRepository) ConsumeExternalLogin(ctx context.Context, stateHash []byte) (*ExternalLogin, error) {
+	row, err := r.queries.ConsumeOIDCLogin(ctx, stateHash)
+	if errors.Is(err, pgx.ErrNoRows) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	return &ExternalLogin{
+		StateHash:      stateHash,
+		Provider:       row.Provider,
+		Nonce:          row.Nonce,
+		CodeVerifier:   row.CodeVerifier,
+		UserID:         db.UUIDPtr(row.UserID),
+		Reauthenticate: row.Reauthenticate,
+	}, nil
+}
+
+// UseIdentity returns the user linked to the provider account, or nil when
+// none is, and records the sign-in along with the email the provider shared.
+func (r *postgresRepository) UseIdentity(ctx context.Context, provider string, identity oidc.Identity) (*uuid.UUID, error) {
+	row, err := r.queries.UseUserIdentity(ctx, dbgen.UseUserIdentityParams{
+		Email:    db.PGTextString(identity.Email),
+		Provider: provider,
+		Subject:  identity.Subject,
+	})
+	if errors.Is(err, pgx.ErrNoRows) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	userID := db.UUID(row)
+	return &userID, nil
+}
+
+func (r *postgresRepository) LinkIdentity(ctx context.Context, userID uuid.UUID, provider string, identity oidc.Identity) error {
+	return createIdentity(ctx, r.queries, userID, provider, identity)
+}
+
+// CreateUserWithIdentity creates an account for someone signing in with a
+// provider for the first time. There is no password; the email counts as
+// verified when the provider says it is.
+func (r *postgresRepository) CreateUserWithIdentity(ctx context.Context, user User, provider string, identity oidc.Identity) (*User, error) {
+	tx, err := r.db.Begin(ctx)
+	if err != nil {
+		return nil, err
+	}
+	defer tx.Rollback(ctx)
+	qtx := r.queries.WithTx(tx)
+
+	if _, err := qtx.CreateUser(ctx, dbgen.CreateUserParams{
+		ID:           db.PGUUID(user.ID),
+		Email:        user.Email,
+		Username:     user.Username,
+		PasswordHash: user.PasswordHash,
+	}); err != nil {
+		return nil, err
+	}
+	if identity.EmailVerified {
+		if _, err := qtx.MarkUserEmailVerified(ctx, db.PGUUID(user.ID)); err != nil {
+			return nil, err
+		}
+	}
+	if err := createIdentity(ctx, qtx, user.ID, provider, identity); err != nil {
+		return nil, err
+	}
+	row, err := qtx.GetUserByID(ctx, db.PGUUID(user.ID))
+	if err != nil {
+		return nil, err
+	}
+	if err := tx.Commit(ctx); err != nil {
+		return nil, err
+	}
+	return mapGetUserByIDRow(row), nil
+}
+
+func (r *postgresRepository) ListIdentities(ctx context.Context, userID uuid.UUID) ([]*ExternalIdentity, error) {
+	rows, err := r.queries.ListUserIdentities(ctx, db.PGUUID(userID))
+	if err != nil {
+		return nil, err
+	}
+	identities := make([]*ExternalIdentity, 0, len(rows))
+	for _, row := range rows {
+		identities = append(identities, &ExternalIdentity{
+			Provider:   row.Provider,
+			Email:      db.StringPtr(row.Email),
+			CreatedAt:  db.Time(row.CreatedAt),
+			LastUsedAt: db.Time(row.LastUsedAt),
+		})
+	}
+	return identities, nil
+}
+
+func (r *postgresRepository) DeleteIdentity(ctx context.Context, userID uuid.UUID, provider string) error {
+	deleted, err := r.queries.DeleteUserIdentity(ctx, dbgen.DeleteUserIdentityParams{UserID: db.PGUUID(userID), Provider: provider})
+	if err != nil {
+		return err
+	}
+	if deleted == 0 {
+		return ErrIdentityNotFound
+	}
+	return nil
+}
+
+func (r *postgresRepository) CreatePersonalAccessToken(ctx context.Context, token PersonalAccessToken) (*PersonalAccessToken, error) {
+	createdAt, err := r.queries.CreatePersonalAccessToken(ctx, dbgen.CreatePersonalAccessTokenParams{
+		ID:        db.PGUUID(token.ID),
+		UserID:    db.PGUUID(token.UserID),
+		Name:      token.Name,
+		TokenHash: token.TokenHash,
+		Scopes:    token.Scopes,
+		ExpiresAt: db.PGTimestamptzPtr(token.ExpiresAt),
+	})
+	if err != nil {
+		return nil, err
+	}
+	token.CreatedAt = db.Time(createdAt)
+	return &token, nil
+}
+
+// FindPersonalAccessToken returns what a token grants, or nil when the token
+// is unknown, revoked or expired.
+func (r *postgresRepository) FindPersonalAccessToken(ctx context.Context, tokenHash []byte) (*TokenGrant, error) {
+	row, err := r.queries.GetActivePersonalAccessToken(ctx, tokenHash)
+	if errors.Is(err, pgx.ErrNoRows) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	return &TokenGrant{
+		TokenID: db.UUID(row.ID),
+		UserID:  db.UUID(row.UserID),
+		Role:    Role(row.Role),
+		Scopes:  row.Scopes,
+	}, nil
+}
+
+// TouchPersonalAccessToken records a use of the token. Within a minute of the
+// last recorded use from the same address it writes nothing, so scripts
+// making many requests do not update the row for each one.
+func (r *postgresRepository) TouchPersonalAccessToken(ctx context.Context, tokenID uuid.UUID, ipAddress string) error {
+	return r.queries.TouchPersonalAccessToken(ctx, dbgen.TouchPersonalAccessTokenParams{
+		ID:         db.PGUUID(tokenID),
+		LastUsedIp: db.PGTextString(ipAddress),
+	})
+}
+
+func (r *postgresRepository) ListPersonalAccessTokens(ctx context.Context, userID uuid.UUID) ([]*PersonalAccessToken, error) {
+	rows, err := r.queries.ListPersonalAccessTokens(ctx, db.PGUUID(userID))
+	if err != nil {
+		return nil, err
+	}
+	tokens := make([]*PersonalAccessToken, 0, len(rows))
+	for _, row := range rows {
+		tokens = append(tokens, &PersonalAccessToken{
+			ID:         db.UUID(row.ID),
+			UserID:     userID,
+			Name:       row.Name,
+			Scopes:     row.Scopes,
+			ExpiresAt:  db.TimePtr(row.ExpiresAt),
+			LastUsedAt: db.TimePtr(row.LastUsedAt),
+			LastUsedIP: db.StringPtr(row.LastUsedIp),
+			CreatedAt:  db.Time(row.CreatedAt),
+		})
+	}
+	return tokens, nil
+}
+
+func (r *postgresRepository) RevokePersonalAccessToken(ctx context.Context, userID, tokenID uuid.UUID) error {
+	revoked, err := r.queries.RevokePersonalAccessToken(ctx, dbgen.RevokePersonalAccessTokenParams{
+		ID:        db.PGUUID(tokenID),
+		UserID:    db.PGUUID(userID),
+		RevokedAt: db.PGTimestamptz(time.Now().UTC()),
+	})
+	if err != nil {
+		return err
+	}
+	if revoked == 0 {
+		return ErrTokenNotFound
+	}
+	return nil
+}
+
+func replaceRecoveryCodes(ctx context.Context, q *dbgen.Queries, userID uuid.UUID, codeHashes [][]byte) error {
+	if err := q.DeleteRecoveryCodes(ctx, db.PGUUID(userID)); err != nil {
+		return err
+	}
+	for _, codeHash := range codeHashes {
+		id, err := uuid.NewV7()
+		if err != nil {
+			return err
+		}
+		if err := q.CreateRecoveryCode(ctx, dbgen.CreateRecoveryCodeParams{
+			ID:       db.PGUUID(id),
+			UserID:   db.PGUUID(userID),
+			CodeHash: codeHash,
+		}); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func createIdentity(ctx context.Context, q *dbgen.Queries, userID uuid.UUID, provider string, identity oidc.Identity) error {
+	id, err := uuid.NewV7()
+	if err != nil {
+		return err
+	}
+	err = q.CreateUserIdentity(ctx, dbgen.CreateUserIdentityParams{
+		ID:       db.PGUUID(id),
+		UserID:   db.PGUUID(userID),
+		Provider: provider,
+		Subject:  identity.Subject,
+		Email:    db.PGTextString(identity.Email),
+	})
+	return mapIdentityError(err)
+}
+
+// mapIdentityError tells apart the two ways a link can collide: the provider
+// account belongs to someone else, or the user already linked the provider.
+func mapIdentityError(err error) error {
+	var pgErr *pgconn.PgError
+	if !errors.As(err, &pgErr) || pgErr.Code != "23505" {
+		return err
+	}
+	if pgErr.ConstraintName == "user_identities_user_id_provider_key" {
+		return ErrProviderAlreadyLinked
+	}
+	return ErrIdentityInUse
+}
+
+func consumeUserToken(ctx context.Context, q *dbgen.Queries, tokenHash []byte, purpose TokenPurpose) (*uuid.UUID, error) {
+	row, err := q.ConsumeUserToken(ctx, dbgen.ConsumeUserTokenParams{TokenHash: tokenHash, Purpose: string(purpose)})
+	if errors.Is(err, pgx.ErrNoRows) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	userID := db.UUID(row)
+	return &userID, nil
+}
+
 func mapCreateUserRow(row dbgen.CreateUserRow) *User {
 	return &User{
-		ID:           db.UUID(row.ID),
-		Email:        row.Email,
-		Username:     row.Username,
-		PasswordHash: row.PasswordHash,
-		CreatedAt:    db.Time(row.CreatedAt),
-		UpdatedAt:    db.Time(row.UpdatedAt),
+		ID:              db.UUID(row.ID),
+		Email:           row.Email,
+		Username:        row.Username,
+		PasswordHash:    row.PasswordHash,
+		Role:            Role(row.Role),
+		EmailVerifiedAt: db.TimePtr(row.EmailVerifiedAt),
+		CreatedAt:       db.Time(row.CreatedAt),
+		UpdatedAt:       db.Time(row.UpdatedAt),
 	}
 }
 
 func mapGetUserByEmailOrUsernameRow(row dbgen.GetUserByEmailOrUsernameRow) *User {
 	return &User{
-		ID:           db.UUID(row.ID),
-		Email:        row.Email,
-		Username:     row.Username,
-		PasswordHash: row.PasswordHash,
-		CreatedAt:    db.Time(row.CreatedAt),
-		UpdatedAt:    db.Time(row.UpdatedAt),
+		ID:              db.UUID(row.ID),
+		Email:           row.Email,
+		Username:        row.Username,
+		PasswordHash:    row.PasswordHash,
+		Role:            Role(row.Role),
+		EmailVerifiedAt: db.TimePtr(row.EmailVerifiedAt),
+		CreatedAt:       db.Time(row.CreatedAt),
+		UpdatedAt:       db.Time(row.UpdatedAt),
 	}
 }
 
 func mapGetUserByIDRow(row dbgen.GetUserByIDRow) *User {
 	return &User{
-		ID:           db.UUID(row.ID),
-		Email:        row.Email,
-		Username:     row.Username,
-		PasswordHash: row.PasswordHash,
-		CreatedAt:    db.Time(row.CreatedAt),
-		UpdatedAt:    db.Time(row.UpdatedAt),
+		ID:              db.UUID(row.ID),
+		Email:           row.Email,
+		Username:        row.Username,
+		PasswordHash:    row.PasswordHash,
+		Role:            Role(row.Role),
+		EmailVerifiedAt: db.TimePtr(row.EmailVerifiedAt),
+		CreatedAt:       db.Time(row.CreatedAt),
+		UpdatedAt:       db.Time(row.UpdatedAt),
 	}
 }
 
@@ -0,0 +1,66 @@
+package auth
+
+import (
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/rand"
+	"encoding/base64"
+	"errors"
+
+	"github.com/gofrs/uuid/v5"
+)
+
+// SecretBox encrypts secrets the server has to read back, such as TOTP keys,
+// with AES-256-GCM. Each secret is bound to its user, so a ciphertext copied
+// onto another account does not decrypt.
+type SecretBox struct {
+	aead cipher.AEAD
+}
+
+// NewSecretBox takes a base64-encoded 32-byte key. Without one it generates a
+// key that lasts until the process exits.
+func NewSecretBox(encodedKey string) (*SecretBox, error) {
+	var key []byte
+	if encodedKey == "" {
+		key = make([]byte, 32)
+		if _, err := rand.Read(key); err != nil {
+			return nil, err
+		}
+	} else {
+		decoded, err := base64.StdEncoding.DecodeString(encodedKey)
+		if err != nil {
+			return nil, err
+		}
+		if len(decoded) != 32 {
+			return nil, errors.New("encryption key must be a base64-encoded 32-byte key")
+		}
+		key = decoded
+	}
+
+	block, err := aes.NewCipher(key)
+	if err != nil {
+		return nil, err
+	}
+	aead, err := cipher.NewGCM(block)
+	if err != nil {
+		return nil, err
+	}
+	return &SecretBox{aead: aead}, nil
+}
+
+// Seal returns the nonce followed by the ciphertext.
+func (b *SecretBox) Seal(userID uuid.UUID, plaintext []byte) ([]byte, error) {
+	nonce := make([]byte, b.aead.NonceSize(), b.aead.NonceSize()+len(plaintext)+b.aead.Overhead())
+	if _, err := rand.Read(nonce); err != nil {
+		return nil, err
+	}
+	return b.aead.Seal(nonce, nonce, plaintext, userID.Bytes()), nil
+}
+
+func (b *SecretBox) Open(userID uuid.UUID, sealed []byte) ([]byte, error) {
+	if len(sealed) < b.aead.NonceSize() {
+		return nil, errors.New("sealed secret is too short")
+	}
+	nonce, ciphertext := sealed[:b.aead.NonceSize()], sealed[b.aead.NonceSize():]
+	return b.aead.Open(nil, nonce, ciphertext, userID.Bytes())
+}
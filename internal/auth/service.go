@@ -2,30 +2,93 @@ package auth
 
 import (
 	"context"
+	"crypto/rand"
 	"errors"
+	"fmt"
 	"log/slog"
-	"net/mail"
+	netmail "net/mail"
 	"regexp"
+	"slices"
 	"strings"
+	"sync"
 	"time"
+	"unicode/utf8"
 
 	"github.com/gofrs/uuid/v5"
+	"github.com/zizouhuweidi/maktaba/internal/mail"
+	"github.com/zizouhuweidi/maktaba/internal/oidc"
 )
 
 var (
-	ErrInvalidCredentials = errors.New("invalid credentials")
-	ErrInvalidSignup      = errors.New("invalid signup data")
-	ErrInvalidRefresh     = errors.New("invalid refresh token")
-	ErrUserNotFound       = errors.New("user not found")
+	ErrInvalidCredentials    = errors.New("invalid credentials")
+	ErrInvalidSignup         = errors.New("invalid signup data")
+	ErrInvalidRefresh        = errors.New("invalid refresh token")
+	ErrUserNotFound          = errors.New("user not found")
+	ErrInvalidRole           = errors.New("invalid role")
+	ErrInvalidToken          = errors.New("invalid or expired token")
+	ErrInvalidPassword       = errors.New("invalid password")
+	ErrAlreadyVerified       = errors.New("email already verified")
+	ErrSessionNotFound       = errors.New("session not found")
+	ErrMFAAlreadyEnabled     = errors.New("two-factor authentication already enabled")
+	ErrMFANotEnabled         = errors.New("two-factor authentication not enabled")
+	ErrInvalidMFACode        = errors.New("invalid authentication code")
+	ErrTooManyAttempts       = errors.New("too many attempts")
+	ErrUnknownProvider       = errors.New("unknown identity provider")
+	ErrExternalLoginFailed   = errors.New("external login failed")
+	ErrIdentityConflict      = errors.New("an account with this email already exists")
+	ErrIdentityInUse         = errors.New("external account linked to another user")
+	ErrProviderAlreadyLinked = errors.New("provider already linked")
+	ErrIdentityNotFound      = errors.New("linked identity not found")
+	ErrWrongIdentity         = errors.New("external account not linked to this user")
+	ErrLastSignInMethod      = errors.New("cannot remove the only way to sign in")
+	ErrInvalidTokenName      = errors.New("invalid token name")
+	ErrInvalidScope          = errors.New("invalid token scope")
+	ErrInvalidTokenLifetime  = errors.New("invalid token lifetime")
+	ErrTokenNotFound         = errors.New("personal access token not found")
+)
+
+const minPasswordLength = 12
+
+// A sign-in challenge lasts long enough to open an authenticator app. Each
+// user gets a few code attempts per window, wherever they come from.
+const (
+	mfaChallengeTTL = 5 * time.Minute
+	mfaAttemptLimit = 5
+	mfaAttemptReset = 5 * time.Minute
+)
+
+// A sign-in at a provider has this long to come back to the callback, and
+// a re-authentication it ends in has this long to confirm a change.
+const (
+	externalLoginTTL = 10 * time.Minute
+	reauthTTL        = 5 * time.Minute
+)
+
+// Work moved off the request path, such as mailing a reset link, has this
+// long to finish.
+const backgroundTimeout = time.Minute
+
+// Personal access tokens are named by their owner and may last up to a year,
+// or until revoked when created without an expiry.
+const (
+	maxTokenNameLength = 100
+	maxTokenLifetime   = 365 * 24 * time.Hour
 )
 
 var usernamePattern = regexp.MustCompile(`^[a-z0-9_][a-z0-9_-]{2,31}$`)
 
 type Service struct {
-	repo       Repository
-	tokens     *TokenManager
-	refreshTTL time.Duration
-	logger     *slog.Logger
+	repo        Repository
+	tokens      *TokenManager
+	secrets     *SecretBox
+	mailer      mail.Mailer
+	providers   map[string]IdentityProvider
+	refreshTTL  time.Duration
+	emails      EmailSettings
+	logger      *slog.Logger
+	mfaAttempts *rateLimiter
+	now         func() time.Time
+	jobs        sync.WaitGroup
 }
 
 type AuthTokens struct {
@@ -35,14 +98,27 @@ type AuthTokens struct {
 	ExpiresIn    int64  `json:"expires_in"`
 }
 
-func NewService(repo Repository, tokens *TokenManager, refreshTTL time.Duration, logger *slog.Logger) *Service {
-	return &Service{repo: repo, tokens: tokens, refreshTTL: refreshTTL, logger: logger}
+// NewService signs users in with a password or with any of providers, keyed
+// by the name used in their URLs.
+func NewService(repo Repository, tokens *TokenManager, secrets *SecretBox, mailer mail.Mailer, providers map[string]IdentityProvider, refreshTTL time.Duration, emails EmailSettings, logger *slog.Logger) *Service {
+	return &Service{
+		repo:        repo,
+		tokens:      tokens,
+		secrets:     secrets,
+		mailer:      mailer,
+		providers:   providers,
+		refreshTTL:  refreshTTL,
+		emails:      emails,
+		logger:      logger,
+		mfaAttempts: newRateLimiter(mfaAttemptLimit, mfaAttemptReset),
+		now:         time.Now,
+	}
 }
 
-func (s *Service) Register(ctx context.Context, email, username, password string) (*User, AuthTokens, error) {
+func (s *Service) Register(ctx context.Context, email, username, password string, client Client) (*User, AuthTokens, error) {
 	email = strings.ToLower(strings.TrimSpace(email))
 	username = strings.ToLower(strings.TrimSpace(username))
-	if !validEmail(email) || !usernamePattern.MatchString(username) || len(password) < 12 {
+	if !validEmail(email) || !usernamePattern.MatchString(username) || len(password) < minPasswordLength {
 		return nil, AuthTokens{}, ErrInvalidSignup
 	}
 
@@ -59,36 +135,87 @@ func (s *Service) Register(ctx context.Context, email, username, password string
 	if err != nil {
 		return nil, AuthTokens{}, err
 	}
+	s.verifyInBackground(ctx, *user)
 
-	tokens, err := s.issueTokens(ctx, *user)
+	tokens, err := s.issueTokens(ctx, *user, client)
 	if err != nil {
 		return nil, AuthTokens{}, err
 	}
 	return user, tokens, nil
 }
 
-func (s *Service) Login(ctx context.Context, login, password string) (*User, AuthTokens, error) {
+// Login checks the password. When the account has two-factor
+// authentication on, it returns a challenge for CompleteMFALogin instead of
+// the user and tokens.
+func (s *Service) Login(ctx context.Context, login, password string, client Client) (*User, AuthTokens, *MFAChallenge, error) {
 	user, err := s.repo.GetUserByEmailOrUsername(ctx, strings.TrimSpace(login))
 	if err != nil {
-		return nil, AuthTokens{}, err
+		return nil, AuthTokens{}, nil, err
 	}
 	if user == nil {
-		return nil, AuthTokens{}, ErrInvalidCredentials
+		return nil, AuthTokens{}, nil, ErrInvalidCredentials
 	}
 
 	valid, err := VerifyPassword(password, user.PasswordHash)
 	if err != nil || !valid {
-		return nil, AuthTokens{}, ErrInvalidCredentials
+		return nil, AuthTokens{}, nil, ErrInvalidCredentials
 	}
 
-	tokens, err := s.issueTokens(ctx, *user)
+	tokens, challenge, err := s.signIn(ctx, *user, client)
+	if err != nil || challenge != nil {
+		return nil, AuthTokens{}, challenge, err
+	}
+	return user, tokens, nil, nil
+}
+
+// CompleteMFALogin finishes a login with the challenge and either a code from
+// the authenticator app or an unused recovery code. A wrong code leaves the
+// challenge usable until it expires or the user runs out of attempts.
+func (s *Service) CompleteMFALogin(ctx context.Context, challenge, code string, client Client) (*User, AuthTokens, error) {
+	challengeHash := HashRefreshToken(challenge)
+	userID, err := s.repo.FindUserToken(ctx, challengeHash, PurposeMFALogin)
+	if err != nil {
+		return nil, AuthTokens{}, err
+	}
+	if userID == nil {
+		return nil, AuthTokens{}, ErrInvalidToken
+	}
+	if !s.mfaAttempts.allow("login:"+userID.String(), s.now()) {
+		return nil, AuthTokens{}, ErrTooManyAttempts
+	}
+
+	valid, err := s.verifySecondFactor(ctx, *userID, code)
+	if err != nil {
+		return nil, AuthTokens{}, err
+	}
+	if !valid {
+		return nil, AuthTokens{}, ErrInvalidMFACode
+	}
+
+	// Consuming the challenge last means two requests racing with the same
+	// challenge cannot both sign in.
+	consumed, err := s.repo.ConsumeUserToken(ctx, challengeHash, PurposeMFALogin)
+	if err != nil {
+		return nil, AuthTokens{}, err
+	}
+	if consumed == nil {
+		return nil, AuthTokens{}, ErrInvalidToken
+	}
+
+	user, err := s.GetUser(ctx, *userID)
+	if err != nil {
+		return nil, AuthTokens{}, err
+	}
+	tokens, err := s.issueTokens(ctx, *user, client)
 	if err != nil {
 		return nil, AuthTokens{}, err
 	}
 	return user, tokens, nil
 }
 
-func (s *Service) Refresh(ctx context.Context, refreshToken string) (AuthTokens, error) {
+// Refresh rotates the refresh token and records the client as the session's
+// latest use.
+func (s *Service) Refresh(ctx context.Context, refreshToken string, client Client) (AuthTokens, error) {
 	existing, err := s.repo.GetRefreshToken(ctx, HashRefreshToken(refreshToken))
 	if err != nil {
 		return AuthTokens{}, err
@@ -109,7 +236,7 @@ func (s *Service) Refresh(ctx context.Context, refreshToken string) (AuthTokens,
 		return AuthTokens{}, ErrUserNotFound
 	}
 
-	accessToken, err := s.tokens.CreateAccessToken(*user)
+	accessToken, err := s.tokens.CreateAccessToken(*user, existing.FamilyID)
 	if err != nil {
 		return AuthTokens{}, err
 	}
@@ -132,6 +259,7 @@ func (s *Service) Refresh(ctx context.Context, refreshToken string) (AuthTokens,
 		CurrentTokenID: existing.ID,
 		NewToken:       newToken,
 		ReplacedByID:   newID,
+		Client:         client,
 	}); err != nil {
 		return AuthTokens{}, err
 	}
@@ -161,29 +289,600 @@ func (s *Service) GetUser(ctx context.Context, userID uuid.UUID) (*User, error)
 	return user, nil
 }
 
+// DeleteAccount permanently deletes the user after re-authenticating them.
+// All refresh token families are revoked in the same transaction.
+func (s *Service) DeleteAccount(ctx context.Context, userID uuid.UUID, confirmation Confirmation) error {
+	if err := s.confirm(ctx, userID, confirmation); err != nil {
+		return err
+	}
+
+	if err := s.repo.DeleteUser(ctx, userID); err != nil {
+		s.logger.Error("failed to delete account", "error", err, "user_id", userID)
+		return err
+	}
+	s.logger.Info("account deleted", "user_id", userID)
+	return nil
+}
+
+// SendVerificationEmail mails the user a fresh link to verify their address.
+// Earlier links stop working.
+func (s *Service) SendVerificationEmail(ctx context.Context, userID uuid.UUID) error {
+	user, err := s.GetUser(ctx, userID)
+	if err != nil {
+		return err
+	}
+	if user.EmailVerifiedAt != nil {
+		return ErrAlreadyVerified
+	}
+	return s.sendVerification(ctx, *user)
+}
+
+// VerifyEmail marks the address of the token's user as verified. Each token
+// works once.
+func (s *Service) VerifyEmail(ctx context.Context, token string) error {
+	userID, err := s.repo.VerifyEmail(ctx, HashRefreshToken(token))
+	if err != nil {
+		return err
+	}
+	if userID == nil {
+		return ErrInvalidToken
+	}
+	s.logger.Info("email verified", "user_id", *userID)
+	return nil
+}
+
+// ForgotPassword mails a password reset link when the email belongs to an
+// account. It returns at once and does the lookup and the mailing in the
+// background, so neither the answer nor how long it takes tells a caller
+// whether the account exists.
+func (s *Service) ForgotPassword(ctx context.Context, email string) {
+	email = strings.ToLower(strings.TrimSpace(email))
+	if !validEmail(email) {
+		return
+	}
+	s.background(ctx, "password reset request failed", func(ctx context.Context) error {
+		return s.sendPasswordReset(ctx, email)
+	})
+}
+
+func (s *Service) sendPasswordReset(ctx context.Context, email string) error {
+	user, err := s.repo.GetUserByEmailOrUsername(ctx, email)
+	if err != nil {
+		return err
+	}
+	if user == nil || user.Email != email {
+		return nil
+	}
+
+	token, err := s.issueUserToken(ctx, user.ID, PurposeResetPassword, s.emails.ResetPasswordTTL)
+	if err != nil {
+		return err
+	}
+	return s.mailer.Send(ctx, resetPasswordMessage(*user, s.emails.link("/recovery", token), s.emails.ResetPasswordTTL))
+}
+
+// background runs job after the request that started it has been answered.
+// The job keeps ctx's values but not its cancellation, has backgroundTimeout
+// to finish, and can only log its failure.
+func (s *Service) background(ctx context.Context, failure string, job func(context.Context) error) {
+	ctx, cancel := context.WithTimeout(context.WithoutCancel(ctx), backgroundTimeout)
+	s.jobs.Go(func() {
+		defer cancel()
+		if err := job(ctx); err != nil {
+			s.logger.Error(failure, "error", err)
+		}
+	})
+}
+
+// Wait blocks until every background job has finished or ctx is done. Call
+// it once the HTTP server has stopped, so that no request can start another.
+func (s *Service) Wait(ctx context.Context) error {
+	done := make(chan struct{})
+	go func() {
+		s.jobs.Wait()
+		close(done)
+	}()
+	select {
+	case <-done:
+		return nil
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
+
+// ResetPassword sets a new password for the token's user and signs them out
+// everywhere by revoking all of their refresh tokens and personal access
+// tokens.
+func (s *Service) ResetPassword(ctx context.Context, token, password string) error {
+	if len(password) < minPasswordLength {
+		return ErrInvalidPassword
+	}
+	passwordHash, err := HashPassword(password)
+	if err != nil {
+		return err
+	}
+
+	userID, err := s.repo.ResetPassword(ctx, HashRefreshToken(token), passwordHash)
+	if err != nil {
+		return err
+	}
+	if userID == nil {
+		return ErrInvalidToken
+	}
+	s.logger.Info("password reset", "user_id", *userID)
+	return nil
+}
+
+// ListSessions returns the user's active sessions, most recently used first,
+// marking the one the request came from.
+func (s *Service) ListSessions(ctx context.Context, userID uuid.UUID, current *uuid.UUID) ([]*Session, error) {
+	sessions, err := s.repo.ListSessions(ctx, userID)
+	if err != nil {
+		return nil, err
+	}
+	for _, session := range sessions {
+		session.Current = current != nil && session.ID == *current
+	}
+	return sessions, nil
+}
+
+// RevokeSession signs one of the user's sessions out by revoking its refresh
+// token family. Access tokens already issued to it keep working until they
+// expire.
+func (s *Service) RevokeSession(ctx context.Context, userID, sessionID uuid.UUID) error {
+	if err := s.repo.RevokeSession(ctx, userID, sessionID); err != nil {
+		return err
+	}
+	s.logger.Info("session revoked", "user_id", userID, "session_id", sessionID)
+	return nil
+}
+
+// RevokeOtherSessions signs the user out everywhere except the current
+// session and returns how many sessions ended. Without a current session,
+// every session ends.
+func (s *Service) RevokeOtherSessions(ctx context.Context, userID uuid.UUID, current *uuid.UUID) (int64, error) {
+	revoked, err := s.repo.RevokeOtherSessions(ctx, userID, current)
+	if err != nil {
+		return 0, err
+	}
+	s.logger.Info("other sessions revoked", "user_id", userID, "revoked", revoked)
+	return revoked, nil
+}
+
+// MFAStatus reports whether the user has two-factor authentication on and
+// how many recovery codes they have left.
+func (s *Service) MFAStatus(ctx context.Context, userID uuid.UUID) (*MFAStatus, error) {
+	credential, err := s.repo.GetTOTP(ctx, userID)
+	if err != nil {
+		return nil, err
+	}
+	if credential == nil || credential.ConfirmedAt == nil {
+		return &MFAStatus{}, nil
+	}
+	remaining, err := s.repo.CountRecoveryCodes(ctx, userID)
+	if err != nil {
+		return nil, err
+	}
+	return &MFAStatus{Enabled: true, EnabledAt: credential.ConfirmedAt, RecoveryCodesRemaining: remaining}, nil
+}
+
+// StartTOTPEnrollment creates a new authenticator secret for the user. It
+// does nothing until ConfirmTOTPEnrollment receives a code made from it, and
+// starting again replaces an unconfirmed secret.
+func (s *Service) StartTOTPEnrollment(ctx context.Context, userID uuid.UUID) (*TOTPEnrollment, error) {
+	user, err := s.GetUser(ctx, userID)
+	if err != nil {
+		return nil, err
+	}
+	secret, err := newTOTPSecret()
+	if err != nil {
+		return nil, err
+	}
+	sealed, err := s.secrets.Seal(userID, secret)
+	if err != nil {
+		return nil, err
+	}
+	if err := s.repo.SavePendingTOTP(ctx, userID, sealed); err != nil {
+		return nil, err
+	}
+	return &TOTPEnrollment{
+		Secret:          totpEncoding.EncodeToString(secret),
+		ProvisioningURI: provisioningURI(secret, user.Email),
+	}, nil
+}
+
+// ConfirmTOTPEnrollment turns two-factor authentication on once the user
+// enters a code from their app, and returns a fresh set of recovery codes.
+// The codes are only ever shown here and by RegenerateRecoveryCodes.
+func (s *Service) ConfirmTOTPEnrollment(ctx context.Context, userID uuid.UUID, code string) ([]string, error) {
+	if !s.mfaAttempts.allow("enroll:"+userID.String(), s.now()) {
+		return nil, ErrTooManyAttempts
+	}
+	credential, err := s.repo.GetTOTP(ctx, userID)
+	if err != nil {
+		return nil, err
+	}
+	if credential == nil {
+		return nil, ErrMFANotEnabled
+	}
+	if credential.ConfirmedAt != nil {
+		return nil, ErrMFAAlreadyEnabled
+	}
+
+	secret, err := s.secrets.Open(userID, credential.Secret)
+	if err != nil {
+		return nil, err
+	}
+	step, ok := matchTOTP(secret, strings.TrimSpace(code), s.now(), credential.LastUsedStep)
+	if !ok {
+		return nil, ErrInvalidMFACode
+	}
+
+	codes, hashes, err := recoveryCodes()
+	if err != nil {
+		return nil, err
+	}
+	if err := s.repo.ConfirmTOTP(ctx, userID, step, hashes); err != nil {
+		return nil, err
+	}
+	s.logger.Info("two-factor authentication enabled", "user_id", userID)
+	return codes, nil
+}
+
+// DisableTOTP turns two-factor authentication off after re-authenticating
+// the user.
+func (s *Service) DisableTOTP(ctx context.Context, userID uuid.UUID, confirmation Confirmation) error {
+	if err := s.confirm(ctx, userID, confirmation); err != nil {
+		return err
+	}
+	if err := s.repo.DisableTOTP(ctx, userID); err != nil {
+		return err
+	}
+	s.logger.Info("two-factor authentication disabled", "user_id", userID)
+	return nil
+}
+
+// RegenerateRecoveryCodes replaces all of the user's recovery codes, used or
+// not, after re-authenticating the user.
+func (s *Service) RegenerateRecoveryCodes(ctx context.Context, userID uuid.UUID, confirmation Confirmation) ([]string, error) {
+	if err := s.confirm(ctx, userID, confirmation); err != nil {
+		return nil, err
+	}
+	credential, err := s.repo.GetTOTP(ctx, userID)
+	if err != nil {
+		return nil, err
+	}
+	if credential == nil || credential.ConfirmedAt == nil {
+		return nil, ErrMFANotEnabled
+	}
+
+	codes, hashes, err := recoveryCodes()
+	if err != nil {
+		return nil, err
+	}
+	if err := s.repo.ReplaceRecoveryCodes(ctx, userID, hashes); err != nil {
+		return nil, err
+	}
+	s.logger.Info("recovery codes regenerated", "user_id", userID)
+	return codes, nil
+}
+
+// ExternalProviders returns the names of the configured identity providers
+// in alphabetical order.
+func (s *Service) ExternalProviders() []string {
+	names := make([]string, 0, len(s.providers))
+	for name := range s.providers {
+		names = append(names, name)
+	}
+	slices.Sort(names)
+	return names
+}
+
+// StartExternalLogin returns where to send the browser to sign in with the
+// provider, and the state the provider will hand back to the callback. With
+// linkUserID set, the callback links the provider account to that user
+// instead of signing in.
+func (s *Service) StartExternalLogin(ctx context.Context, provider string, linkUserID *uuid.UUID) (string, string, error) {
+	if _, ok := s.providers[provider]; !ok {
+		return "", "", ErrUnknownProvider
+	}
+	if linkUserID != nil {
+		linked, err := s.linkedTo(ctx, *linkUserID, provider)
+		if err != nil {
+			return "", "", err
+		}
+		if linked {
+			return "", "", ErrProviderAlreadyLinked
+		}
+	}
+	return s.startExternalLogin(ctx, ExternalLogin{Provider: provider, UserID: linkUserID})
+}
+
+// StartReauthentication is StartExternalLogin for a signed-in user proving
+// it is still them at a provider they linked. The callback hands back a
+// token that confirms one sensitive change, which is how users without a
+// password confirm.
+func (s *Service) StartReauthentication(ctx context.Context, provider string, userID uuid.UUID) (string, string, error) {
+	if _, ok := s.providers[provider]; !ok {
+		return "", "", ErrUnknownProvider
+	}
+	linked, err := s.linkedTo(ctx, userID, provider)
+	if err != nil {
+		return "", "", err
+	}
+	if !linked {
+		return "", "", ErrIdentityNotFound
+	}
+	return s.startExternalLogin(ctx, ExternalLogin{Provider: provider, UserID: &userID, Reauthenticate: true})
+}
+
+// startExternalLogin stores login with fresh state, nonce and PKCE verifier
+// and returns the provider's authorization URL and the state.
+func (s *Service) startExternalLogin(ctx context.Context, login ExternalLogin) (string, string, error) {
+	idp := s.providers[login.Provider]
+
+	// State, nonce and PKCE verifier are random tokens made like refresh
+	// tokens; the verifier's 43 characters are the least RFC 7636 allows.
+	state, stateHash, err := NewRefreshToken()
+	if err != nil {
+		return "", "", err
+	}
+	nonce, _, err := NewRefreshToken()
+	if err != nil {
+		return "", "", err
+	}
+	codeVerifier, _, err := NewRefreshToken()
+	if err != nil {
+		return "", "", err
+	}
+
+	authURL, err := idp.AuthCodeURL(ctx, state, nonce, codeVerifier)
+	if err != nil {
+		return "", "", fmt.Errorf("%w: %v", ErrExternalLoginFailed, err)
+	}
+	login.StateHash = stateHash
+	login.Nonce = nonce
+	login.CodeVerifier = codeVerifier
+	login.ExpiresAt = s.now().UTC().Add(externalLoginTTL)
+	if err := s.repo.CreateExternalLogin(ctx, login); err != nil {
+		return "", "", err
+	}
+	return authURL, state, nil
+}
+
+// linkedTo reports whether the user has linked an account at the provider.
+func (s *Service) linkedTo(ctx context.Context, userID uuid.UUID, provider string) (bool, error) {
+	identities, err := s.repo.ListIdentities(ctx, userID)
+	if err != nil {
+		return false, err
+	}
+	for _, identity := range identities {
+		if identity.Provider == provider {
+			return true, nil
+		}
+	}
+	return false, nil
+}
+
+// CompleteExternalLogin handles the provider redirecting back with state and
+// an authorization code. A provider account seen before signs its user in.
+// A new one is linked to the account with the same email when both the
+// provider and we have verified that address, and otherwise gets a new
+// account. An unverified match is refused, since anyone can put someone
+// else's address on a provider account.
+func (s *Service) CompleteExternalLogin(ctx context.Context, provider, state, code string, client Client) (*ExternalLoginResult, error) {
+	login, err := s.repo.ConsumeExternalLogin(ctx, HashRefreshToken(state))
+	if err != nil {
+		return nil, err
+	}
+	if login == nil || login.Provider != provider {
+		return nil, ErrInvalidToken
+	}
+	idp, ok := s.providers[provider]
+	if !ok {
+		return nil, ErrUnknownProvider
+	}
+
+	identity, err := idp.Exchange(ctx, code, login.CodeVerifier, login.Nonce)
+	if err != nil {
+		return nil, fmt.Errorf("%w: %v", ErrExternalLoginFailed, err)
+	}
+	identity.Email = strings.ToLower(strings.TrimSpace(identity.Email))
+
+	if login.Reauthenticate && login.UserID != nil {
+		token, err := s.reauthenticate(ctx, *login.UserID, provider, *identity)
+		if err != nil {
+			return nil, err
+		}
+		return &ExternalLoginResult{ReauthToken: token}, nil
+	}
+	if login.UserID != nil {
+		user, err := s.linkIdentity(ctx, *login.UserID, provider, *identity)
+		if err != nil {
+			return nil, err
+		}
+		return &ExternalLoginResult{User: user, Linked: true}, nil
+	}
+
+	user, err := s.externalUser(ctx, provider, *identity)
+	if err != nil {
+		return nil, err
+	}
+	tokens, challenge, err := s.signIn(ctx, *user, client)
+	if err != nil {
+		return nil, err
+	}
+	if challenge != nil {
+		return &ExternalLoginResult{Challenge: challenge}, nil
+	}
+	return &ExternalLoginResult{User: user, Tokens: tokens}, nil
+}
+
+// ListIdentities returns the provider accounts the user can sign in with.
+func (s *Service) ListIdentities(ctx context.Context, userID uuid.UUID) ([]*ExternalIdentity, error) {
+	return s.repo.ListIdentities(ctx, userID)
+}
+
+// UnlinkIdentity stops the provider account from signing the user in. A user
+// without a password must keep at least one provider.
+func (s *Service) UnlinkIdentity(ctx context.Context, userID uuid.UUID, provider string) error {
+	user, err := s.GetUser(ctx, userID)
+	if err != nil {
+		return err
+	}
+	if user.PasswordHash == "" {
+		identities, err := s.repo.ListIdentities(ctx, userID)
+		if err != nil {
+			return err
+		}
+		if len(identities) == 1 && identities[0].Provider == provider {
+			return ErrLastSignInMethod
+		}
+	}
+	if err := s.repo.DeleteIdentity(ctx, userID, provider); err != nil {
+		return err
+	}
+	s.logger.Info("identity unlinked", "user_id", userID, "provider", provider)
+	return nil
+}
+
+// CreatePersonalAccessToken creates a token limited to scopes and returns it
+// with the raw token, which is never shown again. A zero lifetime makes a
+// token that works until it is revoked.
+func (s *Service) CreatePersonalAccessToken(ctx context.Context, userID uuid.UUID, name string, scopes []string, lifetime time.Duration) (*PersonalAccessToken, string, error) {
+	name = strings.TrimSpace(name)
+	if name == "" || utf8.RuneCountInString(name) > maxTokenNameLength {
+		return nil, "", ErrInvalidTokenName
+	}
+	scopes, err := normalizeScopes(scopes)
+	if err != nil {
+		return nil, "", err
+	}
+	if lifetime < 0 || lifetime > maxTokenLifetime {
+		return nil, "", ErrInvalidTokenLifetime
+	}
+
+	id, err := uuid.NewV7()
+	if err != nil {
+		return nil, "", err
+	}
+	rawToken, tokenHash, err := NewPersonalAccessToken()
+	if err != nil {
+		return nil, "", err
+	}
+	token := PersonalAccessToken{
+		ID:        id,
+		UserID:    userID,
+		Name:      name,
+		TokenHash: tokenHash,
+		Scopes:    scopes,
+	}
+	if lifetime > 0 {
+		expiresAt := s.now().Add(lifetime).UTC()
+		token.ExpiresAt = &expiresAt
+	}
+	created, err := s.repo.CreatePersonalAccessToken(ctx, token)
+	if err != nil {
+		return nil, "", err
+	}
+	s.logger.Info("personal access token created", "user_id", userID, "token_id", id, "scopes", scopes)
+	return created, rawToken, nil
+}
+
+// AuthenticatePersonalAccessToken returns what a personal access token lets
+// the request do, and records that the client used it.
+func (s *Service) AuthenticatePersonalAccessToken(ctx context.Context, rawToken string, client Client) (*TokenGrant, error) {
+	grant, err := s.repo.FindPersonalAccessToken(ctx, HashRefreshToken(rawToken))
+	if err != nil {
+		return nil, err
+	}
+	if grant == nil {
+		return nil, ErrInvalidToken
+	}
+	if err := s.repo.TouchPersonalAccessToken(ctx, grant.TokenID, client.IPAddress); err != nil {
+		s.logger.Warn("failed to record personal access token use", "error", err, "token_id", grant.TokenID)
+	}
+	return grant, nil
+}
+
+// ListPersonalAccessTokens returns the user's unrevoked tokens, newest first.
+// Expired tokens stay listed so their owner can see why a script stopped
+// working.
+func (s *Service) ListPersonalAccessTokens(ctx context.Context, userID uuid.UUID) ([]*PersonalAccessToken, error) {
+	return s.repo.ListPersonalAccessTokens(ctx, userID)
+}
+
+func (s *Service) RevokePersonalAccessToken(ctx context.Context, userID, tokenID uuid.UUID) error {
+	if err := s.repo.RevokePersonalAccessToken(ctx, userID, tokenID); err != nil {
+		return err
+	}
+	s.logger.Info("personal access token revoked", "user_id", userID, "token_id", tokenID)
+	return nil
+}
+
+// SetRole changes a user's role. It takes effect when their next access token
+// is issued.
+func (s *Service) SetRole(ctx context.Context, userID uuid.UUID, role Role) error {
+	if !validRole(role) {
+		return ErrInvalidRole
+	}
+	if err := s.repo.UpdateUserRole(ctx, userID, role); err != nil {
+		if !errors.Is(err, ErrUserNotFound) {
+			s.logger.Error("failed to update role", "error", err, "user_id", userID)
+		}
+		return err
+	}
+	s.logger.Info("user role updated", "user_id", userID, "role", role)
+	return nil
+}
+
 func (s *Service) VerifyAccessToken(rawToken string) (*AccessClaims, error) {
 	return s.tokens.VerifyAccessToken(rawToken)
 }
 
-func (s *Service) issueTokens(ctx context.Context, user User) (AuthTokens, error) {
-	accessToken, err := s.tokens.CreateAccessToken(user)
+// signIn issues tokens for a user who has proved who they are, or a
+// challenge for CompleteMFALogin when they have a second factor.
+func (s *Service) signIn(ctx context.Context, user User, client Client) (AuthTokens, *MFAChallenge, error) {
+	credential, err := s.repo.GetTOTP(ctx, user.ID)
+	if err != nil {
+		return AuthTokens{}, nil, err
+	}
+	if credential != nil && credential.ConfirmedAt != nil {
+		token, err := s.issueUserToken(ctx, user.ID, PurposeMFALogin, mfaChallengeTTL)
+		if err != nil {
+			return AuthTokens{}, nil, err
+		}
+		return AuthTokens{}, &MFAChallenge{Token: token, ExpiresIn: int64(mfaChallengeTTL.Seconds())}, nil
+	}
+
+	tokens, err := s.issueTokens(ctx, user, client)
+	if err != nil {
+		return AuthTokens{}, nil, err
+	}
+	return tokens, nil, nil
+}
+
+// issueTokens starts a new session for the client with a fresh refresh token
+// family.
+func (s *Service) issueTokens(ctx context.Context, user User, client Client) (AuthTokens, error) {
+	familyID, err := uuid.NewV7()
 	if err != nil {
 		return AuthTokens{}, err
 	}
-	refreshToken, refreshHash, err := NewRefreshToken()
+	accessToken, err := s.tokens.CreateAccessToken(user, familyID)
 	if err != nil {
 		return AuthTokens{}, err
 	}
-	refreshID, err := uuid.NewV7()
+	refreshToken, refreshHash, err := NewRefreshToken()
 	if err != nil {
 		return AuthTokens{}, err
 	}
-	familyID, err := uuid.NewV7()
+	refreshID, err := uuid.NewV7()
 	if err != nil {
 		return AuthTokens{}, err
 	}
 
-	if err := s.repo.CreateRefreshToken(ctx, RefreshToken{
+	if err := s.repo.CreateSession(ctx, client, RefreshToken{
 		ID:        refreshID,
 		UserID:    user.ID,
 		TokenHash: refreshHash,
@@ -196,7 +895,273 @@ func (s *Service) issueTokens(ctx context.Context, user User) (AuthTokens, error
 	return AuthTokens{AccessToken: accessToken, RefreshToken: refreshToken, TokenType: "Bearer", ExpiresIn: int64(s.tokens.accessTTL.Seconds())}, nil
 }
 
+// verifySecondFactor accepts a current code from the authenticator app or an
+// unused recovery code, using it up either way.
+func (s *Service) verifySecondFactor(ctx context.Context, userID uuid.UUID, code string) (bool, error) {
+	code = strings.TrimSpace(code)
+	if !isTOTPCode(code) {
+		return s.repo.UseRecoveryCode(ctx, userID, hashRecoveryCode(code))
+	}
+
+	credential, err := s.repo.GetTOTP(ctx, userID)
+	if err != nil || credential == nil || credential.ConfirmedAt == nil {
+		return false, err
+	}
+	secret, err := s.secrets.Open(userID, credential.Secret)
+	if err != nil {
+		// A changed encryption key makes every stored secret unreadable;
+		// recovery codes still work.
+		s.logger.Error("failed to decrypt TOTP secret", "error", err, "user_id", userID)
+		return false, nil
+	}
+	step, ok := matchTOTP(secret, code, s.now(), credential.LastUsedStep)
+	if !ok {
+		return false, nil
+	}
+	return s.repo.UseTOTPStep(ctx, userID, step)
+}
+
+// reauthenticate checks that the provider account is one the user linked and
+// issues a token confirming one sensitive change.
+func (s *Service) reauthenticate(ctx context.Context, userID uuid.UUID, provider string, identity oidc.Identity) (string, error) {
+	linkedID, err := s.repo.UseIdentity(ctx, provider, identity)
+	if err != nil {
+		return "", err
+	}
+	if linkedID == nil || *linkedID != userID {
+		return "", ErrWrongIdentity
+	}
+	return s.issueUserToken(ctx, userID, PurposeReauthenticate, reauthTTL)
+}
+
+// externalUser finds or creates the user for a provider account, as
+// described on CompleteExternalLogin.
+func (s *Service) externalUser(ctx context.Context, provider string, identity oidc.Identity) (*User, error) {
+	userID, err := s.repo.UseIdentity(ctx, provider, identity)
+	if err != nil {
+		return nil, err
+	}
+	if userID != nil {
+		return s.GetUser(ctx, *userID)
+	}
+
+	if !validEmail(identity.Email) {
+		return nil, fmt.Errorf("%w: provider did not share an email address", ErrExternalLoginFailed)
+	}
+	existing, err := s.repo.GetUserByEmailOrUsername(ctx, identity.Email)
+	if err != nil {
+		return nil, err
+	}
+	if existing != nil && existing.Email == identity.Email {
+		if !identity.EmailVerified || existing.EmailVerifiedAt == nil {
+			return nil, ErrIdentityConflict
+		}
+		if err := s.repo.LinkIdentity(ctx, existing.ID, provider, identity); err != nil {
+			return nil, err
+		}
+		s.logger.Info("identity linked by email", "user_id", existing.ID, "provider", provider)
+		return existing, nil
+	}
+
+	username, err := s.availableUsername(ctx, identity)
+	if err != nil {
+		return nil, err
+	}
+	id, err := uuid.NewV7()
+	if err != nil {
+		return nil, err
+	}
+	user, err := s.repo.CreateUserWithIdentity(ctx, User{ID: id, Email: identity.Email, Username: username}, provider, identity)
+	if err != nil {
+		return nil, err
+	}
+	s.logger.Info("user created from identity", "user_id", user.ID, "provider", provider)
+	if user.EmailVerifiedAt == nil {
+		s.verifyInBackground(ctx, *user)
+	}
+	return user, nil
+}
+
+// linkIdentity adds a provider account to a signed-in user. Linking an
+// account the user already has is a no-op.
+func (s *Service) linkIdentity(ctx context.Context, userID uuid.UUID, provider string, identity oidc.Identity) (*User, error) {
+	user, err := s.GetUser(ctx, userID)
+	if err != nil {
+		return nil, err
+	}
+	linkedTo, err := s.repo.UseIdentity(ctx, provider, identity)
+	if err != nil {
+		return nil, err
+	}
+	if linkedTo != nil && *linkedTo != userID {
+		return nil, ErrIdentityInUse
+	}
+	if linkedTo == nil {
+		if err := s.repo.LinkIdentity(ctx, userID, provider, identity); err != nil {
+			return nil, err
+		}
+		s.logger.Info("identity linked", "user_id", userID, "provider", provider)
+	}
+	return user, nil
+}
+
+// availableUsername derives a free username from the provider's preferred
+// username or the email's local part, adding a random suffix when it is
+// taken.
+func (s *Service) availableUsername(ctx context.Context, identity oidc.Identity) (string, error) {
+	base := "reader"
+	local, _, _ := strings.Cut(identity.Email, "@")
+	for _, candidate := range []string{identity.PreferredUsername, local} {
+		if candidate = usernameFrom(candidate); candidate != "" {
+			base = candidate
+			break
+		}
+	}
+
+	candidate := base
+	for range 5 {
+		existing, err := s.repo.GetUserByEmailOrUsername(ctx, candidate)
+		if err != nil {
+			return "", err
+		}
+		if existing == nil {
+			return candidate, nil
+		}
+		suffix := make([]byte, 2)
+		if _, err := rand.Read(suffix); err != nil {
+			return "", err
+		}
+		candidate = fmt.Sprintf("%.27s_%x", base, suffix)
+	}
+	return "", fmt.Errorf("%w: no username available for %q", ErrExternalLoginFailed, base)
+}
+
+// usernameFrom turns a name into a valid username, or "" when too little of
+// it is usable.
+func usernameFrom(name string) string {
+	username := strings.Map(func(r rune) rune {
+		switch {
+		case r >= 'a' && r <= 'z', r >= '0' && r <= '9', r == '_', r == '-':
+			return r
+		case r == '.' || r == ' ':
+			return '_'
+		default:
+			return -1
+		}
+	}, strings.ToLower(strings.TrimSpace(name)))
+	username = strings.TrimLeft(username, "-")
+	if len(username) > 32 {
+		username = username[:32]
+	}
+	if !usernamePattern.MatchString(username) {
+		return ""
+	}
+	return username
+}
+
+// confirm re-authenticates the user with the token of a fresh provider
+// sign-in, using it up, or else with their password.
+func (s *Service) confirm(ctx context.Context, userID uuid.UUID, confirmation Confirmation) error {
+	user, err := s.GetUser(ctx, userID)
+	if err != nil {
+		return err
+	}
+	if confirmation.ReauthToken != "" {
+		confirmed, err := s.repo.ConsumeUserToken(ctx, HashRefreshToken(confirmation.ReauthToken), PurposeReauthenticate)
+		if err != nil {
+			return err
+		}
+		if confirmed == nil || *confirmed != user.ID {
+			return ErrInvalidCredentials
+		}
+		return nil
+	}
+	if user.PasswordHash == "" {
+		return ErrInvalidCredentials
+	}
+	valid, err := VerifyPassword(confirmation.Password, user.PasswordHash)
+	if err != nil || !valid {
+		return ErrInvalidCredentials
+	}
+	return nil
+}
+
+// verifyInBackground mails a new user their verification link without
+// holding up the sign-up response.
+func (s *Service) verifyInBackground(ctx context.Context, user User) {
+	s.background(ctx, "failed to send verification email", func(ctx context.Context) error {
+		if err := s.sendVerification(ctx, user); err != nil {
+			return fmt.Errorf("user %s: %w", user.ID, err)
+		}
+		return nil
+	})
+}
+
+func (s *Service) sendVerification(ctx context.Context, user User) error {
+	token, err := s.issueUserToken(ctx, user.ID, PurposeVerifyEmail, s.emails.VerifyEmailTTL)
+	if err != nil {
+		return err
+	}
+	return s.mailer.Send(ctx, verifyEmailMessage(user, s.emails.link("/verification", token), s.emails.VerifyEmailTTL))
+}
+
+// issueUserToken stores the hash of a new random token and returns the token
+// itself for the emailed link or sign-in challenge. The tokens are made like
+// refresh tokens.
+func (s *Service) issueUserToken(ctx context.Context, userID uuid.UUID, purpose TokenPurpose, ttl time.Duration) (string, error) {
+	token, tokenHash, err := NewRefreshToken()
+	if err != nil {
+		return "", err
+	}
+	id, err := uuid.NewV7()
+	if err != nil {
+		return "", err
+	}
+	if err := s.repo.CreateUserToken(ctx, UserToken{
+		ID:        id,
+		UserID:    userID,
+		Purpose:   purpose,
+		TokenHash: tokenHash,
+		ExpiresAt: s.now().UTC().Add(ttl),
+	}); err != nil {
+		return "", err
+	}
+	return token, nil
+}
+
+// recoveryCodes returns new recovery codes for the user and the hashes to
+// store.
+func recoveryCodes() ([]string, [][]byte, error) {
+	codes, err := newRecoveryCodes()
+	if err != nil {
+		return nil, nil, err
+	}
+	hashes := make([][]byte, len(codes))
+	for i, code := range codes {
+		hashes[i] = hashRecoveryCode(code)
+	}
+	return codes, hashes, nil
+}
+
+// normalizeScopes checks every scope and returns them sorted without
+// duplicates.
+func normalizeScopes(scopes []string) ([]string, error) {
+	normalized := make([]string, 0, len(scopes))
+	for _, scope := range scopes {
+		scope = strings.ToLower(strings.TrimSpace(scope))
+		if !validScope(scope) {
+			return nil, ErrInvalidScope
+		}
+		normalized = append(normalized, scope)
+	}
+	if len(normalized) == 0 {
+		return nil, ErrInvalidScope
+	}
+	slices.Sort(normalized)
+	return slices.Compact(normalized), nil
+}
+
 func validEmail(email string) bool {
-	_, err := mail.ParseAddress(email)
+	_, err := netmail.ParseAddress(email)
 	return err == nil
 }
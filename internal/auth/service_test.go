@@ -0,0 +1,1014 @@
+package auth
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"io"
+	"log/slog"
+	"net/url"
+	"regexp"
+	"slices"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/gofrs/uuid/v5"
+	"github.com/zizouhuweidi/maktaba/internal/mail"
+	"github.com/zizouhuweidi/maktaba/internal/oidc"
+)
+
+func TestRegisterSendsVerificationLink(t *testing.T) {
+	repo := &fakeAuthRepository{}
+	mailer := &recordingMailer{}
+	service := newTestService(t, repo, mailer)
+
+	user, _, err := service.Register(context.Background(), "Reader@Example.com", "reader", "correct horse battery", Client{})
+	if err != nil {
+		t.Fatalf("Register() error = %v", err)
+	}
+	service.jobs.Wait()
+
+	if len(mailer.sent) != 1 || mailer.sent[0].To != "reader@example.com" {
+		t.Fatalf("sent = %+v, want one email to reader@example.com", mailer.sent)
+	}
+	token := linkToken(t, mailer.sent[0].Body, "https://maktaba.example/verification")
+	if repo.token == nil {
+		t.Fatal("no token stored")
+	}
+	if repo.token.UserID != user.ID || repo.token.Purpose != PurposeVerifyEmail {
+		t.Fatalf("stored token = %+v", repo.token)
+	}
+	if !bytes.Equal(repo.token.TokenHash, HashRefreshToken(token)) {
+		t.Fatal("stored hash does not match the emailed token")
+	}
+	if ttl := time.Until(repo.token.ExpiresAt); ttl < 47*time.Hour || ttl > 48*time.Hour {
+		t.Fatalf("token expires in %s, want 48h", ttl)
+	}
+}
+
+func TestVerifyEmailRejectsUnknownToken(t *testing.T) {
+	service := newTestService(t, &fakeAuthRepository{}, &recordingMailer{})
+
+	if err := service.VerifyEmail(context.Background(), "nope"); !errors.Is(err, ErrInvalidToken) {
+		t.Fatalf("error = %v, want %v", err, ErrInvalidToken)
+	}
+}
+
+func TestSendVerificationEmailWhenAlreadyVerified(t *testing.T) {
+	verifiedAt := time.Now()
+	user := &User{ID: mustTestUUID(t), Email: "reader@example.com", Username: "reader", EmailVerifiedAt: &verifiedAt}
+	mailer := &recordingMailer{}
+	service := newTestService(t, &fakeAuthRepository{user: user}, mailer)
+
+	if err := service.SendVerificationEmail(context.Background(), user.ID); !errors.Is(err, ErrAlreadyVerified) {
+		t.Fatalf("error = %v, want %v", err, ErrAlreadyVerified)
+	}
+	if len(mailer.sent) != 0 {
+		t.Fatalf("sent %d emails, want none", len(mailer.sent))
+	}
+}
+
+func TestForgotPasswordSendsResetLink(t *testing.T) {
+	user := &User{ID: mustTestUUID(t), Email: "reader@example.com", Username: "reader"}
+	repo := &fakeAuthRepository{user: user}
+	mailer := &recordingMailer{}
+	service := newTestService(t, repo, mailer)
+
+	service.ForgotPassword(context.Background(), " Reader@example.com ")
+	service.jobs.Wait()
+	if len(mailer.sent) != 1 {
+		t.Fatalf("sent %d emails, want 1", len(mailer.sent))
+	}
+	token := linkToken(t, mailer.sent[0].Body, "https://maktaba.example/recovery")
+	if repo.token == nil || repo.token.Purpose != PurposeResetPassword || !bytes.Equal(repo.token.TokenHash, HashRefreshToken(token)) {
+		t.Fatalf("stored token = %+v", repo.token)
+	}
+}
+
+func TestForgotPasswordIgnoresUnknownEmail(t *testing.T) {
+	user := &User{ID: mustTestUUID(t), Email: "reader@example.com", Username: "reader"}
+	mailer := &recordingMailer{}
+	service := newTestService(t, &fakeAuthRepository{user: user}, mailer)
+
+	for _, email := range []string{"someone@example.com", "reader", "not an email"} {
+		service.ForgotPassword(context.Background(), email)
+	}
+	service.jobs.Wait()
+	if len(mailer.sent) != 0 {
+		t.Fatalf("sent %d emails, want none", len(mailer.sent))
+	}
+}
+
+func TestForgotPasswordMailsOutsideTheRequest(t *testing.T) {
+	user := &User{ID: mustTestUUID(t), Email: "reader@example.com", Username: "reader"}
+	mailer := &blockingMailer{release: make(chan struct{})}
+	service := newTestService(t, &fakeAuthRepository{user: user}, mailer)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	service.ForgotPassword(ctx, user.Email)
+	cancel()
+	close(mailer.release)
+	service.jobs.Wait()
+
+	if mailer.err != nil {
+		t.Fatalf("mail context error = %v, want the job to outlive the request", mailer.err)
+	}
+}
+
+func TestWaitForBackgroundJobs(t *testing.T) {
+	mailer := &blockingMailer{release: make(chan struct{})}
+	service := newTestService(t, &fakeAuthRepository{}, mailer)
+
+	if _, _, err := service.Register(context.Background(), "reader@example.com", "reader", "correct horse battery", Client{}); err != nil {
+		t.Fatalf("Register() error = %v", err)
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Millisecond)
+	defer cancel()
+	if err := service.Wait(ctx); !errors.Is(err, context.DeadlineExceeded) {
+		t.Fatalf("Wait() with mail pending error = %v, want %v", err, context.DeadlineExceeded)
+	}
+
+	close(mailer.release)
+	if err := service.Wait(context.Background()); err != nil {
+		t.Fatalf("Wait() error = %v", err)
+	}
+}
+
+func TestResetPassword(t *testing.T) {
+	repo := &fakeAuthRepository{resetUserID: mustTestUUID(t)}
+	service := newTestService(t, repo, &recordingMailer{})
+
+	if err := service.ResetPassword(context.Background(), "token", "short"); !errors.Is(err, ErrInvalidPassword) {
+		t.Fatalf("error = %v, want %v", err, ErrInvalidPassword)
+	}
+	if repo.passwordHash != "" {
+		t.Fatal("short password reached the repository")
+	}
+
+	if err := service.ResetPassword(context.Background(), "token", "a much longer password"); err != nil {
+		t.Fatalf("ResetPassword() error = %v", err)
+	}
+	if !bytes.Equal(repo.tokenHash, HashRefreshToken("token")) {
+		t.Fatal("reset looked up the wrong token hash")
+	}
+	if valid, err := VerifyPassword("a much longer password", repo.passwordHash); err != nil || !valid {
+		t.Fatalf("stored hash does not verify: %v", err)
+	}
+
+	repo.resetUserID = uuid.Nil
+	if err := service.ResetPassword(context.Background(), "used", "a much longer password"); !errors.Is(err, ErrInvalidToken) {
+		t.Fatalf("error = %v, want %v", err, ErrInvalidToken)
+	}
+}
+
+func TestLoginStartsSession(t *testing.T) {
+	passwordHash, err := HashPassword("correct horse battery")
+	if err != nil {
+		t.Fatalf("hash password: %v", err)
+	}
+	repo := &fakeAuthRepository{user: &User{ID: mustTestUUID(t), Email: "reader@example.com", Username: "reader", PasswordHash: passwordHash}}
+	service := newTestService(t, repo, &recordingMailer{})
+
+	client := Client{UserAgent: "Firefox", IPAddress: "192.0.2.7"}
+	_, tokens, challenge, err := service.Login(context.Background(), "reader", "correct horse battery", client)
+	if err != nil {
+		t.Fatalf("Login() error = %v", err)
+	}
+	if challenge != nil {
+		t.Fatal("Login() returned a challenge for an account without two-factor authentication")
+	}
+	if repo.client == nil || *repo.client != client {
+		t.Fatalf("session client = %+v, want %+v", repo.client, client)
+	}
+	claims, err := service.VerifyAccessToken(tokens.AccessToken)
+	if err != nil {
+		t.Fatalf("VerifyAccessToken() error = %v", err)
+	}
+	if claims.SessionID != repo.refresh.FamilyID.String() {
+		t.Fatalf("sid = %q, want the refresh token family %s", claims.SessionID, repo.refresh.FamilyID)
+	}
+}
+
+func TestSessionsKeepCurrent(t *testing.T) {
+	current, other := mustTestUUID(t), mustTestUUID(t)
+	repo := &fakeAuthRepository{sessions: []*Session{{ID: other}, {ID: current}}}
+	service := newTestService(t, repo, &recordingMailer{})
+	userID := mustTestUUID(t)
+
+	sessions, err := service.ListSessions(context.Background(), userID, &current)
+	if err != nil {
+		t.Fatalf("ListSessions() error = %v", err)
+	}
+	if sessions[0].Current || !sessions[1].Current {
+		t.Fatalf("current flags = %v, %v; want only the second", sessions[0].Current, sessions[1].Current)
+	}
+
+	if _, err := service.RevokeOtherSessions(context.Background(), userID, &current); err != nil {
+		t.Fatalf("RevokeOtherSessions() error = %v", err)
+	}
+	if repo.kept == nil || *repo.kept != current {
+		t.Fatalf("kept = %v, want %s", repo.kept, current)
+	}
+}
+
+func TestTwoFactorLogin(t *testing.T) {
+	service, repo, clock := newTwoFactorTestService(t)
+	secret, _ := enableTestTOTP(t, service, repo)
+	ctx := context.Background()
+
+	user, _, challenge, err := service.Login(ctx, "reader", "correct horse battery", Client{})
+	if err != nil {
+		t.Fatalf("Login() error = %v", err)
+	}
+	if user != nil || challenge == nil || repo.refresh != nil {
+		t.Fatalf("Login() = %v, %+v; want only a challenge", user, challenge)
+	}
+	if repo.token.Purpose != PurposeMFALogin || repo.token.ExpiresAt.Sub(clock.now) != mfaChallengeTTL {
+		t.Fatalf("stored challenge = %+v", repo.token)
+	}
+
+	if _, _, err := service.CompleteMFALogin(ctx, challenge.Token, "bad-code", Client{}); !errors.Is(err, ErrInvalidMFACode) {
+		t.Fatalf("error = %v, want %v", err, ErrInvalidMFACode)
+	}
+	// The code that confirmed enrollment cannot be replayed.
+	used := hotp(secret, uint64(totpStep(clock.now)), totpDigits)
+	if _, _, err := service.CompleteMFALogin(ctx, challenge.Token, used, Client{}); !errors.Is(err, ErrInvalidMFACode) {
+		t.Fatalf("replayed code: error = %v, want %v", err, ErrInvalidMFACode)
+	}
+
+	clock.now = clock.now.Add(totpPeriod)
+	code := hotp(secret, uint64(totpStep(clock.now)), totpDigits)
+	user, tokens, err := service.CompleteMFALogin(ctx, challenge.Token, code, Client{UserAgent: "Firefox"})
+	if err != nil {
+		t.Fatalf("CompleteMFALogin() error = %v", err)
+	}
+	if user == nil || tokens.AccessToken == "" || repo.refresh == nil || repo.client.UserAgent != "Firefox" {
+		t.Fatalf("CompleteMFALogin() = %v, %+v; want a new session", user, tokens)
+	}
+
+	if _, _, err := service.CompleteMFALogin(ctx, challenge.Token, code, Client{}); !errors.Is(err, ErrInvalidToken) {
+		t.Fatalf("reused challenge: error = %v, want %v", err, ErrInvalidToken)
+	}
+}
+
+func TestTwoFactorRecoveryCode(t *testing.T) {
+	service, repo, _ := newTwoFactorTestService(t)
+	_, codes := enableTestTOTP(t, service, repo)
+	ctx := context.Background()
+
+	_, _, challenge, err := service.Login(ctx, "reader", "correct horse battery", Client{})
+	if err != nil {
+		t.Fatalf("Login() error = %v", err)
+	}
+	if _, _, err := service.CompleteMFALogin(ctx, challenge.Token, strings.ToUpper(codes[0]), Client{}); err != nil {
+		t.Fatalf("CompleteMFALogin() error = %v", err)
+	}
+
+	_, _, challenge, err = service.Login(ctx, "reader", "correct horse battery", Client{})
+	if err != nil {
+		t.Fatalf("Login() error = %v", err)
+	}
+	if _, _, err := service.CompleteMFALogin(ctx, challenge.Token, codes[0], Client{}); !errors.Is(err, ErrInvalidMFACode) {
+		t.Fatalf("reused recovery code: error = %v, want %v", err, ErrInvalidMFACode)
+	}
+
+	status, err := service.MFAStatus(ctx, repo.user.ID)
+	if err != nil {
+		t.Fatalf("MFAStatus() error = %v", err)
+	}
+	if !status.Enabled || status.RecoveryCodesRemaining != recoveryCodeCount-1 {
+		t.Fatalf("status = %+v", status)
+	}
+}
+
+func TestTwoFactorAttemptsAreLimited(t *testing.T) {
+	service, repo, clock := newTwoFactorTestService(t)
+	secret, _ := enableTestTOTP(t, service, repo)
+	ctx := context.Background()
+
+	_, _, challenge, err := service.Login(ctx, "reader", "correct horse battery", Client{})
+	if err != nil {
+		t.Fatalf("Login() error = %v", err)
+	}
+	clock.now = clock.now.Add(totpPeriod)
+	for range mfaAttemptLimit {
+		if _, _, err := service.CompleteMFALogin(ctx, challenge.Token, "wrong", Client{}); !errors.Is(err, ErrInvalidMFACode) {
+			t.Fatalf("error = %v, want %v", err, ErrInvalidMFACode)
+		}
+	}
+	code := hotp(secret, uint64(totpStep(clock.now)), totpDigits)
+	if _, _, err := service.CompleteMFALogin(ctx, challenge.Token, code, Client{}); !errors.Is(err, ErrTooManyAttempts) {
+		t.Fatalf("error = %v, want %v", err, ErrTooManyAttempts)
+	}
+
+	clock.now = clock.now.Add(mfaAttemptReset + time.Second)
+	code = hotp(secret, uint64(totpStep(clock.now)), totpDigits)
+	if _, _, err := service.CompleteMFALogin(ctx, challenge.Token, code, Client{}); err != nil {
+		t.Fatalf("CompleteMFALogin() after the window error = %v", err)
+	}
+}
+
+func TestDisableTOTPNeedsPassword(t *testing.T) {
+	service, repo, _ := newTwoFactorTestService(t)
+	enableTestTOTP(t, service, repo)
+	ctx := context.Background()
+
+	if err := service.DisableTOTP(ctx, repo.user.ID, Confirmation{Password: "wrong password"}); !errors.Is(err, ErrInvalidCredentials) {
+		t.Fatalf("error = %v, want %v", err, ErrInvalidCredentials)
+	}
+	if err := service.DisableTOTP(ctx, repo.user.ID, Confirmation{Password: "correct horse battery"}); err != nil {
+		t.Fatalf("DisableTOTP() error = %v", err)
+	}
+	if _, tokens, challenge, err := service.Login(ctx, "reader", "correct horse battery", Client{}); err != nil || challenge != nil || tokens.AccessToken == "" {
+		t.Fatalf("Login() after disabling = %+v, %v", challenge, err)
+	}
+}
+
+func TestExternalLoginCreatesUser(t *testing.T) {
+	repo := &fakeAuthRepository{}
+	service := newTestService(t, repo, &recordingMailer{})
+	provider := useStubProvider(service, oidc.Identity{Subject: "sub-1", Email: "Battuta@Example.com", EmailVerified: true, PreferredUsername: "Ibn.Battuta"})
+	ctx := context.Background()
+
+	result, state := completeStubLogin(t, service, provider, nil)
+	if result.User == nil || result.Tokens.AccessToken == "" || result.Challenge != nil || result.Linked {
+		t.Fatalf("result = %+v, want a signed-in user", result)
+	}
+	if result.User.Username != "ibn_battuta" || result.User.Email != "battuta@example.com" || result.User.EmailVerifiedAt == nil || result.User.PasswordHash != "" {
+		t.Fatalf("user = %+v", result.User)
+	}
+	if _, err := service.CompleteExternalLogin(ctx, "stub", state, "code", Client{}); !errors.Is(err, ErrInvalidToken) {
+		t.Fatalf("reused state: error = %v, want %v", err, ErrInvalidToken)
+	}
+
+	again, _ := completeStubLogin(t, service, provider, nil)
+	if again.User == nil || again.User.ID != result.User.ID {
+		t.Fatalf("second sign-in user = %+v, want %s", again.User, result.User.ID)
+	}
+}
+
+func TestExternalLoginMatchesEmail(t *testing.T) {
+	for name, test := range map[string]struct {
+		localVerified, providerVerified bool
+		wantErr                         error
+	}{
+		"both verified":         {localVerified: true, providerVerified: true},
+		"unverified locally":    {providerVerified: true, wantErr: ErrIdentityConflict},
+		"unverified at the IdP": {localVerified: true, wantErr: ErrIdentityConflict},
+	} {
+		t.Run(name, func(t *testing.T) {
+			user := &User{ID: mustTestUUID(t), Email: "reader@example.com", Username: "reader"}
+			if test.localVerified {
+				verifiedAt := time.Now()
+				user.EmailVerifiedAt = &verifiedAt
+			}
+			repo := &fakeAuthRepository{user: user}
+			service := newTestService(t, repo, &recordingMailer{})
+			provider := useStubProvider(service, oidc.Identity{Subject: "sub-1", Email: "reader@example.com", EmailVerified: test.providerVerified})
+
+			authURL, state, err := service.StartExternalLogin(context.Background(), "stub", nil)
+			if err != nil || authURL == "" {
+				t.Fatalf("StartExternalLogin() = %q, %v", authURL, err)
+			}
+			result, err := service.CompleteExternalLogin(context.Background(), "stub", state, provider.code, Client{})
+			if !errors.Is(err, test.wantErr) {
+				t.Fatalf("error = %v, want %v", err, test.wantErr)
+			}
+			if test.wantErr == nil && (result.User.ID != user.ID || repo.identities["stub:sub-1"] != user.ID) {
+				t.Fatalf("signed in %+v, want the existing user linked", result.User)
+			}
+		})
+	}
+}
+
+func TestExternalLoginNeedsSecondFactor(t *testing.T) {
+	service, repo, _ := newTwoFactorTestService(t)
+	enableTestTOTP(t, service, repo)
+	provider := useStubProvider(service, oidc.Identity{Subject: "sub-1"})
+	repo.identities = map[string]uuid.UUID{"stub:sub-1": repo.user.ID}
+
+	result, _ := completeStubLogin(t, service, provider, nil)
+	if result.Challenge == nil || result.Tokens.AccessToken != "" || repo.refresh != nil {
+		t.Fatalf("result = %+v, want only a challenge", result)
+	}
+}
+
+func TestLinkIdentity(t *testing.T) {
+	repo := &fakeAuthRepository{user: &User{ID: mustTestUUID(t), Email: "reader@example.com", Username: "reader"}}
+	service := newTestService(t, repo, &recordingMailer{})
+	provider := useStubProvider(service, oidc.Identity{Subject: "sub-1", Email: "someone-else@example.com"})
+	ctx := context.Background()
+
+	result, _ := completeStubLogin(t, service, provider, &repo.user.ID)
+	if !result.Linked || result.Tokens.AccessToken != "" || repo.identities["stub:sub-1"] != repo.user.ID {
+		t.Fatalf("result = %+v, want the provider linked without signing in", result)
+	}
+	if _, _, err := service.StartExternalLogin(ctx, "stub", &repo.user.ID); !errors.Is(err, ErrProviderAlreadyLinked) {
+		t.Fatalf("linking again: error = %v, want %v", err, ErrProviderAlreadyLinked)
+	}
+
+	// Without a password, the only linked provider is the only way in.
+	if err := service.UnlinkIdentity(ctx, repo.user.ID, "stub"); !errors.Is(err, ErrLastSignInMethod) {
+		t.Fatalf("error = %v, want %v", err, ErrLastSignInMethod)
+	}
+	repo.user.PasswordHash = "set"
+	if err := service.UnlinkIdentity(ctx, repo.user.ID, "stub"); err != nil {
+		t.Fatalf("UnlinkIdentity() error = %v", err)
+	}
+}
+
+func TestLinkIdentityOwnedByAnotherUser(t *testing.T) {
+	repo := &fakeAuthRepository{user: &User{ID: mustTestUUID(t), Email: "reader@example.com", Username: "reader"}}
+	repo.identities = map[string]uuid.UUID{"stub:sub-1": mustTestUUID(t)}
+	service := newTestService(t, repo, &recordingMailer{})
+	provider := useStubProvider(service, oidc.Identity{Subject: "sub-1"})
+
+	_, state, err := service.StartExternalLogin(context.Background(), "stub", &repo.user.ID)
+	if err != nil {
+		t.Fatalf("StartExternalLogin() error = %v", err)
+	}
+	if _, err := service.CompleteExternalLogin(context.Background(), "stub", state, provider.code, Client{}); !errors.Is(err, ErrIdentityInUse) {
+		t.Fatalf("error = %v, want %v", err, ErrIdentityInUse)
+	}
+}
+
+func TestDeleteAccountWithoutPassword(t *testing.T) {
+	repo := &fakeAuthRepository{user: &User{ID: mustTestUUID(t), Email: "reader@example.com", Username: "reader"}}
+	repo.identities = map[string]uuid.UUID{"stub:sub-1": repo.user.ID}
+	service := newTestService(t, repo, &recordingMailer{})
+	provider := useStubProvider(service, oidc.Identity{Subject: "sub-1"})
+	ctx := context.Background()
+
+	for _, password := range []string{"", "guessed password"} {
+		if err := service.DeleteAccount(ctx, repo.user.ID, Confirmation{Password: password}); !errors.Is(err, ErrInvalidCredentials) {
+			t.Fatalf("password %q: error = %v, want %v", password, err, ErrInvalidCredentials)
+		}
+	}
+
+	_, state, err := service.StartReauthentication(ctx, "stub", repo.user.ID)
+	if err != nil {
+		t.Fatalf("StartReauthentication() error = %v", err)
+	}
+	result, err := service.CompleteExternalLogin(ctx, "stub", state, provider.code, Client{})
+	if err != nil {
+		t.Fatalf("CompleteExternalLogin() error = %v", err)
+	}
+	if result.ReauthToken == "" || result.Tokens.AccessToken != "" || repo.refresh != nil {
+		t.Fatalf("result = %+v, want only a re-authentication token", result)
+	}
+
+	if err := service.DeleteAccount(ctx, repo.user.ID, Confirmation{ReauthToken: result.ReauthToken}); err != nil {
+		t.Fatalf("DeleteAccount() error = %v", err)
+	}
+	if repo.deleted == nil || *repo.deleted != repo.user.ID {
+		t.Fatalf("deleted = %v, want %s", repo.deleted, repo.user.ID)
+	}
+	if err := service.DisableTOTP(ctx, repo.user.ID, Confirmation{ReauthToken: result.ReauthToken}); !errors.Is(err, ErrInvalidCredentials) {
+		t.Fatalf("reused token: error = %v, want %v", err, ErrInvalidCredentials)
+	}
+}
+
+func TestReauthenticationNeedsTheUsersOwnAccount(t *testing.T) {
+	repo := &fakeAuthRepository{user: &User{ID: mustTestUUID(t), Email: "reader@example.com", Username: "reader"}}
+	repo.identities = map[string]uuid.UUID{"stub:sub-1": repo.user.ID, "stub:sub-2": mustTestUUID(t)}
+	service := newTestService(t, repo, &recordingMailer{})
+	provider := useStubProvider(service, oidc.Identity{Subject: "sub-2"})
+	ctx := context.Background()
+
+	_, state, err := service.StartReauthentication(ctx, "stub", repo.user.ID)
+	if err != nil {
+		t.Fatalf("StartReauthentication() error = %v", err)
+	}
+	if _, err := service.CompleteExternalLogin(ctx, "stub", state, provider.code, Client{}); !errors.Is(err, ErrWrongIdentity) {
+		t.Fatalf("error = %v, want %v", err, ErrWrongIdentity)
+	}
+
+	delete(repo.identities, "stub:sub-1")
+	if _, _, err := service.StartReauthentication(ctx, "stub", repo.user.ID); !errors.Is(err, ErrIdentityNotFound) {
+		t.Fatalf("unlinked provider: error = %v, want %v", err, ErrIdentityNotFound)
+	}
+}
+
+func TestUsernameFrom(t *testing.T) {
+	for name, want := range map[string]string{
+		"Ibn.Battuta":                          "ibn_battuta",
+		"-al-idrisi":                           "al-idrisi",
+		"ab":                                   "",
+		"ابن سينا":                             "",
+		"a-very-long-name-that-goes-on-and-on": "a-very-long-name-that-goes-on-an",
+	} {
+		if got := usernameFrom(name); got != want {
+			t.Errorf("usernameFrom(%q) = %q, want %q", name, got, want)
+		}
+	}
+}
+
+// stubIdentityProvider signs everyone in as identity, checking that the
+// exchange carries what the authorization request was made with.
+type stubIdentityProvider struct {
+	identity oidc.Identity
+	code     string
+	nonce    string
+	verifier string
+}
+
+func (p *stubIdentityProvider) AuthCodeURL(_ context.Context, state, nonce, codeVerifier string) (string, error) {
+	p.nonce, p.verifier = nonce, codeVerifier
+	return "https://idp.example/authorize?state=" + url.QueryEscape(state), nil
+}
+
+func (p *stubIdentityProvider) Exchange(_ context.Context, code, codeVerifier, nonce string) (*oidc.Identity, error) {
+	if code != p.code || codeVerifier != p.verifier || nonce != p.nonce {
+		return nil, errors.New("invalid_grant")
+	}
+	identity := p.identity
+	return &identity, nil
+}
+
+func useStubProvider(service *Service, identity oidc.Identity) *stubIdentityProvider {
+	provider := &stubIdentityProvider{identity: identity, code: "code"}
+	service.providers = map[string]IdentityProvider{"stub": provider}
+	return provider
+}
+
+// completeStubLogin goes through the stub provider and returns the result
+// along with the state it used.
+func completeStubLogin(t *testing.T, service *Service, provider *stubIdentityProvider, linkUserID *uuid.UUID) (*ExternalLoginResult, string) {
+	t.Helper()
+	_, state, err := service.StartExternalLogin(context.Background(), "stub", linkUserID)
+	if err != nil {
+		t.Fatalf("StartExternalLogin() error = %v", err)
+	}
+	result, err := service.CompleteExternalLogin(context.Background(), "stub", state, provider.code, Client{})
+	if err != nil {
+		t.Fatalf("CompleteExternalLogin() error = %v", err)
+	}
+	return result, state
+}
+
+func TestPersonalAccessToken(t *testing.T) {
+	ctx := context.Background()
+	repo := &fakeAuthRepository{}
+	service := newTestService(t, repo, &recordingMailer{})
+	userID := mustTestUUID(t)
+
+	token, rawToken, err := service.CreatePersonalAccessToken(ctx, userID, " backup script ", []string{"notes:write", "Library:read", "notes:write"}, 30*24*time.Hour)
+	if err != nil {
+		t.Fatalf("CreatePersonalAccessToken() error = %v", err)
+	}
+	if !strings.HasPrefix(rawToken, personalAccessTokenPrefix) {
+		t.Fatalf("token %q lacks the %q prefix", rawToken, personalAccessTokenPrefix)
+	}
+	if !bytes.Equal(token.TokenHash, HashRefreshToken(rawToken)) {
+		t.Fatal("stored hash does not match the token")
+	}
+	if token.Name != "backup script" {
+		t.Fatalf("name = %q, want %q", token.Name, "backup script")
+	}
+	if want := []string{"library:read", "notes:write"}; !slices.Equal(token.Scopes, want) {
+		t.Fatalf("scopes = %v, want %v", token.Scopes, want)
+	}
+	if token.ExpiresAt == nil {
+		t.Fatal("token has no expiry")
+	}
+
+	grant, err := service.AuthenticatePersonalAccessToken(ctx, rawToken, Client{IPAddress: "203.0.113.7"})
+	if err != nil {
+		t.Fatalf("AuthenticatePersonalAccessToken() error = %v", err)
+	}
+	if grant.UserID != userID || grant.TokenID != token.ID || !slices.Equal(grant.Scopes, token.Scopes) {
+		t.Fatalf("grant = %+v, want the token's user and scopes", grant)
+	}
+	if !slices.Equal(repo.patUses, []string{"203.0.113.7"}) {
+		t.Fatalf("recorded uses = %v", repo.patUses)
+	}
+
+	if err := service.RevokePersonalAccessToken(ctx, mustTestUUID(t), token.ID); !errors.Is(err, ErrTokenNotFound) {
+		t.Fatalf("revoking another user's token: error = %v, want %v", err, ErrTokenNotFound)
+	}
+	if err := service.RevokePersonalAccessToken(ctx, userID, token.ID); err != nil {
+		t.Fatalf("RevokePersonalAccessToken() error = %v", err)
+	}
+	if _, err := service.AuthenticatePersonalAccessToken(ctx, rawToken, Client{}); !errors.Is(err, ErrInvalidToken) {
+		t.Fatalf("revoked token: error = %v, want %v", err, ErrInvalidToken)
+	}
+}
+
+func TestCreatePersonalAccessTokenValidates(t *testing.T) {
+	tests := []struct {
+		name      string
+		tokenName string
+		scopes    []string
+		lifetime  time.Duration
+		want      error
+	}{
+		{name: "blank name", tokenName: "  ", scopes: []string{"notes:read"}, want: ErrInvalidTokenName},
+		{name: "long name", tokenName: strings.Repeat("a", maxTokenNameLength+1), scopes: []string{"notes:read"}, want: ErrInvalidTokenName},
+		{name: "no scopes", tokenName: "script", want: ErrInvalidScope},
+		{name: "unknown resource", tokenName: "script", scopes: []string{"users:read"}, want: ErrInvalidScope},
+		{name: "unknown access", tokenName: "script", scopes: []string{"notes:admin"}, want: ErrInvalidScope},
+		{name: "bare resource", tokenName: "script", scopes: []string{"notes"}, want: ErrInvalidScope},
+		{name: "lifetime too long", tokenName: "script", scopes: []string{"notes:read"}, lifetime: maxTokenLifetime + time.Hour, want: ErrInvalidTokenLifetime},
+		{name: "negative lifetime", tokenName: "script", scopes: []string{"notes:read"}, lifetime: -time.Hour, want: ErrInvalidTokenLifetime},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			repo := &fakeAuthRepository{}
+			service := newTestService(t, repo, &recordingMailer{})
+
+			_, _, err := service.CreatePersonalAccessToken(context.Background(), mustTestUUID(t), tt.tokenName, tt.scopes, tt.lifetime)
+			if !errors.Is(err, tt.want) {
+				t.Fatalf("error = %v, want %v", err, tt.want)
+			}
+			if len(repo.pats) != 0 {
+				t.Fatal("invalid token reached the repository")
+			}
+		})
+	}
+}
+
+type fakeClock struct {
+	now time.Time
+}
+
+// newTwoFactorTestService returns a service for a user with a password whose
+// clock only moves when the test moves it.
+func newTwoFactorTestService(t *testing.T) (*Service, *fakeAuthRepository, *fakeClock) {
+	t.Helper()
+	passwordHash, err := HashPassword("correct horse battery")
+	if err != nil {
+		t.Fatalf("hash password: %v", err)
+	}
+	repo := &fakeAuthRepository{user: &User{ID: mustTestUUID(t), Email: "reader@example.com", Username: "reader", PasswordHash: passwordHash}}
+	service := newTestService(t, repo, &recordingMailer{})
+	clock := &fakeClock{now: time.Date(2026, 3, 1, 12, 0, 10, 0, time.UTC)}
+	service.now = func() time.Time { return clock.now }
+	return service, repo, clock
+}
+
+// enableTestTOTP enrolls the repository's user and returns the raw secret and
+// the recovery codes.
+func enableTestTOTP(t *testing.T, service *Service, repo *fakeAuthRepository) ([]byte, []string) {
+	t.Helper()
+	ctx := context.Background()
+	enrollment, err := service.StartTOTPEnrollment(ctx, repo.user.ID)
+	if err != nil {
+		t.Fatalf("StartTOTPEnrollment() error = %v", err)
+	}
+	secret, err := totpEncoding.DecodeString(enrollment.Secret)
+	if err != nil {
+		t.Fatalf("decode secret: %v", err)
+	}
+	if status, err := service.MFAStatus(ctx, repo.user.ID); err != nil || status.Enabled {
+		t.Fatalf("status before confirming = %+v, %v", status, err)
+	}
+
+	code := hotp(secret, uint64(totpStep(service.now())), totpDigits)
+	codes, err := service.ConfirmTOTPEnrollment(ctx, repo.user.ID, code)
+	if err != nil {
+		t.Fatalf("ConfirmTOTPEnrollment() error = %v", err)
+	}
+	if len(codes) != recoveryCodeCount {
+		t.Fatalf("got %d recovery codes, want %d", len(codes), recoveryCodeCount)
+	}
+	if _, err := service.StartTOTPEnrollment(ctx, repo.user.ID); !errors.Is(err, ErrMFAAlreadyEnabled) {
+		t.Fatalf("second enrollment: error = %v, want %v", err, ErrMFAAlreadyEnabled)
+	}
+	return secret, codes
+}
+
+func newTestService(t *testing.T, repo Repository, mailer mail.Mailer) *Service {
+	t.Helper()
+	tokens, err := NewTokenManager("test", "test", "", time.Minute)
+	if err != nil {
+		t.Fatalf("new token manager: %v", err)
+	}
+	secrets, err := NewSecretBox("")
+	if err != nil {
+		t.Fatalf("new secret box: %v", err)
+	}
+	return NewService(repo, tokens, secrets, mailer, nil, time.Hour, EmailSettings{
+		AppURL:           "https://maktaba.example/",
+		VerifyEmailTTL:   48 * time.Hour,
+		ResetPasswordTTL: time.Hour,
+	}, slog.New(slog.NewTextHandler(io.Discard, nil)))
+}
+
+// linkToken finds the link starting with prefix in an email body and returns
+// its token.
+func linkToken(t *testing.T, body, prefix string) string {
+	t.Helper()
+	link := regexp.MustCompile(regexp.QuoteMeta(prefix) + `\?token=\S+`).FindString(body)
+	if link == "" {
+		t.Fatalf("no %s link in:\n%s", prefix, body)
+	}
+	parsed, err := url.Parse(link)
+	if err != nil {
+		t.Fatalf("parse link: %v", err)
+	}
+	return parsed.Query().Get("token")
+}
+
+type recordingMailer struct {
+	sent []mail.Message
+}
+
+func (m *recordingMailer) Send(_ context.Context, message mail.Message) error {
+	m.sent = append(m.sent, message)
+	return nil
+}
+
+// blockingMailer holds each message until release is closed, then records
+// whether the sender's context had already ended.
+type blockingMailer struct {
+	release chan struct{}
+	err     error
+}
+
+func (m *blockingMailer) Send(ctx context.Context, _ mail.Message) error {
+	<-m.release
+	m.err = ctx.Err()
+	return nil
+}
+
+type fakeAuthRepository struct {
+	user         *User
+	token        *UserToken
+	resetUserID  uuid.UUID
+	tokenHash    []byte
+	passwordHash string
+	client       *Client
+	refresh      *RefreshToken
+	sessions     []*Session
+	kept         *uuid.UUID
+	totp         *TOTPCredential
+	recovery     map[string]bool
+	consumed     bool
+	login        *ExternalLogin
+	// identities maps "provider:subject" to the linked user.
+	identities map[string]uuid.UUID
+	pats       []*PersonalAccessToken
+	patUses    []string
+	deleted    *uuid.UUID
+}
+
+func (r *fakeAuthRepository) CreateUser(_ context.Context, user User) (*User, error) {
+	r.user = &user
+	return r.user, nil
+}
+
+func (r *fakeAuthRepository) GetUserByEmailOrUsername(_ context.Context, login string) (*User, error) {
+	if r.user == nil || (r.user.Email != login && r.user.Username != login) {
+		return nil, nil
+	}
+	return r.user, nil
+}
+
+func (r *fakeAuthRepository) GetUserByID(_ context.Context, id uuid.UUID) (*User, error) {
+	if r.user == nil || r.user.ID != id {
+		return nil, nil
+	}
+	return r.user, nil
+}
+
+func (r *fakeAuthRepository) CreateSession(_ context.Context, client Client, token RefreshToken) error {
+	r.client = &client
+	r.refresh = &token
+	return nil
+}
+
+func (r *fakeAuthRepository) GetRefreshToken(context.Context, []byte) (*RefreshToken, error) {
+	panic("not implemented")
+}
+
+func (r *fakeAuthRepository) RotateRefreshToken(context.Context, RefreshTokenRotation) error {
+	panic("not implemented")
+}
+
+func (r *fakeAuthRepository) RevokeRefreshTokenFamily(context.Context, uuid.UUID) error {
+	panic("not implemented")
+}
+
+func (r *fakeAuthRepository) DeleteUser(_ context.Context, id uuid.UUID) error {
+	r.deleted = &id
+	return nil
+}
+
+func (r *fakeAuthRepository) UpdateUserRole(context.Context, uuid.UUID, Role) error {
+	panic("not implemented")
+}
+
+func (r *fakeAuthRepository) CreateUserToken(_ context.Context, token UserToken) error {
+	r.token = &token
+	r.consumed = false
+	return nil
+}
+
+func (r *fakeAuthRepository) VerifyEmail(context.Context, []byte) (*uuid.UUID, error) {
+	return nil, nil
+}
+
+func (r *fakeAuthRepository) ResetPassword(_ context.Context, tokenHash []byte, passwordHash string) (*uuid.UUID, error) {
+	if r.resetUserID == uuid.Nil {
+		return nil, nil
+	}
+	r.tokenHash = tokenHash
+	r.passwordHash = passwordHash
+	return &r.resetUserID, nil
+}
+
+func (r *fakeAuthRepository) ListSessions(context.Context, uuid.UUID) ([]*Session, error) {
+	return r.sessions, nil
+}
+
+func (r *fakeAuthRepository) RevokeSession(context.Context, uuid.UUID, uuid.UUID) error {
+	panic("not implemented")
+}
+
+func (r *fakeAuthRepository) RevokeOtherSessions(_ context.Context, _ uuid.UUID, keep *uuid.UUID) (int64, error) {
+	r.kept = keep
+	return int64(len(r.sessions)), nil
+}
+
+func (r *fakeAuthRepository) FindUserToken(_ context.Context, tokenHash []byte, purpose TokenPurpose) (*uuid.UUID, error) {
+	if r.consumed || r.token == nil || r.token.Purpose != purpose || !bytes.Equal(r.token.TokenHash, tokenHash) {
+		return nil, nil
+	}
+	return &r.token.UserID, nil
+}
+
+func (r *fakeAuthRepository) ConsumeUserToken(ctx context.Context, tokenHash []byte, purpose TokenPurpose) (*uuid.UUID, error) {
+	userID, err := r.FindUserToken(ctx, tokenHash, purpose)
+	r.consumed = r.consumed || userID != nil
+	return userID, err
+}
+
+func (r *fakeAuthRepository) GetTOTP(context.Context, uuid.UUID) (*TOTPCredential, error) {
+	return r.totp, nil
+}
+
+func (r *fakeAuthRepository) SavePendingTOTP(_ context.Context, userID uuid.UUID, secret []byte) error {
+	if r.totp != nil && r.totp.ConfirmedAt != nil {
+		return ErrMFAAlreadyEnabled
+	}
+	r.totp = &TOTPCredential{UserID: userID, Secret: secret}
+	return nil
+}
+
+func (r *fakeAuthRepository) ConfirmTOTP(_ context.Context, _ uuid.UUID, step int64, recoveryCodeHashes [][]byte) error {
+	confirmedAt := time.Now()
+	r.totp.ConfirmedAt = &confirmedAt
+	r.totp.LastUsedStep = step
+	return r.ReplaceRecoveryCodes(context.Background(), r.totp.UserID, recoveryCodeHashes)
+}
+
+func (r *fakeAuthRepository) UseTOTPStep(_ context.Context, _ uuid.UUID, step int64) (bool, error) {
+	if step <= r.totp.LastUsedStep {
+		return false, nil
+	}
+	r.totp.LastUsedStep = step
+	return true, nil
+}
+
+func (r *fakeAuthRepository) DisableTOTP(context.Context, uuid.UUID) error {
+	if r.totp == nil {
+		return ErrMFANotEnabled
+	}
+	r.totp, r.recovery = nil, nil
+	return nil
+}
+
+func (r *fakeAuthRepository) ReplaceRecoveryCodes(_ context.Context, _ uuid.UUID, codeHashes [][]byte) error {
+	r.recovery = make(map[string]bool, len(codeHashes))
+	for _, codeHash := range codeHashes {
+		r.recovery[string(codeHash)] = false
+	}
+	return nil
+}
+
+func (r *fakeAuthRepository) UseRecoveryCode(_ context.Context, _ uuid.UUID, codeHash []byte) (bool, error) {
+	used, ok := r.recovery[string(codeHash)]
+	if !ok || used {
+		return false, nil
+	}
+	r.recovery[string(codeHash)] = true
+	return true, nil
+}
+
+func (r *fakeAuthRepository) CountRecoveryCodes(context.Context, uuid.UUID) (int64, error) {
+	var remaining int64
+	for _, used := range r.recovery {
+		if !used {
+			remaining++
+		}
+	}
+	return remaining, nil
+}
+
+func (r *fakeAuthRepository) CreateExternalLogin(_ context.Context, login ExternalLogin) error {
+	r.login = &login
+	return nil
+}
+
+func (r *fakeAuthRepository) ConsumeExternalLogin(_ context.Context, stateHash []byte) (*ExternalLogin, error) {
+	if r.login == nil || !bytes.Equal(r.login.StateHash, stateHash) {
+		return nil, nil
+	}
+	login := r.login
+	r.login = nil
+	return login, nil
+}
+
+func (r *fakeAuthRepository) UseIdentity(_ context.Context, provider string, identity oidc.Identity) (*uuid.UUID, error) {
+	userID, ok := r.identities[provider+":"+identity.Subject]
+	if !ok {
+		return nil, nil
+	}
+	return &userID, nil
+}
+
+func (r *fakeAuthRepository) LinkIdentity(_ context.Context, userID uuid.UUID, provider string, identity oidc.Identity) error {
+	if _, ok := r.identities[provider+":"+identity.Subject]; ok {
+		return ErrIdentityInUse
+	}
+	if r.identities == nil {
+		r.identities = map[string]uuid.UUID{}
+	}
+	r.identities[provider+":"+identity.Subject] = userID
+	return nil
+}
+
+func (r *fakeAuthRepository) CreateUserWithIdentity(ctx context.Context, user User, provider string, identity oidc.Identity) (*User, error) {
+	if identity.EmailVerified {
+		verifiedAt := time.Now()
+		user.EmailVerifiedAt = &verifiedAt
+	}
+	r.user = &user
+	return r.user, r.LinkIdentity(ctx, user.ID, provider, identity)
+}
+
+func (r *fakeAuthRepository) ListIdentities(_ context.Context, userID uuid.UUID) ([]*ExternalIdentity, error) {
+	var identities []*ExternalIdentity
+	for key, linked := range r.identities {
+		if linked == userID {
+			provider, _, _ := strings.Cut(key, ":")
+			identities = append(identities, &ExternalIdentity{Provider: provider})
+		}
+	}
+	return identities, nil
+}
+
+func (r *fakeAuthRepository) DeleteIdentity(_ context.Context, userID uuid.UUID, provider string) error {
+	for key, linked := range r.identities {
+		if linked == userID && strings.HasPrefix(key, provider+":") {
+			delete(r.identities, key)
+			return nil
+		}
+	}
+	return ErrIdentityNotFound
+}
+
+func (r *fakeAuthRepository) CreatePersonalAccessToken(_ context.Context, token PersonalAccessToken) (*PersonalAccessToken, error) {
+	token.CreatedAt = time.Now()
+	r.pats = append(r.pats, &token)
+	return &token, nil
+}
+
+func (r *fakeAuthRepository) FindPersonalAccessToken(_ context.Context, tokenHash []byte) (*TokenGrant, error) {
+	for _, token := range r.pats {
+		if bytes.Equal(token.TokenHash, tokenHash) && (token.ExpiresAt == nil || token.ExpiresAt.After(time.Now())) {
+			return &TokenGrant{TokenID: token.ID, UserID: token.UserID, Role: RoleUser, Scopes: token.Scopes}, nil
+		}
+	}
+	return nil, nil
+}
+
+func (r *fakeAuthRepository) TouchPersonalAccessToken(_ context.Context, _ uuid.UUID, ipAddress string) error {
+	r.patUses = append(r.patUses, ipAddress)
+	return nil
+}
+
+func (r *fakeAuthRepository) ListPersonalAccessTokens(context.Context, uuid.UUID) ([]*PersonalAccessToken, error) {
+	return r.pats, nil
+}
+
+func (r *fakeAuthRepository) RevokePersonalAccessToken(_ context.Context, userID, tokenID uuid.UUID) error {
+	for i, token := range r.pats {
+		if token.ID == tokenID && token.UserID == userID {
+			r.pats = slices.Delete(r.pats, i, i+1)
+			return nil
+		}
+	}
+	return ErrTokenNotFound
+}
+
+func mustTestUUID(t *testing.T) uuid.UUID {
+	t.Helper()
+	id, err := uuid.NewV7()
+	if err != nil {
+		t.Fatalf("new uuid: %v", err)
+	}
+	return id
+}
@@ -21,7 +21,9 @@ type TokenManager struct {
 }
 
 type AccessClaims struct {
-	Username string `json:"username,omitempty"`
+	Username  string `json:"username,omitempty"`
+	Role      string `json:"role,omitempty"`
+	SessionID string `json:"sid,omitempty"`
 	jwt.RegisteredClaims
 }
 
@@ -62,14 +64,18 @@ func NewTokenManager(issuer, audience, encodedPrivateKey string, accessTTL time.
 	}, nil
 }
 
-func (m *TokenManager) CreateAccessToken(user User) (string, error) {
+// CreateAccessToken signs a token for the user in the session, identified by
+// its refresh token family.
+func (m *TokenManager) CreateAccessToken(user User, sessionID uuid.UUID) (string, error) {
 	jti, err := uuid.NewV7()
 	if err != nil {
 		return "", err
 	}
 	now := time.Now().UTC()
 	claims := AccessClaims{
-		Username: user.Username,
+		Username:  user.Username,
+		Role:      string(user.Role),
+		SessionID: sessionID.String(),
 		RegisteredClaims: jwt.RegisteredClaims{
 			Issuer:    m.issuer,
 			Subject:   user.ID.String(),
@@ -111,6 +117,20 @@ func NewRefreshToken() (string, []byte, error) {
 	return token, hash, nil
 }
 
+// personalAccessTokenPrefix starts every personal access token, which tells
+// them apart from access tokens and makes leaked ones easy to search for.
+const personalAccessTokenPrefix = "bh_pat_"
+
+// NewPersonalAccessToken returns a token and the hash to store for it.
+func NewPersonalAccessToken() (string, []byte, error) {
+	secret, _, err := NewRefreshToken()
+	if err != nil {
+		return "", nil, err
+	}
+	token := personalAccessTokenPrefix + secret
+	return token, HashRefreshToken(token), nil
+}
+
 func HashRefreshToken(token string) []byte {
 	hash := sha256.Sum256([]byte(token))
 	return hash[:]
@@ -0,0 +1,113 @@
+package auth
+
+import (
+	"net/url"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/gofrs/uuid/v5"
+)
+
+// Test vectors from RFC 6238 Appendix B for HMAC-SHA1.
+func TestHOTPMatchesRFC6238(t *testing.T) {
+	secret := []byte("12345678901234567890")
+	for _, tc := range []struct {
+		unix int64
+		want string
+	}{
+		{59, "94287082"},
+		{1111111109, "07081804"},
+		{1111111111, "14050471"},
+		{1234567890, "89005924"},
+		{2000000000, "69279037"},
+		{20000000000, "65353130"},
+	} {
+		step := totpStep(time.Unix(tc.unix, 0))
+		if got := hotp(secret, uint64(step), 8); got != tc.want {
+			t.Errorf("hotp at %d = %s, want %s", tc.unix, got, tc.want)
+		}
+	}
+}
+
+func TestMatchTOTP(t *testing.T) {
+	secret := []byte("12345678901234567890")
+	now := time.Unix(1111111111, 0)
+	step := totpStep(now)
+	code := func(step int64) string { return hotp(secret, uint64(step), totpDigits) }
+
+	for _, offset := range []int64{-1, 0, 1} {
+		if got, ok := matchTOTP(secret, code(step+offset), now, 0); !ok || got != step+offset {
+			t.Errorf("code for step %+d: got %d, %v", offset, got, ok)
+		}
+	}
+	if _, ok := matchTOTP(secret, code(step-2), now, 0); ok {
+		t.Error("code two steps old matched")
+	}
+	if _, ok := matchTOTP(secret, code(step), now, step); ok {
+		t.Error("already used step matched")
+	}
+	if _, ok := matchTOTP(secret, "12345", now, 0); ok {
+		t.Error("short code matched")
+	}
+}
+
+func TestProvisioningURI(t *testing.T) {
+	uri := provisioningURI([]byte("12345678901234567890"), "reader@example.com")
+	if !strings.HasPrefix(uri, "otpauth://totp/Bayt%20al%20Hikmah:reader@example.com?") {
+		t.Fatalf("uri = %s", uri)
+	}
+	parsed, err := url.Parse(uri)
+	if err != nil {
+		t.Fatalf("parse uri: %v", err)
+	}
+	query := parsed.Query()
+	if query.Get("secret") != "GEZDGNBVGY3TQOJQGEZDGNBVGY3TQOJQ" || query.Get("issuer") != "Bayt al Hikmah" || query.Get("digits") != "6" || query.Get("period") != "30" {
+		t.Fatalf("query = %v", query)
+	}
+}
+
+func TestRecoveryCodes(t *testing.T) {
+	codes, err := newRecoveryCodes()
+	if err != nil {
+		t.Fatalf("newRecoveryCodes() error = %v", err)
+	}
+	if len(codes) != recoveryCodeCount {
+		t.Fatalf("got %d codes, want %d", len(codes), recoveryCodeCount)
+	}
+	seen := map[string]bool{}
+	for _, code := range codes {
+		if len(code) != 11 || code[5] != '-' || seen[code] {
+			t.Fatalf("bad or repeated code %q", code)
+		}
+		seen[code] = true
+	}
+	if string(hashRecoveryCode(" ABCDE FGHIJ ")) != string(hashRecoveryCode("abcde-fghij")) {
+		t.Fatal("recovery code hash depends on formatting")
+	}
+}
+
+func TestSecretBox(t *testing.T) {
+	box, err := NewSecretBox("")
+	if err != nil {
+		t.Fatalf("NewSecretBox() error = %v", err)
+	}
+	owner, other := mustTestUUID(t), mustTestUUID(t)
+
+	sealed, err := box.Seal(owner, []byte("secret"))
+	if err != nil {
+		t.Fatalf("Seal() error = %v", err)
+	}
+	if opened, err := box.Open(owner, sealed); err != nil || string(opened) != "secret" {
+		t.Fatalf("Open() = %q, %v", opened, err)
+	}
+	if _, err := box.Open(other, sealed); err == nil {
+		t.Fatal("secret opened for another user")
+	}
+	if _, err := NewSecretBox("c2hvcnQ="); err == nil {
+		t.Fatal("expected an error for a short key")
+	}
+	if _, err := box.Open(uuid.Nil, nil); err == nil {
+		t.Fatal("expected an error for an empty ciphertext")
+	}
+}
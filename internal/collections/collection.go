@@ -5,26 +5,198 @@ import (
 	"time"
 
 	"github.com/gofrs/uuid/v5"
+	"github.com/zizouhuweidi/maktaba/internal/echox"
 )
 
+// Outbox aggregate and event types published by this context.
+const (
+	AggregateCollection    = "collection"
+	EventCollectionCreated = "collection.created"
+	EventCollectionUpdated = "collection.updated"
+	EventCollectionDeleted = "collection.deleted"
+
+	EventCollectionItemAdded   = "collection.item_added"
+	EventCollectionItemUpdated = "collection.item_updated"
+	EventCollectionItemRemoved = "collection.item_removed"
+
+	EventCollectionMemberJoined  = "collection.member_joined"
+	EventCollectionMemberUpdated = "collection.member_updated"
+	EventCollectionMemberRemoved = "collection.member_removed"
+)
+
+// Role is a member's standing in a collection. The owner edits the
+// collection and manages its members, editors add, move and annotate items,
+// and viewers can read it while it is private.
+type Role string
+
+const (
+	RoleOwner  Role = "owner"
+	RoleEditor Role = "editor"
+	RoleViewer Role = "viewer"
+)
+
+// Allows reports whether the role grants everything need does. The empty
+// role, held by non-members, allows nothing.
+func (r Role) Allows(need Role) bool {
+	return r.rank() > 0 && r.rank() >= need.rank()
+}
+
+func (r Role) rank() int {
+	switch r {
+	case RoleOwner:
+		return 3
+	case RoleEditor:
+		return 2
+	case RoleViewer:
+		return 1
+	default:
+		return 0
+	}
+}
+
+// Action names an entry in a collection's activity feed
+type Action string
+
+const (
+	ActionCreated           Action = "collection_created"
+	ActionUpdated           Action = "collection_updated"
+	ActionRulesChanged      Action = "rules_changed"
+	ActionItemsReplaced     Action = "items_replaced"
+	ActionItemAdded         Action = "item_added"
+	ActionItemMoved         Action = "item_moved"
+	ActionItemNoteEdited    Action = "item_note_edited"
+	ActionItemRemoved       Action = "item_removed"
+	ActionMemberInvited     Action = "member_invited"
+	ActionMemberJoined      Action = "member_joined"
+	ActionMemberRoleChanged Action = "member_role_changed"
+	ActionMemberRemoved     Action = "member_removed"
+	ActionMemberLeft        Action = "member_left"
+)
+
+// Collection is either manual, listing its Items, or smart, selecting its
+// sources with Rules. A smart collection has no items; reading one by ID
+// resolves its rules into a page of Matches.
 type Collection struct {
-	ID          uuid.UUID   `json:"id"`
-	UserID      uuid.UUID   `json:"user_id"`
-	Name        string      `json:"name"`
-	Description *string     `json:"description,omitempty"`
-	IsPublic    bool        `json:"is_public"`
-	SourceIDs   []uuid.UUID `json:"source_ids,omitempty"`
-	CreatedAt   time.Time   `json:"created_at"`
-	UpdatedAt   time.Time   `json:"updated_at"`
+	ID          uuid.UUID           `json:"id"`
+	UserID      uuid.UUID           `json:"user_id"`
+	Name        string              `json:"name"`
+	Description *string             `json:"description,omitempty"`
+	IsPublic    bool                `json:"is_public"`
+	Rules       *Rules              `json:"rules,omitempty"`
+	SourceIDs   []uuid.UUID         `json:"source_ids,omitempty"`
+	Items       []*Item             `json:"items,omitempty"`
+	Matches     *echox.Page[*Match] `json:"matches,omitempty"`
+	CreatedAt   time.Time           `json:"created_at"`
+	UpdatedAt   time.Time           `json:"updated_at"`
+}
+
+// Smart reports whether the collection's sources come from rules
+func (c *Collection) Smart() bool {
+	return c.Rules != nil
+}
+
+// Item is one source in a collection. Positions start at 0 and have no gaps,
+// and Note is the curator's remark on why the source is there.
+type Item struct {
+	CollectionID uuid.UUID      `json:"collection_id"`
+	SourceID     uuid.UUID      `json:"source_id"`
+	Position     int            `json:"position"`
+	Note         *string        `json:"note,omitempty"`
+	AddedAt      time.Time      `json:"added_at"`
+	AddedBy      *uuid.UUID     `json:"added_by,omitempty"`
+	Source       *SourceSummary `json:"source,omitempty"`
+}
+
+type SourceSummary struct {
+	ID        uuid.UUID `json:"id"`
+	Title     string    `json:"title"`
+	Subtitle  *string   `json:"subtitle,omitempty"`
+	Type      string    `json:"type"`
+	Publisher *string   `json:"publisher,omitempty"`
+	ISBN      *string   `json:"isbn,omitempty"`
+}
+
+// Match is a source a smart collection's rules select. AddedAt is when the
+// owner added it to their library, or when it entered the catalogue if they
+// have not.
+type Match struct {
+	Source  *SourceSummary `json:"source"`
+	AddedAt time.Time      `json:"added_at"`
+}
+
+type Member struct {
+	CollectionID uuid.UUID  `json:"collection_id"`
+	UserID       uuid.UUID  `json:"user_id"`
+	Username     string     `json:"username"`
+	Role         Role       `json:"role"`
+	InvitedBy    *uuid.UUID `json:"invited_by,omitempty"`
+	JoinedAt     time.Time  `json:"joined_at"`
+}
+
+// Invitation offers a user a role in a collection until they accept or
+// decline it
+type Invitation struct {
+	ID                uuid.UUID `json:"id"`
+	CollectionID      uuid.UUID `json:"collection_id"`
+	CollectionName    string    `json:"collection_name"`
+	UserID            uuid.UUID `json:"user_id"`
+	Username          string    `json:"username"`
+	Role              Role      `json:"role"`
+	InvitedBy         uuid.UUID `json:"invited_by"`
+	InvitedByUsername string    `json:"invited_by_username"`
+	CreatedAt         time.Time `json:"created_at"`
+}
+
+// Activity is one entry in a collection's feed: who did what, to which
+// source or member. Usernames and titles are nil once the user or source is
+// deleted.
+type Activity struct {
+	ID             uuid.UUID  `json:"id"`
+	CollectionID   uuid.UUID  `json:"collection_id"`
+	ActorID        *uuid.UUID `json:"actor_id,omitempty"`
+	ActorUsername  *string    `json:"actor_username,omitempty"`
+	Action         Action     `json:"action"`
+	SourceID       *uuid.UUID `json:"source_id,omitempty"`
+	SourceTitle    *string    `json:"source_title,omitempty"`
+	MemberID       *uuid.UUID `json:"member_id,omitempty"`
+	MemberUsername *string    `json:"member_username,omitempty"`
+	Role           *Role      `json:"role,omitempty"`
+	CreatedAt      time.Time  `json:"created_at"`
 }
 
 type Repository interface {
 	Create(ctx context.Context, collection *Collection) (*Collection, error)
 	GetByID(ctx context.Context, id uuid.UUID) (*Collection, error)
 	ListByUser(ctx context.Context, userID uuid.UUID, limit, offset int) ([]*Collection, error)
-	ListPublicByUser(ctx context.Context, userID uuid.UUID, limit, offset int) ([]*Collection, error)
-	Update(ctx context.Context, collection *Collection) (*Collection, error)
+	List(ctx context.Context, filter ListFilter, page echox.PageRequest) ([]*Collection, error)
+	Count(ctx context.Context, filter ListFilter) (int64, error)
+	Update(ctx context.Context, collection *Collection, actor uuid.UUID) (*Collection, error)
 	Delete(ctx context.Context, id uuid.UUID) error
+	AddItem(ctx context.Context, collectionID, actor uuid.UUID, params AddItemParams) (*Item, error)
+	UpdateItem(ctx context.Context, collectionID, sourceID, actor uuid.UUID, params UpdateItemParams) (*Item, error)
+	RemoveItem(ctx context.Context, collectionID, sourceID, actor uuid.UUID) (*Item, error)
+	GetRole(ctx context.Context, collectionID, userID uuid.UUID) (Role, error)
+	ListMembers(ctx context.Context, collectionID uuid.UUID) ([]*Member, error)
+	UpdateMemberRole(ctx context.Context, collectionID, userID, actor uuid.UUID, role Role) (*Member, error)
+	RemoveMember(ctx context.Context, collectionID, userID, actor uuid.UUID) (*Member, error)
+	Invite(ctx context.Context, collectionID uuid.UUID, username string, role Role, actor uuid.UUID) (*Invitation, error)
+	GetInvitation(ctx context.Context, id uuid.UUID) (*Invitation, error)
+	ListInvitations(ctx context.Context, collectionID uuid.UUID) ([]*Invitation, error)
+	ListInvitationsForUser(ctx context.Context, userID uuid.UUID) ([]*Invitation, error)
+	AcceptInvitation(ctx context.Context, invitation *Invitation) (*Member, error)
+	DeleteInvitation(ctx context.Context, id uuid.UUID) error
+	ListActivity(ctx context.Context, collectionID uuid.UUID, page echox.PageRequest) ([]*Activity, error)
+	CountActivity(ctx context.Context, collectionID uuid.UUID) (int64, error)
+	ListMatches(ctx context.Context, collection *Collection, publicOnly bool, page echox.PageRequest) ([]*Match, error)
+	CountMatches(ctx context.Context, collection *Collection, publicOnly bool) (int64, error)
+}
+
+// ListFilter narrows collections to one owner, to those MemberID belongs to
+// in any role, or to public ones.
+type ListFilter struct {
+	UserID     *uuid.UUID
+	MemberID   *uuid.UUID
+	PublicOnly bool
 }
 
 type CreateCollectionParams struct {
@@ -32,12 +204,32 @@ type CreateCollectionParams struct {
 	Name        string
 	Description *string
 	IsPublic    bool
+	Rules       *Rules
 	SourceIDs   []uuid.UUID
 }
 
+// UpdateCollectionParams changes the fields that are set. Rules replace a
+// smart collection's rules and SourceIDs a manual collection's items; a
+// collection cannot switch between the two.
 type UpdateCollectionParams struct {
 	Name        *string
 	Description *string
 	IsPublic    *bool
+	Rules       *Rules
 	SourceIDs   []uuid.UUID
 }
+
+// AddItemParams places a source at Position, or at the end of the collection
+// when Position is nil. Positions past the end are clamped to it.
+type AddItemParams struct {
+	SourceID uuid.UUID
+	Position *int
+	Note     *string
+}
+
+// UpdateItemParams moves an item and edits its note. Nil fields are left
+// alone and an empty Note clears it.
+type UpdateItemParams struct {
+	Position *int
+	Note     *string
+}
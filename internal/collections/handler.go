@@ -20,10 +20,13 @@ func NewHandler(service *Service, logger *slog.Logger) *Handler {
 	return &Handler{service: service, logger: logger}
 }
 
+// CreateRequest makes a smart collection when Rules is set, and a manual one
+// listing SourceIDs otherwise
 type CreateRequest struct {
 	Name        string   `json:"name" validate:"required"`
 	Description *string  `json:"description,omitempty"`
 	IsPublic    bool     `json:"is_public"`
+	Rules       *Rules   `json:"rules,omitempty"`
 	SourceIDs   []string `json:"source_ids,omitempty"`
 }
 
@@ -31,9 +34,30 @@ type UpdateRequest struct {
 	Name        *string  `json:"name,omitempty"`
 	Description *string  `json:"description,omitempty"`
 	IsPublic    *bool    `json:"is_public,omitempty"`
+	Rules       *Rules   `json:"rules,omitempty"`
 	SourceIDs   []string `json:"source_ids,omitempty"`
 }
 
+type AddItemRequest struct {
+	SourceID string  `json:"source_id" validate:"required"`
+	Position *int    `json:"position,omitempty"`
+	Note     *string `json:"note,omitempty"`
+}
+
+type UpdateItemRequest struct {
+	Position *int    `json:"position,omitempty"`
+	Note     *string `json:"note,omitempty"`
+}
+
+type InviteRequest struct {
+	Username string `json:"username" validate:"required"`
+	Role     Role   `json:"role" validate:"required"`
+}
+
+type UpdateMemberRequest struct {
+	Role Role `json:"role" validate:"required"`
+}
+
 func (h *Handler) RegisterPublicRoutes(e *echo.Echo) {
 	e.GET("/collections", h.List)
 	e.GET("/collections/:id", h.GetByID)
@@ -42,8 +66,22 @@ func (h *Handler) RegisterPublicRoutes(e *echo.Echo) {
 func (h *Handler) RegisterProtectedRoutes(g *echo.Group) {
 	g.POST("/collections", h.Create)
 	g.GET("/collections", h.ListOwn)
+	g.GET("/collections/:id", h.Get)
 	g.PUT("/collections/:id", h.Update)
 	g.DELETE("/collections/:id", h.Delete)
+	g.POST("/collections/:id/items", h.AddItem)
+	g.PATCH("/collections/:id/items/:source_id", h.UpdateItem)
+	g.DELETE("/collections/:id/items/:source_id", h.RemoveItem)
+	g.GET("/collections/:id/members", h.Members)
+	g.PATCH("/collections/:id/members/:user_id", h.UpdateMember)
+	g.DELETE("/collections/:id/members/:user_id", h.RemoveMember)
+	g.GET("/collections/:id/invitations", h.Invitations)
+	g.POST("/collections/:id/invitations", h.Invite)
+	g.DELETE("/collections/:id/invitations/:invitation_id", h.RevokeInvitation)
+	g.GET("/collections/:id/activity", h.Activity)
+	g.GET("/me/collection-invitations", h.MyInvitations)
+	g.POST("/collection-invitations/:id/accept", h.AcceptInvitation)
+	g.POST("/collection-invitations/:id/decline", h.DeclineInvitation)
 }
 
 func (h *Handler) Create(c *echo.Context) error {
@@ -61,27 +99,27 @@ func (h *Handler) Create(c *echo.Context) error {
 		return err
 	}
 
-	collection, err := h.service.Create(c.Request().Context(), CreateCollectionParams{UserID: userID, Name: req.Name, Description: req.Description, IsPublic: req.IsPublic, SourceIDs: sourceIDs})
-	if errors.Is(err, ErrInvalidCollection) {
-		return echo.NewHTTPError(http.StatusBadRequest, "invalid collection")
-	}
-	if errors.Is(err, ErrSourceNotFound) {
-		return echo.NewHTTPError(http.StatusNotFound, "source not found")
-	}
+	collection, err := h.service.Create(c.Request().Context(), CreateCollectionParams{UserID: userID, Name: req.Name, Description: req.Description, IsPublic: req.IsPublic, Rules: req.Rules, SourceIDs: sourceIDs})
 	if err != nil {
-		return echo.NewHTTPError(http.StatusInternalServerError, "failed to create collection")
+		return h.collectionError(err, "failed to create collection")
 	}
 
 	return c.JSON(http.StatusCreated, collection)
 }
 
+// GetByID reads a public collection. The limit, cursor and sort parameters
+// page through a smart collection's matches.
 func (h *Handler) GetByID(c *echo.Context) error {
 	id, err := echox.ParamUUID(c, "id", "collection ID")
 	if err != nil {
 		return err
 	}
+	page, err := echox.PageParams(c)
+	if err != nil {
+		return err
+	}
 
-	collection, err := h.service.GetByID(c.Request().Context(), id)
+	collection, err := h.service.GetByID(c.Request().Context(), id, page)
 	if errors.Is(err, ErrCollectionNotFound) {
 		return echo.NewHTTPError(http.StatusNotFound, "collection not found")
 	}
@@ -96,7 +134,10 @@ func (h *Handler) GetByID(c *echo.Context) error {
 }
 
 func (h *Handler) List(c *echo.Context) error {
-	limit, offset := echox.Pagination(c)
+	page, err := echox.PageParams(c)
+	if err != nil {
+		return err
+	}
 	userIDStr := c.QueryParam("user_id")
 	if userIDStr == "" {
 		return echo.NewHTTPError(http.StatusBadRequest, "user_id required")
@@ -106,7 +147,7 @@ func (h *Handler) List(c *echo.Context) error {
 		return echo.NewHTTPError(http.StatusBadRequest, "invalid user_id")
 	}
 
-	collections, err := h.service.ListPublicByUser(c.Request().Context(), userID, limit, offset)
+	collections, err := h.service.List(c.Request().Context(), ListFilter{UserID: &userID, PublicOnly: true}, page)
 	if err != nil {
 		return echo.NewHTTPError(http.StatusInternalServerError, "failed to list collections")
 	}
@@ -114,14 +155,18 @@ func (h *Handler) List(c *echo.Context) error {
 	return c.JSON(http.StatusOK, collections)
 }
 
+// ListOwn lists the collections the caller owns or was invited into
 func (h *Handler) ListOwn(c *echo.Context) error {
 	userID, ok := auth.UserID(c)
 	if !ok {
 		return echo.NewHTTPError(http.StatusUnauthorized, "authentication required")
 	}
-	limit, offset := echox.Pagination(c)
+	page, err := echox.PageParams(c)
+	if err != nil {
+		return err
+	}
 
-	collections, err := h.service.ListByUser(c.Request().Context(), userID, limit, offset)
+	collections, err := h.service.List(c.Request().Context(), ListFilter{MemberID: &userID}, page)
 	if err != nil {
 		return echo.NewHTTPError(http.StatusInternalServerError, "failed to list collections")
 	}
@@ -129,25 +174,29 @@ func (h *Handler) ListOwn(c *echo.Context) error {
 	return c.JSON(http.StatusOK, collections)
 }
 
-func (h *Handler) Update(c *echo.Context) error {
-	userID, ok := auth.UserID(c)
-	if !ok {
-		return echo.NewHTTPError(http.StatusUnauthorized, "authentication required")
+// Get returns a collection to the owner and members even while it is private
+func (h *Handler) Get(c *echo.Context) error {
+	userID, id, err := collectionRequest(c)
+	if err != nil {
+		return err
 	}
-	id, err := echox.ParamUUID(c, "id", "collection ID")
+	page, err := echox.PageParams(c)
 	if err != nil {
 		return err
 	}
 
-	existing, err := h.service.GetByID(c.Request().Context(), id)
-	if errors.Is(err, ErrCollectionNotFound) {
-		return echo.NewHTTPError(http.StatusNotFound, "collection not found")
-	}
+	collection, err := h.service.Get(c.Request().Context(), id, userID, page)
 	if err != nil {
-		return echo.NewHTTPError(http.StatusInternalServerError, "failed to get collection")
+		return h.collectionError(err, "failed to get collection")
 	}
-	if existing.UserID != userID {
-		return echo.NewHTTPError(http.StatusForbidden, "cannot update another user's collection")
+
+	return c.JSON(http.StatusOK, collection)
+}
+
+func (h *Handler) Update(c *echo.Context) error {
+	userID, id, err := collectionRequest(c)
+	if err != nil {
+		return err
 	}
 
 	var req UpdateRequest
@@ -159,47 +208,311 @@ func (h *Handler) Update(c *echo.Context) error {
 		return err
 	}
 
-	collection, err := h.service.Update(c.Request().Context(), id, UpdateCollectionParams{Name: req.Name, Description: req.Description, IsPublic: req.IsPublic, SourceIDs: sourceIDs})
-	if errors.Is(err, ErrInvalidCollection) {
-		return echo.NewHTTPError(http.StatusBadRequest, "invalid collection")
-	}
-	if errors.Is(err, ErrSourceNotFound) {
-		return echo.NewHTTPError(http.StatusNotFound, "source not found")
-	}
+	collection, err := h.service.Update(c.Request().Context(), id, userID, UpdateCollectionParams{Name: req.Name, Description: req.Description, IsPublic: req.IsPublic, Rules: req.Rules, SourceIDs: sourceIDs})
 	if err != nil {
-		return echo.NewHTTPError(http.StatusInternalServerError, "failed to update collection")
+		return h.collectionError(err, "failed to update collection")
 	}
 
 	return c.JSON(http.StatusOK, collection)
 }
 
 func (h *Handler) Delete(c *echo.Context) error {
+	userID, id, err := collectionRequest(c)
+	if err != nil {
+		return err
+	}
+
+	if err := h.service.Delete(c.Request().Context(), id, userID); err != nil {
+		return h.collectionError(err, "failed to delete collection")
+	}
+	return c.NoContent(http.StatusNoContent)
+}
+
+// AddItem inserts one source without resending the whole list
+func (h *Handler) AddItem(c *echo.Context) error {
+	userID, id, err := collectionRequest(c)
+	if err != nil {
+		return err
+	}
+
+	var req AddItemRequest
+	if err := echox.BindAndValidate(c, &req); err != nil {
+		return err
+	}
+	sourceID, err := uuid.FromString(req.SourceID)
+	if err != nil {
+		return echo.NewHTTPError(http.StatusBadRequest, "invalid source_id")
+	}
+
+	item, err := h.service.AddItem(c.Request().Context(), id, userID, AddItemParams{SourceID: sourceID, Position: req.Position, Note: req.Note})
+	if err != nil {
+		return h.collectionError(err, "failed to add collection item")
+	}
+
+	return c.JSON(http.StatusCreated, item)
+}
+
+// UpdateItem moves an item to a new position or edits its note
+func (h *Handler) UpdateItem(c *echo.Context) error {
+	userID, id, err := collectionRequest(c)
+	if err != nil {
+		return err
+	}
+	sourceID, err := echox.ParamUUID(c, "source_id", "source ID")
+	if err != nil {
+		return err
+	}
+
+	var req UpdateItemRequest
+	if err := echox.BindAndValidate(c, &req); err != nil {
+		return err
+	}
+
+	item, err := h.service.UpdateItem(c.Request().Context(), id, sourceID, userID, UpdateItemParams{Position: req.Position, Note: req.Note})
+	if err != nil {
+		return h.collectionError(err, "failed to update collection item")
+	}
+
+	return c.JSON(http.StatusOK, item)
+}
+
+func (h *Handler) RemoveItem(c *echo.Context) error {
+	userID, id, err := collectionRequest(c)
+	if err != nil {
+		return err
+	}
+	sourceID, err := echox.ParamUUID(c, "source_id", "source ID")
+	if err != nil {
+		return err
+	}
+
+	if err := h.service.RemoveItem(c.Request().Context(), id, sourceID, userID); err != nil {
+		return h.collectionError(err, "failed to remove collection item")
+	}
+	return c.NoContent(http.StatusNoContent)
+}
+
+func (h *Handler) Members(c *echo.Context) error {
+	userID, id, err := collectionRequest(c)
+	if err != nil {
+		return err
+	}
+
+	members, err := h.service.Members(c.Request().Context(), id, userID)
+	if err != nil {
+		return h.collectionError(err, "failed to list collection members")
+	}
+
+	return c.JSON(http.StatusOK, members)
+}
+
+func (h *Handler) UpdateMember(c *echo.Context) error {
+	userID, id, err := collectionRequest(c)
+	if err != nil {
+		return err
+	}
+	memberID, err := echox.ParamUUID(c, "user_id", "user ID")
+	if err != nil {
+		return err
+	}
+
+	var req UpdateMemberRequest
+	if err := echox.BindAndValidate(c, &req); err != nil {
+		return err
+	}
+
+	member, err := h.service.UpdateMember(c.Request().Context(), id, userID, memberID, req.Role)
+	if err != nil {
+		return h.collectionError(err, "failed to update collection member")
+	}
+
+	return c.JSON(http.StatusOK, member)
+}
+
+// RemoveMember removes another member, or lets the caller leave when the path
+// names them
+func (h *Handler) RemoveMember(c *echo.Context) error {
+	userID, id, err := collectionRequest(c)
+	if err != nil {
+		return err
+	}
+	memberID, err := echox.ParamUUID(c, "user_id", "user ID")
+	if err != nil {
+		return err
+	}
+
+	if err := h.service.RemoveMember(c.Request().Context(), id, userID, memberID); err != nil {
+		return h.collectionError(err, "failed to remove collection member")
+	}
+	return c.NoContent(http.StatusNoContent)
+}
+
+func (h *Handler) Invitations(c *echo.Context) error {
+	userID, id, err := collectionRequest(c)
+	if err != nil {
+		return err
+	}
+
+	invitations, err := h.service.Invitations(c.Request().Context(), id, userID)
+	if err != nil {
+		return h.collectionError(err, "failed to list invitations")
+	}
+
+	return c.JSON(http.StatusOK, invitations)
+}
+
+func (h *Handler) Invite(c *echo.Context) error {
+	userID, id, err := collectionRequest(c)
+	if err != nil {
+		return err
+	}
+
+	var req InviteRequest
+	if err := echox.BindAndValidate(c, &req); err != nil {
+		return err
+	}
+
+	invitation, err := h.service.Invite(c.Request().Context(), id, userID, req.Username, req.Role)
+	if err != nil {
+		return h.collectionError(err, "failed to invite collection member")
+	}
+
+	return c.JSON(http.StatusCreated, invitation)
+}
+
+func (h *Handler) RevokeInvitation(c *echo.Context) error {
+	userID, id, err := collectionRequest(c)
+	if err != nil {
+		return err
+	}
+	invitationID, err := echox.ParamUUID(c, "invitation_id", "invitation ID")
+	if err != nil {
+		return err
+	}
+
+	if err := h.service.RevokeInvitation(c.Request().Context(), id, userID, invitationID); err != nil {
+		return h.collectionError(err, "failed to revoke invitation")
+	}
+	return c.NoContent(http.StatusNoContent)
+}
+
+func (h *Handler) Activity(c *echo.Context) error {
+	userID, id, err := collectionRequest(c)
+	if err != nil {
+		return err
+	}
+	page, err := echox.PageParams(c)
+	if err != nil {
+		return err
+	}
+
+	activity, err := h.service.Activity(c.Request().Context(), id, userID, page)
+	if err != nil {
+		return h.collectionError(err, "failed to list collection activity")
+	}
+
+	return c.JSON(http.StatusOK, activity)
+}
+
+func (h *Handler) MyInvitations(c *echo.Context) error {
 	userID, ok := auth.UserID(c)
 	if !ok {
 		return echo.NewHTTPError(http.StatusUnauthorized, "authentication required")
 	}
-	id, err := echox.ParamUUID(c, "id", "collection ID")
+
+	invitations, err := h.service.MyInvitations(c.Request().Context(), userID)
 	if err != nil {
-		return err
+		return h.collectionError(err, "failed to list invitations")
 	}
 
-	existing, err := h.service.GetByID(c.Request().Context(), id)
-	if errors.Is(err, ErrCollectionNotFound) {
-		return echo.NewHTTPError(http.StatusNotFound, "collection not found")
+	return c.JSON(http.StatusOK, invitations)
+}
+
+func (h *Handler) AcceptInvitation(c *echo.Context) error {
+	userID, id, err := invitationRequest(c)
+	if err != nil {
+		return err
 	}
+
+	member, err := h.service.AcceptInvitation(c.Request().Context(), id, userID)
 	if err != nil {
-		return echo.NewHTTPError(http.StatusInternalServerError, "failed to get collection")
+		return h.collectionError(err, "failed to accept invitation")
 	}
-	if existing.UserID != userID {
-		return echo.NewHTTPError(http.StatusForbidden, "cannot delete another user's collection")
+
+	return c.JSON(http.StatusOK, member)
+}
+
+func (h *Handler) DeclineInvitation(c *echo.Context) error {
+	userID, id, err := invitationRequest(c)
+	if err != nil {
+		return err
 	}
 
-	if err := h.service.Delete(c.Request().Context(), id); err != nil {
-		return echo.NewHTTPError(http.StatusInternalServerError, "failed to delete collection")
+	if err := h.service.DeclineInvitation(c.Request().Context(), id, userID); err != nil {
+		return h.collectionError(err, "failed to decline invitation")
 	}
 	return c.NoContent(http.StatusNoContent)
 }
 
+func (h *Handler) collectionError(err error, message string) error {
+	switch {
+	case errors.Is(err, ErrInvalidCollection):
+		return echo.NewHTTPError(http.StatusBadRequest, "invalid collection")
+	case errors.Is(err, ErrInvalidRules):
+		return echo.NewHTTPError(http.StatusBadRequest, err.Error())
+	case errors.Is(err, ErrInvalidItem):
+		return echo.NewHTTPError(http.StatusBadRequest, "invalid collection item")
+	case errors.Is(err, ErrInvalidRole):
+		return echo.NewHTTPError(http.StatusBadRequest, "role must be editor or viewer")
+	case errors.Is(err, ErrForbidden):
+		return echo.NewHTTPError(http.StatusForbidden, "your role in this collection does not allow that")
+	case errors.Is(err, ErrCollectionNotFound):
+		return echo.NewHTTPError(http.StatusNotFound, "collection not found")
+	case errors.Is(err, ErrItemNotFound):
+		return echo.NewHTTPError(http.StatusNotFound, "collection item not found")
+	case errors.Is(err, ErrSourceNotFound):
+		return echo.NewHTTPError(http.StatusNotFound, "source not found")
+	case errors.Is(err, ErrUserNotFound):
+		return echo.NewHTTPError(http.StatusNotFound, "user not found")
+	case errors.Is(err, ErrMemberNotFound):
+		return echo.NewHTTPError(http.StatusNotFound, "collection member not found")
+	case errors.Is(err, ErrInvitationNotFound):
+		return echo.NewHTTPError(http.StatusNotFound, "invitation not found")
+	case errors.Is(err, ErrItemExists):
+		return echo.NewHTTPError(http.StatusConflict, "source already in collection")
+	case errors.Is(err, ErrAlreadyMember):
+		return echo.NewHTTPError(http.StatusConflict, "user is already a member")
+	case errors.Is(err, ErrOwnerCannotLeave):
+		return echo.NewHTTPError(http.StatusConflict, "the collection owner cannot leave it")
+	case errors.Is(err, ErrSmartCollection):
+		return echo.NewHTTPError(http.StatusConflict, "a smart collection's sources come from its rules")
+	case errors.Is(err, ErrManualCollection):
+		return echo.NewHTTPError(http.StatusConflict, "a manual collection has no rules")
+	default:
+		h.logger.Error(message, "error", err)
+		return echo.NewHTTPError(http.StatusInternalServerError, message)
+	}
+}
+
+// collectionRequest reads the caller and the collection ID in the path
+func collectionRequest(c *echo.Context) (uuid.UUID, uuid.UUID, error) {
+	userID, ok := auth.UserID(c)
+	if !ok {
+		return uuid.Nil, uuid.Nil, echo.NewHTTPError(http.StatusUnauthorized, "authentication required")
+	}
+	id, err := echox.ParamUUID(c, "id", "collection ID")
+	return userID, id, err
+}
+
+func invitationRequest(c *echo.Context) (uuid.UUID, uuid.UUID, error) {
+	userID, ok := auth.UserID(c)
+	if !ok {
+		return uuid.Nil, uuid.Nil, echo.NewHTTPError(http.StatusUnauthorized, "authentication required")
+	}
+	id, err := echox.ParamUUID(c, "id", "invitation ID")
+	return userID, id, err
+}
+
 func parseUUIDs(values []string, label string) ([]uuid.UUID, error) {
 	if values == nil {
 		return nil, nil
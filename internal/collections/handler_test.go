@@ -10,6 +10,7 @@ import (
 	"github.com/gofrs/uuid/v5"
 	"github.com/labstack/echo/v5"
 	"github.com/zizouhuweidi/maktaba/internal/auth"
+	"github.com/zizouhuweidi/maktaba/internal/echox"
 )
 
 func TestHandlerGetByIDRejectsPrivateCollection(t *testing.T) {
@@ -53,6 +54,30 @@ func TestHandlerDeleteRejectsAnotherUsersCollection(t *testing.T) {
 	}
 }
 
+func TestHandlerRemoveItemRejectsAnotherUsersCollection(t *testing.T) {
+	collectionID, sourceID := mustTestUUID(t), mustTestUUID(t)
+	repo := &fakeCollectionsRepository{collection: &Collection{
+		ID:       collectionID,
+		UserID:   mustTestUUID(t),
+		Name:     "Someone else's list",
+		IsPublic: true,
+	}}
+	handler := NewHandler(NewService(repo, slog.Default()), slog.Default())
+
+	c := testContext(http.MethodDelete, "/api/collections/"+collectionID.String()+"/items/"+sourceID.String(), "id", collectionID.String())
+	c.SetPathValues(echo.PathValues{{Name: "id", Value: collectionID.String()}, {Name: "source_id", Value: sourceID.String()}})
+	auth.SetUserID(c, mustTestUUID(t))
+
+	err := handler.RemoveItem(c)
+
+	if code := statusCode(t, err); code != http.StatusForbidden {
+		t.Fatalf("expected status %d, got %d", http.StatusForbidden, code)
+	}
+	if repo.removed {
+		t.Fatal("expected remove not to be called")
+	}
+}
+
 func testContext(method, target, paramName, paramValue string) *echo.Context {
 	e := echo.New()
 	req := httptest.NewRequest(method, target, nil)
@@ -76,11 +101,20 @@ func statusCode(t *testing.T, err error) int {
 
 type fakeCollectionsRepository struct {
 	collection *Collection
+	members    map[uuid.UUID]Role
+	invitation *Invitation
+	matches    []*Match
+	publicOnly *bool
+	created    *Collection
+	updated    *Collection
+	added      *AddItemParams
 	deleted    bool
+	removed    bool
 }
 
-func (r *fakeCollectionsRepository) Create(context.Context, *Collection) (*Collection, error) {
-	panic("not implemented")
+func (r *fakeCollectionsRepository) Create(_ context.Context, collection *Collection) (*Collection, error) {
+	r.created = collection
+	return collection, nil
 }
 
 func (r *fakeCollectionsRepository) GetByID(_ context.Context, id uuid.UUID) (*Collection, error) {
@@ -94,19 +128,95 @@ func (r *fakeCollectionsRepository) ListByUser(context.Context, uuid.UUID, int,
 	panic("not implemented")
 }
 
-func (r *fakeCollectionsRepository) ListPublicByUser(context.Context, uuid.UUID, int, int) ([]*Collection, error) {
+func (r *fakeCollectionsRepository) List(context.Context, ListFilter, echox.PageRequest) ([]*Collection, error) {
 	panic("not implemented")
 }
 
-func (r *fakeCollectionsRepository) Update(context.Context, *Collection) (*Collection, error) {
+func (r *fakeCollectionsRepository) Count(context.Context, ListFilter) (int64, error) {
 	panic("not implemented")
 }
 
+func (r *fakeCollectionsRepository) Update(_ context.Context, collection *Collection, _ uuid.UUID) (*Collection, error) {
+	r.updated = collection
+	return collection, nil
+}
+
 func (r *fakeCollectionsRepository) Delete(context.Context, uuid.UUID) error {
 	r.deleted = true
 	return nil
 }
 
+func (r *fakeCollectionsRepository) AddItem(_ context.Context, collectionID, _ uuid.UUID, params AddItemParams) (*Item, error) {
+	r.added = &params
+	return &Item{CollectionID: collectionID, SourceID: params.SourceID, Note: params.Note}, nil
+}
+
+func (r *fakeCollectionsRepository) UpdateItem(context.Context, uuid.UUID, uuid.UUID, uuid.UUID, UpdateItemParams) (*Item, error) {
+	panic("not implemented")
+}
+
+func (r *fakeCollectionsRepository) RemoveItem(_ context.Context, collectionID, sourceID, _ uuid.UUID) (*Item, error) {
+	r.removed = true
+	return &Item{CollectionID: collectionID, SourceID: sourceID}, nil
+}
+
+func (r *fakeCollectionsRepository) GetRole(_ context.Context, collectionID, userID uuid.UUID) (Role, error) {
+	if r.collection == nil || r.collection.ID != collectionID {
+		return "", nil
+	}
+	if r.collection.UserID == userID {
+		return RoleOwner, nil
+	}
+	return r.members[userID], nil
+}
+
+func (r *fakeCollectionsRepository) ListMembers(context.Context, uuid.UUID) ([]*Member, error) {
+	panic("not implemented")
+}
+
+func (r *fakeCollectionsRepository) UpdateMemberRole(context.Context, uuid.UUID, uuid.UUID, uuid.UUID, Role) (*Member, error) {
+	panic("not implemented")
+}
+
+func (r *fakeCollectionsRepository) RemoveMember(context.Context, uuid.UUID, uuid.UUID, uuid.UUID) (*Member, error) {
+	panic("not implemented")
+}
+
+func (r *fakeCollectionsRepository) Invite(context.Context, uuid.UUID, string, Role, uuid.UUID) (*Invitation, error) {
+	panic("not implemented")
+}
+
+func (r *fakeCollectionsRepository) GetInvitation(_ context.Context, id uuid.UUID) (*Invitation, error) {
+	if r.invitation == nil || r.invitation.ID != id {
+		return nil, nil
+	}
+	return r.invitation, nil
+}
+
+func (r *fakeCollectionsRepository) ListInvitations(context.Context, uuid.UUID) ([]*Invitation, error) {
+	panic("not implemented")
+}
+
+func (r *fakeCollectionsRepository) ListInvitationsForUser(context.Context, uuid.UUID) ([]*Invitation, error) {
+	panic("not implemented")
+}
+
+func (r *fakeCollectionsRepository) AcceptInvitation(context.Context, *Invitation) (*Member, error) {
+	panic("not implemented")
+}
+
+func (r *fakeCollectionsRepository) DeleteInvitation(context.Context, uuid.UUID) error {
+	panic("not implemented")
+}
+
+func (r *fakeCollectionsRepository) ListActivity(context.Context, uuid.UUID, echox.PageRequest) ([]*Activity, error) {
+	panic("not implemented")
+}
+
+func (r *fakeCollectionsRepository) CountActivity(context.Context, uuid.UUID) (int64, error) {
+	panic("not implemented")
+}
+
 func mustTestUUID(t *testing.T) uuid.UUID {
 	t.Helper()
 	id, err := uuid.NewV7()
@@ -115,3 +225,12 @@ func mustTestUUID(t *testing.T) uuid.UUID {
 	}
 	return id
 }
+
+func (r *fakeCollectionsRepository) ListMatches(_ context.Context, _ *Collection, publicOnly bool, page echox.PageRequest) ([]*Match, error) {
+	r.publicOnly = &publicOnly
+	return r.matches[:min(len(r.matches), page.Limit+1)], nil
+}
+
+func (r *fakeCollectionsRepository) CountMatches(context.Context, *Collection, bool) (int64, error) {
+	return int64(len(r.matches)), nil
+}
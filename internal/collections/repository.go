@@ -1,22 +1,29 @@
 package collections
 
 import (
+	"bytes"
 	"context"
 	"encoding/json"
 	"errors"
+	"time"
 
 	"github.com/gofrs/uuid/v5"
 	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgtype"
 	"github.com/zizouhuweidi/maktaba/internal/db"
 	"github.com/zizouhuweidi/maktaba/internal/db/dbgen"
+	"github.com/zizouhuweidi/maktaba/internal/echox"
+	"github.com/zizouhuweidi/maktaba/internal/outbox"
+	"github.com/zizouhuweidi/maktaba/internal/tags"
 )
 
 type postgresRepository struct {
+	db      *db.DB
 	queries *dbgen.Queries
 }
 
 func NewPostgresRepository(d *db.DB) Repository {
-	return &postgresRepository{queries: dbgen.New(d.Pool)}
+	return &postgresRepository{db: d, queries: dbgen.New(d.Pool)}
 }
 
 func (r *postgresRepository) Create(ctx context.Context, collection *Collection) (*Collection, error) {
@@ -24,27 +31,51 @@ func (r *postgresRepository) Create(ctx context.Context, collection *Collection)
 		return nil, err
 	}
 
+	rules, err := marshalRules(collection.Rules)
+	if err != nil {
+		return nil, err
+	}
 	id, err := uuid.NewV7()
 	if err != nil {
 		return nil, err
 	}
-	sourceIDs, err := json.Marshal(collection.SourceIDs)
+
+	tx, err := r.db.Begin(ctx)
 	if err != nil {
 		return nil, err
 	}
+	defer tx.Rollback(ctx)
+	qtx := r.queries.WithTx(tx)
 
-	row, err := r.queries.CreateCollection(ctx, dbgen.CreateCollectionParams{
+	row, err := qtx.CreateCollection(ctx, dbgen.CreateCollectionParams{
 		ID:          db.PGUUID(id),
 		UserID:      db.PGUUID(collection.UserID),
 		Name:        collection.Name,
 		Description: db.PGText(collection.Description),
 		IsPublic:    db.PGBool(collection.IsPublic),
-		SourceIds:   sourceIDs,
+		Rules:       rules,
 	})
 	if err != nil {
 		return nil, err
 	}
-	return mapCollection(row), nil
+	created := mapCollection(row)
+	if err := qtx.AddCollectionMember(ctx, dbgen.AddCollectionMemberParams{CollectionID: row.ID, UserID: row.UserID, Role: string(RoleOwner)}); err != nil {
+		return nil, err
+	}
+	if err := replaceItems(ctx, qtx, created, created.UserID, collection.SourceIDs); err != nil {
+		return nil, err
+	}
+	if err := recordActivity(ctx, qtx, &Activity{CollectionID: created.ID, ActorID: &created.UserID, Action: ActionCreated}); err != nil {
+		return nil, err
+	}
+	if err := outbox.Record(ctx, qtx, AggregateCollection, created.ID, EventCollectionCreated, created); err != nil {
+		return nil, err
+	}
+
+	if err := tx.Commit(ctx); err != nil {
+		return nil, err
+	}
+	return created, nil
 }
 
 func (r *postgresRepository) GetByID(ctx context.Context, id uuid.UUID) (*Collection, error) {
@@ -55,7 +86,11 @@ func (r *postgresRepository) GetByID(ctx context.Context, id uuid.UUID) (*Collec
 	if err != nil {
 		return nil, err
 	}
-	return mapCollection(row), nil
+	collection := mapCollection(row)
+	if err := attachItems(ctx, r.queries, collection); err != nil {
+		return nil, err
+	}
+	return collection, nil
 }
 
 func (r *postgresRepository) ListByUser(ctx context.Context, userID uuid.UUID, limit, offset int) ([]*Collection, error) {
@@ -63,33 +98,76 @@ func (r *postgresRepository) ListByUser(ctx context.Context, userID uuid.UUID, l
 	if err != nil {
 		return nil, err
 	}
-	return mapCollections(rows), nil
+	collections := mapCollections(rows)
+	if err := attachItems(ctx, r.queries, collections...); err != nil {
+		return nil, err
+	}
+	return collections, nil
 }
 
-func (r *postgresRepository) ListPublicByUser(ctx context.Context, userID uuid.UUID, limit, offset int) ([]*Collection, error) {
-	rows, err := r.queries.ListPublicCollectionsByUser(ctx, dbgen.ListPublicCollectionsByUserParams{UserID: db.PGUUID(userID), Limit: int32(limit), Offset: int32(offset)})
+// List fetches one row past the page limit so the caller can tell whether
+// another page follows
+func (r *postgresRepository) List(ctx context.Context, filter ListFilter, page echox.PageRequest) ([]*Collection, error) {
+	params := dbgen.ListCollectionsNewestParams{
+		UserID:     db.PGUUIDPtr(filter.UserID),
+		MemberID:   db.PGUUIDPtr(filter.MemberID),
+		PublicOnly: filter.PublicOnly,
+		Limit:      int32(page.Limit + 1),
+	}
+	params.AfterCreatedAt, params.AfterID = db.Keyset(page.After.CreatedAt, page.After.ID, page.Oldest)
+	var rows []dbgen.Collection
+	var err error
+	if page.Oldest {
+		rows, err = r.queries.ListCollectionsOldest(ctx, dbgen.ListCollectionsOldestParams(params))
+	} else {
+		rows, err = r.queries.ListCollectionsNewest(ctx, params)
+	}
 	if err != nil {
 		return nil, err
 	}
-	return mapCollections(rows), nil
+	collections := mapCollections(rows)
+	if err := attachSourceIDs(ctx, r.queries, collections...); err != nil {
+		return nil, err
+	}
+	return collections, nil
+}
+
+func (r *postgresRepository) Count(ctx context.Context, filter ListFilter) (int64, error) {
+	return r.queries.CountCollections(ctx, dbgen.CountCollectionsParams{UserID: db.PGUUIDPtr(filter.UserID), MemberID: db.PGUUIDPtr(filter.MemberID), PublicOnly: filter.PublicOnly})
 }
 
-func (r *postgresRepository) Update(ctx context.Context, collection *Collection) (*Collection, error) {
+// Update records collection_updated in the activity feed when the name,
+// description or visibility changed, rules_changed when the rules did, and
+// items_replaced when SourceIDs is set
+func (r *postgresRepository) Update(ctx context.Context, collection *Collection, actor uuid.UUID) (*Collection, error) {
 	if err := r.validateSourceIDs(ctx, collection.SourceIDs); err != nil {
 		return nil, err
 	}
+	rules, err := marshalRules(collection.Rules)
+	if err != nil {
+		return nil, err
+	}
 
-	sourceIDs, err := json.Marshal(collection.SourceIDs)
+	tx, err := r.db.Begin(ctx)
 	if err != nil {
 		return nil, err
 	}
+	defer tx.Rollback(ctx)
+	qtx := r.queries.WithTx(tx)
 
-	row, err := r.queries.UpdateCollection(ctx, dbgen.UpdateCollectionParams{
+	before, err := qtx.GetCollectionByID(ctx, db.PGUUID(collection.ID))
+	if errors.Is(err, pgx.ErrNoRows) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	row, err := qtx.UpdateCollection(ctx, dbgen.UpdateCollectionParams{
 		ID:          db.PGUUID(collection.ID),
 		Name:        collection.Name,
 		Description: db.PGText(collection.Description),
 		IsPublic:    db.PGBool(collection.IsPublic),
-		SourceIds:   sourceIDs,
+		Rules:       rules,
 	})
 	if errors.Is(err, pgx.ErrNoRows) {
 		return nil, nil
@@ -97,11 +175,541 @@ func (r *postgresRepository) Update(ctx context.Context, collection *Collection)
 	if err != nil {
 		return nil, err
 	}
-	return mapCollection(row), nil
+	updated := mapCollection(row)
+	if row.Name != before.Name || row.Description != before.Description || row.IsPublic != before.IsPublic {
+		if err := recordActivity(ctx, qtx, &Activity{CollectionID: updated.ID, ActorID: &actor, Action: ActionUpdated}); err != nil {
+			return nil, err
+		}
+	}
+	if !bytes.Equal(row.Rules, before.Rules) {
+		if err := recordActivity(ctx, qtx, &Activity{CollectionID: updated.ID, ActorID: &actor, Action: ActionRulesChanged}); err != nil {
+			return nil, err
+		}
+	}
+	if collection.SourceIDs != nil {
+		if err := replaceItems(ctx, qtx, updated, actor, collection.SourceIDs); err != nil {
+			return nil, err
+		}
+		if err := recordActivity(ctx, qtx, &Activity{CollectionID: updated.ID, ActorID: &actor, Action: ActionItemsReplaced}); err != nil {
+			return nil, err
+		}
+	} else if err := attachSourceIDs(ctx, qtx, updated); err != nil {
+		return nil, err
+	}
+	if err := outbox.Record(ctx, qtx, AggregateCollection, updated.ID, EventCollectionUpdated, updated); err != nil {
+		return nil, err
+	}
+
+	if err := tx.Commit(ctx); err != nil {
+		return nil, err
+	}
+	return updated, nil
 }
 
 func (r *postgresRepository) Delete(ctx context.Context, id uuid.UUID) error {
-	return r.queries.DeleteCollection(ctx, db.PGUUID(id))
+	tx, err := r.db.Begin(ctx)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback(ctx)
+	qtx := r.queries.WithTx(tx)
+
+	deleted, err := qtx.DeleteCollection(ctx, db.PGUUID(id))
+	if err != nil {
+		return err
+	}
+	if deleted > 0 {
+		if err := outbox.Record(ctx, qtx, AggregateCollection, id, EventCollectionDeleted, outbox.DeletedPayload{ID: id}); err != nil {
+			return err
+		}
+	}
+	return tx.Commit(ctx)
+}
+
+// AddItem shifts later items down to make room for the new one. It returns
+// nil when the collection does not exist.
+func (r *postgresRepository) AddItem(ctx context.Context, collectionID, actor uuid.UUID, params AddItemParams) (*Item, error) {
+	tx, err := r.db.Begin(ctx)
+	if err != nil {
+		return nil, err
+	}
+	defer tx.Rollback(ctx)
+	qtx := r.queries.WithTx(tx)
+
+	count, err := lockCollection(ctx, qtx, collectionID)
+	if count < 0 || err != nil {
+		return nil, err
+	}
+	exists, err := qtx.SourceExists(ctx, db.PGUUID(params.SourceID))
+	if err != nil {
+		return nil, err
+	}
+	if !exists {
+		return nil, ErrSourceNotFound
+	}
+
+	position := count
+	if params.Position != nil {
+		position = min(*params.Position, count)
+	}
+	if err := qtx.ShiftCollectionItems(ctx, dbgen.ShiftCollectionItemsParams{Delta: 1, CollectionID: db.PGUUID(collectionID), FromPosition: int32(position)}); err != nil {
+		return nil, err
+	}
+	inserted, err := qtx.InsertCollectionItem(ctx, dbgen.InsertCollectionItemParams{
+		CollectionID: db.PGUUID(collectionID),
+		SourceID:     db.PGUUID(params.SourceID),
+		Position:     int32(position),
+		Note:         db.PGText(params.Note),
+		AddedBy:      db.PGUUID(actor),
+	})
+	if err != nil {
+		return nil, err
+	}
+	if inserted == 0 {
+		return nil, ErrItemExists
+	}
+
+	if err := recordActivity(ctx, qtx, &Activity{CollectionID: collectionID, ActorID: &actor, Action: ActionItemAdded, SourceID: &params.SourceID}); err != nil {
+		return nil, err
+	}
+	item, err := r.finishItemChange(ctx, qtx, collectionID, params.SourceID, EventCollectionItemAdded)
+	if err != nil {
+		return nil, err
+	}
+	if err := tx.Commit(ctx); err != nil {
+		return nil, err
+	}
+	return item, nil
+}
+
+// UpdateItem moves the item between its neighbours, clamping the position to
+// the end of the collection. It returns nil when the item does not exist.
+func (r *postgresRepository) UpdateItem(ctx context.Context, collectionID, sourceID, actor uuid.UUID, params UpdateItemParams) (*Item, error) {
+	tx, err := r.db.Begin(ctx)
+	if err != nil {
+		return nil, err
+	}
+	defer tx.Rollback(ctx)
+	qtx := r.queries.WithTx(tx)
+
+	count, err := lockCollection(ctx, qtx, collectionID)
+	if count < 0 || err != nil {
+		return nil, err
+	}
+	current, err := getItem(ctx, qtx, collectionID, sourceID)
+	if current == nil || err != nil {
+		return nil, err
+	}
+
+	if params.Position != nil {
+		position := min(*params.Position, count-1)
+		if position != current.Position {
+			if _, err := qtx.MoveCollectionItem(ctx, dbgen.MoveCollectionItemParams{SourceID: db.PGUUID(sourceID), ToPosition: int32(position), CollectionID: db.PGUUID(collectionID)}); err != nil {
+				return nil, err
+			}
+			if err := recordActivity(ctx, qtx, &Activity{CollectionID: collectionID, ActorID: &actor, Action: ActionItemMoved, SourceID: &sourceID}); err != nil {
+				return nil, err
+			}
+		}
+	}
+	if params.Note != nil && *params.Note != stringValue(current.Note) {
+		if err := qtx.UpdateCollectionItemNote(ctx, dbgen.UpdateCollectionItemNoteParams{Note: db.PGTextString(*params.Note), CollectionID: db.PGUUID(collectionID), SourceID: db.PGUUID(sourceID)}); err != nil {
+			return nil, err
+		}
+		if err := recordActivity(ctx, qtx, &Activity{CollectionID: collectionID, ActorID: &actor, Action: ActionItemNoteEdited, SourceID: &sourceID}); err != nil {
+			return nil, err
+		}
+	}
+
+	item, err := r.finishItemChange(ctx, qtx, collectionID, sourceID, EventCollectionItemUpdated)
+	if err != nil {
+		return nil, err
+	}
+	if err := tx.Commit(ctx); err != nil {
+		return nil, err
+	}
+	return item, nil
+}
+
+// RemoveItem closes the gap the item leaves behind and returns the removed
+// item, or nil when there was none.
+func (r *postgresRepository) RemoveItem(ctx context.Context, collectionID, sourceID, actor uuid.UUID) (*Item, error) {
+	tx, err := r.db.Begin(ctx)
+	if err != nil {
+		return nil, err
+	}
+	defer tx.Rollback(ctx)
+	qtx := r.queries.WithTx(tx)
+
+	count, err := lockCollection(ctx, qtx, collectionID)
+	if count < 0 || err != nil {
+		return nil, err
+	}
+	item, err := getItem(ctx, qtx, collectionID, sourceID)
+	if item == nil || err != nil {
+		return nil, err
+	}
+
+	position, err := qtx.DeleteCollectionItem(ctx, dbgen.DeleteCollectionItemParams{CollectionID: db.PGUUID(collectionID), SourceID: db.PGUUID(sourceID)})
+	if err != nil {
+		return nil, err
+	}
+	if err := qtx.ShiftCollectionItems(ctx, dbgen.ShiftCollectionItemsParams{Delta: -1, CollectionID: db.PGUUID(collectionID), FromPosition: position + 1}); err != nil {
+		return nil, err
+	}
+	if err := recordActivity(ctx, qtx, &Activity{CollectionID: collectionID, ActorID: &actor, Action: ActionItemRemoved, SourceID: &sourceID}); err != nil {
+		return nil, err
+	}
+	if err := qtx.TouchCollection(ctx, db.PGUUID(collectionID)); err != nil {
+		return nil, err
+	}
+	if err := outbox.Record(ctx, qtx, AggregateCollection, collectionID, EventCollectionItemRemoved, item); err != nil {
+		return nil, err
+	}
+
+	if err := tx.Commit(ctx); err != nil {
+		return nil, err
+	}
+	return item, nil
+}
+
+// finishItemChange reloads the changed item, bumps the collection's
+// updated_at and records the event
+func (r *postgresRepository) finishItemChange(ctx context.Context, qtx *dbgen.Queries, collectionID, sourceID uuid.UUID, event string) (*Item, error) {
+	item, err := getItem(ctx, qtx, collectionID, sourceID)
+	if err != nil {
+		return nil, err
+	}
+	if err := qtx.TouchCollection(ctx, db.PGUUID(collectionID)); err != nil {
+		return nil, err
+	}
+	if err := outbox.Record(ctx, qtx, AggregateCollection, collectionID, event, item); err != nil {
+		return nil, err
+	}
+	return item, nil
+}
+
+// GetRole returns the user's role in the collection, or the empty role when
+// they are not a member
+func (r *postgresRepository) GetRole(ctx context.Context, collectionID, userID uuid.UUID) (Role, error) {
+	role, err := r.queries.GetCollectionMemberRole(ctx, dbgen.GetCollectionMemberRoleParams{CollectionID: db.PGUUID(collectionID), UserID: db.PGUUID(userID)})
+	if errors.Is(err, pgx.ErrNoRows) {
+		return "", nil
+	}
+	if err != nil {
+		return "", err
+	}
+	return Role(role), nil
+}
+
+func (r *postgresRepository) ListMembers(ctx context.Context, collectionID uuid.UUID) ([]*Member, error) {
+	rows, err := r.queries.ListCollectionMembers(ctx, db.PGUUID(collectionID))
+	if err != nil {
+		return nil, err
+	}
+	members := make([]*Member, 0, len(rows))
+	for _, row := range rows {
+		members = append(members, mapMember(row.CollectionID, row.UserID, row.Username, row.Role, row.InvitedBy, row.JoinedAt))
+	}
+	return members, nil
+}
+
+// UpdateMemberRole never changes the owner. It returns nil when the user is
+// not a member other than the owner.
+func (r *postgresRepository) UpdateMemberRole(ctx context.Context, collectionID, userID, actor uuid.UUID, role Role) (*Member, error) {
+	tx, err := r.db.Begin(ctx)
+	if err != nil {
+		return nil, err
+	}
+	defer tx.Rollback(ctx)
+	qtx := r.queries.WithTx(tx)
+
+	updated, err := qtx.UpdateCollectionMemberRole(ctx, dbgen.UpdateCollectionMemberRoleParams{Role: string(role), CollectionID: db.PGUUID(collectionID), UserID: db.PGUUID(userID)})
+	if updated == 0 || err != nil {
+		return nil, err
+	}
+	member, err := getMember(ctx, qtx, collectionID, userID)
+	if err != nil {
+		return nil, err
+	}
+	if err := recordActivity(ctx, qtx, &Activity{CollectionID: collectionID, ActorID: &actor, Action: ActionMemberRoleChanged, MemberID: &userID, Role: &role}); err != nil {
+		return nil, err
+	}
+	if err := outbox.Record(ctx, qtx, AggregateCollection, collectionID, EventCollectionMemberUpdated, member); err != nil {
+		return nil, err
+	}
+
+	if err := tx.Commit(ctx); err != nil {
+		return nil, err
+	}
+	return member, nil
+}
+
+// RemoveMember never removes the owner. It records member_left when members
+// remove themselves, and returns nil when there was no such member.
+func (r *postgresRepository) RemoveMember(ctx context.Context, collectionID, userID, actor uuid.UUID) (*Member, error) {
+	tx, err := r.db.Begin(ctx)
+	if err != nil {
+		return nil, err
+	}
+	defer tx.Rollback(ctx)
+	qtx := r.queries.WithTx(tx)
+
+	member, err := getMember(ctx, qtx, collectionID, userID)
+	if member == nil || err != nil {
+		return nil, err
+	}
+	removed, err := qtx.DeleteCollectionMember(ctx, dbgen.DeleteCollectionMemberParams{CollectionID: db.PGUUID(collectionID), UserID: db.PGUUID(userID)})
+	if removed == 0 || err != nil {
+		return nil, err
+	}
+	action := ActionMemberRemoved
+	if actor == userID {
+		action = ActionMemberLeft
+	}
+	if err := recordActivity(ctx, qtx, &Activity{CollectionID: collectionID, ActorID: &actor, Action: action, MemberID: &userID}); err != nil {
+		return nil, err
+	}
+	if err := outbox.Record(ctx, qtx, AggregateCollection, collectionID, EventCollectionMemberRemoved, member); err != nil {
+		return nil, err
+	}
+
+	if err := tx.Commit(ctx); err != nil {
+		return nil, err
+	}
+	return member, nil
+}
+
+// Invite looks the user up by username and offers them the role, replacing
+// any invitation they already have to the collection
+func (r *postgresRepository) Invite(ctx context.Context, collectionID uuid.UUID, username string, role Role, actor uuid.UUID) (*Invitation, error) {
+	tx, err := r.db.Begin(ctx)
+	if err != nil {
+		return nil, err
+	}
+	defer tx.Rollback(ctx)
+	qtx := r.queries.WithTx(tx)
+
+	userID, err := qtx.GetUserIDByUsername(ctx, username)
+	if errors.Is(err, pgx.ErrNoRows) {
+		return nil, ErrUserNotFound
+	}
+	if err != nil {
+		return nil, err
+	}
+	if _, err := qtx.GetCollectionMemberRole(ctx, dbgen.GetCollectionMemberRoleParams{CollectionID: db.PGUUID(collectionID), UserID: userID}); err == nil {
+		return nil, ErrAlreadyMember
+	} else if !errors.Is(err, pgx.ErrNoRows) {
+		return nil, err
+	}
+
+	id, err := uuid.NewV7()
+	if err != nil {
+		return nil, err
+	}
+	invitationID, err := qtx.UpsertCollectionInvitation(ctx, dbgen.UpsertCollectionInvitationParams{
+		ID:           db.PGUUID(id),
+		CollectionID: db.PGUUID(collectionID),
+		UserID:       userID,
+		Role:         string(role),
+		InvitedBy:    db.PGUUID(actor),
+	})
+	if err != nil {
+		return nil, err
+	}
+	invitation, err := getInvitation(ctx, qtx, db.UUID(invitationID))
+	if err != nil {
+		return nil, err
+	}
+	if err := recordActivity(ctx, qtx, &Activity{CollectionID: collectionID, ActorID: &actor, Action: ActionMemberInvited, MemberID: &invitation.UserID, Role: &role}); err != nil {
+		return nil, err
+	}
+
+	if err := tx.Commit(ctx); err != nil {
+		return nil, err
+	}
+	return invitation, nil
+}
+
+func (r *postgresRepository) GetInvitation(ctx context.Context, id uuid.UUID) (*Invitation, error) {
+	return getInvitation(ctx, r.queries, id)
+}
+
+func (r *postgresRepository) ListInvitations(ctx context.Context, collectionID uuid.UUID) ([]*Invitation, error) {
+	rows, err := r.queries.ListCollectionInvitations(ctx, db.PGUUID(collectionID))
+	if err != nil {
+		return nil, err
+	}
+	invitations := make([]*Invitation, 0, len(rows))
+	for _, row := range rows {
+		invitations = append(invitations, mapInvitation(dbgen.GetCollectionInvitationRow(row)))
+	}
+	return invitations, nil
+}
+
+func (r *postgresRepository) ListInvitationsForUser(ctx context.Context, userID uuid.UUID) ([]*Invitation, error) {
+	rows, err := r.queries.ListUserCollectionInvitations(ctx, db.PGUUID(userID))
+	if err != nil {
+		return nil, err
+	}
+	invitations := make([]*Invitation, 0, len(rows))
+	for _, row := range rows {
+		invitations = append(invitations, mapInvitation(dbgen.GetCollectionInvitationRow(row)))
+	}
+	return invitations, nil
+}
+
+// AcceptInvitation turns the invitation into a membership. It returns nil
+// when the invitation was already used or withdrawn.
+func (r *postgresRepository) AcceptInvitation(ctx context.Context, invitation *Invitation) (*Member, error) {
+	tx, err := r.db.Begin(ctx)
+	if err != nil {
+		return nil, err
+	}
+	defer tx.Rollback(ctx)
+	qtx := r.queries.WithTx(tx)
+
+	deleted, err := qtx.DeleteCollectionInvitation(ctx, db.PGUUID(invitation.ID))
+	if deleted == 0 || err != nil {
+		return nil, err
+	}
+	if err := qtx.AddCollectionMember(ctx, dbgen.AddCollectionMemberParams{
+		CollectionID: db.PGUUID(invitation.CollectionID),
+		UserID:       db.PGUUID(invitation.UserID),
+		Role:         string(invitation.Role),
+		InvitedBy:    db.PGUUID(invitation.InvitedBy),
+	}); err != nil {
+		return nil, err
+	}
+	member, err := getMember(ctx, qtx, invitation.CollectionID, invitation.UserID)
+	if err != nil {
+		return nil, err
+	}
+	if err := recordActivity(ctx, qtx, &Activity{CollectionID: invitation.CollectionID, ActorID: &invitation.UserID, Action: ActionMemberJoined, MemberID: &invitation.UserID, Role: &member.Role}); err != nil {
+		return nil, err
+	}
+	if err := outbox.Record(ctx, qtx, AggregateCollection, invitation.CollectionID, EventCollectionMemberJoined, member); err != nil {
+		return nil, err
+	}
+
+	if err := tx.Commit(ctx); err != nil {
+		return nil, err
+	}
+	return member, nil
+}
+
+func (r *postgresRepository) DeleteInvitation(ctx context.Context, id uuid.UUID) error {
+	_, err := r.queries.DeleteCollectionInvitation(ctx, db.PGUUID(id))
+	return err
+}
+
+// ListActivity fetches one row past the page limit so the caller can tell
+// whether another page follows
+func (r *postgresRepository) ListActivity(ctx context.Context, collectionID uuid.UUID, page echox.PageRequest) ([]*Activity, error) {
+	params := dbgen.ListCollectionActivityNewestParams{
+		CollectionID: db.PGUUID(collectionID),
+		Limit:        int32(page.Limit + 1),
+	}
+	params.AfterCreatedAt, params.AfterID = db.Keyset(page.After.CreatedAt, page.After.ID, page.Oldest)
+	var rows []dbgen.ListCollectionActivityNewestRow
+	if page.Oldest {
+		oldest, err := r.queries.ListCollectionActivityOldest(ctx, dbgen.ListCollectionActivityOldestParams(params))
+		if err != nil {
+			return nil, err
+		}
+		for _, row := range oldest {
+			rows = append(rows, dbgen.ListCollectionActivityNewestRow(row))
+		}
+	} else {
+		var err error
+		if rows, err = r.queries.ListCollectionActivityNewest(ctx, params); err != nil {
+			return nil, err
+		}
+	}
+	activity := make([]*Activity, 0, len(rows))
+	for _, row := range rows {
+		activity = append(activity, mapActivity(row))
+	}
+	return activity, nil
+}
+
+func (r *postgresRepository) CountActivity(ctx context.Context, collectionID uuid.UUID) (int64, error) {
+	return r.queries.CountCollectionActivity(ctx, db.PGUUID(collectionID))
+}
+
+// ListMatches resolves a smart collection's rules against the owner's
+// library and reviews, most recently added first, fetching one row past the
+// page limit so the caller can tell whether another page follows. With
+// publicOnly the rules only see the owner's public library items and reviews,
+// and not their private library tags.
+func (r *postgresRepository) ListMatches(ctx context.Context, collection *Collection, publicOnly bool, page echox.PageRequest) ([]*Match, error) {
+	filter := matchFilter(collection, publicOnly, time.Now())
+	params := dbgen.ListSmartCollectionMatchesNewestParams{
+		OwnerID:    filter.OwnerID,
+		PublicOnly: filter.PublicOnly,
+		Types:      filter.Types,
+		InLibrary:  filter.InLibrary,
+		Statuses:   filter.Statuses,
+		AddedSince: filter.AddedSince,
+		MinRating:  filter.MinRating,
+		MaxRating:  filter.MaxRating,
+		TagSlugs:   filter.TagSlugs,
+		Limit:      int32(page.Limit + 1),
+	}
+	params.AfterCreatedAt, params.AfterID = db.Keyset(page.After.CreatedAt, page.After.ID, page.Oldest)
+	var rows []dbgen.ListSmartCollectionMatchesNewestRow
+	if page.Oldest {
+		oldest, err := r.queries.ListSmartCollectionMatchesOldest(ctx, dbgen.ListSmartCollectionMatchesOldestParams(params))
+		if err != nil {
+			return nil, err
+		}
+		for _, row := range oldest {
+			rows = append(rows, dbgen.ListSmartCollectionMatchesNewestRow(row))
+		}
+	} else {
+		var err error
+		if rows, err = r.queries.ListSmartCollectionMatchesNewest(ctx, params); err != nil {
+			return nil, err
+		}
+	}
+	matches := make([]*Match, 0, len(rows))
+	for _, row := range rows {
+		matches = append(matches, mapMatch(row.ID, row.Title, row.Subtitle, row.Type, row.Publisher, row.Isbn, row.AddedAt))
+	}
+	return matches, nil
+}
+
+func (r *postgresRepository) CountMatches(ctx context.Context, collection *Collection, publicOnly bool) (int64, error) {
+	return r.queries.CountSmartCollectionMatches(ctx, matchFilter(collection, publicOnly, time.Now()))
+}
+
+// matchFilter turns a smart collection's rules into query parameters, with
+// tags matched on their slugs and the added window ending at now
+func matchFilter(collection *Collection, publicOnly bool, now time.Time) dbgen.CountSmartCollectionMatchesParams {
+	rules := collection.Rules
+	filter := dbgen.CountSmartCollectionMatchesParams{
+		OwnerID:    db.PGUUID(collection.UserID),
+		PublicOnly: publicOnly,
+		Types:      make([]string, 0, len(rules.Types)),
+		InLibrary:  rules.InLibrary,
+		Statuses:   make([]string, 0, len(rules.Statuses)),
+		MinRating:  db.PGInt4Ptr(rules.MinRating),
+		MaxRating:  db.PGInt4Ptr(rules.MaxRating),
+		TagSlugs:   make([]string, 0, len(rules.Tags)),
+	}
+	for _, sourceType := range rules.Types {
+		filter.Types = append(filter.Types, string(sourceType))
+	}
+	for _, status := range rules.Statuses {
+		filter.Statuses = append(filter.Statuses, string(status))
+	}
+	for _, tag := range rules.Tags {
+		filter.TagSlugs = append(filter.TagSlugs, tags.Slug(tag))
+	}
+	if rules.AddedWithinDays != nil {
+		filter.AddedSince = db.PGTimestamptz(now.AddDate(0, 0, -*rules.AddedWithinDays))
+	}
+	return filter
 }
 
 func (r *postgresRepository) validateSourceIDs(ctx context.Context, sourceIDs []uuid.UUID) error {
@@ -117,6 +725,146 @@ func (r *postgresRepository) validateSourceIDs(ctx context.Context, sourceIDs []
 	return nil
 }
 
+// lockCollection serialises item edits on one collection and returns how many
+// items it holds, or -1 when the collection does not exist
+func lockCollection(ctx context.Context, q *dbgen.Queries, collectionID uuid.UUID) (int, error) {
+	if _, err := q.LockCollection(ctx, db.PGUUID(collectionID)); errors.Is(err, pgx.ErrNoRows) {
+		return -1, nil
+	} else if err != nil {
+		return -1, err
+	}
+	count, err := q.CountCollectionItems(ctx, db.PGUUID(collectionID))
+	return int(count), err
+}
+
+func getItem(ctx context.Context, q *dbgen.Queries, collectionID, sourceID uuid.UUID) (*Item, error) {
+	row, err := q.GetCollectionItem(ctx, dbgen.GetCollectionItemParams{CollectionID: db.PGUUID(collectionID), SourceID: db.PGUUID(sourceID)})
+	if errors.Is(err, pgx.ErrNoRows) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	return mapItem(row.CollectionID, row.SourceID, row.Position, row.Note, row.AddedAt, row.AddedBy, row.Title, row.Subtitle, row.Type, row.Publisher, row.Isbn), nil
+}
+
+func getMember(ctx context.Context, q *dbgen.Queries, collectionID, userID uuid.UUID) (*Member, error) {
+	row, err := q.GetCollectionMember(ctx, dbgen.GetCollectionMemberParams{CollectionID: db.PGUUID(collectionID), UserID: db.PGUUID(userID)})
+	if errors.Is(err, pgx.ErrNoRows) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	return mapMember(row.CollectionID, row.UserID, row.Username, row.Role, row.InvitedBy, row.JoinedAt), nil
+}
+
+func getInvitation(ctx context.Context, q *dbgen.Queries, id uuid.UUID) (*Invitation, error) {
+	row, err := q.GetCollectionInvitation(ctx, db.PGUUID(id))
+	if errors.Is(err, pgx.ErrNoRows) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	return mapInvitation(row), nil
+}
+
+// recordActivity adds an entry to the collection's feed in the caller's
+// transaction
+func recordActivity(ctx context.Context, q *dbgen.Queries, activity *Activity) error {
+	id, err := uuid.NewV7()
+	if err != nil {
+		return err
+	}
+	role := pgtype.Text{}
+	if activity.Role != nil {
+		role = db.PGTextString(string(*activity.Role))
+	}
+	return q.InsertCollectionActivity(ctx, dbgen.InsertCollectionActivityParams{
+		ID:           db.PGUUID(id),
+		CollectionID: db.PGUUID(activity.CollectionID),
+		ActorID:      db.PGUUIDPtr(activity.ActorID),
+		Action:       string(activity.Action),
+		SourceID:     db.PGUUIDPtr(activity.SourceID),
+		MemberID:     db.PGUUIDPtr(activity.MemberID),
+		Role:         role,
+	})
+}
+
+// replaceItems makes sourceIDs the collection's items in that order. Sources
+// that stay keep their note and who added them and when.
+func replaceItems(ctx context.Context, q *dbgen.Queries, collection *Collection, actor uuid.UUID, sourceIDs []uuid.UUID) error {
+	ids := make([]pgtype.UUID, 0, len(sourceIDs))
+	for _, id := range sourceIDs {
+		ids = append(ids, db.PGUUID(id))
+	}
+	if err := q.DeleteCollectionItemsExcept(ctx, dbgen.DeleteCollectionItemsExceptParams{CollectionID: db.PGUUID(collection.ID), SourceIds: ids}); err != nil {
+		return err
+	}
+	if len(ids) > 0 {
+		if err := q.UpsertCollectionItems(ctx, dbgen.UpsertCollectionItemsParams{CollectionID: db.PGUUID(collection.ID), AddedBy: db.PGUUID(actor), SourceIds: ids}); err != nil {
+			return err
+		}
+	}
+	collection.SourceIDs = sourceIDs
+	return nil
+}
+
+// attachSourceIDs loads the ordered source IDs of every collection in one
+// query
+func attachSourceIDs(ctx context.Context, q *dbgen.Queries, collections ...*Collection) error {
+	if len(collections) == 0 {
+		return nil
+	}
+	rows, err := q.ListCollectionSourceIDs(ctx, collectionIDs(collections))
+	if err != nil {
+		return err
+	}
+	byCollection := make(map[uuid.UUID][]uuid.UUID, len(collections))
+	for _, row := range rows {
+		id := db.UUID(row.CollectionID)
+		byCollection[id] = append(byCollection[id], db.UUID(row.SourceID))
+	}
+	for _, collection := range collections {
+		collection.SourceIDs = byCollection[collection.ID]
+	}
+	return nil
+}
+
+// attachItems loads the items of every collection with their source
+// summaries in one query
+func attachItems(ctx context.Context, q *dbgen.Queries, collections ...*Collection) error {
+	if len(collections) == 0 {
+		return nil
+	}
+	rows, err := q.ListCollectionItems(ctx, collectionIDs(collections))
+	if err != nil {
+		return err
+	}
+	byCollection := make(map[uuid.UUID][]*Item, len(collections))
+	for _, row := range rows {
+		item := mapItem(row.CollectionID, row.SourceID, row.Position, row.Note, row.AddedAt, row.AddedBy, row.Title, row.Subtitle, row.Type, row.Publisher, row.Isbn)
+		byCollection[item.CollectionID] = append(byCollection[item.CollectionID], item)
+	}
+	for _, collection := range collections {
+		collection.Items = byCollection[collection.ID]
+		collection.SourceIDs = make([]uuid.UUID, 0, len(collection.Items))
+		for _, item := range collection.Items {
+			collection.SourceIDs = append(collection.SourceIDs, item.SourceID)
+		}
+	}
+	return nil
+}
+
+func collectionIDs(collections []*Collection) []pgtype.UUID {
+	ids := make([]pgtype.UUID, 0, len(collections))
+	for _, collection := range collections {
+		ids = append(ids, db.PGUUID(collection.ID))
+	}
+	return ids
+}
+
 func mapCollections(rows []dbgen.Collection) []*Collection {
 	collections := make([]*Collection, 0, len(rows))
 	for _, row := range rows {
@@ -126,19 +874,111 @@ func mapCollections(rows []dbgen.Collection) []*Collection {
 }
 
 func mapCollection(row dbgen.Collection) *Collection {
-	var sourceIDs []uuid.UUID
-	if len(row.SourceIds) > 0 {
-		_ = json.Unmarshal(row.SourceIds, &sourceIDs)
-	}
-
-	return &Collection{
+	collection := &Collection{
 		ID:          db.UUID(row.ID),
 		UserID:      db.UUID(row.UserID),
 		Name:        row.Name,
 		Description: db.StringPtr(row.Description),
 		IsPublic:    db.Bool(row.IsPublic),
-		SourceIDs:   sourceIDs,
 		CreatedAt:   db.Time(row.CreatedAt),
 		UpdatedAt:   db.Time(row.UpdatedAt),
 	}
+	if len(row.Rules) > 0 {
+		collection.Rules = &Rules{}
+		_ = json.Unmarshal(row.Rules, collection.Rules)
+	}
+	return collection
+}
+
+// marshalRules stores nil rules as NULL, which keeps a collection manual
+func marshalRules(rules *Rules) ([]byte, error) {
+	if rules == nil {
+		return nil, nil
+	}
+	return json.Marshal(rules)
+}
+
+func mapMatch(id pgtype.UUID, title string, subtitle pgtype.Text, sourceType string, publisher pgtype.Text, isbn pgtype.Text, addedAt pgtype.Timestamptz) *Match {
+	return &Match{
+		Source: &SourceSummary{
+			ID:        db.UUID(id),
+			Title:     title,
+			Subtitle:  db.StringPtr(subtitle),
+			Type:      sourceType,
+			Publisher: db.StringPtr(publisher),
+			ISBN:      db.StringPtr(isbn),
+		},
+		AddedAt: db.Time(addedAt),
+	}
+}
+
+func mapItem(collectionID, sourceID pgtype.UUID, position int32, note pgtype.Text, addedAt pgtype.Timestamptz, addedBy pgtype.UUID, title string, subtitle pgtype.Text, sourceType string, publisher pgtype.Text, isbn pgtype.Text) *Item {
+	return &Item{
+		CollectionID: db.UUID(collectionID),
+		SourceID:     db.UUID(sourceID),
+		Position:     int(position),
+		Note:         db.StringPtr(note),
+		AddedAt:      db.Time(addedAt),
+		AddedBy:      db.UUIDPtr(addedBy),
+		Source: &SourceSummary{
+			ID:        db.UUID(sourceID),
+			Title:     title,
+			Subtitle:  db.StringPtr(subtitle),
+			Type:      sourceType,
+			Publisher: db.StringPtr(publisher),
+			ISBN:      db.StringPtr(isbn),
+		},
+	}
+}
+
+func mapMember(collectionID, userID pgtype.UUID, username, role string, invitedBy pgtype.UUID, joinedAt pgtype.Timestamptz) *Member {
+	return &Member{
+		CollectionID: db.UUID(collectionID),
+		UserID:       db.UUID(userID),
+		Username:     username,
+		Role:         Role(role),
+		InvitedBy:    db.UUIDPtr(invitedBy),
+		JoinedAt:     db.Time(joinedAt),
+	}
+}
+
+func mapInvitation(row dbgen.GetCollectionInvitationRow) *Invitation {
+	return &Invitation{
+		ID:                db.UUID(row.ID),
+		CollectionID:      db.UUID(row.CollectionID),
+		CollectionName:    row.CollectionName,
+		UserID:            db.UUID(row.UserID),
+		Username:          row.Username,
+		Role:              Role(row.Role),
+		InvitedBy:         db.UUID(row.InvitedBy),
+		InvitedByUsername: row.InvitedByUsername,
+		CreatedAt:         db.Time(row.CreatedAt),
+	}
+}
+
+func mapActivity(row dbgen.ListCollectionActivityNewestRow) *Activity {
+	activity := &Activity{
+		ID:             db.UUID(row.ID),
+		CollectionID:   db.UUID(row.CollectionID),
+		ActorID:        db.UUIDPtr(row.ActorID),
+		ActorUsername:  db.StringPtr(row.ActorUsername),
+		Action:         Action(row.Action),
+		SourceID:       db.UUIDPtr(row.SourceID),
+		SourceTitle:    db.StringPtr(row.SourceTitle),
+		MemberID:       db.UUIDPtr(row.MemberID),
+		MemberUsername: db.StringPtr(row.MemberUsername),
+		CreatedAt:      db.Time(row.CreatedAt),
+	}
+	if row.Role.Valid {
+		role := Role(row.Role.String)
+		activity.Role = &role
+	}
+	return activity
+}
+
+func stringValue(value *string) string {
+	if value == nil {
+		return ""
+	}
+	return *value
 }
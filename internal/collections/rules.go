@@ -0,0 +1,108 @@
+package collections
+
+import (
+	"fmt"
+	"slices"
+
+	"github.com/zizouhuweidi/maktaba/internal/library"
+	"github.com/zizouhuweidi/maktaba/internal/sources"
+	"github.com/zizouhuweidi/maktaba/internal/tags"
+)
+
+const (
+	maxRuleTags    = 10
+	maxAddedWithin = 3650
+	minRuleRating  = 1
+	maxRuleRating  = 5
+)
+
+// Rules select the sources of a smart collection. Every condition that is
+// set must hold. Sources match a tag when either the source or the owner's
+// library item carries it; statuses, the added window and ratings are read
+// from the owner's library and reviews, so they only match sources the owner
+// has added or reviewed.
+type Rules struct {
+	Types           []sources.SourceType `json:"types,omitempty"`
+	Tags            []string             `json:"tags,omitempty"`
+	InLibrary       bool                 `json:"in_library,omitempty"`
+	Statuses        []library.Status     `json:"statuses,omitempty"`
+	MinRating       *int                 `json:"min_rating,omitempty"`
+	MaxRating       *int                 `json:"max_rating,omitempty"`
+	AddedWithinDays *int                 `json:"added_within_days,omitempty"`
+}
+
+// normalizeRules checks rules before they are saved, dropping repeated types,
+// statuses and tags. Errors wrap ErrInvalidRules with the reason.
+func normalizeRules(rules *Rules) error {
+	for _, sourceType := range rules.Types {
+		if !validSourceType(sourceType) {
+			return fmt.Errorf("%w: unknown source type %q", ErrInvalidRules, sourceType)
+		}
+	}
+	rules.Types = unique(rules.Types)
+	for _, status := range rules.Statuses {
+		if !validStatus(status) {
+			return fmt.Errorf("%w: unknown library status %q", ErrInvalidRules, status)
+		}
+	}
+	rules.Statuses = unique(rules.Statuses)
+
+	cleaned, err := tags.Clean(rules.Tags)
+	if err != nil {
+		return fmt.Errorf("%w: %w", ErrInvalidRules, err)
+	}
+	if len(cleaned) > maxRuleTags {
+		return fmt.Errorf("%w: at most %d tags", ErrInvalidRules, maxRuleTags)
+	}
+	rules.Tags = cleaned
+	if len(rules.Tags) == 0 {
+		rules.Tags = nil
+	}
+
+	for _, rating := range []*int{rules.MinRating, rules.MaxRating} {
+		if rating != nil && (*rating < minRuleRating || *rating > maxRuleRating) {
+			return fmt.Errorf("%w: ratings must be between %d and %d", ErrInvalidRules, minRuleRating, maxRuleRating)
+		}
+	}
+	if rules.MinRating != nil && rules.MaxRating != nil && *rules.MinRating > *rules.MaxRating {
+		return fmt.Errorf("%w: min_rating is above max_rating", ErrInvalidRules)
+	}
+	if rules.AddedWithinDays != nil && (*rules.AddedWithinDays < 1 || *rules.AddedWithinDays > maxAddedWithin) {
+		return fmt.Errorf("%w: added_within_days must be between 1 and %d", ErrInvalidRules, maxAddedWithin)
+	}
+
+	if len(rules.Types) == 0 && len(rules.Statuses) == 0 && rules.Tags == nil && !rules.InLibrary &&
+		rules.MinRating == nil && rules.MaxRating == nil && rules.AddedWithinDays == nil {
+		return fmt.Errorf("%w: rules need at least one condition", ErrInvalidRules)
+	}
+	return nil
+}
+
+func validSourceType(sourceType sources.SourceType) bool {
+	switch sourceType {
+	case sources.SourceTypeBook, sources.SourceTypePaper, sources.SourceTypePodcast, sources.SourceTypeVideo, sources.SourceTypeArticle, sources.SourceTypeEssay:
+		return true
+	default:
+		return false
+	}
+}
+
+func validStatus(status library.Status) bool {
+	switch status {
+	case library.StatusToConsume, library.StatusInProgress, library.StatusCompleted, library.StatusPaused, library.StatusAbandoned:
+		return true
+	default:
+		return false
+	}
+}
+
+// unique drops repeated values, keeping the first occurrence
+func unique[T comparable](values []T) []T {
+	var kept []T
+	for _, value := range values {
+		if !slices.Contains(kept, value) {
+			kept = append(kept, value)
+		}
+	}
+	return kept
+}
@@ -0,0 +1,57 @@
+package collections
+
+import (
+	"errors"
+	"slices"
+	"testing"
+
+	"github.com/zizouhuweidi/maktaba/internal/library"
+	"github.com/zizouhuweidi/maktaba/internal/sources"
+)
+
+func TestNormalizeRules(t *testing.T) {
+	zero, four, six := 0, 4, 6
+	tests := []struct {
+		name  string
+		rules Rules
+		valid bool
+	}{
+		{"completed philosophy rated 4+", Rules{Statuses: []library.Status{library.StatusCompleted}, Tags: []string{"philosophy"}, MinRating: &four}, true},
+		{"papers added recently", Rules{Types: []sources.SourceType{sources.SourceTypePaper}, AddedWithinDays: &six}, true},
+		{"no conditions", Rules{}, false},
+		{"blank tags only", Rules{Tags: []string{" ", ""}}, false},
+		{"unknown type", Rules{Types: []sources.SourceType{"novel"}}, false},
+		{"unknown status", Rules{Statuses: []library.Status{"finished"}}, false},
+		{"rating out of range", Rules{MaxRating: &six}, false},
+		{"empty window", Rules{AddedWithinDays: &zero}, false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := normalizeRules(&tt.rules)
+			if tt.valid && err != nil {
+				t.Fatalf("expected valid rules, got %v", err)
+			}
+			if !tt.valid && !errors.Is(err, ErrInvalidRules) {
+				t.Fatalf("expected ErrInvalidRules, got %v", err)
+			}
+		})
+	}
+}
+
+func TestNormalizeRulesDropsRepeats(t *testing.T) {
+	rules := Rules{
+		Types: []sources.SourceType{sources.SourceTypeBook, sources.SourceTypeBook},
+		Tags:  []string{"Philosophy", " philosophy ", "Ethics"},
+	}
+
+	if err := normalizeRules(&rules); err != nil {
+		t.Fatalf("normalizeRules returned error: %v", err)
+	}
+
+	if !slices.Equal(rules.Types, []sources.SourceType{sources.SourceTypeBook}) {
+		t.Fatalf("expected one book type, got %v", rules.Types)
+	}
+	if !slices.Equal(rules.Tags, []string{"Philosophy", "Ethics"}) {
+		t.Fatalf("expected repeated tags dropped, got %v", rules.Tags)
+	}
+}
@@ -4,14 +4,29 @@ import (
 	"context"
 	"errors"
 	"log/slog"
+	"strings"
 
 	"github.com/gofrs/uuid/v5"
+	"github.com/zizouhuweidi/maktaba/internal/echox"
 )
 
 var (
 	ErrCollectionNotFound = errors.New("collection not found")
 	ErrInvalidCollection  = errors.New("invalid collection data")
 	ErrSourceNotFound     = errors.New("source not found")
+	ErrItemNotFound       = errors.New("collection item not found")
+	ErrItemExists         = errors.New("source already in collection")
+	ErrInvalidItem        = errors.New("invalid collection item")
+	ErrForbidden          = errors.New("not allowed to do this in the collection")
+	ErrInvalidRole        = errors.New("invalid collection role")
+	ErrUserNotFound       = errors.New("user not found")
+	ErrAlreadyMember      = errors.New("user is already a member")
+	ErrMemberNotFound     = errors.New("collection member not found")
+	ErrInvitationNotFound = errors.New("invitation not found")
+	ErrOwnerCannotLeave   = errors.New("the collection owner cannot leave it")
+	ErrInvalidRules       = errors.New("invalid smart collection rules")
+	ErrSmartCollection    = errors.New("a smart collection's sources come from its rules")
+	ErrManualCollection   = errors.New("a manual collection has no rules")
 )
 
 type Service struct {
@@ -27,13 +42,22 @@ func (s *Service) Create(ctx context.Context, params CreateCollectionParams) (*C
 	if params.UserID == uuid.Nil || params.Name == "" {
 		return nil, ErrInvalidCollection
 	}
+	if params.Rules != nil {
+		if len(params.SourceIDs) > 0 {
+			return nil, ErrSmartCollection
+		}
+		if err := normalizeRules(params.Rules); err != nil {
+			return nil, err
+		}
+	}
 
 	collection := &Collection{
 		UserID:      params.UserID,
 		Name:        params.Name,
 		Description: params.Description,
 		IsPublic:    params.IsPublic,
-		SourceIDs:   params.SourceIDs,
+		Rules:       params.Rules,
+		SourceIDs:   uniqueIDs(params.SourceIDs),
 	}
 	created, err := s.repo.Create(ctx, collection)
 	if err != nil {
@@ -45,7 +69,21 @@ func (s *Service) Create(ctx context.Context, params CreateCollectionParams) (*C
 	return created, nil
 }
 
-func (s *Service) GetByID(ctx context.Context, id uuid.UUID) (*Collection, error) {
+// GetByID returns the collection with its items, or for a smart collection
+// one page of the sources its rules select right now from what the owner
+// has made public
+func (s *Service) GetByID(ctx context.Context, id uuid.UUID, page echox.PageRequest) (*Collection, error) {
+	collection, err := s.get(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+	if err := s.resolve(ctx, collection, true, page); err != nil {
+		return nil, err
+	}
+	return collection, nil
+}
+
+func (s *Service) get(ctx context.Context, id uuid.UUID) (*Collection, error) {
 	collection, err := s.repo.GetByID(ctx, id)
 	if err != nil {
 		s.logger.Error("failed to get collection", "error", err, "id", id)
@@ -62,18 +100,59 @@ func (s *Service) ListByUser(ctx context.Context, userID uuid.UUID, limit, offse
 	return s.repo.ListByUser(ctx, userID, limit, offset)
 }
 
-func (s *Service) ListPublicByUser(ctx context.Context, userID uuid.UUID, limit, offset int) ([]*Collection, error) {
-	limit, offset = normalizePagination(limit, offset)
-	return s.repo.ListPublicByUser(ctx, userID, limit, offset)
+func (s *Service) List(ctx context.Context, filter ListFilter, page echox.PageRequest) (*echox.Page[*Collection], error) {
+	page = page.Normalize()
+
+	collections, err := s.repo.List(ctx, filter, page)
+	if err != nil {
+		return nil, err
+	}
+	total, err := page.Total(func() (int64, error) { return s.repo.Count(ctx, filter) })
+	if err != nil {
+		return nil, err
+	}
+	return echox.NewPage(collections, page, total, func(collection *Collection) echox.Cursor {
+		return echox.Cursor{CreatedAt: collection.CreatedAt, ID: collection.ID}
+	}), nil
 }
 
-func (s *Service) Update(ctx context.Context, id uuid.UUID, params UpdateCollectionParams) (*Collection, error) {
-	existing, err := s.repo.GetByID(ctx, id)
+// Get returns the collection when the actor may read it: anyone may read a
+// public collection, and members may read a private one. Smart collections
+// are resolved as in GetByID, and only the owner's own view also draws on
+// their private library and reviews.
+func (s *Service) Get(ctx context.Context, id, actor uuid.UUID, page echox.PageRequest) (*Collection, error) {
+	collection, err := s.authorize(ctx, id, actor, RoleViewer)
 	if err != nil {
 		return nil, err
 	}
-	if existing == nil {
-		return nil, ErrCollectionNotFound
+	if err := s.resolve(ctx, collection, actor != collection.UserID, page); err != nil {
+		return nil, err
+	}
+	return collection, nil
+}
+
+// Update lets editors replace the items or rules, while only the owner may
+// rename the collection, describe it or change its visibility
+func (s *Service) Update(ctx context.Context, id, actor uuid.UUID, params UpdateCollectionParams) (*Collection, error) {
+	need := RoleEditor
+	if params.Name != nil || params.Description != nil || params.IsPublic != nil {
+		need = RoleOwner
+	}
+	existing, err := s.authorize(ctx, id, actor, need)
+	if err != nil {
+		return nil, err
+	}
+	if existing.Smart() && params.SourceIDs != nil {
+		return nil, ErrSmartCollection
+	}
+	if params.Rules != nil {
+		if !existing.Smart() {
+			return nil, ErrManualCollection
+		}
+		if err := normalizeRules(params.Rules); err != nil {
+			return nil, err
+		}
+		existing.Rules = params.Rules
 	}
 
 	if params.Name != nil {
@@ -89,20 +168,28 @@ func (s *Service) Update(ctx context.Context, id uuid.UUID, params UpdateCollect
 		existing.IsPublic = *params.IsPublic
 	}
 	if params.SourceIDs != nil {
-		existing.SourceIDs = params.SourceIDs
+		existing.SourceIDs = uniqueIDs(params.SourceIDs)
+	} else {
+		existing.SourceIDs = nil
 	}
 
-	updated, err := s.repo.Update(ctx, existing)
+	updated, err := s.repo.Update(ctx, existing, actor)
 	if err != nil {
 		s.logger.Error("failed to update collection", "error", err, "id", id)
 		return nil, err
 	}
+	if updated == nil {
+		return nil, ErrCollectionNotFound
+	}
 
 	s.logger.Info("collection updated", "id", id)
 	return updated, nil
 }
 
-func (s *Service) Delete(ctx context.Context, id uuid.UUID) error {
+func (s *Service) Delete(ctx context.Context, id, actor uuid.UUID) error {
+	if _, err := s.authorize(ctx, id, actor, RoleOwner); err != nil {
+		return err
+	}
 	if err := s.repo.Delete(ctx, id); err != nil {
 		s.logger.Error("failed to delete collection", "error", err, "id", id)
 		return err
@@ -112,6 +199,327 @@ func (s *Service) Delete(ctx context.Context, id uuid.UUID) error {
 	return nil
 }
 
+func (s *Service) AddItem(ctx context.Context, collectionID, actor uuid.UUID, params AddItemParams) (*Item, error) {
+	if params.SourceID == uuid.Nil || (params.Position != nil && *params.Position < 0) {
+		return nil, ErrInvalidItem
+	}
+	if _, err := s.editItems(ctx, collectionID, actor); err != nil {
+		return nil, err
+	}
+	params.Note = trimNote(params.Note)
+	if params.Note != nil && *params.Note == "" {
+		params.Note = nil
+	}
+
+	item, err := s.repo.AddItem(ctx, collectionID, actor, params)
+	if err != nil {
+		if !errors.Is(err, ErrItemExists) && !errors.Is(err, ErrSourceNotFound) {
+			s.logger.Error("failed to add collection item", "error", err, "id", collectionID)
+		}
+		return nil, err
+	}
+	if item == nil {
+		return nil, ErrCollectionNotFound
+	}
+
+	s.logger.Info("collection item added", "id", collectionID, "source_id", item.SourceID, "position", item.Position)
+	return item, nil
+}
+
+func (s *Service) UpdateItem(ctx context.Context, collectionID, sourceID, actor uuid.UUID, params UpdateItemParams) (*Item, error) {
+	if params.Position != nil && *params.Position < 0 {
+		return nil, ErrInvalidItem
+	}
+	if _, err := s.editItems(ctx, collectionID, actor); err != nil {
+		return nil, err
+	}
+	params.Note = trimNote(params.Note)
+
+	item, err := s.repo.UpdateItem(ctx, collectionID, sourceID, actor, params)
+	if err != nil {
+		s.logger.Error("failed to update collection item", "error", err, "id", collectionID, "source_id", sourceID)
+		return nil, err
+	}
+	if item == nil {
+		return nil, ErrItemNotFound
+	}
+
+	s.logger.Info("collection item updated", "id", collectionID, "source_id", sourceID, "position", item.Position)
+	return item, nil
+}
+
+func (s *Service) RemoveItem(ctx context.Context, collectionID, sourceID, actor uuid.UUID) error {
+	if _, err := s.editItems(ctx, collectionID, actor); err != nil {
+		return err
+	}
+	item, err := s.repo.RemoveItem(ctx, collectionID, sourceID, actor)
+	if err != nil {
+		s.logger.Error("failed to remove collection item", "error", err, "id", collectionID, "source_id", sourceID)
+		return err
+	}
+	if item == nil {
+		return ErrItemNotFound
+	}
+
+	s.logger.Info("collection item removed", "id", collectionID, "source_id", sourceID)
+	return nil
+}
+
+// Members lists everyone in the collection, the owner first, to anyone who
+// may read it
+func (s *Service) Members(ctx context.Context, id, actor uuid.UUID) ([]*Member, error) {
+	if _, err := s.authorize(ctx, id, actor, RoleViewer); err != nil {
+		return nil, err
+	}
+	return s.repo.ListMembers(ctx, id)
+}
+
+// Invite offers a user, found by username, the editor or viewer role. Only
+// the owner can invite.
+func (s *Service) Invite(ctx context.Context, id, actor uuid.UUID, username string, role Role) (*Invitation, error) {
+	username = strings.TrimSpace(username)
+	if username == "" {
+		return nil, ErrUserNotFound
+	}
+	if role != RoleEditor && role != RoleViewer {
+		return nil, ErrInvalidRole
+	}
+	if _, err := s.authorize(ctx, id, actor, RoleOwner); err != nil {
+		return nil, err
+	}
+
+	invitation, err := s.repo.Invite(ctx, id, username, role, actor)
+	if err != nil {
+		if !errors.Is(err, ErrUserNotFound) && !errors.Is(err, ErrAlreadyMember) {
+			s.logger.Error("failed to invite collection member", "error", err, "id", id)
+		}
+		return nil, err
+	}
+
+	s.logger.Info("collection member invited", "id", id, "user_id", invitation.UserID, "role", role)
+	return invitation, nil
+}
+
+// Invitations lists the collection's pending invitations to its owner
+func (s *Service) Invitations(ctx context.Context, id, actor uuid.UUID) ([]*Invitation, error) {
+	if _, err := s.authorize(ctx, id, actor, RoleOwner); err != nil {
+		return nil, err
+	}
+	return s.repo.ListInvitations(ctx, id)
+}
+
+func (s *Service) RevokeInvitation(ctx context.Context, id, actor, invitationID uuid.UUID) error {
+	if _, err := s.authorize(ctx, id, actor, RoleOwner); err != nil {
+		return err
+	}
+	invitation, err := s.repo.GetInvitation(ctx, invitationID)
+	if err != nil {
+		return err
+	}
+	if invitation == nil || invitation.CollectionID != id {
+		return ErrInvitationNotFound
+	}
+	return s.repo.DeleteInvitation(ctx, invitationID)
+}
+
+// MyInvitations lists the invitations waiting for the actor's answer
+func (s *Service) MyInvitations(ctx context.Context, actor uuid.UUID) ([]*Invitation, error) {
+	return s.repo.ListInvitationsForUser(ctx, actor)
+}
+
+func (s *Service) AcceptInvitation(ctx context.Context, invitationID, actor uuid.UUID) (*Member, error) {
+	invitation, err := s.invitationFor(ctx, invitationID, actor)
+	if err != nil {
+		return nil, err
+	}
+
+	member, err := s.repo.AcceptInvitation(ctx, invitation)
+	if err != nil {
+		s.logger.Error("failed to accept collection invitation", "error", err, "id", invitationID)
+		return nil, err
+	}
+	if member == nil {
+		return nil, ErrInvitationNotFound
+	}
+
+	s.logger.Info("collection member joined", "id", member.CollectionID, "user_id", actor, "role", member.Role)
+	return member, nil
+}
+
+func (s *Service) DeclineInvitation(ctx context.Context, invitationID, actor uuid.UUID) error {
+	if _, err := s.invitationFor(ctx, invitationID, actor); err != nil {
+		return err
+	}
+	return s.repo.DeleteInvitation(ctx, invitationID)
+}
+
+// UpdateMember changes a member's role. Only the owner can, and the owner's
+// own role never changes.
+func (s *Service) UpdateMember(ctx context.Context, id, actor, userID uuid.UUID, role Role) (*Member, error) {
+	if role != RoleEditor && role != RoleViewer {
+		return nil, ErrInvalidRole
+	}
+	if _, err := s.authorize(ctx, id, actor, RoleOwner); err != nil {
+		return nil, err
+	}
+	if userID == actor {
+		return nil, ErrInvalidRole
+	}
+
+	member, err := s.repo.UpdateMemberRole(ctx, id, userID, actor, role)
+	if err != nil {
+		s.logger.Error("failed to update collection member", "error", err, "id", id, "user_id", userID)
+		return nil, err
+	}
+	if member == nil {
+		return nil, ErrMemberNotFound
+	}
+
+	s.logger.Info("collection member updated", "id", id, "user_id", userID, "role", role)
+	return member, nil
+}
+
+// RemoveMember lets the owner remove anyone else and any member leave. The
+// owner cannot leave their own collection.
+func (s *Service) RemoveMember(ctx context.Context, id, actor, userID uuid.UUID) error {
+	need := RoleOwner
+	if userID == actor {
+		need = RoleViewer
+	}
+	collection, err := s.authorize(ctx, id, actor, need)
+	if err != nil {
+		return err
+	}
+	if userID == collection.UserID {
+		return ErrOwnerCannotLeave
+	}
+	member, err := s.repo.RemoveMember(ctx, id, userID, actor)
+	if err != nil {
+		s.logger.Error("failed to remove collection member", "error", err, "id", id, "user_id", userID)
+		return err
+	}
+	if member == nil {
+		return ErrMemberNotFound
+	}
+
+	s.logger.Info("collection member removed", "id", id, "user_id", userID)
+	return nil
+}
+
+// Activity pages through who did what in the collection, newest first by
+// default
+func (s *Service) Activity(ctx context.Context, id, actor uuid.UUID, page echox.PageRequest) (*echox.Page[*Activity], error) {
+	if _, err := s.authorize(ctx, id, actor, RoleViewer); err != nil {
+		return nil, err
+	}
+	page = page.Normalize()
+
+	activity, err := s.repo.ListActivity(ctx, id, page)
+	if err != nil {
+		return nil, err
+	}
+	total, err := page.Total(func() (int64, error) { return s.repo.CountActivity(ctx, id) })
+	if err != nil {
+		return nil, err
+	}
+	return echox.NewPage(activity, page, total, func(entry *Activity) echox.Cursor {
+		return echox.Cursor{CreatedAt: entry.CreatedAt, ID: entry.ID}
+	}), nil
+}
+
+// resolve fills a smart collection's Matches with one page of the sources
+// its rules select, reading only the owner's public library items and
+// reviews when publicOnly is set. Manual collections are left alone.
+func (s *Service) resolve(ctx context.Context, collection *Collection, publicOnly bool, page echox.PageRequest) error {
+	if !collection.Smart() {
+		return nil
+	}
+	page = page.Normalize()
+
+	matches, err := s.repo.ListMatches(ctx, collection, publicOnly, page)
+	if err != nil {
+		s.logger.Error("failed to resolve smart collection", "error", err, "id", collection.ID)
+		return err
+	}
+	total, err := page.Total(func() (int64, error) { return s.repo.CountMatches(ctx, collection, publicOnly) })
+	if err != nil {
+		return err
+	}
+	collection.Matches = echox.NewPage(matches, page, total, func(match *Match) echox.Cursor {
+		return echox.Cursor{CreatedAt: match.AddedAt, ID: match.Source.ID}
+	})
+	return nil
+}
+
+// editItems authorizes an editor to change the items of a manual collection
+func (s *Service) editItems(ctx context.Context, id, actor uuid.UUID) (*Collection, error) {
+	collection, err := s.authorize(ctx, id, actor, RoleEditor)
+	if err != nil {
+		return nil, err
+	}
+	if collection.Smart() {
+		return nil, ErrSmartCollection
+	}
+	return collection, nil
+}
+
+// authorize loads the collection and checks that the actor holds at least
+// the role need in it. Anyone may view a public collection.
+func (s *Service) authorize(ctx context.Context, id, actor uuid.UUID, need Role) (*Collection, error) {
+	collection, err := s.get(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+	if need == RoleViewer && collection.IsPublic {
+		return collection, nil
+	}
+	role, err := s.repo.GetRole(ctx, id, actor)
+	if err != nil {
+		return nil, err
+	}
+	if !role.Allows(need) {
+		return nil, ErrForbidden
+	}
+	return collection, nil
+}
+
+// invitationFor loads an invitation addressed to the actor. Invitations to
+// anyone else look missing.
+func (s *Service) invitationFor(ctx context.Context, invitationID, actor uuid.UUID) (*Invitation, error) {
+	invitation, err := s.repo.GetInvitation(ctx, invitationID)
+	if err != nil {
+		return nil, err
+	}
+	if invitation == nil || invitation.UserID != actor {
+		return nil, ErrInvitationNotFound
+	}
+	return invitation, nil
+}
+
+// uniqueIDs drops repeated IDs, keeping the first occurrence
+func uniqueIDs(ids []uuid.UUID) []uuid.UUID {
+	if ids == nil {
+		return nil
+	}
+	seen := make(map[uuid.UUID]bool, len(ids))
+	unique := make([]uuid.UUID, 0, len(ids))
+	for _, id := range ids {
+		if !seen[id] {
+			seen[id] = true
+			unique = append(unique, id)
+		}
+	}
+	return unique
+}
+
+func trimNote(note *string) *string {
+	if note == nil {
+		return nil
+	}
+	trimmed := strings.TrimSpace(*note)
+	return &trimmed
+}
+
 func normalizePagination(limit, offset int) (int, int) {
 	if limit <= 0 {
 		limit = 100
@@ -0,0 +1,299 @@
+package collections
+
+import (
+	"context"
+	"errors"
+	"log/slog"
+	"slices"
+	"testing"
+	"time"
+
+	"github.com/gofrs/uuid/v5"
+	"github.com/zizouhuweidi/maktaba/internal/echox"
+	"github.com/zizouhuweidi/maktaba/internal/library"
+	"github.com/zizouhuweidi/maktaba/internal/sources"
+)
+
+func TestCreateDropsRepeatedSources(t *testing.T) {
+	first, second := mustTestUUID(t), mustTestUUID(t)
+	repo := &fakeCollectionsRepository{}
+	service := NewService(repo, slog.Default())
+
+	_, err := service.Create(context.Background(), CreateCollectionParams{
+		UserID:    mustTestUUID(t),
+		Name:      "Reading list",
+		SourceIDs: []uuid.UUID{first, second, first},
+	})
+	if err != nil {
+		t.Fatalf("create: %v", err)
+	}
+
+	if want := []uuid.UUID{first, second}; !slices.Equal(repo.created.SourceIDs, want) {
+		t.Fatalf("expected source IDs %v, got %v", want, repo.created.SourceIDs)
+	}
+}
+
+func TestUpdateWithoutSourcesKeepsItems(t *testing.T) {
+	collectionID, ownerID := mustTestUUID(t), mustTestUUID(t)
+	repo := &fakeCollectionsRepository{collection: &Collection{
+		ID:        collectionID,
+		UserID:    ownerID,
+		Name:      "Reading list",
+		SourceIDs: []uuid.UUID{mustTestUUID(t)},
+	}}
+	service := NewService(repo, slog.Default())
+	name := "Renamed"
+
+	if _, err := service.Update(context.Background(), collectionID, ownerID, UpdateCollectionParams{Name: &name}); err != nil {
+		t.Fatalf("update: %v", err)
+	}
+
+	if repo.updated.SourceIDs != nil {
+		t.Fatalf("expected items to be left alone, got %v", repo.updated.SourceIDs)
+	}
+}
+
+func TestAddItemRejectsNegativePosition(t *testing.T) {
+	repo := &fakeCollectionsRepository{}
+	service := NewService(repo, slog.Default())
+	position := -1
+
+	_, err := service.AddItem(context.Background(), mustTestUUID(t), mustTestUUID(t), AddItemParams{SourceID: mustTestUUID(t), Position: &position})
+
+	if !errors.Is(err, ErrInvalidItem) {
+		t.Fatalf("expected ErrInvalidItem, got %v", err)
+	}
+	if repo.added != nil {
+		t.Fatal("expected add not to be called")
+	}
+}
+
+func TestAddItemDropsBlankNote(t *testing.T) {
+	collectionID, ownerID := mustTestUUID(t), mustTestUUID(t)
+	repo := &fakeCollectionsRepository{collection: &Collection{ID: collectionID, UserID: ownerID, Name: "Reading list"}}
+	service := NewService(repo, slog.Default())
+	note := "   "
+
+	if _, err := service.AddItem(context.Background(), collectionID, ownerID, AddItemParams{SourceID: mustTestUUID(t), Note: &note}); err != nil {
+		t.Fatalf("add item: %v", err)
+	}
+
+	if repo.added.Note != nil {
+		t.Fatalf("expected blank note to be dropped, got %q", *repo.added.Note)
+	}
+}
+
+func TestEditorsEditItemsButNotSettings(t *testing.T) {
+	collectionID, editorID := mustTestUUID(t), mustTestUUID(t)
+	repo := &fakeCollectionsRepository{
+		collection: &Collection{ID: collectionID, UserID: mustTestUUID(t), Name: "Syllabus"},
+		members:    map[uuid.UUID]Role{editorID: RoleEditor},
+	}
+	service := NewService(repo, slog.Default())
+	name := "Renamed"
+
+	if _, err := service.Update(context.Background(), collectionID, editorID, UpdateCollectionParams{Name: &name}); !errors.Is(err, ErrForbidden) {
+		t.Fatalf("expected ErrForbidden renaming as an editor, got %v", err)
+	}
+	if _, err := service.Update(context.Background(), collectionID, editorID, UpdateCollectionParams{SourceIDs: []uuid.UUID{mustTestUUID(t)}}); err != nil {
+		t.Fatalf("expected an editor to replace items, got %v", err)
+	}
+	if _, err := service.AddItem(context.Background(), collectionID, editorID, AddItemParams{SourceID: mustTestUUID(t)}); err != nil {
+		t.Fatalf("expected an editor to add an item, got %v", err)
+	}
+}
+
+func TestViewersCannotEditItems(t *testing.T) {
+	collectionID, viewerID := mustTestUUID(t), mustTestUUID(t)
+	repo := &fakeCollectionsRepository{
+		collection: &Collection{ID: collectionID, UserID: mustTestUUID(t), Name: "Syllabus"},
+		members:    map[uuid.UUID]Role{viewerID: RoleViewer},
+	}
+	service := NewService(repo, slog.Default())
+
+	if _, err := service.Get(context.Background(), collectionID, viewerID, echox.PageRequest{}); err != nil {
+		t.Fatalf("expected a viewer to read the private collection, got %v", err)
+	}
+	_, err := service.AddItem(context.Background(), collectionID, viewerID, AddItemParams{SourceID: mustTestUUID(t)})
+	if !errors.Is(err, ErrForbidden) {
+		t.Fatalf("expected ErrForbidden, got %v", err)
+	}
+	if repo.added != nil {
+		t.Fatal("expected add not to be called")
+	}
+}
+
+func TestInviteRejectsOwnerRole(t *testing.T) {
+	collectionID, ownerID := mustTestUUID(t), mustTestUUID(t)
+	repo := &fakeCollectionsRepository{collection: &Collection{ID: collectionID, UserID: ownerID, Name: "Syllabus"}}
+	service := NewService(repo, slog.Default())
+
+	_, err := service.Invite(context.Background(), collectionID, ownerID, "reader", RoleOwner)
+
+	if !errors.Is(err, ErrInvalidRole) {
+		t.Fatalf("expected ErrInvalidRole, got %v", err)
+	}
+}
+
+func TestOwnerCannotLeave(t *testing.T) {
+	collectionID, ownerID := mustTestUUID(t), mustTestUUID(t)
+	repo := &fakeCollectionsRepository{collection: &Collection{ID: collectionID, UserID: ownerID, Name: "Syllabus"}}
+	service := NewService(repo, slog.Default())
+
+	err := service.RemoveMember(context.Background(), collectionID, ownerID, ownerID)
+
+	if !errors.Is(err, ErrOwnerCannotLeave) {
+		t.Fatalf("expected ErrOwnerCannotLeave, got %v", err)
+	}
+}
+
+func TestAcceptInvitationForSomeoneElse(t *testing.T) {
+	invitationID := mustTestUUID(t)
+	repo := &fakeCollectionsRepository{invitation: &Invitation{ID: invitationID, CollectionID: mustTestUUID(t), UserID: mustTestUUID(t), Role: RoleEditor}}
+	service := NewService(repo, slog.Default())
+
+	_, err := service.AcceptInvitation(context.Background(), invitationID, mustTestUUID(t))
+
+	if !errors.Is(err, ErrInvitationNotFound) {
+		t.Fatalf("expected ErrInvitationNotFound, got %v", err)
+	}
+}
+
+func TestRoleAllows(t *testing.T) {
+	tests := []struct {
+		role Role
+		need Role
+		want bool
+	}{
+		{RoleOwner, RoleEditor, true},
+		{RoleEditor, RoleEditor, true},
+		{RoleViewer, RoleEditor, false},
+		{RoleEditor, RoleOwner, false},
+		{"", RoleViewer, false},
+	}
+	for _, tt := range tests {
+		if got := tt.role.Allows(tt.need); got != tt.want {
+			t.Errorf("%q.Allows(%q) = %v, want %v", tt.role, tt.need, got, tt.want)
+		}
+	}
+}
+
+func TestCreateSmartCollectionValidatesRules(t *testing.T) {
+	repo := &fakeCollectionsRepository{}
+	service := NewService(repo, slog.Default())
+	minRating, maxRating := 4, 2
+
+	_, err := service.Create(context.Background(), CreateCollectionParams{
+		UserID: mustTestUUID(t),
+		Name:   "Favourites",
+		Rules:  &Rules{MinRating: &minRating, MaxRating: &maxRating},
+	})
+
+	if !errors.Is(err, ErrInvalidRules) {
+		t.Fatalf("expected ErrInvalidRules, got %v", err)
+	}
+	if repo.created != nil {
+		t.Fatal("expected create not to be called")
+	}
+}
+
+func TestCreateSmartCollectionRejectsSourceIDs(t *testing.T) {
+	service := NewService(&fakeCollectionsRepository{}, slog.Default())
+
+	_, err := service.Create(context.Background(), CreateCollectionParams{
+		UserID:    mustTestUUID(t),
+		Name:      "Papers",
+		Rules:     &Rules{Types: []sources.SourceType{sources.SourceTypePaper}},
+		SourceIDs: []uuid.UUID{mustTestUUID(t)},
+	})
+
+	if !errors.Is(err, ErrSmartCollection) {
+		t.Fatalf("expected ErrSmartCollection, got %v", err)
+	}
+}
+
+func TestGetByIDResolvesSmartCollection(t *testing.T) {
+	collectionID := mustTestUUID(t)
+	now := time.Now()
+	repo := &fakeCollectionsRepository{
+		collection: &Collection{ID: collectionID, UserID: mustTestUUID(t), Name: "Recent papers", Rules: &Rules{Types: []sources.SourceType{sources.SourceTypePaper}}},
+		matches: []*Match{
+			{Source: &SourceSummary{ID: mustTestUUID(t), Title: "Newest"}, AddedAt: now},
+			{Source: &SourceSummary{ID: mustTestUUID(t), Title: "Older"}, AddedAt: now.Add(-time.Hour)},
+			{Source: &SourceSummary{ID: mustTestUUID(t), Title: "Oldest"}, AddedAt: now.Add(-2 * time.Hour)},
+		},
+	}
+	service := NewService(repo, slog.Default())
+
+	collection, err := service.GetByID(context.Background(), collectionID, echox.PageRequest{Limit: 2, IncludeTotal: true})
+	if err != nil {
+		t.Fatalf("GetByID returned error: %v", err)
+	}
+
+	if collection.Matches == nil || len(collection.Matches.Items) != 2 || collection.Matches.Total == nil || *collection.Matches.Total != 3 {
+		t.Fatalf("expected a page of 2 of 3 matches, got %+v", collection.Matches)
+	}
+	if collection.Matches.NextCursor == nil {
+		t.Fatal("expected a cursor to the next page")
+	}
+}
+
+func TestGetResolvesSmartCollectionFromPublicDataForOthers(t *testing.T) {
+	collectionID, ownerID, editorID := mustTestUUID(t), mustTestUUID(t), mustTestUUID(t)
+	tests := []struct {
+		name       string
+		actor      uuid.UUID
+		publicOnly bool
+	}{
+		{"owner", ownerID, false},
+		{"editor", editorID, true},
+		{"stranger", mustTestUUID(t), true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			repo := &fakeCollectionsRepository{
+				collection: &Collection{ID: collectionID, UserID: ownerID, Name: "Finished", IsPublic: true, Rules: &Rules{Statuses: []library.Status{library.StatusCompleted}}},
+				members:    map[uuid.UUID]Role{ownerID: RoleOwner, editorID: RoleEditor},
+			}
+			service := NewService(repo, slog.Default())
+
+			if _, err := service.Get(context.Background(), collectionID, tt.actor, echox.PageRequest{}); err != nil {
+				t.Fatalf("Get returned error: %v", err)
+			}
+
+			if repo.publicOnly == nil || *repo.publicOnly != tt.publicOnly {
+				t.Fatalf("expected matches resolved with publicOnly %v, got %v", tt.publicOnly, repo.publicOnly)
+			}
+		})
+	}
+}
+
+func TestSmartCollectionRejectsItemChanges(t *testing.T) {
+	collectionID, ownerID := mustTestUUID(t), mustTestUUID(t)
+	repo := &fakeCollectionsRepository{collection: &Collection{ID: collectionID, UserID: ownerID, Name: "Finished", Rules: &Rules{Statuses: []library.Status{library.StatusCompleted}}}}
+	service := NewService(repo, slog.Default())
+
+	_, err := service.AddItem(context.Background(), collectionID, ownerID, AddItemParams{SourceID: mustTestUUID(t)})
+	if !errors.Is(err, ErrSmartCollection) {
+		t.Fatalf("expected ErrSmartCollection adding an item, got %v", err)
+	}
+	_, err = service.Update(context.Background(), collectionID, ownerID, UpdateCollectionParams{SourceIDs: []uuid.UUID{mustTestUUID(t)}})
+	if !errors.Is(err, ErrSmartCollection) {
+		t.Fatalf("expected ErrSmartCollection replacing items, got %v", err)
+	}
+	if repo.added != nil || repo.updated != nil {
+		t.Fatal("expected the repository not to be called")
+	}
+}
+
+func TestUpdateRejectsRulesOnManualCollection(t *testing.T) {
+	collectionID, ownerID := mustTestUUID(t), mustTestUUID(t)
+	repo := &fakeCollectionsRepository{collection: &Collection{ID: collectionID, UserID: ownerID, Name: "Reading list"}}
+	service := NewService(repo, slog.Default())
+
+	_, err := service.Update(context.Background(), collectionID, ownerID, UpdateCollectionParams{Rules: &Rules{InLibrary: true}})
+
+	if !errors.Is(err, ErrManualCollection) {
+		t.Fatalf("expected ErrManualCollection, got %v", err)
+	}
+}
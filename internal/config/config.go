@@ -3,17 +3,26 @@ package config
 import (
 	"fmt"
 	"os"
+	"regexp"
 	"strconv"
 	"strings"
 	"time"
 )
 
+// oidcProviderName keeps provider names safe to use in URL paths and
+// environment variable names.
+var oidcProviderName = regexp.MustCompile(`^[a-z0-9][a-z0-9-]{0,49}$`)
+
 // Config holds all configuration for the service
 type Config struct {
 	Environment string
 	Server      ServerConfig
 	Database    DatabaseConfig
 	Auth        AuthConfig
+	Outbox      OutboxConfig
+	Embeddings  EmbeddingsConfig
+	Metadata    MetadataConfig
+	Mail        MailConfig
 }
 
 type ServerConfig struct {
@@ -37,6 +46,73 @@ type AuthConfig struct {
 	AccessTokenLifetime  time.Duration
 	RefreshTokenLifetime time.Duration
 	CookieSecure         bool
+	// AppURL is the frontend that verification and password reset links in
+	// emails open.
+	AppURL                     string
+	VerifyEmailTokenLifetime   time.Duration
+	ResetPasswordTokenLifetime time.Duration
+	// TOTPEncryptionKey encrypts two-factor secrets at rest. It is a
+	// base64-encoded 32-byte AES key.
+	TOTPEncryptionKey string
+	// APIURL is where browsers reach this server; identity providers
+	// redirect to its /auth/oidc/{name}/callback.
+	APIURL        string
+	OIDCProviders []OIDCProviderConfig
+}
+
+// OIDCProviderConfig is an OpenID Connect provider users can sign in with.
+// Name appears in URLs and is how linked accounts refer to the provider.
+type OIDCProviderConfig struct {
+	Name         string
+	Issuer       string
+	ClientID     string
+	ClientSecret string
+	Scopes       []string
+}
+
+type OutboxConfig struct {
+	Enabled        bool
+	PollInterval   time.Duration
+	BatchSize      int
+	MaxAttempts    int
+	LogEvents      bool
+	WebhookURL     string
+	WebhookSecret  string
+	WebhookTimeout time.Duration
+}
+
+type EmbeddingsConfig struct {
+	// Provider selects the embedder: "hash" for the local bag-of-words
+	// embedder, "http" for an OpenAI-compatible model server, or "none".
+	Provider         string
+	URL              string
+	Model            string
+	APIKey           string
+	Timeout          time.Duration
+	BackfillInterval time.Duration
+	BatchSize        int
+}
+
+type MetadataConfig struct {
+	// Providers lists the catalogues asked for ISBN and DOI lookups, in order
+	// of preference: "openlibrary", "googlebooks" and "crossref".
+	Providers         []string
+	GoogleBooksAPIKey string
+	CrossrefMailto    string
+	Timeout           time.Duration
+}
+
+type MailConfig struct {
+	// Provider selects how email is delivered: "smtp", "file" to write .eml
+	// files to Dir, or "log" to print messages to the server log.
+	Provider     string
+	From         string
+	SMTPHost     string
+	SMTPPort     int
+	SMTPUsername string
+	SMTPPassword string
+	SMTPTimeout  time.Duration
+	Dir          string
 }
 
 // Load reads configuration from environment variables.
@@ -73,6 +149,66 @@ func Load() (*Config, error) {
 	if err != nil {
 		return nil, err
 	}
+	verifyEmailTokenLifetime, err := getDurationEnv("AUTH_VERIFY_EMAIL_TOKEN_LIFETIME", 48*time.Hour)
+	if err != nil {
+		return nil, err
+	}
+	resetPasswordTokenLifetime, err := getDurationEnv("AUTH_RESET_PASSWORD_TOKEN_LIFETIME", time.Hour)
+	if err != nil {
+		return nil, err
+	}
+	outboxEnabled, err := getBoolEnv("OUTBOX_ENABLED", true)
+	if err != nil {
+		return nil, err
+	}
+	outboxPollInterval, err := getDurationEnv("OUTBOX_POLL_INTERVAL", time.Second)
+	if err != nil {
+		return nil, err
+	}
+	outboxBatchSize, err := getIntEnv("OUTBOX_BATCH_SIZE", 100)
+	if err != nil {
+		return nil, err
+	}
+	outboxMaxAttempts, err := getIntEnv("OUTBOX_MAX_ATTEMPTS", 10)
+	if err != nil {
+		return nil, err
+	}
+	outboxLogEvents, err := getBoolEnv("OUTBOX_LOG_EVENTS", true)
+	if err != nil {
+		return nil, err
+	}
+	outboxWebhookTimeout, err := getDurationEnv("OUTBOX_WEBHOOK_TIMEOUT", 10*time.Second)
+	if err != nil {
+		return nil, err
+	}
+	embeddingsTimeout, err := getDurationEnv("EMBEDDINGS_TIMEOUT", 30*time.Second)
+	if err != nil {
+		return nil, err
+	}
+	embeddingsBackfillInterval, err := getDurationEnv("EMBEDDINGS_BACKFILL_INTERVAL", time.Minute)
+	if err != nil {
+		return nil, err
+	}
+	embeddingsBatchSize, err := getIntEnv("EMBEDDINGS_BATCH_SIZE", 32)
+	if err != nil {
+		return nil, err
+	}
+	metadataTimeout, err := getDurationEnv("METADATA_TIMEOUT", 10*time.Second)
+	if err != nil {
+		return nil, err
+	}
+	smtpPort, err := getIntEnv("SMTP_PORT", 587)
+	if err != nil {
+		return nil, err
+	}
+	smtpTimeout, err := getDurationEnv("SMTP_TIMEOUT", 30*time.Second)
+	if err != nil {
+		return nil, err
+	}
+	oidcProviders, err := getOIDCProviders()
+	if err != nil {
+		return nil, err
+	}
 
 	return &Config{
 		Environment: getEnv("ENVIRONMENT", "development"),
@@ -89,16 +225,84 @@ func Load() (*Config, error) {
 			ConnMaxLifetime: connMaxLifetime,
 		},
 		Auth: AuthConfig{
-			Issuer:               getEnv("AUTH_ISSUER", "bayt-alhikmah"),
-			Audience:             getEnv("AUTH_AUDIENCE", "bayt-alhikmah-api"),
-			Ed25519PrivateKey:    getEnv("AUTH_ED25519_PRIVATE_KEY", ""),
-			AccessTokenLifetime:  accessTokenLifetime,
-			RefreshTokenLifetime: refreshTokenLifetime,
-			CookieSecure:         cookieSecure,
+			Issuer:                     getEnv("AUTH_ISSUER", "bayt-alhikmah"),
+			Audience:                   getEnv("AUTH_AUDIENCE", "bayt-alhikmah-api"),
+			Ed25519PrivateKey:          getEnv("AUTH_ED25519_PRIVATE_KEY", ""),
+			AccessTokenLifetime:        accessTokenLifetime,
+			RefreshTokenLifetime:       refreshTokenLifetime,
+			CookieSecure:               cookieSecure,
+			AppURL:                     getEnv("APP_URL", "http://localhost:3000"),
+			VerifyEmailTokenLifetime:   verifyEmailTokenLifetime,
+			ResetPasswordTokenLifetime: resetPasswordTokenLifetime,
+			TOTPEncryptionKey:          getEnv("AUTH_TOTP_ENCRYPTION_KEY", ""),
+			APIURL:                     getEnv("API_URL", "http://localhost:8080"),
+			OIDCProviders:              oidcProviders,
+		},
+		Outbox: OutboxConfig{
+			Enabled:        outboxEnabled,
+			PollInterval:   outboxPollInterval,
+			BatchSize:      outboxBatchSize,
+			MaxAttempts:    outboxMaxAttempts,
+			LogEvents:      outboxLogEvents,
+			WebhookURL:     getEnv("OUTBOX_WEBHOOK_URL", ""),
+			WebhookSecret:  getEnv("OUTBOX_WEBHOOK_SECRET", ""),
+			WebhookTimeout: outboxWebhookTimeout,
+		},
+		Embeddings: EmbeddingsConfig{
+			Provider:         getEnv("EMBEDDINGS_PROVIDER", "hash"),
+			URL:              getEnv("EMBEDDINGS_URL", ""),
+			Model:            getEnv("EMBEDDINGS_MODEL", ""),
+			APIKey:           getEnv("EMBEDDINGS_API_KEY", ""),
+			Timeout:          embeddingsTimeout,
+			BackfillInterval: embeddingsBackfillInterval,
+			BatchSize:        embeddingsBatchSize,
+		},
+		Metadata: MetadataConfig{
+			Providers:         getEnvSlice("METADATA_PROVIDERS", []string{"openlibrary", "googlebooks", "crossref"}),
+			GoogleBooksAPIKey: getEnv("GOOGLE_BOOKS_API_KEY", ""),
+			CrossrefMailto:    getEnv("CROSSREF_MAILTO", ""),
+			Timeout:           metadataTimeout,
+		},
+		Mail: MailConfig{
+			Provider:     getEnv("MAIL_PROVIDER", "log"),
+			From:         getEnv("MAIL_FROM", "Bayt al-Hikmah <no-reply@localhost>"),
+			SMTPHost:     getEnv("SMTP_HOST", ""),
+			SMTPPort:     smtpPort,
+			SMTPUsername: getEnv("SMTP_USERNAME", ""),
+			SMTPPassword: getEnv("SMTP_PASSWORD", ""),
+			SMTPTimeout:  smtpTimeout,
+			Dir:          getEnv("MAIL_DIR", "tmp/mail"),
 		},
 	}, nil
 }
 
+// getOIDCProviders reads the providers named in AUTH_OIDC_PROVIDERS. Each
+// one is configured by variables with its name in upper case, such as
+// AUTH_OIDC_GOOGLE_ISSUER and AUTH_OIDC_GOOGLE_CLIENT_ID.
+func getOIDCProviders() ([]OIDCProviderConfig, error) {
+	names := getEnvSlice("AUTH_OIDC_PROVIDERS", nil)
+	providers := make([]OIDCProviderConfig, 0, len(names))
+	for _, name := range names {
+		name = strings.ToLower(name)
+		if !oidcProviderName.MatchString(name) {
+			return nil, fmt.Errorf("invalid AUTH_OIDC_PROVIDERS: %q is not a valid provider name", name)
+		}
+		prefix := "AUTH_OIDC_" + strings.ToUpper(strings.ReplaceAll(name, "-", "_")) + "_"
+		provider := OIDCProviderConfig{
+			Name:         name,
+			Issuer:       getEnv(prefix+"ISSUER", ""),
+			ClientID:     getEnv(prefix+"CLIENT_ID", ""),
+			ClientSecret: getEnv(prefix+"CLIENT_SECRET", ""),
+			Scopes:       getEnvSlice(prefix+"SCOPES", nil),
+		}
+		if provider.Issuer == "" || provider.ClientID == "" {
+			return nil, fmt.Errorf("%sISSUER and %sCLIENT_ID are required", prefix, prefix)
+		}
+		providers = append(providers, provider)
+	}
+	return providers, nil
+}
+
 func getEnv(key, defaultValue string) string {
 	if value := os.Getenv(key); value != "" {
 		return value
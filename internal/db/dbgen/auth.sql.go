@@ -11,6 +11,157 @@ import (
 	"github.com/jackc/pgx/v5/pgtype"
 )
 
+const confirmUserTOTP = `-- name: ConfirmUserTOTP :execrows
+UPDATE user_totp
+SET confirmed_at = NOW(), last_used_step = $2
+WHERE user_id = $1 AND confirmed_at IS NULL
+`
+
+type ConfirmUserTOTPParams struct {
+	UserID       pgtype.UUID `db:"user_id" json:"user_id"`
+	LastUsedStep int64       `db:"last_used_step" json:"last_used_step"`
+}
+
+func (q *Queries) ConfirmUserTOTP(ctx context.Context, arg ConfirmUserTOTPParams) (int64, error) {
+	result, err := q.db.Exec(ctx, confirmUserTOTP, arg.UserID, arg.LastUsedStep)
+	if err != nil {
+		return 0, err
+	}
+	return result.RowsAffected(), nil
+}
+
+const consumeOIDCLogin = `-- name: ConsumeOIDCLogin :one
+DELETE FROM oidc_logins
+WHERE state_hash = $1 AND expires_at > NOW()
+RETURNING provider, nonce, code_verifier, user_id, reauthenticate
+`
+
+type ConsumeOIDCLoginRow struct {
+	Provider       string      `db:"provider" json:"provider"`
+	Nonce          string      `db:"nonce" json:"nonce"`
+	CodeVerifier   string      `db:"code_verifier" json:"code_verifier"`
+	UserID         pgtype.UUID `db:"user_id" json:"user_id"`
+	Reauthenticate bool        `db:"reauthenticate" json:"reauthenticate"`
+}
+
+func (q *Queries) ConsumeOIDCLogin(ctx context.Context, stateHash []byte) (ConsumeOIDCLoginRow, error) {
+	row := q.db.QueryRow(ctx, consumeOIDCLogin, stateHash)
+	var i ConsumeOIDCLoginRow
+	err := row.Scan(
+		&i.Provider,
+		&i.Nonce,
+		&i.CodeVerifier,
+		&i.UserID,
+		&i.Reauthenticate,
+	)
+	return i, err
+}
+
+const consumeUserToken = `-- name: ConsumeUserToken :one
+UPDATE user_tokens
+SET used_at = NOW()
+WHERE token_hash = $1 AND purpose = $2 AND used_at IS NULL AND expires_at > NOW()
+RETURNING user_id
+`
+
+type ConsumeUserTokenParams struct {
+	TokenHash []byte `db:"token_hash" json:"token_hash"`
+	Purpose   string `db:"purpose" json:"purpose"`
+}
+
+func (q *Queries) ConsumeUserToken(ctx context.Context, arg ConsumeUserTokenParams) (pgtype.UUID, error) {
+	row := q.db.QueryRow(ctx, consumeUserToken, arg.TokenHash, arg.Purpose)
+	var user_id pgtype.UUID
+	err := row.Scan(&user_id)
+	return user_id, err
+}
+
+const countUnusedRecoveryCodes = `-- name: CountUnusedRecoveryCodes :one
+SELECT COUNT(*)
+FROM recovery_codes
+WHERE user_id = $1 AND used_at IS NULL
+`
+
+func (q *Queries) CountUnusedRecoveryCodes(ctx context.Context, userID pgtype.UUID) (int64, error) {
+	row := q.db.QueryRow(ctx, countUnusedRecoveryCodes, userID)
+	var count int64
+	err := row.Scan(&count)
+	return count, err
+}
+
+const createOIDCLogin = `-- name: CreateOIDCLogin :exec
+INSERT INTO oidc_logins (state_hash, provider, nonce, code_verifier, user_id, reauthenticate, expires_at)
+VALUES ($1, $2, $3, $4, $5, $6, $7)
+`
+
+type CreateOIDCLoginParams struct {
+	StateHash      []byte             `db:"state_hash" json:"state_hash"`
+	Provider       string             `db:"provider" json:"provider"`
+	Nonce          string             `db:"nonce" json:"nonce"`
+	CodeVerifier   string             `db:"code_verifier" json:"code_verifier"`
+	UserID         pgtype.UUID        `db:"user_id" json:"user_id"`
+	Reauthenticate bool               `db:"reauthenticate" json:"reauthenticate"`
+	ExpiresAt      pgtype.Timestamptz `db:"expires_at" json:"expires_at"`
+}
+
+func (q *Queries) CreateOIDCLogin(ctx context.Context, arg CreateOIDCLoginParams) error {
+	_, err := q.db.Exec(ctx, createOIDCLogin,
+		arg.StateHash,
+		arg.Provider,
+		arg.Nonce,
+		arg.CodeVerifier,
+		arg.UserID,
+		arg.Reauthenticate,
+		arg.ExpiresAt,
+	)
+	return err
+}
+
+const createPersonalAccessToken = `-- name: CreatePersonalAccessToken :one
+INSERT INTO personal_access_tokens (id, user_id, name, token_hash, scopes, expires_at)
+VALUES ($1, $2, $3, $4, $5, $6)
+RETURNING created_at
+`
+
+type CreatePersonalAccessTokenParams struct {
+	ID        pgtype.UUID        `db:"id" json:"id"`
+	UserID    pgtype.UUID        `db:"user_id" json:"user_id"`
+	Name      string             `db:"name" json:"name"`
+	TokenHash []byte             `db:"token_hash" json:"token_hash"`
+	Scopes    []string           `db:"scopes" json:"scopes"`
+	ExpiresAt pgtype.Timestamptz `db:"expires_at" json:"expires_at"`
+}
+
+func (q *Queries) CreatePersonalAccessToken(ctx context.Context, arg CreatePersonalAccessTokenParams) (pgtype.Timestamptz, error) {
+	row := q.db.QueryRow(ctx, createPersonalAccessToken,
+		arg.ID,
+		arg.UserID,
+		arg.Name,
+		arg.TokenHash,
+		arg.Scopes,
+		arg.ExpiresAt,
+	)
+	var created_at pgtype.Timestamptz
+	err := row.Scan(&created_at)
+	return created_at, err
+}
+
+const createRecoveryCode = `-- name: CreateRecoveryCode :exec
+INSERT INTO recovery_codes (id, user_id, code_hash)
+VALUES ($1, $2, $3)
+`
+
+type CreateRecoveryCodeParams struct {
+	ID       pgtype.UUID `db:"id" json:"id"`
+	UserID   pgtype.UUID `db:"user_id" json:"user_id"`
+	CodeHash []byte      `db:"code_hash" json:"code_hash"`
+}
+
+func (q *Queries) CreateRecoveryCode(ctx context.Context, arg CreateRecoveryCodeParams) error {
+	_, err := q.db.Exec(ctx, createRecoveryCode, arg.ID, arg.UserID, arg.CodeHash)
+	return err
+}
+
 const createRefreshToken = `-- name: CreateRefreshToken :exec
 INSERT INTO refresh_tokens (id, user_id, token_hash, family_id, expires_at)
 VALUES ($1, $2, $3, $4, $5)
@@ -35,10 +186,32 @@ func (q *Queries) CreateRefreshToken(ctx context.Context, arg CreateRefreshToken
 	return err
 }
 
+const createSession = `-- name: CreateSession :exec
+INSERT INTO sessions (family_id, user_id, user_agent, ip_address)
+VALUES ($1, $2, $3, $4)
+`
+
+type CreateSessionParams struct {
+	FamilyID  pgtype.UUID `db:"family_id" json:"family_id"`
+	UserID    pgtype.UUID `db:"user_id" json:"user_id"`
+	UserAgent pgtype.Text `db:"user_agent" json:"user_agent"`
+	IpAddress pgtype.Text `db:"ip_address" json:"ip_address"`
+}
+
+func (q *Queries) CreateSession(ctx context.Context, arg CreateSessionParams) error {
+	_, err := q.db.Exec(ctx, createSession,
+		arg.FamilyID,
+		arg.UserID,
+		arg.UserAgent,
+		arg.IpAddress,
+	)
+	return err
+}
+
 const createUser = `-- name: CreateUser :one
 INSERT INTO users (id, email, username, password_hash)
 VALUES ($1, LOWER($2), LOWER($3), $4)
-RETURNING id, email, username, password_hash, created_at, updated_at
+RETURNING id, email, username, password_hash, created_at, updated_at, role, email_verified_at
 `
 
 type CreateUserParams struct {
@@ -49,12 +222,14 @@ type CreateUserParams struct {
 }
 
 type CreateUserRow struct {
-	ID           pgtype.UUID        `db:"id" json:"id"`
-	Email        string             `db:"email" json:"email"`
-	Username     string             `db:"username" json:"username"`
-	PasswordHash string             `db:"password_hash" json:"password_hash"`
-	CreatedAt    pgtype.Timestamptz `db:"created_at" json:"created_at"`
-	UpdatedAt    pgtype.Timestamptz `db:"updated_at" json:"updated_at"`
+	ID              pgtype.UUID        `db:"id" json:"id"`
+	Email           string             `db:"email" json:"email"`
+	Username        string             `db:"username" json:"username"`
+	PasswordHash    string             `db:"password_hash" json:"password_hash"`
+	CreatedAt       pgtype.Timestamptz `db:"created_at" json:"created_at"`
+	UpdatedAt       pgtype.Timestamptz `db:"updated_at" json:"updated_at"`
+	Role            string             `db:"role" json:"role"`
+	EmailVerifiedAt pgtype.Timestamptz `db:"email_verified_at" json:"email_verified_at"`
 }
 
 func (q *Queries) CreateUser(ctx context.Context, arg CreateUserParams) (CreateUserRow, error) {
@@ -72,10 +247,171 @@ func (q *Queries) CreateUser(ctx context.Context, arg CreateUserParams) (CreateU
 		&i.PasswordHash,
 		&i.CreatedAt,
 		&i.UpdatedAt,
+		&i.Role,
+		&i.EmailVerifiedAt,
+	)
+	return i, err
+}
+
+const createUserIdentity = `-- name: CreateUserIdentity :exec
+INSERT INTO user_identities (id, user_id, provider, subject, email)
+VALUES ($1, $2, $3, $4, $5)
+`
+
+type CreateUserIdentityParams struct {
+	ID       pgtype.UUID `db:"id" json:"id"`
+	UserID   pgtype.UUID `db:"user_id" json:"user_id"`
+	Provider string      `db:"provider" json:"provider"`
+	Subject  string      `db:"subject" json:"subject"`
+	Email    pgtype.Text `db:"email" json:"email"`
+}
+
+func (q *Queries) CreateUserIdentity(ctx context.Context, arg CreateUserIdentityParams) error {
+	_, err := q.db.Exec(ctx, createUserIdentity,
+		arg.ID,
+		arg.UserID,
+		arg.Provider,
+		arg.Subject,
+		arg.Email,
+	)
+	return err
+}
+
+const createUserToken = `-- name: CreateUserToken :exec
+INSERT INTO user_tokens (id, user_id, purpose, token_hash, expires_at)
+VALUES ($1, $2, $3, $4, $5)
+`
+
+type CreateUserTokenParams struct {
+	ID        pgtype.UUID        `db:"id" json:"id"`
+	UserID    pgtype.UUID        `db:"user_id" json:"user_id"`
+	Purpose   string             `db:"purpose" json:"purpose"`
+	TokenHash []byte             `db:"token_hash" json:"token_hash"`
+	ExpiresAt pgtype.Timestamptz `db:"expires_at" json:"expires_at"`
+}
+
+func (q *Queries) CreateUserToken(ctx context.Context, arg CreateUserTokenParams) error {
+	_, err := q.db.Exec(ctx, createUserToken,
+		arg.ID,
+		arg.UserID,
+		arg.Purpose,
+		arg.TokenHash,
+		arg.ExpiresAt,
+	)
+	return err
+}
+
+const deleteExpiredOIDCLogins = `-- name: DeleteExpiredOIDCLogins :exec
+DELETE FROM oidc_logins
+WHERE expires_at <= NOW()
+`
+
+func (q *Queries) DeleteExpiredOIDCLogins(ctx context.Context) error {
+	_, err := q.db.Exec(ctx, deleteExpiredOIDCLogins)
+	return err
+}
+
+const deleteRecoveryCodes = `-- name: DeleteRecoveryCodes :exec
+DELETE FROM recovery_codes
+WHERE user_id = $1
+`
+
+func (q *Queries) DeleteRecoveryCodes(ctx context.Context, userID pgtype.UUID) error {
+	_, err := q.db.Exec(ctx, deleteRecoveryCodes, userID)
+	return err
+}
+
+const deleteUser = `-- name: DeleteUser :execrows
+DELETE FROM users
+WHERE id = $1
+`
+
+func (q *Queries) DeleteUser(ctx context.Context, id pgtype.UUID) (int64, error) {
+	result, err := q.db.Exec(ctx, deleteUser, id)
+	if err != nil {
+		return 0, err
+	}
+	return result.RowsAffected(), nil
+}
+
+const deleteUserIdentity = `-- name: DeleteUserIdentity :execrows
+DELETE FROM user_identities
+WHERE user_id = $1 AND provider = $2
+`
+
+type DeleteUserIdentityParams struct {
+	UserID   pgtype.UUID `db:"user_id" json:"user_id"`
+	Provider string      `db:"provider" json:"provider"`
+}
+
+func (q *Queries) DeleteUserIdentity(ctx context.Context, arg DeleteUserIdentityParams) (int64, error) {
+	result, err := q.db.Exec(ctx, deleteUserIdentity, arg.UserID, arg.Provider)
+	if err != nil {
+		return 0, err
+	}
+	return result.RowsAffected(), nil
+}
+
+const deleteUserTOTP = `-- name: DeleteUserTOTP :execrows
+DELETE FROM user_totp
+WHERE user_id = $1
+`
+
+func (q *Queries) DeleteUserTOTP(ctx context.Context, userID pgtype.UUID) (int64, error) {
+	result, err := q.db.Exec(ctx, deleteUserTOTP, userID)
+	if err != nil {
+		return 0, err
+	}
+	return result.RowsAffected(), nil
+}
+
+const getActivePersonalAccessToken = `-- name: GetActivePersonalAccessToken :one
+SELECT t.id, t.user_id, t.scopes, u.role
+FROM personal_access_tokens t
+JOIN users u ON u.id = t.user_id
+WHERE t.token_hash = $1
+  AND t.revoked_at IS NULL
+  AND (t.expires_at IS NULL OR t.expires_at > NOW())
+`
+
+type GetActivePersonalAccessTokenRow struct {
+	ID     pgtype.UUID `db:"id" json:"id"`
+	UserID pgtype.UUID `db:"user_id" json:"user_id"`
+	Scopes []string    `db:"scopes" json:"scopes"`
+	Role   string      `db:"role" json:"role"`
+}
+
+func (q *Queries) GetActivePersonalAccessToken(ctx context.Context, tokenHash []byte) (GetActivePersonalAccessTokenRow, error) {
+	row := q.db.QueryRow(ctx, getActivePersonalAccessToken, tokenHash)
+	var i GetActivePersonalAccessTokenRow
+	err := row.Scan(
+		&i.ID,
+		&i.UserID,
+		&i.Scopes,
+		&i.Role,
 	)
 	return i, err
 }
 
+const getActiveUserToken = `-- name: GetActiveUserToken :one
+SELECT user_id
+FROM user_tokens
+WHERE token_hash = $1 AND purpose = $2 AND used_at IS NULL AND expires_at > NOW()
+LIMIT 1
+`
+
+type GetActiveUserTokenParams struct {
+	TokenHash []byte `db:"token_hash" json:"token_hash"`
+	Purpose   string `db:"purpose" json:"purpose"`
+}
+
+func (q *Queries) GetActiveUserToken(ctx context.Context, arg GetActiveUserTokenParams) (pgtype.UUID, error) {
+	row := q.db.QueryRow(ctx, getActiveUserToken, arg.TokenHash, arg.Purpose)
+	var user_id pgtype.UUID
+	err := row.Scan(&user_id)
+	return user_id, err
+}
+
 const getRefreshToken = `-- name: GetRefreshToken :one
 SELECT id, user_id, token_hash, family_id, expires_at, revoked_at, created_at
 FROM refresh_tokens
@@ -109,19 +445,21 @@ func (q *Queries) GetRefreshToken(ctx context.Context, tokenHash []byte) (GetRef
 }
 
 const getUserByEmailOrUsername = `-- name: GetUserByEmailOrUsername :one
-SELECT id, email, username, password_hash, created_at, updated_at
+SELECT id, email, username, password_hash, created_at, updated_at, role, email_verified_at
 FROM users
 WHERE email = LOWER($1) OR username = LOWER($1)
 LIMIT 1
 `
 
 type GetUserByEmailOrUsernameRow struct {
-	ID           pgtype.UUID        `db:"id" json:"id"`
-	Email        string             `db:"email" json:"email"`
-	Username     string             `db:"username" json:"username"`
-	PasswordHash string             `db:"password_hash" json:"password_hash"`
-	CreatedAt    pgtype.Timestamptz `db:"created_at" json:"created_at"`
-	UpdatedAt    pgtype.Timestamptz `db:"updated_at" json:"updated_at"`
+	ID              pgtype.UUID        `db:"id" json:"id"`
+	Email           string             `db:"email" json:"email"`
+	Username        string             `db:"username" json:"username"`
+	PasswordHash    string             `db:"password_hash" json:"password_hash"`
+	CreatedAt       pgtype.Timestamptz `db:"created_at" json:"created_at"`
+	UpdatedAt       pgtype.Timestamptz `db:"updated_at" json:"updated_at"`
+	Role            string             `db:"role" json:"role"`
+	EmailVerifiedAt pgtype.Timestamptz `db:"email_verified_at" json:"email_verified_at"`
 }
 
 func (q *Queries) GetUserByEmailOrUsername(ctx context.Context, lower string) (GetUserByEmailOrUsernameRow, error) {
@@ -134,24 +472,28 @@ func (q *Queries) GetUserByEmailOrUsername(ctx context.Context, lower string) (G
 		&i.PasswordHash,
 		&i.CreatedAt,
 		&i.UpdatedAt,
+		&i.Role,
+		&i.EmailVerifiedAt,
 	)
 	return i, err
 }
 
 const getUserByID = `-- name: GetUserByID :one
-SELECT id, email, username, password_hash, created_at, updated_at
+SELECT id, email, username, password_hash, created_at, updated_at, role, email_verified_at
 FROM users
 WHERE id = $1
 LIMIT 1
 `
 
 type GetUserByIDRow struct {
-	ID           pgtype.UUID        `db:"id" json:"id"`
-	Email        string             `db:"email" json:"email"`
-	Username     string             `db:"username" json:"username"`
-	PasswordHash string             `db:"password_hash" json:"password_hash"`
-	CreatedAt    pgtype.Timestamptz `db:"created_at" json:"created_at"`
-	UpdatedAt    pgtype.Timestamptz `db:"updated_at" json:"updated_at"`
+	ID              pgtype.UUID        `db:"id" json:"id"`
+	Email           string             `db:"email" json:"email"`
+	Username        string             `db:"username" json:"username"`
+	PasswordHash    string             `db:"password_hash" json:"password_hash"`
+	CreatedAt       pgtype.Timestamptz `db:"created_at" json:"created_at"`
+	UpdatedAt       pgtype.Timestamptz `db:"updated_at" json:"updated_at"`
+	Role            string             `db:"role" json:"role"`
+	EmailVerifiedAt pgtype.Timestamptz `db:"email_verified_at" json:"email_verified_at"`
 }
 
 func (q *Queries) GetUserByID(ctx context.Context, id pgtype.UUID) (GetUserByIDRow, error) {
@@ -164,6 +506,34 @@ func (q *Queries) GetUserByID(ctx context.Context, id pgtype.UUID) (GetUserByIDR
 		&i.PasswordHash,
 		&i.CreatedAt,
 		&i.UpdatedAt,
+		&i.Role,
+		&i.EmailVerifiedAt,
+	)
+	return i, err
+}
+
+const getUserTOTP = `-- name: GetUserTOTP :one
+SELECT user_id, secret_ciphertext, confirmed_at, last_used_step
+FROM user_totp
+WHERE user_id = $1
+LIMIT 1
+`
+
+type GetUserTOTPRow struct {
+	UserID           pgtype.UUID        `db:"user_id" json:"user_id"`
+	SecretCiphertext []byte             `db:"secret_ciphertext" json:"secret_ciphertext"`
+	ConfirmedAt      pgtype.Timestamptz `db:"confirmed_at" json:"confirmed_at"`
+	LastUsedStep     int64              `db:"last_used_step" json:"last_used_step"`
+}
+
+func (q *Queries) GetUserTOTP(ctx context.Context, userID pgtype.UUID) (GetUserTOTPRow, error) {
+	row := q.db.QueryRow(ctx, getUserTOTP, userID)
+	var i GetUserTOTPRow
+	err := row.Scan(
+		&i.UserID,
+		&i.SecretCiphertext,
+		&i.ConfirmedAt,
+		&i.LastUsedStep,
 	)
 	return i, err
 }
@@ -192,6 +562,206 @@ func (q *Queries) InsertRotatedRefreshToken(ctx context.Context, arg InsertRotat
 	return err
 }
 
+const invalidateUserTokens = `-- name: InvalidateUserTokens :exec
+UPDATE user_tokens
+SET used_at = NOW()
+WHERE user_id = $1 AND purpose = $2 AND used_at IS NULL
+`
+
+type InvalidateUserTokensParams struct {
+	UserID  pgtype.UUID `db:"user_id" json:"user_id"`
+	Purpose string      `db:"purpose" json:"purpose"`
+}
+
+func (q *Queries) InvalidateUserTokens(ctx context.Context, arg InvalidateUserTokensParams) error {
+	_, err := q.db.Exec(ctx, invalidateUserTokens, arg.UserID, arg.Purpose)
+	return err
+}
+
+const listActiveSessions = `-- name: ListActiveSessions :many
+SELECT s.family_id, s.user_agent, s.ip_address, s.created_at, s.last_used_at, t.expires_at
+FROM sessions s
+JOIN refresh_tokens t ON t.family_id = s.family_id AND t.revoked_at IS NULL AND t.expires_at > NOW()
+WHERE s.user_id = $1
+ORDER BY s.last_used_at DESC, s.family_id
+`
+
+type ListActiveSessionsRow struct {
+	FamilyID   pgtype.UUID        `db:"family_id" json:"family_id"`
+	UserAgent  pgtype.Text        `db:"user_agent" json:"user_agent"`
+	IpAddress  pgtype.Text        `db:"ip_address" json:"ip_address"`
+	CreatedAt  pgtype.Timestamptz `db:"created_at" json:"created_at"`
+	LastUsedAt pgtype.Timestamptz `db:"last_used_at" json:"last_used_at"`
+	ExpiresAt  pgtype.Timestamptz `db:"expires_at" json:"expires_at"`
+}
+
+func (q *Queries) ListActiveSessions(ctx context.Context, userID pgtype.UUID) ([]ListActiveSessionsRow, error) {
+	rows, err := q.db.Query(ctx, listActiveSessions, userID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	items := []ListActiveSessionsRow{}
+	for rows.Next() {
+		var i ListActiveSessionsRow
+		if err := rows.Scan(
+			&i.FamilyID,
+			&i.UserAgent,
+			&i.IpAddress,
+			&i.CreatedAt,
+			&i.LastUsedAt,
+			&i.ExpiresAt,
+		); err != nil {
+			return nil, err
+		}
+		items = append(items, i)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
+
+const listPersonalAccessTokens = `-- name: ListPersonalAccessTokens :many
+SELECT id, name, scopes, expires_at, last_used_at, last_used_ip, created_at
+FROM personal_access_tokens
+WHERE user_id = $1 AND revoked_at IS NULL
+ORDER BY created_at DESC, id
+`
+
+type ListPersonalAccessTokensRow struct {
+	ID         pgtype.UUID        `db:"id" json:"id"`
+	Name       string             `db:"name" json:"name"`
+	Scopes     []string           `db:"scopes" json:"scopes"`
+	ExpiresAt  pgtype.Timestamptz `db:"expires_at" json:"expires_at"`
+	LastUsedAt pgtype.Timestamptz `db:"last_used_at" json:"last_used_at"`
+	LastUsedIp pgtype.Text        `db:"last_used_ip" json:"last_used_ip"`
+	CreatedAt  pgtype.Timestamptz `db:"created_at" json:"created_at"`
+}
+
+func (q *Queries) ListPersonalAccessTokens(ctx context.Context, userID pgtype.UUID) ([]ListPersonalAccessTokensRow, error) {
+	rows, err := q.db.Query(ctx, listPersonalAccessTokens, userID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	items := []ListPersonalAccessTokensRow{}
+	for rows.Next() {
+		var i ListPersonalAccessTokensRow
+		if err := rows.Scan(
+			&i.ID,
+			&i.Name,
+			&i.Scopes,
+			&i.ExpiresAt,
+			&i.LastUsedAt,
+			&i.LastUsedIp,
+			&i.CreatedAt,
+		); err != nil {
+			return nil, err
+		}
+		items = append(items, i)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
+
+const listUserIdentities = `-- name: ListUserIdentities :many
+SELECT provider, email, created_at, last_used_at
+FROM user_identities
+WHERE user_id = $1
+ORDER BY provider
+`
+
+type ListUserIdentitiesRow struct {
+	Provider   string             `db:"provider" json:"provider"`
+	Email      pgtype.Text        `db:"email" json:"email"`
+	CreatedAt  pgtype.Timestamptz `db:"created_at" json:"created_at"`
+	LastUsedAt pgtype.Timestamptz `db:"last_used_at" json:"last_used_at"`
+}
+
+func (q *Queries) ListUserIdentities(ctx context.Context, userID pgtype.UUID) ([]ListUserIdentitiesRow, error) {
+	rows, err := q.db.Query(ctx, listUserIdentities, userID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	items := []ListUserIdentitiesRow{}
+	for rows.Next() {
+		var i ListUserIdentitiesRow
+		if err := rows.Scan(
+			&i.Provider,
+			&i.Email,
+			&i.CreatedAt,
+			&i.LastUsedAt,
+		); err != nil {
+			return nil, err
+		}
+		items = append(items, i)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
+
+const markUserEmailVerified = `-- name: MarkUserEmailVerified :execrows
+UPDATE users
+SET email_verified_at = COALESCE(email_verified_at, NOW()), updated_at = NOW()
+WHERE id = $1
+`
+
+func (q *Queries) MarkUserEmailVerified(ctx context.Context, id pgtype.UUID) (int64, error) {
+	result, err := q.db.Exec(ctx, markUserEmailVerified, id)
+	if err != nil {
+		return 0, err
+	}
+	return result.RowsAffected(), nil
+}
+
+const revokeOtherUserSessions = `-- name: RevokeOtherUserSessions :execrows
+UPDATE refresh_tokens
+SET revoked_at = $1
+WHERE user_id = $2
+  AND revoked_at IS NULL
+  AND ($3::uuid IS NULL OR family_id <> $3::uuid)
+`
+
+type RevokeOtherUserSessionsParams struct {
+	RevokedAt    pgtype.Timestamptz `db:"revoked_at" json:"revoked_at"`
+	UserID       pgtype.UUID        `db:"user_id" json:"user_id"`
+	KeepFamilyID pgtype.UUID        `db:"keep_family_id" json:"keep_family_id"`
+}
+
+func (q *Queries) RevokeOtherUserSessions(ctx context.Context, arg RevokeOtherUserSessionsParams) (int64, error) {
+	result, err := q.db.Exec(ctx, revokeOtherUserSessions, arg.RevokedAt, arg.UserID, arg.KeepFamilyID)
+	if err != nil {
+		return 0, err
+	}
+	return result.RowsAffected(), nil
+}
+
+const revokePersonalAccessToken = `-- name: RevokePersonalAccessToken :execrows
+UPDATE personal_access_tokens
+SET revoked_at = $3
+WHERE id = $1 AND user_id = $2 AND revoked_at IS NULL
+`
+
+type RevokePersonalAccessTokenParams struct {
+	ID        pgtype.UUID        `db:"id" json:"id"`
+	UserID    pgtype.UUID        `db:"user_id" json:"user_id"`
+	RevokedAt pgtype.Timestamptz `db:"revoked_at" json:"revoked_at"`
+}
+
+func (q *Queries) RevokePersonalAccessToken(ctx context.Context, arg RevokePersonalAccessTokenParams) (int64, error) {
+	result, err := q.db.Exec(ctx, revokePersonalAccessToken, arg.ID, arg.UserID, arg.RevokedAt)
+	if err != nil {
+		return 0, err
+	}
+	return result.RowsAffected(), nil
+}
+
 const revokeRefreshTokenFamily = `-- name: RevokeRefreshTokenFamily :exec
 UPDATE refresh_tokens
 SET revoked_at = COALESCE(revoked_at, $2)
@@ -227,3 +797,208 @@ func (q *Queries) RevokeRefreshTokenForRotation(ctx context.Context, arg RevokeR
 	}
 	return result.RowsAffected(), nil
 }
+
+const revokeUserPersonalAccessTokens = `-- name: RevokeUserPersonalAccessTokens :exec
+UPDATE personal_access_tokens
+SET revoked_at = COALESCE(revoked_at, $2)
+WHERE user_id = $1
+`
+
+type RevokeUserPersonalAccessTokensParams struct {
+	UserID    pgtype.UUID        `db:"user_id" json:"user_id"`
+	RevokedAt pgtype.Timestamptz `db:"revoked_at" json:"revoked_at"`
+}
+
+func (q *Queries) RevokeUserPersonalAccessTokens(ctx context.Context, arg RevokeUserPersonalAccessTokensParams) error {
+	_, err := q.db.Exec(ctx, revokeUserPersonalAccessTokens, arg.UserID, arg.RevokedAt)
+	return err
+}
+
+const revokeUserRefreshTokens = `-- name: RevokeUserRefreshTokens :exec
+UPDATE refresh_tokens
+SET revoked_at = COALESCE(revoked_at, $2)
+WHERE user_id = $1
+`
+
+type RevokeUserRefreshTokensParams struct {
+	UserID    pgtype.UUID        `db:"user_id" json:"user_id"`
+	RevokedAt pgtype.Timestamptz `db:"revoked_at" json:"revoked_at"`
+}
+
+func (q *Queries) RevokeUserRefreshTokens(ctx context.Context, arg RevokeUserRefreshTokensParams) error {
+	_, err := q.db.Exec(ctx, revokeUserRefreshTokens, arg.UserID, arg.RevokedAt)
+	return err
+}
+
+const revokeUserSession = `-- name: RevokeUserSession :execrows
+UPDATE refresh_tokens
+SET revoked_at = $3
+WHERE user_id = $1 AND family_id = $2 AND revoked_at IS NULL
+`
+
+type RevokeUserSessionParams struct {
+	UserID    pgtype.UUID        `db:"user_id" json:"user_id"`
+	FamilyID  pgtype.UUID        `db:"family_id" json:"family_id"`
+	RevokedAt pgtype.Timestamptz `db:"revoked_at" json:"revoked_at"`
+}
+
+func (q *Queries) RevokeUserSession(ctx context.Context, arg RevokeUserSessionParams) (int64, error) {
+	result, err := q.db.Exec(ctx, revokeUserSession, arg.UserID, arg.FamilyID, arg.RevokedAt)
+	if err != nil {
+		return 0, err
+	}
+	return result.RowsAffected(), nil
+}
+
+const touchPersonalAccessToken = `-- name: TouchPersonalAccessToken :exec
+UPDATE personal_access_tokens
+SET last_used_at = NOW(), last_used_ip = $2
+WHERE id = $1
+  AND (last_used_at IS NULL OR last_used_at < NOW() - INTERVAL '1 minute' OR last_used_ip IS DISTINCT FROM $2)
+`
+
+type TouchPersonalAccessTokenParams struct {
+	ID         pgtype.UUID `db:"id" json:"id"`
+	LastUsedIp pgtype.Text `db:"last_used_ip" json:"last_used_ip"`
+}
+
+func (q *Queries) TouchPersonalAccessToken(ctx context.Context, arg TouchPersonalAccessTokenParams) error {
+	_, err := q.db.Exec(ctx, touchPersonalAccessToken, arg.ID, arg.LastUsedIp)
+	return err
+}
+
+const touchSession = `-- name: TouchSession :exec
+UPDATE sessions
+SET user_agent = COALESCE($1, user_agent),
+    ip_address = COALESCE($2, ip_address),
+    last_used_at = NOW()
+WHERE family_id = $3
+`
+
+type TouchSessionParams struct {
+	UserAgent pgtype.Text `db:"user_agent" json:"user_agent"`
+	IpAddress pgtype.Text `db:"ip_address" json:"ip_address"`
+	FamilyID  pgtype.UUID `db:"family_id" json:"family_id"`
+}
+
+func (q *Queries) TouchSession(ctx context.Context, arg TouchSessionParams) error {
+	_, err := q.db.Exec(ctx, touchSession, arg.UserAgent, arg.IpAddress, arg.FamilyID)
+	return err
+}
+
+const updateUserPassword = `-- name: UpdateUserPassword :execrows
+UPDATE users
+SET password_hash = $2, email_verified_at = COALESCE(email_verified_at, NOW()), updated_at = NOW()
+WHERE id = $1
+`
+
+type UpdateUserPasswordParams struct {
+	ID           pgtype.UUID `db:"id" json:"id"`
+	PasswordHash string      `db:"password_hash" json:"password_hash"`
+}
+
+func (q *Queries) UpdateUserPassword(ctx context.Context, arg UpdateUserPasswordParams) (int64, error) {
+	result, err := q.db.Exec(ctx, updateUserPassword, arg.ID, arg.PasswordHash)
+	if err != nil {
+		return 0, err
+	}
+	return result.RowsAffected(), nil
+}
+
+const updateUserRole = `-- name: UpdateUserRole :execrows
+UPDATE users
+SET role = $2, updated_at = NOW()
+WHERE id = $1
+`
+
+type UpdateUserRoleParams struct {
+	ID   pgtype.UUID `db:"id" json:"id"`
+	Role string      `db:"role" json:"role"`
+}
+
+func (q *Queries) UpdateUserRole(ctx context.Context, arg UpdateUserRoleParams) (int64, error) {
+	result, err := q.db.Exec(ctx, updateUserRole, arg.ID, arg.Role)
+	if err != nil {
+		return 0, err
+	}
+	return result.RowsAffected(), nil
+}
+
+const upsertPendingUserTOTP = `-- name: UpsertPendingUserTOTP :execrows
+INSERT INTO user_totp (user_id, secret_ciphertext)
+VALUES ($1, $2)
+ON CONFLICT (user_id) DO UPDATE
+SET secret_ciphertext = EXCLUDED.secret_ciphertext, last_used_step = 0, created_at = NOW()
+WHERE user_totp.confirmed_at IS NULL
+`
+
+type UpsertPendingUserTOTPParams struct {
+	UserID           pgtype.UUID `db:"user_id" json:"user_id"`
+	SecretCiphertext []byte      `db:"secret_ciphertext" json:"secret_ciphertext"`
+}
+
+func (q *Queries) UpsertPendingUserTOTP(ctx context.Context, arg UpsertPendingUserTOTPParams) (int64, error) {
+	result, err := q.db.Exec(ctx, upsertPendingUserTOTP, arg.UserID, arg.SecretCiphertext)
+	if err != nil {
+		return 0, err
+	}
+	return result.RowsAffected(), nil
+}
+
+const useRecoveryCode = `-- name: UseRecoveryCode :execrows
+UPDATE recovery_codes
+SET used_at = NOW()
+WHERE user_id = $1 AND code_hash = $2 AND used_at IS NULL
+`
+
+type UseRecoveryCodeParams struct {
+	UserID   pgtype.UUID `db:"user_id" json:"user_id"`
+	CodeHash []byte      `db:"code_hash" json:"code_hash"`
+}
+
+func (q *Queries) UseRecoveryCode(ctx context.Context, arg UseRecoveryCodeParams) (int64, error) {
+	result, err := q.db.Exec(ctx, useRecoveryCode, arg.UserID, arg.CodeHash)
+	if err != nil {
+		return 0, err
+	}
+	return result.RowsAffected(), nil
+}
+
+const useUserIdentity = `-- name: UseUserIdentity :one
+UPDATE user_identities
+SET email = COALESCE($1, email), last_used_at = NOW()
+WHERE provider = $2 AND subject = $3
+RETURNING user_id
+`
+
+type UseUserIdentityParams struct {
+	Email    pgtype.Text `db:"email" json:"email"`
+	Provider string      `db:"provider" json:"provider"`
+	Subject  string      `db:"subject" json:"subject"`
+}
+
+func (q *Queries) UseUserIdentity(ctx context.Context, arg UseUserIdentityParams) (pgtype.UUID, error) {
+	row := q.db.QueryRow(ctx, useUserIdentity, arg.Email, arg.Provider, arg.Subject)
+	var user_id pgtype.UUID
+	err := row.Scan(&user_id)
+	return user_id, err
+}
+
+const useUserTOTPStep = `-- name: UseUserTOTPStep :execrows
+UPDATE user_totp
+SET last_used_step = $2
+WHERE user_id = $1 AND confirmed_at IS NOT NULL AND last_used_step < $2
+`
+
+type UseUserTOTPStepParams struct {
+	UserID       pgtype.UUID `db:"user_id" json:"user_id"`
+	LastUsedStep int64       `db:"last_used_step" json:"last_used_step"`
+}
+
+func (q *Queries) UseUserTOTPStep(ctx context.Context, arg UseUserTOTPStepParams) (int64, error) {
+	result, err := q.db.Exec(ctx, useUserTOTPStep, arg.UserID, arg.LastUsedStep)
+	if err != nil {
+		return 0, err
+	}
+	return result.RowsAffected(), nil
+}
@@ -11,10 +11,129 @@ import (
 	"github.com/jackc/pgx/v5/pgtype"
 )
 
+const addCollectionMember = `-- name: AddCollectionMember :exec
+INSERT INTO collection_members (collection_id, user_id, role, invited_by)
+VALUES ($1, $2, $3, $4)
+ON CONFLICT (collection_id, user_id) DO NOTHING
+`
+
+type AddCollectionMemberParams struct {
+	CollectionID pgtype.UUID `db:"collection_id" json:"collection_id"`
+	UserID       pgtype.UUID `db:"user_id" json:"user_id"`
+	Role         string      `db:"role" json:"role"`
+	InvitedBy    pgtype.UUID `db:"invited_by" json:"invited_by"`
+}
+
+func (q *Queries) AddCollectionMember(ctx context.Context, arg AddCollectionMemberParams) error {
+	_, err := q.db.Exec(ctx, addCollectionMember,
+		arg.CollectionID,
+		arg.UserID,
+		arg.Role,
+		arg.InvitedBy,
+	)
+	return err
+}
+
+const countCollectionActivity = `-- name: CountCollectionActivity :one
+SELECT COUNT(*) FROM collection_activity WHERE collection_id = $1
+`
+
+func (q *Queries) CountCollectionActivity(ctx context.Context, collectionID pgtype.UUID) (int64, error) {
+	row := q.db.QueryRow(ctx, countCollectionActivity, collectionID)
+	var count int64
+	err := row.Scan(&count)
+	return count, err
+}
+
+const countCollectionItems = `-- name: CountCollectionItems :one
+SELECT COUNT(*) FROM collection_items WHERE collection_id = $1
+`
+
+func (q *Queries) CountCollectionItems(ctx context.Context, collectionID pgtype.UUID) (int64, error) {
+	row := q.db.QueryRow(ctx, countCollectionItems, collectionID)
+	var count int64
+	err := row.Scan(&count)
+	return count, err
+}
+
+const countCollections = `-- name: CountCollections :one
+SELECT COUNT(*)
+FROM collections
+WHERE ($1::uuid IS NULL OR user_id = $1::uuid)
+  AND ($2::uuid IS NULL OR EXISTS (
+    SELECT 1 FROM collection_members m WHERE m.collection_id = collections.id AND m.user_id = $2::uuid))
+  AND (NOT $3::bool OR is_public = true)
+`
+
+type CountCollectionsParams struct {
+	UserID     pgtype.UUID `db:"user_id" json:"user_id"`
+	MemberID   pgtype.UUID `db:"member_id" json:"member_id"`
+	PublicOnly bool        `db:"public_only" json:"public_only"`
+}
+
+func (q *Queries) CountCollections(ctx context.Context, arg CountCollectionsParams) (int64, error) {
+	row := q.db.QueryRow(ctx, countCollections, arg.UserID, arg.MemberID, arg.PublicOnly)
+	var count int64
+	err := row.Scan(&count)
+	return count, err
+}
+
+const countSmartCollectionMatches = `-- name: CountSmartCollectionMatches :one
+SELECT COUNT(*)
+FROM sources s
+LEFT JOIN user_library_items li ON li.source_id = s.id AND li.user_id = $1::uuid
+  AND (NOT $2::bool OR li.visibility = 'public')
+LEFT JOIN reviews r ON r.source_id = s.id AND r.user_id = $1::uuid
+  AND (NOT $2::bool OR r.is_public = true)
+WHERE (cardinality($3::text[]) = 0 OR s.type = ANY($3::text[]))
+  AND (NOT $4::bool OR li.id IS NOT NULL)
+  AND (cardinality($5::text[]) = 0 OR li.status = ANY($5::text[]))
+  AND ($6::timestamptz IS NULL OR li.created_at >= $6::timestamptz)
+  AND ($7::int IS NULL OR r.rating >= $7::int)
+  AND ($8::int IS NULL OR r.rating <= $8::int)
+  AND NOT EXISTS (
+    SELECT 1 FROM unnest($9::text[]) AS wanted(slug)
+    WHERE NOT EXISTS (
+        SELECT 1 FROM source_tags st JOIN tags t ON t.id = st.tag_id
+        WHERE st.source_id = s.id AND t.slug = wanted.slug)
+      AND ($2::bool OR NOT EXISTS (
+        SELECT 1 FROM library_item_tags lt JOIN tags t ON t.id = lt.tag_id
+        WHERE lt.library_item_id = li.id AND t.slug = wanted.slug)))
+`
+
+type CountSmartCollectionMatchesParams struct {
+	OwnerID    pgtype.UUID        `db:"owner_id" json:"owner_id"`
+	PublicOnly bool               `db:"public_only" json:"public_only"`
+	Types      []string           `db:"types" json:"types"`
+	InLibrary  bool               `db:"in_library" json:"in_library"`
+	Statuses   []string           `db:"statuses" json:"statuses"`
+	AddedSince pgtype.Timestamptz `db:"added_since" json:"added_since"`
+	MinRating  pgtype.Int4        `db:"min_rating" json:"min_rating"`
+	MaxRating  pgtype.Int4        `db:"max_rating" json:"max_rating"`
+	TagSlugs   []string           `db:"tag_slugs" json:"tag_slugs"`
+}
+
+func (q *Queries) CountSmartCollectionMatches(ctx context.Context, arg CountSmartCollectionMatchesParams) (int64, error) {
+	row := q.db.QueryRow(ctx, countSmartCollectionMatches,
+		arg.OwnerID,
+		arg.PublicOnly,
+		arg.Types,
+		arg.InLibrary,
+		arg.Statuses,
+		arg.AddedSince,
+		arg.MinRating,
+		arg.MaxRating,
+		arg.TagSlugs,
+	)
+	var count int64
+	err := row.Scan(&count)
+	return count, err
+}
+
 const createCollection = `-- name: CreateCollection :one
-INSERT INTO collections (id, user_id, name, description, is_public, source_ids)
+INSERT INTO collections (id, user_id, name, description, is_public, rules)
 VALUES ($1, $2, $3, $4, $5, $6)
-RETURNING id, user_id, name, description, is_public, source_ids, created_at, updated_at
+RETURNING id, user_id, name, description, is_public, created_at, updated_at, rules
 `
 
 type CreateCollectionParams struct {
@@ -23,7 +142,7 @@ type CreateCollectionParams struct {
 	Name        string      `db:"name" json:"name"`
 	Description pgtype.Text `db:"description" json:"description"`
 	IsPublic    pgtype.Bool `db:"is_public" json:"is_public"`
-	SourceIds   []byte      `db:"source_ids" json:"source_ids"`
+	Rules       []byte      `db:"rules" json:"rules"`
 }
 
 func (q *Queries) CreateCollection(ctx context.Context, arg CreateCollectionParams) (Collection, error) {
@@ -33,7 +152,7 @@ func (q *Queries) CreateCollection(ctx context.Context, arg CreateCollectionPara
 		arg.Name,
 		arg.Description,
 		arg.IsPublic,
-		arg.SourceIds,
+		arg.Rules,
 	)
 	var i Collection
 	err := row.Scan(
@@ -42,24 +161,91 @@ func (q *Queries) CreateCollection(ctx context.Context, arg CreateCollectionPara
 		&i.Name,
 		&i.Description,
 		&i.IsPublic,
-		&i.SourceIds,
 		&i.CreatedAt,
 		&i.UpdatedAt,
+		&i.Rules,
 	)
 	return i, err
 }
 
-const deleteCollection = `-- name: DeleteCollection :exec
+const deleteCollection = `-- name: DeleteCollection :execrows
 DELETE FROM collections WHERE id = $1
 `
 
-func (q *Queries) DeleteCollection(ctx context.Context, id pgtype.UUID) error {
-	_, err := q.db.Exec(ctx, deleteCollection, id)
+func (q *Queries) DeleteCollection(ctx context.Context, id pgtype.UUID) (int64, error) {
+	result, err := q.db.Exec(ctx, deleteCollection, id)
+	if err != nil {
+		return 0, err
+	}
+	return result.RowsAffected(), nil
+}
+
+const deleteCollectionInvitation = `-- name: DeleteCollectionInvitation :execrows
+DELETE FROM collection_invitations WHERE id = $1
+`
+
+func (q *Queries) DeleteCollectionInvitation(ctx context.Context, id pgtype.UUID) (int64, error) {
+	result, err := q.db.Exec(ctx, deleteCollectionInvitation, id)
+	if err != nil {
+		return 0, err
+	}
+	return result.RowsAffected(), nil
+}
+
+const deleteCollectionItem = `-- name: DeleteCollectionItem :one
+DELETE FROM collection_items
+WHERE collection_id = $1 AND source_id = $2
+RETURNING position
+`
+
+type DeleteCollectionItemParams struct {
+	CollectionID pgtype.UUID `db:"collection_id" json:"collection_id"`
+	SourceID     pgtype.UUID `db:"source_id" json:"source_id"`
+}
+
+func (q *Queries) DeleteCollectionItem(ctx context.Context, arg DeleteCollectionItemParams) (int32, error) {
+	row := q.db.QueryRow(ctx, deleteCollectionItem, arg.CollectionID, arg.SourceID)
+	var position int32
+	err := row.Scan(&position)
+	return position, err
+}
+
+const deleteCollectionItemsExcept = `-- name: DeleteCollectionItemsExcept :exec
+DELETE FROM collection_items
+WHERE collection_id = $1
+  AND NOT (source_id = ANY($2::uuid[]))
+`
+
+type DeleteCollectionItemsExceptParams struct {
+	CollectionID pgtype.UUID   `db:"collection_id" json:"collection_id"`
+	SourceIds    []pgtype.UUID `db:"source_ids" json:"source_ids"`
+}
+
+func (q *Queries) DeleteCollectionItemsExcept(ctx context.Context, arg DeleteCollectionItemsExceptParams) error {
+	_, err := q.db.Exec(ctx, deleteCollectionItemsExcept, arg.CollectionID, arg.SourceIds)
 	return err
 }
 
+const deleteCollectionMember = `-- name: DeleteCollectionMember :execrows
+DELETE FROM collection_members
+WHERE collection_id = $1 AND user_id = $2 AND role <> 'owner'
+`
+
+type DeleteCollectionMemberParams struct {
+	CollectionID pgtype.UUID `db:"collection_id" json:"collection_id"`
+	UserID       pgtype.UUID `db:"user_id" json:"user_id"`
+}
+
+func (q *Queries) DeleteCollectionMember(ctx context.Context, arg DeleteCollectionMemberParams) (int64, error) {
+	result, err := q.db.Exec(ctx, deleteCollectionMember, arg.CollectionID, arg.UserID)
+	if err != nil {
+		return 0, err
+	}
+	return result.RowsAffected(), nil
+}
+
 const getCollectionByID = `-- name: GetCollectionByID :one
-SELECT id, user_id, name, description, is_public, source_ids, created_at, updated_at
+SELECT id, user_id, name, description, is_public, created_at, updated_at, rules
 FROM collections
 WHERE id = $1
 LIMIT 1
@@ -74,15 +260,545 @@ func (q *Queries) GetCollectionByID(ctx context.Context, id pgtype.UUID) (Collec
 		&i.Name,
 		&i.Description,
 		&i.IsPublic,
-		&i.SourceIds,
 		&i.CreatedAt,
 		&i.UpdatedAt,
+		&i.Rules,
+	)
+	return i, err
+}
+
+const getCollectionInvitation = `-- name: GetCollectionInvitation :one
+SELECT i.id, i.collection_id, c.name AS collection_name, i.user_id, u.username, i.role, i.invited_by, inviter.username AS invited_by_username, i.created_at
+FROM collection_invitations i
+JOIN collections c ON c.id = i.collection_id
+JOIN users u ON u.id = i.user_id
+JOIN users inviter ON inviter.id = i.invited_by
+WHERE i.id = $1
+`
+
+type GetCollectionInvitationRow struct {
+	ID                pgtype.UUID        `db:"id" json:"id"`
+	CollectionID      pgtype.UUID        `db:"collection_id" json:"collection_id"`
+	CollectionName    string             `db:"collection_name" json:"collection_name"`
+	UserID            pgtype.UUID        `db:"user_id" json:"user_id"`
+	Username          string             `db:"username" json:"username"`
+	Role              string             `db:"role" json:"role"`
+	InvitedBy         pgtype.UUID        `db:"invited_by" json:"invited_by"`
+	InvitedByUsername string             `db:"invited_by_username" json:"invited_by_username"`
+	CreatedAt         pgtype.Timestamptz `db:"created_at" json:"created_at"`
+}
+
+func (q *Queries) GetCollectionInvitation(ctx context.Context, id pgtype.UUID) (GetCollectionInvitationRow, error) {
+	row := q.db.QueryRow(ctx, getCollectionInvitation, id)
+	var i GetCollectionInvitationRow
+	err := row.Scan(
+		&i.ID,
+		&i.CollectionID,
+		&i.CollectionName,
+		&i.UserID,
+		&i.Username,
+		&i.Role,
+		&i.InvitedBy,
+		&i.InvitedByUsername,
+		&i.CreatedAt,
+	)
+	return i, err
+}
+
+const getCollectionItem = `-- name: GetCollectionItem :one
+SELECT ci.collection_id, ci.source_id, ci.position, ci.note, ci.added_at, ci.added_by,
+       s.title, s.subtitle, s.type, s.publisher, s.isbn
+FROM collection_items ci
+JOIN sources s ON s.id = ci.source_id
+WHERE ci.collection_id = $1 AND ci.source_id = $2
+`
+
+type GetCollectionItemParams struct {
+	CollectionID pgtype.UUID `db:"collection_id" json:"collection_id"`
+	SourceID     pgtype.UUID `db:"source_id" json:"source_id"`
+}
+
+type GetCollectionItemRow struct {
+	CollectionID pgtype.UUID        `db:"collection_id" json:"collection_id"`
+	SourceID     pgtype.UUID        `db:"source_id" json:"source_id"`
+	Position     int32              `db:"position" json:"position"`
+	Note         pgtype.Text        `db:"note" json:"note"`
+	AddedAt      pgtype.Timestamptz `db:"added_at" json:"added_at"`
+	AddedBy      pgtype.UUID        `db:"added_by" json:"added_by"`
+	Title        string             `db:"title" json:"title"`
+	Subtitle     pgtype.Text        `db:"subtitle" json:"subtitle"`
+	Type         string             `db:"type" json:"type"`
+	Publisher    pgtype.Text        `db:"publisher" json:"publisher"`
+	Isbn         pgtype.Text        `db:"isbn" json:"isbn"`
+}
+
+func (q *Queries) GetCollectionItem(ctx context.Context, arg GetCollectionItemParams) (GetCollectionItemRow, error) {
+	row := q.db.QueryRow(ctx, getCollectionItem, arg.CollectionID, arg.SourceID)
+	var i GetCollectionItemRow
+	err := row.Scan(
+		&i.CollectionID,
+		&i.SourceID,
+		&i.Position,
+		&i.Note,
+		&i.AddedAt,
+		&i.AddedBy,
+		&i.Title,
+		&i.Subtitle,
+		&i.Type,
+		&i.Publisher,
+		&i.Isbn,
+	)
+	return i, err
+}
+
+const getCollectionMember = `-- name: GetCollectionMember :one
+SELECT m.collection_id, m.user_id, u.username, m.role, m.invited_by, m.joined_at
+FROM collection_members m
+JOIN users u ON u.id = m.user_id
+WHERE m.collection_id = $1 AND m.user_id = $2
+`
+
+type GetCollectionMemberParams struct {
+	CollectionID pgtype.UUID `db:"collection_id" json:"collection_id"`
+	UserID       pgtype.UUID `db:"user_id" json:"user_id"`
+}
+
+type GetCollectionMemberRow struct {
+	CollectionID pgtype.UUID        `db:"collection_id" json:"collection_id"`
+	UserID       pgtype.UUID        `db:"user_id" json:"user_id"`
+	Username     string             `db:"username" json:"username"`
+	Role         string             `db:"role" json:"role"`
+	InvitedBy    pgtype.UUID        `db:"invited_by" json:"invited_by"`
+	JoinedAt     pgtype.Timestamptz `db:"joined_at" json:"joined_at"`
+}
+
+func (q *Queries) GetCollectionMember(ctx context.Context, arg GetCollectionMemberParams) (GetCollectionMemberRow, error) {
+	row := q.db.QueryRow(ctx, getCollectionMember, arg.CollectionID, arg.UserID)
+	var i GetCollectionMemberRow
+	err := row.Scan(
+		&i.CollectionID,
+		&i.UserID,
+		&i.Username,
+		&i.Role,
+		&i.InvitedBy,
+		&i.JoinedAt,
 	)
 	return i, err
 }
 
+const getCollectionMemberRole = `-- name: GetCollectionMemberRole :one
+SELECT role FROM collection_members WHERE collection_id = $1 AND user_id = $2
+`
+
+type GetCollectionMemberRoleParams struct {
+	CollectionID pgtype.UUID `db:"collection_id" json:"collection_id"`
+	UserID       pgtype.UUID `db:"user_id" json:"user_id"`
+}
+
+func (q *Queries) GetCollectionMemberRole(ctx context.Context, arg GetCollectionMemberRoleParams) (string, error) {
+	row := q.db.QueryRow(ctx, getCollectionMemberRole, arg.CollectionID, arg.UserID)
+	var role string
+	err := row.Scan(&role)
+	return role, err
+}
+
+const getUserIDByUsername = `-- name: GetUserIDByUsername :one
+SELECT id FROM users WHERE username = LOWER($1)
+`
+
+func (q *Queries) GetUserIDByUsername(ctx context.Context, lower string) (pgtype.UUID, error) {
+	row := q.db.QueryRow(ctx, getUserIDByUsername, lower)
+	var id pgtype.UUID
+	err := row.Scan(&id)
+	return id, err
+}
+
+const insertCollectionActivity = `-- name: InsertCollectionActivity :exec
+INSERT INTO collection_activity (id, collection_id, actor_id, action, source_id, member_id, role)
+VALUES ($1, $2, $3, $4, $5, $6, $7)
+`
+
+type InsertCollectionActivityParams struct {
+	ID           pgtype.UUID `db:"id" json:"id"`
+	CollectionID pgtype.UUID `db:"collection_id" json:"collection_id"`
+	ActorID      pgtype.UUID `db:"actor_id" json:"actor_id"`
+	Action       string      `db:"action" json:"action"`
+	SourceID     pgtype.UUID `db:"source_id" json:"source_id"`
+	MemberID     pgtype.UUID `db:"member_id" json:"member_id"`
+	Role         pgtype.Text `db:"role" json:"role"`
+}
+
+func (q *Queries) InsertCollectionActivity(ctx context.Context, arg InsertCollectionActivityParams) error {
+	_, err := q.db.Exec(ctx, insertCollectionActivity,
+		arg.ID,
+		arg.CollectionID,
+		arg.ActorID,
+		arg.Action,
+		arg.SourceID,
+		arg.MemberID,
+		arg.Role,
+	)
+	return err
+}
+
+const insertCollectionItem = `-- name: InsertCollectionItem :execrows
+INSERT INTO collection_items (collection_id, source_id, position, note, added_by)
+VALUES ($1, $2, $3, $4, $5)
+ON CONFLICT DO NOTHING
+`
+
+type InsertCollectionItemParams struct {
+	CollectionID pgtype.UUID `db:"collection_id" json:"collection_id"`
+	SourceID     pgtype.UUID `db:"source_id" json:"source_id"`
+	Position     int32       `db:"position" json:"position"`
+	Note         pgtype.Text `db:"note" json:"note"`
+	AddedBy      pgtype.UUID `db:"added_by" json:"added_by"`
+}
+
+func (q *Queries) InsertCollectionItem(ctx context.Context, arg InsertCollectionItemParams) (int64, error) {
+	result, err := q.db.Exec(ctx, insertCollectionItem,
+		arg.CollectionID,
+		arg.SourceID,
+		arg.Position,
+		arg.Note,
+		arg.AddedBy,
+	)
+	if err != nil {
+		return 0, err
+	}
+	return result.RowsAffected(), nil
+}
+
+const listCollectionActivityNewest = `-- name: ListCollectionActivityNewest :many
+SELECT a.id, a.collection_id, a.actor_id, actor.username AS actor_username, a.action,
+       a.source_id, s.title AS source_title, a.member_id, member.username AS member_username, a.role, a.created_at
+FROM collection_activity a
+LEFT JOIN users actor ON actor.id = a.actor_id
+LEFT JOIN sources s ON s.id = a.source_id
+LEFT JOIN users member ON member.id = a.member_id
+WHERE a.collection_id = $1
+  AND (a.created_at, a.id) < ($2::timestamptz, $3::uuid)
+ORDER BY a.created_at DESC, a.id DESC
+LIMIT $4
+`
+
+type ListCollectionActivityNewestParams struct {
+	CollectionID   pgtype.UUID        `db:"collection_id" json:"collection_id"`
+	AfterCreatedAt pgtype.Timestamptz `db:"after_created_at" json:"after_created_at"`
+	AfterID        pgtype.UUID        `db:"after_id" json:"after_id"`
+	Limit          int32              `db:"limit" json:"limit"`
+}
+
+type ListCollectionActivityNewestRow struct {
+	ID             pgtype.UUID        `db:"id" json:"id"`
+	CollectionID   pgtype.UUID        `db:"collection_id" json:"collection_id"`
+	ActorID        pgtype.UUID        `db:"actor_id" json:"actor_id"`
+	ActorUsername  pgtype.Text        `db:"actor_username" json:"actor_username"`
+	Action         string             `db:"action" json:"action"`
+	SourceID       pgtype.UUID        `db:"source_id" json:"source_id"`
+	SourceTitle    pgtype.Text        `db:"source_title" json:"source_title"`
+	MemberID       pgtype.UUID        `db:"member_id" json:"member_id"`
+	MemberUsername pgtype.Text        `db:"member_username" json:"member_username"`
+	Role           pgtype.Text        `db:"role" json:"role"`
+	CreatedAt      pgtype.Timestamptz `db:"created_at" json:"created_at"`
+}
+
+func (q *Queries) ListCollectionActivityNewest(ctx context.Context, arg ListCollectionActivityNewestParams) ([]ListCollectionActivityNewestRow, error) {
+	rows, err := q.db.Query(ctx, listCollectionActivityNewest,
+		arg.CollectionID,
+		arg.AfterCreatedAt,
+		arg.AfterID,
+		arg.Limit,
+	)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	items := []ListCollectionActivityNewestRow{}
+	for rows.Next() {
+		var i ListCollectionActivityNewestRow
+		if err := rows.Scan(
+			&i.ID,
+			&i.CollectionID,
+			&i.ActorID,
+			&i.ActorUsername,
+			&i.Action,
+			&i.SourceID,
+			&i.SourceTitle,
+			&i.MemberID,
+			&i.MemberUsername,
+			&i.Role,
+			&i.CreatedAt,
+		); err != nil {
+			return nil, err
+		}
+		items = append(items, i)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
+
+const listCollectionActivityOldest = `-- name: ListCollectionActivityOldest :many
+SELECT a.id, a.collection_id, a.actor_id, actor.username AS actor_username, a.action,
+       a.source_id, s.title AS source_title, a.member_id, member.username AS member_username, a.role, a.created_at
+FROM collection_activity a
+LEFT JOIN users actor ON actor.id = a.actor_id
+LEFT JOIN sources s ON s.id = a.source_id
+LEFT JOIN users member ON member.id = a.member_id
+WHERE a.collection_id = $1
+  AND (a.created_at, a.id) > ($2::timestamptz, $3::uuid)
+ORDER BY a.created_at ASC, a.id ASC
+LIMIT $4
+`
+
+type ListCollectionActivityOldestParams struct {
+	CollectionID   pgtype.UUID        `db:"collection_id" json:"collection_id"`
+	AfterCreatedAt pgtype.Timestamptz `db:"after_created_at" json:"after_created_at"`
+	AfterID        pgtype.UUID        `db:"after_id" json:"after_id"`
+	Limit          int32              `db:"limit" json:"limit"`
+}
+
+type ListCollectionActivityOldestRow struct {
+	ID             pgtype.UUID        `db:"id" json:"id"`
+	CollectionID   pgtype.UUID        `db:"collection_id" json:"collection_id"`
+	ActorID        pgtype.UUID        `db:"actor_id" json:"actor_id"`
+	ActorUsername  pgtype.Text        `db:"actor_username" json:"actor_username"`
+	Action         string             `db:"action" json:"action"`
+	SourceID       pgtype.UUID        `db:"source_id" json:"source_id"`
+	SourceTitle    pgtype.Text        `db:"source_title" json:"source_title"`
+	MemberID       pgtype.UUID        `db:"member_id" json:"member_id"`
+	MemberUsername pgtype.Text        `db:"member_username" json:"member_username"`
+	Role           pgtype.Text        `db:"role" json:"role"`
+	CreatedAt      pgtype.Timestamptz `db:"created_at" json:"created_at"`
+}
+
+func (q *Queries) ListCollectionActivityOldest(ctx context.Context, arg ListCollectionActivityOldestParams) ([]ListCollectionActivityOldestRow, error) {
+	rows, err := q.db.Query(ctx, listCollectionActivityOldest,
+		arg.CollectionID,
+		arg.AfterCreatedAt,
+		arg.AfterID,
+		arg.Limit,
+	)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	items := []ListCollectionActivityOldestRow{}
+	for rows.Next() {
+		var i ListCollectionActivityOldestRow
+		if err := rows.Scan(
+			&i.ID,
+			&i.CollectionID,
+			&i.ActorID,
+			&i.ActorUsername,
+			&i.Action,
+			&i.SourceID,
+			&i.SourceTitle,
+			&i.MemberID,
+			&i.MemberUsername,
+			&i.Role,
+			&i.CreatedAt,
+		); err != nil {
+			return nil, err
+		}
+		items = append(items, i)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
+
+const listCollectionInvitations = `-- name: ListCollectionInvitations :many
+SELECT i.id, i.collection_id, c.name AS collection_name, i.user_id, u.username, i.role, i.invited_by, inviter.username AS invited_by_username, i.created_at
+FROM collection_invitations i
+JOIN collections c ON c.id = i.collection_id
+JOIN users u ON u.id = i.user_id
+JOIN users inviter ON inviter.id = i.invited_by
+WHERE i.collection_id = $1
+ORDER BY i.created_at DESC, i.id DESC
+`
+
+type ListCollectionInvitationsRow struct {
+	ID                pgtype.UUID        `db:"id" json:"id"`
+	CollectionID      pgtype.UUID        `db:"collection_id" json:"collection_id"`
+	CollectionName    string             `db:"collection_name" json:"collection_name"`
+	UserID            pgtype.UUID        `db:"user_id" json:"user_id"`
+	Username          string             `db:"username" json:"username"`
+	Role              string             `db:"role" json:"role"`
+	InvitedBy         pgtype.UUID        `db:"invited_by" json:"invited_by"`
+	InvitedByUsername string             `db:"invited_by_username" json:"invited_by_username"`
+	CreatedAt         pgtype.Timestamptz `db:"created_at" json:"created_at"`
+}
+
+func (q *Queries) ListCollectionInvitations(ctx context.Context, collectionID pgtype.UUID) ([]ListCollectionInvitationsRow, error) {
+	rows, err := q.db.Query(ctx, listCollectionInvitations, collectionID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	items := []ListCollectionInvitationsRow{}
+	for rows.Next() {
+		var i ListCollectionInvitationsRow
+		if err := rows.Scan(
+			&i.ID,
+			&i.CollectionID,
+			&i.CollectionName,
+			&i.UserID,
+			&i.Username,
+			&i.Role,
+			&i.InvitedBy,
+			&i.InvitedByUsername,
+			&i.CreatedAt,
+		); err != nil {
+			return nil, err
+		}
+		items = append(items, i)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
+
+const listCollectionItems = `-- name: ListCollectionItems :many
+SELECT ci.collection_id, ci.source_id, ci.position, ci.note, ci.added_at, ci.added_by,
+       s.title, s.subtitle, s.type, s.publisher, s.isbn
+FROM collection_items ci
+JOIN sources s ON s.id = ci.source_id
+WHERE ci.collection_id = ANY($1::uuid[])
+ORDER BY ci.collection_id, ci.position, ci.added_at
+`
+
+type ListCollectionItemsRow struct {
+	CollectionID pgtype.UUID        `db:"collection_id" json:"collection_id"`
+	SourceID     pgtype.UUID        `db:"source_id" json:"source_id"`
+	Position     int32              `db:"position" json:"position"`
+	Note         pgtype.Text        `db:"note" json:"note"`
+	AddedAt      pgtype.Timestamptz `db:"added_at" json:"added_at"`
+	AddedBy      pgtype.UUID        `db:"added_by" json:"added_by"`
+	Title        string             `db:"title" json:"title"`
+	Subtitle     pgtype.Text        `db:"subtitle" json:"subtitle"`
+	Type         string             `db:"type" json:"type"`
+	Publisher    pgtype.Text        `db:"publisher" json:"publisher"`
+	Isbn         pgtype.Text        `db:"isbn" json:"isbn"`
+}
+
+func (q *Queries) ListCollectionItems(ctx context.Context, collectionIds []pgtype.UUID) ([]ListCollectionItemsRow, error) {
+	rows, err := q.db.Query(ctx, listCollectionItems, collectionIds)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	items := []ListCollectionItemsRow{}
+	for rows.Next() {
+		var i ListCollectionItemsRow
+		if err := rows.Scan(
+			&i.CollectionID,
+			&i.SourceID,
+			&i.Position,
+			&i.Note,
+			&i.AddedAt,
+			&i.AddedBy,
+			&i.Title,
+			&i.Subtitle,
+			&i.Type,
+			&i.Publisher,
+			&i.Isbn,
+		); err != nil {
+			return nil, err
+		}
+		items = append(items, i)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
+
+const listCollectionMembers = `-- name: ListCollectionMembers :many
+SELECT m.collection_id, m.user_id, u.username, m.role, m.invited_by, m.joined_at
+FROM collection_members m
+JOIN users u ON u.id = m.user_id
+WHERE m.collection_id = $1
+ORDER BY CASE m.role WHEN 'owner' THEN 0 WHEN 'editor' THEN 1 ELSE 2 END, m.joined_at, u.username
+`
+
+type ListCollectionMembersRow struct {
+	CollectionID pgtype.UUID        `db:"collection_id" json:"collection_id"`
+	UserID       pgtype.UUID        `db:"user_id" json:"user_id"`
+	Username     string             `db:"username" json:"username"`
+	Role         string             `db:"role" json:"role"`
+	InvitedBy    pgtype.UUID        `db:"invited_by" json:"invited_by"`
+	JoinedAt     pgtype.Timestamptz `db:"joined_at" json:"joined_at"`
+}
+
+func (q *Queries) ListCollectionMembers(ctx context.Context, collectionID pgtype.UUID) ([]ListCollectionMembersRow, error) {
+	rows, err := q.db.Query(ctx, listCollectionMembers, collectionID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	items := []ListCollectionMembersRow{}
+	for rows.Next() {
+		var i ListCollectionMembersRow
+		if err := rows.Scan(
+			&i.CollectionID,
+			&i.UserID,
+			&i.Username,
+			&i.Role,
+			&i.InvitedBy,
+			&i.JoinedAt,
+		); err != nil {
+			return nil, err
+		}
+		items = append(items, i)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
+
+const listCollectionSourceIDs = `-- name: ListCollectionSourceIDs :many
+SELECT collection_id, source_id
+FROM collection_items
+WHERE collection_id = ANY($1::uuid[])
+ORDER BY collection_id, position, added_at
+`
+
+type ListCollectionSourceIDsRow struct {
+	CollectionID pgtype.UUID `db:"collection_id" json:"collection_id"`
+	SourceID     pgtype.UUID `db:"source_id" json:"source_id"`
+}
+
+func (q *Queries) ListCollectionSourceIDs(ctx context.Context, collectionIds []pgtype.UUID) ([]ListCollectionSourceIDsRow, error) {
+	rows, err := q.db.Query(ctx, listCollectionSourceIDs, collectionIds)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	items := []ListCollectionSourceIDsRow{}
+	for rows.Next() {
+		var i ListCollectionSourceIDsRow
+		if err := rows.Scan(
+			&i.CollectionID,
+			&i.SourceID,
+		); err != nil {
+			return nil, err
+		}
+		items = append(items, i)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
+
 const listCollectionsByUser = `-- name: ListCollectionsByUser :many
-SELECT id, user_id, name, description, is_public, source_ids, created_at, updated_at
+SELECT id, user_id, name, description, is_public, created_at, updated_at, rules
 FROM collections
 WHERE user_id = $1
 ORDER BY created_at DESC
@@ -110,9 +826,9 @@ func (q *Queries) ListCollectionsByUser(ctx context.Context, arg ListCollections
 			&i.Name,
 			&i.Description,
 			&i.IsPublic,
-			&i.SourceIds,
 			&i.CreatedAt,
 			&i.UpdatedAt,
+			&i.Rules,
 		); err != nil {
 			return nil, err
 		}
@@ -124,22 +840,93 @@ func (q *Queries) ListCollectionsByUser(ctx context.Context, arg ListCollections
 	return items, nil
 }
 
-const listPublicCollectionsByUser = `-- name: ListPublicCollectionsByUser :many
-SELECT id, user_id, name, description, is_public, source_ids, created_at, updated_at
+const listCollectionsNewest = `-- name: ListCollectionsNewest :many
+SELECT id, user_id, name, description, is_public, created_at, updated_at, rules
 FROM collections
-WHERE user_id = $1 AND is_public = true
-ORDER BY created_at DESC
-LIMIT $2 OFFSET $3
+WHERE ($1::uuid IS NULL OR user_id = $1::uuid)
+  AND ($2::uuid IS NULL OR EXISTS (
+    SELECT 1 FROM collection_members m WHERE m.collection_id = collections.id AND m.user_id = $2::uuid))
+  AND (NOT $3::bool OR is_public = true)
+  AND (created_at, id) < ($4::timestamptz, $5::uuid)
+ORDER BY created_at DESC, id DESC
+LIMIT $6
 `
 
-type ListPublicCollectionsByUserParams struct {
-	UserID pgtype.UUID `db:"user_id" json:"user_id"`
-	Limit  int32       `db:"limit" json:"limit"`
-	Offset int32       `db:"offset" json:"offset"`
+type ListCollectionsNewestParams struct {
+	UserID         pgtype.UUID        `db:"user_id" json:"user_id"`
+	MemberID       pgtype.UUID        `db:"member_id" json:"member_id"`
+	PublicOnly     bool               `db:"public_only" json:"public_only"`
+	AfterCreatedAt pgtype.Timestamptz `db:"after_created_at" json:"after_created_at"`
+	AfterID        pgtype.UUID        `db:"after_id" json:"after_id"`
+	Limit          int32              `db:"limit" json:"limit"`
+}
+
+func (q *Queries) ListCollectionsNewest(ctx context.Context, arg ListCollectionsNewestParams) ([]Collection, error) {
+	rows, err := q.db.Query(ctx, listCollectionsNewest,
+		arg.UserID,
+		arg.MemberID,
+		arg.PublicOnly,
+		arg.AfterCreatedAt,
+		arg.AfterID,
+		arg.Limit,
+	)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	items := []Collection{}
+	for rows.Next() {
+		var i Collection
+		if err := rows.Scan(
+			&i.ID,
+			&i.UserID,
+			&i.Name,
+			&i.Description,
+			&i.IsPublic,
+			&i.CreatedAt,
+			&i.UpdatedAt,
+			&i.Rules,
+		); err != nil {
+			return nil, err
+		}
+		items = append(items, i)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
+
+const listCollectionsOldest = `-- name: ListCollectionsOldest :many
+SELECT id, user_id, name, description, is_public, created_at, updated_at, rules
+FROM collections
+WHERE ($1::uuid IS NULL OR user_id = $1::uuid)
+  AND ($2::uuid IS NULL OR EXISTS (
+    SELECT 1 FROM collection_members m WHERE m.collection_id = collections.id AND m.user_id = $2::uuid))
+  AND (NOT $3::bool OR is_public = true)
+  AND (created_at, id) > ($4::timestamptz, $5::uuid)
+ORDER BY created_at ASC, id ASC
+LIMIT $6
+`
+
+type ListCollectionsOldestParams struct {
+	UserID         pgtype.UUID        `db:"user_id" json:"user_id"`
+	MemberID       pgtype.UUID        `db:"member_id" json:"member_id"`
+	PublicOnly     bool               `db:"public_only" json:"public_only"`
+	AfterCreatedAt pgtype.Timestamptz `db:"after_created_at" json:"after_created_at"`
+	AfterID        pgtype.UUID        `db:"after_id" json:"after_id"`
+	Limit          int32              `db:"limit" json:"limit"`
 }
 
-func (q *Queries) ListPublicCollectionsByUser(ctx context.Context, arg ListPublicCollectionsByUserParams) ([]Collection, error) {
-	rows, err := q.db.Query(ctx, listPublicCollectionsByUser, arg.UserID, arg.Limit, arg.Offset)
+func (q *Queries) ListCollectionsOldest(ctx context.Context, arg ListCollectionsOldestParams) ([]Collection, error) {
+	rows, err := q.db.Query(ctx, listCollectionsOldest,
+		arg.UserID,
+		arg.MemberID,
+		arg.PublicOnly,
+		arg.AfterCreatedAt,
+		arg.AfterID,
+		arg.Limit,
+	)
 	if err != nil {
 		return nil, err
 	}
@@ -153,9 +940,9 @@ func (q *Queries) ListPublicCollectionsByUser(ctx context.Context, arg ListPubli
 			&i.Name,
 			&i.Description,
 			&i.IsPublic,
-			&i.SourceIds,
 			&i.CreatedAt,
 			&i.UpdatedAt,
+			&i.Rules,
 		); err != nil {
 			return nil, err
 		}
@@ -167,6 +954,300 @@ func (q *Queries) ListPublicCollectionsByUser(ctx context.Context, arg ListPubli
 	return items, nil
 }
 
+const listSmartCollectionMatchesNewest = `-- name: ListSmartCollectionMatchesNewest :many
+SELECT s.id, s.title, s.subtitle, s.type, s.publisher, s.isbn,
+       COALESCE(li.created_at, s.created_at)::timestamptz AS added_at
+FROM sources s
+LEFT JOIN user_library_items li ON li.source_id = s.id AND li.user_id = $1::uuid
+  AND (NOT $2::bool OR li.visibility = 'public')
+LEFT JOIN reviews r ON r.source_id = s.id AND r.user_id = $1::uuid
+  AND (NOT $2::bool OR r.is_public = true)
+WHERE (cardinality($3::text[]) = 0 OR s.type = ANY($3::text[]))
+  AND (NOT $4::bool OR li.id IS NOT NULL)
+  AND (cardinality($5::text[]) = 0 OR li.status = ANY($5::text[]))
+  AND ($6::timestamptz IS NULL OR li.created_at >= $6::timestamptz)
+  AND ($7::int IS NULL OR r.rating >= $7::int)
+  AND ($8::int IS NULL OR r.rating <= $8::int)
+  AND NOT EXISTS (
+    SELECT 1 FROM unnest($9::text[]) AS wanted(slug)
+    WHERE NOT EXISTS (
+        SELECT 1 FROM source_tags st JOIN tags t ON t.id = st.tag_id
+        WHERE st.source_id = s.id AND t.slug = wanted.slug)
+      AND ($2::bool OR NOT EXISTS (
+        SELECT 1 FROM library_item_tags lt JOIN tags t ON t.id = lt.tag_id
+        WHERE lt.library_item_id = li.id AND t.slug = wanted.slug)))
+  AND (COALESCE(li.created_at, s.created_at), s.id) < ($10::timestamptz, $11::uuid)
+ORDER BY COALESCE(li.created_at, s.created_at) DESC, s.id DESC
+LIMIT $12
+`
+
+type ListSmartCollectionMatchesNewestParams struct {
+	OwnerID        pgtype.UUID        `db:"owner_id" json:"owner_id"`
+	PublicOnly     bool               `db:"public_only" json:"public_only"`
+	Types          []string           `db:"types" json:"types"`
+	InLibrary      bool               `db:"in_library" json:"in_library"`
+	Statuses       []string           `db:"statuses" json:"statuses"`
+	AddedSince     pgtype.Timestamptz `db:"added_since" json:"added_since"`
+	MinRating      pgtype.Int4        `db:"min_rating" json:"min_rating"`
+	MaxRating      pgtype.Int4        `db:"max_rating" json:"max_rating"`
+	TagSlugs       []string           `db:"tag_slugs" json:"tag_slugs"`
+	AfterCreatedAt pgtype.Timestamptz `db:"after_created_at" json:"after_created_at"`
+	AfterID        pgtype.UUID        `db:"after_id" json:"after_id"`
+	Limit          int32              `db:"limit" json:"limit"`
+}
+
+type ListSmartCollectionMatchesNewestRow struct {
+	ID        pgtype.UUID        `db:"id" json:"id"`
+	Title     string             `db:"title" json:"title"`
+	Subtitle  pgtype.Text        `db:"subtitle" json:"subtitle"`
+	Type      string             `db:"type" json:"type"`
+	Publisher pgtype.Text        `db:"publisher" json:"publisher"`
+	Isbn      pgtype.Text        `db:"isbn" json:"isbn"`
+	AddedAt   pgtype.Timestamptz `db:"added_at" json:"added_at"`
+}
+
+func (q *Queries) ListSmartCollectionMatchesNewest(ctx context.Context, arg ListSmartCollectionMatchesNewestParams) ([]ListSmartCollectionMatchesNewestRow, error) {
+	rows, err := q.db.Query(ctx, listSmartCollectionMatchesNewest,
+		arg.OwnerID,
+		arg.PublicOnly,
+		arg.Types,
+		arg.InLibrary,
+		arg.Statuses,
+		arg.AddedSince,
+		arg.MinRating,
+		arg.MaxRating,
+		arg.TagSlugs,
+		arg.AfterCreatedAt,
+		arg.AfterID,
+		arg.Limit,
+	)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	items := []ListSmartCollectionMatchesNewestRow{}
+	for rows.Next() {
+		var i ListSmartCollectionMatchesNewestRow
+		if err := rows.Scan(
+			&i.ID,
+			&i.Title,
+			&i.Subtitle,
+			&i.Type,
+			&i.Publisher,
+			&i.Isbn,
+			&i.AddedAt,
+		); err != nil {
+			return nil, err
+		}
+		items = append(items, i)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
+
+const listSmartCollectionMatchesOldest = `-- name: ListSmartCollectionMatchesOldest :many
+SELECT s.id, s.title, s.subtitle, s.type, s.publisher, s.isbn,
+       COALESCE(li.created_at, s.created_at)::timestamptz AS added_at
+FROM sources s
+LEFT JOIN user_library_items li ON li.source_id = s.id AND li.user_id = $1::uuid
+  AND (NOT $2::bool OR li.visibility = 'public')
+LEFT JOIN reviews r ON r.source_id = s.id AND r.user_id = $1::uuid
+  AND (NOT $2::bool OR r.is_public = true)
+WHERE (cardinality($3::text[]) = 0 OR s.type = ANY($3::text[]))
+  AND (NOT $4::bool OR li.id IS NOT NULL)
+  AND (cardinality($5::text[]) = 0 OR li.status = ANY($5::text[]))
+  AND ($6::timestamptz IS NULL OR li.created_at >= $6::timestamptz)
+  AND ($7::int IS NULL OR r.rating >= $7::int)
+  AND ($8::int IS NULL OR r.rating <= $8::int)
+  AND NOT EXISTS (
+    SELECT 1 FROM unnest($9::text[]) AS wanted(slug)
+    WHERE NOT EXISTS (
+        SELECT 1 FROM source_tags st JOIN tags t ON t.id = st.tag_id
+        WHERE st.source_id = s.id AND t.slug = wanted.slug)
+      AND ($2::bool OR NOT EXISTS (
+        SELECT 1 FROM library_item_tags lt JOIN tags t ON t.id = lt.tag_id
+        WHERE lt.library_item_id = li.id AND t.slug = wanted.slug)))
+  AND (COALESCE(li.created_at, s.created_at), s.id) > ($10::timestamptz, $11::uuid)
+ORDER BY COALESCE(li.created_at, s.created_at) ASC, s.id ASC
+LIMIT $12
+`
+
+type ListSmartCollectionMatchesOldestParams struct {
+	OwnerID        pgtype.UUID        `db:"owner_id" json:"owner_id"`
+	PublicOnly     bool               `db:"public_only" json:"public_only"`
+	Types          []string           `db:"types" json:"types"`
+	InLibrary      bool               `db:"in_library" json:"in_library"`
+	Statuses       []string           `db:"statuses" json:"statuses"`
+	AddedSince     pgtype.Timestamptz `db:"added_since" json:"added_since"`
+	MinRating      pgtype.Int4        `db:"min_rating" json:"min_rating"`
+	MaxRating      pgtype.Int4        `db:"max_rating" json:"max_rating"`
+	TagSlugs       []string           `db:"tag_slugs" json:"tag_slugs"`
+	AfterCreatedAt pgtype.Timestamptz `db:"after_created_at" json:"after_created_at"`
+	AfterID        pgtype.UUID        `db:"after_id" json:"after_id"`
+	Limit          int32              `db:"limit" json:"limit"`
+}
+
+type ListSmartCollectionMatchesOldestRow struct {
+	ID        pgtype.UUID        `db:"id" json:"id"`
+	Title     string             `db:"title" json:"title"`
+	Subtitle  pgtype.Text        `db:"subtitle" json:"subtitle"`
+	Type      string             `db:"type" json:"type"`
+	Publisher pgtype.Text        `db:"publisher" json:"publisher"`
+	Isbn      pgtype.Text        `db:"isbn" json:"isbn"`
+	AddedAt   pgtype.Timestamptz `db:"added_at" json:"added_at"`
+}
+
+func (q *Queries) ListSmartCollectionMatchesOldest(ctx context.Context, arg ListSmartCollectionMatchesOldestParams) ([]ListSmartCollectionMatchesOldestRow, error) {
+	rows, err := q.db.Query(ctx, listSmartCollectionMatchesOldest,
+		arg.OwnerID,
+		arg.PublicOnly,
+		arg.Types,
+		arg.InLibrary,
+		arg.Statuses,
+		arg.AddedSince,
+		arg.MinRating,
+		arg.MaxRating,
+		arg.TagSlugs,
+		arg.AfterCreatedAt,
+		arg.AfterID,
+		arg.Limit,
+	)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	items := []ListSmartCollectionMatchesOldestRow{}
+	for rows.Next() {
+		var i ListSmartCollectionMatchesOldestRow
+		if err := rows.Scan(
+			&i.ID,
+			&i.Title,
+			&i.Subtitle,
+			&i.Type,
+			&i.Publisher,
+			&i.Isbn,
+			&i.AddedAt,
+		); err != nil {
+			return nil, err
+		}
+		items = append(items, i)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
+
+const listUserCollectionInvitations = `-- name: ListUserCollectionInvitations :many
+SELECT i.id, i.collection_id, c.name AS collection_name, i.user_id, u.username, i.role, i.invited_by, inviter.username AS invited_by_username, i.created_at
+FROM collection_invitations i
+JOIN collections c ON c.id = i.collection_id
+JOIN users u ON u.id = i.user_id
+JOIN users inviter ON inviter.id = i.invited_by
+WHERE i.user_id = $1
+ORDER BY i.created_at DESC, i.id DESC
+`
+
+type ListUserCollectionInvitationsRow struct {
+	ID                pgtype.UUID        `db:"id" json:"id"`
+	CollectionID      pgtype.UUID        `db:"collection_id" json:"collection_id"`
+	CollectionName    string             `db:"collection_name" json:"collection_name"`
+	UserID            pgtype.UUID        `db:"user_id" json:"user_id"`
+	Username          string             `db:"username" json:"username"`
+	Role              string             `db:"role" json:"role"`
+	InvitedBy         pgtype.UUID        `db:"invited_by" json:"invited_by"`
+	InvitedByUsername string             `db:"invited_by_username" json:"invited_by_username"`
+	CreatedAt         pgtype.Timestamptz `db:"created_at" json:"created_at"`
+}
+
+func (q *Queries) ListUserCollectionInvitations(ctx context.Context, userID pgtype.UUID) ([]ListUserCollectionInvitationsRow, error) {
+	rows, err := q.db.Query(ctx, listUserCollectionInvitations, userID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	items := []ListUserCollectionInvitationsRow{}
+	for rows.Next() {
+		var i ListUserCollectionInvitationsRow
+		if err := rows.Scan(
+			&i.ID,
+			&i.CollectionID,
+			&i.CollectionName,
+			&i.UserID,
+			&i.Username,
+			&i.Role,
+			&i.InvitedBy,
+			&i.InvitedByUsername,
+			&i.CreatedAt,
+		); err != nil {
+			return nil, err
+		}
+		items = append(items, i)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
+
+const lockCollection = `-- name: LockCollection :one
+SELECT id FROM collections WHERE id = $1 FOR UPDATE
+`
+
+func (q *Queries) LockCollection(ctx context.Context, id pgtype.UUID) (pgtype.UUID, error) {
+	row := q.db.QueryRow(ctx, lockCollection, id)
+	err := row.Scan(&id)
+	return id, err
+}
+
+const moveCollectionItem = `-- name: MoveCollectionItem :execrows
+UPDATE collection_items ci
+SET position = CASE
+        WHEN ci.source_id = $1 THEN $2::int
+        WHEN $2::int < target.position THEN ci.position + 1
+        ELSE ci.position - 1
+    END
+FROM (
+    SELECT position FROM collection_items
+    WHERE collection_id = $3 AND source_id = $1
+) target
+WHERE ci.collection_id = $3
+  AND ci.position BETWEEN LEAST(target.position, $2::int) AND GREATEST(target.position, $2::int)
+`
+
+type MoveCollectionItemParams struct {
+	SourceID     pgtype.UUID `db:"source_id" json:"source_id"`
+	ToPosition   int32       `db:"to_position" json:"to_position"`
+	CollectionID pgtype.UUID `db:"collection_id" json:"collection_id"`
+}
+
+func (q *Queries) MoveCollectionItem(ctx context.Context, arg MoveCollectionItemParams) (int64, error) {
+	result, err := q.db.Exec(ctx, moveCollectionItem, arg.SourceID, arg.ToPosition, arg.CollectionID)
+	if err != nil {
+		return 0, err
+	}
+	return result.RowsAffected(), nil
+}
+
+const shiftCollectionItems = `-- name: ShiftCollectionItems :exec
+UPDATE collection_items
+SET position = position + $1::int
+WHERE collection_id = $2 AND position >= $3::int
+`
+
+type ShiftCollectionItemsParams struct {
+	Delta        int32       `db:"delta" json:"delta"`
+	CollectionID pgtype.UUID `db:"collection_id" json:"collection_id"`
+	FromPosition int32       `db:"from_position" json:"from_position"`
+}
+
+func (q *Queries) ShiftCollectionItems(ctx context.Context, arg ShiftCollectionItemsParams) error {
+	_, err := q.db.Exec(ctx, shiftCollectionItems, arg.Delta, arg.CollectionID, arg.FromPosition)
+	return err
+}
+
 const sourceExists = `-- name: SourceExists :one
 SELECT EXISTS (SELECT 1 FROM sources WHERE id = $1)
 `
@@ -178,11 +1259,20 @@ func (q *Queries) SourceExists(ctx context.Context, id pgtype.UUID) (bool, error
 	return exists, err
 }
 
+const touchCollection = `-- name: TouchCollection :exec
+UPDATE collections SET updated_at = NOW() WHERE id = $1
+`
+
+func (q *Queries) TouchCollection(ctx context.Context, id pgtype.UUID) error {
+	_, err := q.db.Exec(ctx, touchCollection, id)
+	return err
+}
+
 const updateCollection = `-- name: UpdateCollection :one
 UPDATE collections
-SET name = $2, description = $3, is_public = $4, source_ids = $5, updated_at = NOW()
+SET name = $2, description = $3, is_public = $4, rules = $5, updated_at = NOW()
 WHERE id = $1
-RETURNING id, user_id, name, description, is_public, source_ids, created_at, updated_at
+RETURNING id, user_id, name, description, is_public, created_at, updated_at, rules
 `
 
 type UpdateCollectionParams struct {
@@ -190,7 +1280,7 @@ type UpdateCollectionParams struct {
 	Name        string      `db:"name" json:"name"`
 	Description pgtype.Text `db:"description" json:"description"`
 	IsPublic    pgtype.Bool `db:"is_public" json:"is_public"`
-	SourceIds   []byte      `db:"source_ids" json:"source_ids"`
+	Rules       []byte      `db:"rules" json:"rules"`
 }
 
 func (q *Queries) UpdateCollection(ctx context.Context, arg UpdateCollectionParams) (Collection, error) {
@@ -199,7 +1289,7 @@ func (q *Queries) UpdateCollection(ctx context.Context, arg UpdateCollectionPara
 		arg.Name,
 		arg.Description,
 		arg.IsPublic,
-		arg.SourceIds,
+		arg.Rules,
 	)
 	var i Collection
 	err := row.Scan(
@@ -208,9 +1298,93 @@ func (q *Queries) UpdateCollection(ctx context.Context, arg UpdateCollectionPara
 		&i.Name,
 		&i.Description,
 		&i.IsPublic,
-		&i.SourceIds,
 		&i.CreatedAt,
 		&i.UpdatedAt,
+		&i.Rules,
 	)
 	return i, err
 }
+
+const updateCollectionItemNote = `-- name: UpdateCollectionItemNote :exec
+UPDATE collection_items
+SET note = $1
+WHERE collection_id = $2 AND source_id = $3
+`
+
+type UpdateCollectionItemNoteParams struct {
+	Note         pgtype.Text `db:"note" json:"note"`
+	CollectionID pgtype.UUID `db:"collection_id" json:"collection_id"`
+	SourceID     pgtype.UUID `db:"source_id" json:"source_id"`
+}
+
+func (q *Queries) UpdateCollectionItemNote(ctx context.Context, arg UpdateCollectionItemNoteParams) error {
+	_, err := q.db.Exec(ctx, updateCollectionItemNote, arg.Note, arg.CollectionID, arg.SourceID)
+	return err
+}
+
+const updateCollectionMemberRole = `-- name: UpdateCollectionMemberRole :execrows
+UPDATE collection_members
+SET role = $1
+WHERE collection_id = $2 AND user_id = $3 AND role <> 'owner'
+`
+
+type UpdateCollectionMemberRoleParams struct {
+	Role         string      `db:"role" json:"role"`
+	CollectionID pgtype.UUID `db:"collection_id" json:"collection_id"`
+	UserID       pgtype.UUID `db:"user_id" json:"user_id"`
+}
+
+func (q *Queries) UpdateCollectionMemberRole(ctx context.Context, arg UpdateCollectionMemberRoleParams) (int64, error) {
+	result, err := q.db.Exec(ctx, updateCollectionMemberRole, arg.Role, arg.CollectionID, arg.UserID)
+	if err != nil {
+		return 0, err
+	}
+	return result.RowsAffected(), nil
+}
+
+const upsertCollectionInvitation = `-- name: UpsertCollectionInvitation :one
+INSERT INTO collection_invitations (id, collection_id, user_id, role, invited_by)
+VALUES ($1, $2, $3, $4, $5)
+ON CONFLICT (collection_id, user_id) DO UPDATE
+SET role = EXCLUDED.role, invited_by = EXCLUDED.invited_by, created_at = NOW()
+RETURNING id
+`
+
+type UpsertCollectionInvitationParams struct {
+	ID           pgtype.UUID `db:"id" json:"id"`
+	CollectionID pgtype.UUID `db:"collection_id" json:"collection_id"`
+	UserID       pgtype.UUID `db:"user_id" json:"user_id"`
+	Role         string      `db:"role" json:"role"`
+	InvitedBy    pgtype.UUID `db:"invited_by" json:"invited_by"`
+}
+
+func (q *Queries) UpsertCollectionInvitation(ctx context.Context, arg UpsertCollectionInvitationParams) (pgtype.UUID, error) {
+	row := q.db.QueryRow(ctx, upsertCollectionInvitation,
+		arg.ID,
+		arg.CollectionID,
+		arg.UserID,
+		arg.Role,
+		arg.InvitedBy,
+	)
+	var id pgtype.UUID
+	err := row.Scan(&id)
+	return id, err
+}
+
+const upsertCollectionItems = `-- name: UpsertCollectionItems :exec
+INSERT INTO collection_items (collection_id, source_id, position, added_by)
+SELECT $1::uuid, e.source_id, (e.position - 1)::int, $2::uuid
+FROM unnest($3::uuid[]) WITH ORDINALITY AS e(source_id, position)
+ON CONFLICT (collection_id, source_id) DO UPDATE SET position = EXCLUDED.position
+`
+
+type UpsertCollectionItemsParams struct {
+	CollectionID pgtype.UUID   `db:"collection_id" json:"collection_id"`
+	AddedBy      pgtype.UUID   `db:"added_by" json:"added_by"`
+	SourceIds    []pgtype.UUID `db:"source_ids" json:"source_ids"`
+}
+
+func (q *Queries) UpsertCollectionItems(ctx context.Context, arg UpsertCollectionItemsParams) error {
+	_, err := q.db.Exec(ctx, upsertCollectionItems, arg.CollectionID, arg.AddedBy, arg.SourceIds)
+	return err
+}
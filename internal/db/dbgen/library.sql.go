@@ -11,10 +11,43 @@ import (
 	"github.com/jackc/pgx/v5/pgtype"
 )
 
+const countLibraryItems = `-- name: CountLibraryItems :one
+SELECT COUNT(*)
+FROM user_library_items uli
+WHERE ($1::uuid IS NULL OR uli.user_id = $1::uuid)
+  AND ($2::text IS NULL OR uli.user_id = (SELECT u.id FROM users u WHERE u.username = $2::text))
+  AND (NOT $3::bool OR uli.visibility = 'public')
+  AND ($4::text IS NULL OR uli.status = $4::text)
+  AND ($5::text IS NULL OR EXISTS (
+    SELECT 1 FROM library_item_tags lit JOIN tags t ON t.id = lit.tag_id
+    WHERE lit.library_item_id = uli.id AND t.slug = $5::text))
+`
+
+type CountLibraryItemsParams struct {
+	UserID     pgtype.UUID `db:"user_id" json:"user_id"`
+	Username   pgtype.Text `db:"username" json:"username"`
+	PublicOnly bool        `db:"public_only" json:"public_only"`
+	Status     pgtype.Text `db:"status" json:"status"`
+	Tag        pgtype.Text `db:"tag" json:"tag"`
+}
+
+func (q *Queries) CountLibraryItems(ctx context.Context, arg CountLibraryItemsParams) (int64, error) {
+	row := q.db.QueryRow(ctx, countLibraryItems,
+		arg.UserID,
+		arg.Username,
+		arg.PublicOnly,
+		arg.Status,
+		arg.Tag,
+	)
+	var count int64
+	err := row.Scan(&count)
+	return count, err
+}
+
 const createLibraryItem = `-- name: CreateLibraryItem :one
 INSERT INTO user_library_items (id, user_id, source_id, status, progress_value, progress_unit, visibility, started_at, completed_at)
 VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9)
-RETURNING id, user_id, source_id, status, progress_value, progress_unit, visibility, started_at, completed_at, created_at, updated_at
+RETURNING id, user_id, source_id, status, progress_value, progress_unit, visibility, started_at, completed_at, created_at, updated_at, reread_count
 `
 
 type CreateLibraryItemParams struct {
@@ -54,21 +87,68 @@ func (q *Queries) CreateLibraryItem(ctx context.Context, arg CreateLibraryItemPa
 		&i.CompletedAt,
 		&i.CreatedAt,
 		&i.UpdatedAt,
+		&i.RereadCount,
 	)
 	return i, err
 }
 
-const deleteLibraryItem = `-- name: DeleteLibraryItem :exec
+const createLibraryItemCompletion = `-- name: CreateLibraryItemCompletion :one
+INSERT INTO library_item_completions (id, library_item_id, user_id, started_at, completed_at)
+VALUES ($1, $2, $3, $4, $5)
+RETURNING id, library_item_id, user_id, started_at, completed_at, created_at
+`
+
+type CreateLibraryItemCompletionParams struct {
+	ID            pgtype.UUID        `db:"id" json:"id"`
+	LibraryItemID pgtype.UUID        `db:"library_item_id" json:"library_item_id"`
+	UserID        pgtype.UUID        `db:"user_id" json:"user_id"`
+	StartedAt     pgtype.Timestamptz `db:"started_at" json:"started_at"`
+	CompletedAt   pgtype.Timestamptz `db:"completed_at" json:"completed_at"`
+}
+
+func (q *Queries) CreateLibraryItemCompletion(ctx context.Context, arg CreateLibraryItemCompletionParams) (LibraryItemCompletion, error) {
+	row := q.db.QueryRow(ctx, createLibraryItemCompletion,
+		arg.ID,
+		arg.LibraryItemID,
+		arg.UserID,
+		arg.StartedAt,
+		arg.CompletedAt,
+	)
+	var i LibraryItemCompletion
+	err := row.Scan(
+		&i.ID,
+		&i.LibraryItemID,
+		&i.UserID,
+		&i.StartedAt,
+		&i.CompletedAt,
+		&i.CreatedAt,
+	)
+	return i, err
+}
+
+const deleteLibraryItem = `-- name: DeleteLibraryItem :execrows
 DELETE FROM user_library_items WHERE id = $1
 `
 
-func (q *Queries) DeleteLibraryItem(ctx context.Context, id pgtype.UUID) error {
-	_, err := q.db.Exec(ctx, deleteLibraryItem, id)
+func (q *Queries) DeleteLibraryItem(ctx context.Context, id pgtype.UUID) (int64, error) {
+	result, err := q.db.Exec(ctx, deleteLibraryItem, id)
+	if err != nil {
+		return 0, err
+	}
+	return result.RowsAffected(), nil
+}
+
+const deleteLibraryItemTags = `-- name: DeleteLibraryItemTags :exec
+DELETE FROM library_item_tags WHERE library_item_id = $1
+`
+
+func (q *Queries) DeleteLibraryItemTags(ctx context.Context, libraryItemID pgtype.UUID) error {
+	_, err := q.db.Exec(ctx, deleteLibraryItemTags, libraryItemID)
 	return err
 }
 
 const getLibraryItemByID = `-- name: GetLibraryItemByID :one
-SELECT id, user_id, source_id, status, progress_value, progress_unit, visibility, started_at, completed_at, created_at, updated_at
+SELECT id, user_id, source_id, status, progress_value, progress_unit, visibility, started_at, completed_at, created_at, updated_at, reread_count
 FROM user_library_items
 WHERE id = $1
 LIMIT 1
@@ -89,45 +169,110 @@ func (q *Queries) GetLibraryItemByID(ctx context.Context, id pgtype.UUID) (UserL
 		&i.CompletedAt,
 		&i.CreatedAt,
 		&i.UpdatedAt,
+		&i.RereadCount,
 	)
 	return i, err
 }
 
-const listLibraryItemsByUser = `-- name: ListLibraryItemsByUser :many
-SELECT id, user_id, source_id, status, progress_value, progress_unit, visibility, started_at, completed_at, created_at, updated_at
-FROM user_library_items
+const getSourcePageCount = `-- name: GetSourcePageCount :one
+SELECT page_count FROM book_metadata WHERE source_id = $1
+`
+
+func (q *Queries) GetSourcePageCount(ctx context.Context, sourceID pgtype.UUID) (pgtype.Int4, error) {
+	row := q.db.QueryRow(ctx, getSourcePageCount, sourceID)
+	var page_count pgtype.Int4
+	err := row.Scan(&page_count)
+	return page_count, err
+}
+
+const insertLibraryItemTags = `-- name: InsertLibraryItemTags :exec
+INSERT INTO library_item_tags (library_item_id, tag_id, position)
+SELECT $1::uuid, tag.id, tag.position - 1
+FROM unnest($2::uuid[]) WITH ORDINALITY AS tag(id, position)
+ON CONFLICT DO NOTHING
+`
+
+type InsertLibraryItemTagsParams struct {
+	LibraryItemID pgtype.UUID   `db:"library_item_id" json:"library_item_id"`
+	TagIds        []pgtype.UUID `db:"tag_ids" json:"tag_ids"`
+}
+
+func (q *Queries) InsertLibraryItemTags(ctx context.Context, arg InsertLibraryItemTagsParams) error {
+	_, err := q.db.Exec(ctx, insertLibraryItemTags, arg.LibraryItemID, arg.TagIds)
+	return err
+}
+
+const listLibraryItemCompletions = `-- name: ListLibraryItemCompletions :many
+SELECT id, library_item_id, user_id, started_at, completed_at, created_at
+FROM library_item_completions
+WHERE library_item_id = $1
+ORDER BY completed_at DESC
+LIMIT $2 OFFSET $3
+`
+
+type ListLibraryItemCompletionsParams struct {
+	LibraryItemID pgtype.UUID `db:"library_item_id" json:"library_item_id"`
+	Limit         int32       `db:"limit" json:"limit"`
+	Offset        int32       `db:"offset" json:"offset"`
+}
+
+func (q *Queries) ListLibraryItemCompletions(ctx context.Context, arg ListLibraryItemCompletionsParams) ([]LibraryItemCompletion, error) {
+	rows, err := q.db.Query(ctx, listLibraryItemCompletions, arg.LibraryItemID, arg.Limit, arg.Offset)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	items := []LibraryItemCompletion{}
+	for rows.Next() {
+		var i LibraryItemCompletion
+		if err := rows.Scan(
+			&i.ID,
+			&i.LibraryItemID,
+			&i.UserID,
+			&i.StartedAt,
+			&i.CompletedAt,
+			&i.CreatedAt,
+		); err != nil {
+			return nil, err
+		}
+		items = append(items, i)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
+
+const listLibraryItemCompletionsByUser = `-- name: ListLibraryItemCompletionsByUser :many
+SELECT id, library_item_id, user_id, started_at, completed_at, created_at
+FROM library_item_completions
 WHERE user_id = $1
-ORDER BY created_at DESC
+ORDER BY completed_at DESC
 LIMIT $2 OFFSET $3
 `
 
-type ListLibraryItemsByUserParams struct {
+type ListLibraryItemCompletionsByUserParams struct {
 	UserID pgtype.UUID `db:"user_id" json:"user_id"`
 	Limit  int32       `db:"limit" json:"limit"`
 	Offset int32       `db:"offset" json:"offset"`
 }
 
-func (q *Queries) ListLibraryItemsByUser(ctx context.Context, arg ListLibraryItemsByUserParams) ([]UserLibraryItem, error) {
-	rows, err := q.db.Query(ctx, listLibraryItemsByUser, arg.UserID, arg.Limit, arg.Offset)
+func (q *Queries) ListLibraryItemCompletionsByUser(ctx context.Context, arg ListLibraryItemCompletionsByUserParams) ([]LibraryItemCompletion, error) {
+	rows, err := q.db.Query(ctx, listLibraryItemCompletionsByUser, arg.UserID, arg.Limit, arg.Offset)
 	if err != nil {
 		return nil, err
 	}
 	defer rows.Close()
-	items := []UserLibraryItem{}
+	items := []LibraryItemCompletion{}
 	for rows.Next() {
-		var i UserLibraryItem
+		var i LibraryItemCompletion
 		if err := rows.Scan(
 			&i.ID,
+			&i.LibraryItemID,
 			&i.UserID,
-			&i.SourceID,
-			&i.Status,
-			&i.ProgressValue,
-			&i.ProgressUnit,
-			&i.Visibility,
 			&i.StartedAt,
 			&i.CompletedAt,
 			&i.CreatedAt,
-			&i.UpdatedAt,
 		); err != nil {
 			return nil, err
 		}
@@ -140,7 +285,7 @@ func (q *Queries) ListLibraryItemsByUser(ctx context.Context, arg ListLibraryIte
 }
 
 const listLibraryItemsByUserWithSources = `-- name: ListLibraryItemsByUserWithSources :many
-SELECT uli.id, uli.user_id, uli.source_id, uli.status, uli.progress_value, uli.progress_unit, uli.visibility, uli.started_at, uli.completed_at, uli.created_at, uli.updated_at,
+SELECT uli.id, uli.user_id, uli.source_id, uli.status, uli.progress_value, uli.progress_unit, uli.visibility, uli.started_at, uli.completed_at, uli.created_at, uli.updated_at, uli.reread_count,
        s.title, s.subtitle, s.type, s.publisher, s.isbn
 FROM user_library_items uli
 JOIN sources s ON s.id = uli.source_id
@@ -167,6 +312,7 @@ type ListLibraryItemsByUserWithSourcesRow struct {
 	CompletedAt   pgtype.Timestamptz `db:"completed_at" json:"completed_at"`
 	CreatedAt     pgtype.Timestamptz `db:"created_at" json:"created_at"`
 	UpdatedAt     pgtype.Timestamptz `db:"updated_at" json:"updated_at"`
+	RereadCount   int32              `db:"reread_count" json:"reread_count"`
 	Title         string             `db:"title" json:"title"`
 	Subtitle      pgtype.Text        `db:"subtitle" json:"subtitle"`
 	Type          string             `db:"type" json:"type"`
@@ -195,6 +341,7 @@ func (q *Queries) ListLibraryItemsByUserWithSources(ctx context.Context, arg Lis
 			&i.CompletedAt,
 			&i.CreatedAt,
 			&i.UpdatedAt,
+			&i.RereadCount,
 			&i.Title,
 			&i.Subtitle,
 			&i.Type,
@@ -211,22 +358,43 @@ func (q *Queries) ListLibraryItemsByUserWithSources(ctx context.Context, arg Lis
 	return items, nil
 }
 
-const listPublicLibraryItemsByUser = `-- name: ListPublicLibraryItemsByUser :many
-SELECT id, user_id, source_id, status, progress_value, progress_unit, visibility, started_at, completed_at, created_at, updated_at
-FROM user_library_items
-WHERE user_id = $1 AND visibility = 'public'
-ORDER BY created_at DESC
-LIMIT $2 OFFSET $3
+const listLibraryItemsNewest = `-- name: ListLibraryItemsNewest :many
+SELECT uli.id, uli.user_id, uli.source_id, uli.status, uli.progress_value, uli.progress_unit, uli.visibility, uli.started_at, uli.completed_at, uli.created_at, uli.updated_at, uli.reread_count
+FROM user_library_items uli
+WHERE ($1::uuid IS NULL OR uli.user_id = $1::uuid)
+  AND ($2::text IS NULL OR uli.user_id = (SELECT u.id FROM users u WHERE u.username = $2::text))
+  AND (NOT $3::bool OR uli.visibility = 'public')
+  AND ($4::text IS NULL OR uli.status = $4::text)
+  AND ($5::text IS NULL OR EXISTS (
+    SELECT 1 FROM library_item_tags lit JOIN tags t ON t.id = lit.tag_id
+    WHERE lit.library_item_id = uli.id AND t.slug = $5::text))
+  AND (uli.created_at, uli.id) < ($6::timestamptz, $7::uuid)
+ORDER BY uli.created_at DESC, uli.id DESC
+LIMIT $8
 `
 
-type ListPublicLibraryItemsByUserParams struct {
-	UserID pgtype.UUID `db:"user_id" json:"user_id"`
-	Limit  int32       `db:"limit" json:"limit"`
-	Offset int32       `db:"offset" json:"offset"`
+type ListLibraryItemsNewestParams struct {
+	UserID         pgtype.UUID        `db:"user_id" json:"user_id"`
+	Username       pgtype.Text        `db:"username" json:"username"`
+	PublicOnly     bool               `db:"public_only" json:"public_only"`
+	Status         pgtype.Text        `db:"status" json:"status"`
+	Tag            pgtype.Text        `db:"tag" json:"tag"`
+	AfterCreatedAt pgtype.Timestamptz `db:"after_created_at" json:"after_created_at"`
+	AfterID        pgtype.UUID        `db:"after_id" json:"after_id"`
+	Limit          int32              `db:"limit" json:"limit"`
 }
 
-func (q *Queries) ListPublicLibraryItemsByUser(ctx context.Context, arg ListPublicLibraryItemsByUserParams) ([]UserLibraryItem, error) {
-	rows, err := q.db.Query(ctx, listPublicLibraryItemsByUser, arg.UserID, arg.Limit, arg.Offset)
+func (q *Queries) ListLibraryItemsNewest(ctx context.Context, arg ListLibraryItemsNewestParams) ([]UserLibraryItem, error) {
+	rows, err := q.db.Query(ctx, listLibraryItemsNewest,
+		arg.UserID,
+		arg.Username,
+		arg.PublicOnly,
+		arg.Status,
+		arg.Tag,
+		arg.AfterCreatedAt,
+		arg.AfterID,
+		arg.Limit,
+	)
 	if err != nil {
 		return nil, err
 	}
@@ -246,6 +414,7 @@ func (q *Queries) ListPublicLibraryItemsByUser(ctx context.Context, arg ListPubl
 			&i.CompletedAt,
 			&i.CreatedAt,
 			&i.UpdatedAt,
+			&i.RereadCount,
 		); err != nil {
 			return nil, err
 		}
@@ -257,23 +426,43 @@ func (q *Queries) ListPublicLibraryItemsByUser(ctx context.Context, arg ListPubl
 	return items, nil
 }
 
-const listPublicLibraryItemsByUsername = `-- name: ListPublicLibraryItemsByUsername :many
-SELECT uli.id, uli.user_id, uli.source_id, uli.status, uli.progress_value, uli.progress_unit, uli.visibility, uli.started_at, uli.completed_at, uli.created_at, uli.updated_at
+const listLibraryItemsOldest = `-- name: ListLibraryItemsOldest :many
+SELECT uli.id, uli.user_id, uli.source_id, uli.status, uli.progress_value, uli.progress_unit, uli.visibility, uli.started_at, uli.completed_at, uli.created_at, uli.updated_at, uli.reread_count
 FROM user_library_items uli
-JOIN users u ON u.id = uli.user_id
-WHERE u.username = $1 AND uli.visibility = 'public'
-ORDER BY uli.created_at DESC
-LIMIT $2 OFFSET $3
+WHERE ($1::uuid IS NULL OR uli.user_id = $1::uuid)
+  AND ($2::text IS NULL OR uli.user_id = (SELECT u.id FROM users u WHERE u.username = $2::text))
+  AND (NOT $3::bool OR uli.visibility = 'public')
+  AND ($4::text IS NULL OR uli.status = $4::text)
+  AND ($5::text IS NULL OR EXISTS (
+    SELECT 1 FROM library_item_tags lit JOIN tags t ON t.id = lit.tag_id
+    WHERE lit.library_item_id = uli.id AND t.slug = $5::text))
+  AND (uli.created_at, uli.id) > ($6::timestamptz, $7::uuid)
+ORDER BY uli.created_at ASC, uli.id ASC
+LIMIT $8
 `
 
-type ListPublicLibraryItemsByUsernameParams struct {
-	Username string `db:"username" json:"username"`
-	Limit    int32  `db:"limit" json:"limit"`
-	Offset   int32  `db:"offset" json:"offset"`
+type ListLibraryItemsOldestParams struct {
+	UserID         pgtype.UUID        `db:"user_id" json:"user_id"`
+	Username       pgtype.Text        `db:"username" json:"username"`
+	PublicOnly     bool               `db:"public_only" json:"public_only"`
+	Status         pgtype.Text        `db:"status" json:"status"`
+	Tag            pgtype.Text        `db:"tag" json:"tag"`
+	AfterCreatedAt pgtype.Timestamptz `db:"after_created_at" json:"after_created_at"`
+	AfterID        pgtype.UUID        `db:"after_id" json:"after_id"`
+	Limit          int32              `db:"limit" json:"limit"`
 }
 
-func (q *Queries) ListPublicLibraryItemsByUsername(ctx context.Context, arg ListPublicLibraryItemsByUsernameParams) ([]UserLibraryItem, error) {
-	rows, err := q.db.Query(ctx, listPublicLibraryItemsByUsername, arg.Username, arg.Limit, arg.Offset)
+func (q *Queries) ListLibraryItemsOldest(ctx context.Context, arg ListLibraryItemsOldestParams) ([]UserLibraryItem, error) {
+	rows, err := q.db.Query(ctx, listLibraryItemsOldest,
+		arg.UserID,
+		arg.Username,
+		arg.PublicOnly,
+		arg.Status,
+		arg.Tag,
+		arg.AfterCreatedAt,
+		arg.AfterID,
+		arg.Limit,
+	)
 	if err != nil {
 		return nil, err
 	}
@@ -293,6 +482,7 @@ func (q *Queries) ListPublicLibraryItemsByUsername(ctx context.Context, arg List
 			&i.CompletedAt,
 			&i.CreatedAt,
 			&i.UpdatedAt,
+			&i.RereadCount,
 		); err != nil {
 			return nil, err
 		}
@@ -304,24 +494,35 @@ func (q *Queries) ListPublicLibraryItemsByUsername(ctx context.Context, arg List
 	return items, nil
 }
 
-const listPublicLibraryItemsByUsernameWithSources = `-- name: ListPublicLibraryItemsByUsernameWithSources :many
-SELECT uli.id, uli.user_id, uli.source_id, uli.status, uli.progress_value, uli.progress_unit, uli.visibility, uli.started_at, uli.completed_at, uli.created_at, uli.updated_at,
+const listLibraryItemsWithSourcesNewest = `-- name: ListLibraryItemsWithSourcesNewest :many
+SELECT uli.id, uli.user_id, uli.source_id, uli.status, uli.progress_value, uli.progress_unit, uli.visibility, uli.started_at, uli.completed_at, uli.created_at, uli.updated_at, uli.reread_count,
        s.title, s.subtitle, s.type, s.publisher, s.isbn
 FROM user_library_items uli
-JOIN users u ON u.id = uli.user_id
 JOIN sources s ON s.id = uli.source_id
-WHERE u.username = $1 AND uli.visibility = 'public'
-ORDER BY uli.created_at DESC
-LIMIT $2 OFFSET $3
+WHERE ($1::uuid IS NULL OR uli.user_id = $1::uuid)
+  AND ($2::text IS NULL OR uli.user_id = (SELECT u.id FROM users u WHERE u.username = $2::text))
+  AND (NOT $3::bool OR uli.visibility = 'public')
+  AND ($4::text IS NULL OR uli.status = $4::text)
+  AND ($5::text IS NULL OR EXISTS (
+    SELECT 1 FROM library_item_tags lit JOIN tags t ON t.id = lit.tag_id
+    WHERE lit.library_item_id = uli.id AND t.slug = $5::text))
+  AND (uli.created_at, uli.id) < ($6::timestamptz, $7::uuid)
+ORDER BY uli.created_at DESC, uli.id DESC
+LIMIT $8
 `
 
-type ListPublicLibraryItemsByUsernameWithSourcesParams struct {
-	Username string `db:"username" json:"username"`
-	Limit    int32  `db:"limit" json:"limit"`
-	Offset   int32  `db:"offset" json:"offset"`
+type ListLibraryItemsWithSourcesNewestParams struct {
+	UserID         pgtype.UUID        `db:"user_id" json:"user_id"`
+	Username       pgtype.Text        `db:"username" json:"username"`
+	PublicOnly     bool               `db:"public_only" json:"public_only"`
+	Status         pgtype.Text        `db:"status" json:"status"`
+	Tag            pgtype.Text        `db:"tag" json:"tag"`
+	AfterCreatedAt pgtype.Timestamptz `db:"after_created_at" json:"after_created_at"`
+	AfterID        pgtype.UUID        `db:"after_id" json:"after_id"`
+	Limit          int32              `db:"limit" json:"limit"`
 }
 
-type ListPublicLibraryItemsByUsernameWithSourcesRow struct {
+type ListLibraryItemsWithSourcesNewestRow struct {
 	ID            pgtype.UUID        `db:"id" json:"id"`
 	UserID        pgtype.UUID        `db:"user_id" json:"user_id"`
 	SourceID      pgtype.UUID        `db:"source_id" json:"source_id"`
@@ -333,6 +534,7 @@ type ListPublicLibraryItemsByUsernameWithSourcesRow struct {
 	CompletedAt   pgtype.Timestamptz `db:"completed_at" json:"completed_at"`
 	CreatedAt     pgtype.Timestamptz `db:"created_at" json:"created_at"`
 	UpdatedAt     pgtype.Timestamptz `db:"updated_at" json:"updated_at"`
+	RereadCount   int32              `db:"reread_count" json:"reread_count"`
 	Title         string             `db:"title" json:"title"`
 	Subtitle      pgtype.Text        `db:"subtitle" json:"subtitle"`
 	Type          string             `db:"type" json:"type"`
@@ -340,15 +542,24 @@ type ListPublicLibraryItemsByUsernameWithSourcesRow struct {
 	Isbn          pgtype.Text        `db:"isbn" json:"isbn"`
 }
 
-func (q *Queries) ListPublicLibraryItemsByUsernameWithSources(ctx context.Context, arg ListPublicLibraryItemsByUsernameWithSourcesParams) ([]ListPublicLibraryItemsByUsernameWithSourcesRow, error) {
-	rows, err := q.db.Query(ctx, listPublicLibraryItemsByUsernameWithSources, arg.Username, arg.Limit, arg.Offset)
+func (q *Queries) ListLibraryItemsWithSourcesNewest(ctx context.Context, arg ListLibraryItemsWithSourcesNewestParams) ([]ListLibraryItemsWithSourcesNewestRow, error) {
+	rows, err := q.db.Query(ctx, listLibraryItemsWithSourcesNewest,
+		arg.UserID,
+		arg.Username,
+		arg.PublicOnly,
+		arg.Status,
+		arg.Tag,
+		arg.AfterCreatedAt,
+		arg.AfterID,
+		arg.Limit,
+	)
 	if err != nil {
 		return nil, err
 	}
 	defer rows.Close()
-	items := []ListPublicLibraryItemsByUsernameWithSourcesRow{}
+	items := []ListLibraryItemsWithSourcesNewestRow{}
 	for rows.Next() {
-		var i ListPublicLibraryItemsByUsernameWithSourcesRow
+		var i ListLibraryItemsWithSourcesNewestRow
 		if err := rows.Scan(
 			&i.ID,
 			&i.UserID,
@@ -361,6 +572,7 @@ func (q *Queries) ListPublicLibraryItemsByUsernameWithSources(ctx context.Contex
 			&i.CompletedAt,
 			&i.CreatedAt,
 			&i.UpdatedAt,
+			&i.RereadCount,
 			&i.Title,
 			&i.Subtitle,
 			&i.Type,
@@ -377,11 +589,142 @@ func (q *Queries) ListPublicLibraryItemsByUsernameWithSources(ctx context.Contex
 	return items, nil
 }
 
+const listLibraryItemsWithSourcesOldest = `-- name: ListLibraryItemsWithSourcesOldest :many
+SELECT uli.id, uli.user_id, uli.source_id, uli.status, uli.progress_value, uli.progress_unit, uli.visibility, uli.started_at, uli.completed_at, uli.created_at, uli.updated_at, uli.reread_count,
+       s.title, s.subtitle, s.type, s.publisher, s.isbn
+FROM user_library_items uli
+JOIN sources s ON s.id = uli.source_id
+WHERE ($1::uuid IS NULL OR uli.user_id = $1::uuid)
+  AND ($2::text IS NULL OR uli.user_id = (SELECT u.id FROM users u WHERE u.username = $2::text))
+  AND (NOT $3::bool OR uli.visibility = 'public')
+  AND ($4::text IS NULL OR uli.status = $4::text)
+  AND ($5::text IS NULL OR EXISTS (
+    SELECT 1 FROM library_item_tags lit JOIN tags t ON t.id = lit.tag_id
+    WHERE lit.library_item_id = uli.id AND t.slug = $5::text))
+  AND (uli.created_at, uli.id) > ($6::timestamptz, $7::uuid)
+ORDER BY uli.created_at ASC, uli.id ASC
+LIMIT $8
+`
+
+type ListLibraryItemsWithSourcesOldestParams struct {
+	UserID         pgtype.UUID        `db:"user_id" json:"user_id"`
+	Username       pgtype.Text        `db:"username" json:"username"`
+	PublicOnly     bool               `db:"public_only" json:"public_only"`
+	Status         pgtype.Text        `db:"status" json:"status"`
+	Tag            pgtype.Text        `db:"tag" json:"tag"`
+	AfterCreatedAt pgtype.Timestamptz `db:"after_created_at" json:"after_created_at"`
+	AfterID        pgtype.UUID        `db:"after_id" json:"after_id"`
+	Limit          int32              `db:"limit" json:"limit"`
+}
+
+type ListLibraryItemsWithSourcesOldestRow struct {
+	ID            pgtype.UUID        `db:"id" json:"id"`
+	UserID        pgtype.UUID        `db:"user_id" json:"user_id"`
+	SourceID      pgtype.UUID        `db:"source_id" json:"source_id"`
+	Status        string             `db:"status" json:"status"`
+	ProgressValue pgtype.Int4        `db:"progress_value" json:"progress_value"`
+	ProgressUnit  pgtype.Text        `db:"progress_unit" json:"progress_unit"`
+	Visibility    string             `db:"visibility" json:"visibility"`
+	StartedAt     pgtype.Timestamptz `db:"started_at" json:"started_at"`
+	CompletedAt   pgtype.Timestamptz `db:"completed_at" json:"completed_at"`
+	CreatedAt     pgtype.Timestamptz `db:"created_at" json:"created_at"`
+	UpdatedAt     pgtype.Timestamptz `db:"updated_at" json:"updated_at"`
+	RereadCount   int32              `db:"reread_count" json:"reread_count"`
+	Title         string             `db:"title" json:"title"`
+	Subtitle      pgtype.Text        `db:"subtitle" json:"subtitle"`
+	Type          string             `db:"type" json:"type"`
+	Publisher     pgtype.Text        `db:"publisher" json:"publisher"`
+	Isbn          pgtype.Text        `db:"isbn" json:"isbn"`
+}
+
+func (q *Queries) ListLibraryItemsWithSourcesOldest(ctx context.Context, arg ListLibraryItemsWithSourcesOldestParams) ([]ListLibraryItemsWithSourcesOldestRow, error) {
+	rows, err := q.db.Query(ctx, listLibraryItemsWithSourcesOldest,
+		arg.UserID,
+		arg.Username,
+		arg.PublicOnly,
+		arg.Status,
+		arg.Tag,
+		arg.AfterCreatedAt,
+		arg.AfterID,
+		arg.Limit,
+	)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	items := []ListLibraryItemsWithSourcesOldestRow{}
+	for rows.Next() {
+		var i ListLibraryItemsWithSourcesOldestRow
+		if err := rows.Scan(
+			&i.ID,
+			&i.UserID,
+			&i.SourceID,
+			&i.Status,
+			&i.ProgressValue,
+			&i.ProgressUnit,
+			&i.Visibility,
+			&i.StartedAt,
+			&i.CompletedAt,
+			&i.CreatedAt,
+			&i.UpdatedAt,
+			&i.RereadCount,
+			&i.Title,
+			&i.Subtitle,
+			&i.Type,
+			&i.Publisher,
+			&i.Isbn,
+		); err != nil {
+			return nil, err
+		}
+		items = append(items, i)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
+
+const listTagsByLibraryItems = `-- name: ListTagsByLibraryItems :many
+SELECT lit.library_item_id, t.name
+FROM library_item_tags lit
+JOIN tags t ON t.id = lit.tag_id
+WHERE lit.library_item_id = ANY($1::uuid[])
+ORDER BY lit.library_item_id, lit.position, t.slug
+`
+
+type ListTagsByLibraryItemsRow struct {
+	LibraryItemID pgtype.UUID `db:"library_item_id" json:"library_item_id"`
+	Name          string      `db:"name" json:"name"`
+}
+
+func (q *Queries) ListTagsByLibraryItems(ctx context.Context, libraryItemIds []pgtype.UUID) ([]ListTagsByLibraryItemsRow, error) {
+	rows, err := q.db.Query(ctx, listTagsByLibraryItems, libraryItemIds)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	items := []ListTagsByLibraryItemsRow{}
+	for rows.Next() {
+		var i ListTagsByLibraryItemsRow
+		if err := rows.Scan(
+			&i.LibraryItemID,
+			&i.Name,
+		); err != nil {
+			return nil, err
+		}
+		items = append(items, i)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
+
 const updateLibraryItem = `-- name: UpdateLibraryItem :one
 UPDATE user_library_items
-SET status = $2, progress_value = $3, progress_unit = $4, visibility = $5, started_at = $6, completed_at = $7, updated_at = NOW()
+SET status = $2, progress_value = $3, progress_unit = $4, visibility = $5, started_at = $6, completed_at = $7, reread_count = $8, updated_at = NOW()
 WHERE id = $1
-RETURNING id, user_id, source_id, status, progress_value, progress_unit, visibility, started_at, completed_at, created_at, updated_at
+RETURNING id, user_id, source_id, status, progress_value, progress_unit, visibility, started_at, completed_at, created_at, updated_at, reread_count
 `
 
 type UpdateLibraryItemParams struct {
@@ -392,6 +735,7 @@ type UpdateLibraryItemParams struct {
 	Visibility    string             `db:"visibility" json:"visibility"`
 	StartedAt     pgtype.Timestamptz `db:"started_at" json:"started_at"`
 	CompletedAt   pgtype.Timestamptz `db:"completed_at" json:"completed_at"`
+	RereadCount   int32              `db:"reread_count" json:"reread_count"`
 }
 
 func (q *Queries) UpdateLibraryItem(ctx context.Context, arg UpdateLibraryItemParams) (UserLibraryItem, error) {
@@ -403,6 +747,7 @@ func (q *Queries) UpdateLibraryItem(ctx context.Context, arg UpdateLibraryItemPa
 		arg.Visibility,
 		arg.StartedAt,
 		arg.CompletedAt,
+		arg.RereadCount,
 	)
 	var i UserLibraryItem
 	err := row.Scan(
@@ -417,6 +762,7 @@ func (q *Queries) UpdateLibraryItem(ctx context.Context, arg UpdateLibraryItemPa
 		&i.CompletedAt,
 		&i.CreatedAt,
 		&i.UpdatedAt,
+		&i.RereadCount,
 	)
 	return i, err
 }
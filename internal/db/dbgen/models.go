@@ -6,8 +6,17 @@ package dbgen
 
 import (
 	"github.com/jackc/pgx/v5/pgtype"
+	pgvector "github.com/pgvector/pgvector-go"
 )
 
+type ArticleMetadatum struct {
+	SourceID  pgtype.UUID        `db:"source_id" json:"source_id"`
+	Site      pgtype.Text        `db:"site" json:"site"`
+	WordCount pgtype.Int4        `db:"word_count" json:"word_count"`
+	CreatedAt pgtype.Timestamptz `db:"created_at" json:"created_at"`
+	UpdatedAt pgtype.Timestamptz `db:"updated_at" json:"updated_at"`
+}
+
 type BookMetadatum struct {
 	SourceID  pgtype.UUID        `db:"source_id" json:"source_id"`
 	Isbn10    pgtype.Text        `db:"isbn_10" json:"isbn_10"`
@@ -26,9 +35,46 @@ type Collection struct {
 	Name        string             `db:"name" json:"name"`
 	Description pgtype.Text        `db:"description" json:"description"`
 	IsPublic    pgtype.Bool        `db:"is_public" json:"is_public"`
-	SourceIds   []byte             `db:"source_ids" json:"source_ids"`
 	CreatedAt   pgtype.Timestamptz `db:"created_at" json:"created_at"`
 	UpdatedAt   pgtype.Timestamptz `db:"updated_at" json:"updated_at"`
+	Rules       []byte             `db:"rules" json:"rules"`
+}
+
+type CollectionActivity struct {
+	ID           pgtype.UUID        `db:"id" json:"id"`
+	CollectionID pgtype.UUID        `db:"collection_id" json:"collection_id"`
+	ActorID      pgtype.UUID        `db:"actor_id" json:"actor_id"`
+	Action       string             `db:"action" json:"action"`
+	SourceID     pgtype.UUID        `db:"source_id" json:"source_id"`
+	MemberID     pgtype.UUID        `db:"member_id" json:"member_id"`
+	Role         pgtype.Text        `db:"role" json:"role"`
+	CreatedAt    pgtype.Timestamptz `db:"created_at" json:"created_at"`
+}
+
+type CollectionInvitation struct {
+	ID           pgtype.UUID        `db:"id" json:"id"`
+	CollectionID pgtype.UUID        `db:"collection_id" json:"collection_id"`
+	UserID       pgtype.UUID        `db:"user_id" json:"user_id"`
+	Role         string             `db:"role" json:"role"`
+	InvitedBy    pgtype.UUID        `db:"invited_by" json:"invited_by"`
+	CreatedAt    pgtype.Timestamptz `db:"created_at" json:"created_at"`
+}
+
+type CollectionItem struct {
+	CollectionID pgtype.UUID        `db:"collection_id" json:"collection_id"`
+	SourceID     pgtype.UUID        `db:"source_id" json:"source_id"`
+	Position     int32              `db:"position" json:"position"`
+	Note         pgtype.Text        `db:"note" json:"note"`
+	AddedAt      pgtype.Timestamptz `db:"added_at" json:"added_at"`
+	AddedBy      pgtype.UUID        `db:"added_by" json:"added_by"`
+}
+
+type CollectionMember struct {
+	CollectionID pgtype.UUID        `db:"collection_id" json:"collection_id"`
+	UserID       pgtype.UUID        `db:"user_id" json:"user_id"`
+	Role         string             `db:"role" json:"role"`
+	InvitedBy    pgtype.UUID        `db:"invited_by" json:"invited_by"`
+	JoinedAt     pgtype.Timestamptz `db:"joined_at" json:"joined_at"`
 }
 
 type Contributor struct {
@@ -39,6 +85,54 @@ type Contributor struct {
 	UpdatedAt pgtype.Timestamptz `db:"updated_at" json:"updated_at"`
 }
 
+type Goal struct {
+	ID         pgtype.UUID        `db:"id" json:"id"`
+	OwnerID    pgtype.UUID        `db:"owner_id" json:"owner_id"`
+	Title      string             `db:"title" json:"title"`
+	Target     int32              `db:"target" json:"target"`
+	Period     string             `db:"period" json:"period"`
+	SourceType pgtype.Text        `db:"source_type" json:"source_type"`
+	StartsOn   pgtype.Date        `db:"starts_on" json:"starts_on"`
+	EndsOn     pgtype.Date        `db:"ends_on" json:"ends_on"`
+	Shared     bool               `db:"shared" json:"shared"`
+	CreatedAt  pgtype.Timestamptz `db:"created_at" json:"created_at"`
+	UpdatedAt  pgtype.Timestamptz `db:"updated_at" json:"updated_at"`
+}
+
+type GoalAchievement struct {
+	GoalID      pgtype.UUID        `db:"goal_id" json:"goal_id"`
+	UserID      pgtype.UUID        `db:"user_id" json:"user_id"`
+	PeriodStart pgtype.Date        `db:"period_start" json:"period_start"`
+	AchievedAt  pgtype.Timestamptz `db:"achieved_at" json:"achieved_at"`
+}
+
+type GoalParticipant struct {
+	GoalID   pgtype.UUID        `db:"goal_id" json:"goal_id"`
+	UserID   pgtype.UUID        `db:"user_id" json:"user_id"`
+	JoinedAt pgtype.Timestamptz `db:"joined_at" json:"joined_at"`
+}
+
+type LibraryCompletion struct {
+	LibraryItemID pgtype.UUID        `db:"library_item_id" json:"library_item_id"`
+	UserID        pgtype.UUID        `db:"user_id" json:"user_id"`
+	CompletedAt   pgtype.Timestamptz `db:"completed_at" json:"completed_at"`
+}
+
+type LibraryItemCompletion struct {
+	ID            pgtype.UUID        `db:"id" json:"id"`
+	LibraryItemID pgtype.UUID        `db:"library_item_id" json:"library_item_id"`
+	UserID        pgtype.UUID        `db:"user_id" json:"user_id"`
+	StartedAt     pgtype.Timestamptz `db:"started_at" json:"started_at"`
+	CompletedAt   pgtype.Timestamptz `db:"completed_at" json:"completed_at"`
+	CreatedAt     pgtype.Timestamptz `db:"created_at" json:"created_at"`
+}
+
+type LibraryItemTag struct {
+	LibraryItemID pgtype.UUID `db:"library_item_id" json:"library_item_id"`
+	TagID         pgtype.UUID `db:"tag_id" json:"tag_id"`
+	Position      int32       `db:"position" json:"position"`
+}
+
 type Note struct {
 	ID          pgtype.UUID        `db:"id" json:"id"`
 	UserID      pgtype.UUID        `db:"user_id" json:"user_id"`
@@ -47,11 +141,27 @@ type Note struct {
 	ContentType string             `db:"content_type" json:"content_type"`
 	IsPublic    pgtype.Bool        `db:"is_public" json:"is_public"`
 	Annotations []byte             `db:"annotations" json:"annotations"`
-	Tags        []byte             `db:"tags" json:"tags"`
+	CreatedAt   pgtype.Timestamptz `db:"created_at" json:"created_at"`
+	UpdatedAt   pgtype.Timestamptz `db:"updated_at" json:"updated_at"`
+	Anchor      []byte             `db:"anchor" json:"anchor"`
+	LocationKey pgtype.Text        `db:"location_key" json:"location_key"`
+}
+
+type NoteEmbedding struct {
+	NoteID      pgtype.UUID        `db:"note_id" json:"note_id"`
+	Model       string             `db:"model" json:"model"`
+	Embedding   pgvector.Vector    `db:"embedding" json:"embedding"`
+	ContentHash []byte             `db:"content_hash" json:"content_hash"`
 	CreatedAt   pgtype.Timestamptz `db:"created_at" json:"created_at"`
 	UpdatedAt   pgtype.Timestamptz `db:"updated_at" json:"updated_at"`
 }
 
+type NoteTag struct {
+	NoteID   pgtype.UUID `db:"note_id" json:"note_id"`
+	TagID    pgtype.UUID `db:"tag_id" json:"tag_id"`
+	Position int32       `db:"position" json:"position"`
+}
+
 type Outbox struct {
 	ID            pgtype.UUID        `db:"id" json:"id"`
 	AggregateType string             `db:"aggregate_type" json:"aggregate_type"`
@@ -61,16 +171,84 @@ type Outbox struct {
 	Published     pgtype.Bool        `db:"published" json:"published"`
 	CreatedAt     pgtype.Timestamptz `db:"created_at" json:"created_at"`
 	PublishedAt   pgtype.Timestamptz `db:"published_at" json:"published_at"`
+	Attempts      int32              `db:"attempts" json:"attempts"`
+	LastError     pgtype.Text        `db:"last_error" json:"last_error"`
+	NextAttemptAt pgtype.Timestamptz `db:"next_attempt_at" json:"next_attempt_at"`
+	DeadAt        pgtype.Timestamptz `db:"dead_at" json:"dead_at"`
+}
+
+type OidcLogin struct {
+	StateHash      []byte             `db:"state_hash" json:"state_hash"`
+	Provider       string             `db:"provider" json:"provider"`
+	Nonce          string             `db:"nonce" json:"nonce"`
+	CodeVerifier   string             `db:"code_verifier" json:"code_verifier"`
+	UserID         pgtype.UUID        `db:"user_id" json:"user_id"`
+	ExpiresAt      pgtype.Timestamptz `db:"expires_at" json:"expires_at"`
+	CreatedAt      pgtype.Timestamptz `db:"created_at" json:"created_at"`
+	Reauthenticate bool               `db:"reauthenticate" json:"reauthenticate"`
+}
+
+type PaperMetadatum struct {
+	SourceID  pgtype.UUID        `db:"source_id" json:"source_id"`
+	Venue     pgtype.Text        `db:"venue" json:"venue"`
+	ArxivID   pgtype.Text        `db:"arxiv_id" json:"arxiv_id"`
+	CreatedAt pgtype.Timestamptz `db:"created_at" json:"created_at"`
+	UpdatedAt pgtype.Timestamptz `db:"updated_at" json:"updated_at"`
+}
+
+type PersonalAccessToken struct {
+	ID         pgtype.UUID        `db:"id" json:"id"`
+	UserID     pgtype.UUID        `db:"user_id" json:"user_id"`
+	Name       string             `db:"name" json:"name"`
+	TokenHash  []byte             `db:"token_hash" json:"token_hash"`
+	Scopes     []string           `db:"scopes" json:"scopes"`
+	ExpiresAt  pgtype.Timestamptz `db:"expires_at" json:"expires_at"`
+	LastUsedAt pgtype.Timestamptz `db:"last_used_at" json:"last_used_at"`
+	LastUsedIp pgtype.Text        `db:"last_used_ip" json:"last_used_ip"`
+	RevokedAt  pgtype.Timestamptz `db:"revoked_at" json:"revoked_at"`
+	CreatedAt  pgtype.Timestamptz `db:"created_at" json:"created_at"`
+}
+
+type PodcastMetadatum struct {
+	SourceID        pgtype.UUID        `db:"source_id" json:"source_id"`
+	Show            string             `db:"show" json:"show"`
+	EpisodeNumber   pgtype.Int4        `db:"episode_number" json:"episode_number"`
+	DurationSeconds pgtype.Int4        `db:"duration_seconds" json:"duration_seconds"`
+	FeedUrl         pgtype.Text        `db:"feed_url" json:"feed_url"`
+	CreatedAt       pgtype.Timestamptz `db:"created_at" json:"created_at"`
+	UpdatedAt       pgtype.Timestamptz `db:"updated_at" json:"updated_at"`
 }
 
 type Profile struct {
+	ID                 pgtype.UUID        `db:"id" json:"id"`
+	UserID             pgtype.UUID        `db:"user_id" json:"user_id"`
+	DisplayName        pgtype.Text        `db:"display_name" json:"display_name"`
+	Bio                pgtype.Text        `db:"bio" json:"bio"`
+	PublicProfile      pgtype.Bool        `db:"public_profile" json:"public_profile"`
+	CreatedAt          pgtype.Timestamptz `db:"created_at" json:"created_at"`
+	UpdatedAt          pgtype.Timestamptz `db:"updated_at" json:"updated_at"`
+	PublicYearInReview bool               `db:"public_year_in_review" json:"public_year_in_review"`
+}
+
+type ReadingSession struct {
 	ID            pgtype.UUID        `db:"id" json:"id"`
+	LibraryItemID pgtype.UUID        `db:"library_item_id" json:"library_item_id"`
 	UserID        pgtype.UUID        `db:"user_id" json:"user_id"`
-	DisplayName   pgtype.Text        `db:"display_name" json:"display_name"`
-	Bio           pgtype.Text        `db:"bio" json:"bio"`
-	PublicProfile pgtype.Bool        `db:"public_profile" json:"public_profile"`
+	StartedAt     pgtype.Timestamptz `db:"started_at" json:"started_at"`
+	EndedAt       pgtype.Timestamptz `db:"ended_at" json:"ended_at"`
+	ProgressFrom  pgtype.Int4        `db:"progress_from" json:"progress_from"`
+	ProgressTo    pgtype.Int4        `db:"progress_to" json:"progress_to"`
+	ProgressUnit  pgtype.Text        `db:"progress_unit" json:"progress_unit"`
+	Device        pgtype.Text        `db:"device" json:"device"`
 	CreatedAt     pgtype.Timestamptz `db:"created_at" json:"created_at"`
-	UpdatedAt     pgtype.Timestamptz `db:"updated_at" json:"updated_at"`
+}
+
+type RecoveryCode struct {
+	ID        pgtype.UUID        `db:"id" json:"id"`
+	UserID    pgtype.UUID        `db:"user_id" json:"user_id"`
+	CodeHash  []byte             `db:"code_hash" json:"code_hash"`
+	UsedAt    pgtype.Timestamptz `db:"used_at" json:"used_at"`
+	CreatedAt pgtype.Timestamptz `db:"created_at" json:"created_at"`
 }
 
 type RefreshToken struct {
@@ -95,6 +273,15 @@ type Review struct {
 	UpdatedAt pgtype.Timestamptz `db:"updated_at" json:"updated_at"`
 }
 
+type Session struct {
+	FamilyID   pgtype.UUID        `db:"family_id" json:"family_id"`
+	UserID     pgtype.UUID        `db:"user_id" json:"user_id"`
+	UserAgent  pgtype.Text        `db:"user_agent" json:"user_agent"`
+	IpAddress  pgtype.Text        `db:"ip_address" json:"ip_address"`
+	CreatedAt  pgtype.Timestamptz `db:"created_at" json:"created_at"`
+	LastUsedAt pgtype.Timestamptz `db:"last_used_at" json:"last_used_at"`
+}
+
 type Source struct {
 	ID          pgtype.UUID        `db:"id" json:"id"`
 	Title       string             `db:"title" json:"title"`
@@ -106,10 +293,10 @@ type Source struct {
 	Doi         pgtype.Text        `db:"doi" json:"doi"`
 	Url         pgtype.Text        `db:"url" json:"url"`
 	ExternalID  pgtype.Text        `db:"external_id" json:"external_id"`
-	Tags        []byte             `db:"tags" json:"tags"`
 	PublishedAt pgtype.Timestamptz `db:"published_at" json:"published_at"`
 	CreatedAt   pgtype.Timestamptz `db:"created_at" json:"created_at"`
 	UpdatedAt   pgtype.Timestamptz `db:"updated_at" json:"updated_at"`
+	CreatedBy   pgtype.UUID        `db:"created_by" json:"created_by"`
 }
 
 type SourceContributor struct {
@@ -120,11 +307,61 @@ type SourceContributor struct {
 	CreatedAt     pgtype.Timestamptz `db:"created_at" json:"created_at"`
 }
 
+type SourceEmbedding struct {
+	SourceID    pgtype.UUID        `db:"source_id" json:"source_id"`
+	Model       string             `db:"model" json:"model"`
+	Embedding   pgvector.Vector    `db:"embedding" json:"embedding"`
+	ContentHash []byte             `db:"content_hash" json:"content_hash"`
+	CreatedAt   pgtype.Timestamptz `db:"created_at" json:"created_at"`
+	UpdatedAt   pgtype.Timestamptz `db:"updated_at" json:"updated_at"`
+}
+
+type SourceProposal struct {
+	ID         pgtype.UUID        `db:"id" json:"id"`
+	SourceID   pgtype.UUID        `db:"source_id" json:"source_id"`
+	ProposedBy pgtype.UUID        `db:"proposed_by" json:"proposed_by"`
+	Changes    []byte             `db:"changes" json:"changes"`
+	Status     string             `db:"status" json:"status"`
+	ReviewedBy pgtype.UUID        `db:"reviewed_by" json:"reviewed_by"`
+	ReviewNote pgtype.Text        `db:"review_note" json:"review_note"`
+	ReviewedAt pgtype.Timestamptz `db:"reviewed_at" json:"reviewed_at"`
+	CreatedAt  pgtype.Timestamptz `db:"created_at" json:"created_at"`
+	UpdatedAt  pgtype.Timestamptz `db:"updated_at" json:"updated_at"`
+}
+
+type SourceRevision struct {
+	ID         pgtype.UUID        `db:"id" json:"id"`
+	SourceID   pgtype.UUID        `db:"source_id" json:"source_id"`
+	Revision   int32              `db:"revision" json:"revision"`
+	Action     string             `db:"action" json:"action"`
+	EditedBy   pgtype.UUID        `db:"edited_by" json:"edited_by"`
+	ProposalID pgtype.UUID        `db:"proposal_id" json:"proposal_id"`
+	RevertedTo pgtype.Int4        `db:"reverted_to" json:"reverted_to"`
+	Snapshot   []byte             `db:"snapshot" json:"snapshot"`
+	Changes    []byte             `db:"changes" json:"changes"`
+	CreatedAt  pgtype.Timestamptz `db:"created_at" json:"created_at"`
+	MergedFrom pgtype.UUID        `db:"merged_from" json:"merged_from"`
+}
+
+type SourceSearch struct {
+	SourceID   pgtype.UUID        `db:"source_id" json:"source_id"`
+	Document   interface{}        `db:"document" json:"document"`
+	SearchText string             `db:"search_text" json:"search_text"`
+	UpdatedAt  pgtype.Timestamptz `db:"updated_at" json:"updated_at"`
+}
+
+type SourceTag struct {
+	SourceID pgtype.UUID `db:"source_id" json:"source_id"`
+	TagID    pgtype.UUID `db:"tag_id" json:"tag_id"`
+	Position int32       `db:"position" json:"position"`
+}
+
 type Tag struct {
 	ID        pgtype.UUID        `db:"id" json:"id"`
 	Name      string             `db:"name" json:"name"`
 	CreatedAt pgtype.Timestamptz `db:"created_at" json:"created_at"`
 	UpdatedAt pgtype.Timestamptz `db:"updated_at" json:"updated_at"`
+	Slug      string             `db:"slug" json:"slug"`
 }
 
 type User struct {
@@ -135,6 +372,17 @@ type User struct {
 	EmailVerifiedAt pgtype.Timestamptz `db:"email_verified_at" json:"email_verified_at"`
 	CreatedAt       pgtype.Timestamptz `db:"created_at" json:"created_at"`
 	UpdatedAt       pgtype.Timestamptz `db:"updated_at" json:"updated_at"`
+	Role            string             `db:"role" json:"role"`
+}
+
+type UserIdentity struct {
+	ID         pgtype.UUID        `db:"id" json:"id"`
+	UserID     pgtype.UUID        `db:"user_id" json:"user_id"`
+	Provider   string             `db:"provider" json:"provider"`
+	Subject    string             `db:"subject" json:"subject"`
+	Email      pgtype.Text        `db:"email" json:"email"`
+	CreatedAt  pgtype.Timestamptz `db:"created_at" json:"created_at"`
+	LastUsedAt pgtype.Timestamptz `db:"last_used_at" json:"last_used_at"`
 }
 
 type UserLibraryItem struct {
@@ -149,4 +397,33 @@ type UserLibraryItem struct {
 	CompletedAt   pgtype.Timestamptz `db:"completed_at" json:"completed_at"`
 	CreatedAt     pgtype.Timestamptz `db:"created_at" json:"created_at"`
 	UpdatedAt     pgtype.Timestamptz `db:"updated_at" json:"updated_at"`
+	RereadCount   int32              `db:"reread_count" json:"reread_count"`
+}
+
+type UserTotp struct {
+	UserID           pgtype.UUID        `db:"user_id" json:"user_id"`
+	SecretCiphertext []byte             `db:"secret_ciphertext" json:"secret_ciphertext"`
+	ConfirmedAt      pgtype.Timestamptz `db:"confirmed_at" json:"confirmed_at"`
+	LastUsedStep     int64              `db:"last_used_step" json:"last_used_step"`
+	CreatedAt        pgtype.Timestamptz `db:"created_at" json:"created_at"`
+}
+
+type UserToken struct {
+	ID        pgtype.UUID        `db:"id" json:"id"`
+	UserID    pgtype.UUID        `db:"user_id" json:"user_id"`
+	Purpose   string             `db:"purpose" json:"purpose"`
+	TokenHash []byte             `db:"token_hash" json:"token_hash"`
+	ExpiresAt pgtype.Timestamptz `db:"expires_at" json:"expires_at"`
+	UsedAt    pgtype.Timestamptz `db:"used_at" json:"used_at"`
+	CreatedAt pgtype.Timestamptz `db:"created_at" json:"created_at"`
+}
+
+type VideoMetadatum struct {
+	SourceID        pgtype.UUID        `db:"source_id" json:"source_id"`
+	Channel         pgtype.Text        `db:"channel" json:"channel"`
+	DurationSeconds pgtype.Int4        `db:"duration_seconds" json:"duration_seconds"`
+	Platform        pgtype.Text        `db:"platform" json:"platform"`
+	PlatformID      pgtype.Text        `db:"platform_id" json:"platform_id"`
+	CreatedAt       pgtype.Timestamptz `db:"created_at" json:"created_at"`
+	UpdatedAt       pgtype.Timestamptz `db:"updated_at" json:"updated_at"`
 }
@@ -11,6 +11,39 @@ import (
 	"github.com/jackc/pgx/v5/pgtype"
 )
 
+const countNotes = `-- name: CountNotes :one
+SELECT COUNT(*)
+FROM notes
+WHERE ($1::uuid IS NULL OR user_id = $1::uuid)
+  AND ($2::uuid IS NULL OR source_id = $2::uuid)
+  AND (NOT $3::bool OR is_public = true)
+  AND ($4::text IS NULL OR content_type = $4::text)
+  AND ($5::text IS NULL OR EXISTS (
+    SELECT 1 FROM note_tags nt JOIN tags t ON t.id = nt.tag_id
+    WHERE nt.note_id = notes.id AND t.slug = $5::text))
+`
+
+type CountNotesParams struct {
+	UserID      pgtype.UUID `db:"user_id" json:"user_id"`
+	SourceID    pgtype.UUID `db:"source_id" json:"source_id"`
+	PublicOnly  bool        `db:"public_only" json:"public_only"`
+	ContentType pgtype.Text `db:"content_type" json:"content_type"`
+	Tag         pgtype.Text `db:"tag" json:"tag"`
+}
+
+func (q *Queries) CountNotes(ctx context.Context, arg CountNotesParams) (int64, error) {
+	row := q.db.QueryRow(ctx, countNotes,
+		arg.UserID,
+		arg.SourceID,
+		arg.PublicOnly,
+		arg.ContentType,
+		arg.Tag,
+	)
+	var count int64
+	err := row.Scan(&count)
+	return count, err
+}
+
 const countNotesByUser = `-- name: CountNotesByUser :one
 SELECT COUNT(*) FROM notes WHERE user_id = $1
 `
@@ -23,9 +56,9 @@ func (q *Queries) CountNotesByUser(ctx context.Context, userID pgtype.UUID) (int
 }
 
 const createNote = `-- name: CreateNote :one
-INSERT INTO notes (id, user_id, source_id, content, content_type, is_public, annotations, tags)
-VALUES ($1, $2, $3, $4, $5, $6, $7, $8)
-RETURNING id, user_id, source_id, content, content_type, is_public, annotations, tags, created_at, updated_at
+INSERT INTO notes (id, user_id, source_id, content, content_type, is_public, annotations, anchor, location_key)
+VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9)
+RETURNING id, user_id, source_id, content, content_type, is_public, annotations, created_at, updated_at, anchor, location_key
 `
 
 type CreateNoteParams struct {
@@ -36,7 +69,8 @@ type CreateNoteParams struct {
 	ContentType string      `db:"content_type" json:"content_type"`
 	IsPublic    pgtype.Bool `db:"is_public" json:"is_public"`
 	Annotations []byte      `db:"annotations" json:"annotations"`
-	Tags        []byte      `db:"tags" json:"tags"`
+	Anchor      []byte      `db:"anchor" json:"anchor"`
+	LocationKey pgtype.Text `db:"location_key" json:"location_key"`
 }
 
 func (q *Queries) CreateNote(ctx context.Context, arg CreateNoteParams) (Note, error) {
@@ -48,7 +82,8 @@ func (q *Queries) CreateNote(ctx context.Context, arg CreateNoteParams) (Note, e
 		arg.ContentType,
 		arg.IsPublic,
 		arg.Annotations,
-		arg.Tags,
+		arg.Anchor,
+		arg.LocationKey,
 	)
 	var i Note
 	err := row.Scan(
@@ -59,24 +94,37 @@ func (q *Queries) CreateNote(ctx context.Context, arg CreateNoteParams) (Note, e
 		&i.ContentType,
 		&i.IsPublic,
 		&i.Annotations,
-		&i.Tags,
 		&i.CreatedAt,
 		&i.UpdatedAt,
+		&i.Anchor,
+		&i.LocationKey,
 	)
 	return i, err
 }
 
-const deleteNote = `-- name: DeleteNote :exec
+const deleteNote = `-- name: DeleteNote :execrows
 DELETE FROM notes WHERE id = $1
 `
 
-func (q *Queries) DeleteNote(ctx context.Context, id pgtype.UUID) error {
-	_, err := q.db.Exec(ctx, deleteNote, id)
+func (q *Queries) DeleteNote(ctx context.Context, id pgtype.UUID) (int64, error) {
+	result, err := q.db.Exec(ctx, deleteNote, id)
+	if err != nil {
+		return 0, err
+	}
+	return result.RowsAffected(), nil
+}
+
+const deleteNoteTags = `-- name: DeleteNoteTags :exec
+DELETE FROM note_tags WHERE note_id = $1
+`
+
+func (q *Queries) DeleteNoteTags(ctx context.Context, noteID pgtype.UUID) error {
+	_, err := q.db.Exec(ctx, deleteNoteTags, noteID)
 	return err
 }
 
 const getNoteByID = `-- name: GetNoteByID :one
-SELECT id, user_id, source_id, content, content_type, is_public, annotations, tags, created_at, updated_at
+SELECT id, user_id, source_id, content, content_type, is_public, annotations, created_at, updated_at, anchor, location_key
 FROM notes
 WHERE id = $1
 LIMIT 1
@@ -93,15 +141,92 @@ func (q *Queries) GetNoteByID(ctx context.Context, id pgtype.UUID) (Note, error)
 		&i.ContentType,
 		&i.IsPublic,
 		&i.Annotations,
-		&i.Tags,
 		&i.CreatedAt,
 		&i.UpdatedAt,
+		&i.Anchor,
+		&i.LocationKey,
 	)
 	return i, err
 }
 
+const insertNoteTags = `-- name: InsertNoteTags :exec
+INSERT INTO note_tags (note_id, tag_id, position)
+SELECT $1::uuid, tag.id, tag.position - 1
+FROM unnest($2::uuid[]) WITH ORDINALITY AS tag(id, position)
+ON CONFLICT DO NOTHING
+`
+
+type InsertNoteTagsParams struct {
+	NoteID pgtype.UUID   `db:"note_id" json:"note_id"`
+	TagIds []pgtype.UUID `db:"tag_ids" json:"tag_ids"`
+}
+
+func (q *Queries) InsertNoteTags(ctx context.Context, arg InsertNoteTagsParams) error {
+	_, err := q.db.Exec(ctx, insertNoteTags, arg.NoteID, arg.TagIds)
+	return err
+}
+
+const listNotesByLocation = `-- name: ListNotesByLocation :many
+SELECT id, user_id, source_id, content, content_type, is_public, annotations, created_at, updated_at, anchor, location_key
+FROM notes
+WHERE source_id = $1
+  AND ($2::uuid IS NULL OR user_id = $2::uuid)
+  AND (NOT $3::bool OR is_public = true)
+  AND ($4::text IS NULL OR content_type = $4::text)
+ORDER BY location_key ASC NULLS LAST, created_at ASC, id ASC
+LIMIT $5 OFFSET $6
+`
+
+type ListNotesByLocationParams struct {
+	SourceID    pgtype.UUID `db:"source_id" json:"source_id"`
+	UserID      pgtype.UUID `db:"user_id" json:"user_id"`
+	PublicOnly  bool        `db:"public_only" json:"public_only"`
+	ContentType pgtype.Text `db:"content_type" json:"content_type"`
+	Limit       int32       `db:"limit" json:"limit"`
+	Offset      int32       `db:"offset" json:"offset"`
+}
+
+func (q *Queries) ListNotesByLocation(ctx context.Context, arg ListNotesByLocationParams) ([]Note, error) {
+	rows, err := q.db.Query(ctx, listNotesByLocation,
+		arg.SourceID,
+		arg.UserID,
+		arg.PublicOnly,
+		arg.ContentType,
+		arg.Limit,
+		arg.Offset,
+	)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	items := []Note{}
+	for rows.Next() {
+		var i Note
+		if err := rows.Scan(
+			&i.ID,
+			&i.UserID,
+			&i.SourceID,
+			&i.Content,
+			&i.ContentType,
+			&i.IsPublic,
+			&i.Annotations,
+			&i.CreatedAt,
+			&i.UpdatedAt,
+			&i.Anchor,
+			&i.LocationKey,
+		); err != nil {
+			return nil, err
+		}
+		items = append(items, i)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
+
 const listNotesBySource = `-- name: ListNotesBySource :many
-SELECT id, user_id, source_id, content, content_type, is_public, annotations, tags, created_at, updated_at
+SELECT id, user_id, source_id, content, content_type, is_public, annotations, created_at, updated_at, anchor, location_key
 FROM notes
 WHERE source_id = $1
 ORDER BY created_at DESC
@@ -131,9 +256,10 @@ func (q *Queries) ListNotesBySource(ctx context.Context, arg ListNotesBySourcePa
 			&i.ContentType,
 			&i.IsPublic,
 			&i.Annotations,
-			&i.Tags,
 			&i.CreatedAt,
 			&i.UpdatedAt,
+			&i.Anchor,
+			&i.LocationKey,
 		); err != nil {
 			return nil, err
 		}
@@ -146,7 +272,7 @@ func (q *Queries) ListNotesBySource(ctx context.Context, arg ListNotesBySourcePa
 }
 
 const listNotesByUser = `-- name: ListNotesByUser :many
-SELECT id, user_id, source_id, content, content_type, is_public, annotations, tags, created_at, updated_at
+SELECT id, user_id, source_id, content, content_type, is_public, annotations, created_at, updated_at, anchor, location_key
 FROM notes
 WHERE user_id = $1
 ORDER BY created_at DESC
@@ -176,9 +302,10 @@ func (q *Queries) ListNotesByUser(ctx context.Context, arg ListNotesByUserParams
 			&i.ContentType,
 			&i.IsPublic,
 			&i.Annotations,
-			&i.Tags,
 			&i.CreatedAt,
 			&i.UpdatedAt,
+			&i.Anchor,
+			&i.LocationKey,
 		); err != nil {
 			return nil, err
 		}
@@ -190,21 +317,43 @@ func (q *Queries) ListNotesByUser(ctx context.Context, arg ListNotesByUserParams
 	return items, nil
 }
 
-const listPublicNotes = `-- name: ListPublicNotes :many
-SELECT id, user_id, source_id, content, content_type, is_public, annotations, tags, created_at, updated_at
+const listNotesNewest = `-- name: ListNotesNewest :many
+SELECT *
 FROM notes
-WHERE is_public = true
-ORDER BY created_at DESC
-LIMIT $1 OFFSET $2
+WHERE ($1::uuid IS NULL OR user_id = $1::uuid)
+  AND ($2::uuid IS NULL OR source_id = $2::uuid)
+  AND (NOT $3::bool OR is_public = true)
+  AND ($4::text IS NULL OR content_type = $4::text)
+  AND ($5::text IS NULL OR EXISTS (
+    SELECT 1 FROM note_tags nt JOIN tags t ON t.id = nt.tag_id
+    WHERE nt.note_id = notes.id AND t.slug = $5::text))
+  AND (created_at, id) < ($6::timestamptz, $7::uuid)
+ORDER BY created_at DESC, id DESC
+LIMIT $8
 `
 
-type ListPublicNotesParams struct {
-	Limit  int32 `db:"limit" json:"limit"`
-	Offset int32 `db:"offset" json:"offset"`
+type ListNotesNewestParams struct {
+	UserID         pgtype.UUID        `db:"user_id" json:"user_id"`
+	SourceID       pgtype.UUID        `db:"source_id" json:"source_id"`
+	PublicOnly     bool               `db:"public_only" json:"public_only"`
+	ContentType    pgtype.Text        `db:"content_type" json:"content_type"`
+	Tag            pgtype.Text        `db:"tag" json:"tag"`
+	AfterCreatedAt pgtype.Timestamptz `db:"after_created_at" json:"after_created_at"`
+	AfterID        pgtype.UUID        `db:"after_id" json:"after_id"`
+	Limit          int32              `db:"limit" json:"limit"`
 }
 
-func (q *Queries) ListPublicNotes(ctx context.Context, arg ListPublicNotesParams) ([]Note, error) {
-	rows, err := q.db.Query(ctx, listPublicNotes, arg.Limit, arg.Offset)
+func (q *Queries) ListNotesNewest(ctx context.Context, arg ListNotesNewestParams) ([]Note, error) {
+	rows, err := q.db.Query(ctx, listNotesNewest,
+		arg.UserID,
+		arg.SourceID,
+		arg.PublicOnly,
+		arg.ContentType,
+		arg.Tag,
+		arg.AfterCreatedAt,
+		arg.AfterID,
+		arg.Limit,
+	)
 	if err != nil {
 		return nil, err
 	}
@@ -220,9 +369,10 @@ func (q *Queries) ListPublicNotes(ctx context.Context, arg ListPublicNotesParams
 			&i.ContentType,
 			&i.IsPublic,
 			&i.Annotations,
-			&i.Tags,
 			&i.CreatedAt,
 			&i.UpdatedAt,
+			&i.Anchor,
+			&i.LocationKey,
 		); err != nil {
 			return nil, err
 		}
@@ -234,22 +384,43 @@ func (q *Queries) ListPublicNotes(ctx context.Context, arg ListPublicNotesParams
 	return items, nil
 }
 
-const listPublicNotesBySource = `-- name: ListPublicNotesBySource :many
-SELECT id, user_id, source_id, content, content_type, is_public, annotations, tags, created_at, updated_at
+const listNotesOldest = `-- name: ListNotesOldest :many
+SELECT *
 FROM notes
-WHERE source_id = $1 AND is_public = true
-ORDER BY created_at DESC
-LIMIT $2 OFFSET $3
+WHERE ($1::uuid IS NULL OR user_id = $1::uuid)
+  AND ($2::uuid IS NULL OR source_id = $2::uuid)
+  AND (NOT $3::bool OR is_public = true)
+  AND ($4::text IS NULL OR content_type = $4::text)
+  AND ($5::text IS NULL OR EXISTS (
+    SELECT 1 FROM note_tags nt JOIN tags t ON t.id = nt.tag_id
+    WHERE nt.note_id = notes.id AND t.slug = $5::text))
+  AND (created_at, id) > ($6::timestamptz, $7::uuid)
+ORDER BY created_at ASC, id ASC
+LIMIT $8
 `
 
-type ListPublicNotesBySourceParams struct {
-	SourceID pgtype.UUID `db:"source_id" json:"source_id"`
-	Limit    int32       `db:"limit" json:"limit"`
-	Offset   int32       `db:"offset" json:"offset"`
+type ListNotesOldestParams struct {
+	UserID         pgtype.UUID        `db:"user_id" json:"user_id"`
+	SourceID       pgtype.UUID        `db:"source_id" json:"source_id"`
+	PublicOnly     bool               `db:"public_only" json:"public_only"`
+	ContentType    pgtype.Text        `db:"content_type" json:"content_type"`
+	Tag            pgtype.Text        `db:"tag" json:"tag"`
+	AfterCreatedAt pgtype.Timestamptz `db:"after_created_at" json:"after_created_at"`
+	AfterID        pgtype.UUID        `db:"after_id" json:"after_id"`
+	Limit          int32              `db:"limit" json:"limit"`
 }
 
-func (q *Queries) ListPublicNotesBySource(ctx context.Context, arg ListPublicNotesBySourceParams) ([]Note, error) {
-	rows, err := q.db.Query(ctx, listPublicNotesBySource, arg.SourceID, arg.Limit, arg.Offset)
+func (q *Queries) ListNotesOldest(ctx context.Context, arg ListNotesOldestParams) ([]Note, error) {
+	rows, err := q.db.Query(ctx, listNotesOldest,
+		arg.UserID,
+		arg.SourceID,
+		arg.PublicOnly,
+		arg.ContentType,
+		arg.Tag,
+		arg.AfterCreatedAt,
+		arg.AfterID,
+		arg.Limit,
+	)
 	if err != nil {
 		return nil, err
 	}
@@ -265,9 +436,10 @@ func (q *Queries) ListPublicNotesBySource(ctx context.Context, arg ListPublicNot
 			&i.ContentType,
 			&i.IsPublic,
 			&i.Annotations,
-			&i.Tags,
 			&i.CreatedAt,
 			&i.UpdatedAt,
+			&i.Anchor,
+			&i.LocationKey,
 		); err != nil {
 			return nil, err
 		}
@@ -279,40 +451,31 @@ func (q *Queries) ListPublicNotesBySource(ctx context.Context, arg ListPublicNot
 	return items, nil
 }
 
-const listPublicNotesByUser = `-- name: ListPublicNotesByUser :many
-SELECT id, user_id, source_id, content, content_type, is_public, annotations, tags, created_at, updated_at
-FROM notes
-WHERE user_id = $1 AND is_public = true
-ORDER BY created_at DESC
-LIMIT $2 OFFSET $3
+const listTagsByNotes = `-- name: ListTagsByNotes :many
+SELECT nt.note_id, t.name
+FROM note_tags nt
+JOIN tags t ON t.id = nt.tag_id
+WHERE nt.note_id = ANY($1::uuid[])
+ORDER BY nt.note_id, nt.position, t.slug
 `
 
-type ListPublicNotesByUserParams struct {
-	UserID pgtype.UUID `db:"user_id" json:"user_id"`
-	Limit  int32       `db:"limit" json:"limit"`
-	Offset int32       `db:"offset" json:"offset"`
+type ListTagsByNotesRow struct {
+	NoteID pgtype.UUID `db:"note_id" json:"note_id"`
+	Name   string      `db:"name" json:"name"`
 }
 
-func (q *Queries) ListPublicNotesByUser(ctx context.Context, arg ListPublicNotesByUserParams) ([]Note, error) {
-	rows, err := q.db.Query(ctx, listPublicNotesByUser, arg.UserID, arg.Limit, arg.Offset)
+func (q *Queries) ListTagsByNotes(ctx context.Context, noteIds []pgtype.UUID) ([]ListTagsByNotesRow, error) {
+	rows, err := q.db.Query(ctx, listTagsByNotes, noteIds)
 	if err != nil {
 		return nil, err
 	}
 	defer rows.Close()
-	items := []Note{}
+	items := []ListTagsByNotesRow{}
 	for rows.Next() {
-		var i Note
+		var i ListTagsByNotesRow
 		if err := rows.Scan(
-			&i.ID,
-			&i.UserID,
-			&i.SourceID,
-			&i.Content,
-			&i.ContentType,
-			&i.IsPublic,
-			&i.Annotations,
-			&i.Tags,
-			&i.CreatedAt,
-			&i.UpdatedAt,
+			&i.NoteID,
+			&i.Name,
 		); err != nil {
 			return nil, err
 		}
@@ -326,9 +489,9 @@ func (q *Queries) ListPublicNotesByUser(ctx context.Context, arg ListPublicNotes
 
 const updateNote = `-- name: UpdateNote :one
 UPDATE notes
-SET source_id = $2, content = $3, content_type = $4, is_public = $5, annotations = $6, tags = $7, updated_at = NOW()
+SET source_id = $2, content = $3, content_type = $4, is_public = $5, annotations = $6, anchor = $7, location_key = $8, updated_at = NOW()
 WHERE id = $1
-RETURNING id, user_id, source_id, content, content_type, is_public, annotations, tags, created_at, updated_at
+RETURNING id, user_id, source_id, content, content_type, is_public, annotations, created_at, updated_at, anchor, location_key
 `
 
 type UpdateNoteParams struct {
@@ -338,7 +501,8 @@ type UpdateNoteParams struct {
 	ContentType string      `db:"content_type" json:"content_type"`
 	IsPublic    pgtype.Bool `db:"is_public" json:"is_public"`
 	Annotations []byte      `db:"annotations" json:"annotations"`
-	Tags        []byte      `db:"tags" json:"tags"`
+	Anchor      []byte      `db:"anchor" json:"anchor"`
+	LocationKey pgtype.Text `db:"location_key" json:"location_key"`
 }
 
 func (q *Queries) UpdateNote(ctx context.Context, arg UpdateNoteParams) (Note, error) {
@@ -349,7 +513,8 @@ func (q *Queries) UpdateNote(ctx context.Context, arg UpdateNoteParams) (Note, e
 		arg.ContentType,
 		arg.IsPublic,
 		arg.Annotations,
-		arg.Tags,
+		arg.Anchor,
+		arg.LocationKey,
 	)
 	var i Note
 	err := row.Scan(
@@ -360,9 +525,10 @@ func (q *Queries) UpdateNote(ctx context.Context, arg UpdateNoteParams) (Note, e
 		&i.ContentType,
 		&i.IsPublic,
 		&i.Annotations,
-		&i.Tags,
 		&i.CreatedAt,
 		&i.UpdatedAt,
+		&i.Anchor,
+		&i.LocationKey,
 	)
 	return i, err
 }
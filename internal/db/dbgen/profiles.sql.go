@@ -12,7 +12,7 @@ import (
 )
 
 const getProfileByUserID = `-- name: GetProfileByUserID :one
-SELECT id, user_id, display_name, bio, public_profile, created_at, updated_at
+SELECT id, user_id, display_name, bio, public_profile, created_at, updated_at, public_year_in_review
 FROM profiles
 WHERE user_id = $1
 LIMIT 1
@@ -29,12 +29,13 @@ func (q *Queries) GetProfileByUserID(ctx context.Context, userID pgtype.UUID) (P
 		&i.PublicProfile,
 		&i.CreatedAt,
 		&i.UpdatedAt,
+		&i.PublicYearInReview,
 	)
 	return i, err
 }
 
 const getPublicProfileByUsername = `-- name: GetPublicProfileByUsername :one
-SELECT p.id, p.user_id, u.username, p.display_name, p.bio, p.public_profile, p.created_at, p.updated_at
+SELECT p.id, p.user_id, u.username, p.display_name, p.bio, p.public_profile, p.public_year_in_review, p.created_at, p.updated_at
 FROM profiles p
 JOIN users u ON u.id = p.user_id
 WHERE u.username = $1 AND p.public_profile = true
@@ -42,14 +43,15 @@ LIMIT 1
 `
 
 type GetPublicProfileByUsernameRow struct {
-	ID            pgtype.UUID        `db:"id" json:"id"`
-	UserID        pgtype.UUID        `db:"user_id" json:"user_id"`
-	Username      string             `db:"username" json:"username"`
-	DisplayName   pgtype.Text        `db:"display_name" json:"display_name"`
-	Bio           pgtype.Text        `db:"bio" json:"bio"`
-	PublicProfile pgtype.Bool        `db:"public_profile" json:"public_profile"`
-	CreatedAt     pgtype.Timestamptz `db:"created_at" json:"created_at"`
-	UpdatedAt     pgtype.Timestamptz `db:"updated_at" json:"updated_at"`
+	ID                 pgtype.UUID        `db:"id" json:"id"`
+	UserID             pgtype.UUID        `db:"user_id" json:"user_id"`
+	Username           string             `db:"username" json:"username"`
+	DisplayName        pgtype.Text        `db:"display_name" json:"display_name"`
+	Bio                pgtype.Text        `db:"bio" json:"bio"`
+	PublicProfile      pgtype.Bool        `db:"public_profile" json:"public_profile"`
+	PublicYearInReview bool               `db:"public_year_in_review" json:"public_year_in_review"`
+	CreatedAt          pgtype.Timestamptz `db:"created_at" json:"created_at"`
+	UpdatedAt          pgtype.Timestamptz `db:"updated_at" json:"updated_at"`
 }
 
 func (q *Queries) GetPublicProfileByUsername(ctx context.Context, username string) (GetPublicProfileByUsernameRow, error) {
@@ -62,6 +64,7 @@ func (q *Queries) GetPublicProfileByUsername(ctx context.Context, username strin
 		&i.DisplayName,
 		&i.Bio,
 		&i.PublicProfile,
+		&i.PublicYearInReview,
 		&i.CreatedAt,
 		&i.UpdatedAt,
 	)
@@ -70,37 +73,40 @@ func (q *Queries) GetPublicProfileByUsername(ctx context.Context, username strin
 
 const upsertProfile = `-- name: UpsertProfile :one
 WITH upserted AS (
-    INSERT INTO profiles (id, user_id, display_name, bio, public_profile)
-    VALUES ($1, $2, $3, $4, $5)
+    INSERT INTO profiles (id, user_id, display_name, bio, public_profile, public_year_in_review)
+    VALUES ($1, $2, $3, $4, $5, $6)
     ON CONFLICT (user_id) DO UPDATE SET
         display_name = EXCLUDED.display_name,
         bio = EXCLUDED.bio,
         public_profile = EXCLUDED.public_profile,
+        public_year_in_review = EXCLUDED.public_year_in_review,
         updated_at = NOW()
-    RETURNING id, user_id, display_name, bio, public_profile, created_at, updated_at
+    RETURNING id, user_id, display_name, bio, public_profile, created_at, updated_at, public_year_in_review
 )
-SELECT p.id, p.user_id, u.username, p.display_name, p.bio, p.public_profile, p.created_at, p.updated_at
+SELECT p.id, p.user_id, u.username, p.display_name, p.bio, p.public_profile, p.public_year_in_review, p.created_at, p.updated_at
 FROM upserted p
 JOIN users u ON u.id = p.user_id
 `
 
 type UpsertProfileParams struct {
-	ID            pgtype.UUID `db:"id" json:"id"`
-	UserID        pgtype.UUID `db:"user_id" json:"user_id"`
-	DisplayName   pgtype.Text `db:"display_name" json:"display_name"`
-	Bio           pgtype.Text `db:"bio" json:"bio"`
-	PublicProfile pgtype.Bool `db:"public_profile" json:"public_profile"`
+	ID                 pgtype.UUID `db:"id" json:"id"`
+	UserID             pgtype.UUID `db:"user_id" json:"user_id"`
+	DisplayName        pgtype.Text `db:"display_name" json:"display_name"`
+	Bio                pgtype.Text `db:"bio" json:"bio"`
+	PublicProfile      pgtype.Bool `db:"public_profile" json:"public_profile"`
+	PublicYearInReview bool        `db:"public_year_in_review" json:"public_year_in_review"`
 }
 
 type UpsertProfileRow struct {
-	ID            pgtype.UUID        `db:"id" json:"id"`
-	UserID        pgtype.UUID        `db:"user_id" json:"user_id"`
-	Username      string             `db:"username" json:"username"`
-	DisplayName   pgtype.Text        `db:"display_name" json:"display_name"`
-	Bio           pgtype.Text        `db:"bio" json:"bio"`
-	PublicProfile pgtype.Bool        `db:"public_profile" json:"public_profile"`
-	CreatedAt     pgtype.Timestamptz `db:"created_at" json:"created_at"`
-	UpdatedAt     pgtype.Timestamptz `db:"updated_at" json:"updated_at"`
+	ID                 pgtype.UUID        `db:"id" json:"id"`
+	UserID             pgtype.UUID        `db:"user_id" json:"user_id"`
+	Username           string             `db:"username" json:"username"`
+	DisplayName        pgtype.Text        `db:"display_name" json:"display_name"`
+	Bio                pgtype.Text        `db:"bio" json:"bio"`
+	PublicProfile      pgtype.Bool        `db:"public_profile" json:"public_profile"`
+	PublicYearInReview bool               `db:"public_year_in_review" json:"public_year_in_review"`
+	CreatedAt          pgtype.Timestamptz `db:"created_at" json:"created_at"`
+	UpdatedAt          pgtype.Timestamptz `db:"updated_at" json:"updated_at"`
 }
 
 func (q *Queries) UpsertProfile(ctx context.Context, arg UpsertProfileParams) (UpsertProfileRow, error) {
@@ -110,6 +116,7 @@ func (q *Queries) UpsertProfile(ctx context.Context, arg UpsertProfileParams) (U
 		arg.DisplayName,
 		arg.Bio,
 		arg.PublicProfile,
+		arg.PublicYearInReview,
 	)
 	var i UpsertProfileRow
 	err := row.Scan(
@@ -119,6 +126,7 @@ func (q *Queries) UpsertProfile(ctx context.Context, arg UpsertProfileParams) (U
 		&i.DisplayName,
 		&i.Bio,
 		&i.PublicProfile,
+		&i.PublicYearInReview,
 		&i.CreatedAt,
 		&i.UpdatedAt,
 	)
@@ -11,6 +11,37 @@ import (
 	"github.com/jackc/pgx/v5/pgtype"
 )
 
+const countReviews = `-- name: CountReviews :one
+SELECT COUNT(*)
+FROM reviews
+WHERE ($1::uuid IS NULL OR user_id = $1::uuid)
+  AND ($2::uuid IS NULL OR source_id = $2::uuid)
+  AND (NOT $3::bool OR is_public = true)
+  AND ($4::int IS NULL OR rating >= $4::int)
+  AND ($5::int IS NULL OR rating <= $5::int)
+`
+
+type CountReviewsParams struct {
+	UserID     pgtype.UUID `db:"user_id" json:"user_id"`
+	SourceID   pgtype.UUID `db:"source_id" json:"source_id"`
+	PublicOnly bool        `db:"public_only" json:"public_only"`
+	MinRating  pgtype.Int4 `db:"min_rating" json:"min_rating"`
+	MaxRating  pgtype.Int4 `db:"max_rating" json:"max_rating"`
+}
+
+func (q *Queries) CountReviews(ctx context.Context, arg CountReviewsParams) (int64, error) {
+	row := q.db.QueryRow(ctx, countReviews,
+		arg.UserID,
+		arg.SourceID,
+		arg.PublicOnly,
+		arg.MinRating,
+		arg.MaxRating,
+	)
+	var count int64
+	err := row.Scan(&count)
+	return count, err
+}
+
 const createReview = `-- name: CreateReview :one
 INSERT INTO reviews (id, user_id, source_id, rating, content, is_public)
 VALUES ($1, $2, $3, $4, $5, $6)
@@ -49,13 +80,16 @@ func (q *Queries) CreateReview(ctx context.Context, arg CreateReviewParams) (Rev
 	return i, err
 }
 
-const deleteReview = `-- name: DeleteReview :exec
+const deleteReview = `-- name: DeleteReview :execrows
 DELETE FROM reviews WHERE id = $1
 `
 
-func (q *Queries) DeleteReview(ctx context.Context, id pgtype.UUID) error {
-	_, err := q.db.Exec(ctx, deleteReview, id)
-	return err
+func (q *Queries) DeleteReview(ctx context.Context, id pgtype.UUID) (int64, error) {
+	result, err := q.db.Exec(ctx, deleteReview, id)
+	if err != nil {
+		return 0, err
+	}
+	return result.RowsAffected(), nil
 }
 
 const getReviewByID = `-- name: GetReviewByID :one
@@ -81,22 +115,22 @@ func (q *Queries) GetReviewByID(ctx context.Context, id pgtype.UUID) (Review, er
 	return i, err
 }
 
-const listPublicReviewsBySource = `-- name: ListPublicReviewsBySource :many
+const listReviewsBySource = `-- name: ListReviewsBySource :many
 SELECT id, user_id, source_id, rating, content, is_public, created_at, updated_at
 FROM reviews
-WHERE source_id = $1 AND is_public = true
+WHERE source_id = $1
 ORDER BY created_at DESC
 LIMIT $2 OFFSET $3
 `
 
-type ListPublicReviewsBySourceParams struct {
+type ListReviewsBySourceParams struct {
 	SourceID pgtype.UUID `db:"source_id" json:"source_id"`
 	Limit    int32       `db:"limit" json:"limit"`
 	Offset   int32       `db:"offset" json:"offset"`
 }
 
-func (q *Queries) ListPublicReviewsBySource(ctx context.Context, arg ListPublicReviewsBySourceParams) ([]Review, error) {
-	rows, err := q.db.Query(ctx, listPublicReviewsBySource, arg.SourceID, arg.Limit, arg.Offset)
+func (q *Queries) ListReviewsBySource(ctx context.Context, arg ListReviewsBySourceParams) ([]Review, error) {
+	rows, err := q.db.Query(ctx, listReviewsBySource, arg.SourceID, arg.Limit, arg.Offset)
 	if err != nil {
 		return nil, err
 	}
@@ -124,22 +158,22 @@ func (q *Queries) ListPublicReviewsBySource(ctx context.Context, arg ListPublicR
 	return items, nil
 }
 
-const listPublicReviewsByUser = `-- name: ListPublicReviewsByUser :many
+const listReviewsByUser = `-- name: ListReviewsByUser :many
 SELECT id, user_id, source_id, rating, content, is_public, created_at, updated_at
 FROM reviews
-WHERE user_id = $1 AND is_public = true
+WHERE user_id = $1
 ORDER BY created_at DESC
 LIMIT $2 OFFSET $3
 `
 
-type ListPublicReviewsByUserParams struct {
+type ListReviewsByUserParams struct {
 	UserID pgtype.UUID `db:"user_id" json:"user_id"`
 	Limit  int32       `db:"limit" json:"limit"`
 	Offset int32       `db:"offset" json:"offset"`
 }
 
-func (q *Queries) ListPublicReviewsByUser(ctx context.Context, arg ListPublicReviewsByUserParams) ([]Review, error) {
-	rows, err := q.db.Query(ctx, listPublicReviewsByUser, arg.UserID, arg.Limit, arg.Offset)
+func (q *Queries) ListReviewsByUser(ctx context.Context, arg ListReviewsByUserParams) ([]Review, error) {
+	rows, err := q.db.Query(ctx, listReviewsByUser, arg.UserID, arg.Limit, arg.Offset)
 	if err != nil {
 		return nil, err
 	}
@@ -167,22 +201,41 @@ func (q *Queries) ListPublicReviewsByUser(ctx context.Context, arg ListPublicRev
 	return items, nil
 }
 
-const listReviewsBySource = `-- name: ListReviewsBySource :many
+const listReviewsNewest = `-- name: ListReviewsNewest :many
 SELECT id, user_id, source_id, rating, content, is_public, created_at, updated_at
 FROM reviews
-WHERE source_id = $1
-ORDER BY created_at DESC
-LIMIT $2 OFFSET $3
+WHERE ($1::uuid IS NULL OR user_id = $1::uuid)
+  AND ($2::uuid IS NULL OR source_id = $2::uuid)
+  AND (NOT $3::bool OR is_public = true)
+  AND ($4::int IS NULL OR rating >= $4::int)
+  AND ($5::int IS NULL OR rating <= $5::int)
+  AND (created_at, id) < ($6::timestamptz, $7::uuid)
+ORDER BY created_at DESC, id DESC
+LIMIT $8
 `
 
-type ListReviewsBySourceParams struct {
-	SourceID pgtype.UUID `db:"source_id" json:"source_id"`
-	Limit    int32       `db:"limit" json:"limit"`
-	Offset   int32       `db:"offset" json:"offset"`
+type ListReviewsNewestParams struct {
+	UserID         pgtype.UUID        `db:"user_id" json:"user_id"`
+	SourceID       pgtype.UUID        `db:"source_id" json:"source_id"`
+	PublicOnly     bool               `db:"public_only" json:"public_only"`
+	MinRating      pgtype.Int4        `db:"min_rating" json:"min_rating"`
+	MaxRating      pgtype.Int4        `db:"max_rating" json:"max_rating"`
+	AfterCreatedAt pgtype.Timestamptz `db:"after_created_at" json:"after_created_at"`
+	AfterID        pgtype.UUID        `db:"after_id" json:"after_id"`
+	Limit          int32              `db:"limit" json:"limit"`
 }
 
-func (q *Queries) ListReviewsBySource(ctx context.Context, arg ListReviewsBySourceParams) ([]Review, error) {
-	rows, err := q.db.Query(ctx, listReviewsBySource, arg.SourceID, arg.Limit, arg.Offset)
+func (q *Queries) ListReviewsNewest(ctx context.Context, arg ListReviewsNewestParams) ([]Review, error) {
+	rows, err := q.db.Query(ctx, listReviewsNewest,
+		arg.UserID,
+		arg.SourceID,
+		arg.PublicOnly,
+		arg.MinRating,
+		arg.MaxRating,
+		arg.AfterCreatedAt,
+		arg.AfterID,
+		arg.Limit,
+	)
 	if err != nil {
 		return nil, err
 	}
@@ -210,22 +263,41 @@ func (q *Queries) ListReviewsBySource(ctx context.Context, arg ListReviewsBySour
 	return items, nil
 }
 
-const listReviewsByUser = `-- name: ListReviewsByUser :many
+const listReviewsOldest = `-- name: ListReviewsOldest :many
 SELECT id, user_id, source_id, rating, content, is_public, created_at, updated_at
 FROM reviews
-WHERE user_id = $1
-ORDER BY created_at DESC
-LIMIT $2 OFFSET $3
+WHERE ($1::uuid IS NULL OR user_id = $1::uuid)
+  AND ($2::uuid IS NULL OR source_id = $2::uuid)
+  AND (NOT $3::bool OR is_public = true)
+  AND ($4::int IS NULL OR rating >= $4::int)
+  AND ($5::int IS NULL OR rating <= $5::int)
+  AND (created_at, id) > ($6::timestamptz, $7::uuid)
+ORDER BY created_at ASC, id ASC
+LIMIT $8
 `
 
-type ListReviewsByUserParams struct {
-	UserID pgtype.UUID `db:"user_id" json:"user_id"`
-	Limit  int32       `db:"limit" json:"limit"`
-	Offset int32       `db:"offset" json:"offset"`
+type ListReviewsOldestParams struct {
+	UserID         pgtype.UUID        `db:"user_id" json:"user_id"`
+	SourceID       pgtype.UUID        `db:"source_id" json:"source_id"`
+	PublicOnly     bool               `db:"public_only" json:"public_only"`
+	MinRating      pgtype.Int4        `db:"min_rating" json:"min_rating"`
+	MaxRating      pgtype.Int4        `db:"max_rating" json:"max_rating"`
+	AfterCreatedAt pgtype.Timestamptz `db:"after_created_at" json:"after_created_at"`
+	AfterID        pgtype.UUID        `db:"after_id" json:"after_id"`
+	Limit          int32              `db:"limit" json:"limit"`
 }
 
-func (q *Queries) ListReviewsByUser(ctx context.Context, arg ListReviewsByUserParams) ([]Review, error) {
-	rows, err := q.db.Query(ctx, listReviewsByUser, arg.UserID, arg.Limit, arg.Offset)
+func (q *Queries) ListReviewsOldest(ctx context.Context, arg ListReviewsOldestParams) ([]Review, error) {
+	rows, err := q.db.Query(ctx, listReviewsOldest,
+		arg.UserID,
+		arg.SourceID,
+		arg.PublicOnly,
+		arg.MinRating,
+		arg.MaxRating,
+		arg.AfterCreatedAt,
+		arg.AfterID,
+		arg.Limit,
+	)
 	if err != nil {
 		return nil, err
 	}
@@ -11,21 +11,118 @@ import (
 	"github.com/jackc/pgx/v5/pgtype"
 )
 
+const copyBookMetadata = `-- name: CopyBookMetadata :exec
+INSERT INTO book_metadata (source_id, isbn_10, isbn_13, publisher, page_count, language, cover_url)
+SELECT $1::uuid, isbn_10, isbn_13, publisher, page_count, language, cover_url
+FROM book_metadata
+WHERE source_id = $2
+ON CONFLICT (source_id) DO UPDATE
+SET isbn_10 = COALESCE(book_metadata.isbn_10, EXCLUDED.isbn_10),
+    isbn_13 = COALESCE(book_metadata.isbn_13, EXCLUDED.isbn_13),
+    publisher = COALESCE(book_metadata.publisher, EXCLUDED.publisher),
+    page_count = COALESCE(book_metadata.page_count, EXCLUDED.page_count),
+    language = COALESCE(book_metadata.language, EXCLUDED.language),
+    cover_url = COALESCE(book_metadata.cover_url, EXCLUDED.cover_url),
+    updated_at = NOW()
+`
+
+type CopyBookMetadataParams struct {
+	SurvivorID  pgtype.UUID `db:"survivor_id" json:"survivor_id"`
+	DuplicateID pgtype.UUID `db:"duplicate_id" json:"duplicate_id"`
+}
+
+func (q *Queries) CopyBookMetadata(ctx context.Context, arg CopyBookMetadataParams) error {
+	_, err := q.db.Exec(ctx, copyBookMetadata, arg.SurvivorID, arg.DuplicateID)
+	return err
+}
+
+const copySourceContributors = `-- name: CopySourceContributors :exec
+INSERT INTO source_contributors (source_id, contributor_id, role, position)
+SELECT $1::uuid, sc.contributor_id, sc.role,
+       sc.position + (SELECT COALESCE(MAX(position) + 1, 0) FROM source_contributors WHERE source_id = $1::uuid)
+FROM source_contributors sc
+WHERE sc.source_id = $2
+ON CONFLICT DO NOTHING
+`
+
+type CopySourceContributorsParams struct {
+	SurvivorID  pgtype.UUID `db:"survivor_id" json:"survivor_id"`
+	DuplicateID pgtype.UUID `db:"duplicate_id" json:"duplicate_id"`
+}
+
+func (q *Queries) CopySourceContributors(ctx context.Context, arg CopySourceContributorsParams) error {
+	_, err := q.db.Exec(ctx, copySourceContributors, arg.SurvivorID, arg.DuplicateID)
+	return err
+}
+
+const copySourceTags = `-- name: CopySourceTags :exec
+INSERT INTO source_tags (source_id, tag_id, position)
+SELECT $1::uuid, st.tag_id,
+       st.position + (SELECT COALESCE(MAX(position) + 1, 0) FROM source_tags WHERE source_id = $1::uuid)
+FROM source_tags st
+WHERE st.source_id = $2
+ON CONFLICT DO NOTHING
+`
+
+type CopySourceTagsParams struct {
+	SurvivorID  pgtype.UUID `db:"survivor_id" json:"survivor_id"`
+	DuplicateID pgtype.UUID `db:"duplicate_id" json:"duplicate_id"`
+}
+
+func (q *Queries) CopySourceTags(ctx context.Context, arg CopySourceTagsParams) error {
+	_, err := q.db.Exec(ctx, copySourceTags, arg.SurvivorID, arg.DuplicateID)
+	return err
+}
+
+const countSourceReferencesByOthers = `-- name: CountSourceReferencesByOthers :one
+WITH target AS (
+    SELECT $1::uuid AS source_id, $2::uuid AS user_id
+)
+SELECT (
+    (SELECT COUNT(*) FROM user_library_items li, target WHERE li.source_id = target.source_id AND li.user_id <> target.user_id) +
+    (SELECT COUNT(*) FROM notes n, target WHERE n.source_id = target.source_id AND n.user_id <> target.user_id) +
+    (SELECT COUNT(*) FROM reviews r, target WHERE r.source_id = target.source_id AND r.user_id <> target.user_id) +
+    (SELECT COUNT(*) FROM collection_items ci JOIN collections c ON c.id = ci.collection_id, target WHERE ci.source_id = target.source_id AND c.user_id <> target.user_id)
+)::bigint AS reference_count
+`
+
+type CountSourceReferencesByOthersParams struct {
+	SourceID pgtype.UUID `db:"source_id" json:"source_id"`
+	UserID   pgtype.UUID `db:"user_id" json:"user_id"`
+}
+
+func (q *Queries) CountSourceReferencesByOthers(ctx context.Context, arg CountSourceReferencesByOthersParams) (int64, error) {
+	row := q.db.QueryRow(ctx, countSourceReferencesByOthers, arg.SourceID, arg.UserID)
+	var reference_count int64
+	err := row.Scan(&reference_count)
+	return reference_count, err
+}
+
 const countSources = `-- name: CountSources :one
-SELECT COUNT(*) FROM sources
+SELECT COUNT(*)
+FROM sources
+WHERE ($1::text IS NULL OR type = $1::text)
+  AND ($2::text IS NULL OR EXISTS (
+    SELECT 1 FROM source_tags st JOIN tags t ON t.id = st.tag_id
+    WHERE st.source_id = sources.id AND t.slug = $2::text))
 `
 
-func (q *Queries) CountSources(ctx context.Context) (int64, error) {
-	row := q.db.QueryRow(ctx, countSources)
+type CountSourcesParams struct {
+	Type pgtype.Text `db:"type" json:"type"`
+	Tag  pgtype.Text `db:"tag" json:"tag"`
+}
+
+func (q *Queries) CountSources(ctx context.Context, arg CountSourcesParams) (int64, error) {
+	row := q.db.QueryRow(ctx, countSources, arg.Type, arg.Tag)
 	var count int64
 	err := row.Scan(&count)
 	return count, err
 }
 
 const createSource = `-- name: CreateSource :one
-INSERT INTO sources (id, title, subtitle, type, description, publisher, isbn, doi, url, external_id, tags, published_at)
+INSERT INTO sources (id, title, subtitle, type, description, publisher, isbn, doi, url, external_id, published_at, created_by)
 VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9, $10, $11, $12)
-RETURNING id, title, subtitle, type, description, publisher, isbn, doi, url, external_id, tags, published_at, created_at, updated_at
+RETURNING id, title, subtitle, type, description, publisher, isbn, doi, url, external_id, published_at, created_at, updated_at, created_by
 `
 
 type CreateSourceParams struct {
@@ -39,8 +136,8 @@ type CreateSourceParams struct {
 	Doi         pgtype.Text        `db:"doi" json:"doi"`
 	Url         pgtype.Text        `db:"url" json:"url"`
 	ExternalID  pgtype.Text        `db:"external_id" json:"external_id"`
-	Tags        []byte             `db:"tags" json:"tags"`
 	PublishedAt pgtype.Timestamptz `db:"published_at" json:"published_at"`
+	CreatedBy   pgtype.UUID        `db:"created_by" json:"created_by"`
 }
 
 func (q *Queries) CreateSource(ctx context.Context, arg CreateSourceParams) (Source, error) {
@@ -55,8 +152,8 @@ func (q *Queries) CreateSource(ctx context.Context, arg CreateSourceParams) (Sou
 		arg.Doi,
 		arg.Url,
 		arg.ExternalID,
-		arg.Tags,
 		arg.PublishedAt,
+		arg.CreatedBy,
 	)
 	var i Source
 	err := row.Scan(
@@ -70,23 +167,245 @@ func (q *Queries) CreateSource(ctx context.Context, arg CreateSourceParams) (Sou
 		&i.Doi,
 		&i.Url,
 		&i.ExternalID,
-		&i.Tags,
 		&i.PublishedAt,
 		&i.CreatedAt,
 		&i.UpdatedAt,
+		&i.CreatedBy,
+	)
+	return i, err
+}
+
+const createSourceProposal = `-- name: CreateSourceProposal :one
+INSERT INTO source_proposals (id, source_id, proposed_by, changes)
+VALUES ($1, $2, $3, $4)
+RETURNING id, source_id, proposed_by, changes, status, reviewed_by, review_note, reviewed_at, created_at, updated_at
+`
+
+type CreateSourceProposalParams struct {
+	ID         pgtype.UUID `db:"id" json:"id"`
+	SourceID   pgtype.UUID `db:"source_id" json:"source_id"`
+	ProposedBy pgtype.UUID `db:"proposed_by" json:"proposed_by"`
+	Changes    []byte      `db:"changes" json:"changes"`
+}
+
+func (q *Queries) CreateSourceProposal(ctx context.Context, arg CreateSourceProposalParams) (SourceProposal, error) {
+	row := q.db.QueryRow(ctx, createSourceProposal,
+		arg.ID,
+		arg.SourceID,
+		arg.ProposedBy,
+		arg.Changes,
+	)
+	var i SourceProposal
+	err := row.Scan(
+		&i.ID,
+		&i.SourceID,
+		&i.ProposedBy,
+		&i.Changes,
+		&i.Status,
+		&i.ReviewedBy,
+		&i.ReviewNote,
+		&i.ReviewedAt,
+		&i.CreatedAt,
+		&i.UpdatedAt,
+	)
+	return i, err
+}
+
+const createSourceRevision = `-- name: CreateSourceRevision :one
+INSERT INTO source_revisions (id, source_id, revision, action, edited_by, proposal_id, reverted_to, snapshot, changes, merged_from)
+VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9, $10)
+RETURNING id, source_id, revision, action, edited_by, proposal_id, reverted_to, snapshot, changes, created_at, merged_from
+`
+
+type CreateSourceRevisionParams struct {
+	ID         pgtype.UUID `db:"id" json:"id"`
+	SourceID   pgtype.UUID `db:"source_id" json:"source_id"`
+	Revision   int32       `db:"revision" json:"revision"`
+	Action     string      `db:"action" json:"action"`
+	EditedBy   pgtype.UUID `db:"edited_by" json:"edited_by"`
+	ProposalID pgtype.UUID `db:"proposal_id" json:"proposal_id"`
+	RevertedTo pgtype.Int4 `db:"reverted_to" json:"reverted_to"`
+	Snapshot   []byte      `db:"snapshot" json:"snapshot"`
+	Changes    []byte      `db:"changes" json:"changes"`
+	MergedFrom pgtype.UUID `db:"merged_from" json:"merged_from"`
+}
+
+func (q *Queries) CreateSourceRevision(ctx context.Context, arg CreateSourceRevisionParams) (SourceRevision, error) {
+	row := q.db.QueryRow(ctx, createSourceRevision,
+		arg.ID,
+		arg.SourceID,
+		arg.Revision,
+		arg.Action,
+		arg.EditedBy,
+		arg.ProposalID,
+		arg.RevertedTo,
+		arg.Snapshot,
+		arg.Changes,
+		arg.MergedFrom,
+	)
+	var i SourceRevision
+	err := row.Scan(
+		&i.ID,
+		&i.SourceID,
+		&i.Revision,
+		&i.Action,
+		&i.EditedBy,
+		&i.ProposalID,
+		&i.RevertedTo,
+		&i.Snapshot,
+		&i.Changes,
+		&i.CreatedAt,
+		&i.MergedFrom,
 	)
 	return i, err
 }
 
-const deleteSource = `-- name: DeleteSource :exec
+const deleteBookMetadata = `-- name: DeleteBookMetadata :exec
+DELETE FROM book_metadata WHERE source_id = $1
+`
+
+func (q *Queries) DeleteBookMetadata(ctx context.Context, sourceID pgtype.UUID) error {
+	_, err := q.db.Exec(ctx, deleteBookMetadata, sourceID)
+	return err
+}
+
+const deleteSource = `-- name: DeleteSource :execrows
 DELETE FROM sources WHERE id = $1
 `
 
-func (q *Queries) DeleteSource(ctx context.Context, id pgtype.UUID) error {
-	_, err := q.db.Exec(ctx, deleteSource, id)
+func (q *Queries) DeleteSource(ctx context.Context, id pgtype.UUID) (int64, error) {
+	result, err := q.db.Exec(ctx, deleteSource, id)
+	if err != nil {
+		return 0, err
+	}
+	return result.RowsAffected(), nil
+}
+
+const deleteSourceContributors = `-- name: DeleteSourceContributors :exec
+DELETE FROM source_contributors WHERE source_id = $1
+`
+
+func (q *Queries) DeleteSourceContributors(ctx context.Context, sourceID pgtype.UUID) error {
+	_, err := q.db.Exec(ctx, deleteSourceContributors, sourceID)
+	return err
+}
+
+const deleteSourceTags = `-- name: DeleteSourceTags :exec
+DELETE FROM source_tags WHERE source_id = $1
+`
+
+func (q *Queries) DeleteSourceTags(ctx context.Context, sourceID pgtype.UUID) error {
+	_, err := q.db.Exec(ctx, deleteSourceTags, sourceID)
 	return err
 }
 
+const deleteSupersededCollectionItems = `-- name: DeleteSupersededCollectionItems :one
+WITH superseded AS (
+    DELETE FROM collection_items ci
+    USING collection_items other
+    WHERE ci.collection_id = other.collection_id
+      AND ((ci.source_id = $1 AND other.source_id = $2 AND ci.position >= other.position)
+        OR (ci.source_id = $2 AND other.source_id = $1 AND ci.position > other.position))
+    RETURNING ci.collection_id, ci.position
+), shifted AS (
+    UPDATE collection_items ci
+    SET position = ci.position - 1
+    FROM superseded
+    WHERE ci.collection_id = superseded.collection_id AND ci.position > superseded.position
+)
+SELECT COUNT(*) FROM superseded
+`
+
+type DeleteSupersededCollectionItemsParams struct {
+	DuplicateID pgtype.UUID `db:"duplicate_id" json:"duplicate_id"`
+	SurvivorID  pgtype.UUID `db:"survivor_id" json:"survivor_id"`
+}
+
+func (q *Queries) DeleteSupersededCollectionItems(ctx context.Context, arg DeleteSupersededCollectionItemsParams) (int64, error) {
+	row := q.db.QueryRow(ctx, deleteSupersededCollectionItems, arg.DuplicateID, arg.SurvivorID)
+	var count int64
+	err := row.Scan(&count)
+	return count, err
+}
+
+const deleteSupersededLibraryItems = `-- name: DeleteSupersededLibraryItems :execrows
+DELETE FROM user_library_items li
+USING user_library_items other
+WHERE li.user_id = other.user_id
+  AND ((li.source_id = $1 AND other.source_id = $2 AND (li.updated_at > other.updated_at) IS NOT TRUE)
+    OR (li.source_id = $2 AND other.source_id = $1 AND li.updated_at < other.updated_at))
+`
+
+type DeleteSupersededLibraryItemsParams struct {
+	DuplicateID pgtype.UUID `db:"duplicate_id" json:"duplicate_id"`
+	SurvivorID  pgtype.UUID `db:"survivor_id" json:"survivor_id"`
+}
+
+func (q *Queries) DeleteSupersededLibraryItems(ctx context.Context, arg DeleteSupersededLibraryItemsParams) (int64, error) {
+	result, err := q.db.Exec(ctx, deleteSupersededLibraryItems, arg.DuplicateID, arg.SurvivorID)
+	if err != nil {
+		return 0, err
+	}
+	return result.RowsAffected(), nil
+}
+
+const deleteSupersededReviews = `-- name: DeleteSupersededReviews :execrows
+DELETE FROM reviews r
+USING reviews other
+WHERE r.user_id = other.user_id
+  AND ((r.source_id = $1 AND other.source_id = $2 AND (r.updated_at > other.updated_at) IS NOT TRUE)
+    OR (r.source_id = $2 AND other.source_id = $1 AND r.updated_at < other.updated_at))
+`
+
+type DeleteSupersededReviewsParams struct {
+	DuplicateID pgtype.UUID `db:"duplicate_id" json:"duplicate_id"`
+	SurvivorID  pgtype.UUID `db:"survivor_id" json:"survivor_id"`
+}
+
+func (q *Queries) DeleteSupersededReviews(ctx context.Context, arg DeleteSupersededReviewsParams) (int64, error) {
+	result, err := q.db.Exec(ctx, deleteSupersededReviews, arg.DuplicateID, arg.SurvivorID)
+	if err != nil {
+		return 0, err
+	}
+	return result.RowsAffected(), nil
+}
+
+const findBookByISBN = `-- name: FindBookByISBN :one
+SELECT s.id, s.title, s.subtitle, s.type, s.description, s.publisher, s.isbn, s.doi, s.url, s.external_id, s.published_at, s.created_at, s.updated_at, s.created_by
+FROM sources s
+JOIN book_metadata bm ON bm.source_id = s.id
+WHERE bm.isbn_13 = $1::text OR bm.isbn_10 = $2::text
+ORDER BY (bm.isbn_13 = $1::text) IS TRUE DESC, s.created_at ASC
+LIMIT 1
+`
+
+type FindBookByISBNParams struct {
+	Isbn13 pgtype.Text `db:"isbn_13" json:"isbn_13"`
+	Isbn10 pgtype.Text `db:"isbn_10" json:"isbn_10"`
+}
+
+func (q *Queries) FindBookByISBN(ctx context.Context, arg FindBookByISBNParams) (Source, error) {
+	row := q.db.QueryRow(ctx, findBookByISBN, arg.Isbn13, arg.Isbn10)
+	var i Source
+	err := row.Scan(
+		&i.ID,
+		&i.Title,
+		&i.Subtitle,
+		&i.Type,
+		&i.Description,
+		&i.Publisher,
+		&i.Isbn,
+		&i.Doi,
+		&i.Url,
+		&i.ExternalID,
+		&i.PublishedAt,
+		&i.CreatedAt,
+		&i.UpdatedAt,
+		&i.CreatedBy,
+	)
+	return i, err
+}
+
 const getBookMetadata = `-- name: GetBookMetadata :one
 SELECT source_id, isbn_10, isbn_13, publisher, page_count, language, cover_url, created_at, updated_at
 FROM book_metadata
@@ -112,7 +431,7 @@ func (q *Queries) GetBookMetadata(ctx context.Context, sourceID pgtype.UUID) (Bo
 }
 
 const getBookSourceByID = `-- name: GetBookSourceByID :one
-SELECT id, title, subtitle, type, description, publisher, isbn, doi, url, external_id, tags, published_at, created_at, updated_at
+SELECT id, title, subtitle, type, description, publisher, isbn, doi, url, external_id, published_at, created_at, updated_at, created_by
 FROM sources
 WHERE id = $1 AND type = 'book'
 LIMIT 1
@@ -132,16 +451,43 @@ func (q *Queries) GetBookSourceByID(ctx context.Context, id pgtype.UUID) (Source
 		&i.Doi,
 		&i.Url,
 		&i.ExternalID,
-		&i.Tags,
 		&i.PublishedAt,
 		&i.CreatedAt,
 		&i.UpdatedAt,
+		&i.CreatedBy,
+	)
+	return i, err
+}
+
+const getLatestSourceRevision = `-- name: GetLatestSourceRevision :one
+SELECT id, source_id, revision, action, edited_by, proposal_id, reverted_to, snapshot, changes, created_at, merged_from
+FROM source_revisions
+WHERE source_id = $1
+ORDER BY revision DESC
+LIMIT 1
+`
+
+func (q *Queries) GetLatestSourceRevision(ctx context.Context, sourceID pgtype.UUID) (SourceRevision, error) {
+	row := q.db.QueryRow(ctx, getLatestSourceRevision, sourceID)
+	var i SourceRevision
+	err := row.Scan(
+		&i.ID,
+		&i.SourceID,
+		&i.Revision,
+		&i.Action,
+		&i.EditedBy,
+		&i.ProposalID,
+		&i.RevertedTo,
+		&i.Snapshot,
+		&i.Changes,
+		&i.CreatedAt,
+		&i.MergedFrom,
 	)
 	return i, err
 }
 
 const getSourceByID = `-- name: GetSourceByID :one
-SELECT id, title, subtitle, type, description, publisher, isbn, doi, url, external_id, tags, published_at, created_at, updated_at
+SELECT id, title, subtitle, type, description, publisher, isbn, doi, url, external_id, published_at, created_at, updated_at, created_by
 FROM sources
 WHERE id = $1
 LIMIT 1
@@ -161,10 +507,66 @@ func (q *Queries) GetSourceByID(ctx context.Context, id pgtype.UUID) (Source, er
 		&i.Doi,
 		&i.Url,
 		&i.ExternalID,
-		&i.Tags,
 		&i.PublishedAt,
 		&i.CreatedAt,
 		&i.UpdatedAt,
+		&i.CreatedBy,
+	)
+	return i, err
+}
+
+const getSourceProposal = `-- name: GetSourceProposal :one
+SELECT id, source_id, proposed_by, changes, status, reviewed_by, review_note, reviewed_at, created_at, updated_at
+FROM source_proposals
+WHERE id = $1
+LIMIT 1
+`
+
+func (q *Queries) GetSourceProposal(ctx context.Context, id pgtype.UUID) (SourceProposal, error) {
+	row := q.db.QueryRow(ctx, getSourceProposal, id)
+	var i SourceProposal
+	err := row.Scan(
+		&i.ID,
+		&i.SourceID,
+		&i.ProposedBy,
+		&i.Changes,
+		&i.Status,
+		&i.ReviewedBy,
+		&i.ReviewNote,
+		&i.ReviewedAt,
+		&i.CreatedAt,
+		&i.UpdatedAt,
+	)
+	return i, err
+}
+
+const getSourceRevision = `-- name: GetSourceRevision :one
+SELECT id, source_id, revision, action, edited_by, proposal_id, reverted_to, snapshot, changes, created_at, merged_from
+FROM source_revisions
+WHERE source_id = $1 AND revision = $2
+LIMIT 1
+`
+
+type GetSourceRevisionParams struct {
+	SourceID pgtype.UUID `db:"source_id" json:"source_id"`
+	Revision int32       `db:"revision" json:"revision"`
+}
+
+func (q *Queries) GetSourceRevision(ctx context.Context, arg GetSourceRevisionParams) (SourceRevision, error) {
+	row := q.db.QueryRow(ctx, getSourceRevision, arg.SourceID, arg.Revision)
+	var i SourceRevision
+	err := row.Scan(
+		&i.ID,
+		&i.SourceID,
+		&i.Revision,
+		&i.Action,
+		&i.EditedBy,
+		&i.ProposalID,
+		&i.RevertedTo,
+		&i.Snapshot,
+		&i.Changes,
+		&i.CreatedAt,
+		&i.MergedFrom,
 	)
 	return i, err
 }
@@ -211,9 +613,9 @@ func (q *Queries) InsertBookMetadata(ctx context.Context, arg InsertBookMetadata
 }
 
 const insertBookSource = `-- name: InsertBookSource :one
-INSERT INTO sources (id, title, subtitle, type, description, publisher, isbn, url, external_id, tags, published_at)
-VALUES ($1, $2, $3, 'book', $4, $5, COALESCE($6, $7), $8, $9, $10, $11)
-RETURNING id, title, subtitle, type, description, publisher, isbn, doi, url, external_id, tags, published_at, created_at, updated_at
+INSERT INTO sources (id, title, subtitle, type, description, publisher, isbn, doi, url, external_id, published_at, created_by)
+VALUES ($1, $2, $3, 'book', $4, $5, COALESCE($6, $7), $8, $9, $10, $11, $12)
+RETURNING id, title, subtitle, type, description, publisher, isbn, doi, url, external_id, published_at, created_at, updated_at, created_by
 `
 
 type InsertBookSourceParams struct {
@@ -224,10 +626,11 @@ type InsertBookSourceParams struct {
 	Publisher   pgtype.Text        `db:"publisher" json:"publisher"`
 	Column6     interface{}        `db:"column_6" json:"column_6"`
 	Column7     interface{}        `db:"column_7" json:"column_7"`
+	Doi         pgtype.Text        `db:"doi" json:"doi"`
 	Url         pgtype.Text        `db:"url" json:"url"`
 	ExternalID  pgtype.Text        `db:"external_id" json:"external_id"`
-	Tags        []byte             `db:"tags" json:"tags"`
 	PublishedAt pgtype.Timestamptz `db:"published_at" json:"published_at"`
+	CreatedBy   pgtype.UUID        `db:"created_by" json:"created_by"`
 }
 
 func (q *Queries) InsertBookSource(ctx context.Context, arg InsertBookSourceParams) (Source, error) {
@@ -239,10 +642,11 @@ func (q *Queries) InsertBookSource(ctx context.Context, arg InsertBookSourcePara
 		arg.Publisher,
 		arg.Column6,
 		arg.Column7,
+		arg.Doi,
 		arg.Url,
 		arg.ExternalID,
-		arg.Tags,
 		arg.PublishedAt,
+		arg.CreatedBy,
 	)
 	var i Source
 	err := row.Scan(
@@ -256,10 +660,10 @@ func (q *Queries) InsertBookSource(ctx context.Context, arg InsertBookSourcePara
 		&i.Doi,
 		&i.Url,
 		&i.ExternalID,
-		&i.Tags,
 		&i.PublishedAt,
 		&i.CreatedAt,
 		&i.UpdatedAt,
+		&i.CreatedBy,
 	)
 	return i, err
 }
@@ -307,6 +711,23 @@ func (q *Queries) InsertSourceContributor(ctx context.Context, arg InsertSourceC
 	return i, err
 }
 
+const insertSourceTags = `-- name: InsertSourceTags :exec
+INSERT INTO source_tags (source_id, tag_id, position)
+SELECT $1::uuid, tag.id, tag.position - 1
+FROM unnest($2::uuid[]) WITH ORDINALITY AS tag(id, position)
+ON CONFLICT DO NOTHING
+`
+
+type InsertSourceTagsParams struct {
+	SourceID pgtype.UUID   `db:"source_id" json:"source_id"`
+	TagIds   []pgtype.UUID `db:"tag_ids" json:"tag_ids"`
+}
+
+func (q *Queries) InsertSourceTags(ctx context.Context, arg InsertSourceTagsParams) error {
+	_, err := q.db.Exec(ctx, insertSourceTags, arg.SourceID, arg.TagIds)
+	return err
+}
+
 const listContributorsBySource = `-- name: ListContributorsBySource :many
 SELECT c.id, c.name, sc.role, sc.position, c.created_at, c.updated_at
 FROM source_contributors sc
@@ -351,20 +772,197 @@ func (q *Queries) ListContributorsBySource(ctx context.Context, sourceID pgtype.
 	return items, nil
 }
 
-const listSources = `-- name: ListSources :many
-SELECT id, title, subtitle, type, description, publisher, isbn, doi, url, external_id, tags, published_at, created_at, updated_at
-FROM sources
-ORDER BY created_at DESC
-LIMIT $1 OFFSET $2
+const listDuplicateSourcePairs = `-- name: ListDuplicateSourcePairs :many
+WITH isbns AS (
+    SELECT DISTINCT raw.source_id,
+           CASE
+               WHEN length(raw.value) = 10 THEN substr(raw.value, 1, 9)
+               WHEN raw.value LIKE '978%' THEN substr(raw.value, 4, 9)
+               ELSE raw.value
+           END AS value
+    FROM (
+        SELECT s.id AS source_id, upper(regexp_replace(ids.isbn, '[^0-9Xx]', '', 'g')) AS value
+        FROM sources s
+        LEFT JOIN book_metadata bm ON bm.source_id = s.id
+        CROSS JOIN LATERAL (VALUES (s.isbn), (bm.isbn_10), (bm.isbn_13)) AS ids(isbn)
+        WHERE ids.isbn IS NOT NULL
+    ) raw
+    WHERE length(raw.value) IN (10, 13)
+),
+dois AS (
+    SELECT id AS source_id, lower(regexp_replace(trim(doi), '^(https?://(dx\.)?doi\.org/|doi:)', '', 'i')) AS value
+    FROM sources
+    WHERE doi IS NOT NULL AND trim(doi) <> ''
+),
+contributor_names AS (
+    SELECT sc.source_id, lower(string_agg(c.name, ' ' ORDER BY c.name)) AS names
+    FROM source_contributors sc
+    JOIN contributors c ON c.id = sc.contributor_id
+    GROUP BY sc.source_id
+)
+SELECT a.source_id AS source_id, b.source_id AS duplicate_id, 'isbn'::text AS reason, 1::real AS score
+FROM isbns a
+JOIN isbns b ON b.value = a.value AND b.source_id > a.source_id
+UNION
+SELECT a.source_id, b.source_id, 'doi'::text, 1::real
+FROM dois a
+JOIN dois b ON b.value = a.value AND b.source_id > a.source_id
+UNION
+SELECT a.id, b.id, 'title'::text, similarity(lower(a.title), lower(b.title))::real
+FROM source_search sa
+JOIN source_search sb ON sb.search_text % sa.search_text AND sb.source_id > sa.source_id
+JOIN sources a ON a.id = sa.source_id
+JOIN sources b ON b.id = sb.source_id AND b.type = a.type
+LEFT JOIN contributor_names ca ON ca.source_id = a.id
+LEFT JOIN contributor_names cb ON cb.source_id = b.id
+WHERE similarity(lower(a.title), lower(b.title)) >= 0.7
+  AND (ca.names IS NULL OR cb.names IS NULL OR similarity(ca.names, cb.names) >= 0.5)
+ORDER BY score DESC, source_id, duplicate_id
+LIMIT $1
 `
 
-type ListSourcesParams struct {
-	Limit  int32 `db:"limit" json:"limit"`
-	Offset int32 `db:"offset" json:"offset"`
+type ListDuplicateSourcePairsRow struct {
+	SourceID    pgtype.UUID `db:"source_id" json:"source_id"`
+	DuplicateID pgtype.UUID `db:"duplicate_id" json:"duplicate_id"`
+	Reason      string      `db:"reason" json:"reason"`
+	Score       float32     `db:"score" json:"score"`
 }
 
-func (q *Queries) ListSources(ctx context.Context, arg ListSourcesParams) ([]Source, error) {
-	rows, err := q.db.Query(ctx, listSources, arg.Limit, arg.Offset)
+func (q *Queries) ListDuplicateSourcePairs(ctx context.Context, limit int32) ([]ListDuplicateSourcePairsRow, error) {
+	rows, err := q.db.Query(ctx, listDuplicateSourcePairs, limit)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	items := []ListDuplicateSourcePairsRow{}
+	for rows.Next() {
+		var i ListDuplicateSourcePairsRow
+		if err := rows.Scan(
+			&i.SourceID,
+			&i.DuplicateID,
+			&i.Reason,
+			&i.Score,
+		); err != nil {
+			return nil, err
+		}
+		items = append(items, i)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
+
+const listSourceProposals = `-- name: ListSourceProposals :many
+SELECT id, source_id, proposed_by, changes, status, reviewed_by, review_note, reviewed_at, created_at, updated_at
+FROM source_proposals
+WHERE ($1::text IS NULL OR status = $1::text)
+  AND ($2::uuid IS NULL OR source_id = $2::uuid)
+  AND ($3::uuid IS NULL OR proposed_by = $3::uuid)
+ORDER BY created_at ASC
+LIMIT $4 OFFSET $5
+`
+
+type ListSourceProposalsParams struct {
+	Status     pgtype.Text `db:"status" json:"status"`
+	SourceID   pgtype.UUID `db:"source_id" json:"source_id"`
+	ProposedBy pgtype.UUID `db:"proposed_by" json:"proposed_by"`
+	Limit      int32       `db:"limit" json:"limit"`
+	Offset     int32       `db:"offset" json:"offset"`
+}
+
+func (q *Queries) ListSourceProposals(ctx context.Context, arg ListSourceProposalsParams) ([]SourceProposal, error) {
+	rows, err := q.db.Query(ctx, listSourceProposals,
+		arg.Status,
+		arg.SourceID,
+		arg.ProposedBy,
+		arg.Limit,
+		arg.Offset,
+	)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var items []SourceProposal
+	for rows.Next() {
+		var i SourceProposal
+		if err := rows.Scan(
+			&i.ID,
+			&i.SourceID,
+			&i.ProposedBy,
+			&i.Changes,
+			&i.Status,
+			&i.ReviewedBy,
+			&i.ReviewNote,
+			&i.ReviewedAt,
+			&i.CreatedAt,
+			&i.UpdatedAt,
+		); err != nil {
+			return nil, err
+		}
+		items = append(items, i)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
+
+const listSourceRevisions = `-- name: ListSourceRevisions :many
+SELECT id, source_id, revision, action, edited_by, proposal_id, reverted_to, snapshot, changes, created_at, merged_from
+FROM source_revisions
+WHERE source_id = $1
+ORDER BY revision DESC
+LIMIT $2 OFFSET $3
+`
+
+type ListSourceRevisionsParams struct {
+	SourceID pgtype.UUID `db:"source_id" json:"source_id"`
+	Limit    int32       `db:"limit" json:"limit"`
+	Offset   int32       `db:"offset" json:"offset"`
+}
+
+func (q *Queries) ListSourceRevisions(ctx context.Context, arg ListSourceRevisionsParams) ([]SourceRevision, error) {
+	rows, err := q.db.Query(ctx, listSourceRevisions, arg.SourceID, arg.Limit, arg.Offset)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	items := []SourceRevision{}
+	for rows.Next() {
+		var i SourceRevision
+		if err := rows.Scan(
+			&i.ID,
+			&i.SourceID,
+			&i.Revision,
+			&i.Action,
+			&i.EditedBy,
+			&i.ProposalID,
+			&i.RevertedTo,
+			&i.Snapshot,
+			&i.Changes,
+			&i.CreatedAt,
+			&i.MergedFrom,
+		); err != nil {
+			return nil, err
+		}
+		items = append(items, i)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
+
+const listSourcesByIDs = `-- name: ListSourcesByIDs :many
+SELECT id, title, subtitle, type, description, publisher, isbn, doi, url, external_id, published_at, created_at, updated_at, created_by
+FROM sources
+WHERE id = ANY($1::uuid[])
+ORDER BY created_at ASC
+`
+
+func (q *Queries) ListSourcesByIDs(ctx context.Context, ids []pgtype.UUID) ([]Source, error) {
+	rows, err := q.db.Query(ctx, listSourcesByIDs, ids)
 	if err != nil {
 		return nil, err
 	}
@@ -383,10 +981,10 @@ func (q *Queries) ListSources(ctx context.Context, arg ListSourcesParams) ([]Sou
 			&i.Doi,
 			&i.Url,
 			&i.ExternalID,
-			&i.Tags,
 			&i.PublishedAt,
 			&i.CreatedAt,
 			&i.UpdatedAt,
+			&i.CreatedBy,
 		); err != nil {
 			return nil, err
 		}
@@ -398,22 +996,34 @@ func (q *Queries) ListSources(ctx context.Context, arg ListSourcesParams) ([]Sou
 	return items, nil
 }
 
-const listSourcesByType = `-- name: ListSourcesByType :many
-SELECT id, title, subtitle, type, description, publisher, isbn, doi, url, external_id, tags, published_at, created_at, updated_at
+const listSourcesNewest = `-- name: ListSourcesNewest :many
+SELECT id, title, subtitle, type, description, publisher, isbn, doi, url, external_id, published_at, created_at, updated_at, created_by
 FROM sources
-WHERE type = $1
-ORDER BY created_at DESC
-LIMIT $2 OFFSET $3
+WHERE ($1::text IS NULL OR type = $1::text)
+  AND ($2::text IS NULL OR EXISTS (
+    SELECT 1 FROM source_tags st JOIN tags t ON t.id = st.tag_id
+    WHERE st.source_id = sources.id AND t.slug = $2::text))
+  AND (created_at, id) < ($3::timestamptz, $4::uuid)
+ORDER BY created_at DESC, id DESC
+LIMIT $5
 `
 
-type ListSourcesByTypeParams struct {
-	Type   string `db:"type" json:"type"`
-	Limit  int32  `db:"limit" json:"limit"`
-	Offset int32  `db:"offset" json:"offset"`
+type ListSourcesNewestParams struct {
+	Type           pgtype.Text        `db:"type" json:"type"`
+	Tag            pgtype.Text        `db:"tag" json:"tag"`
+	AfterCreatedAt pgtype.Timestamptz `db:"after_created_at" json:"after_created_at"`
+	AfterID        pgtype.UUID        `db:"after_id" json:"after_id"`
+	Limit          int32              `db:"limit" json:"limit"`
 }
 
-func (q *Queries) ListSourcesByType(ctx context.Context, arg ListSourcesByTypeParams) ([]Source, error) {
-	rows, err := q.db.Query(ctx, listSourcesByType, arg.Type, arg.Limit, arg.Offset)
+func (q *Queries) ListSourcesNewest(ctx context.Context, arg ListSourcesNewestParams) ([]Source, error) {
+	rows, err := q.db.Query(ctx, listSourcesNewest,
+		arg.Type,
+		arg.Tag,
+		arg.AfterCreatedAt,
+		arg.AfterID,
+		arg.Limit,
+	)
 	if err != nil {
 		return nil, err
 	}
@@ -432,10 +1042,10 @@ func (q *Queries) ListSourcesByType(ctx context.Context, arg ListSourcesByTypePa
 			&i.Doi,
 			&i.Url,
 			&i.ExternalID,
-			&i.Tags,
 			&i.PublishedAt,
 			&i.CreatedAt,
 			&i.UpdatedAt,
+			&i.CreatedBy,
 		); err != nil {
 			return nil, err
 		}
@@ -447,22 +1057,34 @@ func (q *Queries) ListSourcesByType(ctx context.Context, arg ListSourcesByTypePa
 	return items, nil
 }
 
-const searchSources = `-- name: SearchSources :many
-SELECT id, title, subtitle, type, description, publisher, isbn, doi, url, external_id, tags, published_at, created_at, updated_at
+const listSourcesOldest = `-- name: ListSourcesOldest :many
+SELECT id, title, subtitle, type, description, publisher, isbn, doi, url, external_id, published_at, created_at, updated_at, created_by
 FROM sources
-WHERE title ILIKE '%' || $1 || '%'
-ORDER BY created_at DESC
-LIMIT $2 OFFSET $3
+WHERE ($1::text IS NULL OR type = $1::text)
+  AND ($2::text IS NULL OR EXISTS (
+    SELECT 1 FROM source_tags st JOIN tags t ON t.id = st.tag_id
+    WHERE st.source_id = sources.id AND t.slug = $2::text))
+  AND (created_at, id) > ($3::timestamptz, $4::uuid)
+ORDER BY created_at ASC, id ASC
+LIMIT $5
 `
 
-type SearchSourcesParams struct {
-	Column1 pgtype.Text `db:"column_1" json:"column_1"`
-	Limit   int32       `db:"limit" json:"limit"`
-	Offset  int32       `db:"offset" json:"offset"`
+type ListSourcesOldestParams struct {
+	Type           pgtype.Text        `db:"type" json:"type"`
+	Tag            pgtype.Text        `db:"tag" json:"tag"`
+	AfterCreatedAt pgtype.Timestamptz `db:"after_created_at" json:"after_created_at"`
+	AfterID        pgtype.UUID        `db:"after_id" json:"after_id"`
+	Limit          int32              `db:"limit" json:"limit"`
 }
 
-func (q *Queries) SearchSources(ctx context.Context, arg SearchSourcesParams) ([]Source, error) {
-	rows, err := q.db.Query(ctx, searchSources, arg.Column1, arg.Limit, arg.Offset)
+func (q *Queries) ListSourcesOldest(ctx context.Context, arg ListSourcesOldestParams) ([]Source, error) {
+	rows, err := q.db.Query(ctx, listSourcesOldest,
+		arg.Type,
+		arg.Tag,
+		arg.AfterCreatedAt,
+		arg.AfterID,
+		arg.Limit,
+	)
 	if err != nil {
 		return nil, err
 	}
@@ -481,10 +1103,304 @@ func (q *Queries) SearchSources(ctx context.Context, arg SearchSourcesParams) ([
 			&i.Doi,
 			&i.Url,
 			&i.ExternalID,
-			&i.Tags,
 			&i.PublishedAt,
 			&i.CreatedAt,
 			&i.UpdatedAt,
+			&i.CreatedBy,
+		); err != nil {
+			return nil, err
+		}
+		items = append(items, i)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
+
+const listTagsBySources = `-- name: ListTagsBySources :many
+SELECT st.source_id, t.name
+FROM source_tags st
+JOIN tags t ON t.id = st.tag_id
+WHERE st.source_id = ANY($1::uuid[])
+ORDER BY st.source_id, st.position, t.slug
+`
+
+type ListTagsBySourcesRow struct {
+	SourceID pgtype.UUID `db:"source_id" json:"source_id"`
+	Name     string      `db:"name" json:"name"`
+}
+
+func (q *Queries) ListTagsBySources(ctx context.Context, sourceIds []pgtype.UUID) ([]ListTagsBySourcesRow, error) {
+	rows, err := q.db.Query(ctx, listTagsBySources, sourceIds)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	items := []ListTagsBySourcesRow{}
+	for rows.Next() {
+		var i ListTagsBySourcesRow
+		if err := rows.Scan(
+			&i.SourceID,
+			&i.Name,
+		); err != nil {
+			return nil, err
+		}
+		items = append(items, i)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
+
+const lockSource = `-- name: LockSource :one
+SELECT id FROM sources WHERE id = $1 FOR UPDATE
+`
+
+func (q *Queries) LockSource(ctx context.Context, id pgtype.UUID) (pgtype.UUID, error) {
+	row := q.db.QueryRow(ctx, lockSource, id)
+	err := row.Scan(&id)
+	return id, err
+}
+
+const moveCollectionItemsToSource = `-- name: MoveCollectionItemsToSource :execrows
+UPDATE collection_items SET source_id = $1 WHERE source_id = $2
+`
+
+type MoveCollectionItemsToSourceParams struct {
+	SurvivorID  pgtype.UUID `db:"survivor_id" json:"survivor_id"`
+	DuplicateID pgtype.UUID `db:"duplicate_id" json:"duplicate_id"`
+}
+
+func (q *Queries) MoveCollectionItemsToSource(ctx context.Context, arg MoveCollectionItemsToSourceParams) (int64, error) {
+	result, err := q.db.Exec(ctx, moveCollectionItemsToSource, arg.SurvivorID, arg.DuplicateID)
+	if err != nil {
+		return 0, err
+	}
+	return result.RowsAffected(), nil
+}
+
+const moveLibraryItemsToSource = `-- name: MoveLibraryItemsToSource :execrows
+UPDATE user_library_items SET source_id = $1 WHERE source_id = $2
+`
+
+type MoveLibraryItemsToSourceParams struct {
+	SurvivorID  pgtype.UUID `db:"survivor_id" json:"survivor_id"`
+	DuplicateID pgtype.UUID `db:"duplicate_id" json:"duplicate_id"`
+}
+
+func (q *Queries) MoveLibraryItemsToSource(ctx context.Context, arg MoveLibraryItemsToSourceParams) (int64, error) {
+	result, err := q.db.Exec(ctx, moveLibraryItemsToSource, arg.SurvivorID, arg.DuplicateID)
+	if err != nil {
+		return 0, err
+	}
+	return result.RowsAffected(), nil
+}
+
+const moveNotesToSource = `-- name: MoveNotesToSource :execrows
+UPDATE notes SET source_id = $1 WHERE source_id = $2
+`
+
+type MoveNotesToSourceParams struct {
+	SurvivorID  pgtype.UUID `db:"survivor_id" json:"survivor_id"`
+	DuplicateID pgtype.UUID `db:"duplicate_id" json:"duplicate_id"`
+}
+
+func (q *Queries) MoveNotesToSource(ctx context.Context, arg MoveNotesToSourceParams) (int64, error) {
+	result, err := q.db.Exec(ctx, moveNotesToSource, arg.SurvivorID, arg.DuplicateID)
+	if err != nil {
+		return 0, err
+	}
+	return result.RowsAffected(), nil
+}
+
+const moveReviewsToSource = `-- name: MoveReviewsToSource :execrows
+UPDATE reviews SET source_id = $1 WHERE source_id = $2
+`
+
+type MoveReviewsToSourceParams struct {
+	SurvivorID  pgtype.UUID `db:"survivor_id" json:"survivor_id"`
+	DuplicateID pgtype.UUID `db:"duplicate_id" json:"duplicate_id"`
+}
+
+func (q *Queries) MoveReviewsToSource(ctx context.Context, arg MoveReviewsToSourceParams) (int64, error) {
+	result, err := q.db.Exec(ctx, moveReviewsToSource, arg.SurvivorID, arg.DuplicateID)
+	if err != nil {
+		return 0, err
+	}
+	return result.RowsAffected(), nil
+}
+
+const moveSupersededLibraryItemCompletions = `-- name: MoveSupersededLibraryItemCompletions :execrows
+UPDATE library_item_completions c
+SET library_item_id = other.id
+FROM user_library_items li
+JOIN user_library_items other ON other.user_id = li.user_id
+WHERE c.library_item_id = li.id
+  AND ((li.source_id = $1 AND other.source_id = $2 AND (li.updated_at > other.updated_at) IS NOT TRUE)
+    OR (li.source_id = $2 AND other.source_id = $1 AND li.updated_at < other.updated_at))
+`
+
+type MoveSupersededLibraryItemCompletionsParams struct {
+	DuplicateID pgtype.UUID `db:"duplicate_id" json:"duplicate_id"`
+	SurvivorID  pgtype.UUID `db:"survivor_id" json:"survivor_id"`
+}
+
+func (q *Queries) MoveSupersededLibraryItemCompletions(ctx context.Context, arg MoveSupersededLibraryItemCompletionsParams) (int64, error) {
+	result, err := q.db.Exec(ctx, moveSupersededLibraryItemCompletions, arg.DuplicateID, arg.SurvivorID)
+	if err != nil {
+		return 0, err
+	}
+	return result.RowsAffected(), nil
+}
+
+const moveSupersededReadingSessions = `-- name: MoveSupersededReadingSessions :execrows
+UPDATE reading_sessions rs
+SET library_item_id = other.id
+FROM user_library_items li
+JOIN user_library_items other ON other.user_id = li.user_id
+WHERE rs.library_item_id = li.id
+  AND ((li.source_id = $1 AND other.source_id = $2 AND (li.updated_at > other.updated_at) IS NOT TRUE)
+    OR (li.source_id = $2 AND other.source_id = $1 AND li.updated_at < other.updated_at))
+`
+
+type MoveSupersededReadingSessionsParams struct {
+	DuplicateID pgtype.UUID `db:"duplicate_id" json:"duplicate_id"`
+	SurvivorID  pgtype.UUID `db:"survivor_id" json:"survivor_id"`
+}
+
+func (q *Queries) MoveSupersededReadingSessions(ctx context.Context, arg MoveSupersededReadingSessionsParams) (int64, error) {
+	result, err := q.db.Exec(ctx, moveSupersededReadingSessions, arg.DuplicateID, arg.SurvivorID)
+	if err != nil {
+		return 0, err
+	}
+	return result.RowsAffected(), nil
+}
+
+const reviewSourceProposal = `-- name: ReviewSourceProposal :one
+UPDATE source_proposals
+SET status = $2, reviewed_by = $3, review_note = $4, reviewed_at = NOW(), updated_at = NOW()
+WHERE id = $1 AND status = 'pending'
+RETURNING id, source_id, proposed_by, changes, status, reviewed_by, review_note, reviewed_at, created_at, updated_at
+`
+
+type ReviewSourceProposalParams struct {
+	ID         pgtype.UUID `db:"id" json:"id"`
+	Status     string      `db:"status" json:"status"`
+	ReviewedBy pgtype.UUID `db:"reviewed_by" json:"reviewed_by"`
+	ReviewNote pgtype.Text `db:"review_note" json:"review_note"`
+}
+
+func (q *Queries) ReviewSourceProposal(ctx context.Context, arg ReviewSourceProposalParams) (SourceProposal, error) {
+	row := q.db.QueryRow(ctx, reviewSourceProposal,
+		arg.ID,
+		arg.Status,
+		arg.ReviewedBy,
+		arg.ReviewNote,
+	)
+	var i SourceProposal
+	err := row.Scan(
+		&i.ID,
+		&i.SourceID,
+		&i.ProposedBy,
+		&i.Changes,
+		&i.Status,
+		&i.ReviewedBy,
+		&i.ReviewNote,
+		&i.ReviewedAt,
+		&i.CreatedAt,
+		&i.UpdatedAt,
+	)
+	return i, err
+}
+
+const searchSources = `-- name: SearchSources :many
+WITH search AS (
+    SELECT websearch_to_tsquery('simple', $1::text) AS tsquery, lower($1::text) AS term
+)
+SELECT s.id, s.title, s.subtitle, s.type, s.description, s.publisher, s.isbn, s.doi, s.url, s.external_id, s.published_at, s.created_at, s.updated_at, s.created_by,
+       (ts_rank_cd(ss.document, search.tsquery) + word_similarity(search.term, ss.search_text))::real AS rank,
+       ts_headline('simple', concat_ws(' — ', s.title, s.subtitle, s.description), search.tsquery,
+                   'StartSel=<mark>, StopSel=</mark>, MaxWords=35, MinWords=15, MaxFragments=2')::text AS snippet
+FROM sources s
+JOIN source_search ss ON ss.source_id = s.id
+CROSS JOIN search
+LEFT JOIN book_metadata bm ON bm.source_id = s.id
+WHERE (ss.document @@ search.tsquery OR search.term <% ss.search_text)
+  AND ($2::text IS NULL OR s.type = $2::text)
+  AND ($3::text IS NULL OR EXISTS (
+    SELECT 1 FROM source_tags st JOIN tags t ON t.id = st.tag_id
+    WHERE st.source_id = s.id AND t.slug = $3::text))
+  AND ($4::text IS NULL OR lower(bm.language) = lower($4::text))
+  AND ($5::int IS NULL OR EXTRACT(YEAR FROM s.published_at AT TIME ZONE 'UTC') = $5::int)
+ORDER BY rank DESC, s.created_at DESC
+LIMIT $6 OFFSET $7
+`
+
+type SearchSourcesParams struct {
+	Query    string      `db:"query" json:"query"`
+	Type     pgtype.Text `db:"type" json:"type"`
+	Tag      pgtype.Text `db:"tag" json:"tag"`
+	Language pgtype.Text `db:"language" json:"language"`
+	Year     pgtype.Int4 `db:"year" json:"year"`
+	Limit    int32       `db:"limit" json:"limit"`
+	Offset   int32       `db:"offset" json:"offset"`
+}
+
+type SearchSourcesRow struct {
+	ID          pgtype.UUID        `db:"id" json:"id"`
+	Title       string             `db:"title" json:"title"`
+	Subtitle    pgtype.Text        `db:"subtitle" json:"subtitle"`
+	Type        string             `db:"type" json:"type"`
+	Description pgtype.Text        `db:"description" json:"description"`
+	Publisher   pgtype.Text        `db:"publisher" json:"publisher"`
+	Isbn        pgtype.Text        `db:"isbn" json:"isbn"`
+	Doi         pgtype.Text        `db:"doi" json:"doi"`
+	Url         pgtype.Text        `db:"url" json:"url"`
+	ExternalID  pgtype.Text        `db:"external_id" json:"external_id"`
+	PublishedAt pgtype.Timestamptz `db:"published_at" json:"published_at"`
+	CreatedAt   pgtype.Timestamptz `db:"created_at" json:"created_at"`
+	UpdatedAt   pgtype.Timestamptz `db:"updated_at" json:"updated_at"`
+	CreatedBy   pgtype.UUID        `db:"created_by" json:"created_by"`
+	Rank        float32            `db:"rank" json:"rank"`
+	Snippet     string             `db:"snippet" json:"snippet"`
+}
+
+func (q *Queries) SearchSources(ctx context.Context, arg SearchSourcesParams) ([]SearchSourcesRow, error) {
+	rows, err := q.db.Query(ctx, searchSources,
+		arg.Query,
+		arg.Type,
+		arg.Tag,
+		arg.Language,
+		arg.Year,
+		arg.Limit,
+		arg.Offset,
+	)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	items := []SearchSourcesRow{}
+	for rows.Next() {
+		var i SearchSourcesRow
+		if err := rows.Scan(
+			&i.ID,
+			&i.Title,
+			&i.Subtitle,
+			&i.Type,
+			&i.Description,
+			&i.Publisher,
+			&i.Isbn,
+			&i.Doi,
+			&i.Url,
+			&i.ExternalID,
+			&i.PublishedAt,
+			&i.CreatedAt,
+			&i.UpdatedAt,
+			&i.CreatedBy,
+			&i.Rank,
+			&i.Snippet,
 		); err != nil {
 			return nil, err
 		}
@@ -499,9 +1415,9 @@ func (q *Queries) SearchSources(ctx context.Context, arg SearchSourcesParams) ([
 const updateSource = `-- name: UpdateSource :one
 UPDATE sources
 SET title = $2, subtitle = $3, type = $4, description = $5, publisher = $6,
-    isbn = $7, doi = $8, url = $9, external_id = $10, tags = $11, published_at = $12, updated_at = NOW()
+    isbn = $7, doi = $8, url = $9, external_id = $10, published_at = $11, updated_at = NOW()
 WHERE id = $1
-RETURNING id, title, subtitle, type, description, publisher, isbn, doi, url, external_id, tags, published_at, created_at, updated_at
+RETURNING id, title, subtitle, type, description, publisher, isbn, doi, url, external_id, published_at, created_at, updated_at, created_by
 `
 
 type UpdateSourceParams struct {
@@ -515,7 +1431,6 @@ type UpdateSourceParams struct {
 	Doi         pgtype.Text        `db:"doi" json:"doi"`
 	Url         pgtype.Text        `db:"url" json:"url"`
 	ExternalID  pgtype.Text        `db:"external_id" json:"external_id"`
-	Tags        []byte             `db:"tags" json:"tags"`
 	PublishedAt pgtype.Timestamptz `db:"published_at" json:"published_at"`
 }
 
@@ -531,7 +1446,6 @@ func (q *Queries) UpdateSource(ctx context.Context, arg UpdateSourceParams) (Sou
 		arg.Doi,
 		arg.Url,
 		arg.ExternalID,
-		arg.Tags,
 		arg.PublishedAt,
 	)
 	var i Source
@@ -546,14 +1460,45 @@ func (q *Queries) UpdateSource(ctx context.Context, arg UpdateSourceParams) (Sou
 		&i.Doi,
 		&i.Url,
 		&i.ExternalID,
-		&i.Tags,
 		&i.PublishedAt,
 		&i.CreatedAt,
 		&i.UpdatedAt,
+		&i.CreatedBy,
 	)
 	return i, err
 }
 
+const upsertBookMetadata = `-- name: UpsertBookMetadata :exec
+INSERT INTO book_metadata (source_id, isbn_10, isbn_13, publisher, page_count, language, cover_url)
+VALUES ($1, $2, $3, $4, $5, $6, $7)
+ON CONFLICT (source_id) DO UPDATE
+SET isbn_10 = EXCLUDED.isbn_10, isbn_13 = EXCLUDED.isbn_13, publisher = EXCLUDED.publisher,
+    page_count = EXCLUDED.page_count, language = EXCLUDED.language, cover_url = EXCLUDED.cover_url, updated_at = NOW()
+`
+
+type UpsertBookMetadataParams struct {
+	SourceID  pgtype.UUID `db:"source_id" json:"source_id"`
+	Isbn10    pgtype.Text `db:"isbn_10" json:"isbn_10"`
+	Isbn13    pgtype.Text `db:"isbn_13" json:"isbn_13"`
+	Publisher pgtype.Text `db:"publisher" json:"publisher"`
+	PageCount pgtype.Int4 `db:"page_count" json:"page_count"`
+	Language  pgtype.Text `db:"language" json:"language"`
+	CoverUrl  pgtype.Text `db:"cover_url" json:"cover_url"`
+}
+
+func (q *Queries) UpsertBookMetadata(ctx context.Context, arg UpsertBookMetadataParams) error {
+	_, err := q.db.Exec(ctx, upsertBookMetadata,
+		arg.SourceID,
+		arg.Isbn10,
+		arg.Isbn13,
+		arg.Publisher,
+		arg.PageCount,
+		arg.Language,
+		arg.CoverUrl,
+	)
+	return err
+}
+
 const upsertContributor = `-- name: UpsertContributor :one
 INSERT INTO contributors (id, name)
 VALUES ($1, $2)
@@ -18,6 +18,21 @@ func UUID(value pgtype.UUID) uuid.UUID {
 	return uuid.UUID(value.Bytes)
 }
 
+func PGUUIDPtr(id *uuid.UUID) pgtype.UUID {
+	if id == nil {
+		return pgtype.UUID{}
+	}
+	return PGUUID(*id)
+}
+
+func UUIDPtr(value pgtype.UUID) *uuid.UUID {
+	if !value.Valid {
+		return nil
+	}
+	id := UUID(value)
+	return &id
+}
+
 func PGText(value *string) pgtype.Text {
 	if value == nil {
 		return pgtype.Text{}
@@ -89,3 +104,30 @@ func TimePtr(value pgtype.Timestamptz) *time.Time {
 	}
 	return &value.Time
 }
+
+func PGDate(value time.Time) pgtype.Date {
+	if value.IsZero() {
+		return pgtype.Date{}
+	}
+	return pgtype.Date{Time: value, Valid: true}
+}
+
+func Date(value pgtype.Date) time.Time {
+	if !value.Valid {
+		return time.Time{}
+	}
+	return value.Time
+}
+
+// Keyset returns the (created_at, id) bound a keyset-paginated list continues
+// after. A zero cursor maps to a bound past every row in the given direction,
+// so the first page uses the same sargable row comparison as the rest.
+func Keyset(createdAt time.Time, id uuid.UUID, oldest bool) (pgtype.Timestamptz, pgtype.UUID) {
+	if id != uuid.Nil {
+		return pgtype.Timestamptz{Time: createdAt, Valid: true}, PGUUID(id)
+	}
+	if oldest {
+		return pgtype.Timestamptz{InfinityModifier: pgtype.NegativeInfinity, Valid: true}, pgtype.UUID{Valid: true}
+	}
+	return pgtype.Timestamptz{InfinityModifier: pgtype.Infinity, Valid: true}, pgtype.UUID{Bytes: uuid.Max, Valid: true}
+}
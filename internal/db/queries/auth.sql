@@ -1,16 +1,16 @@
 -- name: CreateUser :one
 INSERT INTO users (id, email, username, password_hash)
 VALUES (sqlc.arg(id), LOWER(sqlc.arg(email)), LOWER(sqlc.arg(username)), sqlc.arg(password_hash))
-RETURNING id, email, username, password_hash, created_at, updated_at;
+RETURNING id, email, username, password_hash, created_at, updated_at, role, email_verified_at;
 
 -- name: GetUserByEmailOrUsername :one
-SELECT id, email, username, password_hash, created_at, updated_at
+SELECT id, email, username, password_hash, created_at, updated_at, role, email_verified_at
 FROM users
 WHERE email = LOWER($1) OR username = LOWER($1)
 LIMIT 1;
 
 -- name: GetUserByID :one
-SELECT id, email, username, password_hash, created_at, updated_at
+SELECT id, email, username, password_hash, created_at, updated_at, role, email_verified_at
 FROM users
 WHERE id = $1
 LIMIT 1;
@@ -19,6 +19,36 @@ LIMIT 1;
 INSERT INTO refresh_tokens (id, user_id, token_hash, family_id, expires_at)
 VALUES ($1, $2, $3, $4, $5);
 
+-- name: CreateSession :exec
+INSERT INTO sessions (family_id, user_id, user_agent, ip_address)
+VALUES ($1, $2, $3, $4);
+
+-- name: TouchSession :exec
+UPDATE sessions
+SET user_agent = COALESCE(sqlc.narg(user_agent), user_agent),
+    ip_address = COALESCE(sqlc.narg(ip_address), ip_address),
+    last_used_at = NOW()
+WHERE family_id = sqlc.arg(family_id);
+
+-- name: ListActiveSessions :many
+SELECT s.family_id, s.user_agent, s.ip_address, s.created_at, s.last_used_at, t.expires_at
+FROM sessions s
+JOIN refresh_tokens t ON t.family_id = s.family_id AND t.revoked_at IS NULL AND t.expires_at > NOW()
+WHERE s.user_id = $1
+ORDER BY s.last_used_at DESC, s.family_id;
+
+-- name: RevokeUserSession :execrows
+UPDATE refresh_tokens
+SET revoked_at = $3
+WHERE user_id = $1 AND family_id = $2 AND revoked_at IS NULL;
+
+-- name: RevokeOtherUserSessions :execrows
+UPDATE refresh_tokens
+SET revoked_at = sqlc.arg(revoked_at)
+WHERE user_id = sqlc.arg(user_id)
+  AND revoked_at IS NULL
+  AND (sqlc.narg(keep_family_id)::uuid IS NULL OR family_id <> sqlc.narg(keep_family_id)::uuid);
+
 -- name: GetRefreshToken :one
 SELECT id, user_id, token_hash, family_id, expires_at, revoked_at, created_at
 FROM refresh_tokens
@@ -38,3 +68,161 @@ WHERE id = $1 AND revoked_at IS NULL;
 UPDATE refresh_tokens
 SET revoked_at = COALESCE(revoked_at, $2)
 WHERE family_id = $1;
+
+-- name: RevokeUserRefreshTokens :exec
+UPDATE refresh_tokens
+SET revoked_at = COALESCE(revoked_at, $2)
+WHERE user_id = $1;
+
+-- name: DeleteUser :execrows
+DELETE FROM users
+WHERE id = $1;
+
+-- name: UpdateUserRole :execrows
+UPDATE users
+SET role = $2, updated_at = NOW()
+WHERE id = $1;
+
+-- name: MarkUserEmailVerified :execrows
+UPDATE users
+SET email_verified_at = COALESCE(email_verified_at, NOW()), updated_at = NOW()
+WHERE id = $1;
+
+-- name: UpdateUserPassword :execrows
+UPDATE users
+SET password_hash = $2, email_verified_at = COALESCE(email_verified_at, NOW()), updated_at = NOW()
+WHERE id = $1;
+
+-- name: CreateUserToken :exec
+INSERT INTO user_tokens (id, user_id, purpose, token_hash, expires_at)
+VALUES ($1, $2, $3, $4, $5);
+
+-- name: InvalidateUserTokens :exec
+UPDATE user_tokens
+SET used_at = NOW()
+WHERE user_id = $1 AND purpose = $2 AND used_at IS NULL;
+
+-- name: ConsumeUserToken :one
+UPDATE user_tokens
+SET used_at = NOW()
+WHERE token_hash = $1 AND purpose = $2 AND used_at IS NULL AND expires_at > NOW()
+RETURNING user_id;
+
+-- name: GetActiveUserToken :one
+SELECT user_id
+FROM user_tokens
+WHERE token_hash = $1 AND purpose = $2 AND used_at IS NULL AND expires_at > NOW()
+LIMIT 1;
+
+-- name: GetUserTOTP :one
+SELECT user_id, secret_ciphertext, confirmed_at, last_used_step
+FROM user_totp
+WHERE user_id = $1
+LIMIT 1;
+
+-- name: UpsertPendingUserTOTP :execrows
+INSERT INTO user_totp (user_id, secret_ciphertext)
+VALUES ($1, $2)
+ON CONFLICT (user_id) DO UPDATE
+SET secret_ciphertext = EXCLUDED.secret_ciphertext, last_used_step = 0, created_at = NOW()
+WHERE user_totp.confirmed_at IS NULL;
+
+-- name: ConfirmUserTOTP :execrows
+UPDATE user_totp
+SET confirmed_at = NOW(), last_used_step = $2
+WHERE user_id = $1 AND confirmed_at IS NULL;
+
+-- name: UseUserTOTPStep :execrows
+UPDATE user_totp
+SET last_used_step = $2
+WHERE user_id = $1 AND confirmed_at IS NOT NULL AND last_used_step < $2;
+
+-- name: DeleteUserTOTP :execrows
+DELETE FROM user_totp
+WHERE user_id = $1;
+
+-- name: CreateRecoveryCode :exec
+INSERT INTO recovery_codes (id, user_id, code_hash)
+VALUES ($1, $2, $3);
+
+-- name: DeleteRecoveryCodes :exec
+DELETE FROM recovery_codes
+WHERE user_id = $1;
+
+-- name: UseRecoveryCode :execrows
+UPDATE recovery_codes
+SET used_at = NOW()
+WHERE user_id = $1 AND code_hash = $2 AND used_at IS NULL;
+
+-- name: CountUnusedRecoveryCodes :one
+SELECT COUNT(*)
+FROM recovery_codes
+WHERE user_id = $1 AND used_at IS NULL;
+
+-- name: CreateOIDCLogin :exec
+INSERT INTO oidc_logins (state_hash, provider, nonce, code_verifier, user_id, reauthenticate, expires_at)
+VALUES ($1, $2, $3, $4, $5, $6, $7);
+
+-- name: DeleteExpiredOIDCLogins :exec
+DELETE FROM oidc_logins
+WHERE expires_at <= NOW();
+
+-- name: ConsumeOIDCLogin :one
+DELETE FROM oidc_logins
+WHERE state_hash = $1 AND expires_at > NOW()
+RETURNING provider, nonce, code_verifier, user_id, reauthenticate;
+
+-- name: UseUserIdentity :one
+UPDATE user_identities
+SET email = COALESCE(sqlc.narg(email), email), last_used_at = NOW()
+WHERE provider = sqlc.arg(provider) AND subject = sqlc.arg(subject)
+RETURNING user_id;
+
+-- name: CreateUserIdentity :exec
+INSERT INTO user_identities (id, user_id, provider, subject, email)
+VALUES ($1, $2, $3, $4, $5);
+
+-- name: ListUserIdentities :many
+SELECT provider, email, created_at, last_used_at
+FROM user_identities
+WHERE user_id = $1
+ORDER BY provider;
+
+-- name: DeleteUserIdentity :execrows
+DELETE FROM user_identities
+WHERE user_id = $1 AND provider = $2;
+
+-- name: CreatePersonalAccessToken :one
+INSERT INTO personal_access_tokens (id, user_id, name, token_hash, scopes, expires_at)
+VALUES ($1, $2, $3, $4, $5, $6)
+RETURNING created_at;
+
+-- name: GetActivePersonalAccessToken :one
+SELECT t.id, t.user_id, t.scopes, u.role
+FROM personal_access_tokens t
+JOIN users u ON u.id = t.user_id
+WHERE t.token_hash = $1
+  AND t.revoked_at IS NULL
+  AND (t.expires_at IS NULL OR t.expires_at > NOW());
+
+-- name: TouchPersonalAccessToken :exec
+UPDATE personal_access_tokens
+SET last_used_at = NOW(), last_used_ip = $2
+WHERE id = $1
+  AND (last_used_at IS NULL OR last_used_at < NOW() - INTERVAL '1 minute' OR last_used_ip IS DISTINCT FROM $2);
+
+-- name: ListPersonalAccessTokens :many
+SELECT id, name, scopes, expires_at, last_used_at, last_used_ip, created_at
+FROM personal_access_tokens
+WHERE user_id = $1 AND revoked_at IS NULL
+ORDER BY created_at DESC, id;
+
+-- name: RevokePersonalAccessToken :execrows
+UPDATE personal_access_tokens
+SET revoked_at = $3
+WHERE id = $1 AND user_id = $2 AND revoked_at IS NULL;
+
+-- name: RevokeUserPersonalAccessTokens :exec
+UPDATE personal_access_tokens
+SET revoked_at = COALESCE(revoked_at, $2)
+WHERE user_id = $1;
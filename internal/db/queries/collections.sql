@@ -1,36 +1,308 @@
 -- name: CreateCollection :one
-INSERT INTO collections (id, user_id, name, description, is_public, source_ids)
+INSERT INTO collections (id, user_id, name, description, is_public, rules)
 VALUES ($1, $2, $3, $4, $5, $6)
-RETURNING id, user_id, name, description, is_public, source_ids, created_at, updated_at;
+RETURNING id, user_id, name, description, is_public, created_at, updated_at, rules;
 
 -- name: GetCollectionByID :one
-SELECT id, user_id, name, description, is_public, source_ids, created_at, updated_at
+SELECT id, user_id, name, description, is_public, created_at, updated_at, rules
 FROM collections
 WHERE id = $1
 LIMIT 1;
 
 -- name: ListCollectionsByUser :many
-SELECT id, user_id, name, description, is_public, source_ids, created_at, updated_at
+SELECT id, user_id, name, description, is_public, created_at, updated_at, rules
 FROM collections
 WHERE user_id = $1
 ORDER BY created_at DESC
 LIMIT $2 OFFSET $3;
 
--- name: ListPublicCollectionsByUser :many
-SELECT id, user_id, name, description, is_public, source_ids, created_at, updated_at
+-- name: ListCollectionsNewest :many
+SELECT id, user_id, name, description, is_public, created_at, updated_at, rules
 FROM collections
-WHERE user_id = $1 AND is_public = true
-ORDER BY created_at DESC
-LIMIT $2 OFFSET $3;
+WHERE (sqlc.narg(user_id)::uuid IS NULL OR user_id = sqlc.narg(user_id)::uuid)
+  AND (sqlc.narg(member_id)::uuid IS NULL OR EXISTS (
+    SELECT 1 FROM collection_members m WHERE m.collection_id = collections.id AND m.user_id = sqlc.narg(member_id)::uuid))
+  AND (NOT sqlc.arg(public_only)::bool OR is_public = true)
+  AND (created_at, id) < (sqlc.arg(after_created_at)::timestamptz, sqlc.arg(after_id)::uuid)
+ORDER BY created_at DESC, id DESC
+LIMIT sqlc.arg('limit');
+
+-- name: ListCollectionsOldest :many
+SELECT id, user_id, name, description, is_public, created_at, updated_at, rules
+FROM collections
+WHERE (sqlc.narg(user_id)::uuid IS NULL OR user_id = sqlc.narg(user_id)::uuid)
+  AND (sqlc.narg(member_id)::uuid IS NULL OR EXISTS (
+    SELECT 1 FROM collection_members m WHERE m.collection_id = collections.id AND m.user_id = sqlc.narg(member_id)::uuid))
+  AND (NOT sqlc.arg(public_only)::bool OR is_public = true)
+  AND (created_at, id) > (sqlc.arg(after_created_at)::timestamptz, sqlc.arg(after_id)::uuid)
+ORDER BY created_at ASC, id ASC
+LIMIT sqlc.arg('limit');
+
+-- name: CountCollections :one
+SELECT COUNT(*)
+FROM collections
+WHERE (sqlc.narg(user_id)::uuid IS NULL OR user_id = sqlc.narg(user_id)::uuid)
+  AND (sqlc.narg(member_id)::uuid IS NULL OR EXISTS (
+    SELECT 1 FROM collection_members m WHERE m.collection_id = collections.id AND m.user_id = sqlc.narg(member_id)::uuid))
+  AND (NOT sqlc.arg(public_only)::bool OR is_public = true);
 
 -- name: UpdateCollection :one
 UPDATE collections
-SET name = $2, description = $3, is_public = $4, source_ids = $5, updated_at = NOW()
+SET name = $2, description = $3, is_public = $4, rules = $5, updated_at = NOW()
 WHERE id = $1
-RETURNING id, user_id, name, description, is_public, source_ids, created_at, updated_at;
+RETURNING id, user_id, name, description, is_public, created_at, updated_at, rules;
 
--- name: DeleteCollection :exec
+-- name: DeleteCollection :execrows
 DELETE FROM collections WHERE id = $1;
 
 -- name: SourceExists :one
 SELECT EXISTS (SELECT 1 FROM sources WHERE id = $1);
+
+-- name: LockCollection :one
+SELECT id FROM collections WHERE id = $1 FOR UPDATE;
+
+-- name: TouchCollection :exec
+UPDATE collections SET updated_at = NOW() WHERE id = $1;
+
+-- name: ListCollectionItems :many
+SELECT ci.collection_id, ci.source_id, ci.position, ci.note, ci.added_at, ci.added_by,
+       s.title, s.subtitle, s.type, s.publisher, s.isbn
+FROM collection_items ci
+JOIN sources s ON s.id = ci.source_id
+WHERE ci.collection_id = ANY(sqlc.arg(collection_ids)::uuid[])
+ORDER BY ci.collection_id, ci.position, ci.added_at;
+
+-- name: ListCollectionSourceIDs :many
+SELECT collection_id, source_id
+FROM collection_items
+WHERE collection_id = ANY(sqlc.arg(collection_ids)::uuid[])
+ORDER BY collection_id, position, added_at;
+
+-- name: GetCollectionItem :one
+SELECT ci.collection_id, ci.source_id, ci.position, ci.note, ci.added_at, ci.added_by,
+       s.title, s.subtitle, s.type, s.publisher, s.isbn
+FROM collection_items ci
+JOIN sources s ON s.id = ci.source_id
+WHERE ci.collection_id = sqlc.arg(collection_id) AND ci.source_id = sqlc.arg(source_id);
+
+-- name: CountCollectionItems :one
+SELECT COUNT(*) FROM collection_items WHERE collection_id = $1;
+
+-- name: InsertCollectionItem :execrows
+INSERT INTO collection_items (collection_id, source_id, position, note, added_by)
+VALUES (sqlc.arg(collection_id), sqlc.arg(source_id), sqlc.arg(position), sqlc.narg(note), sqlc.arg(added_by))
+ON CONFLICT DO NOTHING;
+
+-- name: ShiftCollectionItems :exec
+UPDATE collection_items
+SET position = position + sqlc.arg(delta)::int
+WHERE collection_id = sqlc.arg(collection_id) AND position >= sqlc.arg(from_position)::int;
+
+-- name: MoveCollectionItem :execrows
+UPDATE collection_items ci
+SET position = CASE
+        WHEN ci.source_id = sqlc.arg(source_id) THEN sqlc.arg(to_position)::int
+        WHEN sqlc.arg(to_position)::int < target.position THEN ci.position + 1
+        ELSE ci.position - 1
+    END
+FROM (
+    SELECT position FROM collection_items
+    WHERE collection_id = sqlc.arg(collection_id) AND source_id = sqlc.arg(source_id)
+) target
+WHERE ci.collection_id = sqlc.arg(collection_id)
+  AND ci.position BETWEEN LEAST(target.position, sqlc.arg(to_position)::int) AND GREATEST(target.position, sqlc.arg(to_position)::int);
+
+-- name: UpdateCollectionItemNote :exec
+UPDATE collection_items
+SET note = sqlc.narg(note)
+WHERE collection_id = sqlc.arg(collection_id) AND source_id = sqlc.arg(source_id);
+
+-- name: DeleteCollectionItem :one
+DELETE FROM collection_items
+WHERE collection_id = sqlc.arg(collection_id) AND source_id = sqlc.arg(source_id)
+RETURNING position;
+
+-- name: DeleteCollectionItemsExcept :exec
+DELETE FROM collection_items
+WHERE collection_id = sqlc.arg(collection_id)
+  AND NOT (source_id = ANY(sqlc.arg(source_ids)::uuid[]));
+
+-- name: UpsertCollectionItems :exec
+INSERT INTO collection_items (collection_id, source_id, position, added_by)
+SELECT sqlc.arg(collection_id)::uuid, e.source_id, (e.position - 1)::int, sqlc.arg(added_by)::uuid
+FROM unnest(sqlc.arg(source_ids)::uuid[]) WITH ORDINALITY AS e(source_id, position)
+ON CONFLICT (collection_id, source_id) DO UPDATE SET position = EXCLUDED.position;
+
+-- name: AddCollectionMember :exec
+INSERT INTO collection_members (collection_id, user_id, role, invited_by)
+VALUES (sqlc.arg(collection_id), sqlc.arg(user_id), sqlc.arg(role), sqlc.narg(invited_by))
+ON CONFLICT (collection_id, user_id) DO NOTHING;
+
+-- name: GetCollectionMemberRole :one
+SELECT role FROM collection_members WHERE collection_id = $1 AND user_id = $2;
+
+-- name: GetCollectionMember :one
+SELECT m.collection_id, m.user_id, u.username, m.role, m.invited_by, m.joined_at
+FROM collection_members m
+JOIN users u ON u.id = m.user_id
+WHERE m.collection_id = $1 AND m.user_id = $2;
+
+-- name: ListCollectionMembers :many
+SELECT m.collection_id, m.user_id, u.username, m.role, m.invited_by, m.joined_at
+FROM collection_members m
+JOIN users u ON u.id = m.user_id
+WHERE m.collection_id = $1
+ORDER BY CASE m.role WHEN 'owner' THEN 0 WHEN 'editor' THEN 1 ELSE 2 END, m.joined_at, u.username;
+
+-- name: UpdateCollectionMemberRole :execrows
+UPDATE collection_members
+SET role = sqlc.arg(role)
+WHERE collection_id = sqlc.arg(collection_id) AND user_id = sqlc.arg(user_id) AND role <> 'owner';
+
+-- name: DeleteCollectionMember :execrows
+DELETE FROM collection_members
+WHERE collection_id = sqlc.arg(collection_id) AND user_id = sqlc.arg(user_id) AND role <> 'owner';
+
+-- name: GetUserIDByUsername :one
+SELECT id FROM users WHERE username = LOWER($1);
+
+-- name: UpsertCollectionInvitation :one
+INSERT INTO collection_invitations (id, collection_id, user_id, role, invited_by)
+VALUES ($1, $2, $3, $4, $5)
+ON CONFLICT (collection_id, user_id) DO UPDATE
+SET role = EXCLUDED.role, invited_by = EXCLUDED.invited_by, created_at = NOW()
+RETURNING id;
+
+-- name: GetCollectionInvitation :one
+SELECT i.id, i.collection_id, c.name AS collection_name, i.user_id, u.username, i.role, i.invited_by, inviter.username AS invited_by_username, i.created_at
+FROM collection_invitations i
+JOIN collections c ON c.id = i.collection_id
+JOIN users u ON u.id = i.user_id
+JOIN users inviter ON inviter.id = i.invited_by
+WHERE i.id = $1;
+
+-- name: ListCollectionInvitations :many
+SELECT i.id, i.collection_id, c.name AS collection_name, i.user_id, u.username, i.role, i.invited_by, inviter.username AS invited_by_username, i.created_at
+FROM collection_invitations i
+JOIN collections c ON c.id = i.collection_id
+JOIN users u ON u.id = i.user_id
+JOIN users inviter ON inviter.id = i.invited_by
+WHERE i.collection_id = $1
+ORDER BY i.created_at DESC, i.id DESC;
+
+-- name: ListUserCollectionInvitations :many
+SELECT i.id, i.collection_id, c.name AS collection_name, i.user_id, u.username, i.role, i.invited_by, inviter.username AS invited_by_username, i.created_at
+FROM collection_invitations i
+JOIN collections c ON c.id = i.collection_id
+JOIN users u ON u.id = i.user_id
+JOIN users inviter ON inviter.id = i.invited_by
+WHERE i.user_id = $1
+ORDER BY i.created_at DESC, i.id DESC;
+
+-- name: DeleteCollectionInvitation :execrows
+DELETE FROM collection_invitations WHERE id = $1;
+
+-- name: InsertCollectionActivity :exec
+INSERT INTO collection_activity (id, collection_id, actor_id, action, source_id, member_id, role)
+VALUES ($1, $2, $3, $4, $5, $6, $7);
+
+-- name: ListCollectionActivityNewest :many
+SELECT a.id, a.collection_id, a.actor_id, actor.username AS actor_username, a.action,
+       a.source_id, s.title AS source_title, a.member_id, member.username AS member_username, a.role, a.created_at
+FROM collection_activity a
+LEFT JOIN users actor ON actor.id = a.actor_id
+LEFT JOIN sources s ON s.id = a.source_id
+LEFT JOIN users member ON member.id = a.member_id
+WHERE a.collection_id = sqlc.arg(collection_id)
+  AND (a.created_at, a.id) < (sqlc.arg(after_created_at)::timestamptz, sqlc.arg(after_id)::uuid)
+ORDER BY a.created_at DESC, a.id DESC
+LIMIT sqlc.arg('limit');
+
+-- name: ListCollectionActivityOldest :many
+SELECT a.id, a.collection_id, a.actor_id, actor.username AS actor_username, a.action,
+       a.source_id, s.title AS source_title, a.member_id, member.username AS member_username, a.role, a.created_at
+FROM collection_activity a
+LEFT JOIN users actor ON actor.id = a.actor_id
+LEFT JOIN sources s ON s.id = a.source_id
+LEFT JOIN users member ON member.id = a.member_id
+WHERE a.collection_id = sqlc.arg(collection_id)
+  AND (a.created_at, a.id) > (sqlc.arg(after_created_at)::timestamptz, sqlc.arg(after_id)::uuid)
+ORDER BY a.created_at ASC, a.id ASC
+LIMIT sqlc.arg('limit');
+
+-- name: CountCollectionActivity :one
+SELECT COUNT(*) FROM collection_activity WHERE collection_id = $1;
+
+-- name: ListSmartCollectionMatchesNewest :many
+SELECT s.id, s.title, s.subtitle, s.type, s.publisher, s.isbn,
+       COALESCE(li.created_at, s.created_at)::timestamptz AS added_at
+FROM sources s
+LEFT JOIN user_library_items li ON li.source_id = s.id AND li.user_id = sqlc.arg(owner_id)::uuid
+  AND (NOT sqlc.arg(public_only)::bool OR li.visibility = 'public')
+LEFT JOIN reviews r ON r.source_id = s.id AND r.user_id = sqlc.arg(owner_id)::uuid
+  AND (NOT sqlc.arg(public_only)::bool OR r.is_public = true)
+WHERE (cardinality(sqlc.arg(types)::text[]) = 0 OR s.type = ANY(sqlc.arg(types)::text[]))
+  AND (NOT sqlc.arg(in_library)::bool OR li.id IS NOT NULL)
+  AND (cardinality(sqlc.arg(statuses)::text[]) = 0 OR li.status = ANY(sqlc.arg(statuses)::text[]))
+  AND (sqlc.narg(added_since)::timestamptz IS NULL OR li.created_at >= sqlc.narg(added_since)::timestamptz)
+  AND (sqlc.narg(min_rating)::int IS NULL OR r.rating >= sqlc.narg(min_rating)::int)
+  AND (sqlc.narg(max_rating)::int IS NULL OR r.rating <= sqlc.narg(max_rating)::int)
+  AND NOT EXISTS (
+    SELECT 1 FROM unnest(sqlc.arg(tag_slugs)::text[]) AS wanted(slug)
+    WHERE NOT EXISTS (
+        SELECT 1 FROM source_tags st JOIN tags t ON t.id = st.tag_id
+        WHERE st.source_id = s.id AND t.slug = wanted.slug)
+      AND (sqlc.arg(public_only)::bool OR NOT EXISTS (
+        SELECT 1 FROM library_item_tags lt JOIN tags t ON t.id = lt.tag_id
+        WHERE lt.library_item_id = li.id AND t.slug = wanted.slug)))
+  AND (COALESCE(li.created_at, s.created_at), s.id) < (sqlc.arg(after_created_at)::timestamptz, sqlc.arg(after_id)::uuid)
+ORDER BY COALESCE(li.created_at, s.created_at) DESC, s.id DESC
+LIMIT sqlc.arg('limit');
+
+-- name: ListSmartCollectionMatchesOldest :many
+SELECT s.id, s.title, s.subtitle, s.type, s.publisher, s.isbn,
+       COALESCE(li.created_at, s.created_at)::timestamptz AS added_at
+FROM sources s
+LEFT JOIN user_library_items li ON li.source_id = s.id AND li.user_id = sqlc.arg(owner_id)::uuid
+  AND (NOT sqlc.arg(public_only)::bool OR li.visibility = 'public')
+LEFT JOIN reviews r ON r.source_id = s.id AND r.user_id = sqlc.arg(owner_id)::uuid
+  AND (NOT sqlc.arg(public_only)::bool OR r.is_public = true)
+WHERE (cardinality(sqlc.arg(types)::text[]) = 0 OR s.type = ANY(sqlc.arg(types)::text[]))
+  AND (NOT sqlc.arg(in_library)::bool OR li.id IS NOT NULL)
+  AND (cardinality(sqlc.arg(statuses)::text[]) = 0 OR li.status = ANY(sqlc.arg(statuses)::text[]))
+  AND (sqlc.narg(added_since)::timestamptz IS NULL OR li.created_at >= sqlc.narg(added_since)::timestamptz)
+  AND (sqlc.narg(min_rating)::int IS NULL OR r.rating >= sqlc.narg(min_rating)::int)
+  AND (sqlc.narg(max_rating)::int IS NULL OR r.rating <= sqlc.narg(max_rating)::int)
+  AND NOT EXISTS (
+    SELECT 1 FROM unnest(sqlc.arg(tag_slugs)::text[]) AS wanted(slug)
+    WHERE NOT EXISTS (
+        SELECT 1 FROM source_tags st JOIN tags t ON t.id = st.tag_id
+        WHERE st.source_id = s.id AND t.slug = wanted.slug)
+      AND (sqlc.arg(public_only)::bool OR NOT EXISTS (
+        SELECT 1 FROM library_item_tags lt JOIN tags t ON t.id = lt.tag_id
+        WHERE lt.library_item_id = li.id AND t.slug = wanted.slug)))
+  AND (COALESCE(li.created_at, s.created_at), s.id) > (sqlc.arg(after_created_at)::timestamptz, sqlc.arg(after_id)::uuid)
+ORDER BY COALESCE(li.created_at, s.created_at) ASC, s.id ASC
+LIMIT sqlc.arg('limit');
+
+-- name: CountSmartCollectionMatches :one
+SELECT COUNT(*)
+FROM sources s
+LEFT JOIN user_library_items li ON li.source_id = s.id AND li.user_id = sqlc.arg(owner_id)::uuid
+  AND (NOT sqlc.arg(public_only)::bool OR li.visibility = 'public')
+LEFT JOIN reviews r ON r.source_id = s.id AND r.user_id = sqlc.arg(owner_id)::uuid
+  AND (NOT sqlc.arg(public_only)::bool OR r.is_public = true)
+WHERE (cardinality(sqlc.arg(types)::text[]) = 0 OR s.type = ANY(sqlc.arg(types)::text[]))
+  AND (NOT sqlc.arg(in_library)::bool OR li.id IS NOT NULL)
+  AND (cardinality(sqlc.arg(statuses)::text[]) = 0 OR li.status = ANY(sqlc.arg(statuses)::text[]))
+  AND (sqlc.narg(added_since)::timestamptz IS NULL OR li.created_at >= sqlc.narg(added_since)::timestamptz)
+  AND (sqlc.narg(min_rating)::int IS NULL OR r.rating >= sqlc.narg(min_rating)::int)
+  AND (sqlc.narg(max_rating)::int IS NULL OR r.rating <= sqlc.narg(max_rating)::int)
+  AND NOT EXISTS (
+    SELECT 1 FROM unnest(sqlc.arg(tag_slugs)::text[]) AS wanted(slug)
+    WHERE NOT EXISTS (
+        SELECT 1 FROM source_tags st JOIN tags t ON t.id = st.tag_id
+        WHERE st.source_id = s.id AND t.slug = wanted.slug)
+      AND (sqlc.arg(public_only)::bool OR NOT EXISTS (
+        SELECT 1 FROM library_item_tags lt JOIN tags t ON t.id = lt.tag_id
+        WHERE lt.library_item_id = li.id AND t.slug = wanted.slug)));
@@ -0,0 +1,80 @@
+-- name: ListNotesNeedingEmbedding :many
+SELECT n.id, n.content
+FROM notes n
+LEFT JOIN note_embeddings e ON e.note_id = n.id
+WHERE e.note_id IS NULL
+   OR e.model <> sqlc.arg(model)::text
+   OR e.content_hash <> sha256(convert_to(n.content, 'UTF8'))
+ORDER BY n.created_at ASC
+LIMIT sqlc.arg('limit');
+
+-- name: ListSourcesNeedingEmbedding :many
+SELECT d.id, d.document
+FROM (
+    SELECT s.id, s.created_at,
+           concat_ws(E'\n', s.title, s.subtitle, c.names, s.description)::text AS document
+    FROM sources s
+    LEFT JOIN LATERAL (
+        SELECT string_agg(ct.name, ', ' ORDER BY sc.position) AS names
+        FROM source_contributors sc
+        JOIN contributors ct ON ct.id = sc.contributor_id
+        WHERE sc.source_id = s.id
+    ) c ON TRUE
+) d
+LEFT JOIN source_embeddings e ON e.source_id = d.id
+WHERE e.source_id IS NULL
+   OR e.model <> sqlc.arg(model)::text
+   OR e.content_hash <> sha256(convert_to(d.document, 'UTF8'))
+ORDER BY d.created_at ASC
+LIMIT sqlc.arg('limit');
+
+-- name: UpsertNoteEmbedding :exec
+INSERT INTO note_embeddings (note_id, model, embedding, content_hash)
+VALUES ($1, $2, $3, $4)
+ON CONFLICT (note_id) DO UPDATE
+SET model = EXCLUDED.model, embedding = EXCLUDED.embedding, content_hash = EXCLUDED.content_hash;
+
+-- name: UpsertSourceEmbedding :exec
+INSERT INTO source_embeddings (source_id, model, embedding, content_hash)
+VALUES ($1, $2, $3, $4)
+ON CONFLICT (source_id) DO UPDATE
+SET model = EXCLUDED.model, embedding = EXCLUDED.embedding, content_hash = EXCLUDED.content_hash;
+
+-- name: GetNoteEmbedding :one
+SELECT model, embedding FROM note_embeddings WHERE note_id = $1;
+
+-- name: GetSourceEmbedding :one
+SELECT model, embedding FROM source_embeddings WHERE source_id = $1;
+
+-- name: SetHNSWEfSearch :exec
+SELECT set_config('hnsw.ef_search', sqlc.arg(ef_search)::text, true);
+
+-- name: ListSimilarNotes :many
+SELECT n.id, n.user_id, n.source_id, n.content, n.content_type, n.is_public, n.annotations, n.created_at, n.updated_at, n.anchor, n.location_key,
+       (1 - c.distance)::real AS similarity
+FROM (
+    SELECT e.note_id, e.embedding <=> sqlc.arg(embedding)::vector AS distance
+    FROM note_embeddings e
+    WHERE e.model = sqlc.arg(model)::text
+    ORDER BY e.embedding <=> sqlc.arg(embedding)::vector
+    LIMIT sqlc.arg(candidates)
+) c
+JOIN notes n ON n.id = c.note_id
+WHERE n.id <> sqlc.arg(note_id) AND n.user_id = sqlc.arg(user_id)
+ORDER BY c.distance
+LIMIT sqlc.arg('limit');
+
+-- name: ListRelatedSources :many
+SELECT s.id, s.title, s.subtitle, s.type, s.description, s.publisher, s.isbn, s.doi, s.url, s.external_id, s.published_at, s.created_at, s.updated_at, s.created_by,
+       (1 - c.distance)::real AS similarity
+FROM (
+    SELECT e.source_id, e.embedding <=> sqlc.arg(embedding)::vector AS distance
+    FROM source_embeddings e
+    WHERE e.model = sqlc.arg(model)::text
+    ORDER BY e.embedding <=> sqlc.arg(embedding)::vector
+    LIMIT sqlc.arg(candidates)
+) c
+JOIN sources s ON s.id = c.source_id
+WHERE s.id <> sqlc.arg(source_id)
+ORDER BY c.distance
+LIMIT sqlc.arg('limit');
@@ -0,0 +1,94 @@
+-- name: CreateGoal :one
+INSERT INTO goals (id, owner_id, title, target, period, source_type, starts_on, ends_on, shared)
+VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9)
+RETURNING id, owner_id, title, target, period, source_type, starts_on, ends_on, shared, created_at, updated_at;
+
+-- name: GetGoalByID :one
+SELECT id, owner_id, title, target, period, source_type, starts_on, ends_on, shared, created_at, updated_at
+FROM goals
+WHERE id = $1
+LIMIT 1;
+
+-- name: ListGoalsByParticipant :many
+SELECT g.id, g.owner_id, g.title, g.target, g.period, g.source_type, g.starts_on, g.ends_on, g.shared, g.created_at, g.updated_at
+FROM goals g
+JOIN goal_participants gp ON gp.goal_id = g.id
+WHERE gp.user_id = $1
+ORDER BY g.ends_on DESC, g.id DESC
+LIMIT $2 OFFSET $3;
+
+-- name: ListGoalParticipationsByUser :many
+SELECT g.id, g.owner_id, g.title, g.target, g.period, g.source_type, g.starts_on, g.ends_on, g.shared, g.created_at, g.updated_at, gp.joined_at
+FROM goals g
+JOIN goal_participants gp ON gp.goal_id = g.id
+WHERE gp.user_id = $1
+ORDER BY gp.joined_at, g.id
+LIMIT $2 OFFSET $3;
+
+-- name: ListActiveGoalsByParticipant :many
+SELECT g.id, g.owner_id, g.title, g.target, g.period, g.source_type, g.starts_on, g.ends_on, g.shared, g.created_at, g.updated_at
+FROM goals g
+JOIN goal_participants gp ON gp.goal_id = g.id
+WHERE gp.user_id = sqlc.arg(user_id) AND g.starts_on <= sqlc.arg(day)::date AND g.ends_on >= sqlc.arg(day)::date
+ORDER BY g.id;
+
+-- name: UpdateGoal :one
+UPDATE goals
+SET title = $2, target = $3, period = $4, source_type = $5, starts_on = $6, ends_on = $7, shared = $8, updated_at = NOW()
+WHERE id = $1
+RETURNING id, owner_id, title, target, period, source_type, starts_on, ends_on, shared, created_at, updated_at;
+
+-- name: DeleteGoal :execrows
+DELETE FROM goals WHERE id = $1;
+
+-- name: AddGoalParticipant :execrows
+INSERT INTO goal_participants (goal_id, user_id)
+VALUES ($1, $2)
+ON CONFLICT DO NOTHING;
+
+-- name: RemoveGoalParticipant :execrows
+DELETE FROM goal_participants WHERE goal_id = $1 AND user_id = $2;
+
+-- name: IsGoalParticipant :one
+SELECT EXISTS (SELECT 1 FROM goal_participants WHERE goal_id = $1 AND user_id = $2);
+
+-- name: CountCompletedInWindow :one
+SELECT COUNT(*)
+FROM library_completions lc
+JOIN user_library_items uli ON uli.id = lc.library_item_id
+JOIN sources s ON s.id = uli.source_id
+WHERE lc.user_id = sqlc.arg(user_id)
+  AND lc.completed_at >= sqlc.arg(window_start) AND lc.completed_at < sqlc.arg(window_end)
+  AND (sqlc.narg(source_type)::text IS NULL OR s.type = sqlc.narg(source_type)::text);
+
+-- name: GetGoalAchievement :one
+SELECT achieved_at
+FROM goal_achievements
+WHERE goal_id = $1 AND user_id = $2 AND period_start = $3;
+
+-- name: ListGoalAchievementsByUser :many
+SELECT goal_id, period_start, achieved_at
+FROM goal_achievements
+WHERE user_id = sqlc.arg(user_id) AND goal_id = ANY(sqlc.arg(goal_ids)::uuid[])
+ORDER BY goal_id, period_start;
+
+-- name: CreateGoalAchievement :execrows
+INSERT INTO goal_achievements (goal_id, user_id, period_start)
+VALUES ($1, $2, $3)
+ON CONFLICT DO NOTHING;
+
+-- name: ListGoalStandings :many
+SELECT gp.user_id, u.username, gp.joined_at, ga.achieved_at,
+       (SELECT COUNT(*)
+        FROM library_completions lc
+        JOIN user_library_items uli ON uli.id = lc.library_item_id
+        JOIN sources s ON s.id = uli.source_id
+        WHERE lc.user_id = gp.user_id
+          AND lc.completed_at >= sqlc.arg(window_start) AND lc.completed_at < sqlc.arg(window_end)
+          AND (sqlc.narg(source_type)::text IS NULL OR s.type = sqlc.narg(source_type)::text)) AS completed
+FROM goal_participants gp
+JOIN users u ON u.id = gp.user_id
+LEFT JOIN goal_achievements ga ON ga.goal_id = gp.goal_id AND ga.user_id = gp.user_id AND ga.period_start = sqlc.arg(period_start)
+WHERE gp.goal_id = sqlc.arg(goal_id)
+ORDER BY completed DESC, gp.joined_at
+LIMIT sqlc.arg('limit') OFFSET sqlc.arg('offset');
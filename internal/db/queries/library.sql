@@ -1,60 +1,137 @@
 -- name: CreateLibraryItem :one
 INSERT INTO user_library_items (id, user_id, source_id, status, progress_value, progress_unit, visibility, started_at, completed_at)
 VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9)
-RETURNING id, user_id, source_id, status, progress_value, progress_unit, visibility, started_at, completed_at, created_at, updated_at;
+RETURNING id, user_id, source_id, status, progress_value, progress_unit, visibility, started_at, completed_at, created_at, updated_at, reread_count;
 
 -- name: GetLibraryItemByID :one
-SELECT id, user_id, source_id, status, progress_value, progress_unit, visibility, started_at, completed_at, created_at, updated_at
+SELECT id, user_id, source_id, status, progress_value, progress_unit, visibility, started_at, completed_at, created_at, updated_at, reread_count
 FROM user_library_items
 WHERE id = $1
 LIMIT 1;
 
--- name: ListLibraryItemsByUser :many
-SELECT id, user_id, source_id, status, progress_value, progress_unit, visibility, started_at, completed_at, created_at, updated_at
-FROM user_library_items
-WHERE user_id = $1
-ORDER BY created_at DESC
+-- name: ListLibraryItemsByUserWithSources :many
+SELECT uli.id, uli.user_id, uli.source_id, uli.status, uli.progress_value, uli.progress_unit, uli.visibility, uli.started_at, uli.completed_at, uli.created_at, uli.updated_at, uli.reread_count,
+       s.title, s.subtitle, s.type, s.publisher, s.isbn
+FROM user_library_items uli
+JOIN sources s ON s.id = uli.source_id
+WHERE uli.user_id = $1
+ORDER BY uli.created_at DESC
 LIMIT $2 OFFSET $3;
 
--- name: ListPublicLibraryItemsByUser :many
-SELECT id, user_id, source_id, status, progress_value, progress_unit, visibility, started_at, completed_at, created_at, updated_at
-FROM user_library_items
-WHERE user_id = $1 AND visibility = 'public'
-ORDER BY created_at DESC
-LIMIT $2 OFFSET $3;
+-- name: ListLibraryItemsNewest :many
+SELECT uli.id, uli.user_id, uli.source_id, uli.status, uli.progress_value, uli.progress_unit, uli.visibility, uli.started_at, uli.completed_at, uli.created_at, uli.updated_at, uli.reread_count
+FROM user_library_items uli
+WHERE (sqlc.narg(user_id)::uuid IS NULL OR uli.user_id = sqlc.narg(user_id)::uuid)
+  AND (sqlc.narg(username)::text IS NULL OR uli.user_id = (SELECT u.id FROM users u WHERE u.username = sqlc.narg(username)::text))
+  AND (NOT sqlc.arg(public_only)::bool OR uli.visibility = 'public')
+  AND (sqlc.narg(status)::text IS NULL OR uli.status = sqlc.narg(status)::text)
+  AND (sqlc.narg(tag)::text IS NULL OR EXISTS (
+    SELECT 1 FROM library_item_tags lit JOIN tags t ON t.id = lit.tag_id
+    WHERE lit.library_item_id = uli.id AND t.slug = sqlc.narg(tag)::text))
+  AND (uli.created_at, uli.id) < (sqlc.arg(after_created_at)::timestamptz, sqlc.arg(after_id)::uuid)
+ORDER BY uli.created_at DESC, uli.id DESC
+LIMIT sqlc.arg('limit');
 
--- name: ListPublicLibraryItemsByUsername :many
-SELECT uli.id, uli.user_id, uli.source_id, uli.status, uli.progress_value, uli.progress_unit, uli.visibility, uli.started_at, uli.completed_at, uli.created_at, uli.updated_at
+-- name: ListLibraryItemsOldest :many
+SELECT uli.id, uli.user_id, uli.source_id, uli.status, uli.progress_value, uli.progress_unit, uli.visibility, uli.started_at, uli.completed_at, uli.created_at, uli.updated_at, uli.reread_count
 FROM user_library_items uli
-JOIN users u ON u.id = uli.user_id
-WHERE u.username = $1 AND uli.visibility = 'public'
-ORDER BY uli.created_at DESC
-LIMIT $2 OFFSET $3;
+WHERE (sqlc.narg(user_id)::uuid IS NULL OR uli.user_id = sqlc.narg(user_id)::uuid)
+  AND (sqlc.narg(username)::text IS NULL OR uli.user_id = (SELECT u.id FROM users u WHERE u.username = sqlc.narg(username)::text))
+  AND (NOT sqlc.arg(public_only)::bool OR uli.visibility = 'public')
+  AND (sqlc.narg(status)::text IS NULL OR uli.status = sqlc.narg(status)::text)
+  AND (sqlc.narg(tag)::text IS NULL OR EXISTS (
+    SELECT 1 FROM library_item_tags lit JOIN tags t ON t.id = lit.tag_id
+    WHERE lit.library_item_id = uli.id AND t.slug = sqlc.narg(tag)::text))
+  AND (uli.created_at, uli.id) > (sqlc.arg(after_created_at)::timestamptz, sqlc.arg(after_id)::uuid)
+ORDER BY uli.created_at ASC, uli.id ASC
+LIMIT sqlc.arg('limit');
 
--- name: ListLibraryItemsByUserWithSources :many
-SELECT uli.id, uli.user_id, uli.source_id, uli.status, uli.progress_value, uli.progress_unit, uli.visibility, uli.started_at, uli.completed_at, uli.created_at, uli.updated_at,
+-- name: ListLibraryItemsWithSourcesNewest :many
+SELECT uli.id, uli.user_id, uli.source_id, uli.status, uli.progress_value, uli.progress_unit, uli.visibility, uli.started_at, uli.completed_at, uli.created_at, uli.updated_at, uli.reread_count,
        s.title, s.subtitle, s.type, s.publisher, s.isbn
 FROM user_library_items uli
 JOIN sources s ON s.id = uli.source_id
-WHERE uli.user_id = $1
-ORDER BY uli.created_at DESC
-LIMIT $2 OFFSET $3;
+WHERE (sqlc.narg(user_id)::uuid IS NULL OR uli.user_id = sqlc.narg(user_id)::uuid)
+  AND (sqlc.narg(username)::text IS NULL OR uli.user_id = (SELECT u.id FROM users u WHERE u.username = sqlc.narg(username)::text))
+  AND (NOT sqlc.arg(public_only)::bool OR uli.visibility = 'public')
+  AND (sqlc.narg(status)::text IS NULL OR uli.status = sqlc.narg(status)::text)
+  AND (sqlc.narg(tag)::text IS NULL OR EXISTS (
+    SELECT 1 FROM library_item_tags lit JOIN tags t ON t.id = lit.tag_id
+    WHERE lit.library_item_id = uli.id AND t.slug = sqlc.narg(tag)::text))
+  AND (uli.created_at, uli.id) < (sqlc.arg(after_created_at)::timestamptz, sqlc.arg(after_id)::uuid)
+ORDER BY uli.created_at DESC, uli.id DESC
+LIMIT sqlc.arg('limit');
 
--- name: ListPublicLibraryItemsByUsernameWithSources :many
-SELECT uli.id, uli.user_id, uli.source_id, uli.status, uli.progress_value, uli.progress_unit, uli.visibility, uli.started_at, uli.completed_at, uli.created_at, uli.updated_at,
+-- name: ListLibraryItemsWithSourcesOldest :many
+SELECT uli.id, uli.user_id, uli.source_id, uli.status, uli.progress_value, uli.progress_unit, uli.visibility, uli.started_at, uli.completed_at, uli.created_at, uli.updated_at, uli.reread_count,
        s.title, s.subtitle, s.type, s.publisher, s.isbn
 FROM user_library_items uli
-JOIN users u ON u.id = uli.user_id
 JOIN sources s ON s.id = uli.source_id
-WHERE u.username = $1 AND uli.visibility = 'public'
-ORDER BY uli.created_at DESC
-LIMIT $2 OFFSET $3;
+WHERE (sqlc.narg(user_id)::uuid IS NULL OR uli.user_id = sqlc.narg(user_id)::uuid)
+  AND (sqlc.narg(username)::text IS NULL OR uli.user_id = (SELECT u.id FROM users u WHERE u.username = sqlc.narg(username)::text))
+  AND (NOT sqlc.arg(public_only)::bool OR uli.visibility = 'public')
+  AND (sqlc.narg(status)::text IS NULL OR uli.status = sqlc.narg(status)::text)
+  AND (sqlc.narg(tag)::text IS NULL OR EXISTS (
+    SELECT 1 FROM library_item_tags lit JOIN tags t ON t.id = lit.tag_id
+    WHERE lit.library_item_id = uli.id AND t.slug = sqlc.narg(tag)::text))
+  AND (uli.created_at, uli.id) > (sqlc.arg(after_created_at)::timestamptz, sqlc.arg(after_id)::uuid)
+ORDER BY uli.created_at ASC, uli.id ASC
+LIMIT sqlc.arg('limit');
+
+-- name: CountLibraryItems :one
+SELECT COUNT(*)
+FROM user_library_items uli
+WHERE (sqlc.narg(user_id)::uuid IS NULL OR uli.user_id = sqlc.narg(user_id)::uuid)
+  AND (sqlc.narg(username)::text IS NULL OR uli.user_id = (SELECT u.id FROM users u WHERE u.username = sqlc.narg(username)::text))
+  AND (NOT sqlc.arg(public_only)::bool OR uli.visibility = 'public')
+  AND (sqlc.narg(status)::text IS NULL OR uli.status = sqlc.narg(status)::text)
+  AND (sqlc.narg(tag)::text IS NULL OR EXISTS (
+    SELECT 1 FROM library_item_tags lit JOIN tags t ON t.id = lit.tag_id
+    WHERE lit.library_item_id = uli.id AND t.slug = sqlc.narg(tag)::text));
 
 -- name: UpdateLibraryItem :one
 UPDATE user_library_items
-SET status = $2, progress_value = $3, progress_unit = $4, visibility = $5, started_at = $6, completed_at = $7, updated_at = NOW()
+SET status = $2, progress_value = $3, progress_unit = $4, visibility = $5, started_at = $6, completed_at = $7, reread_count = $8, updated_at = NOW()
 WHERE id = $1
-RETURNING id, user_id, source_id, status, progress_value, progress_unit, visibility, started_at, completed_at, created_at, updated_at;
+RETURNING id, user_id, source_id, status, progress_value, progress_unit, visibility, started_at, completed_at, created_at, updated_at, reread_count;
 
--- name: DeleteLibraryItem :exec
+-- name: DeleteLibraryItem :execrows
 DELETE FROM user_library_items WHERE id = $1;
+
+-- name: GetSourcePageCount :one
+SELECT page_count FROM book_metadata WHERE source_id = $1;
+
+-- name: CreateLibraryItemCompletion :one
+INSERT INTO library_item_completions (id, library_item_id, user_id, started_at, completed_at)
+VALUES ($1, $2, $3, $4, $5)
+RETURNING id, library_item_id, user_id, started_at, completed_at, created_at;
+
+-- name: ListLibraryItemCompletions :many
+SELECT id, library_item_id, user_id, started_at, completed_at, created_at
+FROM library_item_completions
+WHERE library_item_id = $1
+ORDER BY completed_at DESC
+LIMIT $2 OFFSET $3;
+
+-- name: ListLibraryItemCompletionsByUser :many
+SELECT id, library_item_id, user_id, started_at, completed_at, created_at
+FROM library_item_completions
+WHERE user_id = $1
+ORDER BY completed_at DESC
+LIMIT $2 OFFSET $3;
+
+-- name: ListTagsByLibraryItems :many
+SELECT lit.library_item_id, t.name
+FROM library_item_tags lit
+JOIN tags t ON t.id = lit.tag_id
+WHERE lit.library_item_id = ANY(sqlc.arg(library_item_ids)::uuid[])
+ORDER BY lit.library_item_id, lit.position, t.slug;
+
+-- name: DeleteLibraryItemTags :exec
+DELETE FROM library_item_tags WHERE library_item_id = $1;
+
+-- name: InsertLibraryItemTags :exec
+INSERT INTO library_item_tags (library_item_id, tag_id, position)
+SELECT sqlc.arg(library_item_id)::uuid, tag.id, tag.position - 1
+FROM unnest(sqlc.arg(tag_ids)::uuid[]) WITH ORDINALITY AS tag(id, position)
+ON CONFLICT DO NOTHING;
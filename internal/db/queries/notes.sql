@@ -1,6 +1,6 @@
 -- name: CreateNote :one
-INSERT INTO notes (id, user_id, source_id, content, content_type, is_public, annotations, tags)
-VALUES ($1, $2, $3, $4, $5, $6, $7, $8)
+INSERT INTO notes (id, user_id, source_id, content, content_type, is_public, annotations, anchor, location_key)
+VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9)
 RETURNING *;
 
 -- name: GetNoteByID :one
@@ -16,42 +16,86 @@ WHERE user_id = $1
 ORDER BY created_at DESC
 LIMIT $2 OFFSET $3;
 
--- name: ListPublicNotesByUser :many
+-- name: ListNotesBySource :many
 SELECT *
 FROM notes
-WHERE user_id = $1 AND is_public = true
+WHERE source_id = $1
 ORDER BY created_at DESC
 LIMIT $2 OFFSET $3;
 
--- name: ListNotesBySource :many
+-- name: ListNotesNewest :many
 SELECT *
 FROM notes
-WHERE source_id = $1
-ORDER BY created_at DESC
-LIMIT $2 OFFSET $3;
+WHERE (sqlc.narg(user_id)::uuid IS NULL OR user_id = sqlc.narg(user_id)::uuid)
+  AND (sqlc.narg(source_id)::uuid IS NULL OR source_id = sqlc.narg(source_id)::uuid)
+  AND (NOT sqlc.arg(public_only)::bool OR is_public = true)
+  AND (sqlc.narg(content_type)::text IS NULL OR content_type = sqlc.narg(content_type)::text)
+  AND (sqlc.narg(tag)::text IS NULL OR EXISTS (
+    SELECT 1 FROM note_tags nt JOIN tags t ON t.id = nt.tag_id
+    WHERE nt.note_id = notes.id AND t.slug = sqlc.narg(tag)::text))
+  AND (created_at, id) < (sqlc.arg(after_created_at)::timestamptz, sqlc.arg(after_id)::uuid)
+ORDER BY created_at DESC, id DESC
+LIMIT sqlc.arg('limit');
 
--- name: ListPublicNotesBySource :many
+-- name: ListNotesOldest :many
 SELECT *
 FROM notes
-WHERE source_id = $1 AND is_public = true
-ORDER BY created_at DESC
-LIMIT $2 OFFSET $3;
+WHERE (sqlc.narg(user_id)::uuid IS NULL OR user_id = sqlc.narg(user_id)::uuid)
+  AND (sqlc.narg(source_id)::uuid IS NULL OR source_id = sqlc.narg(source_id)::uuid)
+  AND (NOT sqlc.arg(public_only)::bool OR is_public = true)
+  AND (sqlc.narg(content_type)::text IS NULL OR content_type = sqlc.narg(content_type)::text)
+  AND (sqlc.narg(tag)::text IS NULL OR EXISTS (
+    SELECT 1 FROM note_tags nt JOIN tags t ON t.id = nt.tag_id
+    WHERE nt.note_id = notes.id AND t.slug = sqlc.narg(tag)::text))
+  AND (created_at, id) > (sqlc.arg(after_created_at)::timestamptz, sqlc.arg(after_id)::uuid)
+ORDER BY created_at ASC, id ASC
+LIMIT sqlc.arg('limit');
 
--- name: ListPublicNotes :many
+-- name: ListNotesByLocation :many
 SELECT *
 FROM notes
-WHERE is_public = true
-ORDER BY created_at DESC
-LIMIT $1 OFFSET $2;
+WHERE source_id = sqlc.arg(source_id)
+  AND (sqlc.narg(user_id)::uuid IS NULL OR user_id = sqlc.narg(user_id)::uuid)
+  AND (NOT sqlc.arg(public_only)::bool OR is_public = true)
+  AND (sqlc.narg(content_type)::text IS NULL OR content_type = sqlc.narg(content_type)::text)
+ORDER BY location_key ASC NULLS LAST, created_at ASC, id ASC
+LIMIT sqlc.arg('limit') OFFSET sqlc.arg('offset');
+
+-- name: CountNotes :one
+SELECT COUNT(*)
+FROM notes
+WHERE (sqlc.narg(user_id)::uuid IS NULL OR user_id = sqlc.narg(user_id)::uuid)
+  AND (sqlc.narg(source_id)::uuid IS NULL OR source_id = sqlc.narg(source_id)::uuid)
+  AND (NOT sqlc.arg(public_only)::bool OR is_public = true)
+  AND (sqlc.narg(content_type)::text IS NULL OR content_type = sqlc.narg(content_type)::text)
+  AND (sqlc.narg(tag)::text IS NULL OR EXISTS (
+    SELECT 1 FROM note_tags nt JOIN tags t ON t.id = nt.tag_id
+    WHERE nt.note_id = notes.id AND t.slug = sqlc.narg(tag)::text));
 
 -- name: CountNotesByUser :one
 SELECT COUNT(*) FROM notes WHERE user_id = $1;
 
+-- name: ListTagsByNotes :many
+SELECT nt.note_id, t.name
+FROM note_tags nt
+JOIN tags t ON t.id = nt.tag_id
+WHERE nt.note_id = ANY(sqlc.arg(note_ids)::uuid[])
+ORDER BY nt.note_id, nt.position, t.slug;
+
+-- name: DeleteNoteTags :exec
+DELETE FROM note_tags WHERE note_id = $1;
+
+-- name: InsertNoteTags :exec
+INSERT INTO note_tags (note_id, tag_id, position)
+SELECT sqlc.arg(note_id)::uuid, tag.id, tag.position - 1
+FROM unnest(sqlc.arg(tag_ids)::uuid[]) WITH ORDINALITY AS tag(id, position)
+ON CONFLICT DO NOTHING;
+
 -- name: UpdateNote :one
 UPDATE notes
-SET source_id = $2, content = $3, content_type = $4, is_public = $5, annotations = $6, tags = $7, updated_at = NOW()
+SET source_id = $2, content = $3, content_type = $4, is_public = $5, annotations = $6, anchor = $7, location_key = $8, updated_at = NOW()
 WHERE id = $1
 RETURNING *;
 
--- name: DeleteNote :exec
+-- name: DeleteNote :execrows
 DELETE FROM notes WHERE id = $1;
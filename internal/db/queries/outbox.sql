@@ -0,0 +1,24 @@
+-- name: InsertOutboxEvent :exec
+INSERT INTO outbox (id, aggregate_type, aggregate_id, event_type, payload)
+VALUES ($1, $2, $3, $4, $5);
+
+-- name: ClaimOutboxEvents :many
+SELECT id, aggregate_type, aggregate_id, event_type, payload, attempts, created_at
+FROM outbox
+WHERE published = false AND dead_at IS NULL AND next_attempt_at <= NOW()
+ORDER BY created_at ASC, id ASC
+LIMIT $1
+FOR UPDATE SKIP LOCKED;
+
+-- name: MarkOutboxEventsPublished :exec
+UPDATE outbox
+SET published = true, published_at = NOW()
+WHERE id = ANY(sqlc.arg(ids)::uuid[]);
+
+-- name: MarkOutboxEventFailed :exec
+UPDATE outbox
+SET attempts = attempts + 1,
+    last_error = sqlc.arg(last_error),
+    next_attempt_at = sqlc.arg(next_attempt_at),
+    dead_at = CASE WHEN sqlc.arg(dead)::bool THEN NOW() END
+WHERE id = sqlc.arg(id);
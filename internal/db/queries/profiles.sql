@@ -1,11 +1,11 @@
 -- name: GetProfileByUserID :one
-SELECT id, user_id, display_name, bio, public_profile, created_at, updated_at
+SELECT id, user_id, display_name, bio, public_profile, created_at, updated_at, public_year_in_review
 FROM profiles
 WHERE user_id = $1
 LIMIT 1;
 
 -- name: GetPublicProfileByUsername :one
-SELECT p.id, p.user_id, u.username, p.display_name, p.bio, p.public_profile, p.created_at, p.updated_at
+SELECT p.id, p.user_id, u.username, p.display_name, p.bio, p.public_profile, p.public_year_in_review, p.created_at, p.updated_at
 FROM profiles p
 JOIN users u ON u.id = p.user_id
 WHERE u.username = $1 AND p.public_profile = true
@@ -13,15 +13,16 @@ LIMIT 1;
 
 -- name: UpsertProfile :one
 WITH upserted AS (
-    INSERT INTO profiles (id, user_id, display_name, bio, public_profile)
-    VALUES ($1, $2, $3, $4, $5)
+    INSERT INTO profiles (id, user_id, display_name, bio, public_profile, public_year_in_review)
+    VALUES ($1, $2, $3, $4, $5, $6)
     ON CONFLICT (user_id) DO UPDATE SET
         display_name = EXCLUDED.display_name,
         bio = EXCLUDED.bio,
         public_profile = EXCLUDED.public_profile,
+        public_year_in_review = EXCLUDED.public_year_in_review,
         updated_at = NOW()
-    RETURNING id, user_id, display_name, bio, public_profile, created_at, updated_at
+    RETURNING id, user_id, display_name, bio, public_profile, created_at, updated_at, public_year_in_review
 )
-SELECT p.id, p.user_id, u.username, p.display_name, p.bio, p.public_profile, p.created_at, p.updated_at
+SELECT p.id, p.user_id, u.username, p.display_name, p.bio, p.public_profile, p.public_year_in_review, p.created_at, p.updated_at
 FROM upserted p
 JOIN users u ON u.id = p.user_id;
@@ -0,0 +1,25 @@
+-- name: CreateReadingSession :one
+INSERT INTO reading_sessions (id, library_item_id, user_id, started_at, ended_at, progress_from, progress_to, progress_unit, device)
+VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9)
+RETURNING id, library_item_id, user_id, started_at, ended_at, progress_from, progress_to, progress_unit, device, created_at;
+
+-- name: GetLatestReadingSession :one
+SELECT id, library_item_id, user_id, started_at, ended_at, progress_from, progress_to, progress_unit, device, created_at
+FROM reading_sessions
+WHERE library_item_id = $1
+ORDER BY started_at DESC, id DESC
+LIMIT 1;
+
+-- name: ListReadingSessionsByItem :many
+SELECT id, library_item_id, user_id, started_at, ended_at, progress_from, progress_to, progress_unit, device, created_at
+FROM reading_sessions
+WHERE library_item_id = $1
+ORDER BY started_at DESC, id DESC
+LIMIT $2 OFFSET $3;
+
+-- name: ListReadingSessionsByUser :many
+SELECT id, library_item_id, user_id, started_at, ended_at, progress_from, progress_to, progress_unit, device, created_at
+FROM reading_sessions
+WHERE user_id = $1
+ORDER BY started_at DESC, id DESC
+LIMIT $2 OFFSET $3;
@@ -16,26 +16,45 @@ WHERE user_id = $1
 ORDER BY created_at DESC
 LIMIT $2 OFFSET $3;
 
--- name: ListPublicReviewsByUser :many
+-- name: ListReviewsBySource :many
 SELECT id, user_id, source_id, rating, content, is_public, created_at, updated_at
 FROM reviews
-WHERE user_id = $1 AND is_public = true
+WHERE source_id = $1
 ORDER BY created_at DESC
 LIMIT $2 OFFSET $3;
 
--- name: ListReviewsBySource :many
+-- name: ListReviewsNewest :many
 SELECT id, user_id, source_id, rating, content, is_public, created_at, updated_at
 FROM reviews
-WHERE source_id = $1
-ORDER BY created_at DESC
-LIMIT $2 OFFSET $3;
+WHERE (sqlc.narg(user_id)::uuid IS NULL OR user_id = sqlc.narg(user_id)::uuid)
+  AND (sqlc.narg(source_id)::uuid IS NULL OR source_id = sqlc.narg(source_id)::uuid)
+  AND (NOT sqlc.arg(public_only)::bool OR is_public = true)
+  AND (sqlc.narg(min_rating)::int IS NULL OR rating >= sqlc.narg(min_rating)::int)
+  AND (sqlc.narg(max_rating)::int IS NULL OR rating <= sqlc.narg(max_rating)::int)
+  AND (created_at, id) < (sqlc.arg(after_created_at)::timestamptz, sqlc.arg(after_id)::uuid)
+ORDER BY created_at DESC, id DESC
+LIMIT sqlc.arg('limit');
 
--- name: ListPublicReviewsBySource :many
+-- name: ListReviewsOldest :many
 SELECT id, user_id, source_id, rating, content, is_public, created_at, updated_at
 FROM reviews
-WHERE source_id = $1 AND is_public = true
-ORDER BY created_at DESC
-LIMIT $2 OFFSET $3;
+WHERE (sqlc.narg(user_id)::uuid IS NULL OR user_id = sqlc.narg(user_id)::uuid)
+  AND (sqlc.narg(source_id)::uuid IS NULL OR source_id = sqlc.narg(source_id)::uuid)
+  AND (NOT sqlc.arg(public_only)::bool OR is_public = true)
+  AND (sqlc.narg(min_rating)::int IS NULL OR rating >= sqlc.narg(min_rating)::int)
+  AND (sqlc.narg(max_rating)::int IS NULL OR rating <= sqlc.narg(max_rating)::int)
+  AND (created_at, id) > (sqlc.arg(after_created_at)::timestamptz, sqlc.arg(after_id)::uuid)
+ORDER BY created_at ASC, id ASC
+LIMIT sqlc.arg('limit');
+
+-- name: CountReviews :one
+SELECT COUNT(*)
+FROM reviews
+WHERE (sqlc.narg(user_id)::uuid IS NULL OR user_id = sqlc.narg(user_id)::uuid)
+  AND (sqlc.narg(source_id)::uuid IS NULL OR source_id = sqlc.narg(source_id)::uuid)
+  AND (NOT sqlc.arg(public_only)::bool OR is_public = true)
+  AND (sqlc.narg(min_rating)::int IS NULL OR rating >= sqlc.narg(min_rating)::int)
+  AND (sqlc.narg(max_rating)::int IS NULL OR rating <= sqlc.narg(max_rating)::int);
 
 -- name: UpdateReview :one
 UPDATE reviews
@@ -43,5 +62,5 @@ SET rating = $2, content = $3, is_public = $4, updated_at = NOW()
 WHERE id = $1
 RETURNING id, user_id, source_id, rating, content, is_public, created_at, updated_at;
 
--- name: DeleteReview :exec
+-- name: DeleteReview :execrows
 DELETE FROM reviews WHERE id = $1;
@@ -0,0 +1,59 @@
+-- name: GetArticleMetadata :one
+SELECT source_id, site, word_count, created_at, updated_at
+FROM article_metadata
+WHERE source_id = $1
+LIMIT 1;
+
+-- name: UpsertArticleMetadata :exec
+INSERT INTO article_metadata (source_id, site, word_count)
+VALUES ($1, $2, $3)
+ON CONFLICT (source_id) DO UPDATE
+SET site = EXCLUDED.site, word_count = EXCLUDED.word_count, updated_at = NOW();
+
+-- name: DeleteArticleMetadata :exec
+DELETE FROM article_metadata WHERE source_id = $1;
+
+-- name: GetPaperMetadata :one
+SELECT source_id, venue, arxiv_id, created_at, updated_at
+FROM paper_metadata
+WHERE source_id = $1
+LIMIT 1;
+
+-- name: UpsertPaperMetadata :exec
+INSERT INTO paper_metadata (source_id, venue, arxiv_id)
+VALUES ($1, $2, $3)
+ON CONFLICT (source_id) DO UPDATE
+SET venue = EXCLUDED.venue, arxiv_id = EXCLUDED.arxiv_id, updated_at = NOW();
+
+-- name: DeletePaperMetadata :exec
+DELETE FROM paper_metadata WHERE source_id = $1;
+
+-- name: GetPodcastMetadata :one
+SELECT source_id, show, episode_number, duration_seconds, feed_url, created_at, updated_at
+FROM podcast_metadata
+WHERE source_id = $1
+LIMIT 1;
+
+-- name: UpsertPodcastMetadata :exec
+INSERT INTO podcast_metadata (source_id, show, episode_number, duration_seconds, feed_url)
+VALUES ($1, $2, $3, $4, $5)
+ON CONFLICT (source_id) DO UPDATE
+SET show = EXCLUDED.show, episode_number = EXCLUDED.episode_number, duration_seconds = EXCLUDED.duration_seconds, feed_url = EXCLUDED.feed_url, updated_at = NOW();
+
+-- name: DeletePodcastMetadata :exec
+DELETE FROM podcast_metadata WHERE source_id = $1;
+
+-- name: GetVideoMetadata :one
+SELECT source_id, channel, duration_seconds, platform, platform_id, created_at, updated_at
+FROM video_metadata
+WHERE source_id = $1
+LIMIT 1;
+
+-- name: UpsertVideoMetadata :exec
+INSERT INTO video_metadata (source_id, channel, duration_seconds, platform, platform_id)
+VALUES ($1, $2, $3, $4, $5)
+ON CONFLICT (source_id) DO UPDATE
+SET channel = EXCLUDED.channel, duration_seconds = EXCLUDED.duration_seconds, platform = EXCLUDED.platform, platform_id = EXCLUDED.platform_id, updated_at = NOW();
+
+-- name: DeleteVideoMetadata :exec
+DELETE FROM video_metadata WHERE source_id = $1;
@@ -1,12 +1,12 @@
 -- name: CreateSource :one
-INSERT INTO sources (id, title, subtitle, type, description, publisher, isbn, doi, url, external_id, tags, published_at)
+INSERT INTO sources (id, title, subtitle, type, description, publisher, isbn, doi, url, external_id, published_at, created_by)
 VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9, $10, $11, $12)
-RETURNING id, title, subtitle, type, description, publisher, isbn, doi, url, external_id, tags, published_at, created_at, updated_at;
+RETURNING id, title, subtitle, type, description, publisher, isbn, doi, url, external_id, published_at, created_at, updated_at, created_by;
 
 -- name: InsertBookSource :one
-INSERT INTO sources (id, title, subtitle, type, description, publisher, isbn, url, external_id, tags, published_at)
-VALUES ($1, $2, $3, 'book', $4, $5, COALESCE($6, $7), $8, $9, $10, $11)
-RETURNING id, title, subtitle, type, description, publisher, isbn, doi, url, external_id, tags, published_at, created_at, updated_at;
+INSERT INTO sources (id, title, subtitle, type, description, publisher, isbn, doi, url, external_id, published_at, created_by)
+VALUES ($1, $2, $3, 'book', $4, $5, COALESCE($6, $7), $8, $9, $10, $11, $12)
+RETURNING id, title, subtitle, type, description, publisher, isbn, doi, url, external_id, published_at, created_at, updated_at, created_by;
 
 -- name: InsertBookMetadata :one
 INSERT INTO book_metadata (source_id, isbn_10, isbn_13, publisher, page_count, language, cover_url)
@@ -25,17 +25,25 @@ VALUES ($1, $2, $3, $4)
 RETURNING contributor_id, sqlc.arg('contributor_name')::text AS name, role, position, NOW()::timestamptz AS created_at, NOW()::timestamptz AS updated_at;
 
 -- name: GetSourceByID :one
-SELECT id, title, subtitle, type, description, publisher, isbn, doi, url, external_id, tags, published_at, created_at, updated_at
+SELECT id, title, subtitle, type, description, publisher, isbn, doi, url, external_id, published_at, created_at, updated_at, created_by
 FROM sources
 WHERE id = $1
 LIMIT 1;
 
 -- name: GetBookSourceByID :one
-SELECT id, title, subtitle, type, description, publisher, isbn, doi, url, external_id, tags, published_at, created_at, updated_at
+SELECT id, title, subtitle, type, description, publisher, isbn, doi, url, external_id, published_at, created_at, updated_at, created_by
 FROM sources
 WHERE id = $1 AND type = 'book'
 LIMIT 1;
 
+-- name: FindBookByISBN :one
+SELECT s.id, s.title, s.subtitle, s.type, s.description, s.publisher, s.isbn, s.doi, s.url, s.external_id, s.published_at, s.created_at, s.updated_at, s.created_by
+FROM sources s
+JOIN book_metadata bm ON bm.source_id = s.id
+WHERE bm.isbn_13 = sqlc.narg(isbn_13)::text OR bm.isbn_10 = sqlc.narg(isbn_10)::text
+ORDER BY (bm.isbn_13 = sqlc.narg(isbn_13)::text) IS TRUE DESC, s.created_at ASC
+LIMIT 1;
+
 -- name: GetBookMetadata :one
 SELECT source_id, isbn_10, isbn_13, publisher, page_count, language, cover_url, created_at, updated_at
 FROM book_metadata
@@ -49,35 +57,302 @@ JOIN contributors c ON c.id = sc.contributor_id
 WHERE sc.source_id = $1
 ORDER BY sc.position ASC, c.name ASC;
 
--- name: ListSources :many
-SELECT id, title, subtitle, type, description, publisher, isbn, doi, url, external_id, tags, published_at, created_at, updated_at
+-- name: ListTagsBySources :many
+SELECT st.source_id, t.name
+FROM source_tags st
+JOIN tags t ON t.id = st.tag_id
+WHERE st.source_id = ANY(sqlc.arg(source_ids)::uuid[])
+ORDER BY st.source_id, st.position, t.slug;
+
+-- name: DeleteSourceTags :exec
+DELETE FROM source_tags WHERE source_id = $1;
+
+-- name: InsertSourceTags :exec
+INSERT INTO source_tags (source_id, tag_id, position)
+SELECT sqlc.arg(source_id)::uuid, tag.id, tag.position - 1
+FROM unnest(sqlc.arg(tag_ids)::uuid[]) WITH ORDINALITY AS tag(id, position)
+ON CONFLICT DO NOTHING;
+
+-- name: ListSourcesNewest :many
+SELECT id, title, subtitle, type, description, publisher, isbn, doi, url, external_id, published_at, created_at, updated_at, created_by
 FROM sources
-ORDER BY created_at DESC
-LIMIT $1 OFFSET $2;
+WHERE (sqlc.narg(type)::text IS NULL OR type = sqlc.narg(type)::text)
+  AND (sqlc.narg(tag)::text IS NULL OR EXISTS (
+    SELECT 1 FROM source_tags st JOIN tags t ON t.id = st.tag_id
+    WHERE st.source_id = sources.id AND t.slug = sqlc.narg(tag)::text))
+  AND (created_at, id) < (sqlc.arg(after_created_at)::timestamptz, sqlc.arg(after_id)::uuid)
+ORDER BY created_at DESC, id DESC
+LIMIT sqlc.arg('limit');
 
--- name: ListSourcesByType :many
-SELECT id, title, subtitle, type, description, publisher, isbn, doi, url, external_id, tags, published_at, created_at, updated_at
+-- name: ListSourcesOldest :many
+SELECT id, title, subtitle, type, description, publisher, isbn, doi, url, external_id, published_at, created_at, updated_at, created_by
 FROM sources
-WHERE type = $1
-ORDER BY created_at DESC
-LIMIT $2 OFFSET $3;
+WHERE (sqlc.narg(type)::text IS NULL OR type = sqlc.narg(type)::text)
+  AND (sqlc.narg(tag)::text IS NULL OR EXISTS (
+    SELECT 1 FROM source_tags st JOIN tags t ON t.id = st.tag_id
+    WHERE st.source_id = sources.id AND t.slug = sqlc.narg(tag)::text))
+  AND (created_at, id) > (sqlc.arg(after_created_at)::timestamptz, sqlc.arg(after_id)::uuid)
+ORDER BY created_at ASC, id ASC
+LIMIT sqlc.arg('limit');
+
+-- name: CountSources :one
+SELECT COUNT(*)
+FROM sources
+WHERE (sqlc.narg(type)::text IS NULL OR type = sqlc.narg(type)::text)
+  AND (sqlc.narg(tag)::text IS NULL OR EXISTS (
+    SELECT 1 FROM source_tags st JOIN tags t ON t.id = st.tag_id
+    WHERE st.source_id = sources.id AND t.slug = sqlc.narg(tag)::text));
 
 -- name: UpdateSource :one
 UPDATE sources
 SET title = $2, subtitle = $3, type = $4, description = $5, publisher = $6,
-    isbn = $7, doi = $8, url = $9, external_id = $10, tags = $11, published_at = $12, updated_at = NOW()
+    isbn = $7, doi = $8, url = $9, external_id = $10, published_at = $11, updated_at = NOW()
 WHERE id = $1
-RETURNING id, title, subtitle, type, description, publisher, isbn, doi, url, external_id, tags, published_at, created_at, updated_at;
+RETURNING id, title, subtitle, type, description, publisher, isbn, doi, url, external_id, published_at, created_at, updated_at, created_by;
 
--- name: DeleteSource :exec
+-- name: DeleteSource :execrows
 DELETE FROM sources WHERE id = $1;
 
 -- name: SearchSources :many
-SELECT id, title, subtitle, type, description, publisher, isbn, doi, url, external_id, tags, published_at, created_at, updated_at
-FROM sources
-WHERE title ILIKE '%' || $1 || '%'
-ORDER BY created_at DESC
+WITH search AS (
+    SELECT websearch_to_tsquery('simple', sqlc.arg(query)::text) AS tsquery, lower(sqlc.arg(query)::text) AS term
+)
+SELECT s.id, s.title, s.subtitle, s.type, s.description, s.publisher, s.isbn, s.doi, s.url, s.external_id, s.published_at, s.created_at, s.updated_at, s.created_by,
+       (ts_rank_cd(ss.document, search.tsquery) + word_similarity(search.term, ss.search_text))::real AS rank,
+       ts_headline('simple', concat_ws(' — ', s.title, s.subtitle, s.description), search.tsquery,
+                   'StartSel=<mark>, StopSel=</mark>, MaxWords=35, MinWords=15, MaxFragments=2')::text AS snippet
+FROM sources s
+JOIN source_search ss ON ss.source_id = s.id
+CROSS JOIN search
+LEFT JOIN book_metadata bm ON bm.source_id = s.id
+WHERE (ss.document @@ search.tsquery OR search.term <% ss.search_text)
+  AND (sqlc.narg(type)::text IS NULL OR s.type = sqlc.narg(type)::text)
+  AND (sqlc.narg(tag)::text IS NULL OR EXISTS (
+    SELECT 1 FROM source_tags st JOIN tags t ON t.id = st.tag_id
+    WHERE st.source_id = s.id AND t.slug = sqlc.narg(tag)::text))
+  AND (sqlc.narg(language)::text IS NULL OR lower(bm.language) = lower(sqlc.narg(language)::text))
+  AND (sqlc.narg(year)::int IS NULL OR EXTRACT(YEAR FROM s.published_at AT TIME ZONE 'UTC') = sqlc.narg(year)::int)
+ORDER BY rank DESC, s.created_at DESC
+LIMIT sqlc.arg('limit') OFFSET sqlc.arg('offset');
+
+-- name: CountSourceReferencesByOthers :one
+WITH target AS (
+    SELECT sqlc.arg(source_id)::uuid AS source_id, sqlc.arg(user_id)::uuid AS user_id
+)
+SELECT (
+    (SELECT COUNT(*) FROM user_library_items li, target WHERE li.source_id = target.source_id AND li.user_id <> target.user_id) +
+    (SELECT COUNT(*) FROM notes n, target WHERE n.source_id = target.source_id AND n.user_id <> target.user_id) +
+    (SELECT COUNT(*) FROM reviews r, target WHERE r.source_id = target.source_id AND r.user_id <> target.user_id) +
+    (SELECT COUNT(*) FROM collection_items ci JOIN collections c ON c.id = ci.collection_id, target WHERE ci.source_id = target.source_id AND c.user_id <> target.user_id)
+)::bigint AS reference_count;
+
+-- name: CreateSourceProposal :one
+INSERT INTO source_proposals (id, source_id, proposed_by, changes)
+VALUES ($1, $2, $3, $4)
+RETURNING id, source_id, proposed_by, changes, status, reviewed_by, review_note, reviewed_at, created_at, updated_at;
+
+-- name: GetSourceProposal :one
+SELECT id, source_id, proposed_by, changes, status, reviewed_by, review_note, reviewed_at, created_at, updated_at
+FROM source_proposals
+WHERE id = $1
+LIMIT 1;
+
+-- name: ListSourceProposals :many
+SELECT id, source_id, proposed_by, changes, status, reviewed_by, review_note, reviewed_at, created_at, updated_at
+FROM source_proposals
+WHERE (sqlc.narg(status)::text IS NULL OR status = sqlc.narg(status)::text)
+  AND (sqlc.narg(source_id)::uuid IS NULL OR source_id = sqlc.narg(source_id)::uuid)
+  AND (sqlc.narg(proposed_by)::uuid IS NULL OR proposed_by = sqlc.narg(proposed_by)::uuid)
+ORDER BY created_at ASC
+LIMIT sqlc.arg('limit') OFFSET sqlc.arg('offset');
+
+-- name: ReviewSourceProposal :one
+UPDATE source_proposals
+SET status = $2, reviewed_by = $3, review_note = $4, reviewed_at = NOW(), updated_at = NOW()
+WHERE id = $1 AND status = 'pending'
+RETURNING id, source_id, proposed_by, changes, status, reviewed_by, review_note, reviewed_at, created_at, updated_at;
+
+-- name: LockSource :one
+SELECT id FROM sources WHERE id = $1 FOR UPDATE;
+
+-- name: UpsertBookMetadata :exec
+INSERT INTO book_metadata (source_id, isbn_10, isbn_13, publisher, page_count, language, cover_url)
+VALUES ($1, $2, $3, $4, $5, $6, $7)
+ON CONFLICT (source_id) DO UPDATE
+SET isbn_10 = EXCLUDED.isbn_10, isbn_13 = EXCLUDED.isbn_13, publisher = EXCLUDED.publisher,
+    page_count = EXCLUDED.page_count, language = EXCLUDED.language, cover_url = EXCLUDED.cover_url, updated_at = NOW();
+
+-- name: DeleteBookMetadata :exec
+DELETE FROM book_metadata WHERE source_id = $1;
+
+-- name: DeleteSourceContributors :exec
+DELETE FROM source_contributors WHERE source_id = $1;
+
+-- name: CreateSourceRevision :one
+INSERT INTO source_revisions (id, source_id, revision, action, edited_by, proposal_id, reverted_to, snapshot, changes, merged_from)
+VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9, $10)
+RETURNING id, source_id, revision, action, edited_by, proposal_id, reverted_to, snapshot, changes, created_at, merged_from;
+
+-- name: GetLatestSourceRevision :one
+SELECT id, source_id, revision, action, edited_by, proposal_id, reverted_to, snapshot, changes, created_at, merged_from
+FROM source_revisions
+WHERE source_id = $1
+ORDER BY revision DESC
+LIMIT 1;
+
+-- name: GetSourceRevision :one
+SELECT id, source_id, revision, action, edited_by, proposal_id, reverted_to, snapshot, changes, created_at, merged_from
+FROM source_revisions
+WHERE source_id = $1 AND revision = $2
+LIMIT 1;
+
+-- name: ListSourceRevisions :many
+SELECT id, source_id, revision, action, edited_by, proposal_id, reverted_to, snapshot, changes, created_at, merged_from
+FROM source_revisions
+WHERE source_id = $1
+ORDER BY revision DESC
 LIMIT $2 OFFSET $3;
 
--- name: CountSources :one
-SELECT COUNT(*) FROM sources;
+-- name: ListSourcesByIDs :many
+SELECT id, title, subtitle, type, description, publisher, isbn, doi, url, external_id, published_at, created_at, updated_at, created_by
+FROM sources
+WHERE id = ANY(sqlc.arg(ids)::uuid[])
+ORDER BY created_at ASC;
+
+-- name: ListDuplicateSourcePairs :many
+WITH isbns AS (
+    SELECT DISTINCT raw.source_id,
+           CASE
+               WHEN length(raw.value) = 10 THEN substr(raw.value, 1, 9)
+               WHEN raw.value LIKE '978%' THEN substr(raw.value, 4, 9)
+               ELSE raw.value
+           END AS value
+    FROM (
+        SELECT s.id AS source_id, upper(regexp_replace(ids.isbn, '[^0-9Xx]', '', 'g')) AS value
+        FROM sources s
+        LEFT JOIN book_metadata bm ON bm.source_id = s.id
+        CROSS JOIN LATERAL (VALUES (s.isbn), (bm.isbn_10), (bm.isbn_13)) AS ids(isbn)
+        WHERE ids.isbn IS NOT NULL
+    ) raw
+    WHERE length(raw.value) IN (10, 13)
+),
+dois AS (
+    SELECT id AS source_id, lower(regexp_replace(trim(doi), '^(https?://(dx\.)?doi\.org/|doi:)', '', 'i')) AS value
+    FROM sources
+    WHERE doi IS NOT NULL AND trim(doi) <> ''
+),
+contributor_names AS (
+    SELECT sc.source_id, lower(string_agg(c.name, ' ' ORDER BY c.name)) AS names
+    FROM source_contributors sc
+    JOIN contributors c ON c.id = sc.contributor_id
+    GROUP BY sc.source_id
+)
+SELECT a.source_id AS source_id, b.source_id AS duplicate_id, 'isbn'::text AS reason, 1::real AS score
+FROM isbns a
+JOIN isbns b ON b.value = a.value AND b.source_id > a.source_id
+UNION
+SELECT a.source_id, b.source_id, 'doi'::text, 1::real
+FROM dois a
+JOIN dois b ON b.value = a.value AND b.source_id > a.source_id
+UNION
+SELECT a.id, b.id, 'title'::text, similarity(lower(a.title), lower(b.title))::real
+FROM source_search sa
+JOIN source_search sb ON sb.search_text % sa.search_text AND sb.source_id > sa.source_id
+JOIN sources a ON a.id = sa.source_id
+JOIN sources b ON b.id = sb.source_id AND b.type = a.type
+LEFT JOIN contributor_names ca ON ca.source_id = a.id
+LEFT JOIN contributor_names cb ON cb.source_id = b.id
+WHERE similarity(lower(a.title), lower(b.title)) >= 0.7
+  AND (ca.names IS NULL OR cb.names IS NULL OR similarity(ca.names, cb.names) >= 0.5)
+ORDER BY score DESC, source_id, duplicate_id
+LIMIT $1;
+
+-- name: MoveSupersededReadingSessions :execrows
+UPDATE reading_sessions rs
+SET library_item_id = other.id
+FROM user_library_items li
+JOIN user_library_items other ON other.user_id = li.user_id
+WHERE rs.library_item_id = li.id
+  AND ((li.source_id = sqlc.arg(duplicate_id) AND other.source_id = sqlc.arg(survivor_id) AND (li.updated_at > other.updated_at) IS NOT TRUE)
+    OR (li.source_id = sqlc.arg(survivor_id) AND other.source_id = sqlc.arg(duplicate_id) AND li.updated_at < other.updated_at));
+
+-- name: MoveSupersededLibraryItemCompletions :execrows
+UPDATE library_item_completions c
+SET library_item_id = other.id
+FROM user_library_items li
+JOIN user_library_items other ON other.user_id = li.user_id
+WHERE c.library_item_id = li.id
+  AND ((li.source_id = sqlc.arg(duplicate_id) AND other.source_id = sqlc.arg(survivor_id) AND (li.updated_at > other.updated_at) IS NOT TRUE)
+    OR (li.source_id = sqlc.arg(survivor_id) AND other.source_id = sqlc.arg(duplicate_id) AND li.updated_at < other.updated_at));
+
+-- name: DeleteSupersededLibraryItems :execrows
+DELETE FROM user_library_items li
+USING user_library_items other
+WHERE li.user_id = other.user_id
+  AND ((li.source_id = sqlc.arg(duplicate_id) AND other.source_id = sqlc.arg(survivor_id) AND (li.updated_at > other.updated_at) IS NOT TRUE)
+    OR (li.source_id = sqlc.arg(survivor_id) AND other.source_id = sqlc.arg(duplicate_id) AND li.updated_at < other.updated_at));
+
+-- name: MoveLibraryItemsToSource :execrows
+UPDATE user_library_items SET source_id = sqlc.arg(survivor_id) WHERE source_id = sqlc.arg(duplicate_id);
+
+-- name: DeleteSupersededReviews :execrows
+DELETE FROM reviews r
+USING reviews other
+WHERE r.user_id = other.user_id
+  AND ((r.source_id = sqlc.arg(duplicate_id) AND other.source_id = sqlc.arg(survivor_id) AND (r.updated_at > other.updated_at) IS NOT TRUE)
+    OR (r.source_id = sqlc.arg(survivor_id) AND other.source_id = sqlc.arg(duplicate_id) AND r.updated_at < other.updated_at));
+
+-- name: MoveReviewsToSource :execrows
+UPDATE reviews SET source_id = sqlc.arg(survivor_id) WHERE source_id = sqlc.arg(duplicate_id);
+
+-- name: MoveNotesToSource :execrows
+UPDATE notes SET source_id = sqlc.arg(survivor_id) WHERE source_id = sqlc.arg(duplicate_id);
+
+-- name: DeleteSupersededCollectionItems :one
+WITH superseded AS (
+    DELETE FROM collection_items ci
+    USING collection_items other
+    WHERE ci.collection_id = other.collection_id
+      AND ((ci.source_id = sqlc.arg(duplicate_id) AND other.source_id = sqlc.arg(survivor_id) AND ci.position >= other.position)
+        OR (ci.source_id = sqlc.arg(survivor_id) AND other.source_id = sqlc.arg(duplicate_id) AND ci.position > other.position))
+    RETURNING ci.collection_id, ci.position
+), shifted AS (
+    UPDATE collection_items ci
+    SET position = ci.position - 1
+    FROM superseded
+    WHERE ci.collection_id = superseded.collection_id AND ci.position > superseded.position
+)
+SELECT COUNT(*) FROM superseded;
+
+-- name: MoveCollectionItemsToSource :execrows
+UPDATE collection_items SET source_id = sqlc.arg(survivor_id) WHERE source_id = sqlc.arg(duplicate_id);
+
+-- name: CopySourceContributors :exec
+INSERT INTO source_contributors (source_id, contributor_id, role, position)
+SELECT sqlc.arg(survivor_id)::uuid, sc.contributor_id, sc.role,
+       sc.position + (SELECT COALESCE(MAX(position) + 1, 0) FROM source_contributors WHERE source_id = sqlc.arg(survivor_id)::uuid)
+FROM source_contributors sc
+WHERE sc.source_id = sqlc.arg(duplicate_id)
+ON CONFLICT DO NOTHING;
+
+-- name: CopySourceTags :exec
+INSERT INTO source_tags (source_id, tag_id, position)
+SELECT sqlc.arg(survivor_id)::uuid, st.tag_id,
+       st.position + (SELECT COALESCE(MAX(position) + 1, 0) FROM source_tags WHERE source_id = sqlc.arg(survivor_id)::uuid)
+FROM source_tags st
+WHERE st.source_id = sqlc.arg(duplicate_id)
+ON CONFLICT DO NOTHING;
+
+-- name: CopyBookMetadata :exec
+INSERT INTO book_metadata (source_id, isbn_10, isbn_13, publisher, page_count, language, cover_url)
+SELECT sqlc.arg(survivor_id)::uuid, isbn_10, isbn_13, publisher, page_count, language, cover_url
+FROM book_metadata
+WHERE source_id = sqlc.arg(duplicate_id)
+ON CONFLICT (source_id) DO UPDATE
+SET isbn_10 = COALESCE(book_metadata.isbn_10, EXCLUDED.isbn_10),
+    isbn_13 = COALESCE(book_metadata.isbn_13, EXCLUDED.isbn_13),
+    publisher = COALESCE(book_metadata.publisher, EXCLUDED.publisher),
+    page_count = COALESCE(book_metadata.page_count, EXCLUDED.page_count),
+    language = COALESCE(book_metadata.language, EXCLUDED.language),
+    cover_url = COALESCE(book_metadata.cover_url, EXCLUDED.cover_url),
+    updated_at = NOW();
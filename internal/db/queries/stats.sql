@@ -0,0 +1,115 @@
+-- name: ListCompletedByMonth :many
+SELECT EXTRACT(MONTH FROM lc.completed_at AT TIME ZONE 'UTC')::int AS month, COUNT(*) AS completed
+FROM library_completions lc
+JOIN user_library_items uli ON uli.id = lc.library_item_id
+WHERE lc.user_id = sqlc.arg(user_id)
+  AND lc.completed_at >= sqlc.arg(year_start) AND lc.completed_at < sqlc.arg(year_end)
+  AND (NOT sqlc.arg(public_only)::boolean OR uli.visibility = 'public')
+GROUP BY month
+ORDER BY month;
+
+-- name: ListCompletedBySourceType :many
+SELECT s.type, COUNT(*) AS completed
+FROM library_completions lc
+JOIN user_library_items uli ON uli.id = lc.library_item_id
+JOIN sources s ON s.id = uli.source_id
+WHERE lc.user_id = sqlc.arg(user_id)
+  AND lc.completed_at >= sqlc.arg(year_start) AND lc.completed_at < sqlc.arg(year_end)
+  AND (NOT sqlc.arg(public_only)::boolean OR uli.visibility = 'public')
+GROUP BY s.type
+ORDER BY completed DESC, s.type;
+
+-- name: ListTopCompletedContributors :many
+SELECT c.id, c.name, COUNT(*) AS completed
+FROM library_completions lc
+JOIN user_library_items uli ON uli.id = lc.library_item_id
+CROSS JOIN LATERAL (
+    SELECT DISTINCT sc.contributor_id
+    FROM source_contributors sc
+    WHERE sc.source_id = uli.source_id
+) sc
+JOIN contributors c ON c.id = sc.contributor_id
+WHERE lc.user_id = sqlc.arg(user_id)
+  AND lc.completed_at >= sqlc.arg(year_start) AND lc.completed_at < sqlc.arg(year_end)
+  AND (NOT sqlc.arg(public_only)::boolean OR uli.visibility = 'public')
+GROUP BY c.id, c.name
+ORDER BY completed DESC, c.name
+LIMIT sqlc.arg(max_rows);
+
+-- name: ListTopCompletedTags :many
+SELECT t.name AS tag, COUNT(*) AS completed
+FROM library_completions lc
+JOIN user_library_items uli ON uli.id = lc.library_item_id
+JOIN source_tags st ON st.source_id = uli.source_id
+JOIN tags t ON t.id = st.tag_id
+WHERE lc.user_id = sqlc.arg(user_id)
+  AND lc.completed_at >= sqlc.arg(year_start) AND lc.completed_at < sqlc.arg(year_end)
+  AND (NOT sqlc.arg(public_only)::boolean OR uli.visibility = 'public')
+GROUP BY t.id, t.name
+ORDER BY completed DESC, t.name
+LIMIT sqlc.arg(max_rows);
+
+-- name: GetConsumedTotals :one
+WITH logged AS (
+    SELECT rs.library_item_id,
+           SUM(GREATEST(rs.progress_to - rs.progress_from, 0)) FILTER (WHERE rs.progress_unit = 'page') AS pages,
+           SUM(EXTRACT(EPOCH FROM rs.ended_at - rs.started_at) / 60) AS minutes
+    FROM reading_sessions rs
+    JOIN user_library_items uli ON uli.id = rs.library_item_id
+    WHERE rs.user_id = sqlc.arg(user_id)
+      AND rs.started_at >= sqlc.arg(year_start) AND rs.started_at < sqlc.arg(year_end)
+      AND (NOT sqlc.arg(public_only)::boolean OR uli.visibility = 'public')
+    GROUP BY rs.library_item_id
+),
+unlogged AS (
+    SELECT bm.page_count AS pages,
+           COALESCE(pm.duration_seconds, vm.duration_seconds) / 60 AS minutes
+    FROM library_completions lc
+    JOIN user_library_items uli ON uli.id = lc.library_item_id
+    LEFT JOIN book_metadata bm ON bm.source_id = uli.source_id
+    LEFT JOIN podcast_metadata pm ON pm.source_id = uli.source_id
+    LEFT JOIN video_metadata vm ON vm.source_id = uli.source_id
+    WHERE lc.user_id = sqlc.arg(user_id)
+      AND lc.completed_at >= sqlc.arg(year_start) AND lc.completed_at < sqlc.arg(year_end)
+      AND (NOT sqlc.arg(public_only)::boolean OR uli.visibility = 'public')
+      AND NOT EXISTS (SELECT 1 FROM logged WHERE logged.library_item_id = uli.id)
+)
+SELECT (COALESCE((SELECT SUM(pages) FROM logged), 0) + COALESCE((SELECT SUM(pages) FROM unlogged), 0))::bigint AS pages,
+       (COALESCE((SELECT SUM(minutes) FROM logged), 0) + COALESCE((SELECT SUM(minutes) FROM unlogged), 0))::bigint AS minutes;
+
+-- name: GetReviewStats :one
+SELECT COUNT(rating) AS reviews, COALESCE(AVG(rating), 0)::float8 AS average_rating
+FROM reviews
+WHERE user_id = sqlc.arg(user_id)
+  AND created_at >= sqlc.arg(year_start) AND created_at < sqlc.arg(year_end)
+  AND (NOT sqlc.arg(public_only)::boolean OR is_public = true);
+
+-- name: CountNotesInYear :one
+SELECT COUNT(*)
+FROM notes
+WHERE user_id = sqlc.arg(user_id)
+  AND created_at >= sqlc.arg(year_start) AND created_at < sqlc.arg(year_end)
+  AND (NOT sqlc.arg(public_only)::boolean OR is_public = true);
+
+-- name: ListActivityDays :many
+SELECT (rs.started_at AT TIME ZONE 'UTC')::date AS day
+FROM reading_sessions rs
+JOIN user_library_items uli ON uli.id = rs.library_item_id
+WHERE rs.user_id = sqlc.arg(user_id)
+  AND rs.started_at >= sqlc.arg(year_start) AND rs.started_at < sqlc.arg(year_end)
+  AND (NOT sqlc.arg(public_only)::boolean OR uli.visibility = 'public')
+UNION
+SELECT (lc.completed_at AT TIME ZONE 'UTC')::date AS day
+FROM library_completions lc
+JOIN user_library_items uli ON uli.id = lc.library_item_id
+WHERE lc.user_id = sqlc.arg(user_id)
+  AND lc.completed_at >= sqlc.arg(year_start) AND lc.completed_at < sqlc.arg(year_end)
+  AND (NOT sqlc.arg(public_only)::boolean OR uli.visibility = 'public')
+ORDER BY day;
+
+-- name: GetYearInReviewUserID :one
+SELECT u.id
+FROM users u
+JOIN profiles p ON p.user_id = u.id
+WHERE u.username = $1 AND p.public_profile = true AND p.public_year_in_review = true
+LIMIT 1;
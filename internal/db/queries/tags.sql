@@ -0,0 +1,73 @@
+-- name: UpsertTags :many
+INSERT INTO tags (id, name, slug)
+SELECT unnest(sqlc.arg(ids)::uuid[]), unnest(sqlc.arg(names)::text[]), unnest(sqlc.arg(slugs)::text[])
+ON CONFLICT (slug) DO UPDATE SET slug = EXCLUDED.slug
+RETURNING id, slug;
+
+-- name: GetTagByID :one
+SELECT id, name, created_at, updated_at, slug
+FROM tags
+WHERE id = $1
+LIMIT 1;
+
+-- name: GetTagBySlug :one
+SELECT t.id, t.name, t.slug, t.created_at, t.updated_at,
+       (SELECT COUNT(*) FROM source_tags st WHERE st.tag_id = t.id) AS source_count,
+       (SELECT COUNT(*) FROM note_tags nt JOIN notes n ON n.id = nt.note_id WHERE nt.tag_id = t.id AND n.is_public = true) AS note_count
+FROM tags t
+WHERE t.slug = $1
+LIMIT 1;
+
+-- name: ListTags :many
+WITH counted AS (
+    SELECT t.id, t.name, t.slug, t.created_at, t.updated_at,
+           (SELECT COUNT(*) FROM source_tags st WHERE st.tag_id = t.id) AS source_count,
+           (SELECT COUNT(*) FROM note_tags nt JOIN notes n ON n.id = nt.note_id WHERE nt.tag_id = t.id AND n.is_public = true) AS note_count
+    FROM tags t
+    WHERE sqlc.narg(prefix)::text IS NULL OR t.slug LIKE sqlc.narg(prefix)::text || '%' OR t.slug LIKE '% ' || sqlc.narg(prefix)::text || '%'
+)
+SELECT id, name, slug, created_at, updated_at, source_count::bigint AS source_count, note_count::bigint AS note_count
+FROM counted
+WHERE source_count + note_count > 0
+ORDER BY source_count + note_count DESC, slug ASC
+LIMIT sqlc.arg('limit') OFFSET sqlc.arg('offset');
+
+-- name: ListUserTags :many
+SELECT t.id, t.name, t.slug, COUNT(*) AS item_count
+FROM library_item_tags lit
+JOIN user_library_items uli ON uli.id = lit.library_item_id
+JOIN tags t ON t.id = lit.tag_id
+WHERE uli.user_id = sqlc.arg(user_id)
+  AND (sqlc.narg(prefix)::text IS NULL OR t.slug LIKE sqlc.narg(prefix)::text || '%' OR t.slug LIKE '% ' || sqlc.narg(prefix)::text || '%')
+GROUP BY t.id, t.name, t.slug
+ORDER BY item_count DESC, t.slug ASC;
+
+-- name: RenameTag :one
+UPDATE tags
+SET name = $2, slug = $3
+WHERE id = $1
+RETURNING id, name, created_at, updated_at, slug;
+
+-- name: MoveSourceTags :exec
+INSERT INTO source_tags (source_id, tag_id, position)
+SELECT source_id, sqlc.arg(into_id)::uuid, position
+FROM source_tags
+WHERE tag_id = sqlc.arg(from_id)
+ON CONFLICT DO NOTHING;
+
+-- name: MoveNoteTags :exec
+INSERT INTO note_tags (note_id, tag_id, position)
+SELECT note_id, sqlc.arg(into_id)::uuid, position
+FROM note_tags
+WHERE tag_id = sqlc.arg(from_id)
+ON CONFLICT DO NOTHING;
+
+-- name: MoveLibraryItemTags :exec
+INSERT INTO library_item_tags (library_item_id, tag_id, position)
+SELECT library_item_id, sqlc.arg(into_id)::uuid, position
+FROM library_item_tags
+WHERE tag_id = sqlc.arg(from_id)
+ON CONFLICT DO NOTHING;
+
+-- name: DeleteTag :execrows
+DELETE FROM tags WHERE id = $1;
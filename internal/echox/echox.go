@@ -3,6 +3,7 @@ package echox
 import (
 	"net/http"
 	"strconv"
+	"strings"
 
 	"github.com/gofrs/uuid/v5"
 	"github.com/labstack/echo/v5"
@@ -43,6 +44,28 @@ func Pagination(c *echo.Context) (limit, offset int) {
 	return limit, offset
 }
 
+// QueryString returns a trimmed query parameter, or nil when it is absent or blank.
+func QueryString(c *echo.Context, name string) *string {
+	value := strings.TrimSpace(c.QueryParam(name))
+	if value == "" {
+		return nil
+	}
+	return &value
+}
+
+// QueryInt parses an optional integer query parameter.
+func QueryInt(c *echo.Context, name, label string) (*int, error) {
+	value := c.QueryParam(name)
+	if value == "" {
+		return nil, nil
+	}
+	parsed, err := strconv.Atoi(value)
+	if err != nil {
+		return nil, echo.NewHTTPError(http.StatusBadRequest, "invalid "+label)
+	}
+	return &parsed, nil
+}
+
 func ParamUUID(c *echo.Context, name, label string) (uuid.UUID, error) {
 	id, err := uuid.FromString(c.Param(name))
 	if err != nil {
@@ -51,6 +74,15 @@ func ParamUUID(c *echo.Context, name, label string) (uuid.UUID, error) {
 	return id, nil
 }
 
+// ParamInt parses an integer path parameter.
+func ParamInt(c *echo.Context, name, label string) (int, error) {
+	value, err := strconv.Atoi(c.Param(name))
+	if err != nil {
+		return 0, echo.NewHTTPError(http.StatusBadRequest, "invalid "+label)
+	}
+	return value, nil
+}
+
 func OptionalUUID(value *string, label string) (*uuid.UUID, error) {
 	if value == nil {
 		return nil, nil
@@ -0,0 +1,144 @@
+package echox
+
+import (
+	"encoding/base64"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/gofrs/uuid/v5"
+	"github.com/labstack/echo/v5"
+)
+
+const (
+	DefaultPageLimit = 50
+	MaxPageLimit     = 100
+)
+
+// Cursor is a position in a list ordered by (created_at, id). Lists continue
+// strictly after it, so rows inserted while a client pages never shift what
+// it sees next.
+type Cursor struct {
+	CreatedAt time.Time
+	ID        uuid.UUID
+}
+
+func (c Cursor) String() string {
+	raw := strconv.FormatInt(c.CreatedAt.UnixMicro(), 10) + "." + c.ID.String()
+	return base64.RawURLEncoding.EncodeToString([]byte(raw))
+}
+
+func ParseCursor(value string) (Cursor, error) {
+	raw, err := base64.RawURLEncoding.DecodeString(value)
+	if err != nil {
+		return Cursor{}, err
+	}
+	micros, id, ok := strings.Cut(string(raw), ".")
+	if !ok {
+		return Cursor{}, strconv.ErrSyntax
+	}
+	createdAt, err := strconv.ParseInt(micros, 10, 64)
+	if err != nil {
+		return Cursor{}, err
+	}
+	parsedID, err := uuid.FromString(id)
+	if err != nil || parsedID == uuid.Nil {
+		return Cursor{}, strconv.ErrSyntax
+	}
+	return Cursor{CreatedAt: time.UnixMicro(createdAt).UTC(), ID: parsedID}, nil
+}
+
+// PageRequest selects one page of a keyset-paginated list. A zero After
+// starts at the first page, and Oldest reverses the default newest-first
+// order. Counting every matching row costs a scan the page itself avoids, so
+// it only happens when IncludeTotal asks for it.
+type PageRequest struct {
+	Limit        int
+	After        Cursor
+	Oldest       bool
+	IncludeTotal bool
+}
+
+// Normalize clamps the limit to the range every list accepts.
+func (r PageRequest) Normalize() PageRequest {
+	if r.Limit <= 0 {
+		r.Limit = DefaultPageLimit
+	}
+	if r.Limit > MaxPageLimit {
+		r.Limit = MaxPageLimit
+	}
+	return r
+}
+
+// Total runs count when the request asked for a total and returns nil
+// otherwise.
+func (r PageRequest) Total(count func() (int64, error)) (*int64, error) {
+	if !r.IncludeTotal {
+		return nil, nil
+	}
+	total, err := count()
+	if err != nil {
+		return nil, err
+	}
+	return &total, nil
+}
+
+// Page is the response envelope of every paginated list. NextCursor is nil
+// on the last page; Total counts every row matching the filters and is only
+// set when the request included it.
+type Page[T any] struct {
+	Items      []T     `json:"items"`
+	NextCursor *string `json:"next_cursor"`
+	Total      *int64  `json:"total,omitempty"`
+}
+
+// NewPage builds a page from rows fetched with a limit one higher than the
+// request's, the extra row only telling that another page follows.
+func NewPage[T any](rows []T, req PageRequest, total *int64, key func(T) Cursor) *Page[T] {
+	page := &Page[T]{Items: rows, Total: total}
+	if len(rows) > req.Limit {
+		page.Items = rows[:req.Limit]
+		next := key(page.Items[req.Limit-1]).String()
+		page.NextCursor = &next
+	}
+	if page.Items == nil {
+		page.Items = []T{}
+	}
+	return page
+}
+
+// PageParams reads the limit, cursor, sort and include_total query
+// parameters. Sort is "newest" (the default) or "oldest".
+func PageParams(c *echo.Context) (PageRequest, error) {
+	var req PageRequest
+	if value := c.QueryParam("limit"); value != "" {
+		limit, err := strconv.Atoi(value)
+		if err != nil || limit <= 0 {
+			return req, echo.NewHTTPError(http.StatusBadRequest, "invalid limit")
+		}
+		req.Limit = limit
+	}
+	if value := c.QueryParam("cursor"); value != "" {
+		cursor, err := ParseCursor(value)
+		if err != nil {
+			return req, echo.NewHTTPError(http.StatusBadRequest, "invalid cursor")
+		}
+		req.After = cursor
+	}
+	switch c.QueryParam("sort") {
+	case "", "newest":
+	case "oldest":
+		req.Oldest = true
+	default:
+		return req, echo.NewHTTPError(http.StatusBadRequest, "invalid sort")
+	}
+	if value := c.QueryParam("include_total"); value != "" {
+		include, err := strconv.ParseBool(value)
+		if err != nil {
+			return req, echo.NewHTTPError(http.StatusBadRequest, "invalid include_total")
+		}
+		req.IncludeTotal = include
+	}
+	return req.Normalize(), nil
+}
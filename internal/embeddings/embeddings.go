@@ -0,0 +1,39 @@
+// Package embeddings turns notes and sources into vectors for semantic
+// similarity search and keeps the stored vectors in sync with their text.
+package embeddings
+
+import (
+	"context"
+	"errors"
+	"fmt"
+
+	"github.com/gofrs/uuid/v5"
+)
+
+// Dimensions is the vector size stored in the embeddings tables.
+const Dimensions = 384
+
+var ErrDimensionMismatch = errors.New("embedding dimension mismatch")
+
+// Embedder converts texts into fixed-size vectors.
+type Embedder interface {
+	// Model identifies the embedding model; vectors from different models are
+	// never compared with each other.
+	Model() string
+	Embed(ctx context.Context, texts []string) ([][]float32, error)
+}
+
+// Document is a piece of text whose embedding is missing or stale.
+type Document struct {
+	ID   uuid.UUID
+	Text string
+}
+
+func checkDimensions(vectors [][]float32, want int) error {
+	for _, vector := range vectors {
+		if len(vector) != want {
+			return fmt.Errorf("%w: got %d, want %d", ErrDimensionMismatch, len(vector), want)
+		}
+	}
+	return nil
+}
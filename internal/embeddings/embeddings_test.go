@@ -0,0 +1,202 @@
+package embeddings
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/json"
+	"errors"
+	"io"
+	"log/slog"
+	"math"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/gofrs/uuid/v5"
+)
+
+func TestHashEmbedderIsDeterministicAndNormalized(t *testing.T) {
+	embedder := NewHashEmbedder()
+
+	first, err := embedder.Embed(context.Background(), []string{"The Muqaddimah of Ibn Khaldun"})
+	if err != nil {
+		t.Fatalf("Embed() error = %v", err)
+	}
+	second, _ := embedder.Embed(context.Background(), []string{"the muqaddimah of ibn khaldun"})
+
+	if len(first[0]) != Dimensions {
+		t.Fatalf("dimensions = %d, want %d", len(first[0]), Dimensions)
+	}
+	if cosine(first[0], second[0]) < 0.9999 {
+		t.Fatal("expected case-insensitive deterministic embeddings")
+	}
+	if norm := cosine(first[0], first[0]); math.Abs(float64(norm)-1) > 1e-5 {
+		t.Fatalf("self similarity = %f, want 1", norm)
+	}
+}
+
+func TestHashEmbedderRanksSharedWordsHigher(t *testing.T) {
+	vectors, err := NewHashEmbedder().Embed(context.Background(), []string{
+		"asabiyyah and the rise and fall of dynasties",
+		"the fall of dynasties and group solidarity",
+		"a recipe for lentil soup",
+	})
+	if err != nil {
+		t.Fatalf("Embed() error = %v", err)
+	}
+
+	related := cosine(vectors[0], vectors[1])
+	unrelated := cosine(vectors[0], vectors[2])
+	if related <= unrelated {
+		t.Fatalf("related = %f, unrelated = %f", related, unrelated)
+	}
+}
+
+func TestHashEmbedderHandlesEmptyText(t *testing.T) {
+	vectors, err := NewHashEmbedder().Embed(context.Background(), []string{""})
+	if err != nil {
+		t.Fatalf("Embed() error = %v", err)
+	}
+	for _, value := range vectors[0] {
+		if value != 0 {
+			t.Fatal("expected zero vector for empty text")
+		}
+	}
+}
+
+func TestHTTPEmbedderOrdersByIndex(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != "/v1/embeddings" {
+			t.Errorf("path = %q", r.URL.Path)
+		}
+		if got := r.Header.Get("Authorization"); got != "Bearer secret" {
+			t.Errorf("authorization = %q", got)
+		}
+		var req embeddingRequest
+		if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+			t.Errorf("decode request: %v", err)
+			return
+		}
+		if req.Model != "minilm" || len(req.Input) != 2 {
+			t.Errorf("request = %+v", req)
+		}
+		_ = json.NewEncoder(w).Encode(map[string]any{"data": []map[string]any{
+			{"index": 1, "embedding": filled(2)},
+			{"index": 0, "embedding": filled(1)},
+		}})
+	}))
+	defer server.Close()
+
+	vectors, err := NewHTTPEmbedder(server.URL+"/", "minilm", "secret", time.Second).Embed(context.Background(), []string{"a", "b"})
+	if err != nil {
+		t.Fatalf("Embed() error = %v", err)
+	}
+	if vectors[0][0] != 1 || vectors[1][0] != 2 {
+		t.Fatalf("vectors not ordered by index: %v, %v", vectors[0][0], vectors[1][0])
+	}
+}
+
+func TestHTTPEmbedderRejectsWrongDimensions(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		_ = json.NewEncoder(w).Encode(map[string]any{"data": []map[string]any{{"index": 0, "embedding": []float32{1, 2, 3}}}})
+	}))
+	defer server.Close()
+
+	_, err := NewHTTPEmbedder(server.URL, "minilm", "", time.Second).Embed(context.Background(), []string{"a"})
+	if !errors.Is(err, ErrDimensionMismatch) {
+		t.Fatalf("error = %v, want %v", err, ErrDimensionMismatch)
+	}
+}
+
+func TestIndexerSavesPendingDocuments(t *testing.T) {
+	noteID := uuid.Must(uuid.NewV7())
+	sourceID := uuid.Must(uuid.NewV7())
+	store := &fakeStore{
+		notes:   []Document{{ID: noteID, Text: "a highlight about dynasties"}},
+		sources: []Document{{ID: sourceID, Text: "The Muqaddimah\nIbn Khaldun"}},
+		saved:   map[uuid.UUID][]byte{},
+	}
+	indexer := NewIndexer(store, NewHashEmbedder(), 10, time.Minute, slog.New(slog.NewTextHandler(io.Discard, nil)))
+
+	indexed, err := indexer.IndexBatch(context.Background())
+	if err != nil {
+		t.Fatalf("IndexBatch() error = %v", err)
+	}
+	if indexed != 2 {
+		t.Fatalf("indexed = %d, want 2", indexed)
+	}
+	wantHash := sha256.Sum256([]byte("a highlight about dynasties"))
+	if string(store.saved[noteID]) != string(wantHash[:]) {
+		t.Fatal("expected note content hash to be saved")
+	}
+	if _, ok := store.saved[sourceID]; !ok {
+		t.Fatal("expected source embedding to be saved")
+	}
+	if store.model != "hash-bow-384" {
+		t.Fatalf("model = %q", store.model)
+	}
+
+	indexed, err = indexer.IndexBatch(context.Background())
+	if err != nil || indexed != 0 {
+		t.Fatalf("second batch = %d, %v; want 0, nil", indexed, err)
+	}
+}
+
+type fakeStore struct {
+	notes   []Document
+	sources []Document
+	saved   map[uuid.UUID][]byte
+	model   string
+}
+
+func (s *fakeStore) PendingNotes(context.Context, string, int) ([]Document, error) {
+	return pending(s.notes, s.saved), nil
+}
+
+func (s *fakeStore) PendingSources(context.Context, string, int) ([]Document, error) {
+	return pending(s.sources, s.saved), nil
+}
+
+func (s *fakeStore) SaveNote(_ context.Context, id uuid.UUID, model string, _ []float32, hash []byte) error {
+	s.saved[id] = hash
+	s.model = model
+	return nil
+}
+
+func (s *fakeStore) SaveSource(_ context.Context, id uuid.UUID, model string, _ []float32, hash []byte) error {
+	s.saved[id] = hash
+	s.model = model
+	return nil
+}
+
+func pending(documents []Document, saved map[uuid.UUID][]byte) []Document {
+	var out []Document
+	for _, document := range documents {
+		if _, ok := saved[document.ID]; !ok {
+			out = append(out, document)
+		}
+	}
+	return out
+}
+
+func filled(value float32) []float32 {
+	vector := make([]float32, Dimensions)
+	for i := range vector {
+		vector[i] = value
+	}
+	return vector
+}
+
+func cosine(a, b []float32) float32 {
+	var dot, na, nb float64
+	for i := range a {
+		dot += float64(a[i]) * float64(b[i])
+		na += float64(a[i]) * float64(a[i])
+		nb += float64(b[i]) * float64(b[i])
+	}
+	if na == 0 || nb == 0 {
+		return 0
+	}
+	return float32(dot / math.Sqrt(na*nb))
+}
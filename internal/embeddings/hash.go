@@ -0,0 +1,77 @@
+package embeddings
+
+import (
+	"context"
+	"hash/fnv"
+	"math"
+	"strings"
+	"unicode"
+)
+
+// HashEmbedder is a deterministic bag-of-words embedder. Each token and
+// adjacent token pair is hashed into a signed bucket, so texts sharing words
+// end up close together. It needs no model server and is used in tests and
+// local development.
+type HashEmbedder struct {
+	dimensions int
+}
+
+func NewHashEmbedder() *HashEmbedder {
+	return &HashEmbedder{dimensions: Dimensions}
+}
+
+func (e *HashEmbedder) Model() string {
+	return "hash-bow-384"
+}
+
+func (e *HashEmbedder) Embed(_ context.Context, texts []string) ([][]float32, error) {
+	vectors := make([][]float32, 0, len(texts))
+	for _, text := range texts {
+		vectors = append(vectors, e.embed(text))
+	}
+	return vectors, nil
+}
+
+func (e *HashEmbedder) embed(text string) []float32 {
+	vector := make([]float32, e.dimensions)
+	tokens := tokenize(text)
+	for i, token := range tokens {
+		e.add(vector, token, 1)
+		if i > 0 {
+			e.add(vector, tokens[i-1]+" "+token, 0.5)
+		}
+	}
+	normalize(vector)
+	return vector
+}
+
+func (e *HashEmbedder) add(vector []float32, feature string, weight float32) {
+	h := fnv.New64a()
+	h.Write([]byte(feature))
+	sum := h.Sum64()
+	bucket := int(sum % uint64(e.dimensions))
+	if sum&(1<<63) != 0 {
+		weight = -weight
+	}
+	vector[bucket] += weight
+}
+
+func tokenize(text string) []string {
+	return strings.FieldsFunc(strings.ToLower(text), func(r rune) bool {
+		return !unicode.IsLetter(r) && !unicode.IsNumber(r)
+	})
+}
+
+func normalize(vector []float32) {
+	var sum float64
+	for _, value := range vector {
+		sum += float64(value) * float64(value)
+	}
+	if sum == 0 {
+		return
+	}
+	norm := float32(math.Sqrt(sum))
+	for i := range vector {
+		vector[i] /= norm
+	}
+}
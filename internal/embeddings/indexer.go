@@ -0,0 +1,113 @@
+package embeddings
+
+import (
+	"context"
+	"crypto/sha256"
+	"fmt"
+	"log/slog"
+	"time"
+
+	"github.com/gofrs/uuid/v5"
+)
+
+// Indexer backfills embeddings for notes and sources whose text has no
+// vector yet, was embedded by another model, or changed since it was embedded.
+type Indexer struct {
+	store     Store
+	embedder  Embedder
+	batchSize int
+	interval  time.Duration
+	logger    *slog.Logger
+}
+
+func NewIndexer(store Store, embedder Embedder, batchSize int, interval time.Duration, logger *slog.Logger) *Indexer {
+	if batchSize <= 0 {
+		batchSize = 32
+	}
+	if interval <= 0 {
+		interval = time.Minute
+	}
+	return &Indexer{store: store, embedder: embedder, batchSize: batchSize, interval: interval, logger: logger}
+}
+
+// Run indexes pending documents until ctx is cancelled.
+func (i *Indexer) Run(ctx context.Context) {
+	i.logger.Info("embedding indexer started", "model", i.embedder.Model(), "interval", i.interval)
+	ticker := time.NewTicker(i.interval)
+	defer ticker.Stop()
+
+	for {
+		for {
+			indexed, err := i.IndexBatch(ctx)
+			if err != nil {
+				if ctx.Err() == nil {
+					i.logger.Error("embedding batch failed", "error", err)
+				}
+				break
+			}
+			if indexed == 0 {
+				break
+			}
+		}
+
+		select {
+		case <-ctx.Done():
+			i.logger.Info("embedding indexer stopped")
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+// IndexBatch embeds one batch of pending notes and one of pending sources and
+// returns how many documents were saved.
+func (i *Indexer) IndexBatch(ctx context.Context) (int, error) {
+	model := i.embedder.Model()
+
+	notes, err := i.store.PendingNotes(ctx, model, i.batchSize)
+	if err != nil {
+		return 0, err
+	}
+	indexed, err := i.index(ctx, notes, i.store.SaveNote)
+	if err != nil {
+		return indexed, err
+	}
+
+	sources, err := i.store.PendingSources(ctx, model, i.batchSize)
+	if err != nil {
+		return indexed, err
+	}
+	n, err := i.index(ctx, sources, i.store.SaveSource)
+	return indexed + n, err
+}
+
+type saveFunc func(ctx context.Context, id uuid.UUID, model string, vector []float32, contentHash []byte) error
+
+func (i *Indexer) index(ctx context.Context, documents []Document, save saveFunc) (int, error) {
+	if len(documents) == 0 {
+		return 0, nil
+	}
+	texts := make([]string, 0, len(documents))
+	for _, document := range documents {
+		texts = append(texts, document.Text)
+	}
+	vectors, err := i.embedder.Embed(ctx, texts)
+	if err != nil {
+		return 0, err
+	}
+	if len(vectors) != len(documents) {
+		return 0, fmt.Errorf("embedder returned %d vectors for %d documents", len(vectors), len(documents))
+	}
+	if err := checkDimensions(vectors, Dimensions); err != nil {
+		return 0, err
+	}
+
+	model := i.embedder.Model()
+	for n, document := range documents {
+		hash := sha256.Sum256([]byte(document.Text))
+		if err := save(ctx, document.ID, model, vectors[n], hash[:]); err != nil {
+			return n, err
+		}
+	}
+	return len(documents), nil
+}
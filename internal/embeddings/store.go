@@ -0,0 +1,68 @@
+package embeddings
+
+import (
+	"context"
+
+	"github.com/gofrs/uuid/v5"
+	pgvector "github.com/pgvector/pgvector-go"
+	"github.com/zizouhuweidi/maktaba/internal/db"
+	"github.com/zizouhuweidi/maktaba/internal/db/dbgen"
+)
+
+// Store reads documents that need embedding and saves computed vectors.
+type Store interface {
+	PendingNotes(ctx context.Context, model string, limit int) ([]Document, error)
+	PendingSources(ctx context.Context, model string, limit int) ([]Document, error)
+	SaveNote(ctx context.Context, id uuid.UUID, model string, vector []float32, contentHash []byte) error
+	SaveSource(ctx context.Context, id uuid.UUID, model string, vector []float32, contentHash []byte) error
+}
+
+type postgresStore struct {
+	queries *dbgen.Queries
+}
+
+func NewPostgresStore(d *db.DB) Store {
+	return &postgresStore{queries: dbgen.New(d.Pool)}
+}
+
+func (s *postgresStore) PendingNotes(ctx context.Context, model string, limit int) ([]Document, error) {
+	rows, err := s.queries.ListNotesNeedingEmbedding(ctx, dbgen.ListNotesNeedingEmbeddingParams{Model: model, Limit: int32(limit)})
+	if err != nil {
+		return nil, err
+	}
+	documents := make([]Document, 0, len(rows))
+	for _, row := range rows {
+		documents = append(documents, Document{ID: db.UUID(row.ID), Text: row.Content})
+	}
+	return documents, nil
+}
+
+func (s *postgresStore) PendingSources(ctx context.Context, model string, limit int) ([]Document, error) {
+	rows, err := s.queries.ListSourcesNeedingEmbedding(ctx, dbgen.ListSourcesNeedingEmbeddingParams{Model: model, Limit: int32(limit)})
+	if err != nil {
+		return nil, err
+	}
+	documents := make([]Document, 0, len(rows))
+	for _, row := range rows {
+		documents = append(documents, Document{ID: db.UUID(row.ID), Text: row.Document})
+	}
+	return documents, nil
+}
+
+func (s *postgresStore) SaveNote(ctx context.Context, id uuid.UUID, model string, vector []float32, contentHash []byte) error {
+	return s.queries.UpsertNoteEmbedding(ctx, dbgen.UpsertNoteEmbeddingParams{
+		NoteID:      db.PGUUID(id),
+		Model:       model,
+		Embedding:   pgvector.NewVector(vector),
+		ContentHash: contentHash,
+	})
+}
+
+func (s *postgresStore) SaveSource(ctx context.Context, id uuid.UUID, model string, vector []float32, contentHash []byte) error {
+	return s.queries.UpsertSourceEmbedding(ctx, dbgen.UpsertSourceEmbeddingParams{
+		SourceID:    db.PGUUID(id),
+		Model:       model,
+		Embedding:   pgvector.NewVector(vector),
+		ContentHash: contentHash,
+	})
+}
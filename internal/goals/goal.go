@@ -0,0 +1,151 @@
+// Package goals lets readers set completion targets, such as 24 books in a
+// year or 3 papers a month, and share them as challenges others can join.
+package goals
+
+import (
+	"context"
+	"time"
+
+	"github.com/gofrs/uuid/v5"
+)
+
+// Outbox aggregate and event types published by this context.
+const (
+	AggregateGoal     = "goal"
+	EventGoalCreated  = "goal.created"
+	EventGoalUpdated  = "goal.updated"
+	EventGoalDeleted  = "goal.deleted"
+	EventGoalAchieved = "goal.achieved"
+)
+
+// Period says whether the target covers the whole goal or each month of it
+type Period string
+
+const (
+	PeriodTotal   Period = "total"
+	PeriodMonthly Period = "monthly"
+)
+
+// Pace compares a participant's completions with a steady rate towards the
+// target.
+type Pace string
+
+const (
+	PaceUpcoming Pace = "upcoming"
+	PaceAhead    Pace = "ahead"
+	PaceOnTrack  Pace = "on_track"
+	PaceBehind   Pace = "behind"
+	PaceAchieved Pace = "achieved"
+	PaceMissed   Pace = "missed"
+)
+
+// Goal counts completed library items, optionally of one source type,
+// between StartsOn and EndsOn inclusive. Shared goals are challenges other
+// readers can join.
+type Goal struct {
+	ID         uuid.UUID `json:"id"`
+	OwnerID    uuid.UUID `json:"owner_id"`
+	Title      string    `json:"title"`
+	Target     int       `json:"target"`
+	Period     Period    `json:"period"`
+	SourceType *string   `json:"source_type,omitempty"`
+	StartsOn   time.Time `json:"starts_on"`
+	EndsOn     time.Time `json:"ends_on"`
+	Shared     bool      `json:"shared"`
+	CreatedAt  time.Time `json:"created_at"`
+	UpdatedAt  time.Time `json:"updated_at"`
+}
+
+// Progress is one participant's standing in the goal's current period.
+// PeriodEnd is exclusive.
+type Progress struct {
+	PeriodStart time.Time  `json:"period_start"`
+	PeriodEnd   time.Time  `json:"period_end"`
+	Completed   int        `json:"completed"`
+	Target      int        `json:"target"`
+	Expected    float64    `json:"expected"`
+	Pace        Pace       `json:"pace"`
+	AchievedAt  *time.Time `json:"achieved_at,omitempty"`
+}
+
+type GoalWithProgress struct {
+	*Goal
+	Progress *Progress `json:"progress"`
+}
+
+// Standing is a participant's progress in a shared goal
+type Standing struct {
+	UserID     uuid.UUID  `json:"user_id"`
+	Username   string     `json:"username"`
+	JoinedAt   time.Time  `json:"joined_at"`
+	Completed  int        `json:"completed"`
+	AchievedAt *time.Time `json:"achieved_at,omitempty"`
+}
+
+// Participation is a goal a reader set or joined, with every period they
+// met. It is how goals appear in an account export.
+type Participation struct {
+	Goal         *Goal          `json:"goal"`
+	JoinedAt     time.Time      `json:"joined_at"`
+	Achievements []*Achievement `json:"achievements"`
+}
+
+// Achievement records when a participant met the target of the period
+// starting on PeriodStart.
+type Achievement struct {
+	PeriodStart time.Time `json:"period_start"`
+	AchievedAt  time.Time `json:"achieved_at"`
+}
+
+// Window is the span of time one period of a goal covers. End is exclusive.
+type Window struct {
+	Start time.Time
+	End   time.Time
+}
+
+type Repository interface {
+	Create(ctx context.Context, goal *Goal) (*Goal, error)
+	GetByID(ctx context.Context, id uuid.UUID) (*Goal, error)
+	ListByParticipant(ctx context.Context, userID uuid.UUID, limit, offset int) ([]*Goal, error)
+	ListActiveByParticipant(ctx context.Context, userID uuid.UUID, day time.Time) ([]*Goal, error)
+	ListParticipations(ctx context.Context, userID uuid.UUID, limit, offset int) ([]*Participation, error)
+	Update(ctx context.Context, goal *Goal) (*Goal, error)
+	Delete(ctx context.Context, id uuid.UUID) error
+	AddParticipant(ctx context.Context, goalID, userID uuid.UUID) error
+	RemoveParticipant(ctx context.Context, goalID, userID uuid.UUID) error
+	IsParticipant(ctx context.Context, goalID, userID uuid.UUID) (bool, error)
+	CountCompleted(ctx context.Context, userID uuid.UUID, sourceType *string, window Window) (int, error)
+	GetAchievement(ctx context.Context, goalID, userID uuid.UUID, periodStart time.Time) (*time.Time, error)
+	RecordAchievement(ctx context.Context, goal *Goal, userID uuid.UUID, periodStart time.Time, completed int) (bool, error)
+	ListStandings(ctx context.Context, goal *Goal, window Window, limit, offset int) ([]*Standing, error)
+}
+
+type CreateGoalParams struct {
+	OwnerID    uuid.UUID
+	Title      string
+	Target     int
+	Period     Period
+	SourceType *string
+	StartsOn   time.Time
+	EndsOn     time.Time
+	Shared     bool
+}
+
+type UpdateGoalParams struct {
+	Title      *string
+	Target     *int
+	Period     *Period
+	SourceType *string
+	StartsOn   *time.Time
+	EndsOn     *time.Time
+	Shared     *bool
+}
+
+// AchievedPayload is the payload recorded for goal.achieved events
+type AchievedPayload struct {
+	GoalID      uuid.UUID `json:"goal_id"`
+	UserID      uuid.UUID `json:"user_id"`
+	PeriodStart time.Time `json:"period_start"`
+	Completed   int       `json:"completed"`
+	Target      int       `json:"target"`
+}
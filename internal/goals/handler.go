@@ -0,0 +1,247 @@
+package goals
+
+import (
+	"errors"
+	"log/slog"
+	"net/http"
+	"time"
+
+	"github.com/labstack/echo/v5"
+	"github.com/zizouhuweidi/maktaba/internal/auth"
+	"github.com/zizouhuweidi/maktaba/internal/echox"
+)
+
+type Handler struct {
+	service *Service
+	logger  *slog.Logger
+}
+
+func NewHandler(service *Service, logger *slog.Logger) *Handler {
+	return &Handler{service: service, logger: logger}
+}
+
+// CreateRequest takes dates as YYYY-MM-DD; both ends are inclusive
+type CreateRequest struct {
+	Title      string  `json:"title" validate:"required"`
+	Target     int     `json:"target" validate:"required"`
+	Period     string  `json:"period,omitempty"`
+	SourceType *string `json:"source_type,omitempty"`
+	StartsOn   string  `json:"starts_on" validate:"required"`
+	EndsOn     string  `json:"ends_on" validate:"required"`
+	Shared     bool    `json:"shared"`
+}
+
+type UpdateRequest struct {
+	Title      *string `json:"title,omitempty"`
+	Target     *int    `json:"target,omitempty"`
+	Period     *string `json:"period,omitempty"`
+	SourceType *string `json:"source_type,omitempty"`
+	StartsOn   *string `json:"starts_on,omitempty"`
+	EndsOn     *string `json:"ends_on,omitempty"`
+	Shared     *bool   `json:"shared,omitempty"`
+}
+
+func (h *Handler) RegisterProtectedRoutes(g *echo.Group) {
+	g.POST("/goals", h.Create)
+	g.GET("/goals", h.List)
+	g.GET("/goals/:id", h.Get)
+	g.PUT("/goals/:id", h.Update)
+	g.DELETE("/goals/:id", h.Delete)
+	g.POST("/goals/:id/join", h.Join)
+	g.DELETE("/goals/:id/join", h.Leave)
+	g.GET("/goals/:id/standings", h.Standings)
+}
+
+func (h *Handler) Create(c *echo.Context) error {
+	userID, ok := auth.UserID(c)
+	if !ok {
+		return echo.NewHTTPError(http.StatusUnauthorized, "authentication required")
+	}
+
+	var req CreateRequest
+	if err := echox.BindAndValidate(c, &req); err != nil {
+		return err
+	}
+	startsOn, err := parseDate(req.StartsOn, "starts_on")
+	if err != nil {
+		return err
+	}
+	endsOn, err := parseDate(req.EndsOn, "ends_on")
+	if err != nil {
+		return err
+	}
+
+	goal, err := h.service.Create(c.Request().Context(), CreateGoalParams{
+		OwnerID:    userID,
+		Title:      req.Title,
+		Target:     req.Target,
+		Period:     Period(req.Period),
+		SourceType: req.SourceType,
+		StartsOn:   startsOn,
+		EndsOn:     endsOn,
+		Shared:     req.Shared,
+	})
+	if err != nil {
+		return h.goalError(err, "failed to create goal")
+	}
+	return c.JSON(http.StatusCreated, goal)
+}
+
+func (h *Handler) List(c *echo.Context) error {
+	userID, ok := auth.UserID(c)
+	if !ok {
+		return echo.NewHTTPError(http.StatusUnauthorized, "authentication required")
+	}
+	limit, offset := echox.Pagination(c)
+	goals, err := h.service.List(c.Request().Context(), userID, limit, offset)
+	if err != nil {
+		return echo.NewHTTPError(http.StatusInternalServerError, "failed to list goals")
+	}
+	return c.JSON(http.StatusOK, goals)
+}
+
+func (h *Handler) Get(c *echo.Context) error {
+	userID, ok := auth.UserID(c)
+	if !ok {
+		return echo.NewHTTPError(http.StatusUnauthorized, "authentication required")
+	}
+	id, err := echox.ParamUUID(c, "id", "goal ID")
+	if err != nil {
+		return err
+	}
+	goal, err := h.service.Get(c.Request().Context(), id, userID)
+	if err != nil {
+		return h.goalError(err, "failed to get goal")
+	}
+	return c.JSON(http.StatusOK, goal)
+}
+
+func (h *Handler) Update(c *echo.Context) error {
+	userID, ok := auth.UserID(c)
+	if !ok {
+		return echo.NewHTTPError(http.StatusUnauthorized, "authentication required")
+	}
+	id, err := echox.ParamUUID(c, "id", "goal ID")
+	if err != nil {
+		return err
+	}
+
+	var req UpdateRequest
+	if err := echox.BindAndValidate(c, &req); err != nil {
+		return err
+	}
+	params := UpdateGoalParams{Title: req.Title, Target: req.Target, SourceType: req.SourceType, Shared: req.Shared}
+	if req.Period != nil {
+		period := Period(*req.Period)
+		params.Period = &period
+	}
+	if req.StartsOn != nil {
+		startsOn, err := parseDate(*req.StartsOn, "starts_on")
+		if err != nil {
+			return err
+		}
+		params.StartsOn = &startsOn
+	}
+	if req.EndsOn != nil {
+		endsOn, err := parseDate(*req.EndsOn, "ends_on")
+		if err != nil {
+			return err
+		}
+		params.EndsOn = &endsOn
+	}
+
+	goal, err := h.service.Update(c.Request().Context(), id, userID, params)
+	if err != nil {
+		return h.goalError(err, "failed to update goal")
+	}
+	return c.JSON(http.StatusOK, goal)
+}
+
+func (h *Handler) Delete(c *echo.Context) error {
+	userID, ok := auth.UserID(c)
+	if !ok {
+		return echo.NewHTTPError(http.StatusUnauthorized, "authentication required")
+	}
+	id, err := echox.ParamUUID(c, "id", "goal ID")
+	if err != nil {
+		return err
+	}
+	if err := h.service.Delete(c.Request().Context(), id, userID); err != nil {
+		return h.goalError(err, "failed to delete goal")
+	}
+	return c.NoContent(http.StatusNoContent)
+}
+
+func (h *Handler) Join(c *echo.Context) error {
+	userID, ok := auth.UserID(c)
+	if !ok {
+		return echo.NewHTTPError(http.StatusUnauthorized, "authentication required")
+	}
+	id, err := echox.ParamUUID(c, "id", "goal ID")
+	if err != nil {
+		return err
+	}
+	goal, err := h.service.Join(c.Request().Context(), id, userID)
+	if err != nil {
+		return h.goalError(err, "failed to join goal")
+	}
+	return c.JSON(http.StatusOK, goal)
+}
+
+func (h *Handler) Leave(c *echo.Context) error {
+	userID, ok := auth.UserID(c)
+	if !ok {
+		return echo.NewHTTPError(http.StatusUnauthorized, "authentication required")
+	}
+	id, err := echox.ParamUUID(c, "id", "goal ID")
+	if err != nil {
+		return err
+	}
+	if err := h.service.Leave(c.Request().Context(), id, userID); err != nil {
+		return h.goalError(err, "failed to leave goal")
+	}
+	return c.NoContent(http.StatusNoContent)
+}
+
+func (h *Handler) Standings(c *echo.Context) error {
+	userID, ok := auth.UserID(c)
+	if !ok {
+		return echo.NewHTTPError(http.StatusUnauthorized, "authentication required")
+	}
+	id, err := echox.ParamUUID(c, "id", "goal ID")
+	if err != nil {
+		return err
+	}
+	limit, offset := echox.Pagination(c)
+	standings, err := h.service.Standings(c.Request().Context(), id, userID, limit, offset)
+	if err != nil {
+		return h.goalError(err, "failed to list goal standings")
+	}
+	return c.JSON(http.StatusOK, standings)
+}
+
+func (h *Handler) goalError(err error, message string) error {
+	switch {
+	case errors.Is(err, ErrInvalidGoal):
+		return echo.NewHTTPError(http.StatusBadRequest, "invalid goal")
+	case errors.Is(err, ErrGoalNotFound):
+		return echo.NewHTTPError(http.StatusNotFound, "goal not found")
+	case errors.Is(err, ErrForbidden):
+		return echo.NewHTTPError(http.StatusForbidden, "only the goal owner can change it")
+	case errors.Is(err, ErrNotShared):
+		return echo.NewHTTPError(http.StatusForbidden, "goal is not shared")
+	case errors.Is(err, ErrOwnerCannotLeave):
+		return echo.NewHTTPError(http.StatusConflict, "the goal owner cannot leave it")
+	default:
+		h.logger.Error(message, "error", err)
+		return echo.NewHTTPError(http.StatusInternalServerError, message)
+	}
+}
+
+func parseDate(value, field string) (time.Time, error) {
+	parsed, err := time.Parse(time.DateOnly, value)
+	if err != nil {
+		return time.Time{}, echo.NewHTTPError(http.StatusBadRequest, "invalid "+field)
+	}
+	return parsed, nil
+}
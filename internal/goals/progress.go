@@ -0,0 +1,72 @@
+package goals
+
+import (
+	"math"
+	"time"
+)
+
+// windowAt returns the period of the goal that contains at. Monthly goals
+// are split into calendar months clipped to the goal's dates; times outside
+// the goal fall into its first or last period.
+func windowAt(goal *Goal, at time.Time) Window {
+	start := day(goal.StartsOn)
+	end := day(goal.EndsOn).AddDate(0, 0, 1)
+	if goal.Period != PeriodMonthly {
+		return Window{Start: start, End: end}
+	}
+
+	at = at.UTC()
+	if at.Before(start) {
+		at = start
+	}
+	if !at.Before(end) {
+		at = end.Add(-time.Nanosecond)
+	}
+	month := time.Date(at.Year(), at.Month(), 1, 0, 0, 0, 0, time.UTC)
+	window := Window{Start: month, End: month.AddDate(0, 1, 0)}
+	if window.Start.Before(start) {
+		window.Start = start
+	}
+	if window.End.After(end) {
+		window.End = end
+	}
+	return window
+}
+
+// progressOf measures completed against a steady pace through the window.
+// A participant is ahead or behind once they are a whole item off that pace.
+func progressOf(target, completed int, window Window, now time.Time) *Progress {
+	progress := &Progress{
+		PeriodStart: window.Start,
+		PeriodEnd:   window.End,
+		Completed:   completed,
+		Target:      target,
+	}
+
+	switch {
+	case now.Before(window.Start):
+		progress.Pace = PaceUpcoming
+	case !now.Before(window.End):
+		progress.Expected = float64(target)
+		progress.Pace = PaceMissed
+	default:
+		elapsed := float64(now.Sub(window.Start)) / float64(window.End.Sub(window.Start))
+		progress.Expected = math.Round(float64(target)*elapsed*10) / 10
+		switch diff := float64(completed) - float64(target)*elapsed; {
+		case diff >= 1:
+			progress.Pace = PaceAhead
+		case diff <= -1:
+			progress.Pace = PaceBehind
+		default:
+			progress.Pace = PaceOnTrack
+		}
+	}
+	if completed >= target {
+		progress.Pace = PaceAchieved
+	}
+	return progress
+}
+
+func day(value time.Time) time.Time {
+	return time.Date(value.Year(), value.Month(), value.Day(), 0, 0, 0, 0, time.UTC)
+}
@@ -0,0 +1,310 @@
+package goals
+
+import (
+	"context"
+	"errors"
+	"time"
+
+	"github.com/gofrs/uuid/v5"
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgtype"
+	"github.com/zizouhuweidi/maktaba/internal/db"
+	"github.com/zizouhuweidi/maktaba/internal/db/dbgen"
+	"github.com/zizouhuweidi/maktaba/internal/outbox"
+)
+
+type postgresRepository struct {
+	db      *db.DB
+	queries *dbgen.Queries
+}
+
+func NewPostgresRepository(d *db.DB) Repository {
+	return &postgresRepository{db: d, queries: dbgen.New(d.Pool)}
+}
+
+// Create stores the goal and makes its owner the first participant
+func (r *postgresRepository) Create(ctx context.Context, goal *Goal) (*Goal, error) {
+	id, err := uuid.NewV7()
+	if err != nil {
+		return nil, err
+	}
+
+	tx, err := r.db.Begin(ctx)
+	if err != nil {
+		return nil, err
+	}
+	defer tx.Rollback(ctx)
+	qtx := r.queries.WithTx(tx)
+
+	row, err := qtx.CreateGoal(ctx, dbgen.CreateGoalParams{
+		ID:         db.PGUUID(id),
+		OwnerID:    db.PGUUID(goal.OwnerID),
+		Title:      goal.Title,
+		Target:     int32(goal.Target),
+		Period:     string(goal.Period),
+		SourceType: db.PGText(goal.SourceType),
+		StartsOn:   db.PGDate(goal.StartsOn),
+		EndsOn:     db.PGDate(goal.EndsOn),
+		Shared:     goal.Shared,
+	})
+	if err != nil {
+		return nil, err
+	}
+	if _, err := qtx.AddGoalParticipant(ctx, dbgen.AddGoalParticipantParams{GoalID: row.ID, UserID: row.OwnerID}); err != nil {
+		return nil, err
+	}
+	created := mapGoal(row)
+	if err := outbox.Record(ctx, qtx, AggregateGoal, created.ID, EventGoalCreated, created); err != nil {
+		return nil, err
+	}
+
+	if err := tx.Commit(ctx); err != nil {
+		return nil, err
+	}
+	return created, nil
+}
+
+func (r *postgresRepository) GetByID(ctx context.Context, id uuid.UUID) (*Goal, error) {
+	row, err := r.queries.GetGoalByID(ctx, db.PGUUID(id))
+	if errors.Is(err, pgx.ErrNoRows) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	return mapGoal(row), nil
+}
+
+func (r *postgresRepository) ListByParticipant(ctx context.Context, userID uuid.UUID, limit, offset int) ([]*Goal, error) {
+	rows, err := r.queries.ListGoalsByParticipant(ctx, dbgen.ListGoalsByParticipantParams{UserID: db.PGUUID(userID), Limit: int32(limit), Offset: int32(offset)})
+	if err != nil {
+		return nil, err
+	}
+	return mapGoals(rows), nil
+}
+
+// ListParticipations pages through the goals the user takes part in, oldest
+// first, each with the user's achievements.
+func (r *postgresRepository) ListParticipations(ctx context.Context, userID uuid.UUID, limit, offset int) ([]*Participation, error) {
+	rows, err := r.queries.ListGoalParticipationsByUser(ctx, dbgen.ListGoalParticipationsByUserParams{UserID: db.PGUUID(userID), Limit: int32(limit), Offset: int32(offset)})
+	if err != nil {
+		return nil, err
+	}
+	participations := make([]*Participation, 0, len(rows))
+	byGoal := make(map[uuid.UUID]*Participation, len(rows))
+	goalIDs := make([]pgtype.UUID, 0, len(rows))
+	for _, row := range rows {
+		participation := &Participation{
+			Goal: mapGoal(dbgen.Goal{
+				ID:         row.ID,
+				OwnerID:    row.OwnerID,
+				Title:      row.Title,
+				Target:     row.Target,
+				Period:     row.Period,
+				SourceType: row.SourceType,
+				StartsOn:   row.StartsOn,
+				EndsOn:     row.EndsOn,
+				Shared:     row.Shared,
+				CreatedAt:  row.CreatedAt,
+				UpdatedAt:  row.UpdatedAt,
+			}),
+			JoinedAt:     db.Time(row.JoinedAt),
+			Achievements: []*Achievement{},
+		}
+		participations = append(participations, participation)
+		byGoal[participation.Goal.ID] = participation
+		goalIDs = append(goalIDs, row.ID)
+	}
+	if len(goalIDs) == 0 {
+		return participations, nil
+	}
+
+	achievements, err := r.queries.ListGoalAchievementsByUser(ctx, dbgen.ListGoalAchievementsByUserParams{UserID: db.PGUUID(userID), GoalIds: goalIDs})
+	if err != nil {
+		return nil, err
+	}
+	for _, row := range achievements {
+		participation := byGoal[db.UUID(row.GoalID)]
+		participation.Achievements = append(participation.Achievements, &Achievement{
+			PeriodStart: db.Date(row.PeriodStart),
+			AchievedAt:  db.Time(row.AchievedAt),
+		})
+	}
+	return participations, nil
+}
+
+func (r *postgresRepository) ListActiveByParticipant(ctx context.Context, userID uuid.UUID, day time.Time) ([]*Goal, error) {
+	rows, err := r.queries.ListActiveGoalsByParticipant(ctx, dbgen.ListActiveGoalsByParticipantParams{UserID: db.PGUUID(userID), Day: db.PGDate(day.UTC())})
+	if err != nil {
+		return nil, err
+	}
+	return mapGoals(rows), nil
+}
+
+func (r *postgresRepository) Update(ctx context.Context, goal *Goal) (*Goal, error) {
+	tx, err := r.db.Begin(ctx)
+	if err != nil {
+		return nil, err
+	}
+	defer tx.Rollback(ctx)
+	qtx := r.queries.WithTx(tx)
+
+	row, err := qtx.UpdateGoal(ctx, dbgen.UpdateGoalParams{
+		ID:         db.PGUUID(goal.ID),
+		Title:      goal.Title,
+		Target:     int32(goal.Target),
+		Period:     string(goal.Period),
+		SourceType: db.PGText(goal.SourceType),
+		StartsOn:   db.PGDate(goal.StartsOn),
+		EndsOn:     db.PGDate(goal.EndsOn),
+		Shared:     goal.Shared,
+	})
+	if errors.Is(err, pgx.ErrNoRows) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	updated := mapGoal(row)
+	if err := outbox.Record(ctx, qtx, AggregateGoal, updated.ID, EventGoalUpdated, updated); err != nil {
+		return nil, err
+	}
+
+	if err := tx.Commit(ctx); err != nil {
+		return nil, err
+	}
+	return updated, nil
+}
+
+func (r *postgresRepository) Delete(ctx context.Context, id uuid.UUID) error {
+	tx, err := r.db.Begin(ctx)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback(ctx)
+	qtx := r.queries.WithTx(tx)
+
+	deleted, err := qtx.DeleteGoal(ctx, db.PGUUID(id))
+	if err != nil {
+		return err
+	}
+	if deleted > 0 {
+		if err := outbox.Record(ctx, qtx, AggregateGoal, id, EventGoalDeleted, outbox.DeletedPayload{ID: id}); err != nil {
+			return err
+		}
+	}
+	return tx.Commit(ctx)
+}
+
+func (r *postgresRepository) AddParticipant(ctx context.Context, goalID, userID uuid.UUID) error {
+	_, err := r.queries.AddGoalParticipant(ctx, dbgen.AddGoalParticipantParams{GoalID: db.PGUUID(goalID), UserID: db.PGUUID(userID)})
+	return err
+}
+
+func (r *postgresRepository) RemoveParticipant(ctx context.Context, goalID, userID uuid.UUID) error {
+	_, err := r.queries.RemoveGoalParticipant(ctx, dbgen.RemoveGoalParticipantParams{GoalID: db.PGUUID(goalID), UserID: db.PGUUID(userID)})
+	return err
+}
+
+func (r *postgresRepository) IsParticipant(ctx context.Context, goalID, userID uuid.UUID) (bool, error) {
+	return r.queries.IsGoalParticipant(ctx, dbgen.IsGoalParticipantParams{GoalID: db.PGUUID(goalID), UserID: db.PGUUID(userID)})
+}
+
+func (r *postgresRepository) CountCompleted(ctx context.Context, userID uuid.UUID, sourceType *string, window Window) (int, error) {
+	count, err := r.queries.CountCompletedInWindow(ctx, dbgen.CountCompletedInWindowParams{
+		UserID:      db.PGUUID(userID),
+		WindowStart: db.PGTimestamptz(window.Start),
+		WindowEnd:   db.PGTimestamptz(window.End),
+		SourceType:  db.PGText(sourceType),
+	})
+	return int(count), err
+}
+
+func (r *postgresRepository) GetAchievement(ctx context.Context, goalID, userID uuid.UUID, periodStart time.Time) (*time.Time, error) {
+	achievedAt, err := r.queries.GetGoalAchievement(ctx, dbgen.GetGoalAchievementParams{GoalID: db.PGUUID(goalID), UserID: db.PGUUID(userID), PeriodStart: db.PGDate(periodStart)})
+	if errors.Is(err, pgx.ErrNoRows) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	return db.TimePtr(achievedAt), nil
+}
+
+// RecordAchievement stores the first time a participant meets a period's
+// target and announces it. It reports false when it was already recorded.
+func (r *postgresRepository) RecordAchievement(ctx context.Context, goal *Goal, userID uuid.UUID, periodStart time.Time, completed int) (bool, error) {
+	tx, err := r.db.Begin(ctx)
+	if err != nil {
+		return false, err
+	}
+	defer tx.Rollback(ctx)
+	qtx := r.queries.WithTx(tx)
+
+	inserted, err := qtx.CreateGoalAchievement(ctx, dbgen.CreateGoalAchievementParams{GoalID: db.PGUUID(goal.ID), UserID: db.PGUUID(userID), PeriodStart: db.PGDate(periodStart)})
+	if err != nil {
+		return false, err
+	}
+	if inserted == 0 {
+		return false, nil
+	}
+	payload := AchievedPayload{GoalID: goal.ID, UserID: userID, PeriodStart: periodStart, Completed: completed, Target: goal.Target}
+	if err := outbox.Record(ctx, qtx, AggregateGoal, goal.ID, EventGoalAchieved, payload); err != nil {
+		return false, err
+	}
+
+	if err := tx.Commit(ctx); err != nil {
+		return false, err
+	}
+	return true, nil
+}
+
+func (r *postgresRepository) ListStandings(ctx context.Context, goal *Goal, window Window, limit, offset int) ([]*Standing, error) {
+	rows, err := r.queries.ListGoalStandings(ctx, dbgen.ListGoalStandingsParams{
+		WindowStart: db.PGTimestamptz(window.Start),
+		WindowEnd:   db.PGTimestamptz(window.End),
+		SourceType:  db.PGText(goal.SourceType),
+		PeriodStart: db.PGDate(window.Start),
+		GoalID:      db.PGUUID(goal.ID),
+		Limit:       int32(limit),
+		Offset:      int32(offset),
+	})
+	if err != nil {
+		return nil, err
+	}
+	standings := make([]*Standing, 0, len(rows))
+	for _, row := range rows {
+		standings = append(standings, &Standing{
+			UserID:     db.UUID(row.UserID),
+			Username:   row.Username,
+			JoinedAt:   db.Time(row.JoinedAt),
+			Completed:  int(row.Completed),
+			AchievedAt: db.TimePtr(row.AchievedAt),
+		})
+	}
+	return standings, nil
+}
+
+func mapGoals(rows []dbgen.Goal) []*Goal {
+	goals := make([]*Goal, 0, len(rows))
+	for _, row := range rows {
+		goals = append(goals, mapGoal(row))
+	}
+	return goals
+}
+
+func mapGoal(row dbgen.Goal) *Goal {
+	return &Goal{
+		ID:         db.UUID(row.ID),
+		OwnerID:    db.UUID(row.OwnerID),
+		Title:      row.Title,
+		Target:     int(row.Target),
+		Period:     Period(row.Period),
+		SourceType: db.StringPtr(row.SourceType),
+		StartsOn:   db.Date(row.StartsOn),
+		EndsOn:     db.Date(row.EndsOn),
+		Shared:     row.Shared,
+		CreatedAt:  db.Time(row.CreatedAt),
+		UpdatedAt:  db.Time(row.UpdatedAt),
+	}
+}
@@ -0,0 +1,328 @@
+package goals
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"log/slog"
+	"strings"
+	"time"
+
+	"github.com/gofrs/uuid/v5"
+	"github.com/zizouhuweidi/maktaba/internal/library"
+	"github.com/zizouhuweidi/maktaba/internal/outbox"
+	"github.com/zizouhuweidi/maktaba/internal/sources"
+)
+
+var (
+	ErrGoalNotFound     = errors.New("goal not found")
+	ErrInvalidGoal      = errors.New("invalid goal")
+	ErrForbidden        = errors.New("only the goal owner can change it")
+	ErrNotShared        = errors.New("goal is not shared")
+	ErrOwnerCannotLeave = errors.New("the goal owner cannot leave it")
+)
+
+const (
+	maxTitleLength = 255
+	maxTarget      = 10000
+	// maxSpan keeps goals to a length where a steady pace still means something
+	maxSpan = 10 * 366 * 24 * time.Hour
+)
+
+type Service struct {
+	repo   Repository
+	logger *slog.Logger
+}
+
+func NewService(repo Repository, logger *slog.Logger) *Service {
+	return &Service{repo: repo, logger: logger}
+}
+
+func (s *Service) Create(ctx context.Context, params CreateGoalParams) (*GoalWithProgress, error) {
+	if params.OwnerID == uuid.Nil {
+		return nil, ErrInvalidGoal
+	}
+	if params.Period == "" {
+		params.Period = PeriodTotal
+	}
+	goal := &Goal{
+		OwnerID:    params.OwnerID,
+		Title:      strings.TrimSpace(params.Title),
+		Target:     params.Target,
+		Period:     params.Period,
+		SourceType: params.SourceType,
+		StartsOn:   day(params.StartsOn),
+		EndsOn:     day(params.EndsOn),
+		Shared:     params.Shared,
+	}
+	if err := validateGoal(goal); err != nil {
+		return nil, err
+	}
+
+	created, err := s.repo.Create(ctx, goal)
+	if err != nil {
+		s.logger.Error("failed to create goal", "error", err)
+		return nil, err
+	}
+	return s.withProgress(ctx, created, created.OwnerID)
+}
+
+// Get returns a goal the actor takes part in, or any shared goal. Progress
+// is only filled in for participants.
+func (s *Service) Get(ctx context.Context, id, actor uuid.UUID) (*GoalWithProgress, error) {
+	goal, participant, err := s.visibleGoal(ctx, id, actor)
+	if err != nil {
+		return nil, err
+	}
+	if !participant {
+		return &GoalWithProgress{Goal: goal}, nil
+	}
+	return s.withProgress(ctx, goal, actor)
+}
+
+func (s *Service) List(ctx context.Context, actor uuid.UUID, limit, offset int) ([]*GoalWithProgress, error) {
+	limit, offset = normalizePagination(limit, offset)
+	goals, err := s.repo.ListByParticipant(ctx, actor, limit, offset)
+	if err != nil {
+		return nil, err
+	}
+	result := make([]*GoalWithProgress, 0, len(goals))
+	for _, goal := range goals {
+		withProgress, err := s.withProgress(ctx, goal, actor)
+		if err != nil {
+			return nil, err
+		}
+		result = append(result, withProgress)
+	}
+	return result, nil
+}
+
+// ListParticipations returns the goals the user set or joined with the
+// periods they met, for the account export.
+func (s *Service) ListParticipations(ctx context.Context, userID uuid.UUID, limit, offset int) ([]*Participation, error) {
+	limit, offset = normalizePagination(limit, offset)
+	return s.repo.ListParticipations(ctx, userID, limit, offset)
+}
+
+func (s *Service) Update(ctx context.Context, id, actor uuid.UUID, params UpdateGoalParams) (*GoalWithProgress, error) {
+	goal, err := s.ownedGoal(ctx, id, actor)
+	if err != nil {
+		return nil, err
+	}
+
+	if params.Title != nil {
+		goal.Title = strings.TrimSpace(*params.Title)
+	}
+	if params.Target != nil {
+		goal.Target = *params.Target
+	}
+	if params.Period != nil {
+		goal.Period = *params.Period
+	}
+	if params.SourceType != nil {
+		goal.SourceType = params.SourceType
+		if *params.SourceType == "" {
+			goal.SourceType = nil
+		}
+	}
+	if params.StartsOn != nil {
+		goal.StartsOn = day(*params.StartsOn)
+	}
+	if params.EndsOn != nil {
+		goal.EndsOn = day(*params.EndsOn)
+	}
+	if params.Shared != nil {
+		goal.Shared = *params.Shared
+	}
+	if err := validateGoal(goal); err != nil {
+		return nil, err
+	}
+
+	updated, err := s.repo.Update(ctx, goal)
+	if err != nil {
+		s.logger.Error("failed to update goal", "error", err, "id", id)
+		return nil, err
+	}
+	if updated == nil {
+		return nil, ErrGoalNotFound
+	}
+	return s.withProgress(ctx, updated, actor)
+}
+
+func (s *Service) Delete(ctx context.Context, id, actor uuid.UUID) error {
+	if _, err := s.ownedGoal(ctx, id, actor); err != nil {
+		return err
+	}
+	return s.repo.Delete(ctx, id)
+}
+
+// Join adds the actor to a shared goal. Joining twice is a no-op.
+func (s *Service) Join(ctx context.Context, id, actor uuid.UUID) (*GoalWithProgress, error) {
+	goal, err := s.repo.GetByID(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+	if goal == nil {
+		return nil, ErrGoalNotFound
+	}
+	if !goal.Shared {
+		return nil, ErrNotShared
+	}
+	if err := s.repo.AddParticipant(ctx, id, actor); err != nil {
+		return nil, err
+	}
+	return s.withProgress(ctx, goal, actor)
+}
+
+func (s *Service) Leave(ctx context.Context, id, actor uuid.UUID) error {
+	goal, err := s.repo.GetByID(ctx, id)
+	if err != nil {
+		return err
+	}
+	if goal == nil {
+		return ErrGoalNotFound
+	}
+	if goal.OwnerID == actor {
+		return ErrOwnerCannotLeave
+	}
+	return s.repo.RemoveParticipant(ctx, id, actor)
+}
+
+// Standings ranks every participant of a goal by completions in its
+// current period.
+func (s *Service) Standings(ctx context.Context, id, actor uuid.UUID, limit, offset int) ([]*Standing, error) {
+	goal, _, err := s.visibleGoal(ctx, id, actor)
+	if err != nil {
+		return nil, err
+	}
+	limit, offset = normalizePagination(limit, offset)
+	return s.repo.ListStandings(ctx, goal, windowAt(goal, time.Now()), limit, offset)
+}
+
+// Subscribe evaluates goals whenever a library item is created or updated
+func (s *Service) Subscribe(subscribers *outbox.Subscribers) {
+	subscribers.Subscribe(library.EventLibraryItemCreated, s.HandleLibraryEvent)
+	subscribers.Subscribe(library.EventLibraryItemUpdated, s.HandleLibraryEvent)
+}
+
+// HandleLibraryEvent records an achievement for every goal of the item's
+// owner that the completion pushes over its target. Events are delivered at
+// least once, so recording is idempotent.
+func (s *Service) HandleLibraryEvent(ctx context.Context, event outbox.Event) error {
+	var item library.Item
+	if err := json.Unmarshal(event.Payload, &item); err != nil {
+		return err
+	}
+	if item.Status != library.StatusCompleted || item.CompletedAt == nil {
+		return nil
+	}
+
+	goals, err := s.repo.ListActiveByParticipant(ctx, item.UserID, *item.CompletedAt)
+	if err != nil {
+		return err
+	}
+	for _, goal := range goals {
+		window := windowAt(goal, *item.CompletedAt)
+		completed, err := s.repo.CountCompleted(ctx, item.UserID, goal.SourceType, window)
+		if err != nil {
+			return err
+		}
+		if completed < goal.Target {
+			continue
+		}
+		recorded, err := s.repo.RecordAchievement(ctx, goal, item.UserID, window.Start, completed)
+		if err != nil {
+			return err
+		}
+		if recorded {
+			s.logger.Info("goal achieved", "goal_id", goal.ID, "user_id", item.UserID, "period_start", window.Start)
+		}
+	}
+	return nil
+}
+
+func (s *Service) withProgress(ctx context.Context, goal *Goal, userID uuid.UUID) (*GoalWithProgress, error) {
+	now := time.Now()
+	window := windowAt(goal, now)
+	completed, err := s.repo.CountCompleted(ctx, userID, goal.SourceType, window)
+	if err != nil {
+		return nil, err
+	}
+	progress := progressOf(goal.Target, completed, window, now)
+	if progress.AchievedAt, err = s.repo.GetAchievement(ctx, goal.ID, userID, window.Start); err != nil {
+		return nil, err
+	}
+	return &GoalWithProgress{Goal: goal, Progress: progress}, nil
+}
+
+// visibleGoal loads a goal the actor may see. Private goals the actor is not
+// part of are reported as missing.
+func (s *Service) visibleGoal(ctx context.Context, id, actor uuid.UUID) (*Goal, bool, error) {
+	goal, err := s.repo.GetByID(ctx, id)
+	if err != nil {
+		return nil, false, err
+	}
+	if goal == nil {
+		return nil, false, ErrGoalNotFound
+	}
+	participant, err := s.repo.IsParticipant(ctx, id, actor)
+	if err != nil {
+		return nil, false, err
+	}
+	if !participant && !goal.Shared {
+		return nil, false, ErrGoalNotFound
+	}
+	return goal, participant, nil
+}
+
+func (s *Service) ownedGoal(ctx context.Context, id, actor uuid.UUID) (*Goal, error) {
+	goal, err := s.repo.GetByID(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+	if goal == nil {
+		return nil, ErrGoalNotFound
+	}
+	if goal.OwnerID != actor {
+		return nil, ErrForbidden
+	}
+	return goal, nil
+}
+
+func validateGoal(goal *Goal) error {
+	if goal.Title == "" || len(goal.Title) > maxTitleLength {
+		return ErrInvalidGoal
+	}
+	if goal.Target < 1 || goal.Target > maxTarget {
+		return ErrInvalidGoal
+	}
+	if goal.Period != PeriodTotal && goal.Period != PeriodMonthly {
+		return ErrInvalidGoal
+	}
+	if goal.SourceType != nil && !validSourceType(sources.SourceType(*goal.SourceType)) {
+		return ErrInvalidGoal
+	}
+	if goal.StartsOn.IsZero() || goal.EndsOn.IsZero() || goal.EndsOn.Before(goal.StartsOn) || goal.EndsOn.Sub(goal.StartsOn) > maxSpan {
+		return ErrInvalidGoal
+	}
+	return nil
+}
+
+func validSourceType(sourceType sources.SourceType) bool {
+	switch sourceType {
+	case sources.SourceTypeBook, sources.SourceTypePaper, sources.SourceTypePodcast, sources.SourceTypeVideo, sources.SourceTypeArticle, sources.SourceTypeEssay:
+		return true
+	default:
+		return false
+	}
+}
+
+func normalizePagination(limit, offset int) (int, int) {
+	if limit <= 0 || limit > 100 {
+		limit = 100
+	}
+	if offset < 0 {
+		offset = 0
+	}
+	return limit, offset
+}
@@ -0,0 +1,237 @@
+package goals
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"io"
+	"log/slog"
+	"testing"
+	"time"
+
+	"github.com/gofrs/uuid/v5"
+	"github.com/zizouhuweidi/maktaba/internal/library"
+	"github.com/zizouhuweidi/maktaba/internal/outbox"
+)
+
+type fakeGoalRepo struct {
+	goals     map[uuid.UUID]*Goal
+	completed int
+	window    Window
+	achieved  []time.Time
+	joined    []uuid.UUID
+}
+
+func (r *fakeGoalRepo) Create(ctx context.Context, goal *Goal) (*Goal, error) {
+	goal.ID = uuid.Must(uuid.NewV7())
+	r.goals[goal.ID] = goal
+	return goal, nil
+}
+
+func (r *fakeGoalRepo) GetByID(ctx context.Context, id uuid.UUID) (*Goal, error) {
+	return r.goals[id], nil
+}
+
+func (r *fakeGoalRepo) ListByParticipant(ctx context.Context, userID uuid.UUID, limit, offset int) ([]*Goal, error) {
+	panic("not implemented")
+}
+
+func (r *fakeGoalRepo) ListActiveByParticipant(ctx context.Context, userID uuid.UUID, day time.Time) ([]*Goal, error) {
+	var active []*Goal
+	for _, goal := range r.goals {
+		active = append(active, goal)
+	}
+	return active, nil
+}
+
+func (r *fakeGoalRepo) ListParticipations(ctx context.Context, userID uuid.UUID, limit, offset int) ([]*Participation, error) {
+	panic("not implemented")
+}
+
+func (r *fakeGoalRepo) Update(ctx context.Context, goal *Goal) (*Goal, error) {
+	return goal, nil
+}
+
+func (r *fakeGoalRepo) Delete(ctx context.Context, id uuid.UUID) error {
+	panic("not implemented")
+}
+
+func (r *fakeGoalRepo) AddParticipant(ctx context.Context, goalID, userID uuid.UUID) error {
+	r.joined = append(r.joined, userID)
+	return nil
+}
+
+func (r *fakeGoalRepo) RemoveParticipant(ctx context.Context, goalID, userID uuid.UUID) error {
+	panic("not implemented")
+}
+
+func (r *fakeGoalRepo) IsParticipant(ctx context.Context, goalID, userID uuid.UUID) (bool, error) {
+	return r.goals[goalID] != nil && r.goals[goalID].OwnerID == userID, nil
+}
+
+func (r *fakeGoalRepo) CountCompleted(ctx context.Context, userID uuid.UUID, sourceType *string, window Window) (int, error) {
+	r.window = window
+	return r.completed, nil
+}
+
+func (r *fakeGoalRepo) GetAchievement(ctx context.Context, goalID, userID uuid.UUID, periodStart time.Time) (*time.Time, error) {
+	return nil, nil
+}
+
+func (r *fakeGoalRepo) RecordAchievement(ctx context.Context, goal *Goal, userID uuid.UUID, periodStart time.Time, completed int) (bool, error) {
+	r.achieved = append(r.achieved, periodStart)
+	return true, nil
+}
+
+func (r *fakeGoalRepo) ListStandings(ctx context.Context, goal *Goal, window Window, limit, offset int) ([]*Standing, error) {
+	panic("not implemented")
+}
+
+func testService(repo Repository) *Service {
+	return NewService(repo, slog.New(slog.NewTextHandler(io.Discard, nil)))
+}
+
+func date(year int, month time.Month, day int) time.Time {
+	return time.Date(year, month, day, 0, 0, 0, 0, time.UTC)
+}
+
+func TestCreateValidatesGoal(t *testing.T) {
+	service := testService(&fakeGoalRepo{goals: map[uuid.UUID]*Goal{}})
+	owner := uuid.Must(uuid.NewV7())
+	valid := CreateGoalParams{OwnerID: owner, Title: "Read 24 books", Target: 24, StartsOn: date(2026, 1, 1), EndsOn: date(2026, 12, 31)}
+
+	created, err := service.Create(context.Background(), valid)
+	if err != nil {
+		t.Fatalf("Create returned error: %v", err)
+	}
+	if created.Period != PeriodTotal || created.Progress == nil || created.Progress.Target != 24 {
+		t.Fatalf("created = %+v, want a total goal with progress", created)
+	}
+
+	paper := "zine"
+	invalid := map[string]func(*CreateGoalParams){
+		"blank title":       func(p *CreateGoalParams) { p.Title = "  " },
+		"zero target":       func(p *CreateGoalParams) { p.Target = 0 },
+		"unknown period":    func(p *CreateGoalParams) { p.Period = "weekly" },
+		"unknown type":      func(p *CreateGoalParams) { p.SourceType = &paper },
+		"ends before start": func(p *CreateGoalParams) { p.EndsOn = date(2025, 12, 31) },
+	}
+	for name, mutate := range invalid {
+		t.Run(name, func(t *testing.T) {
+			params := valid
+			mutate(&params)
+			if _, err := service.Create(context.Background(), params); !errors.Is(err, ErrInvalidGoal) {
+				t.Fatalf("error = %v, want %v", err, ErrInvalidGoal)
+			}
+		})
+	}
+}
+
+func TestOnlyOwnerUpdatesAndSharedGoalsCanBeJoined(t *testing.T) {
+	owner, reader := uuid.Must(uuid.NewV7()), uuid.Must(uuid.NewV7())
+	goal := &Goal{ID: uuid.Must(uuid.NewV7()), OwnerID: owner, Title: "Club challenge", Target: 12, Period: PeriodTotal, StartsOn: date(2026, 1, 1), EndsOn: date(2026, 12, 31)}
+	repo := &fakeGoalRepo{goals: map[uuid.UUID]*Goal{goal.ID: goal}}
+	service := testService(repo)
+	title := "Mine now"
+
+	if _, err := service.Update(context.Background(), goal.ID, reader, UpdateGoalParams{Title: &title}); !errors.Is(err, ErrForbidden) {
+		t.Fatalf("Update error = %v, want %v", err, ErrForbidden)
+	}
+	if _, err := service.Get(context.Background(), goal.ID, reader); !errors.Is(err, ErrGoalNotFound) {
+		t.Fatalf("Get error = %v, want private goals hidden", err)
+	}
+	if _, err := service.Join(context.Background(), goal.ID, reader); !errors.Is(err, ErrNotShared) {
+		t.Fatalf("Join error = %v, want %v", err, ErrNotShared)
+	}
+
+	goal.Shared = true
+	if _, err := service.Join(context.Background(), goal.ID, reader); err != nil {
+		t.Fatalf("Join returned error: %v", err)
+	}
+	if len(repo.joined) != 1 || repo.joined[0] != reader {
+		t.Fatalf("joined = %v, want %s", repo.joined, reader)
+	}
+	if err := service.Leave(context.Background(), goal.ID, owner); !errors.Is(err, ErrOwnerCannotLeave) {
+		t.Fatalf("Leave error = %v, want %v", err, ErrOwnerCannotLeave)
+	}
+}
+
+func TestHandleLibraryEventRecordsAchievement(t *testing.T) {
+	userID := uuid.Must(uuid.NewV7())
+	goal := &Goal{ID: uuid.Must(uuid.NewV7()), OwnerID: userID, Title: "3 papers a month", Target: 3, Period: PeriodMonthly, StartsOn: date(2026, 1, 1), EndsOn: date(2026, 12, 31)}
+	repo := &fakeGoalRepo{goals: map[uuid.UUID]*Goal{goal.ID: goal}, completed: 3}
+	service := testService(repo)
+
+	publish := func(item library.Item) error {
+		payload, err := json.Marshal(item)
+		if err != nil {
+			t.Fatal(err)
+		}
+		return service.HandleLibraryEvent(context.Background(), outbox.Event{EventType: library.EventLibraryItemUpdated, Payload: payload})
+	}
+
+	if err := publish(library.Item{UserID: userID, Status: library.StatusInProgress}); err != nil {
+		t.Fatalf("HandleLibraryEvent returned error: %v", err)
+	}
+	if len(repo.achieved) != 0 {
+		t.Fatalf("achieved = %v, want nothing for an unfinished item", repo.achieved)
+	}
+
+	completedAt := time.Date(2026, 4, 18, 21, 0, 0, 0, time.UTC)
+	if err := publish(library.Item{UserID: userID, Status: library.StatusCompleted, CompletedAt: &completedAt}); err != nil {
+		t.Fatalf("HandleLibraryEvent returned error: %v", err)
+	}
+	if len(repo.achieved) != 1 || !repo.achieved[0].Equal(date(2026, 4, 1)) {
+		t.Fatalf("achieved = %v, want April 2026", repo.achieved)
+	}
+	if !repo.window.End.Equal(date(2026, 5, 1)) {
+		t.Fatalf("window end = %v, want May 1", repo.window.End)
+	}
+}
+
+func TestWindowAt(t *testing.T) {
+	monthly := &Goal{Period: PeriodMonthly, StartsOn: date(2026, 1, 15), EndsOn: date(2026, 6, 10)}
+	tests := []struct {
+		name string
+		goal *Goal
+		at   time.Time
+		want Window
+	}{
+		{"total", &Goal{Period: PeriodTotal, StartsOn: date(2026, 1, 1), EndsOn: date(2026, 12, 31)}, date(2026, 5, 5), Window{date(2026, 1, 1), date(2027, 1, 1)}},
+		{"first month is clipped", monthly, date(2026, 1, 20), Window{date(2026, 1, 15), date(2026, 2, 1)}},
+		{"middle month", monthly, date(2026, 3, 31), Window{date(2026, 3, 1), date(2026, 4, 1)}},
+		{"after the goal uses the last month", monthly, date(2026, 9, 1), Window{date(2026, 6, 1), date(2026, 6, 11)}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := windowAt(tt.goal, tt.at); !got.Start.Equal(tt.want.Start) || !got.End.Equal(tt.want.End) {
+				t.Fatalf("windowAt = %v..%v, want %v..%v", got.Start, got.End, tt.want.Start, tt.want.End)
+			}
+		})
+	}
+}
+
+func TestProgressOfPace(t *testing.T) {
+	window := Window{Start: date(2026, 1, 1), End: date(2026, 1, 11)}
+	halfway := date(2026, 1, 6)
+	tests := []struct {
+		name      string
+		completed int
+		now       time.Time
+		want      Pace
+	}{
+		{"upcoming", 0, date(2025, 12, 1), PaceUpcoming},
+		{"on track", 5, halfway, PaceOnTrack},
+		{"ahead", 7, halfway, PaceAhead},
+		{"behind", 3, halfway, PaceBehind},
+		{"achieved", 10, halfway, PaceAchieved},
+		{"missed", 9, date(2026, 2, 1), PaceMissed},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := progressOf(10, tt.completed, window, tt.now); got.Pace != tt.want {
+				t.Fatalf("pace = %q, want %q", got.Pace, tt.want)
+			}
+		})
+	}
+}
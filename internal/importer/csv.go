@@ -0,0 +1,245 @@
+package importer
+
+import (
+	"encoding/csv"
+	"errors"
+	"fmt"
+	"io"
+	"math"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/zizouhuweidi/maktaba/internal/library"
+)
+
+// Format identifies the service that produced a CSV export.
+type Format string
+
+const (
+	FormatGoodreads  Format = "goodreads"
+	FormatStoryGraph Format = "storygraph"
+)
+
+var (
+	ErrUnknownFormat = errors.New("unrecognised import format")
+	ErrEmptyFile     = errors.New("import file is empty")
+)
+
+// Row is one book from an export, normalised across formats.
+type Row struct {
+	Line        int
+	Title       string
+	Authors     []string
+	ISBN10      *string
+	ISBN13      *string
+	Publisher   *string
+	PageCount   *int
+	PublishedAt *time.Time
+	Status      library.Status
+	Rating      *int
+	Review      *string
+	DateAdded   *time.Time
+	DateRead    *time.Time
+}
+
+// Parse reads a Goodreads or StoryGraph CSV export and returns its rows and
+// format. When format is empty it is detected from the header row.
+func Parse(r io.Reader, format Format) ([]Row, Format, error) {
+	reader := csv.NewReader(r)
+	reader.FieldsPerRecord = -1
+	reader.LazyQuotes = true
+
+	header, err := reader.Read()
+	if errors.Is(err, io.EOF) {
+		return nil, "", ErrEmptyFile
+	}
+	if err != nil {
+		return nil, "", err
+	}
+	columns := make(map[string]int, len(header))
+	for i, name := range header {
+		columns[strings.TrimSpace(strings.TrimPrefix(name, "\ufeff"))] = i
+	}
+
+	if format == "" {
+		format = DetectFormat(columns)
+	}
+	var parse func(record) Row
+	switch format {
+	case FormatGoodreads:
+		parse = parseGoodreads
+	case FormatStoryGraph:
+		parse = parseStoryGraph
+	default:
+		return nil, "", ErrUnknownFormat
+	}
+
+	var rows []Row
+	for line := 2; ; line++ {
+		fields, err := reader.Read()
+		if errors.Is(err, io.EOF) {
+			break
+		}
+		if err != nil {
+			return nil, "", fmt.Errorf("line %d: %w", line, err)
+		}
+		row := parse(record{columns: columns, fields: fields})
+		row.Line = line
+		rows = append(rows, row)
+	}
+	return rows, format, nil
+}
+
+// DetectFormat guesses the export format from its header columns.
+func DetectFormat(columns map[string]int) Format {
+	if _, ok := columns["Exclusive Shelf"]; ok {
+		return FormatGoodreads
+	}
+	if _, ok := columns["Read Status"]; ok {
+		return FormatStoryGraph
+	}
+	return ""
+}
+
+type record struct {
+	columns map[string]int
+	fields  []string
+}
+
+func (r record) get(name string) string {
+	i, ok := r.columns[name]
+	if !ok || i >= len(r.fields) {
+		return ""
+	}
+	return strings.TrimSpace(r.fields[i])
+}
+
+func parseGoodreads(r record) Row {
+	row := Row{
+		Title:     r.get("Title"),
+		Publisher: optional(r.get("Publisher")),
+		PageCount: positiveInt(r.get("Number of Pages")),
+		Status:    mapShelf(r.get("Exclusive Shelf")),
+		Rating:    rating(r.get("My Rating")),
+		Review:    optional(r.get("My Review")),
+		DateAdded: date(r.get("Date Added")),
+		DateRead:  date(r.get("Date Read")),
+	}
+	if author := r.get("Author"); author != "" {
+		row.Authors = append(row.Authors, author)
+	}
+	row.Authors = append(row.Authors, splitList(r.get("Additional Authors"))...)
+	row.ISBN10, row.ISBN13 = isbnPair(r.get("ISBN"), r.get("ISBN13"))
+
+	year := r.get("Original Publication Year")
+	if year == "" {
+		year = r.get("Year Published")
+	}
+	row.PublishedAt = yearDate(year)
+	return row
+}
+
+func parseStoryGraph(r record) Row {
+	row := Row{
+		Title:     r.get("Title"),
+		Authors:   splitList(r.get("Authors")),
+		Status:    mapShelf(r.get("Read Status")),
+		Rating:    rating(r.get("Star Rating")),
+		Review:    optional(r.get("Review")),
+		DateAdded: date(r.get("Date Added")),
+		DateRead:  date(r.get("Last Date Read")),
+	}
+	row.ISBN10, row.ISBN13 = isbnPair(r.get("ISBN/UID"), "")
+	return row
+}
+
+// mapShelf maps Goodreads exclusive shelves and StoryGraph read statuses to
+// library statuses. Unknown custom shelves are treated as want-to-read.
+func mapShelf(shelf string) library.Status {
+	switch strings.ToLower(strings.TrimSpace(shelf)) {
+	case "read":
+		return library.StatusCompleted
+	case "currently-reading":
+		return library.StatusInProgress
+	case "paused", "on-hold":
+		return library.StatusPaused
+	case "did-not-finish", "dnf", "abandoned":
+		return library.StatusAbandoned
+	default:
+		return library.StatusToConsume
+	}
+}
+
+func isbnPair(values ...string) (*string, *string) {
+	var isbn10, isbn13 string
+	for _, value := range values {
+		switch isbn := normalizeISBN(value); len(isbn) {
+		case 10:
+			isbn10 = isbn
+		case 13:
+			isbn13 = isbn
+		}
+	}
+	if isbn13 == "" && isbn10 != "" {
+		isbn13 = isbn10To13(isbn10)
+	}
+	if isbn10 == "" && isbn13 != "" {
+		isbn10 = isbn13To10(isbn13)
+	}
+	return optional(isbn10), optional(isbn13)
+}
+
+// rating converts a 0-5 star rating, rounding half stars. Zero means unrated.
+func rating(value string) *int {
+	parsed, err := strconv.ParseFloat(value, 64)
+	if err != nil || parsed <= 0 {
+		return nil
+	}
+	rounded := int(math.Round(parsed))
+	rounded = max(1, min(5, rounded))
+	return &rounded
+}
+
+func date(value string) *time.Time {
+	for _, layout := range []string{"2006/01/02", "2006-01-02", "2006/1/2"} {
+		if parsed, err := time.Parse(layout, value); err == nil {
+			return &parsed
+		}
+	}
+	return nil
+}
+
+func yearDate(value string) *time.Time {
+	year, err := strconv.Atoi(value)
+	if err != nil || year <= 0 {
+		return nil
+	}
+	published := time.Date(year, time.January, 1, 0, 0, 0, 0, time.UTC)
+	return &published
+}
+
+func positiveInt(value string) *int {
+	parsed, err := strconv.Atoi(value)
+	if err != nil || parsed <= 0 {
+		return nil
+	}
+	return &parsed
+}
+
+func splitList(value string) []string {
+	var items []string
+	for _, item := range strings.Split(value, ",") {
+		if item = strings.TrimSpace(item); item != "" {
+			items = append(items, item)
+		}
+	}
+	return items
+}
+
+func optional(value string) *string {
+	if value == "" {
+		return nil
+	}
+	return &value
+}
@@ -0,0 +1,118 @@
+package importer
+
+import (
+	"errors"
+	"strings"
+	"testing"
+
+	"github.com/zizouhuweidi/maktaba/internal/library"
+)
+
+const goodreadsExport = "\ufeffBook Id,Title,Author,Additional Authors,ISBN,ISBN13,My Rating,Publisher,Number of Pages,Year Published,Original Publication Year,Date Read,Date Added,Exclusive Shelf,My Review\n" +
+	`1,The Muqaddimah,Ibn Khaldun,"Franz Rosenthal, N. J. Dawood","=""0691120544""","=""9780691120546""",4,Princeton University Press,512,2004,1377,2024/03/02,2024/01/15,read,A history of history` + "\n" +
+	`2,Pride and Prejudice,Jane Austen,,"=""""","=""9780141439518""",0,Penguin,480,2002,1813,,2024/05/01,currently-reading,` + "\n" +
+	`3,Unknown Pamphlet,,,"=""""","=""""",0,,,,,,,to-read,` + "\n"
+
+const storyGraphExport = "Title,Authors,Contributors,ISBN/UID,Format,Read Status,Date Added,Last Date Read,Star Rating,Review\n" +
+	`Pride and Prejudice,Jane Austen,,9780141439518,paperback,read,2024/05/01,2024/06/10,4.5,` + "\n" +
+	`The Muqaddimah,"Ibn Khaldun, Franz Rosenthal",,0-691-12054-4,hardcover,did-not-finish,2024/01/15,,,` + "\n"
+
+func TestParseGoodreads(t *testing.T) {
+	rows, format, err := Parse(strings.NewReader(goodreadsExport), "")
+	if err != nil {
+		t.Fatalf("Parse() error = %v", err)
+	}
+	if format != FormatGoodreads {
+		t.Fatalf("format = %q, want %q", format, FormatGoodreads)
+	}
+	if len(rows) != 3 {
+		t.Fatalf("rows = %d, want 3", len(rows))
+	}
+
+	first := rows[0]
+	if first.Line != 2 || first.Title != "The Muqaddimah" {
+		t.Fatalf("first row = %+v", first)
+	}
+	if len(first.Authors) != 3 || first.Authors[0] != "Ibn Khaldun" || first.Authors[2] != "N. J. Dawood" {
+		t.Fatalf("authors = %v", first.Authors)
+	}
+	if first.ISBN10 == nil || *first.ISBN10 != "0691120544" || first.ISBN13 == nil || *first.ISBN13 != "9780691120546" {
+		t.Fatalf("isbn = %v / %v", first.ISBN10, first.ISBN13)
+	}
+	if first.Status != library.StatusCompleted || first.Rating == nil || *first.Rating != 4 {
+		t.Fatalf("status = %q, rating = %v", first.Status, first.Rating)
+	}
+	if first.PublishedAt == nil || first.PublishedAt.Year() != 1377 {
+		t.Fatalf("published = %v, want original publication year", first.PublishedAt)
+	}
+	if first.DateRead == nil || first.DateRead.Format("2006-01-02") != "2024-03-02" {
+		t.Fatalf("date read = %v", first.DateRead)
+	}
+
+	second := rows[1]
+	if second.ISBN10 == nil || *second.ISBN10 != "0141439513" {
+		t.Fatalf("isbn10 = %v, want derived from isbn13", second.ISBN10)
+	}
+	if second.Status != library.StatusInProgress || second.Rating != nil || second.Review != nil {
+		t.Fatalf("second row = %+v", second)
+	}
+
+	third := rows[2]
+	if third.ISBN10 != nil || third.ISBN13 != nil || third.Authors != nil || third.Status != library.StatusToConsume {
+		t.Fatalf("third row = %+v", third)
+	}
+}
+
+func TestParseStoryGraph(t *testing.T) {
+	rows, format, err := Parse(strings.NewReader(storyGraphExport), "")
+	if err != nil {
+		t.Fatalf("Parse() error = %v", err)
+	}
+	if format != FormatStoryGraph {
+		t.Fatalf("format = %q, want %q", format, FormatStoryGraph)
+	}
+	if len(rows) != 2 {
+		t.Fatalf("rows = %d, want 2", len(rows))
+	}
+	if rows[0].Rating == nil || *rows[0].Rating != 5 {
+		t.Fatalf("rating = %v, want half stars rounded up", rows[0].Rating)
+	}
+	if rows[0].Status != library.StatusCompleted || rows[0].DateRead == nil {
+		t.Fatalf("first row = %+v", rows[0])
+	}
+	if rows[1].Status != library.StatusAbandoned || len(rows[1].Authors) != 2 {
+		t.Fatalf("second row = %+v", rows[1])
+	}
+	if rows[1].ISBN13 == nil || *rows[1].ISBN13 != "9780691120546" {
+		t.Fatalf("isbn13 = %v, want converted from hyphenated isbn10", rows[1].ISBN13)
+	}
+}
+
+func TestParseRejectsUnknownFormat(t *testing.T) {
+	_, _, err := Parse(strings.NewReader("name,author\nfoo,bar\n"), "")
+	if !errors.Is(err, ErrUnknownFormat) {
+		t.Fatalf("Parse() error = %v, want ErrUnknownFormat", err)
+	}
+	_, _, err = Parse(strings.NewReader(""), "")
+	if !errors.Is(err, ErrEmptyFile) {
+		t.Fatalf("Parse() error = %v, want ErrEmptyFile", err)
+	}
+}
+
+func TestNormalizeISBN(t *testing.T) {
+	tests := map[string]string{
+		`="0141439513"`:     "0141439513",
+		"978-0-14-143951-8": "9780141439518",
+		"0141439514":        "",
+		"9780141439519":     "",
+		"B00ABC1234":        "",
+	}
+	for input, want := range tests {
+		if got := normalizeISBN(input); got != want {
+			t.Errorf("normalizeISBN(%q) = %q, want %q", input, got, want)
+		}
+	}
+	if got := isbn13To10("9791234567896"); got != "" {
+		t.Errorf("isbn13To10(979 prefix) = %q, want empty", got)
+	}
+}
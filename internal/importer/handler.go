@@ -0,0 +1,76 @@
+package importer
+
+import (
+	"errors"
+	"log/slog"
+	"net/http"
+
+	"github.com/labstack/echo/v5"
+	"github.com/zizouhuweidi/maktaba/internal/auth"
+	"github.com/zizouhuweidi/maktaba/internal/library"
+)
+
+// maxUploadBytes bounds the size of an uploaded export.
+const maxUploadBytes = 10 << 20
+
+type Handler struct {
+	service *Service
+	logger  *slog.Logger
+}
+
+func NewHandler(service *Service, logger *slog.Logger) *Handler {
+	return &Handler{service: service, logger: logger}
+}
+
+func (h *Handler) RegisterProtectedRoutes(g *echo.Group) {
+	g.POST("/library/import", h.Import)
+}
+
+// Import accepts a multipart upload with a "file" field and optional
+// "format", "visibility" and "public_reviews" fields.
+func (h *Handler) Import(c *echo.Context) error {
+	userID, ok := auth.UserID(c)
+	if !ok {
+		return echo.NewHTTPError(http.StatusUnauthorized, "authentication required")
+	}
+
+	c.Request().Body = http.MaxBytesReader(c.Response(), c.Request().Body, maxUploadBytes)
+	header, err := c.FormFile("file")
+	if err != nil {
+		return echo.NewHTTPError(http.StatusBadRequest, "file is required")
+	}
+	file, err := header.Open()
+	if err != nil {
+		return echo.NewHTTPError(http.StatusBadRequest, "invalid file")
+	}
+	defer file.Close()
+
+	format := Format(c.FormValue("format"))
+	if format != "" && format != FormatGoodreads && format != FormatStoryGraph {
+		return echo.NewHTTPError(http.StatusBadRequest, "format must be goodreads or storygraph")
+	}
+	visibility := library.Visibility(c.FormValue("visibility"))
+	switch visibility {
+	case "", library.VisibilityPrivate, library.VisibilityUnlisted, library.VisibilityPublic:
+	default:
+		return echo.NewHTTPError(http.StatusBadRequest, "invalid visibility")
+	}
+
+	report, err := h.service.Import(c.Request().Context(), file, Options{
+		UserID:        userID,
+		Format:        format,
+		Visibility:    visibility,
+		PublicReviews: c.FormValue("public_reviews") == "true",
+	})
+	if errors.Is(err, ErrUnknownFormat) {
+		return echo.NewHTTPError(http.StatusBadRequest, "unrecognised export format")
+	}
+	if errors.Is(err, ErrInvalidImport) {
+		return echo.NewHTTPError(http.StatusBadRequest, "invalid import file")
+	}
+	if err != nil {
+		return echo.NewHTTPError(http.StatusInternalServerError, "failed to import library")
+	}
+
+	return c.JSON(http.StatusOK, report)
+}
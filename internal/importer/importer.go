@@ -0,0 +1,277 @@
+// Package importer brings reading history from Goodreads and StoryGraph CSV
+// exports into a user's library.
+package importer
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"log/slog"
+	"strings"
+
+	"github.com/gofrs/uuid/v5"
+	"github.com/zizouhuweidi/maktaba/internal/library"
+	"github.com/zizouhuweidi/maktaba/internal/reviews"
+	"github.com/zizouhuweidi/maktaba/internal/sources"
+)
+
+// Outcome describes what happened to a single imported row.
+type Outcome string
+
+const (
+	OutcomeCreated Outcome = "created"
+	OutcomeMatched Outcome = "matched"
+	OutcomeSkipped Outcome = "skipped"
+	OutcomeFailed  Outcome = "failed"
+)
+
+var ErrInvalidImport = errors.New("invalid import")
+
+// Books finds, creates and removes book sources. sources.Service satisfies
+// it.
+type Books interface {
+	FindBookByISBN(ctx context.Context, isbn10, isbn13 *string) (*sources.Source, error)
+	CreateBook(ctx context.Context, params sources.CreateBookParams) (*sources.Book, error)
+	Delete(ctx context.Context, id uuid.UUID, actor sources.Actor) error
+}
+
+// Library adds items to a user's library. library.Service satisfies it.
+type Library interface {
+	Create(ctx context.Context, params library.CreateItemParams) (*library.Item, error)
+}
+
+// Reviews records ratings. reviews.Service satisfies it.
+type Reviews interface {
+	Create(ctx context.Context, params reviews.CreateReviewParams) (*reviews.Review, error)
+}
+
+// Options controls how imported rows are stored.
+type Options struct {
+	UserID        uuid.UUID
+	Format        Format
+	Visibility    library.Visibility
+	PublicReviews bool
+}
+
+// RowResult reports the outcome for one CSV row.
+type RowResult struct {
+	Line     int        `json:"line"`
+	Title    string     `json:"title"`
+	Outcome  Outcome    `json:"outcome"`
+	SourceID *uuid.UUID `json:"source_id,omitempty"`
+	Reason   string     `json:"reason,omitempty"`
+}
+
+// Report summarises an import.
+type Report struct {
+	Format  Format      `json:"format"`
+	Created int         `json:"created"`
+	Matched int         `json:"matched"`
+	Skipped int         `json:"skipped"`
+	Failed  int         `json:"failed"`
+	Rows    []RowResult `json:"rows"`
+}
+
+func (r *Report) add(result RowResult) {
+	switch result.Outcome {
+	case OutcomeCreated:
+		r.Created++
+	case OutcomeMatched:
+		r.Matched++
+	case OutcomeSkipped:
+		r.Skipped++
+	case OutcomeFailed:
+		r.Failed++
+	}
+	r.Rows = append(r.Rows, result)
+}
+
+type Service struct {
+	books   Books
+	library Library
+	reviews Reviews
+	logger  *slog.Logger
+}
+
+func NewService(books Books, library Library, reviews Reviews, logger *slog.Logger) *Service {
+	return &Service{books: books, library: library, reviews: reviews, logger: logger}
+}
+
+// Import parses a CSV export and adds every book to the user's library.
+// Rows are processed independently so one bad row does not abort the rest.
+func (s *Service) Import(ctx context.Context, r io.Reader, opts Options) (*Report, error) {
+	if opts.UserID == uuid.Nil {
+		return nil, ErrInvalidImport
+	}
+	if opts.Visibility == "" {
+		opts.Visibility = library.VisibilityPrivate
+	}
+
+	rows, format, err := Parse(r, opts.Format)
+	if err != nil {
+		return nil, fmt.Errorf("%w: %w", ErrInvalidImport, err)
+	}
+
+	report := &Report{Format: format, Rows: make([]RowResult, 0, len(rows))}
+	seen := make(map[string]uuid.UUID)
+	for _, row := range rows {
+		report.add(s.importRow(ctx, row, opts, seen))
+	}
+
+	s.logger.Info("library import finished", "user_id", opts.UserID, "created", report.Created, "matched", report.Matched, "skipped", report.Skipped, "failed", report.Failed)
+	return report, nil
+}
+
+func (s *Service) importRow(ctx context.Context, row Row, opts Options, seen map[string]uuid.UUID) RowResult {
+	result := RowResult{Line: row.Line, Title: row.Title}
+	if row.Title == "" {
+		result.Outcome = OutcomeSkipped
+		result.Reason = "missing title"
+		return result
+	}
+
+	keys := dedupeKeys(row)
+	for _, key := range keys {
+		if sourceID, ok := seen[key]; ok {
+			result.Outcome = OutcomeSkipped
+			result.SourceID = &sourceID
+			result.Reason = "duplicate row"
+			return result
+		}
+	}
+
+	sourceID, created, err := s.resolveBook(ctx, row, opts.UserID)
+	if errors.Is(err, sources.ErrInvalidSource) {
+		result.Outcome = OutcomeFailed
+		result.Reason = "invalid book"
+		return result
+	} else if err != nil {
+		s.logger.Error("failed to import book", "error", err, "line", row.Line)
+		result.Outcome = OutcomeFailed
+		result.Reason = "failed to save book"
+		return result
+	}
+	for _, key := range keys {
+		seen[key] = sourceID
+	}
+	result.SourceID = &sourceID
+	result.Outcome = OutcomeMatched
+	if created {
+		result.Outcome = OutcomeCreated
+	}
+
+	item := library.CreateItemParams{
+		UserID:     opts.UserID,
+		SourceID:   sourceID,
+		Status:     row.Status,
+		Visibility: opts.Visibility,
+	}
+	switch row.Status {
+	case library.StatusCompleted:
+		item.CompletedAt = row.DateRead
+		item.CompletedAtUnknown = row.DateRead == nil
+	case library.StatusInProgress:
+		item.StartedAt = row.DateAdded
+	}
+	if _, err := s.library.Create(ctx, item); errors.Is(err, library.ErrItemExists) {
+		result.Outcome = OutcomeSkipped
+		result.Reason = "already in library"
+		return result
+	} else if err != nil {
+		s.logger.Error("failed to import library item", "error", err, "line", row.Line)
+		if created {
+			s.discardBook(ctx, sourceID, opts.UserID)
+			for _, key := range keys {
+				delete(seen, key)
+			}
+			result.SourceID = nil
+		}
+		result.Outcome = OutcomeFailed
+		result.Reason = "failed to add to library"
+		return result
+	}
+
+	if row.Rating != nil {
+		_, err := s.reviews.Create(ctx, reviews.CreateReviewParams{
+			UserID:   opts.UserID,
+			SourceID: sourceID,
+			Rating:   *row.Rating,
+			Content:  row.Review,
+			IsPublic: opts.PublicReviews,
+		})
+		if err != nil && !errors.Is(err, reviews.ErrReviewExists) {
+			s.logger.Error("failed to import review", "error", err, "line", row.Line)
+			result.Reason = "rating not imported"
+		}
+	}
+	return result
+}
+
+// resolveBook returns the ID of an existing book with the same ISBN, or
+// creates the book with its contributors.
+func (s *Service) resolveBook(ctx context.Context, row Row, userID uuid.UUID) (uuid.UUID, bool, error) {
+	if row.ISBN10 != nil || row.ISBN13 != nil {
+		existing, err := s.books.FindBookByISBN(ctx, row.ISBN10, row.ISBN13)
+		if err != nil {
+			return uuid.Nil, false, err
+		}
+		if existing != nil {
+			return existing.ID, false, nil
+		}
+	}
+
+	contributors := make([]sources.ContributorInput, 0, len(row.Authors))
+	for _, author := range row.Authors {
+		contributors = append(contributors, sources.ContributorInput{Name: author, Role: "author"})
+	}
+	book, err := s.books.CreateBook(ctx, sources.CreateBookParams{
+		Title:        row.Title,
+		ISBN10:       row.ISBN10,
+		ISBN13:       row.ISBN13,
+		Publisher:    row.Publisher,
+		PageCount:    row.PageCount,
+		PublishedAt:  row.PublishedAt,
+		Contributors: contributors,
+		CreatedBy:    userID,
+	})
+	if errors.Is(err, sources.ErrDuplicateSource) {
+		// Someone else added the book since the lookup above.
+		existing, err := s.books.FindBookByISBN(ctx, row.ISBN10, row.ISBN13)
+		if err != nil {
+			return uuid.Nil, false, err
+		}
+		if existing != nil {
+			return existing.ID, false, nil
+		}
+		return uuid.Nil, false, sources.ErrDuplicateSource
+	}
+	if err != nil {
+		return uuid.Nil, false, err
+	}
+	return book.Source.ID, true, nil
+}
+
+// discardBook deletes a book this import created for a row that then could
+// not be added to the library, so the import leaves no unused sources.
+func (s *Service) discardBook(ctx context.Context, id, userID uuid.UUID) {
+	if err := s.books.Delete(ctx, id, sources.Actor{UserID: userID}); err != nil {
+		s.logger.Error("failed to remove imported book", "error", err, "source_id", id)
+	}
+}
+
+// dedupeKeys returns the keys a row is matched against earlier rows on:
+// each of its ISBNs, or its title and authors when it has neither.
+func dedupeKeys(row Row) []string {
+	var keys []string
+	if row.ISBN13 != nil {
+		keys = append(keys, "isbn:"+*row.ISBN13)
+	}
+	if row.ISBN10 != nil {
+		keys = append(keys, "isbn:"+*row.ISBN10)
+	}
+	if len(keys) > 0 {
+		return keys
+	}
+	return []string{"title:" + strings.ToLower(row.Title) + "|" + strings.ToLower(strings.Join(row.Authors, ","))}
+}
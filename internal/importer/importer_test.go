@@ -0,0 +1,183 @@
+package importer
+
+import (
+	"context"
+	"errors"
+	"io"
+	"log/slog"
+	"strings"
+	"testing"
+
+	"github.com/gofrs/uuid/v5"
+	"github.com/zizouhuweidi/maktaba/internal/library"
+	"github.com/zizouhuweidi/maktaba/internal/reviews"
+	"github.com/zizouhuweidi/maktaba/internal/sources"
+)
+
+type fakeBooks struct {
+	byISBN  map[string]*sources.Source
+	created []sources.CreateBookParams
+	deleted []uuid.UUID
+	failOn  string
+}
+
+func (b *fakeBooks) FindBookByISBN(ctx context.Context, isbn10, isbn13 *string) (*sources.Source, error) {
+	for _, isbn := range []*string{isbn13, isbn10} {
+		if isbn != nil && b.byISBN[*isbn] != nil {
+			return b.byISBN[*isbn], nil
+		}
+	}
+	return nil, nil
+}
+
+func (b *fakeBooks) CreateBook(ctx context.Context, params sources.CreateBookParams) (*sources.Book, error) {
+	if params.Title == b.failOn {
+		return nil, errors.New("insert failed")
+	}
+	b.created = append(b.created, params)
+	return &sources.Book{Source: &sources.Source{ID: uuid.Must(uuid.NewV7()), Title: params.Title}}, nil
+}
+
+func (b *fakeBooks) Delete(ctx context.Context, id uuid.UUID, actor sources.Actor) error {
+	b.deleted = append(b.deleted, id)
+	return nil
+}
+
+type fakeLibrary struct {
+	items    []library.CreateItemParams
+	existing map[uuid.UUID]bool
+	err      error
+}
+
+func (l *fakeLibrary) Create(ctx context.Context, params library.CreateItemParams) (*library.Item, error) {
+	if l.existing[params.SourceID] {
+		return nil, library.ErrItemExists
+	}
+	if l.err != nil {
+		return nil, l.err
+	}
+	l.items = append(l.items, params)
+	return &library.Item{ID: uuid.Must(uuid.NewV7()), SourceID: params.SourceID, Status: params.Status}, nil
+}
+
+type fakeReviews struct {
+	created []reviews.CreateReviewParams
+}
+
+func (r *fakeReviews) Create(ctx context.Context, params reviews.CreateReviewParams) (*reviews.Review, error) {
+	r.created = append(r.created, params)
+	return &reviews.Review{ID: uuid.Must(uuid.NewV7()), Rating: params.Rating}, nil
+}
+
+func testLogger() *slog.Logger {
+	return slog.New(slog.NewTextHandler(io.Discard, nil))
+}
+
+func TestImportCreatesAndMatchesBooks(t *testing.T) {
+	existing := &sources.Source{ID: uuid.Must(uuid.NewV7()), Title: "Pride and Prejudice"}
+	books := &fakeBooks{byISBN: map[string]*sources.Source{"9780141439518": existing}}
+	lib := &fakeLibrary{}
+	revs := &fakeReviews{}
+	service := NewService(books, lib, revs, testLogger())
+
+	userID := uuid.Must(uuid.NewV7())
+	report, err := service.Import(context.Background(), strings.NewReader(goodreadsExport), Options{UserID: userID})
+	if err != nil {
+		t.Fatalf("Import() error = %v", err)
+	}
+	if report.Format != FormatGoodreads || report.Created != 2 || report.Matched != 1 || report.Skipped != 0 || report.Failed != 0 {
+		t.Fatalf("report = %+v", report)
+	}
+	if *report.Rows[1].SourceID != existing.ID {
+		t.Fatalf("matched source = %v, want %v", report.Rows[1].SourceID, existing.ID)
+	}
+	if len(books.created) != 2 || len(books.created[0].Contributors) != 3 || books.created[0].Contributors[0].Role != "author" {
+		t.Fatalf("created books = %+v", books.created)
+	}
+	if len(lib.items) != 3 || lib.items[0].Visibility != library.VisibilityPrivate || lib.items[0].CompletedAt == nil || lib.items[0].CompletedAtUnknown || lib.items[1].StartedAt == nil {
+		t.Fatalf("library items = %+v", lib.items)
+	}
+	if len(revs.created) != 1 || revs.created[0].Rating != 4 || revs.created[0].UserID != userID || revs.created[0].IsPublic {
+		t.Fatalf("reviews = %+v", revs.created)
+	}
+}
+
+func TestImportSkipsDuplicatesAndExistingItems(t *testing.T) {
+	existing := &sources.Source{ID: uuid.Must(uuid.NewV7())}
+	books := &fakeBooks{byISBN: map[string]*sources.Source{"9780691120546": existing}}
+	lib := &fakeLibrary{existing: map[uuid.UUID]bool{existing.ID: true}}
+	service := NewService(books, lib, &fakeReviews{}, testLogger())
+
+	export := storyGraphExport + `Pride and Prejudice,Jane Austen,,978-0-14-143951-8,ebook,to-read,2024/07/01,,,` + "\n"
+	report, err := service.Import(context.Background(), strings.NewReader(export), Options{UserID: uuid.Must(uuid.NewV7())})
+	if err != nil {
+		t.Fatalf("Import() error = %v", err)
+	}
+	if report.Created != 1 || report.Skipped != 2 {
+		t.Fatalf("report = %+v", report)
+	}
+	if report.Rows[1].Reason != "already in library" || report.Rows[2].Reason != "duplicate row" {
+		t.Fatalf("rows = %+v", report.Rows)
+	}
+}
+
+func TestImportMatchesRowsOnEitherISBN(t *testing.T) {
+	books := &fakeBooks{}
+	service := NewService(books, &fakeLibrary{}, &fakeReviews{}, testLogger())
+
+	// The same ISBN-10 with a different edition's ISBN-13.
+	export := goodreadsExport + `4,The Muqaddimah,Ibn Khaldun,,"=""0691120544""","=""9791234567896""",0,,,,,,2024/01/15,to-read,` + "\n"
+	report, err := service.Import(context.Background(), strings.NewReader(export), Options{UserID: uuid.Must(uuid.NewV7())})
+	if err != nil {
+		t.Fatalf("Import() error = %v", err)
+	}
+	if report.Created != 3 || report.Skipped != 1 || report.Rows[3].Reason != "duplicate row" {
+		t.Fatalf("report = %+v", report)
+	}
+	if *report.Rows[3].SourceID != *report.Rows[0].SourceID {
+		t.Fatalf("duplicate source = %v, want %v", report.Rows[3].SourceID, report.Rows[0].SourceID)
+	}
+}
+
+func TestImportRemovesBooksItCouldNotShelve(t *testing.T) {
+	existing := &sources.Source{ID: uuid.Must(uuid.NewV7())}
+	books := &fakeBooks{byISBN: map[string]*sources.Source{"9780141439518": existing}}
+	lib := &fakeLibrary{err: errors.New("insert failed")}
+	service := NewService(books, lib, &fakeReviews{}, testLogger())
+
+	report, err := service.Import(context.Background(), strings.NewReader(storyGraphExport), Options{UserID: uuid.Must(uuid.NewV7())})
+	if err != nil {
+		t.Fatalf("Import() error = %v", err)
+	}
+	if report.Failed != 2 || report.Rows[0].SourceID == nil || report.Rows[1].SourceID != nil {
+		t.Fatalf("report = %+v", report)
+	}
+	if len(books.created) != 1 || len(books.deleted) != 1 || books.deleted[0] == existing.ID {
+		t.Fatalf("created %d books and deleted %v, want only the new book removed", len(books.created), books.deleted)
+	}
+}
+
+func TestImportReportsFailedRows(t *testing.T) {
+	books := &fakeBooks{failOn: "The Muqaddimah"}
+	service := NewService(books, &fakeLibrary{}, &fakeReviews{}, testLogger())
+
+	report, err := service.Import(context.Background(), strings.NewReader(storyGraphExport), Options{UserID: uuid.Must(uuid.NewV7())})
+	if err != nil {
+		t.Fatalf("Import() error = %v", err)
+	}
+	if report.Created != 1 || report.Failed != 1 || report.Rows[1].Outcome != OutcomeFailed {
+		t.Fatalf("report = %+v", report)
+	}
+}
+
+func TestImportRejectsInvalidInput(t *testing.T) {
+	service := NewService(&fakeBooks{}, &fakeLibrary{}, &fakeReviews{}, testLogger())
+
+	if _, err := service.Import(context.Background(), strings.NewReader(goodreadsExport), Options{}); !errors.Is(err, ErrInvalidImport) {
+		t.Fatalf("Import() error = %v, want ErrInvalidImport", err)
+	}
+	_, err := service.Import(context.Background(), strings.NewReader("a,b\n1,2\n"), Options{UserID: uuid.Must(uuid.NewV7())})
+	if !errors.Is(err, ErrInvalidImport) || !errors.Is(err, ErrUnknownFormat) {
+		t.Fatalf("Import() error = %v, want ErrInvalidImport wrapping ErrUnknownFormat", err)
+	}
+}
@@ -0,0 +1,88 @@
+package importer
+
+import "strings"
+
+// normalizeISBN strips spreadsheet quoting, hyphens and spaces from an ISBN
+// and returns it only when it has a valid ISBN-10 or ISBN-13 checksum.
+func normalizeISBN(value string) string {
+	value = strings.TrimSpace(value)
+	value = strings.TrimPrefix(value, "=")
+	value = strings.Trim(value, `"`)
+	value = strings.ToUpper(strings.NewReplacer("-", "", " ", "").Replace(value))
+
+	switch len(value) {
+	case 10:
+		if validISBN10(value) {
+			return value
+		}
+	case 13:
+		if validISBN13(value) {
+			return value
+		}
+	}
+	return ""
+}
+
+func validISBN10(isbn string) bool {
+	sum := 0
+	for i, r := range isbn {
+		var digit int
+		switch {
+		case r >= '0' && r <= '9':
+			digit = int(r - '0')
+		case r == 'X' && i == 9:
+			digit = 10
+		default:
+			return false
+		}
+		sum += digit * (10 - i)
+	}
+	return sum%11 == 0
+}
+
+func validISBN13(isbn string) bool {
+	sum := 0
+	for i, r := range isbn {
+		if r < '0' || r > '9' {
+			return false
+		}
+		digit := int(r - '0')
+		if i%2 == 1 {
+			digit *= 3
+		}
+		sum += digit
+	}
+	return sum%10 == 0
+}
+
+// isbn10To13 converts an ISBN-10 into its 978-prefixed ISBN-13.
+func isbn10To13(isbn10 string) string {
+	body := "978" + isbn10[:9]
+	sum := 0
+	for i, r := range body {
+		digit := int(r - '0')
+		if i%2 == 1 {
+			digit *= 3
+		}
+		sum += digit
+	}
+	return body + string(rune('0'+(10-sum%10)%10))
+}
+
+// isbn13To10 converts a 978-prefixed ISBN-13 into an ISBN-10. Other prefixes
+// have no ISBN-10 form and return an empty string.
+func isbn13To10(isbn13 string) string {
+	if !strings.HasPrefix(isbn13, "978") {
+		return ""
+	}
+	body := isbn13[3:12]
+	sum := 0
+	for i, r := range body {
+		sum += int(r-'0') * (10 - i)
+	}
+	check := (11 - sum%11) % 11
+	if check == 10 {
+		return body + "X"
+	}
+	return body + string(rune('0'+check))
+}
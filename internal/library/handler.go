@@ -25,6 +25,7 @@ type createRequest struct {
 	Visibility    string        `json:"visibility,omitempty"`
 	StartedAt     *time.Time    `json:"started_at,omitempty"`
 	CompletedAt   *time.Time    `json:"completed_at,omitempty"`
+	Tags          []string      `json:"tags,omitempty"`
 }
 
 type updateRequest struct {
@@ -34,6 +35,17 @@ type updateRequest struct {
 	Visibility    *string       `json:"visibility,omitempty"`
 	StartedAt     *time.Time    `json:"started_at,omitempty"`
 	CompletedAt   *time.Time    `json:"completed_at,omitempty"`
+	Tags          []string      `json:"tags,omitempty"`
+}
+
+type logSessionRequest struct {
+	StartedAt    time.Time     `json:"started_at" validate:"required"`
+	EndedAt      *time.Time    `json:"ended_at,omitempty"`
+	ProgressFrom *int          `json:"progress_from,omitempty"`
+	ProgressTo   *int          `json:"progress_to,omitempty"`
+	ProgressUnit *ProgressUnit `json:"progress_unit,omitempty"`
+	Device       *string       `json:"device,omitempty"`
+	Finished     bool          `json:"finished,omitempty"`
 }
 
 func NewHandler(service *Service, logger *slog.Logger) *Handler {
@@ -52,6 +64,9 @@ func (h *Handler) RegisterProtectedRoutes(g *echo.Group) {
 	g.GET("/library/items/:id", h.GetMine)
 	g.PUT("/library/items/:id", h.Update)
 	g.DELETE("/library/items/:id", h.Delete)
+	g.POST("/library/items/:id/sessions", h.LogSession)
+	g.GET("/library/items/:id/sessions", h.ListSessions)
+	g.GET("/library/items/:id/completions", h.ListCompletions)
 }
 
 func (h *Handler) Create(c *echo.Context) error {
@@ -82,10 +97,14 @@ func (h *Handler) Create(c *echo.Context) error {
 		Visibility:    visibility,
 		StartedAt:     req.StartedAt,
 		CompletedAt:   req.CompletedAt,
+		Tags:          req.Tags,
 	})
 	if errors.Is(err, ErrInvalidItem) {
 		return echo.NewHTTPError(http.StatusBadRequest, "invalid library item")
 	}
+	if errors.Is(err, ErrProgressOutOfRange) {
+		return echo.NewHTTPError(http.StatusBadRequest, "progress exceeds the source's length")
+	}
 	if errors.Is(err, ErrSourceNotFound) {
 		return echo.NewHTTPError(http.StatusNotFound, "source not found")
 	}
@@ -108,10 +127,16 @@ func (h *Handler) ListMine(c *echo.Context) error {
 	if !ok {
 		return echo.NewHTTPError(http.StatusUnauthorized, "authentication required")
 	}
-	limit, offset := echox.Pagination(c)
-	items, err := h.service.ListByUser(c.Request().Context(), userID, limit, offset)
+	page, err := echox.PageParams(c)
+	if err != nil {
+		return err
+	}
+	filter := listFilter(c)
+	filter.UserID = &userID
+
+	items, err := h.service.List(c.Request().Context(), filter, page)
 	if err != nil {
-		return echo.NewHTTPError(http.StatusInternalServerError, "failed to list library items")
+		return h.listError(err, "failed to list library items")
 	}
 	return c.JSON(http.StatusOK, items)
 }
@@ -121,10 +146,16 @@ func (h *Handler) ListMineWithSources(c *echo.Context) error {
 	if !ok {
 		return echo.NewHTTPError(http.StatusUnauthorized, "authentication required")
 	}
-	limit, offset := echox.Pagination(c)
-	items, err := h.service.ListByUserWithSources(c.Request().Context(), userID, limit, offset)
+	page, err := echox.PageParams(c)
+	if err != nil {
+		return err
+	}
+	filter := listFilter(c)
+	filter.UserID = &userID
+
+	items, err := h.service.ListWithSources(c.Request().Context(), filter, page)
 	if err != nil {
-		return echo.NewHTTPError(http.StatusInternalServerError, "failed to list library items")
+		return h.listError(err, "failed to list library items")
 	}
 	return c.JSON(http.StatusOK, items)
 }
@@ -173,10 +204,17 @@ func (h *Handler) Update(c *echo.Context) error {
 		Visibility:    visibility,
 		StartedAt:     req.StartedAt,
 		CompletedAt:   req.CompletedAt,
+		Tags:          req.Tags,
 	})
 	if errors.Is(err, ErrInvalidItem) {
 		return echo.NewHTTPError(http.StatusBadRequest, "invalid library item")
 	}
+	if errors.Is(err, ErrProgressOutOfRange) {
+		return echo.NewHTTPError(http.StatusBadRequest, "progress exceeds the source's length")
+	}
+	if errors.Is(err, ErrInvalidTransition) {
+		return echo.NewHTTPError(http.StatusConflict, "cannot move library item from "+string(item.Status)+" to "+string(*status))
+	}
 	if err != nil {
 		return echo.NewHTTPError(http.StatusInternalServerError, "failed to update library item")
 	}
@@ -198,41 +236,140 @@ func (h *Handler) Delete(c *echo.Context) error {
 	return c.NoContent(http.StatusNoContent)
 }
 
-func (h *Handler) ListPublicLibrary(c *echo.Context) error {
+func (h *Handler) LogSession(c *echo.Context) error {
+	userID, ok := auth.UserID(c)
+	if !ok {
+		return echo.NewHTTPError(http.StatusUnauthorized, "authentication required")
+	}
+	item, err := h.getOwnedItem(c, userID)
+	if err != nil {
+		return err
+	}
+
+	var req logSessionRequest
+	if err := echox.BindAndValidate(c, &req); err != nil {
+		return err
+	}
+
+	result, err := h.service.LogSession(c.Request().Context(), item, LogSessionParams{
+		StartedAt:    req.StartedAt,
+		EndedAt:      req.EndedAt,
+		ProgressFrom: req.ProgressFrom,
+		ProgressTo:   req.ProgressTo,
+		ProgressUnit: req.ProgressUnit,
+		Device:       req.Device,
+		Finished:     req.Finished,
+	})
+	if errors.Is(err, ErrInvalidSession) {
+		return echo.NewHTTPError(http.StatusBadRequest, "invalid reading session")
+	}
+	if errors.Is(err, ErrProgressOutOfRange) {
+		return echo.NewHTTPError(http.StatusBadRequest, "progress exceeds the source's length")
+	}
+	if errors.Is(err, ErrInvalidTransition) {
+		return echo.NewHTTPError(http.StatusConflict, "reading session cannot change the library item's status")
+	}
+	if err != nil {
+		return echo.NewHTTPError(http.StatusInternalServerError, "failed to log reading session")
+	}
+	return c.JSON(http.StatusCreated, result)
+}
+
+func (h *Handler) ListSessions(c *echo.Context) error {
+	userID, ok := auth.UserID(c)
+	if !ok {
+		return echo.NewHTTPError(http.StatusUnauthorized, "authentication required")
+	}
+	item, err := h.getOwnedItem(c, userID)
+	if err != nil {
+		return err
+	}
 	limit, offset := echox.Pagination(c)
-	user := c.Param("user")
+	sessions, err := h.service.ListSessions(c.Request().Context(), item.ID, limit, offset)
+	if err != nil {
+		return echo.NewHTTPError(http.StatusInternalServerError, "failed to list reading sessions")
+	}
+	return c.JSON(http.StatusOK, sessions)
+}
 
-	userID, err := uuid.FromString(user)
-	if err == nil {
-		items, err := h.service.ListPublicByUser(c.Request().Context(), userID, limit, offset)
-		if err != nil {
-			return echo.NewHTTPError(http.StatusInternalServerError, "failed to list public library items")
-		}
-		return c.JSON(http.StatusOK, items)
+func (h *Handler) ListCompletions(c *echo.Context) error {
+	userID, ok := auth.UserID(c)
+	if !ok {
+		return echo.NewHTTPError(http.StatusUnauthorized, "authentication required")
+	}
+	item, err := h.getOwnedItem(c, userID)
+	if err != nil {
+		return err
+	}
+	limit, offset := echox.Pagination(c)
+	completions, err := h.service.ListCompletions(c.Request().Context(), item.ID, limit, offset)
+	if err != nil {
+		return echo.NewHTTPError(http.StatusInternalServerError, "failed to list library item completions")
 	}
+	return c.JSON(http.StatusOK, completions)
+}
 
-	items, err := h.service.ListPublicByUsername(c.Request().Context(), user, limit, offset)
-	if errors.Is(err, ErrInvalidUser) {
-		return echo.NewHTTPError(http.StatusBadRequest, "invalid username")
+func (h *Handler) ListPublicLibrary(c *echo.Context) error {
+	page, err := echox.PageParams(c)
+	if err != nil {
+		return err
 	}
+	items, err := h.service.List(c.Request().Context(), publicFilter(c), page)
 	if err != nil {
-		return echo.NewHTTPError(http.StatusInternalServerError, "failed to list public library items")
+		return h.listError(err, "failed to list public library items")
 	}
 	return c.JSON(http.StatusOK, items)
 }
 
 func (h *Handler) ListPublicLibraryWithSources(c *echo.Context) error {
-	limit, offset := echox.Pagination(c)
-	items, err := h.service.ListPublicByUsernameWithSources(c.Request().Context(), c.Param("user"), limit, offset)
-	if errors.Is(err, ErrInvalidUser) {
-		return echo.NewHTTPError(http.StatusBadRequest, "invalid username")
+	page, err := echox.PageParams(c)
+	if err != nil {
+		return err
 	}
+	items, err := h.service.ListWithSources(c.Request().Context(), publicFilter(c), page)
 	if err != nil {
-		return echo.NewHTTPError(http.StatusInternalServerError, "failed to list public library items")
+		return h.listError(err, "failed to list public library items")
 	}
 	return c.JSON(http.StatusOK, items)
 }
 
+// listFilter reads the status and tag query parameters
+func listFilter(c *echo.Context) ListFilter {
+	filter := ListFilter{Tag: echox.QueryString(c, "tag")}
+	if value := echox.QueryString(c, "status"); value != nil {
+		status := Status(*value)
+		filter.Status = &status
+	}
+	return filter
+}
+
+// publicFilter limits a list to the public items of the user named in the
+// path, either by ID or by username. Tags are private and not filtered on.
+func publicFilter(c *echo.Context) ListFilter {
+	filter := listFilter(c)
+	filter.PublicOnly = true
+	filter.Tag = nil
+	user := c.Param("user")
+	if userID, err := uuid.FromString(user); err == nil {
+		filter.UserID = &userID
+	} else {
+		filter.Username = &user
+	}
+	return filter
+}
+
+func (h *Handler) listError(err error, message string) error {
+	switch {
+	case errors.Is(err, ErrInvalidUser):
+		return echo.NewHTTPError(http.StatusBadRequest, "invalid username")
+	case errors.Is(err, ErrInvalidFilter):
+		return echo.NewHTTPError(http.StatusBadRequest, "invalid status")
+	default:
+		h.logger.Error(message, "error", err)
+		return echo.NewHTTPError(http.StatusInternalServerError, message)
+	}
+}
+
 func (h *Handler) getOwnedItem(c *echo.Context, userID uuid.UUID) (*Item, error) {
 	id, err := echox.ParamUUID(c, "id", "library item ID")
 	if err != nil {
@@ -10,6 +10,7 @@ import (
 	"github.com/gofrs/uuid/v5"
 	"github.com/labstack/echo/v5"
 	"github.com/zizouhuweidi/maktaba/internal/auth"
+	"github.com/zizouhuweidi/maktaba/internal/echox"
 )
 
 func TestHandlerGetMineRejectsAnotherUsersItem(t *testing.T) {
@@ -97,15 +98,15 @@ func (r *fakeLibraryRepository) GetByID(_ context.Context, id uuid.UUID) (*Item,
 	return r.item, nil
 }
 
-func (r *fakeLibraryRepository) ListByUser(context.Context, uuid.UUID, int, int) ([]*Item, error) {
+func (r *fakeLibraryRepository) List(context.Context, ListFilter, echox.PageRequest) ([]*Item, error) {
 	panic("not implemented")
 }
 
-func (r *fakeLibraryRepository) ListPublicByUser(context.Context, uuid.UUID, int, int) ([]*Item, error) {
+func (r *fakeLibraryRepository) ListWithSources(context.Context, ListFilter, echox.PageRequest) ([]*ItemWithSource, error) {
 	panic("not implemented")
 }
 
-func (r *fakeLibraryRepository) ListPublicByUsername(context.Context, string, int, int) ([]*Item, error) {
+func (r *fakeLibraryRepository) Count(context.Context, ListFilter) (int64, error) {
 	panic("not implemented")
 }
 
@@ -113,11 +114,11 @@ func (r *fakeLibraryRepository) ListByUserWithSources(context.Context, uuid.UUID
 	panic("not implemented")
 }
 
-func (r *fakeLibraryRepository) ListPublicByUsernameWithSources(context.Context, string, int, int) ([]*ItemWithSource, error) {
+func (r *fakeLibraryRepository) Update(context.Context, *Item) (*Item, error) {
 	panic("not implemented")
 }
 
-func (r *fakeLibraryRepository) Update(context.Context, *Item) (*Item, error) {
+func (r *fakeLibraryRepository) Reread(context.Context, *Item, *Completion) (*Item, error) {
 	panic("not implemented")
 }
 
@@ -126,6 +127,34 @@ func (r *fakeLibraryRepository) Delete(context.Context, uuid.UUID) error {
 	return nil
 }
 
+func (r *fakeLibraryRepository) CreateSession(context.Context, *Session, *Item, *Completion) (*Session, *Item, error) {
+	panic("not implemented")
+}
+
+func (r *fakeLibraryRepository) LatestSession(context.Context, uuid.UUID) (*Session, error) {
+	panic("not implemented")
+}
+
+func (r *fakeLibraryRepository) ListSessions(context.Context, uuid.UUID, int, int) ([]*Session, error) {
+	panic("not implemented")
+}
+
+func (r *fakeLibraryRepository) ListSessionsByUser(context.Context, uuid.UUID, int, int) ([]*Session, error) {
+	panic("not implemented")
+}
+
+func (r *fakeLibraryRepository) PageCount(context.Context, uuid.UUID) (*int, error) {
+	panic("not implemented")
+}
+
+func (r *fakeLibraryRepository) ListCompletions(context.Context, uuid.UUID, int, int) ([]*Completion, error) {
+	panic("not implemented")
+}
+
+func (r *fakeLibraryRepository) ListCompletionsByUser(context.Context, uuid.UUID, int, int) ([]*Completion, error) {
+	panic("not implemented")
+}
+
 func mustTestUUID(t *testing.T) uuid.UUID {
 	t.Helper()
 	id, err := uuid.NewV7()
@@ -5,6 +5,18 @@ import (
 	"time"
 
 	"github.com/gofrs/uuid/v5"
+	"github.com/zizouhuweidi/maktaba/internal/echox"
+)
+
+// Outbox aggregate and event types published by this context.
+const (
+	AggregateLibraryItem    = "library_item"
+	EventLibraryItemCreated = "library_item.created"
+	EventLibraryItemUpdated = "library_item.updated"
+	EventLibraryItemDeleted = "library_item.deleted"
+
+	AggregateReadingSession    = "reading_session"
+	EventReadingSessionCreated = "reading_session.created"
 )
 
 type Status string
@@ -45,10 +57,23 @@ type Item struct {
 	Visibility    Visibility    `json:"visibility"`
 	StartedAt     *time.Time    `json:"started_at,omitempty"`
 	CompletedAt   *time.Time    `json:"completed_at,omitempty"`
+	RereadCount   int           `json:"reread_count"`
+	Tags          []string      `json:"tags,omitempty"`
 	CreatedAt     time.Time     `json:"created_at"`
 	UpdatedAt     time.Time     `json:"updated_at"`
 }
 
+// Completion is an earlier finished read of an item, kept when the item is
+// started again.
+type Completion struct {
+	ID            uuid.UUID  `json:"id"`
+	LibraryItemID uuid.UUID  `json:"library_item_id"`
+	UserID        uuid.UUID  `json:"user_id"`
+	StartedAt     *time.Time `json:"started_at,omitempty"`
+	CompletedAt   time.Time  `json:"completed_at"`
+	CreatedAt     time.Time  `json:"created_at"`
+}
+
 type SourceSummary struct {
 	ID        uuid.UUID `json:"id"`
 	Title     string    `json:"title"`
@@ -66,24 +91,47 @@ type ItemWithSource struct {
 type Repository interface {
 	Create(ctx context.Context, item *Item) (*Item, error)
 	GetByID(ctx context.Context, id uuid.UUID) (*Item, error)
-	ListByUser(ctx context.Context, userID uuid.UUID, limit, offset int) ([]*Item, error)
-	ListPublicByUser(ctx context.Context, userID uuid.UUID, limit, offset int) ([]*Item, error)
-	ListPublicByUsername(ctx context.Context, username string, limit, offset int) ([]*Item, error)
+	List(ctx context.Context, filter ListFilter, page echox.PageRequest) ([]*Item, error)
+	ListWithSources(ctx context.Context, filter ListFilter, page echox.PageRequest) ([]*ItemWithSource, error)
+	Count(ctx context.Context, filter ListFilter) (int64, error)
 	ListByUserWithSources(ctx context.Context, userID uuid.UUID, limit, offset int) ([]*ItemWithSource, error)
-	ListPublicByUsernameWithSources(ctx context.Context, username string, limit, offset int) ([]*ItemWithSource, error)
 	Update(ctx context.Context, item *Item) (*Item, error)
+	Reread(ctx context.Context, item *Item, previous *Completion) (*Item, error)
 	Delete(ctx context.Context, id uuid.UUID) error
+	PageCount(ctx context.Context, sourceID uuid.UUID) (*int, error)
+	ListCompletions(ctx context.Context, itemID uuid.UUID, limit, offset int) ([]*Completion, error)
+	ListCompletionsByUser(ctx context.Context, userID uuid.UUID, limit, offset int) ([]*Completion, error)
+	CreateSession(ctx context.Context, session *Session, item *Item, previous *Completion) (*Session, *Item, error)
+	LatestSession(ctx context.Context, itemID uuid.UUID) (*Session, error)
+	ListSessions(ctx context.Context, itemID uuid.UUID, limit, offset int) ([]*Session, error)
+	ListSessionsByUser(ctx context.Context, userID uuid.UUID, limit, offset int) ([]*Session, error)
 }
 
+// ListFilter narrows a library list. A user is picked by ID or by username.
+// Tags are private, so Tag only makes sense on a reader's own library.
+type ListFilter struct {
+	UserID     *uuid.UUID
+	Username   *string
+	PublicOnly bool
+	Status     *Status
+	Tag        *string
+}
+
+// CreateItemParams describes a new library item. A completed item without a
+// CompletedAt is stamped with the current time, as Update does, unless
+// CompletedAtUnknown says the finish date was never recorded (an imported
+// book with no date read).
 type CreateItemParams struct {
-	UserID        uuid.UUID
-	SourceID      uuid.UUID
-	Status        Status
-	ProgressValue *int
-	ProgressUnit  *ProgressUnit
-	Visibility    Visibility
-	StartedAt     *time.Time
-	CompletedAt   *time.Time
+	UserID             uuid.UUID
+	SourceID           uuid.UUID
+	Status             Status
+	ProgressValue      *int
+	ProgressUnit       *ProgressUnit
+	Visibility         Visibility
+	StartedAt          *time.Time
+	CompletedAt        *time.Time
+	CompletedAtUnknown bool
+	Tags               []string
 }
 
 type UpdateItemParams struct {
@@ -93,4 +141,5 @@ type UpdateItemParams struct {
 	Visibility    *Visibility
 	StartedAt     *time.Time
 	CompletedAt   *time.Time
+	Tags          []string
 }
@@ -10,14 +10,18 @@ import (
 	"github.com/jackc/pgx/v5/pgtype"
 	"github.com/zizouhuweidi/maktaba/internal/db"
 	"github.com/zizouhuweidi/maktaba/internal/db/dbgen"
+	"github.com/zizouhuweidi/maktaba/internal/echox"
+	"github.com/zizouhuweidi/maktaba/internal/outbox"
+	"github.com/zizouhuweidi/maktaba/internal/tags"
 )
 
 type postgresRepository struct {
+	db      *db.DB
 	queries *dbgen.Queries
 }
 
 func NewPostgresRepository(d *db.DB) Repository {
-	return &postgresRepository{queries: dbgen.New(d.Pool)}
+	return &postgresRepository{db: d, queries: dbgen.New(d.Pool)}
 }
 
 func (r *postgresRepository) Create(ctx context.Context, item *Item) (*Item, error) {
@@ -26,7 +30,14 @@ func (r *postgresRepository) Create(ctx context.Context, item *Item) (*Item, err
 		return nil, err
 	}
 
-	row, err := r.queries.CreateLibraryItem(ctx, dbgen.CreateLibraryItemParams{
+	tx, err := r.db.Begin(ctx)
+	if err != nil {
+		return nil, err
+	}
+	defer tx.Rollback(ctx)
+	qtx := r.queries.WithTx(tx)
+
+	row, err := qtx.CreateLibraryItem(ctx, dbgen.CreateLibraryItemParams{
 		ID:            db.PGUUID(id),
 		UserID:        db.PGUUID(item.UserID),
 		SourceID:      db.PGUUID(item.SourceID),
@@ -40,7 +51,18 @@ func (r *postgresRepository) Create(ctx context.Context, item *Item) (*Item, err
 	if err != nil {
 		return nil, mapCreateError(err)
 	}
-	return mapItem(row), nil
+	created := mapItem(row)
+	if err := saveTags(ctx, qtx, created, item.Tags); err != nil {
+		return nil, err
+	}
+	if err := outbox.Record(ctx, qtx, AggregateLibraryItem, created.ID, EventLibraryItemCreated, created); err != nil {
+		return nil, err
+	}
+
+	if err := tx.Commit(ctx); err != nil {
+		return nil, err
+	}
+	return created, nil
 }
 
 func mapCreateError(err error) error {
@@ -69,31 +91,89 @@ func (r *postgresRepository) GetByID(ctx context.Context, id uuid.UUID) (*Item,
 	if err != nil {
 		return nil, err
 	}
-	return mapItem(row), nil
-}
-
-func (r *postgresRepository) ListByUser(ctx context.Context, userID uuid.UUID, limit, offset int) ([]*Item, error) {
-	rows, err := r.queries.ListLibraryItemsByUser(ctx, dbgen.ListLibraryItemsByUserParams{UserID: db.PGUUID(userID), Limit: int32(limit), Offset: int32(offset)})
-	if err != nil {
+	item := mapItem(row)
+	if err := attachTags(ctx, r.queries, item); err != nil {
 		return nil, err
 	}
-	return mapItems(rows), nil
+	return item, nil
 }
 
-func (r *postgresRepository) ListPublicByUser(ctx context.Context, userID uuid.UUID, limit, offset int) ([]*Item, error) {
-	rows, err := r.queries.ListPublicLibraryItemsByUser(ctx, dbgen.ListPublicLibraryItemsByUserParams{UserID: db.PGUUID(userID), Limit: int32(limit), Offset: int32(offset)})
+// List fetches one row past the page limit so the caller can tell whether
+// another page follows
+func (r *postgresRepository) List(ctx context.Context, filter ListFilter, page echox.PageRequest) ([]*Item, error) {
+	params := dbgen.ListLibraryItemsNewestParams{
+		UserID:     db.PGUUIDPtr(filter.UserID),
+		Username:   db.PGText(filter.Username),
+		PublicOnly: filter.PublicOnly,
+		Status:     db.PGText((*string)(filter.Status)),
+		Tag:        db.PGText(filter.Tag),
+		Limit:      int32(page.Limit + 1),
+	}
+	params.AfterCreatedAt, params.AfterID = db.Keyset(page.After.CreatedAt, page.After.ID, page.Oldest)
+	var rows []dbgen.UserLibraryItem
+	var err error
+	if page.Oldest {
+		rows, err = r.queries.ListLibraryItemsOldest(ctx, dbgen.ListLibraryItemsOldestParams(params))
+	} else {
+		rows, err = r.queries.ListLibraryItemsNewest(ctx, params)
+	}
 	if err != nil {
 		return nil, err
 	}
-	return mapItems(rows), nil
+	items := mapItems(rows)
+	if !filter.PublicOnly {
+		if err := attachTags(ctx, r.queries, items...); err != nil {
+			return nil, err
+		}
+	}
+	return items, nil
 }
 
-func (r *postgresRepository) ListPublicByUsername(ctx context.Context, username string, limit, offset int) ([]*Item, error) {
-	rows, err := r.queries.ListPublicLibraryItemsByUsername(ctx, dbgen.ListPublicLibraryItemsByUsernameParams{Username: username, Limit: int32(limit), Offset: int32(offset)})
-	if err != nil {
-		return nil, err
+func (r *postgresRepository) ListWithSources(ctx context.Context, filter ListFilter, page echox.PageRequest) ([]*ItemWithSource, error) {
+	params := dbgen.ListLibraryItemsWithSourcesNewestParams{
+		UserID:     db.PGUUIDPtr(filter.UserID),
+		Username:   db.PGText(filter.Username),
+		PublicOnly: filter.PublicOnly,
+		Status:     db.PGText((*string)(filter.Status)),
+		Tag:        db.PGText(filter.Tag),
+		Limit:      int32(page.Limit + 1),
+	}
+	params.AfterCreatedAt, params.AfterID = db.Keyset(page.After.CreatedAt, page.After.ID, page.Oldest)
+	var rows []dbgen.ListLibraryItemsWithSourcesNewestRow
+	if page.Oldest {
+		oldest, err := r.queries.ListLibraryItemsWithSourcesOldest(ctx, dbgen.ListLibraryItemsWithSourcesOldestParams(params))
+		if err != nil {
+			return nil, err
+		}
+		for _, row := range oldest {
+			rows = append(rows, dbgen.ListLibraryItemsWithSourcesNewestRow(row))
+		}
+	} else {
+		var err error
+		if rows, err = r.queries.ListLibraryItemsWithSourcesNewest(ctx, params); err != nil {
+			return nil, err
+		}
 	}
-	return mapItems(rows), nil
+	items := make([]*ItemWithSource, 0, len(rows))
+	for _, row := range rows {
+		items = append(items, mapListedItemWithSource(row))
+	}
+	if !filter.PublicOnly {
+		if err := attachTags(ctx, r.queries, joinedItems(items)...); err != nil {
+			return nil, err
+		}
+	}
+	return items, nil
+}
+
+func (r *postgresRepository) Count(ctx context.Context, filter ListFilter) (int64, error) {
+	return r.queries.CountLibraryItems(ctx, dbgen.CountLibraryItemsParams{
+		UserID:     db.PGUUIDPtr(filter.UserID),
+		Username:   db.PGText(filter.Username),
+		PublicOnly: filter.PublicOnly,
+		Status:     db.PGText((*string)(filter.Status)),
+		Tag:        db.PGText(filter.Tag),
+	})
 }
 
 func (r *postgresRepository) ListByUserWithSources(ctx context.Context, userID uuid.UUID, limit, offset int) ([]*ItemWithSource, error) {
@@ -105,23 +185,69 @@ func (r *postgresRepository) ListByUserWithSources(ctx context.Context, userID u
 	for _, row := range rows {
 		items = append(items, mapItemWithSource(row))
 	}
+	if err := attachTags(ctx, r.queries, joinedItems(items)...); err != nil {
+		return nil, err
+	}
 	return items, nil
 }
 
-func (r *postgresRepository) ListPublicByUsernameWithSources(ctx context.Context, username string, limit, offset int) ([]*ItemWithSource, error) {
-	rows, err := r.queries.ListPublicLibraryItemsByUsernameWithSources(ctx, dbgen.ListPublicLibraryItemsByUsernameWithSourcesParams{Username: username, Limit: int32(limit), Offset: int32(offset)})
+func (r *postgresRepository) Update(ctx context.Context, item *Item) (*Item, error) {
+	tx, err := r.db.Begin(ctx)
 	if err != nil {
 		return nil, err
 	}
-	items := make([]*ItemWithSource, 0, len(rows))
-	for _, row := range rows {
-		items = append(items, mapPublicItemWithSource(row))
+	defer tx.Rollback(ctx)
+	qtx := r.queries.WithTx(tx)
+
+	row, err := qtx.UpdateLibraryItem(ctx, dbgen.UpdateLibraryItemParams{
+		ID:            db.PGUUID(item.ID),
+		Status:        string(item.Status),
+		ProgressValue: db.PGInt4Ptr(item.ProgressValue),
+		ProgressUnit:  pgProgressUnit(item.ProgressUnit),
+		Visibility:    string(item.Visibility),
+		StartedAt:     db.PGTimestamptzPtr(item.StartedAt),
+		CompletedAt:   db.PGTimestamptzPtr(item.CompletedAt),
+		RereadCount:   int32(item.RereadCount),
+	})
+	if errors.Is(err, pgx.ErrNoRows) {
+		return nil, nil
 	}
-	return items, nil
+	if err != nil {
+		return nil, err
+	}
+	updated := mapItem(row)
+	if err := saveTags(ctx, qtx, updated, item.Tags); err != nil {
+		return nil, err
+	}
+	if err := outbox.Record(ctx, qtx, AggregateLibraryItem, updated.ID, EventLibraryItemUpdated, updated); err != nil {
+		return nil, err
+	}
+
+	if err := tx.Commit(ctx); err != nil {
+		return nil, err
+	}
+	return updated, nil
 }
 
-func (r *postgresRepository) Update(ctx context.Context, item *Item) (*Item, error) {
-	row, err := r.queries.UpdateLibraryItem(ctx, dbgen.UpdateLibraryItemParams{
+// Reread keeps the finished read and saves the item started again in one
+// transaction.
+func (r *postgresRepository) Reread(ctx context.Context, item *Item, previous *Completion) (*Item, error) {
+	id, err := uuid.NewV7()
+	if err != nil {
+		return nil, err
+	}
+
+	tx, err := r.db.Begin(ctx)
+	if err != nil {
+		return nil, err
+	}
+	defer tx.Rollback(ctx)
+	qtx := r.queries.WithTx(tx)
+
+	if err := createCompletion(ctx, qtx, id, previous); err != nil {
+		return nil, err
+	}
+	row, err := qtx.UpdateLibraryItem(ctx, dbgen.UpdateLibraryItemParams{
 		ID:            db.PGUUID(item.ID),
 		Status:        string(item.Status),
 		ProgressValue: db.PGInt4Ptr(item.ProgressValue),
@@ -129,6 +255,7 @@ func (r *postgresRepository) Update(ctx context.Context, item *Item) (*Item, err
 		Visibility:    string(item.Visibility),
 		StartedAt:     db.PGTimestamptzPtr(item.StartedAt),
 		CompletedAt:   db.PGTimestamptzPtr(item.CompletedAt),
+		RereadCount:   int32(item.RereadCount),
 	})
 	if errors.Is(err, pgx.ErrNoRows) {
 		return nil, nil
@@ -136,11 +263,260 @@ func (r *postgresRepository) Update(ctx context.Context, item *Item) (*Item, err
 	if err != nil {
 		return nil, err
 	}
-	return mapItem(row), nil
+	updated := mapItem(row)
+	if err := saveTags(ctx, qtx, updated, item.Tags); err != nil {
+		return nil, err
+	}
+	if err := outbox.Record(ctx, qtx, AggregateLibraryItem, updated.ID, EventLibraryItemUpdated, updated); err != nil {
+		return nil, err
+	}
+
+	if err := tx.Commit(ctx); err != nil {
+		return nil, err
+	}
+	return updated, nil
+}
+
+func createCompletion(ctx context.Context, qtx *dbgen.Queries, id uuid.UUID, previous *Completion) error {
+	_, err := qtx.CreateLibraryItemCompletion(ctx, dbgen.CreateLibraryItemCompletionParams{
+		ID:            db.PGUUID(id),
+		LibraryItemID: db.PGUUID(previous.LibraryItemID),
+		UserID:        db.PGUUID(previous.UserID),
+		StartedAt:     db.PGTimestamptzPtr(previous.StartedAt),
+		CompletedAt:   db.PGTimestamptz(previous.CompletedAt),
+	})
+	return err
 }
 
 func (r *postgresRepository) Delete(ctx context.Context, id uuid.UUID) error {
-	return r.queries.DeleteLibraryItem(ctx, db.PGUUID(id))
+	tx, err := r.db.Begin(ctx)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback(ctx)
+	qtx := r.queries.WithTx(tx)
+
+	deleted, err := qtx.DeleteLibraryItem(ctx, db.PGUUID(id))
+	if err != nil {
+		return err
+	}
+	if deleted > 0 {
+		if err := outbox.Record(ctx, qtx, AggregateLibraryItem, id, EventLibraryItemDeleted, outbox.DeletedPayload{ID: id}); err != nil {
+			return err
+		}
+	}
+	return tx.Commit(ctx)
+}
+
+func (r *postgresRepository) PageCount(ctx context.Context, sourceID uuid.UUID) (*int, error) {
+	pageCount, err := r.queries.GetSourcePageCount(ctx, db.PGUUID(sourceID))
+	if errors.Is(err, pgx.ErrNoRows) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	return db.IntPtr(pageCount), nil
+}
+
+func (r *postgresRepository) ListCompletions(ctx context.Context, itemID uuid.UUID, limit, offset int) ([]*Completion, error) {
+	rows, err := r.queries.ListLibraryItemCompletions(ctx, dbgen.ListLibraryItemCompletionsParams{LibraryItemID: db.PGUUID(itemID), Limit: int32(limit), Offset: int32(offset)})
+	if err != nil {
+		return nil, err
+	}
+	return mapCompletions(rows), nil
+}
+
+func (r *postgresRepository) ListCompletionsByUser(ctx context.Context, userID uuid.UUID, limit, offset int) ([]*Completion, error) {
+	rows, err := r.queries.ListLibraryItemCompletionsByUser(ctx, dbgen.ListLibraryItemCompletionsByUserParams{UserID: db.PGUUID(userID), Limit: int32(limit), Offset: int32(offset)})
+	if err != nil {
+		return nil, err
+	}
+	return mapCompletions(rows), nil
+}
+
+// CreateSession saves the session and the item it updated in one
+// transaction, keeping previous as an earlier completion when the session
+// finished a re-read.
+func (r *postgresRepository) CreateSession(ctx context.Context, session *Session, item *Item, previous *Completion) (*Session, *Item, error) {
+	id, err := uuid.NewV7()
+	if err != nil {
+		return nil, nil, err
+	}
+
+	tx, err := r.db.Begin(ctx)
+	if err != nil {
+		return nil, nil, err
+	}
+	defer tx.Rollback(ctx)
+	qtx := r.queries.WithTx(tx)
+
+	row, err := qtx.CreateReadingSession(ctx, dbgen.CreateReadingSessionParams{
+		ID:            db.PGUUID(id),
+		LibraryItemID: db.PGUUID(session.LibraryItemID),
+		UserID:        db.PGUUID(session.UserID),
+		StartedAt:     db.PGTimestamptz(session.StartedAt),
+		EndedAt:       db.PGTimestamptzPtr(session.EndedAt),
+		ProgressFrom:  db.PGInt4Ptr(session.ProgressFrom),
+		ProgressTo:    db.PGInt4Ptr(session.ProgressTo),
+		ProgressUnit:  pgProgressUnit(session.ProgressUnit),
+		Device:        db.PGText(session.Device),
+	})
+	if err != nil {
+		return nil, nil, err
+	}
+	created := mapSession(row)
+	if err := outbox.Record(ctx, qtx, AggregateReadingSession, created.ID, EventReadingSessionCreated, created); err != nil {
+		return nil, nil, err
+	}
+
+	if previous != nil {
+		completionID, err := uuid.NewV7()
+		if err != nil {
+			return nil, nil, err
+		}
+		if err := createCompletion(ctx, qtx, completionID, previous); err != nil {
+			return nil, nil, err
+		}
+	}
+
+	itemRow, err := qtx.UpdateLibraryItem(ctx, dbgen.UpdateLibraryItemParams{
+		ID:            db.PGUUID(item.ID),
+		Status:        string(item.Status),
+		ProgressValue: db.PGInt4Ptr(item.ProgressValue),
+		ProgressUnit:  pgProgressUnit(item.ProgressUnit),
+		Visibility:    string(item.Visibility),
+		StartedAt:     db.PGTimestamptzPtr(item.StartedAt),
+		CompletedAt:   db.PGTimestamptzPtr(item.CompletedAt),
+		RereadCount:   int32(item.RereadCount),
+	})
+	if err != nil {
+		return nil, nil, err
+	}
+	updated := mapItem(itemRow)
+	if err := saveTags(ctx, qtx, updated, item.Tags); err != nil {
+		return nil, nil, err
+	}
+	if err := outbox.Record(ctx, qtx, AggregateLibraryItem, updated.ID, EventLibraryItemUpdated, updated); err != nil {
+		return nil, nil, err
+	}
+
+	if err := tx.Commit(ctx); err != nil {
+		return nil, nil, err
+	}
+	return created, updated, nil
+}
+
+func (r *postgresRepository) LatestSession(ctx context.Context, itemID uuid.UUID) (*Session, error) {
+	row, err := r.queries.GetLatestReadingSession(ctx, db.PGUUID(itemID))
+	if errors.Is(err, pgx.ErrNoRows) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	return mapSession(row), nil
+}
+
+func (r *postgresRepository) ListSessions(ctx context.Context, itemID uuid.UUID, limit, offset int) ([]*Session, error) {
+	rows, err := r.queries.ListReadingSessionsByItem(ctx, dbgen.ListReadingSessionsByItemParams{LibraryItemID: db.PGUUID(itemID), Limit: int32(limit), Offset: int32(offset)})
+	if err != nil {
+		return nil, err
+	}
+	return mapSessions(rows), nil
+}
+
+func (r *postgresRepository) ListSessionsByUser(ctx context.Context, userID uuid.UUID, limit, offset int) ([]*Session, error) {
+	rows, err := r.queries.ListReadingSessionsByUser(ctx, dbgen.ListReadingSessionsByUserParams{UserID: db.PGUUID(userID), Limit: int32(limit), Offset: int32(offset)})
+	if err != nil {
+		return nil, err
+	}
+	return mapSessions(rows), nil
+}
+
+// saveTags replaces the private tags of item with names, creating any tag
+// that does not exist yet, and reads back the names they are stored under
+func saveTags(ctx context.Context, qtx *dbgen.Queries, item *Item, names []string) error {
+	if err := qtx.DeleteLibraryItemTags(ctx, db.PGUUID(item.ID)); err != nil {
+		return err
+	}
+	ids, err := tags.Upsert(ctx, qtx, names)
+	if err != nil {
+		return err
+	}
+	if len(ids) > 0 {
+		if err := qtx.InsertLibraryItemTags(ctx, dbgen.InsertLibraryItemTagsParams{LibraryItemID: db.PGUUID(item.ID), TagIds: ids}); err != nil {
+			return err
+		}
+	}
+	return attachTags(ctx, qtx, item)
+}
+
+// attachTags loads the private tags of a batch of items in one query. Only
+// call it for lists shown to the items' owner.
+func attachTags(ctx context.Context, q *dbgen.Queries, items ...*Item) error {
+	if len(items) == 0 {
+		return nil
+	}
+	ids := make([]pgtype.UUID, 0, len(items))
+	for _, item := range items {
+		ids = append(ids, db.PGUUID(item.ID))
+	}
+	rows, err := q.ListTagsByLibraryItems(ctx, ids)
+	if err != nil {
+		return err
+	}
+	grouped := tags.Group(rows, func(row dbgen.ListTagsByLibraryItemsRow) (pgtype.UUID, string) { return row.LibraryItemID, row.Name })
+	for _, item := range items {
+		item.Tags = grouped[item.ID]
+	}
+	return nil
+}
+
+func joinedItems(rows []*ItemWithSource) []*Item {
+	items := make([]*Item, 0, len(rows))
+	for _, row := range rows {
+		items = append(items, row.Item)
+	}
+	return items
+}
+
+func mapSessions(rows []dbgen.ReadingSession) []*Session {
+	sessions := make([]*Session, 0, len(rows))
+	for _, row := range rows {
+		sessions = append(sessions, mapSession(row))
+	}
+	return sessions
+}
+
+func mapSession(row dbgen.ReadingSession) *Session {
+	return &Session{
+		ID:            db.UUID(row.ID),
+		LibraryItemID: db.UUID(row.LibraryItemID),
+		UserID:        db.UUID(row.UserID),
+		StartedAt:     db.Time(row.StartedAt),
+		EndedAt:       db.TimePtr(row.EndedAt),
+		ProgressFrom:  db.IntPtr(row.ProgressFrom),
+		ProgressTo:    db.IntPtr(row.ProgressTo),
+		ProgressUnit:  progressUnitPtr(row.ProgressUnit),
+		Device:        db.StringPtr(row.Device),
+		CreatedAt:     db.Time(row.CreatedAt),
+	}
+}
+
+func mapCompletions(rows []dbgen.LibraryItemCompletion) []*Completion {
+	completions := make([]*Completion, 0, len(rows))
+	for _, row := range rows {
+		completions = append(completions, &Completion{
+			ID:            db.UUID(row.ID),
+			LibraryItemID: db.UUID(row.LibraryItemID),
+			UserID:        db.UUID(row.UserID),
+			StartedAt:     db.TimePtr(row.StartedAt),
+			CompletedAt:   db.Time(row.CompletedAt),
+			CreatedAt:     db.Time(row.CreatedAt),
+		})
+	}
+	return completions
 }
 
 func mapItems(rows []dbgen.UserLibraryItem) []*Item {
@@ -162,22 +538,23 @@ func mapItem(row dbgen.UserLibraryItem) *Item {
 		Visibility:    Visibility(row.Visibility),
 		StartedAt:     db.TimePtr(row.StartedAt),
 		CompletedAt:   db.TimePtr(row.CompletedAt),
+		RereadCount:   int(row.RereadCount),
 		CreatedAt:     db.Time(row.CreatedAt),
 		UpdatedAt:     db.Time(row.UpdatedAt),
 	}
 }
 
 func mapItemWithSource(row dbgen.ListLibraryItemsByUserWithSourcesRow) *ItemWithSource {
-	item := mapJoinedItem(row.ID, row.UserID, row.SourceID, row.Status, row.ProgressValue, row.ProgressUnit, row.Visibility, row.StartedAt, row.CompletedAt, row.CreatedAt, row.UpdatedAt)
+	item := mapJoinedItem(row.ID, row.UserID, row.SourceID, row.Status, row.ProgressValue, row.ProgressUnit, row.Visibility, row.StartedAt, row.CompletedAt, row.CreatedAt, row.UpdatedAt, row.RereadCount)
 	return &ItemWithSource{Item: item, Source: mapSourceSummary(item.SourceID, row.Title, row.Subtitle, row.Type, row.Publisher, row.Isbn)}
 }
 
-func mapPublicItemWithSource(row dbgen.ListPublicLibraryItemsByUsernameWithSourcesRow) *ItemWithSource {
-	item := mapJoinedItem(row.ID, row.UserID, row.SourceID, row.Status, row.ProgressValue, row.ProgressUnit, row.Visibility, row.StartedAt, row.CompletedAt, row.CreatedAt, row.UpdatedAt)
+func mapListedItemWithSource(row dbgen.ListLibraryItemsWithSourcesNewestRow) *ItemWithSource {
+	item := mapJoinedItem(row.ID, row.UserID, row.SourceID, row.Status, row.ProgressValue, row.ProgressUnit, row.Visibility, row.StartedAt, row.CompletedAt, row.CreatedAt, row.UpdatedAt, row.RereadCount)
 	return &ItemWithSource{Item: item, Source: mapSourceSummary(item.SourceID, row.Title, row.Subtitle, row.Type, row.Publisher, row.Isbn)}
 }
 
-func mapJoinedItem(id, userID, sourceID pgtype.UUID, status string, progressValue pgtype.Int4, progressUnit pgtype.Text, visibility string, startedAt, completedAt, createdAt, updatedAt pgtype.Timestamptz) *Item {
+func mapJoinedItem(id, userID, sourceID pgtype.UUID, status string, progressValue pgtype.Int4, progressUnit pgtype.Text, visibility string, startedAt, completedAt, createdAt, updatedAt pgtype.Timestamptz, rereadCount int32) *Item {
 	return &Item{
 		ID:            db.UUID(id),
 		UserID:        db.UUID(userID),
@@ -188,6 +565,7 @@ func mapJoinedItem(id, userID, sourceID pgtype.UUID, status string, progressValu
 		Visibility:    Visibility(visibility),
 		StartedAt:     db.TimePtr(startedAt),
 		CompletedAt:   db.TimePtr(completedAt),
+		RereadCount:   int(rereadCount),
 		CreatedAt:     db.Time(createdAt),
 		UpdatedAt:     db.Time(updatedAt),
 	}
@@ -4,19 +4,29 @@ import (
 	"context"
 	"errors"
 	"log/slog"
+	"strings"
+	"time"
 
 	"github.com/gofrs/uuid/v5"
+	"github.com/zizouhuweidi/maktaba/internal/echox"
+	"github.com/zizouhuweidi/maktaba/internal/tags"
 )
 
 var (
-	ErrItemNotFound    = errors.New("library item not found")
-	ErrInvalidItem     = errors.New("invalid library item")
-	ErrInvalidUser     = errors.New("invalid user")
-	ErrItemExists      = errors.New("library item already exists")
-	ErrSourceNotFound  = errors.New("source not found")
-	ErrLibraryConflict = errors.New("library item conflict")
+	ErrItemNotFound       = errors.New("library item not found")
+	ErrInvalidItem        = errors.New("invalid library item")
+	ErrInvalidUser        = errors.New("invalid user")
+	ErrItemExists         = errors.New("library item already exists")
+	ErrSourceNotFound     = errors.New("source not found")
+	ErrLibraryConflict    = errors.New("library item conflict")
+	ErrInvalidSession     = errors.New("invalid reading session")
+	ErrInvalidTransition  = errors.New("invalid library status transition")
+	ErrProgressOutOfRange = errors.New("progress exceeds the source's length")
+	ErrInvalidFilter      = errors.New("invalid library filter")
 )
 
+const maxDeviceLength = 100
+
 type Service struct {
 	repo   Repository
 	logger *slog.Logger
@@ -36,6 +46,17 @@ func (s *Service) Create(ctx context.Context, params CreateItemParams) (*Item, e
 	if !validVisibility(params.Visibility) || !validProgress(params.ProgressValue, params.ProgressUnit) {
 		return nil, ErrInvalidItem
 	}
+	cleaned, err := tags.Clean(params.Tags)
+	if err != nil {
+		return nil, ErrInvalidItem
+	}
+	now := time.Now().UTC()
+	if params.StartedAt == nil && (params.Status == StatusInProgress || params.Status == StatusPaused) {
+		params.StartedAt = &now
+	}
+	if params.CompletedAt == nil && params.Status == StatusCompleted && !params.CompletedAtUnknown {
+		params.CompletedAt = &now
+	}
 
 	item := &Item{
 		UserID:        params.UserID,
@@ -46,6 +67,13 @@ func (s *Service) Create(ctx context.Context, params CreateItemParams) (*Item, e
 		Visibility:    params.Visibility,
 		StartedAt:     params.StartedAt,
 		CompletedAt:   params.CompletedAt,
+		Tags:          cleaned,
+	}
+	if !validDates(item) {
+		return nil, ErrInvalidItem
+	}
+	if err := s.checkLength(ctx, item.SourceID, item.ProgressValue, item.ProgressUnit); err != nil {
+		return nil, err
 	}
 
 	created, err := s.repo.Create(ctx, item)
@@ -67,37 +95,57 @@ func (s *Service) GetByID(ctx context.Context, id uuid.UUID) (*Item, error) {
 	return item, nil
 }
 
-func (s *Service) ListByUser(ctx context.Context, userID uuid.UUID, limit, offset int) ([]*Item, error) {
-	limit, offset = normalizePagination(limit, offset)
-	return s.repo.ListByUser(ctx, userID, limit, offset)
-}
+// List returns one page of library items matching the filter
+func (s *Service) List(ctx context.Context, filter ListFilter, page echox.PageRequest) (*echox.Page[*Item], error) {
+	filter, err := normalizeFilter(filter)
+	if err != nil {
+		return nil, err
+	}
+	page = page.Normalize()
 
-func (s *Service) ListByUserWithSources(ctx context.Context, userID uuid.UUID, limit, offset int) ([]*ItemWithSource, error) {
-	limit, offset = normalizePagination(limit, offset)
-	return s.repo.ListByUserWithSources(ctx, userID, limit, offset)
+	items, err := s.repo.List(ctx, filter, page)
+	if err != nil {
+		return nil, err
+	}
+	total, err := page.Total(func() (int64, error) { return s.repo.Count(ctx, filter) })
+	if err != nil {
+		return nil, err
+	}
+	return echox.NewPage(items, page, total, func(item *Item) echox.Cursor {
+		return echox.Cursor{CreatedAt: item.CreatedAt, ID: item.ID}
+	}), nil
 }
 
-func (s *Service) ListPublicByUser(ctx context.Context, userID uuid.UUID, limit, offset int) ([]*Item, error) {
-	limit, offset = normalizePagination(limit, offset)
-	return s.repo.ListPublicByUser(ctx, userID, limit, offset)
-}
+// ListWithSources is List with a summary of each item's source
+func (s *Service) ListWithSources(ctx context.Context, filter ListFilter, page echox.PageRequest) (*echox.Page[*ItemWithSource], error) {
+	filter, err := normalizeFilter(filter)
+	if err != nil {
+		return nil, err
+	}
+	page = page.Normalize()
 
-func (s *Service) ListPublicByUsername(ctx context.Context, username string, limit, offset int) ([]*Item, error) {
-	if username == "" {
-		return nil, ErrInvalidUser
+	items, err := s.repo.ListWithSources(ctx, filter, page)
+	if err != nil {
+		return nil, err
 	}
-	limit, offset = normalizePagination(limit, offset)
-	return s.repo.ListPublicByUsername(ctx, username, limit, offset)
+	total, err := page.Total(func() (int64, error) { return s.repo.Count(ctx, filter) })
+	if err != nil {
+		return nil, err
+	}
+	return echox.NewPage(items, page, total, func(item *ItemWithSource) echox.Cursor {
+		return echox.Cursor{CreatedAt: item.CreatedAt, ID: item.ID}
+	}), nil
 }
 
-func (s *Service) ListPublicByUsernameWithSources(ctx context.Context, username string, limit, offset int) ([]*ItemWithSource, error) {
-	if username == "" {
-		return nil, ErrInvalidUser
-	}
+func (s *Service) ListByUserWithSources(ctx context.Context, userID uuid.UUID, limit, offset int) ([]*ItemWithSource, error) {
 	limit, offset = normalizePagination(limit, offset)
-	return s.repo.ListPublicByUsernameWithSources(ctx, username, limit, offset)
+	return s.repo.ListByUserWithSources(ctx, userID, limit, offset)
 }
 
+// Update changes an item, moving it through the status transitions. Entering
+// a status stamps its start or completion time unless one is given, and
+// starting a completed item again keeps the finished read and counts a
+// re-read.
 func (s *Service) Update(ctx context.Context, id uuid.UUID, params UpdateItemParams) (*Item, error) {
 	existing, err := s.repo.GetByID(ctx, id)
 	if err != nil {
@@ -107,11 +155,15 @@ func (s *Service) Update(ctx context.Context, id uuid.UUID, params UpdateItemPar
 		return nil, ErrItemNotFound
 	}
 
+	var previous *Completion
 	if params.Status != nil {
 		if !validStatus(*params.Status) {
 			return nil, ErrInvalidItem
 		}
-		existing.Status = *params.Status
+		if !canTransition(existing.Status, *params.Status) {
+			return nil, ErrInvalidTransition
+		}
+		previous = enterStatus(existing, *params.Status, time.Now().UTC())
 	}
 	if params.Visibility != nil {
 		if !validVisibility(*params.Visibility) {
@@ -134,8 +186,34 @@ func (s *Service) Update(ctx context.Context, id uuid.UUID, params UpdateItemPar
 	if params.CompletedAt != nil {
 		existing.CompletedAt = params.CompletedAt
 	}
+	if params.Tags != nil {
+		cleaned, err := tags.Clean(params.Tags)
+		if err != nil {
+			return nil, ErrInvalidItem
+		}
+		existing.Tags = cleaned
+	}
+	if !validDates(existing) || (previous != nil && existing.StartedAt != nil && existing.StartedAt.Before(previous.CompletedAt)) {
+		return nil, ErrInvalidItem
+	}
 
-	updated, err := s.repo.Update(ctx, existing)
+	pageCount, err := s.pageCount(ctx, existing.SourceID, existing.ProgressUnit)
+	if err != nil {
+		return nil, err
+	}
+	if params.Status != nil && *params.Status == StatusCompleted && params.ProgressValue == nil {
+		finishProgress(existing, pageCount)
+	}
+	if !withinLength(existing.ProgressValue, existing.ProgressUnit, pageCount) {
+		return nil, ErrProgressOutOfRange
+	}
+
+	var updated *Item
+	if previous != nil {
+		updated, err = s.repo.Reread(ctx, existing, previous)
+	} else {
+		updated, err = s.repo.Update(ctx, existing)
+	}
 	if err != nil {
 		s.logger.Error("failed to update library item", "error", err, "id", id)
 		return nil, err
@@ -147,6 +225,138 @@ func (s *Service) Delete(ctx context.Context, id uuid.UUID) error {
 	return s.repo.Delete(ctx, id)
 }
 
+// LogSession records a reading session for an item and updates the item's
+// progress, dates and status to match. Finishing a completed item again
+// keeps its earlier completion, as starting a re-read with Update does.
+func (s *Service) LogSession(ctx context.Context, item *Item, params LogSessionParams) (*SessionResult, error) {
+	if params.StartedAt.IsZero() || (params.EndedAt != nil && params.EndedAt.Before(params.StartedAt)) {
+		return nil, ErrInvalidSession
+	}
+	if !validProgress(params.ProgressFrom, params.ProgressUnit) || !validProgress(params.ProgressTo, params.ProgressUnit) {
+		return nil, ErrInvalidSession
+	}
+	if params.ProgressFrom != nil && params.ProgressTo != nil && *params.ProgressTo < *params.ProgressFrom {
+		return nil, ErrInvalidSession
+	}
+	if params.Device != nil {
+		device := strings.TrimSpace(*params.Device)
+		if len(device) > maxDeviceLength {
+			return nil, ErrInvalidSession
+		}
+		params.Device = &device
+		if device == "" {
+			params.Device = nil
+		}
+	}
+
+	session := &Session{
+		LibraryItemID: item.ID,
+		UserID:        item.UserID,
+		StartedAt:     params.StartedAt,
+		EndedAt:       params.EndedAt,
+		ProgressFrom:  params.ProgressFrom,
+		ProgressTo:    params.ProgressTo,
+		ProgressUnit:  params.ProgressUnit,
+		Device:        params.Device,
+	}
+	if session.ProgressUnit == nil && (session.ProgressFrom != nil || session.ProgressTo != nil) {
+		session.ProgressUnit = item.ProgressUnit
+	}
+	if err := s.checkLength(ctx, item.SourceID, session.ProgressTo, session.ProgressUnit); err != nil {
+		return nil, err
+	}
+
+	latestSession, err := s.repo.LatestSession(ctx, item.ID)
+	if err != nil {
+		return nil, err
+	}
+	latest := latestSession == nil || !session.StartedAt.Before(latestSession.StartedAt)
+	wasCompleted := item.Status == StatusCompleted
+	previous, err := applySession(item, session, latest, params.Finished)
+	if err != nil {
+		return nil, err
+	}
+	if !validDates(item) {
+		return nil, ErrInvalidSession
+	}
+	if item.Status == StatusCompleted && (!wasCompleted || previous != nil) && (!latest || session.ProgressTo == nil) {
+		pageCount, err := s.pageCount(ctx, item.SourceID, item.ProgressUnit)
+		if err != nil {
+			return nil, err
+		}
+		finishProgress(item, pageCount)
+	}
+
+	created, updated, err := s.repo.CreateSession(ctx, session, item, previous)
+	if err != nil {
+		s.logger.Error("failed to log reading session", "error", err, "item_id", item.ID)
+		return nil, err
+	}
+	return &SessionResult{Session: created, Item: updated}, nil
+}
+
+func (s *Service) ListSessions(ctx context.Context, itemID uuid.UUID, limit, offset int) ([]*Session, error) {
+	limit, offset = normalizePagination(limit, offset)
+	return s.repo.ListSessions(ctx, itemID, limit, offset)
+}
+
+func (s *Service) ListSessionsByUser(ctx context.Context, userID uuid.UUID, limit, offset int) ([]*Session, error) {
+	limit, offset = normalizePagination(limit, offset)
+	return s.repo.ListSessionsByUser(ctx, userID, limit, offset)
+}
+
+// ListCompletions returns the earlier finished reads of an item, newest first
+func (s *Service) ListCompletions(ctx context.Context, itemID uuid.UUID, limit, offset int) ([]*Completion, error) {
+	limit, offset = normalizePagination(limit, offset)
+	return s.repo.ListCompletions(ctx, itemID, limit, offset)
+}
+
+func (s *Service) ListCompletionsByUser(ctx context.Context, userID uuid.UUID, limit, offset int) ([]*Completion, error) {
+	limit, offset = normalizePagination(limit, offset)
+	return s.repo.ListCompletionsByUser(ctx, userID, limit, offset)
+}
+
+// pageCount loads the source's page count when progress is counted in pages
+func (s *Service) pageCount(ctx context.Context, sourceID uuid.UUID, unit *ProgressUnit) (*int, error) {
+	if unit == nil || *unit != ProgressUnitPage {
+		return nil, nil
+	}
+	return s.repo.PageCount(ctx, sourceID)
+}
+
+func (s *Service) checkLength(ctx context.Context, sourceID uuid.UUID, value *int, unit *ProgressUnit) error {
+	if value == nil {
+		return nil
+	}
+	pageCount, err := s.pageCount(ctx, sourceID, unit)
+	if err != nil {
+		return err
+	}
+	if !withinLength(value, unit, pageCount) {
+		return ErrProgressOutOfRange
+	}
+	return nil
+}
+
+// normalizeFilter validates filter and canonicalizes its tag. Tags are
+// private, so a public list cannot be filtered by one.
+func normalizeFilter(filter ListFilter) (ListFilter, error) {
+	if filter.Username != nil && *filter.Username == "" {
+		return filter, ErrInvalidUser
+	}
+	if filter.Status != nil && !validStatus(*filter.Status) {
+		return filter, ErrInvalidFilter
+	}
+	if filter.Tag != nil {
+		if filter.PublicOnly {
+			return filter, ErrInvalidFilter
+		}
+		slug := tags.Slug(*filter.Tag)
+		filter.Tag = &slug
+	}
+	return filter, nil
+}
+
 func validStatus(status Status) bool {
 	switch status {
 	case StatusToConsume, StatusInProgress, StatusCompleted, StatusPaused, StatusAbandoned:
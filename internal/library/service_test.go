@@ -5,15 +5,27 @@ import (
 	"errors"
 	"io"
 	"log/slog"
+	"slices"
+	"strings"
 	"testing"
+	"time"
 
 	"github.com/gofrs/uuid/v5"
+	"github.com/zizouhuweidi/maktaba/internal/echox"
 )
 
 type fakeLibraryRepo struct {
-	created    *Item
-	listLimit  int
-	listOffset int
+	item           *Item
+	pageCount      *int
+	reread         *Completion
+	created        *Item
+	listLimit      int
+	listOffset     int
+	listPage       echox.PageRequest
+	listFilter     ListFilter
+	listed         []*Item
+	latestSession  *Session
+	createdSession *Session
 }
 
 func (r *fakeLibraryRepo) Create(ctx context.Context, item *Item) (*Item, error) {
@@ -23,34 +35,36 @@ func (r *fakeLibraryRepo) Create(ctx context.Context, item *Item) (*Item, error)
 }
 
 func (r *fakeLibraryRepo) GetByID(ctx context.Context, id uuid.UUID) (*Item, error) {
-	return nil, nil
+	return r.item, nil
 }
 
-func (r *fakeLibraryRepo) ListByUser(ctx context.Context, userID uuid.UUID, limit, offset int) ([]*Item, error) {
-	r.listLimit = limit
-	r.listOffset = offset
-	return nil, nil
+func (r *fakeLibraryRepo) List(ctx context.Context, filter ListFilter, page echox.PageRequest) ([]*Item, error) {
+	r.listPage = page
+	r.listFilter = filter
+	if len(r.listed) > page.Limit+1 {
+		return r.listed[:page.Limit+1], nil
+	}
+	return r.listed, nil
 }
 
-func (r *fakeLibraryRepo) ListByUserWithSources(ctx context.Context, userID uuid.UUID, limit, offset int) ([]*ItemWithSource, error) {
-	r.listLimit = limit
-	r.listOffset = offset
+func (r *fakeLibraryRepo) ListWithSources(ctx context.Context, filter ListFilter, page echox.PageRequest) ([]*ItemWithSource, error) {
 	return nil, nil
 }
 
-func (r *fakeLibraryRepo) ListPublicByUser(ctx context.Context, userID uuid.UUID, limit, offset int) ([]*Item, error) {
-	return nil, nil
+func (r *fakeLibraryRepo) Count(ctx context.Context, filter ListFilter) (int64, error) {
+	return int64(len(r.listed)), nil
 }
 
-func (r *fakeLibraryRepo) ListPublicByUsername(ctx context.Context, username string, limit, offset int) ([]*Item, error) {
+func (r *fakeLibraryRepo) ListByUserWithSources(ctx context.Context, userID uuid.UUID, limit, offset int) ([]*ItemWithSource, error) {
 	return nil, nil
 }
 
-func (r *fakeLibraryRepo) ListPublicByUsernameWithSources(ctx context.Context, username string, limit, offset int) ([]*ItemWithSource, error) {
-	return nil, nil
+func (r *fakeLibraryRepo) Update(ctx context.Context, item *Item) (*Item, error) {
+	return item, nil
 }
 
-func (r *fakeLibraryRepo) Update(ctx context.Context, item *Item) (*Item, error) {
+func (r *fakeLibraryRepo) Reread(ctx context.Context, item *Item, previous *Completion) (*Item, error) {
+	r.reread = previous
 	return item, nil
 }
 
@@ -58,6 +72,39 @@ func (r *fakeLibraryRepo) Delete(ctx context.Context, id uuid.UUID) error {
 	return nil
 }
 
+func (r *fakeLibraryRepo) PageCount(ctx context.Context, sourceID uuid.UUID) (*int, error) {
+	return r.pageCount, nil
+}
+
+func (r *fakeLibraryRepo) ListCompletions(ctx context.Context, itemID uuid.UUID, limit, offset int) ([]*Completion, error) {
+	return nil, nil
+}
+
+func (r *fakeLibraryRepo) ListCompletionsByUser(ctx context.Context, userID uuid.UUID, limit, offset int) ([]*Completion, error) {
+	return nil, nil
+}
+
+func (r *fakeLibraryRepo) CreateSession(ctx context.Context, session *Session, item *Item, previous *Completion) (*Session, *Item, error) {
+	r.createdSession = session
+	r.reread = previous
+	session.ID = uuid.Must(uuid.NewV7())
+	return session, item, nil
+}
+
+func (r *fakeLibraryRepo) LatestSession(ctx context.Context, itemID uuid.UUID) (*Session, error) {
+	return r.latestSession, nil
+}
+
+func (r *fakeLibraryRepo) ListSessions(ctx context.Context, itemID uuid.UUID, limit, offset int) ([]*Session, error) {
+	r.listLimit = limit
+	r.listOffset = offset
+	return nil, nil
+}
+
+func (r *fakeLibraryRepo) ListSessionsByUser(ctx context.Context, userID uuid.UUID, limit, offset int) ([]*Session, error) {
+	return nil, nil
+}
+
 func TestCreateDefaultsVisibilityToPrivate(t *testing.T) {
 	repo := &fakeLibraryRepo{}
 	service := NewService(repo, slog.New(slog.NewTextHandler(io.Discard, nil)))
@@ -78,6 +125,46 @@ func TestCreateDefaultsVisibilityToPrivate(t *testing.T) {
 	}
 }
 
+func TestCreateStampsCompletion(t *testing.T) {
+	service := NewService(&fakeLibraryRepo{}, slog.New(slog.NewTextHandler(io.Discard, nil)))
+	finished := time.Date(2024, 3, 2, 0, 0, 0, 0, time.UTC)
+
+	tests := []struct {
+		name    string
+		params  CreateItemParams
+		want    *time.Time
+		stamped bool
+	}{
+		{name: "stamped", params: CreateItemParams{}, stamped: true},
+		{name: "given", params: CreateItemParams{CompletedAt: &finished}, want: &finished},
+		{name: "unknown", params: CreateItemParams{CompletedAtUnknown: true}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			tt.params.UserID = uuid.Must(uuid.NewV7())
+			tt.params.SourceID = uuid.Must(uuid.NewV7())
+			tt.params.Status = StatusCompleted
+
+			created, err := service.Create(context.Background(), tt.params)
+			if err != nil {
+				t.Fatalf("Create returned error: %v", err)
+			}
+			switch {
+			case tt.stamped:
+				if created.CompletedAt == nil {
+					t.Fatal("CompletedAt = nil, want a stamp")
+				}
+			case tt.want == nil:
+				if created.CompletedAt != nil {
+					t.Fatalf("CompletedAt = %v, want nil", created.CompletedAt)
+				}
+			case created.CompletedAt == nil || !created.CompletedAt.Equal(*tt.want):
+				t.Fatalf("CompletedAt = %v, want %v", created.CompletedAt, tt.want)
+			}
+		})
+	}
+}
+
 func TestCreateRejectsInvalidProgress(t *testing.T) {
 	service := NewService(&fakeLibraryRepo{}, slog.New(slog.NewTextHandler(io.Discard, nil)))
 	negative := -1
@@ -93,18 +180,396 @@ func TestCreateRejectsInvalidProgress(t *testing.T) {
 	}
 }
 
-func TestListByUserNormalizesPagination(t *testing.T) {
+func TestListNormalizesPageAndSetsCursor(t *testing.T) {
+	repo := &fakeLibraryRepo{}
+	start := time.Date(2026, 3, 1, 12, 0, 0, 0, time.UTC)
+	for i := range 150 {
+		repo.listed = append(repo.listed, &Item{ID: uuid.Must(uuid.NewV7()), CreatedAt: start.Add(-time.Duration(i) * time.Minute)})
+	}
+	service := NewService(repo, slog.New(slog.NewTextHandler(io.Discard, nil)))
+	userID := uuid.Must(uuid.NewV7())
+
+	page, err := service.List(context.Background(), ListFilter{UserID: &userID}, echox.PageRequest{Limit: 500, IncludeTotal: true})
+	if err != nil {
+		t.Fatalf("List returned error: %v", err)
+	}
+	if repo.listPage.Limit != 100 {
+		t.Fatalf("limit = %d, want 100", repo.listPage.Limit)
+	}
+	if len(page.Items) != 100 || page.Total == nil || *page.Total != 150 {
+		t.Fatalf("page has %d items of %v, want 100 of 150", len(page.Items), page.Total)
+	}
+	if page.NextCursor == nil {
+		t.Fatal("next cursor is nil, want one pointing after the last item")
+	}
+	cursor, err := echox.ParseCursor(*page.NextCursor)
+	if err != nil {
+		t.Fatalf("ParseCursor returned error: %v", err)
+	}
+	if last := page.Items[99]; cursor.ID != last.ID || !cursor.CreatedAt.Equal(last.CreatedAt) {
+		t.Fatalf("cursor = %+v, want the last item %s", cursor, last.ID)
+	}
+
+	page, err = service.List(context.Background(), ListFilter{UserID: &userID}, echox.PageRequest{})
+	if err != nil {
+		t.Fatalf("List returned error: %v", err)
+	}
+	if page.Total != nil {
+		t.Fatalf("total = %d, want none when the request does not ask for it", *page.Total)
+	}
+
+	status := Status("shelved")
+	if _, err := service.List(context.Background(), ListFilter{Status: &status}, echox.PageRequest{}); !errors.Is(err, ErrInvalidFilter) {
+		t.Fatalf("error = %v, want %v", err, ErrInvalidFilter)
+	}
+}
+
+func TestListFiltersByPrivateTag(t *testing.T) {
+	repo := &fakeLibraryRepo{}
+	service := NewService(repo, slog.New(slog.NewTextHandler(io.Discard, nil)))
+	userID := uuid.Must(uuid.NewV7())
+	tag := " Ḥadīth "
+
+	if _, err := service.List(context.Background(), ListFilter{UserID: &userID, Tag: &tag}, echox.PageRequest{}); err != nil {
+		t.Fatalf("List returned error: %v", err)
+	}
+	if repo.listFilter.Tag == nil || *repo.listFilter.Tag != "hadith" {
+		t.Fatalf("tag = %v, want the slug", repo.listFilter.Tag)
+	}
+
+	_, err := service.List(context.Background(), ListFilter{UserID: &userID, PublicOnly: true, Tag: &tag}, echox.PageRequest{})
+	if !errors.Is(err, ErrInvalidFilter) {
+		t.Fatalf("error = %v, want %v", err, ErrInvalidFilter)
+	}
+}
+
+func TestCreateCleansTags(t *testing.T) {
+	repo := &fakeLibraryRepo{}
+	service := NewService(repo, slog.New(slog.NewTextHandler(io.Discard, nil)))
+
+	_, err := service.Create(context.Background(), CreateItemParams{
+		UserID:   uuid.Must(uuid.NewV7()),
+		SourceID: uuid.Must(uuid.NewV7()),
+		Status:   StatusToConsume,
+		Tags:     []string{"to reread ", "To  Reread", "مكتبة", ""},
+	})
+	if err != nil {
+		t.Fatalf("Create returned error: %v", err)
+	}
+	if !slices.Equal(repo.created.Tags, []string{"to reread", "مكتبة"}) {
+		t.Fatalf("tags = %q", repo.created.Tags)
+	}
+}
+
+func TestLogSessionDerivesItemState(t *testing.T) {
 	repo := &fakeLibraryRepo{}
 	service := NewService(repo, slog.New(slog.NewTextHandler(io.Discard, nil)))
+	page := ProgressUnitPage
+	item := &Item{ID: uuid.Must(uuid.NewV7()), UserID: uuid.Must(uuid.NewV7()), Status: StatusToConsume, ProgressUnit: &page}
+	started := time.Date(2026, 3, 1, 20, 0, 0, 0, time.UTC)
+	ended := started.Add(time.Hour)
+	from, to := 0, 42
 
-	_, err := service.ListByUser(context.Background(), uuid.Must(uuid.NewV7()), 500, -20)
+	result, err := service.LogSession(context.Background(), item, LogSessionParams{
+		StartedAt:    started,
+		EndedAt:      &ended,
+		ProgressFrom: &from,
+		ProgressTo:   &to,
+	})
 	if err != nil {
-		t.Fatalf("ListByUser returned error: %v", err)
+		t.Fatalf("LogSession returned error: %v", err)
+	}
+	if result.Item.Status != StatusInProgress {
+		t.Fatalf("Status = %q, want %q", result.Item.Status, StatusInProgress)
+	}
+	if result.Item.StartedAt == nil || !result.Item.StartedAt.Equal(started) {
+		t.Fatalf("StartedAt = %v, want %v", result.Item.StartedAt, started)
+	}
+	if result.Item.ProgressValue == nil || *result.Item.ProgressValue != 42 {
+		t.Fatalf("ProgressValue = %v, want 42", result.Item.ProgressValue)
+	}
+	if repo.createdSession.ProgressUnit == nil || *repo.createdSession.ProgressUnit != ProgressUnitPage {
+		t.Fatalf("session unit = %v, want the item's unit", repo.createdSession.ProgressUnit)
+	}
+}
+
+func TestLogSessionOnCompletedItem(t *testing.T) {
+	percent := ProgressUnitPercent
+	started := time.Date(2026, 1, 5, 20, 0, 0, 0, time.UTC)
+	completedAt := time.Date(2026, 2, 1, 20, 0, 0, 0, time.UTC)
+	march := time.Date(2026, 3, 1, 20, 0, 0, 0, time.UTC)
+	ended := march.Add(time.Hour)
+	completed := func() *Item {
+		progress := 100
+		return &Item{
+			ID:            uuid.Must(uuid.NewV7()),
+			UserID:        uuid.Must(uuid.NewV7()),
+			Status:        StatusCompleted,
+			ProgressValue: &progress,
+			ProgressUnit:  &percent,
+			StartedAt:     &started,
+			CompletedAt:   &completedAt,
+		}
+	}
+
+	t.Run("partial session keeps the finished read", func(t *testing.T) {
+		repo := &fakeLibraryRepo{}
+		service := NewService(repo, slog.New(slog.NewTextHandler(io.Discard, nil)))
+		ten := 10
+
+		result, err := service.LogSession(context.Background(), completed(), LogSessionParams{StartedAt: march, EndedAt: &ended, ProgressTo: &ten})
+		if err != nil {
+			t.Fatalf("LogSession returned error: %v", err)
+		}
+		item := result.Item
+		if item.Status != StatusCompleted || !item.CompletedAt.Equal(completedAt) || *item.ProgressValue != 100 {
+			t.Fatalf("item = %q %v %d, want completed at %v with progress 100", item.Status, item.CompletedAt, *item.ProgressValue, completedAt)
+		}
+		if repo.reread != nil {
+			t.Fatalf("kept completion = %+v, want none", repo.reread)
+		}
+	})
+
+	t.Run("finishing a new read keeps the earlier completion", func(t *testing.T) {
+		repo := &fakeLibraryRepo{}
+		service := NewService(repo, slog.New(slog.NewTextHandler(io.Discard, nil)))
+
+		result, err := service.LogSession(context.Background(), completed(), LogSessionParams{StartedAt: march, EndedAt: &ended, Finished: true})
+		if err != nil {
+			t.Fatalf("LogSession returned error: %v", err)
+		}
+		if repo.reread == nil || !repo.reread.CompletedAt.Equal(completedAt) || !repo.reread.StartedAt.Equal(started) {
+			t.Fatalf("kept completion = %+v, want the read from %v to %v", repo.reread, started, completedAt)
+		}
+		item := result.Item
+		if item.Status != StatusCompleted || !item.CompletedAt.Equal(ended) || !item.StartedAt.Equal(march) {
+			t.Fatalf("item = %q %v-%v, want completed from %v to %v", item.Status, item.StartedAt, item.CompletedAt, march, ended)
+		}
+		if item.RereadCount != 1 || *item.ProgressValue != 100 {
+			t.Fatalf("item = %d re-reads at %d, want one finished re-read", item.RereadCount, *item.ProgressValue)
+		}
+	})
+}
+
+func TestLogSessionOnAbandonedItem(t *testing.T) {
+	march := time.Date(2026, 3, 1, 20, 0, 0, 0, time.UTC)
+	ended := march.Add(time.Hour)
+	page := ProgressUnitPage
+	abandoned := func() *Item {
+		progress := 30
+		return &Item{ID: uuid.Must(uuid.NewV7()), UserID: uuid.Must(uuid.NewV7()), Status: StatusAbandoned, ProgressValue: &progress, ProgressUnit: &page}
+	}
+	forty := 40
+
+	tests := []struct {
+		name         string
+		latest       *Session
+		params       LogSessionParams
+		wantStatus   Status
+		wantProgress int
+	}{
+		{name: "latest session resumes it", params: LogSessionParams{StartedAt: march, ProgressTo: &forty}, wantStatus: StatusInProgress, wantProgress: 40},
+		{name: "back-filled session leaves it", latest: &Session{StartedAt: ended}, params: LogSessionParams{StartedAt: march, ProgressTo: &forty}, wantStatus: StatusAbandoned, wantProgress: 30},
+		{name: "finishing session completes it", params: LogSessionParams{StartedAt: march, EndedAt: &ended, ProgressTo: &forty, Finished: true}, wantStatus: StatusCompleted, wantProgress: 40},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			service := NewService(&fakeLibraryRepo{latestSession: tt.latest}, slog.New(slog.NewTextHandler(io.Discard, nil)))
+
+			result, err := service.LogSession(context.Background(), abandoned(), tt.params)
+			if err != nil {
+				t.Fatalf("LogSession returned error: %v", err)
+			}
+			item := result.Item
+			if item.Status != tt.wantStatus || *item.ProgressValue != tt.wantProgress {
+				t.Fatalf("item = %q at %d, want %q at %d", item.Status, *item.ProgressValue, tt.wantStatus, tt.wantProgress)
+			}
+			if (item.Status == StatusCompleted) != (item.CompletedAt != nil) || !validDates(item) {
+				t.Fatalf("item dates = %v-%v, want ones matching %q", item.StartedAt, item.CompletedAt, item.Status)
+			}
+		})
+	}
+}
+
+func TestLogSessionRejectsInvalidSessions(t *testing.T) {
+	service := NewService(&fakeLibraryRepo{}, slog.New(slog.NewTextHandler(io.Discard, nil)))
+	item := &Item{ID: uuid.Must(uuid.NewV7()), Status: StatusInProgress}
+	started := time.Date(2026, 3, 1, 20, 0, 0, 0, time.UTC)
+	before := started.Add(-time.Minute)
+	from, to := 50, 10
+	device := strings.Repeat("x", maxDeviceLength+1)
+
+	tests := map[string]LogSessionParams{
+		"missing start":     {},
+		"ends before start": {StartedAt: started, EndedAt: &before},
+		"progress backward": {StartedAt: started, ProgressFrom: &from, ProgressTo: &to},
+		"device too long":   {StartedAt: started, Device: &device},
+	}
+	for name, params := range tests {
+		t.Run(name, func(t *testing.T) {
+			if _, err := service.LogSession(context.Background(), item, params); !errors.Is(err, ErrInvalidSession) {
+				t.Fatalf("error = %v, want %v", err, ErrInvalidSession)
+			}
+		})
+	}
+}
+
+func TestApplySession(t *testing.T) {
+	march := time.Date(2026, 3, 10, 20, 0, 0, 0, time.UTC)
+	february := time.Date(2026, 2, 1, 20, 0, 0, 0, time.UTC)
+	percent := ProgressUnitPercent
+	ten, hundred := 10, 100
+
+	t.Run("back-filled session only moves the start date", func(t *testing.T) {
+		progress := 60
+		item := &Item{Status: StatusPaused, StartedAt: &march, ProgressValue: &progress}
+		applySession(item, &Session{StartedAt: february, ProgressTo: &ten}, false, false)
+		if !item.StartedAt.Equal(february) {
+			t.Fatalf("StartedAt = %v, want %v", item.StartedAt, february)
+		}
+		if *item.ProgressValue != 60 || item.Status != StatusPaused {
+			t.Fatalf("item = %d %q, want 60 paused", *item.ProgressValue, item.Status)
+		}
+	})
+
+	t.Run("reaching 100 percent completes the item", func(t *testing.T) {
+		ended := march.Add(time.Hour)
+		item := &Item{Status: StatusInProgress}
+		applySession(item, &Session{StartedAt: march, EndedAt: &ended, ProgressTo: &hundred, ProgressUnit: &percent}, true, false)
+		if item.Status != StatusCompleted || item.CompletedAt == nil || !item.CompletedAt.Equal(ended) {
+			t.Fatalf("item = %q %v, want completed at %v", item.Status, item.CompletedAt, ended)
+		}
+	})
+
+	t.Run("completed items stay completed", func(t *testing.T) {
+		item := &Item{Status: StatusCompleted, CompletedAt: &february}
+		applySession(item, &Session{StartedAt: march, ProgressTo: &ten, ProgressUnit: &percent}, true, false)
+		if item.Status != StatusCompleted || !item.CompletedAt.Equal(february) {
+			t.Fatalf("item = %q %v, want completed at %v", item.Status, item.CompletedAt, february)
+		}
+		if item.StartedAt != nil {
+			t.Fatalf("StartedAt = %v, want no start after the completion", item.StartedAt)
+		}
+	})
+}
+
+func TestUpdateEnforcesTransitions(t *testing.T) {
+	tests := []struct {
+		from, to Status
+		allowed  bool
+	}{
+		{StatusToConsume, StatusInProgress, true},
+		{StatusToConsume, StatusPaused, false},
+		{StatusInProgress, StatusPaused, true},
+		{StatusPaused, StatusToConsume, false},
+		{StatusAbandoned, StatusInProgress, true},
+		{StatusCompleted, StatusAbandoned, false},
+		{StatusCompleted, StatusCompleted, true},
+	}
+	for _, tt := range tests {
+		t.Run(string(tt.from)+" to "+string(tt.to), func(t *testing.T) {
+			item := &Item{ID: uuid.Must(uuid.NewV7()), Status: tt.from, Visibility: VisibilityPrivate}
+			service := NewService(&fakeLibraryRepo{item: item}, slog.New(slog.NewTextHandler(io.Discard, nil)))
+			to := tt.to
+
+			_, err := service.Update(context.Background(), item.ID, UpdateItemParams{Status: &to})
+			if tt.allowed && err != nil {
+				t.Fatalf("Update returned error: %v", err)
+			}
+			if !tt.allowed && !errors.Is(err, ErrInvalidTransition) {
+				t.Fatalf("error = %v, want %v", err, ErrInvalidTransition)
+			}
+		})
+	}
+}
+
+func TestUpdateStampsStatusChanges(t *testing.T) {
+	page := ProgressUnitPage
+	pageCount, progress := 320, 3
+	item := &Item{ID: uuid.Must(uuid.NewV7()), Status: StatusToConsume, Visibility: VisibilityPrivate, ProgressUnit: &page}
+	service := NewService(&fakeLibraryRepo{item: item, pageCount: &pageCount}, slog.New(slog.NewTextHandler(io.Discard, nil)))
+	inProgress, completed := StatusInProgress, StatusCompleted
+
+	started, err := service.Update(context.Background(), item.ID, UpdateItemParams{Status: &inProgress, ProgressValue: &progress})
+	if err != nil {
+		t.Fatalf("Update returned error: %v", err)
+	}
+	if started.StartedAt == nil || started.CompletedAt != nil {
+		t.Fatalf("dates = %v %v, want only a start", started.StartedAt, started.CompletedAt)
+	}
+
+	finished, err := service.Update(context.Background(), item.ID, UpdateItemParams{Status: &completed})
+	if err != nil {
+		t.Fatalf("Update returned error: %v", err)
+	}
+	if finished.CompletedAt == nil || finished.CompletedAt.Before(*finished.StartedAt) {
+		t.Fatalf("CompletedAt = %v, want a stamp after %v", finished.CompletedAt, finished.StartedAt)
+	}
+	if *finished.ProgressValue != pageCount {
+		t.Fatalf("ProgressValue = %d, want the page count %d", *finished.ProgressValue, pageCount)
+	}
+}
+
+func TestUpdateRejectsInconsistentItems(t *testing.T) {
+	page := ProgressUnitPage
+	pageCount, pastEnd := 200, 201
+	now := time.Date(2026, 3, 1, 20, 0, 0, 0, time.UTC)
+	earlier := now.Add(-time.Hour)
+
+	tests := map[string]struct {
+		item   Item
+		params UpdateItemParams
+		want   error
+	}{
+		"completed date on an unfinished item": {Item{Status: StatusInProgress}, UpdateItemParams{CompletedAt: &now}, ErrInvalidItem},
+		"start date on a queued item":          {Item{Status: StatusToConsume}, UpdateItemParams{StartedAt: &now}, ErrInvalidItem},
+		"completed before started":             {Item{Status: StatusCompleted, StartedAt: &now}, UpdateItemParams{CompletedAt: &earlier}, ErrInvalidItem},
+		"progress past the last page":          {Item{Status: StatusInProgress, ProgressUnit: &page}, UpdateItemParams{ProgressValue: &pastEnd}, ErrProgressOutOfRange},
+	}
+	for name, tt := range tests {
+		t.Run(name, func(t *testing.T) {
+			item := tt.item
+			item.ID = uuid.Must(uuid.NewV7())
+			item.Visibility = VisibilityPrivate
+			service := NewService(&fakeLibraryRepo{item: &item, pageCount: &pageCount}, slog.New(slog.NewTextHandler(io.Discard, nil)))
+
+			if _, err := service.Update(context.Background(), item.ID, tt.params); !errors.Is(err, tt.want) {
+				t.Fatalf("error = %v, want %v", err, tt.want)
+			}
+		})
+	}
+}
+
+func TestUpdateRereadKeepsEarlierCompletion(t *testing.T) {
+	percent := ProgressUnitPercent
+	started := time.Date(2025, 1, 5, 0, 0, 0, 0, time.UTC)
+	completedAt := time.Date(2025, 2, 1, 0, 0, 0, 0, time.UTC)
+	progress := 100
+	item := &Item{
+		ID:            uuid.Must(uuid.NewV7()),
+		UserID:        uuid.Must(uuid.NewV7()),
+		Status:        StatusCompleted,
+		Visibility:    VisibilityPrivate,
+		ProgressValue: &progress,
+		ProgressUnit:  &percent,
+		StartedAt:     &started,
+		CompletedAt:   &completedAt,
+	}
+	repo := &fakeLibraryRepo{item: item}
+	service := NewService(repo, slog.New(slog.NewTextHandler(io.Discard, nil)))
+	inProgress := StatusInProgress
+
+	updated, err := service.Update(context.Background(), item.ID, UpdateItemParams{Status: &inProgress})
+	if err != nil {
+		t.Fatalf("Update returned error: %v", err)
+	}
+	if repo.reread == nil || !repo.reread.CompletedAt.Equal(completedAt) || !repo.reread.StartedAt.Equal(started) {
+		t.Fatalf("kept completion = %+v, want the read from %v to %v", repo.reread, started, completedAt)
 	}
-	if repo.listLimit != 100 {
-		t.Fatalf("limit = %d, want 100", repo.listLimit)
+	if updated.RereadCount != 1 || updated.CompletedAt != nil || *updated.ProgressValue != 0 {
+		t.Fatalf("item = %d %v %d, want one re-read restarted from zero", updated.RereadCount, updated.CompletedAt, *updated.ProgressValue)
 	}
-	if repo.listOffset != 0 {
-		t.Fatalf("offset = %d, want 0", repo.listOffset)
+	if !updated.StartedAt.After(completedAt) {
+		t.Fatalf("StartedAt = %v, want a new start", updated.StartedAt)
 	}
 }
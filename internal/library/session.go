@@ -0,0 +1,127 @@
+package library
+
+import (
+	"time"
+
+	"github.com/gofrs/uuid/v5"
+)
+
+// Session is one sitting with a library item: when it happened and how far
+// the user got.
+type Session struct {
+	ID            uuid.UUID     `json:"id"`
+	LibraryItemID uuid.UUID     `json:"library_item_id"`
+	UserID        uuid.UUID     `json:"user_id"`
+	StartedAt     time.Time     `json:"started_at"`
+	EndedAt       *time.Time    `json:"ended_at,omitempty"`
+	ProgressFrom  *int          `json:"progress_from,omitempty"`
+	ProgressTo    *int          `json:"progress_to,omitempty"`
+	ProgressUnit  *ProgressUnit `json:"progress_unit,omitempty"`
+	Device        *string       `json:"device,omitempty"`
+	CreatedAt     time.Time     `json:"created_at"`
+}
+
+// SessionResult is a logged session together with the item it updated
+type SessionResult struct {
+	Session *Session `json:"session"`
+	Item    *Item    `json:"item"`
+}
+
+type LogSessionParams struct {
+	StartedAt    time.Time
+	EndedAt      *time.Time
+	ProgressFrom *int
+	ProgressTo   *int
+	ProgressUnit *ProgressUnit
+	Device       *string
+	// Finished marks the session in which the item was completed
+	Finished bool
+}
+
+// applySession derives the item's dates, progress and status from a newly
+// logged session, moving the status through the same transitions an update
+// takes. Only the latest session moves progress, so back-filling an old
+// sitting does not rewind the item. A session belonging to a finished read
+// leaves a completed item alone, while finishing a new read after the last
+// completion starts a re-read and returns the earlier completion to keep.
+func applySession(item *Item, session *Session, latest, finished bool) (*Completion, error) {
+	ended := session.StartedAt
+	if session.EndedAt != nil {
+		ended = *session.EndedAt
+	}
+	finishing := finished || (latest && reachedEnd(session))
+	to := sessionStatus(item, latest, finishing)
+
+	var previous *Completion
+	if item.Status == StatusCompleted {
+		rereading := finishing && item.CompletedAt != nil && session.StartedAt.After(*item.CompletedAt)
+		if !rereading {
+			moveStart(item, session)
+			if finishing && item.CompletedAt == nil {
+				item.CompletedAt = &ended
+			}
+			return nil, nil
+		}
+		previous = enterStatus(item, StatusInProgress, session.StartedAt)
+	}
+
+	moveStart(item, session)
+	// An abandoned item has to be resumed before it can be completed
+	path := []Status{to}
+	if !canTransition(item.Status, to) && canTransition(item.Status, StatusInProgress) {
+		path = []Status{StatusInProgress, to}
+	}
+	for _, next := range path {
+		if !canTransition(item.Status, next) {
+			return nil, ErrInvalidTransition
+		}
+		at := session.StartedAt
+		if next == StatusCompleted {
+			at = ended
+		}
+		enterStatus(item, next, at)
+	}
+
+	if latest && session.ProgressTo != nil {
+		progress := *session.ProgressTo
+		item.ProgressValue = &progress
+		if session.ProgressUnit != nil {
+			unit := *session.ProgressUnit
+			item.ProgressUnit = &unit
+		}
+	}
+	return previous, nil
+}
+
+// sessionStatus is the status a session moves the item to. Finishing always
+// completes it, and only the latest session resumes a paused or abandoned
+// item.
+func sessionStatus(item *Item, latest, finishing bool) Status {
+	if finishing {
+		return StatusCompleted
+	}
+	switch item.Status {
+	case StatusToConsume:
+		return StatusInProgress
+	case StatusPaused, StatusAbandoned:
+		if latest {
+			return StatusInProgress
+		}
+	}
+	return item.Status
+}
+
+// moveStart moves the item's start back to the session's when the session
+// came first, keeping the start no later than the item's completion.
+func moveStart(item *Item, session *Session) {
+	beforeCompletion := item.CompletedAt == nil || !session.StartedAt.After(*item.CompletedAt)
+	if beforeCompletion && (item.StartedAt == nil || session.StartedAt.Before(*item.StartedAt)) {
+		started := session.StartedAt
+		item.StartedAt = &started
+	}
+}
+
+func reachedEnd(session *Session) bool {
+	return session.ProgressUnit != nil && *session.ProgressUnit == ProgressUnitPercent &&
+		session.ProgressTo != nil && *session.ProgressTo >= 100
+}
@@ -0,0 +1,110 @@
+package library
+
+import "time"
+
+// transitions lists the statuses an item may move to from each status.
+// A completed item can only be started again, which begins a re-read.
+var transitions = map[Status][]Status{
+	StatusToConsume:  {StatusInProgress, StatusCompleted, StatusAbandoned},
+	StatusInProgress: {StatusToConsume, StatusCompleted, StatusPaused, StatusAbandoned},
+	StatusPaused:     {StatusInProgress, StatusCompleted, StatusAbandoned},
+	StatusAbandoned:  {StatusToConsume, StatusInProgress},
+	StatusCompleted:  {StatusInProgress},
+}
+
+func canTransition(from, to Status) bool {
+	if from == to {
+		return true
+	}
+	for _, next := range transitions[from] {
+		if next == to {
+			return true
+		}
+	}
+	return false
+}
+
+// enterStatus moves the item to a new status, clearing the dates and
+// progress that belonged to the old one and stamping the ones the new one
+// needs. Starting a completed item again returns the finished read so it can
+// be kept.
+func enterStatus(item *Item, to Status, now time.Time) *Completion {
+	from := item.Status
+	item.Status = to
+	if from == to {
+		return nil
+	}
+
+	var previous *Completion
+	switch to {
+	case StatusToConsume:
+		item.StartedAt, item.CompletedAt, item.ProgressValue = nil, nil, nil
+	case StatusCompleted:
+		if item.CompletedAt == nil {
+			item.CompletedAt = &now
+		}
+	default:
+		if from == StatusCompleted {
+			previous = &Completion{LibraryItemID: item.ID, UserID: item.UserID, StartedAt: item.StartedAt, CompletedAt: item.UpdatedAt}
+			if item.CompletedAt != nil {
+				previous.CompletedAt = *item.CompletedAt
+			}
+			item.RereadCount++
+			item.StartedAt = nil
+			if item.ProgressValue != nil {
+				zero := 0
+				item.ProgressValue = &zero
+			}
+		}
+		item.CompletedAt = nil
+		if item.StartedAt == nil && to != StatusAbandoned {
+			item.StartedAt = &now
+		}
+	}
+	return previous
+}
+
+// finishProgress moves progress to the end of the source when an item is
+// completed, where the end is known.
+func finishProgress(item *Item, pageCount *int) {
+	if item.ProgressUnit == nil {
+		return
+	}
+	switch *item.ProgressUnit {
+	case ProgressUnitPercent:
+		end := 100
+		item.ProgressValue = &end
+	case ProgressUnitPage:
+		if pageCount != nil {
+			end := *pageCount
+			item.ProgressValue = &end
+		}
+	}
+}
+
+// validDates reports whether the item's dates agree with its status
+func validDates(item *Item) bool {
+	if item.Status == StatusToConsume && item.StartedAt != nil {
+		return false
+	}
+	if item.Status != StatusCompleted && item.CompletedAt != nil {
+		return false
+	}
+	return item.StartedAt == nil || item.CompletedAt == nil || !item.CompletedAt.Before(*item.StartedAt)
+}
+
+// withinLength reports whether progress stays inside the source: no more
+// than 100 percent, or the book's page count when it is known.
+func withinLength(value *int, unit *ProgressUnit, pageCount *int) bool {
+	if value == nil || unit == nil {
+		return true
+	}
+	switch *unit {
+	case ProgressUnitPercent:
+		return *value <= 100
+	case ProgressUnitPage:
+		return pageCount == nil || *value <= *pageCount
+	default:
+		return true
+	}
+}
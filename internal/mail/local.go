@@ -0,0 +1,53 @@
+package mail
+
+import (
+	"context"
+	"log/slog"
+	"os"
+	"path/filepath"
+	"time"
+
+	"github.com/gofrs/uuid/v5"
+)
+
+// LogMailer writes each message to the log instead of sending it, so links
+// can be copied out of the server output during local development.
+type LogMailer struct {
+	logger *slog.Logger
+}
+
+func NewLogMailer(logger *slog.Logger) *LogMailer {
+	return &LogMailer{logger: logger}
+}
+
+func (m *LogMailer) Send(_ context.Context, message Message) error {
+	m.logger.Info("email not sent", "to", message.To, "subject", message.Subject, "body", message.Body)
+	return nil
+}
+
+// FileMailer saves each message as an .eml file in a directory, where a mail
+// client or a test can open it.
+type FileMailer struct {
+	dir  string
+	from string
+}
+
+func NewFileMailer(dir, from string) *FileMailer {
+	return &FileMailer{dir: dir, from: from}
+}
+
+func (m *FileMailer) Send(_ context.Context, message Message) error {
+	now := time.Now()
+	body, err := compose(m.from, message, now)
+	if err != nil {
+		return err
+	}
+	if err := os.MkdirAll(m.dir, 0o750); err != nil {
+		return err
+	}
+	id, err := uuid.NewV7()
+	if err != nil {
+		return err
+	}
+	return os.WriteFile(filepath.Join(m.dir, id.String()+".eml"), body, 0o600)
+}
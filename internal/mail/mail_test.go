@@ -0,0 +1,98 @@
+package mail
+
+import (
+	"context"
+	"io"
+	"net"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestCompose(t *testing.T) {
+	date := time.Date(2026, 3, 1, 12, 0, 0, 0, time.UTC)
+	got, err := compose("Maktaba <no-reply@example.com>", Message{
+		To:      "reader@example.com",
+		Subject: "Reset your password",
+		Body:    "Open this link:\nhttps://example.com/reset",
+	}, date)
+	if err != nil {
+		t.Fatalf("compose() error = %v", err)
+	}
+
+	text := string(got)
+	for _, want := range []string{
+		"From: Maktaba <no-reply@example.com>\r\n",
+		"To: reader@example.com\r\n",
+		"Subject: Reset your password\r\n",
+		"Date: Sun, 01 Mar 2026 12:00:00 +0000\r\n",
+		"\r\n\r\nOpen this link:\r\nhttps://example.com/reset\r\n",
+	} {
+		if !strings.Contains(text, want) {
+			t.Fatalf("message missing %q:\n%s", want, text)
+		}
+	}
+}
+
+func TestComposeRejectsHeaderInjection(t *testing.T) {
+	if _, err := compose("no-reply@example.com", Message{To: "reader@example.com", Subject: "Hi\r\nBcc: x@example.com"}, time.Now()); err == nil {
+		t.Fatal("expected an error for a subject with a line break")
+	}
+	if _, err := compose("no-reply@example.com", Message{To: "reader@example.com\r\nBcc: x@example.com"}, time.Now()); err == nil {
+		t.Fatal("expected an error for an invalid recipient")
+	}
+}
+
+func TestFileMailer(t *testing.T) {
+	dir := t.TempDir()
+	mailer := NewFileMailer(dir, "no-reply@example.com")
+	if err := mailer.Send(context.Background(), Message{To: "reader@example.com", Subject: "Hello", Body: "Hi"}); err != nil {
+		t.Fatalf("Send() error = %v", err)
+	}
+
+	files, err := filepath.Glob(filepath.Join(dir, "*.eml"))
+	if err != nil || len(files) != 1 {
+		t.Fatalf("files = %v, %v; want one .eml file", files, err)
+	}
+	body, err := os.ReadFile(files[0])
+	if err != nil {
+		t.Fatalf("read message: %v", err)
+	}
+	if !strings.Contains(string(body), "Subject: Hello\r\n") {
+		t.Fatalf("unexpected message:\n%s", body)
+	}
+}
+
+func TestSMTPMailerStopsAtContextDeadline(t *testing.T) {
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatalf("listen: %v", err)
+	}
+	defer listener.Close()
+	// The server accepts the connection but never sends its greeting.
+	go func() {
+		conn, err := listener.Accept()
+		if err == nil {
+			defer conn.Close()
+			io.Copy(io.Discard, conn)
+		}
+	}()
+
+	addr := listener.Addr().(*net.TCPAddr)
+	mailer := NewSMTPMailer("127.0.0.1", addr.Port, "", "", "no-reply@example.com", time.Minute)
+	ctx, cancel := context.WithTimeout(context.Background(), 100*time.Millisecond)
+	defer cancel()
+
+	done := make(chan error, 1)
+	go func() { done <- mailer.Send(ctx, Message{To: "reader@example.com", Subject: "Hello", Body: "Hi"}) }()
+	select {
+	case err := <-done:
+		if err == nil {
+			t.Fatal("Send() error = nil, want a timeout")
+		}
+	case <-time.After(5 * time.Second):
+		t.Fatal("Send() did not return after the context deadline")
+	}
+}
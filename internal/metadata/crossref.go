@@ -0,0 +1,129 @@
+package metadata
+
+import (
+	"context"
+	"net/http"
+	"net/url"
+	"regexp"
+	"strings"
+	"time"
+)
+
+// Crossref looks works up by DOI in the Crossref REST API. Giving a contact
+// address puts requests in Crossref's more reliable "polite" pool.
+type Crossref struct {
+	client  *http.Client
+	baseURL string
+	mailto  string
+}
+
+func NewCrossref(client *http.Client, baseURL, mailto string) *Crossref {
+	if baseURL == "" {
+		baseURL = "https://api.crossref.org"
+	}
+	return &Crossref{client: client, baseURL: strings.TrimRight(baseURL, "/"), mailto: mailto}
+}
+
+func (c *Crossref) Name() string {
+	return "crossref"
+}
+
+type crossrefPerson struct {
+	Given  string `json:"given"`
+	Family string `json:"family"`
+	Name   string `json:"name"`
+}
+
+func (p crossrefPerson) fullName() string {
+	if p.Name != "" {
+		return p.Name
+	}
+	return strings.TrimSpace(p.Given + " " + p.Family)
+}
+
+type crossrefWork struct {
+	Message struct {
+		Title     []string         `json:"title"`
+		Subtitle  []string         `json:"subtitle"`
+		Author    []crossrefPerson `json:"author"`
+		Editor    []crossrefPerson `json:"editor"`
+		Publisher string           `json:"publisher"`
+		Abstract  string           `json:"abstract"`
+		DOI       string           `json:"DOI"`
+		URL       string           `json:"URL"`
+		ISBN      []string         `json:"ISBN"`
+		Language  string           `json:"language"`
+		Subject   []string         `json:"subject"`
+		Published struct {
+			DateParts [][]int `json:"date-parts"`
+		} `json:"published"`
+	} `json:"message"`
+}
+
+var markupTags = regexp.MustCompile(`<[^>]+>`)
+
+// plainText strips the JATS markup Crossref wraps abstracts in, keeping
+// paragraphs apart.
+func plainText(markup string) string {
+	text := markupTags.ReplaceAllString(strings.ReplaceAll(markup, "</jats:p>", " "), "")
+	return strings.Join(strings.Fields(text), " ")
+}
+
+func (c *Crossref) LookupISBN(ctx context.Context, isbn13 string) (*Record, error) {
+	return nil, ErrUnsupported
+}
+
+func (c *Crossref) LookupDOI(ctx context.Context, doi string) (*Record, error) {
+	header := http.Header{}
+	if c.mailto != "" {
+		header.Set("User-Agent", userAgent+" (mailto:"+c.mailto+")")
+	}
+	var work crossrefWork
+	if err := getJSON(ctx, c.client, c.baseURL+"/works/"+url.PathEscape(doi), header, &work); err != nil {
+		return nil, err
+	}
+	message := work.Message
+	if len(message.Title) == 0 || message.Title[0] == "" {
+		return nil, ErrNotFound
+	}
+
+	record := &Record{
+		Title:     message.Title[0],
+		Publisher: optional(message.Publisher),
+		DOI:       optional(message.DOI),
+		URL:       optional(message.URL),
+		Language:  optional(message.Language),
+		Subjects:  message.Subject,
+	}
+	if len(message.Subtitle) > 0 {
+		record.Subtitle = optional(message.Subtitle[0])
+	}
+	if abstract := plainText(message.Abstract); abstract != "" {
+		record.Description = &abstract
+	}
+	if parts := message.Published.DateParts; len(parts) > 0 && len(parts[0]) > 0 {
+		date := parts[0]
+		month, day := 1, 1
+		if len(date) > 1 {
+			month = date[1]
+		}
+		if len(date) > 2 {
+			day = date[2]
+		}
+		published := time.Date(date[0], time.Month(month), day, 0, 0, 0, 0, time.UTC)
+		record.PublishedAt = &published
+	}
+	for _, isbn := range message.ISBN {
+		if normalized, err := NormalizeISBN(isbn); err == nil {
+			record.ISBN13 = &normalized
+			break
+		}
+	}
+	for _, author := range message.Author {
+		record.Contributors = append(record.Contributors, Contributor{Name: author.fullName(), Role: "author"})
+	}
+	for _, editor := range message.Editor {
+		record.Contributors = append(record.Contributors, Contributor{Name: editor.fullName(), Role: "editor"})
+	}
+	return record, nil
+}
@@ -0,0 +1,98 @@
+package metadata
+
+import (
+	"context"
+	"net/http"
+	"net/url"
+	"strings"
+)
+
+// GoogleBooks looks books up by ISBN in the Google Books volumes API. The API
+// key is optional but raises the daily quota.
+type GoogleBooks struct {
+	client  *http.Client
+	baseURL string
+	apiKey  string
+}
+
+func NewGoogleBooks(client *http.Client, baseURL, apiKey string) *GoogleBooks {
+	if baseURL == "" {
+		baseURL = "https://www.googleapis.com"
+	}
+	return &GoogleBooks{client: client, baseURL: strings.TrimRight(baseURL, "/"), apiKey: apiKey}
+}
+
+func (g *GoogleBooks) Name() string {
+	return "googlebooks"
+}
+
+type googleVolumes struct {
+	TotalItems int `json:"totalItems"`
+	Items      []struct {
+		VolumeInfo struct {
+			Title               string   `json:"title"`
+			Subtitle            string   `json:"subtitle"`
+			Authors             []string `json:"authors"`
+			Publisher           string   `json:"publisher"`
+			PublishedDate       string   `json:"publishedDate"`
+			Description         string   `json:"description"`
+			PageCount           int      `json:"pageCount"`
+			Categories          []string `json:"categories"`
+			Language            string   `json:"language"`
+			CanonicalVolumeLink string   `json:"canonicalVolumeLink"`
+			IndustryIdentifiers []struct {
+				Type       string `json:"type"`
+				Identifier string `json:"identifier"`
+			} `json:"industryIdentifiers"`
+			ImageLinks struct {
+				Thumbnail string `json:"thumbnail"`
+			} `json:"imageLinks"`
+		} `json:"volumeInfo"`
+	} `json:"items"`
+}
+
+func (g *GoogleBooks) LookupISBN(ctx context.Context, isbn13 string) (*Record, error) {
+	query := url.Values{"q": {"isbn:" + isbn13}}
+	if g.apiKey != "" {
+		query.Set("key", g.apiKey)
+	}
+	var volumes googleVolumes
+	if err := getJSON(ctx, g.client, g.baseURL+"/books/v1/volumes?"+query.Encode(), nil, &volumes); err != nil {
+		return nil, err
+	}
+	if volumes.TotalItems == 0 || len(volumes.Items) == 0 || volumes.Items[0].VolumeInfo.Title == "" {
+		return nil, ErrNotFound
+	}
+
+	info := volumes.Items[0].VolumeInfo
+	record := &Record{
+		Title:       info.Title,
+		Subtitle:    optional(info.Subtitle),
+		Description: optional(info.Description),
+		Publisher:   optional(info.Publisher),
+		PublishedAt: parseDate(info.PublishedDate),
+		URL:         optional(info.CanonicalVolumeLink),
+		Language:    optional(info.Language),
+		CoverURL:    optional(strings.Replace(info.ImageLinks.Thumbnail, "http://", "https://", 1)),
+		Subjects:    info.Categories,
+	}
+	if info.PageCount > 0 {
+		record.PageCount = &info.PageCount
+	}
+	for _, identifier := range info.IndustryIdentifiers {
+		switch identifier.Type {
+		case "ISBN_10":
+			record.ISBN10 = optional(identifier.Identifier)
+		case "ISBN_13":
+			record.ISBN13 = optional(identifier.Identifier)
+		}
+	}
+	for _, author := range info.Authors {
+		record.Contributors = append(record.Contributors, Contributor{Name: author, Role: "author"})
+	}
+	return record, nil
+}
+
+func (g *GoogleBooks) LookupDOI(ctx context.Context, doi string) (*Record, error) {
+	return nil, ErrUnsupported
+}
@@ -0,0 +1,271 @@
+// Package metadata looks up bibliographic records for ISBNs and DOIs in public
+// catalogues so sources do not have to be typed in by hand.
+package metadata
+
+import (
+	"context"
+	"errors"
+	"log/slog"
+	"strings"
+	"time"
+)
+
+var (
+	ErrNotFound    = errors.New("no metadata found")
+	ErrUnsupported = errors.New("lookup not supported by provider")
+	ErrInvalidISBN = errors.New("invalid ISBN")
+	ErrInvalidDOI  = errors.New("invalid DOI")
+)
+
+// Record is what a provider knows about a work. Fields a provider does not
+// supply are left empty.
+type Record struct {
+	Title        string        `json:"title"`
+	Subtitle     *string       `json:"subtitle,omitempty"`
+	Description  *string       `json:"description,omitempty"`
+	Publisher    *string       `json:"publisher,omitempty"`
+	PublishedAt  *time.Time    `json:"published_at,omitempty"`
+	ISBN10       *string       `json:"isbn_10,omitempty"`
+	ISBN13       *string       `json:"isbn_13,omitempty"`
+	DOI          *string       `json:"doi,omitempty"`
+	URL          *string       `json:"url,omitempty"`
+	PageCount    *int          `json:"page_count,omitempty"`
+	Language     *string       `json:"language,omitempty"`
+	CoverURL     *string       `json:"cover_url,omitempty"`
+	Contributors []Contributor `json:"contributors,omitempty"`
+	Subjects     []string      `json:"subjects,omitempty"`
+	Providers    []string      `json:"providers"`
+}
+
+type Contributor struct {
+	Name string `json:"name"`
+	Role string `json:"role"`
+}
+
+// Provider is a catalogue that can be asked about an identifier. Lookups a
+// catalogue cannot answer return ErrUnsupported, and unknown identifiers
+// return ErrNotFound.
+type Provider interface {
+	Name() string
+	LookupISBN(ctx context.Context, isbn13 string) (*Record, error)
+	LookupDOI(ctx context.Context, doi string) (*Record, error)
+}
+
+// Lookup asks each provider in turn and merges their answers. Earlier
+// providers win for fields that more than one of them supplies.
+type Lookup struct {
+	providers []Provider
+	logger    *slog.Logger
+}
+
+func NewLookup(providers []Provider, logger *slog.Logger) *Lookup {
+	return &Lookup{providers: providers, logger: logger}
+}
+
+// ISBN looks up a book by ISBN-10 or ISBN-13
+func (l *Lookup) ISBN(ctx context.Context, isbn string) (*Record, error) {
+	isbn13, err := NormalizeISBN(isbn)
+	if err != nil {
+		return nil, err
+	}
+	record, err := l.lookup(ctx, func(p Provider) (*Record, error) { return p.LookupISBN(ctx, isbn13) })
+	if err != nil {
+		return nil, err
+	}
+	if record.ISBN13 == nil {
+		record.ISBN13 = &isbn13
+	}
+	if record.ISBN10 == nil {
+		if isbn10, ok := ISBN10(isbn13); ok {
+			record.ISBN10 = &isbn10
+		}
+	}
+	return record, nil
+}
+
+// DOI looks up a work by DOI
+func (l *Lookup) DOI(ctx context.Context, doi string) (*Record, error) {
+	normalized, err := NormalizeDOI(doi)
+	if err != nil {
+		return nil, err
+	}
+	record, err := l.lookup(ctx, func(p Provider) (*Record, error) { return p.LookupDOI(ctx, normalized) })
+	if err != nil {
+		return nil, err
+	}
+	if record.DOI == nil {
+		record.DOI = &normalized
+	}
+	return record, nil
+}
+
+// lookup merges every answer it gets. A provider that fails is logged and
+// skipped; its error is only returned when no provider had an answer.
+func (l *Lookup) lookup(ctx context.Context, ask func(Provider) (*Record, error)) (*Record, error) {
+	var merged *Record
+	var lastErr error
+	for _, provider := range l.providers {
+		record, err := ask(provider)
+		if errors.Is(err, ErrNotFound) || errors.Is(err, ErrUnsupported) {
+			continue
+		}
+		if err != nil {
+			l.logger.Warn("metadata provider failed", "provider", provider.Name(), "error", err)
+			lastErr = err
+			continue
+		}
+		record.Providers = []string{provider.Name()}
+		if merged == nil {
+			merged = record
+		} else {
+			merged.merge(record)
+		}
+	}
+	if merged == nil {
+		if lastErr != nil {
+			return nil, lastErr
+		}
+		return nil, ErrNotFound
+	}
+	return merged, nil
+}
+
+// merge fills the fields r is missing from other
+func (r *Record) merge(other *Record) {
+	if r.Title == "" {
+		r.Title = other.Title
+	}
+	fill(&r.Subtitle, other.Subtitle)
+	fill(&r.Description, other.Description)
+	fill(&r.Publisher, other.Publisher)
+	fill(&r.PublishedAt, other.PublishedAt)
+	fill(&r.ISBN10, other.ISBN10)
+	fill(&r.ISBN13, other.ISBN13)
+	fill(&r.DOI, other.DOI)
+	fill(&r.URL, other.URL)
+	fill(&r.PageCount, other.PageCount)
+	fill(&r.Language, other.Language)
+	fill(&r.CoverURL, other.CoverURL)
+	if len(r.Contributors) == 0 {
+		r.Contributors = other.Contributors
+	}
+	if len(r.Subjects) == 0 {
+		r.Subjects = other.Subjects
+	}
+	r.Providers = append(r.Providers, other.Providers...)
+}
+
+func fill[T any](dst **T, src *T) {
+	if *dst == nil && src != nil {
+		*dst = src
+	}
+}
+
+// NormalizeISBN strips separators from an ISBN-10 or ISBN-13, checks its
+// check digit, and returns it as an ISBN-13.
+func NormalizeISBN(value string) (string, error) {
+	isbn := strings.ToUpper(strings.Map(func(r rune) rune {
+		if r == '-' || r == ' ' {
+			return -1
+		}
+		return r
+	}, strings.TrimSpace(value)))
+
+	switch len(isbn) {
+	case 10:
+		sum := 0
+		for i, r := range isbn {
+			digit := int(r - '0')
+			if r == 'X' && i == 9 {
+				digit = 10
+			} else if r < '0' || r > '9' {
+				return "", ErrInvalidISBN
+			}
+			sum += (10 - i) * digit
+		}
+		if sum%11 != 0 {
+			return "", ErrInvalidISBN
+		}
+		core := "978" + isbn[:9]
+		return core + isbn13CheckDigit(core), nil
+	case 13:
+		for _, r := range isbn {
+			if r < '0' || r > '9' {
+				return "", ErrInvalidISBN
+			}
+		}
+		if isbn13CheckDigit(isbn[:12]) != isbn[12:] {
+			return "", ErrInvalidISBN
+		}
+		return isbn, nil
+	default:
+		return "", ErrInvalidISBN
+	}
+}
+
+// ISBN10 converts a 978-prefixed ISBN-13 back to its ISBN-10 form
+func ISBN10(isbn13 string) (string, bool) {
+	if len(isbn13) != 13 || !strings.HasPrefix(isbn13, "978") {
+		return "", false
+	}
+	core := isbn13[3:12]
+	sum := 0
+	for i, r := range core {
+		sum += (10 - i) * int(r-'0')
+	}
+	check := (11 - sum%11) % 11
+	if check == 10 {
+		return core + "X", true
+	}
+	return core + string(rune('0'+check)), true
+}
+
+func isbn13CheckDigit(first12 string) string {
+	sum := 0
+	for i, r := range first12 {
+		weight := 1
+		if i%2 == 1 {
+			weight = 3
+		}
+		sum += weight * int(r-'0')
+	}
+	return string(rune('0' + (10-sum%10)%10))
+}
+
+// NormalizeDOI strips resolver and "doi:" prefixes and checks the DOI has a
+// 10.x registrant prefix and a suffix.
+func NormalizeDOI(value string) (string, error) {
+	doi := strings.TrimSpace(value)
+	lower := strings.ToLower(doi)
+	for _, prefix := range []string{"https://doi.org/", "http://doi.org/", "https://dx.doi.org/", "http://dx.doi.org/", "doi:"} {
+		if strings.HasPrefix(lower, prefix) {
+			doi = strings.TrimSpace(doi[len(prefix):])
+			break
+		}
+	}
+	prefix, suffix, ok := strings.Cut(doi, "/")
+	if !ok || !strings.HasPrefix(prefix, "10.") || len(prefix) < 4 || suffix == "" {
+		return "", ErrInvalidDOI
+	}
+	return doi, nil
+}
+
+// parseDate reads the loose publication dates catalogues return, from a bare
+// year to a full date.
+func parseDate(value string) *time.Time {
+	value = strings.TrimSpace(value)
+	for _, layout := range []string{"2006-01-02", "2006-01", "2006", "January 2, 2006", "Jan 2, 2006", "2 January 2006", "January 2006", "Jan 2006"} {
+		if parsed, err := time.Parse(layout, value); err == nil {
+			return &parsed
+		}
+	}
+	return nil
+}
+
+func optional(value string) *string {
+	value = strings.TrimSpace(value)
+	if value == "" {
+		return nil
+	}
+	return &value
+}
@@ -0,0 +1,118 @@
+package metadata
+
+import (
+	"context"
+	"errors"
+	"io"
+	"log/slog"
+	"slices"
+	"testing"
+)
+
+type fakeProvider struct {
+	name   string
+	record *Record
+	err    error
+	isbns  []string
+}
+
+func (p *fakeProvider) Name() string {
+	return p.name
+}
+
+func (p *fakeProvider) LookupISBN(ctx context.Context, isbn13 string) (*Record, error) {
+	p.isbns = append(p.isbns, isbn13)
+	if p.err != nil {
+		return nil, p.err
+	}
+	record := *p.record
+	return &record, nil
+}
+
+func (p *fakeProvider) LookupDOI(ctx context.Context, doi string) (*Record, error) {
+	return nil, ErrUnsupported
+}
+
+func TestNormalizeISBN(t *testing.T) {
+	tests := []struct {
+		input string
+		want  string
+		err   error
+	}{
+		{input: "0-691-12054-4", want: "9780691120546"},
+		{input: "978 0 691 12054 6", want: "9780691120546"},
+		{input: "080442957x", want: "9780804429573"},
+		{input: "0691120545", err: ErrInvalidISBN},
+		{input: "9780691120547", err: ErrInvalidISBN},
+		{input: "12345", err: ErrInvalidISBN},
+	}
+	for _, tt := range tests {
+		t.Run(tt.input, func(t *testing.T) {
+			got, err := NormalizeISBN(tt.input)
+			if !errors.Is(err, tt.err) || got != tt.want {
+				t.Fatalf("NormalizeISBN(%q) = %q, %v; want %q, %v", tt.input, got, err, tt.want, tt.err)
+			}
+		})
+	}
+
+	if isbn10, ok := ISBN10("9780804429573"); !ok || isbn10 != "080442957X" {
+		t.Fatalf("ISBN10() = %q, %v", isbn10, ok)
+	}
+	if _, ok := ISBN10("9798886450071"); ok {
+		t.Fatal("ISBN10() of a 979 ISBN should fail")
+	}
+}
+
+func TestNormalizeDOI(t *testing.T) {
+	for _, input := range []string{"10.1016/j.cognition.2008.05.007", "https://doi.org/10.1016/j.cognition.2008.05.007", "doi: 10.1016/j.cognition.2008.05.007"} {
+		if got, err := NormalizeDOI(input); err != nil || got != "10.1016/j.cognition.2008.05.007" {
+			t.Fatalf("NormalizeDOI(%q) = %q, %v", input, got, err)
+		}
+	}
+	for _, input := range []string{"", "10.1016", "11.1016/abc", "https://example.com/paper"} {
+		if _, err := NormalizeDOI(input); !errors.Is(err, ErrInvalidDOI) {
+			t.Fatalf("NormalizeDOI(%q) error = %v, want %v", input, err, ErrInvalidDOI)
+		}
+	}
+}
+
+func TestLookupMergesProviders(t *testing.T) {
+	pages := 465
+	language := "en"
+	publisher := "Princeton University Press"
+	otherPublisher := "Princeton"
+	failing := &fakeProvider{name: "down", err: errors.New("connection refused")}
+	missing := &fakeProvider{name: "missing", err: ErrNotFound}
+	first := &fakeProvider{name: "first", record: &Record{Title: "The Muqaddimah", Publisher: &publisher, PageCount: &pages}}
+	second := &fakeProvider{name: "second", record: &Record{
+		Title:        "Muqaddimah",
+		Publisher:    &otherPublisher,
+		Language:     &language,
+		Contributors: []Contributor{{Name: "Ibn Khaldun", Role: "author"}},
+	}}
+	lookup := NewLookup([]Provider{failing, missing, first, second}, slog.New(slog.NewTextHandler(io.Discard, nil)))
+
+	record, err := lookup.ISBN(context.Background(), "0-691-12054-4")
+	if err != nil {
+		t.Fatalf("ISBN() error = %v", err)
+	}
+	if first.isbns[0] != "9780691120546" {
+		t.Fatalf("provider asked for %q, want the ISBN-13", first.isbns[0])
+	}
+	if record.Title != "The Muqaddimah" || *record.Publisher != publisher || *record.PageCount != pages || *record.Language != language {
+		t.Fatalf("record = %+v", record)
+	}
+	if len(record.Contributors) != 1 || *record.ISBN13 != "9780691120546" || *record.ISBN10 != "0691120544" {
+		t.Fatalf("record = %+v", record)
+	}
+	if !slices.Equal(record.Providers, []string{"first", "second"}) {
+		t.Fatalf("providers = %v", record.Providers)
+	}
+
+	if _, err := NewLookup([]Provider{missing}, slog.New(slog.NewTextHandler(io.Discard, nil))).ISBN(context.Background(), "9780691120546"); !errors.Is(err, ErrNotFound) {
+		t.Fatalf("ISBN() with no answers error = %v, want %v", err, ErrNotFound)
+	}
+	if _, err := lookup.ISBN(context.Background(), "not an isbn"); !errors.Is(err, ErrInvalidISBN) {
+		t.Fatalf("ISBN() error = %v, want %v", err, ErrInvalidISBN)
+	}
+}
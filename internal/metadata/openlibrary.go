@@ -0,0 +1,95 @@
+package metadata
+
+import (
+	"context"
+	"net/http"
+	"net/url"
+	"strings"
+)
+
+// OpenLibrary looks books up by ISBN in the Open Library books API.
+type OpenLibrary struct {
+	client  *http.Client
+	baseURL string
+}
+
+func NewOpenLibrary(client *http.Client, baseURL string) *OpenLibrary {
+	if baseURL == "" {
+		baseURL = "https://openlibrary.org"
+	}
+	return &OpenLibrary{client: client, baseURL: strings.TrimRight(baseURL, "/")}
+}
+
+func (o *OpenLibrary) Name() string {
+	return "openlibrary"
+}
+
+type openLibraryName struct {
+	Name string `json:"name"`
+}
+
+type openLibraryBook struct {
+	URL           string            `json:"url"`
+	Title         string            `json:"title"`
+	Subtitle      string            `json:"subtitle"`
+	Authors       []openLibraryName `json:"authors"`
+	Publishers    []openLibraryName `json:"publishers"`
+	PublishDate   string            `json:"publish_date"`
+	NumberOfPages int               `json:"number_of_pages"`
+	Subjects      []openLibraryName `json:"subjects"`
+	Identifiers   struct {
+		ISBN10 []string `json:"isbn_10"`
+		ISBN13 []string `json:"isbn_13"`
+	} `json:"identifiers"`
+	Cover struct {
+		Large  string `json:"large"`
+		Medium string `json:"medium"`
+	} `json:"cover"`
+}
+
+func (o *OpenLibrary) LookupISBN(ctx context.Context, isbn13 string) (*Record, error) {
+	key := "ISBN:" + isbn13
+	query := url.Values{"bibkeys": {key}, "format": {"json"}, "jscmd": {"data"}}
+	var books map[string]openLibraryBook
+	if err := getJSON(ctx, o.client, o.baseURL+"/api/books?"+query.Encode(), nil, &books); err != nil {
+		return nil, err
+	}
+	book, ok := books[key]
+	if !ok || book.Title == "" {
+		return nil, ErrNotFound
+	}
+
+	record := &Record{
+		Title:       book.Title,
+		Subtitle:    optional(book.Subtitle),
+		PublishedAt: parseDate(book.PublishDate),
+		URL:         optional(book.URL),
+		CoverURL:    optional(book.Cover.Large),
+	}
+	if record.CoverURL == nil {
+		record.CoverURL = optional(book.Cover.Medium)
+	}
+	if len(book.Publishers) > 0 {
+		record.Publisher = optional(book.Publishers[0].Name)
+	}
+	if book.NumberOfPages > 0 {
+		record.PageCount = &book.NumberOfPages
+	}
+	if len(book.Identifiers.ISBN10) > 0 {
+		record.ISBN10 = optional(book.Identifiers.ISBN10[0])
+	}
+	if len(book.Identifiers.ISBN13) > 0 {
+		record.ISBN13 = optional(book.Identifiers.ISBN13[0])
+	}
+	for _, author := range book.Authors {
+		record.Contributors = append(record.Contributors, Contributor{Name: author.Name, Role: "author"})
+	}
+	for _, subject := range book.Subjects {
+		record.Subjects = append(record.Subjects, subject.Name)
+	}
+	return record, nil
+}
+
+func (o *OpenLibrary) LookupDOI(ctx context.Context, doi string) (*Record, error) {
+	return nil, ErrUnsupported
+}
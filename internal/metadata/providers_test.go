@@ -0,0 +1,140 @@
+package metadata
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+// fixtureServer answers requests for path with a recorded response. When
+// known is set and reports false the server answers with an empty object, as
+// catalogues do for identifiers they lack. Every other path is a 404.
+func fixtureServer(t *testing.T, path, fixture string, known func(*http.Request) bool) *httptest.Server {
+	t.Helper()
+	body, err := os.ReadFile(filepath.Join("testdata", fixture))
+	if err != nil {
+		t.Fatal(err)
+	}
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != path {
+			http.NotFound(w, r)
+			return
+		}
+		w.Header().Set("Content-Type", "application/json")
+		if known != nil && !known(r) {
+			_, _ = w.Write([]byte("{}"))
+			return
+		}
+		_, _ = w.Write(body)
+	}))
+	t.Cleanup(server.Close)
+	return server
+}
+
+func TestOpenLibraryLookupISBN(t *testing.T) {
+	server := fixtureServer(t, "/api/books", "openlibrary_books.json", func(r *http.Request) bool {
+		query := r.URL.Query()
+		if query.Get("format") != "json" || query.Get("jscmd") != "data" {
+			t.Errorf("query = %v", query)
+		}
+		return query.Get("bibkeys") == "ISBN:9780691120546"
+	})
+	provider := NewOpenLibrary(server.Client(), server.URL)
+
+	record, err := provider.LookupISBN(context.Background(), "9780691120546")
+	if err != nil {
+		t.Fatalf("LookupISBN() error = %v", err)
+	}
+	if record.Title != "The Muqaddimah" || *record.Subtitle != "an introduction to history" || *record.Publisher != "Princeton University Press" {
+		t.Fatalf("record = %+v", record)
+	}
+	if *record.PageCount != 465 || *record.ISBN10 != "0691120544" || record.PublishedAt.Year() != 2005 {
+		t.Fatalf("record = %+v", record)
+	}
+	if *record.CoverURL != "https://covers.openlibrary.org/b/id/8091016-L.jpg" {
+		t.Fatalf("cover = %q", *record.CoverURL)
+	}
+	if len(record.Contributors) != 1 || record.Contributors[0] != (Contributor{Name: "Ibn Khaldun", Role: "author"}) {
+		t.Fatalf("contributors = %+v", record.Contributors)
+	}
+
+	if _, err := provider.LookupISBN(context.Background(), "9780141439518"); !errors.Is(err, ErrNotFound) {
+		t.Fatalf("LookupISBN() for unknown ISBN error = %v, want %v", err, ErrNotFound)
+	}
+	if _, err := provider.LookupDOI(context.Background(), "10.1000/xyz"); !errors.Is(err, ErrUnsupported) {
+		t.Fatalf("LookupDOI() error = %v, want %v", err, ErrUnsupported)
+	}
+}
+
+func TestGoogleBooksLookupISBN(t *testing.T) {
+	server := fixtureServer(t, "/books/v1/volumes", "googlebooks_volumes.json", func(r *http.Request) bool {
+		if got := r.URL.Query().Get("key"); got != "secret" {
+			t.Errorf("key = %q", got)
+		}
+		return r.URL.Query().Get("q") == "isbn:9780691120546"
+	})
+	provider := NewGoogleBooks(server.Client(), server.URL, "secret")
+
+	record, err := provider.LookupISBN(context.Background(), "9780691120546")
+	if err != nil {
+		t.Fatalf("LookupISBN() error = %v", err)
+	}
+	if record.Title != "The Muqaddimah" || *record.Language != "en" || *record.PageCount != 512 || *record.ISBN13 != "9780691120546" {
+		t.Fatalf("record = %+v", record)
+	}
+	if want := time.Date(2005, time.April, 4, 0, 0, 0, 0, time.UTC); !record.PublishedAt.Equal(want) {
+		t.Fatalf("published = %v, want %v", record.PublishedAt, want)
+	}
+	if *record.CoverURL != "https://books.google.com/books/content?id=Q3bFxsPMWTgC&printsec=frontcover&img=1&zoom=1" {
+		t.Fatalf("cover = %q", *record.CoverURL)
+	}
+	if record.Description == nil || len(record.Subjects) != 1 {
+		t.Fatalf("record = %+v", record)
+	}
+
+	if _, err := provider.LookupISBN(context.Background(), "9780141439518"); !errors.Is(err, ErrNotFound) {
+		t.Fatalf("LookupISBN() for unknown ISBN error = %v, want %v", err, ErrNotFound)
+	}
+}
+
+func TestCrossrefLookupDOI(t *testing.T) {
+	server := fixtureServer(t, "/works/10.1016/j.cognition.2008.05.007", "crossref_work.json", func(r *http.Request) bool {
+		if got := r.Header.Get("User-Agent"); got != "bayt-alhikmah/1.0 (mailto:catalog@example.com)" {
+			t.Errorf("User-Agent = %q", got)
+		}
+		return true
+	})
+	provider := NewCrossref(server.Client(), server.URL, "catalog@example.com")
+
+	record, err := provider.LookupDOI(context.Background(), "10.1016/j.cognition.2008.05.007")
+	if err != nil {
+		t.Fatalf("LookupDOI() error = %v", err)
+	}
+	if record.Title != "The mystery of the missing fraction" || *record.DOI != "10.1016/j.cognition.2008.05.007" || *record.Publisher != "Elsevier BV" {
+		t.Fatalf("record = %+v", record)
+	}
+	if *record.Description != "Children struggle with fractions." {
+		t.Fatalf("description = %q", *record.Description)
+	}
+	if want := time.Date(2008, time.September, 1, 0, 0, 0, 0, time.UTC); !record.PublishedAt.Equal(want) {
+		t.Fatalf("published = %v, want %v", record.PublishedAt, want)
+	}
+	want := []Contributor{{Name: "Ada Rahman", Role: "author"}, {Name: "Yusuf Haddad", Role: "author"}, {Name: "Cognition Editorial Board", Role: "editor"}}
+	if len(record.Contributors) != len(want) {
+		t.Fatalf("contributors = %+v", record.Contributors)
+	}
+	for i := range want {
+		if record.Contributors[i] != want[i] {
+			t.Fatalf("contributors = %+v, want %+v", record.Contributors, want)
+		}
+	}
+
+	if _, err := provider.LookupDOI(context.Background(), "10.1000/missing"); !errors.Is(err, ErrNotFound) {
+		t.Fatalf("LookupDOI() for unknown DOI error = %v, want %v", err, ErrNotFound)
+	}
+}
@@ -0,0 +1,209 @@
+package notes
+
+import (
+	"fmt"
+	"regexp"
+	"strconv"
+	"strings"
+	"unicode/utf8"
+)
+
+// SelectorType names a W3C Web Annotation selector
+type SelectorType string
+
+const (
+	// SelectorFragment points at a fragment of the source, such as an EPUB
+	// CFI, named by ConformsTo
+	SelectorFragment SelectorType = "FragmentSelector"
+	// SelectorTextQuote quotes the exact text with a little context either
+	// side, so the passage can be found again in another edition
+	SelectorTextQuote SelectorType = "TextQuoteSelector"
+	// SelectorTextPosition gives character offsets into the text
+	SelectorTextPosition SelectorType = "TextPositionSelector"
+)
+
+// EPUBCFI is the ConformsTo of a fragment selector holding an EPUB CFI.
+const EPUBCFI = "http://www.idpf.org/epub/linking/cfi/epub-cfi.html"
+
+const (
+	maxChapterLength = 200
+	maxQuoteLength   = 5000
+	maxContextLength = 200
+	maxSelectors     = 5
+)
+
+// Anchor places a note in its source: a page range in a book, a stretch of
+// a podcast or video from StartSeconds, a chapter label, and selectors
+// following the W3C Web Annotation model for e-books and web text. Any
+// combination may be set.
+type Anchor struct {
+	PageStart    *int       `json:"page_start,omitempty"`
+	PageEnd      *int       `json:"page_end,omitempty"`
+	StartSeconds *int       `json:"start_seconds,omitempty"`
+	EndSeconds   *int       `json:"end_seconds,omitempty"`
+	Chapter      *string    `json:"chapter,omitempty"`
+	Selectors    []Selector `json:"selectors,omitempty"`
+}
+
+// Selector is a W3C Web Annotation selector and keeps the model's field
+// names. Which fields apply depends on Type.
+type Selector struct {
+	Type       SelectorType `json:"type"`
+	Value      *string      `json:"value,omitempty"`
+	ConformsTo *string      `json:"conformsTo,omitempty"`
+	Exact      *string      `json:"exact,omitempty"`
+	Prefix     *string      `json:"prefix,omitempty"`
+	Suffix     *string      `json:"suffix,omitempty"`
+	Start      *int         `json:"start,omitempty"`
+	End        *int         `json:"end,omitempty"`
+}
+
+// normalizeAnchor checks an anchor before it is saved. A missing end of a
+// range defaults to its start, and an anchor with nothing set is dropped.
+func normalizeAnchor(anchor *Anchor) (*Anchor, error) {
+	if anchor == nil {
+		return nil, nil
+	}
+	if (anchor.PageEnd != nil && anchor.PageStart == nil) || (anchor.EndSeconds != nil && anchor.StartSeconds == nil) {
+		return nil, fmt.Errorf("%w: a range needs its start", ErrInvalidAnchor)
+	}
+	if anchor.PageStart != nil {
+		if *anchor.PageStart < 1 {
+			return nil, fmt.Errorf("%w: pages start at 1", ErrInvalidAnchor)
+		}
+		if anchor.PageEnd == nil {
+			anchor.PageEnd = anchor.PageStart
+		}
+		if *anchor.PageEnd < *anchor.PageStart {
+			return nil, fmt.Errorf("%w: page_end is before page_start", ErrInvalidAnchor)
+		}
+	}
+	if anchor.StartSeconds != nil {
+		if *anchor.StartSeconds < 0 {
+			return nil, fmt.Errorf("%w: start_seconds is negative", ErrInvalidAnchor)
+		}
+		if anchor.EndSeconds != nil && *anchor.EndSeconds < *anchor.StartSeconds {
+			return nil, fmt.Errorf("%w: end_seconds is before start_seconds", ErrInvalidAnchor)
+		}
+	}
+	anchor.Chapter = trimmed(anchor.Chapter)
+	if anchor.Chapter != nil && utf8.RuneCountInString(*anchor.Chapter) > maxChapterLength {
+		return nil, fmt.Errorf("%w: chapter is longer than %d characters", ErrInvalidAnchor, maxChapterLength)
+	}
+
+	if len(anchor.Selectors) > maxSelectors {
+		return nil, fmt.Errorf("%w: at most %d selectors", ErrInvalidAnchor, maxSelectors)
+	}
+	for i := range anchor.Selectors {
+		if err := normalizeSelector(&anchor.Selectors[i]); err != nil {
+			return nil, err
+		}
+	}
+	if len(anchor.Selectors) == 0 {
+		anchor.Selectors = nil
+	}
+
+	if anchor.PageStart == nil && anchor.StartSeconds == nil && anchor.Chapter == nil && anchor.Selectors == nil {
+		return nil, nil
+	}
+	return anchor, nil
+}
+
+func normalizeSelector(selector *Selector) error {
+	switch selector.Type {
+	case SelectorFragment:
+		selector.Value = trimmed(selector.Value)
+		if selector.Value == nil {
+			return fmt.Errorf("%w: a FragmentSelector needs a value", ErrInvalidAnchor)
+		}
+		if selector.ConformsTo == nil && strings.HasPrefix(*selector.Value, "epubcfi(") {
+			conformsTo := EPUBCFI
+			selector.ConformsTo = &conformsTo
+		}
+	case SelectorTextQuote:
+		if selector.Exact == nil || strings.TrimSpace(*selector.Exact) == "" {
+			return fmt.Errorf("%w: a TextQuoteSelector needs the exact text", ErrInvalidAnchor)
+		}
+		if utf8.RuneCountInString(*selector.Exact) > maxQuoteLength {
+			return fmt.Errorf("%w: exact is longer than %d characters", ErrInvalidAnchor, maxQuoteLength)
+		}
+		for _, around := range []*string{selector.Prefix, selector.Suffix} {
+			if around != nil && utf8.RuneCountInString(*around) > maxContextLength {
+				return fmt.Errorf("%w: prefix and suffix are limited to %d characters", ErrInvalidAnchor, maxContextLength)
+			}
+		}
+	case SelectorTextPosition:
+		if selector.Start == nil || selector.End == nil || *selector.Start < 0 || *selector.End < *selector.Start {
+			return fmt.Errorf("%w: a TextPositionSelector needs a start and an end after it", ErrInvalidAnchor)
+		}
+	default:
+		return fmt.Errorf("%w: unknown selector type %q", ErrInvalidAnchor, selector.Type)
+	}
+	return nil
+}
+
+// cfiStep matches the step and character offset numbers of an EPUB CFI
+var cfiStep = regexp.MustCompile(`[/:](\d+)`)
+
+// cfiAssertion matches the bracketed ID assertions a CFI may carry
+var cfiAssertion = regexp.MustCompile(`\[[^\]]*\]`)
+
+// locationKey orders notes on a source by where they point. Pages come
+// first, then times, then EPUB CFIs, then text offsets, each numbered so
+// they sort in that order and zero padded so numbers compare as text. Notes
+// anchored only to a chapter, or not at all, have no key and sort last.
+func locationKey(anchor *Anchor) *string {
+	if anchor == nil {
+		return nil
+	}
+	var parts []string
+	if anchor.PageStart != nil {
+		parts = append(parts, fmt.Sprintf("1:%08d-%08d", *anchor.PageStart, *anchor.PageEnd))
+	}
+	if anchor.StartSeconds != nil {
+		parts = append(parts, fmt.Sprintf("2:%08d", *anchor.StartSeconds))
+	}
+	for _, selector := range anchor.Selectors {
+		if selector.Type == SelectorFragment && selector.ConformsTo != nil && *selector.ConformsTo == EPUBCFI {
+			parts = append(parts, "3:"+cfiKey(*selector.Value))
+		}
+	}
+	for _, selector := range anchor.Selectors {
+		if selector.Type == SelectorTextPosition {
+			parts = append(parts, fmt.Sprintf("4:%010d", *selector.Start))
+		}
+	}
+	if len(parts) == 0 {
+		return nil
+	}
+	key := strings.Join(parts, "|")
+	return &key
+}
+
+// cfiKey turns an EPUB CFI into a string that sorts in document order. A
+// range CFI sorts by where it starts.
+func cfiKey(cfi string) string {
+	cfi = strings.TrimSuffix(strings.TrimPrefix(cfi, "epubcfi("), ")")
+	cfi = cfiAssertion.ReplaceAllString(cfi, "")
+	if parent, rest, ok := strings.Cut(cfi, ","); ok {
+		start, _, _ := strings.Cut(rest, ",")
+		cfi = parent + start
+	}
+	var steps []string
+	for _, match := range cfiStep.FindAllStringSubmatch(cfi, -1) {
+		step, _ := strconv.Atoi(match[1])
+		steps = append(steps, fmt.Sprintf("%08d", step))
+	}
+	return strings.Join(steps, ".")
+}
+
+func trimmed(value *string) *string {
+	if value == nil {
+		return nil
+	}
+	trimmed := strings.TrimSpace(*value)
+	if trimmed == "" {
+		return nil
+	}
+	return &trimmed
+}
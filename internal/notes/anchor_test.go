@@ -0,0 +1,105 @@
+package notes
+
+import (
+	"errors"
+	"slices"
+	"testing"
+)
+
+func TestNormalizeAnchor(t *testing.T) {
+	page := func(n int) *int { return &n }
+	text := func(s string) *string { return &s }
+
+	tests := []struct {
+		name    string
+		anchor  *Anchor
+		wantErr bool
+		wantNil bool
+	}{
+		{name: "missing", anchor: nil, wantNil: true},
+		{name: "empty", anchor: &Anchor{Chapter: text("  ")}, wantNil: true},
+		{name: "single page", anchor: &Anchor{PageStart: page(12)}},
+		{name: "page range", anchor: &Anchor{PageStart: page(12), PageEnd: page(14)}},
+		{name: "page zero", anchor: &Anchor{PageStart: page(0)}, wantErr: true},
+		{name: "backwards range", anchor: &Anchor{PageStart: page(14), PageEnd: page(12)}, wantErr: true},
+		{name: "end without start", anchor: &Anchor{PageEnd: page(3)}, wantErr: true},
+		{name: "timestamp", anchor: &Anchor{StartSeconds: page(95)}},
+		{name: "negative timestamp", anchor: &Anchor{StartSeconds: page(-1)}, wantErr: true},
+		{name: "text quote", anchor: &Anchor{Selectors: []Selector{{Type: SelectorTextQuote, Exact: text("the quote")}}}},
+		{name: "text quote without text", anchor: &Anchor{Selectors: []Selector{{Type: SelectorTextQuote}}}, wantErr: true},
+		{name: "text position", anchor: &Anchor{Selectors: []Selector{{Type: SelectorTextPosition, Start: page(10), End: page(4)}}}, wantErr: true},
+		{name: "unknown selector", anchor: &Anchor{Selectors: []Selector{{Type: "CssSelector", Value: text("p")}}}, wantErr: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := normalizeAnchor(tt.anchor)
+			if tt.wantErr {
+				if !errors.Is(err, ErrInvalidAnchor) {
+					t.Fatalf("error = %v, want %v", err, ErrInvalidAnchor)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("normalizeAnchor() error = %v", err)
+			}
+			if (got == nil) != tt.wantNil {
+				t.Fatalf("normalizeAnchor() = %+v, want nil %v", got, tt.wantNil)
+			}
+		})
+	}
+}
+
+func TestNormalizeAnchorDefaults(t *testing.T) {
+	start := 7
+	cfi := "epubcfi(/6/4!/4/2/1:3)"
+	anchor, err := normalizeAnchor(&Anchor{
+		PageStart: &start,
+		Selectors: []Selector{{Type: SelectorFragment, Value: &cfi}},
+	})
+	if err != nil {
+		t.Fatalf("normalizeAnchor() error = %v", err)
+	}
+	if anchor.PageEnd == nil || *anchor.PageEnd != start {
+		t.Fatalf("PageEnd = %v, want %d", anchor.PageEnd, start)
+	}
+	if conformsTo := anchor.Selectors[0].ConformsTo; conformsTo == nil || *conformsTo != EPUBCFI {
+		t.Fatalf("ConformsTo = %v, want %q", conformsTo, EPUBCFI)
+	}
+}
+
+func TestLocationKeyOrder(t *testing.T) {
+	pages := func(start, end int) *Anchor { return &Anchor{PageStart: &start, PageEnd: &end} }
+	seconds := func(n int) *Anchor { return &Anchor{StartSeconds: &n} }
+	cfi := func(value string) *Anchor {
+		conformsTo := EPUBCFI
+		return &Anchor{Selectors: []Selector{{Type: SelectorFragment, Value: &value, ConformsTo: &conformsTo}}}
+	}
+
+	ordered := []*Anchor{
+		pages(2, 2),
+		pages(9, 9),
+		pages(10, 12),
+		seconds(30),
+		seconds(600),
+		cfi("epubcfi(/6/4[chap01]!/4/2/1:3)"),
+		cfi("epubcfi(/6/4!/4/10/1:0)"),
+		cfi("epubcfi(/6/10!/4/2,/1:0,/1:9)"),
+	}
+	var keys []string
+	for _, anchor := range ordered {
+		key := locationKey(anchor)
+		if key == nil {
+			t.Fatalf("locationKey(%+v) = nil", anchor)
+		}
+		keys = append(keys, *key)
+	}
+	if !slices.IsSorted(keys) {
+		t.Fatalf("keys out of reading order: %q", keys)
+	}
+
+	chapter := "Chapter 3"
+	if key := locationKey(&Anchor{Chapter: &chapter}); key != nil {
+		t.Fatalf("chapter-only key = %q, want nil", *key)
+	}
+}
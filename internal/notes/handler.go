@@ -5,7 +5,6 @@ import (
 	"log/slog"
 	"net/http"
 
-	"github.com/gofrs/uuid/v5"
 	"github.com/labstack/echo/v5"
 	"github.com/zizouhuweidi/maktaba/internal/auth"
 	"github.com/zizouhuweidi/maktaba/internal/echox"
@@ -25,6 +24,7 @@ type CreateRequest struct {
 	Content     string   `json:"content" validate:"required"`
 	ContentType string   `json:"content_type" validate:"required"`
 	IsPublic    bool     `json:"is_public"`
+	Anchor      *Anchor  `json:"anchor,omitempty"`
 	Annotations []string `json:"annotations,omitempty"`
 	Tags        []string `json:"tags,omitempty"`
 }
@@ -33,6 +33,7 @@ type UpdateRequest struct {
 	Content     *string  `json:"content,omitempty"`
 	ContentType *string  `json:"content_type,omitempty"`
 	IsPublic    *bool    `json:"is_public,omitempty"`
+	Anchor      *Anchor  `json:"anchor,omitempty"`
 	Annotations []string `json:"annotations,omitempty"`
 	Tags        []string `json:"tags,omitempty"`
 }
@@ -40,11 +41,15 @@ type UpdateRequest struct {
 func (h *Handler) RegisterPublicRoutes(e *echo.Echo) {
 	e.GET("/notes", h.List)
 	e.GET("/notes/:id", h.GetByID)
+	e.GET("/tags/:slug/notes", h.ListByTag)
+	e.GET("/sources/:id/notes", h.ListBySource)
 }
 
 func (h *Handler) RegisterProtectedRoutes(g *echo.Group) {
 	g.POST("/notes", h.Create)
 	g.GET("/notes", h.ListMine)
+	g.GET("/notes/:id/similar", h.ListSimilar)
+	g.GET("/sources/:id/notes", h.ListMineBySource)
 	g.PUT("/notes/:id", h.Update)
 	g.DELETE("/notes/:id", h.Delete)
 }
@@ -71,12 +76,16 @@ func (h *Handler) Create(c *echo.Context) error {
 		Content:     req.Content,
 		ContentType: ContentType(req.ContentType),
 		IsPublic:    req.IsPublic,
+		Anchor:      req.Anchor,
 		Annotations: req.Annotations,
 		Tags:        req.Tags,
 	})
 	if errors.Is(err, ErrInvalidNote) {
 		return echo.NewHTTPError(http.StatusBadRequest, "invalid note")
 	}
+	if errors.Is(err, ErrInvalidAnchor) {
+		return echo.NewHTTPError(http.StatusBadRequest, err.Error())
+	}
 	if errors.Is(err, ErrSourceNotFound) {
 		return echo.NewHTTPError(http.StatusNotFound, "source not found")
 	}
@@ -108,30 +117,36 @@ func (h *Handler) GetByID(c *echo.Context) error {
 }
 
 func (h *Handler) List(c *echo.Context) error {
-	limit, offset := echox.Pagination(c)
-	userIDStr := c.QueryParam("user_id")
-	sourceIDStr := c.QueryParam("source_id")
-	publicOnly := c.QueryParam("public") == "true"
-
-	var result []*Note
-	var err error
-	if userIDStr != "" {
-		userID, parseErr := uuid.FromString(userIDStr)
-		if parseErr != nil {
-			return echo.NewHTTPError(http.StatusBadRequest, "invalid user_id")
-		}
-		result, err = h.service.ListPublicByUser(c.Request().Context(), userID, limit, offset)
-	} else if sourceIDStr != "" {
-		sourceID, parseErr := uuid.FromString(sourceIDStr)
-		if parseErr != nil {
-			return echo.NewHTTPError(http.StatusBadRequest, "invalid source_id")
-		}
-		result, err = h.service.ListPublicBySource(c.Request().Context(), sourceID, limit, offset)
-	} else if publicOnly {
-		result, err = h.service.ListPublic(c.Request().Context(), limit, offset)
-	} else {
+	page, err := echox.PageParams(c)
+	if err != nil {
+		return err
+	}
+	filter, err := listFilter(c)
+	if err != nil {
+		return err
+	}
+	if filter.UserID == nil && filter.SourceID == nil && c.QueryParam("public") != "true" {
 		return echo.NewHTTPError(http.StatusBadRequest, "user_id, source_id, or public=true required")
 	}
+	filter.PublicOnly = true
+
+	result, err := h.service.List(c.Request().Context(), filter, page)
+	if err != nil {
+		return echo.NewHTTPError(http.StatusInternalServerError, "failed to list notes")
+	}
+
+	return c.JSON(http.StatusOK, result)
+}
+
+// ListByTag pages through the public notes carrying the tag in the path
+func (h *Handler) ListByTag(c *echo.Context) error {
+	page, err := echox.PageParams(c)
+	if err != nil {
+		return err
+	}
+	tag := c.Param("slug")
+
+	result, err := h.service.List(c.Request().Context(), ListFilter{PublicOnly: true, Tag: &tag}, page)
 	if err != nil {
 		return echo.NewHTTPError(http.StatusInternalServerError, "failed to list notes")
 	}
@@ -144,9 +159,67 @@ func (h *Handler) ListMine(c *echo.Context) error {
 	if !ok {
 		return echo.NewHTTPError(http.StatusUnauthorized, "authentication required")
 	}
+	page, err := echox.PageParams(c)
+	if err != nil {
+		return err
+	}
+	filter, err := listFilter(c)
+	if err != nil {
+		return err
+	}
+	filter.UserID = &userID
+
+	notes, err := h.service.List(c.Request().Context(), filter, page)
+	if err != nil {
+		return echo.NewHTTPError(http.StatusInternalServerError, "failed to list notes")
+	}
+
+	return c.JSON(http.StatusOK, notes)
+}
+
+// ListBySource lists the public notes on a source in reading order,
+// optionally narrowed to one user or content type
+func (h *Handler) ListBySource(c *echo.Context) error {
+	sourceID, err := echox.ParamUUID(c, "id", "source ID")
+	if err != nil {
+		return err
+	}
+	filter, err := listFilter(c)
+	if err != nil {
+		return err
+	}
+	filter.SourceID = &sourceID
+	filter.PublicOnly = true
+
 	limit, offset := echox.Pagination(c)
+	notes, err := h.service.ListByLocation(c.Request().Context(), filter, limit, offset)
+	if err != nil {
+		return echo.NewHTTPError(http.StatusInternalServerError, "failed to list notes")
+	}
+
+	return c.JSON(http.StatusOK, notes)
+}
 
-	notes, err := h.service.ListByUser(c.Request().Context(), userID, limit, offset)
+// ListMineBySource lists the caller's notes on a source in reading order,
+// private ones included
+func (h *Handler) ListMineBySource(c *echo.Context) error {
+	userID, ok := auth.UserID(c)
+	if !ok {
+		return echo.NewHTTPError(http.StatusUnauthorized, "authentication required")
+	}
+	sourceID, err := echox.ParamUUID(c, "id", "source ID")
+	if err != nil {
+		return err
+	}
+	filter, err := listFilter(c)
+	if err != nil {
+		return err
+	}
+	filter.UserID = &userID
+	filter.SourceID = &sourceID
+
+	limit, offset := echox.Pagination(c)
+	notes, err := h.service.ListByLocation(c.Request().Context(), filter, limit, offset)
 	if err != nil {
 		return echo.NewHTTPError(http.StatusInternalServerError, "failed to list notes")
 	}
@@ -154,6 +227,55 @@ func (h *Handler) ListMine(c *echo.Context) error {
 	return c.JSON(http.StatusOK, notes)
 }
 
+// listFilter reads the user_id, source_id, content_type and tag query
+// parameters
+func listFilter(c *echo.Context) (ListFilter, error) {
+	userID, err := echox.OptionalUUID(echox.QueryString(c, "user_id"), "user_id")
+	if err != nil {
+		return ListFilter{}, err
+	}
+	sourceID, err := echox.OptionalUUID(echox.QueryString(c, "source_id"), "source_id")
+	if err != nil {
+		return ListFilter{}, err
+	}
+	var contentType *ContentType
+	if value := echox.QueryString(c, "content_type"); value != nil {
+		ct := ContentType(*value)
+		contentType = &ct
+	}
+	return ListFilter{UserID: userID, SourceID: sourceID, ContentType: contentType, Tag: echox.QueryString(c, "tag")}, nil
+}
+
+func (h *Handler) ListSimilar(c *echo.Context) error {
+	userID, ok := auth.UserID(c)
+	if !ok {
+		return echo.NewHTTPError(http.StatusUnauthorized, "authentication required")
+	}
+	id, err := echox.ParamUUID(c, "id", "note ID")
+	if err != nil {
+		return err
+	}
+
+	existing, err := h.service.GetByID(c.Request().Context(), id)
+	if errors.Is(err, ErrNoteNotFound) {
+		return echo.NewHTTPError(http.StatusNotFound, "note not found")
+	}
+	if err != nil {
+		return echo.NewHTTPError(http.StatusInternalServerError, "failed to get note")
+	}
+	if existing.UserID != userID {
+		return echo.NewHTTPError(http.StatusForbidden, "cannot read another user's note")
+	}
+
+	limit, _ := echox.Pagination(c)
+	notes, err := h.service.ListSimilar(c.Request().Context(), id, userID, limit)
+	if err != nil {
+		return echo.NewHTTPError(http.StatusInternalServerError, "failed to find similar notes")
+	}
+
+	return c.JSON(http.StatusOK, notes)
+}
+
 func (h *Handler) Update(c *echo.Context) error {
 	userID, ok := auth.UserID(c)
 	if !ok {
@@ -190,9 +312,16 @@ func (h *Handler) Update(c *echo.Context) error {
 		Content:     req.Content,
 		ContentType: contentType,
 		IsPublic:    req.IsPublic,
+		Anchor:      req.Anchor,
 		Annotations: req.Annotations,
 		Tags:        req.Tags,
 	})
+	if errors.Is(err, ErrInvalidNote) {
+		return echo.NewHTTPError(http.StatusBadRequest, "invalid note")
+	}
+	if errors.Is(err, ErrInvalidAnchor) {
+		return echo.NewHTTPError(http.StatusBadRequest, err.Error())
+	}
 	if err != nil {
 		return echo.NewHTTPError(http.StatusInternalServerError, "failed to update note")
 	}
@@ -10,6 +10,7 @@ import (
 	"github.com/gofrs/uuid/v5"
 	"github.com/labstack/echo/v5"
 	"github.com/zizouhuweidi/maktaba/internal/auth"
+	"github.com/zizouhuweidi/maktaba/internal/echox"
 )
 
 func TestHandlerGetByIDRejectsPrivateNote(t *testing.T) {
@@ -58,6 +59,30 @@ func TestHandlerDeleteRejectsAnotherUsersNote(t *testing.T) {
 	}
 }
 
+func TestHandlerListSimilarRejectsAnotherUsersNote(t *testing.T) {
+	noteID := mustTestUUID(t)
+	repo := &fakeNotesRepository{note: &Note{
+		ID:          noteID,
+		UserID:      mustTestUUID(t),
+		Content:     "public but not yours",
+		ContentType: ContentTypeQuote,
+		IsPublic:    true,
+	}}
+	handler := NewHandler(NewService(repo, slog.Default()), slog.Default())
+
+	c := testContext(http.MethodGet, "/api/notes/"+noteID.String()+"/similar", "id", noteID.String())
+	auth.SetUserID(c, mustTestUUID(t))
+
+	err := handler.ListSimilar(c)
+
+	if code := statusCode(t, err); code != http.StatusForbidden {
+		t.Fatalf("expected status %d, got %d", http.StatusForbidden, code)
+	}
+	if repo.similarCalled {
+		t.Fatal("expected similarity search not to be called")
+	}
+}
+
 func testContext(method, target, paramName, paramValue string) *echo.Context {
 	e := echo.New()
 	req := httptest.NewRequest(method, target, nil)
@@ -80,8 +105,9 @@ func statusCode(t *testing.T, err error) int {
 }
 
 type fakeNotesRepository struct {
-	note    *Note
-	deleted bool
+	note          *Note
+	deleted       bool
+	similarCalled bool
 }
 
 func (r *fakeNotesRepository) Create(context.Context, *Note) (*Note, error) {
@@ -103,15 +129,15 @@ func (r *fakeNotesRepository) ListBySource(context.Context, uuid.UUID, int, int)
 	panic("not implemented")
 }
 
-func (r *fakeNotesRepository) ListPublicByUser(context.Context, uuid.UUID, int, int) ([]*Note, error) {
+func (r *fakeNotesRepository) ListByLocation(context.Context, ListFilter, int, int) ([]*Note, error) {
 	panic("not implemented")
 }
 
-func (r *fakeNotesRepository) ListPublicBySource(context.Context, uuid.UUID, int, int) ([]*Note, error) {
+func (r *fakeNotesRepository) List(context.Context, ListFilter, echox.PageRequest) ([]*Note, error) {
 	panic("not implemented")
 }
 
-func (r *fakeNotesRepository) ListPublic(context.Context, int, int) ([]*Note, error) {
+func (r *fakeNotesRepository) Count(context.Context, ListFilter) (int64, error) {
 	panic("not implemented")
 }
 
@@ -128,6 +154,11 @@ func (r *fakeNotesRepository) CountByUser(context.Context, uuid.UUID) (int64, er
 	panic("not implemented")
 }
 
+func (r *fakeNotesRepository) ListSimilar(context.Context, uuid.UUID, uuid.UUID, int) ([]*SimilarNote, error) {
+	r.similarCalled = true
+	return []*SimilarNote{}, nil
+}
+
 func mustTestUUID(t *testing.T) uuid.UUID {
 	t.Helper()
 	id, err := uuid.NewV7()
@@ -7,6 +7,15 @@ import (
 	"time"
 
 	"github.com/gofrs/uuid/v5"
+	"github.com/zizouhuweidi/maktaba/internal/echox"
+)
+
+// Outbox aggregate and event types published by this context.
+const (
+	AggregateNote    = "note"
+	EventNoteCreated = "note.created"
+	EventNoteUpdated = "note.updated"
+	EventNoteDeleted = "note.deleted"
 )
 
 // ContentType represents the type of note content
@@ -20,7 +29,8 @@ const (
 	ContentTypeReflection ContentType = "reflection"
 )
 
-// Note represents a user's thought or annotation on a source
+// Note represents a user's thought or annotation on a source. Anchor says
+// where in the source it points.
 type Note struct {
 	ID          uuid.UUID   `json:"id"`
 	UserID      uuid.UUID   `json:"user_id"`
@@ -28,24 +38,41 @@ type Note struct {
 	Content     string      `json:"content"`
 	ContentType ContentType `json:"content_type"`
 	IsPublic    bool        `json:"is_public"`
+	Anchor      *Anchor     `json:"anchor,omitempty"`
 	Annotations []string    `json:"annotations,omitempty"`
 	Tags        []string    `json:"tags,omitempty"`
 	CreatedAt   time.Time   `json:"created_at"`
 	UpdatedAt   time.Time   `json:"updated_at"`
 }
 
+// SimilarNote is a note ranked by semantic similarity to another note
+type SimilarNote struct {
+	*Note
+	Similarity float32 `json:"similarity"`
+}
+
 // Repository defines the interface for note data access
 type Repository interface {
 	Create(ctx context.Context, note *Note) (*Note, error)
 	GetByID(ctx context.Context, id uuid.UUID) (*Note, error)
 	ListByUser(ctx context.Context, userID uuid.UUID, limit, offset int) ([]*Note, error)
 	ListBySource(ctx context.Context, sourceID uuid.UUID, limit, offset int) ([]*Note, error)
-	ListPublicByUser(ctx context.Context, userID uuid.UUID, limit, offset int) ([]*Note, error)
-	ListPublicBySource(ctx context.Context, sourceID uuid.UUID, limit, offset int) ([]*Note, error)
-	ListPublic(ctx context.Context, limit, offset int) ([]*Note, error)
+	ListByLocation(ctx context.Context, filter ListFilter, limit, offset int) ([]*Note, error)
+	List(ctx context.Context, filter ListFilter, page echox.PageRequest) ([]*Note, error)
+	Count(ctx context.Context, filter ListFilter) (int64, error)
 	Update(ctx context.Context, note *Note) (*Note, error)
 	Delete(ctx context.Context, id uuid.UUID) error
 	CountByUser(ctx context.Context, userID uuid.UUID) (int64, error)
+	ListSimilar(ctx context.Context, noteID, userID uuid.UUID, limit int) ([]*SimilarNote, error)
+}
+
+// ListFilter narrows a note list; nil fields match every note
+type ListFilter struct {
+	UserID      *uuid.UUID
+	SourceID    *uuid.UUID
+	PublicOnly  bool
+	ContentType *ContentType
+	Tag         *string
 }
 
 // CreateNoteParams contains parameters for creating a note
@@ -55,15 +82,18 @@ type CreateNoteParams struct {
 	Content     string
 	ContentType ContentType
 	IsPublic    bool
+	Anchor      *Anchor
 	Annotations []string
 	Tags        []string
 }
 
-// UpdateNoteParams contains parameters for updating a note
+// UpdateNoteParams contains parameters for updating a note. An anchor with
+// nothing set removes the note's anchor.
 type UpdateNoteParams struct {
 	Content     *string
 	ContentType *ContentType
 	IsPublic    *bool
+	Anchor      *Anchor
 	Annotations []string
 	Tags        []string
 }
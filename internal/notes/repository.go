@@ -4,6 +4,7 @@ import (
 	"context"
 	"encoding/json"
 	"errors"
+	"strconv"
 
 	"github.com/gofrs/uuid/v5"
 	"github.com/jackc/pgx/v5"
@@ -11,14 +12,18 @@ import (
 	"github.com/jackc/pgx/v5/pgtype"
 	"github.com/zizouhuweidi/maktaba/internal/db"
 	"github.com/zizouhuweidi/maktaba/internal/db/dbgen"
+	"github.com/zizouhuweidi/maktaba/internal/echox"
+	"github.com/zizouhuweidi/maktaba/internal/outbox"
+	"github.com/zizouhuweidi/maktaba/internal/tags"
 )
 
 type postgresRepository struct {
+	db      *db.DB
 	queries *dbgen.Queries
 }
 
 func NewPostgresRepository(d *db.DB) Repository {
-	return &postgresRepository{queries: dbgen.New(d.Pool)}
+	return &postgresRepository{db: d, queries: dbgen.New(d.Pool)}
 }
 
 func (r *postgresRepository) Create(ctx context.Context, n *Note) (*Note, error) {
@@ -30,25 +35,44 @@ func (r *postgresRepository) Create(ctx context.Context, n *Note) (*Note, error)
 	if err != nil {
 		return nil, err
 	}
-	tags, err := json.Marshal(n.Tags)
+	anchor, err := marshalAnchor(n.Anchor)
 	if err != nil {
 		return nil, err
 	}
 
-	row, err := r.queries.CreateNote(ctx, dbgen.CreateNoteParams{
+	tx, err := r.db.Begin(ctx)
+	if err != nil {
+		return nil, err
+	}
+	defer tx.Rollback(ctx)
+	qtx := r.queries.WithTx(tx)
+
+	row, err := qtx.CreateNote(ctx, dbgen.CreateNoteParams{
 		ID:          db.PGUUID(id),
 		UserID:      db.PGUUID(n.UserID),
-		SourceID:    pgUUIDPtr(n.SourceID),
+		SourceID:    db.PGUUIDPtr(n.SourceID),
 		Content:     n.Content,
 		ContentType: string(n.ContentType),
 		IsPublic:    db.PGBool(n.IsPublic),
 		Annotations: annotations,
-		Tags:        tags,
+		Anchor:      anchor,
+		LocationKey: db.PGText(locationKey(n.Anchor)),
 	})
 	if err != nil {
 		return nil, mapCreateError(err)
 	}
-	return mapNote(row), nil
+	created := mapNote(row)
+	if err := saveTags(ctx, qtx, created, n.Tags); err != nil {
+		return nil, err
+	}
+	if err := outbox.Record(ctx, qtx, AggregateNote, created.ID, EventNoteCreated, created); err != nil {
+		return nil, err
+	}
+
+	if err := tx.Commit(ctx); err != nil {
+		return nil, err
+	}
+	return created, nil
 }
 
 func mapCreateError(err error) error {
@@ -70,7 +94,11 @@ func (r *postgresRepository) GetByID(ctx context.Context, id uuid.UUID) (*Note,
 	if err != nil {
 		return nil, err
 	}
-	return mapNote(row), nil
+	note := mapNote(row)
+	if err := attachTags(ctx, r.queries, note); err != nil {
+		return nil, err
+	}
+	return note, nil
 }
 
 func (r *postgresRepository) ListByUser(ctx context.Context, userID uuid.UUID, limit, offset int) ([]*Note, error) {
@@ -78,7 +106,11 @@ func (r *postgresRepository) ListByUser(ctx context.Context, userID uuid.UUID, l
 	if err != nil {
 		return nil, err
 	}
-	return mapNotes(rows), nil
+	notes := mapNotes(rows)
+	if err := attachTags(ctx, r.queries, notes...); err != nil {
+		return nil, err
+	}
+	return notes, nil
 }
 
 func (r *postgresRepository) ListBySource(ctx context.Context, sourceID uuid.UUID, limit, offset int) ([]*Note, error) {
@@ -86,31 +118,69 @@ func (r *postgresRepository) ListBySource(ctx context.Context, sourceID uuid.UUI
 	if err != nil {
 		return nil, err
 	}
-	return mapNotes(rows), nil
-}
-
-func (r *postgresRepository) ListPublicByUser(ctx context.Context, userID uuid.UUID, limit, offset int) ([]*Note, error) {
-	rows, err := r.queries.ListPublicNotesByUser(ctx, dbgen.ListPublicNotesByUserParams{UserID: db.PGUUID(userID), Limit: int32(limit), Offset: int32(offset)})
-	if err != nil {
+	notes := mapNotes(rows)
+	if err := attachTags(ctx, r.queries, notes...); err != nil {
 		return nil, err
 	}
-	return mapNotes(rows), nil
+	return notes, nil
 }
 
-func (r *postgresRepository) ListPublicBySource(ctx context.Context, sourceID uuid.UUID, limit, offset int) ([]*Note, error) {
-	rows, err := r.queries.ListPublicNotesBySource(ctx, dbgen.ListPublicNotesBySourceParams{SourceID: db.PGUUID(sourceID), Limit: int32(limit), Offset: int32(offset)})
+func (r *postgresRepository) ListByLocation(ctx context.Context, filter ListFilter, limit, offset int) ([]*Note, error) {
+	rows, err := r.queries.ListNotesByLocation(ctx, dbgen.ListNotesByLocationParams{
+		SourceID:    db.PGUUIDPtr(filter.SourceID),
+		UserID:      db.PGUUIDPtr(filter.UserID),
+		PublicOnly:  filter.PublicOnly,
+		ContentType: contentTypeText(filter.ContentType),
+		Limit:       int32(limit),
+		Offset:      int32(offset),
+	})
 	if err != nil {
 		return nil, err
 	}
-	return mapNotes(rows), nil
+	notes := mapNotes(rows)
+	if err := attachTags(ctx, r.queries, notes...); err != nil {
+		return nil, err
+	}
+	return notes, nil
 }
 
-func (r *postgresRepository) ListPublic(ctx context.Context, limit, offset int) ([]*Note, error) {
-	rows, err := r.queries.ListPublicNotes(ctx, dbgen.ListPublicNotesParams{Limit: int32(limit), Offset: int32(offset)})
+// List fetches one row past the page limit so the caller can tell whether
+// another page follows
+func (r *postgresRepository) List(ctx context.Context, filter ListFilter, page echox.PageRequest) ([]*Note, error) {
+	params := dbgen.ListNotesNewestParams{
+		UserID:      db.PGUUIDPtr(filter.UserID),
+		SourceID:    db.PGUUIDPtr(filter.SourceID),
+		PublicOnly:  filter.PublicOnly,
+		ContentType: contentTypeText(filter.ContentType),
+		Tag:         db.PGText(filter.Tag),
+		Limit:       int32(page.Limit + 1),
+	}
+	params.AfterCreatedAt, params.AfterID = db.Keyset(page.After.CreatedAt, page.After.ID, page.Oldest)
+	var rows []dbgen.Note
+	var err error
+	if page.Oldest {
+		rows, err = r.queries.ListNotesOldest(ctx, dbgen.ListNotesOldestParams(params))
+	} else {
+		rows, err = r.queries.ListNotesNewest(ctx, params)
+	}
 	if err != nil {
 		return nil, err
 	}
-	return mapNotes(rows), nil
+	notes := mapNotes(rows)
+	if err := attachTags(ctx, r.queries, notes...); err != nil {
+		return nil, err
+	}
+	return notes, nil
+}
+
+func (r *postgresRepository) Count(ctx context.Context, filter ListFilter) (int64, error) {
+	return r.queries.CountNotes(ctx, dbgen.CountNotesParams{
+		UserID:      db.PGUUIDPtr(filter.UserID),
+		SourceID:    db.PGUUIDPtr(filter.SourceID),
+		PublicOnly:  filter.PublicOnly,
+		ContentType: contentTypeText(filter.ContentType),
+		Tag:         db.PGText(filter.Tag),
+	})
 }
 
 func (r *postgresRepository) Update(ctx context.Context, n *Note) (*Note, error) {
@@ -118,19 +188,27 @@ func (r *postgresRepository) Update(ctx context.Context, n *Note) (*Note, error)
 	if err != nil {
 		return nil, err
 	}
-	tags, err := json.Marshal(n.Tags)
+	anchor, err := marshalAnchor(n.Anchor)
+	if err != nil {
+		return nil, err
+	}
+
+	tx, err := r.db.Begin(ctx)
 	if err != nil {
 		return nil, err
 	}
+	defer tx.Rollback(ctx)
+	qtx := r.queries.WithTx(tx)
 
-	row, err := r.queries.UpdateNote(ctx, dbgen.UpdateNoteParams{
+	row, err := qtx.UpdateNote(ctx, dbgen.UpdateNoteParams{
 		ID:          db.PGUUID(n.ID),
-		SourceID:    pgUUIDPtr(n.SourceID),
+		SourceID:    db.PGUUIDPtr(n.SourceID),
 		Content:     n.Content,
 		ContentType: string(n.ContentType),
 		IsPublic:    db.PGBool(n.IsPublic),
 		Annotations: annotations,
-		Tags:        tags,
+		Anchor:      anchor,
+		LocationKey: db.PGText(locationKey(n.Anchor)),
 	})
 	if errors.Is(err, pgx.ErrNoRows) {
 		return nil, nil
@@ -138,17 +216,154 @@ func (r *postgresRepository) Update(ctx context.Context, n *Note) (*Note, error)
 	if err != nil {
 		return nil, err
 	}
-	return mapNote(row), nil
+	updated := mapNote(row)
+	if err := saveTags(ctx, qtx, updated, n.Tags); err != nil {
+		return nil, err
+	}
+	if err := outbox.Record(ctx, qtx, AggregateNote, updated.ID, EventNoteUpdated, updated); err != nil {
+		return nil, err
+	}
+
+	if err := tx.Commit(ctx); err != nil {
+		return nil, err
+	}
+	return updated, nil
 }
 
 func (r *postgresRepository) Delete(ctx context.Context, id uuid.UUID) error {
-	return r.queries.DeleteNote(ctx, db.PGUUID(id))
+	tx, err := r.db.Begin(ctx)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback(ctx)
+	qtx := r.queries.WithTx(tx)
+
+	deleted, err := qtx.DeleteNote(ctx, db.PGUUID(id))
+	if err != nil {
+		return err
+	}
+	if deleted > 0 {
+		if err := outbox.Record(ctx, qtx, AggregateNote, id, EventNoteDeleted, outbox.DeletedPayload{ID: id}); err != nil {
+			return err
+		}
+	}
+	return tx.Commit(ctx)
 }
 
 func (r *postgresRepository) CountByUser(ctx context.Context, userID uuid.UUID) (int64, error) {
 	return r.queries.CountNotesByUser(ctx, db.PGUUID(userID))
 }
 
+// similarCandidates is how many nearest notes of every user the vector
+// index returns for each similar note asked for, since only the user's own
+// are kept. The search breadth stays between pgvector's default
+// hnsw.ef_search and the most it allows.
+const (
+	similarCandidates = 20
+	minEfSearch       = 40
+	maxEfSearch       = 1000
+)
+
+// ListSimilar reads the note's embedding first so the nearest-neighbour
+// search orders by distance to a constant vector, which the HNSW index can
+// serve, widening the search enough to leave limit notes after the
+// user filter.
+func (r *postgresRepository) ListSimilar(ctx context.Context, noteID, userID uuid.UUID, limit int) ([]*SimilarNote, error) {
+	tx, err := r.db.Begin(ctx)
+	if err != nil {
+		return nil, err
+	}
+	defer tx.Rollback(ctx)
+	qtx := r.queries.WithTx(tx)
+
+	target, err := qtx.GetNoteEmbedding(ctx, db.PGUUID(noteID))
+	if errors.Is(err, pgx.ErrNoRows) {
+		return []*SimilarNote{}, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	candidates := min(max(limit*similarCandidates, minEfSearch), maxEfSearch)
+	if err := qtx.SetHNSWEfSearch(ctx, strconv.Itoa(candidates)); err != nil {
+		return nil, err
+	}
+	rows, err := qtx.ListSimilarNotes(ctx, dbgen.ListSimilarNotesParams{
+		Embedding:  target.Embedding,
+		Model:      target.Model,
+		Candidates: int32(candidates),
+		NoteID:     db.PGUUID(noteID),
+		UserID:     db.PGUUID(userID),
+		Limit:      int32(limit),
+	})
+	if err != nil {
+		return nil, err
+	}
+	if err := tx.Commit(ctx); err != nil {
+		return nil, err
+	}
+	similar := make([]*SimilarNote, 0, len(rows))
+	notes := make([]*Note, 0, len(rows))
+	for _, row := range rows {
+		note := mapNote(dbgen.Note{
+			ID:          row.ID,
+			UserID:      row.UserID,
+			SourceID:    row.SourceID,
+			Content:     row.Content,
+			ContentType: row.ContentType,
+			IsPublic:    row.IsPublic,
+			Annotations: row.Annotations,
+			CreatedAt:   row.CreatedAt,
+			UpdatedAt:   row.UpdatedAt,
+			Anchor:      row.Anchor,
+			LocationKey: row.LocationKey,
+		})
+		similar = append(similar, &SimilarNote{Note: note, Similarity: row.Similarity})
+		notes = append(notes, note)
+	}
+	if err := attachTags(ctx, r.queries, notes...); err != nil {
+		return nil, err
+	}
+	return similar, nil
+}
+
+// saveTags replaces the tags of note with names, creating any tag that does
+// not exist yet, and reads back the names they are stored under
+func saveTags(ctx context.Context, qtx *dbgen.Queries, note *Note, names []string) error {
+	if err := qtx.DeleteNoteTags(ctx, db.PGUUID(note.ID)); err != nil {
+		return err
+	}
+	ids, err := tags.Upsert(ctx, qtx, names)
+	if err != nil {
+		return err
+	}
+	if len(ids) > 0 {
+		if err := qtx.InsertNoteTags(ctx, dbgen.InsertNoteTagsParams{NoteID: db.PGUUID(note.ID), TagIds: ids}); err != nil {
+			return err
+		}
+	}
+	return attachTags(ctx, qtx, note)
+}
+
+// attachTags loads the tags of a batch of notes in one query
+func attachTags(ctx context.Context, q *dbgen.Queries, notes ...*Note) error {
+	if len(notes) == 0 {
+		return nil
+	}
+	ids := make([]pgtype.UUID, 0, len(notes))
+	for _, note := range notes {
+		ids = append(ids, db.PGUUID(note.ID))
+	}
+	rows, err := q.ListTagsByNotes(ctx, ids)
+	if err != nil {
+		return err
+	}
+	grouped := tags.Group(rows, func(row dbgen.ListTagsByNotesRow) (pgtype.UUID, string) { return row.NoteID, row.Name })
+	for _, note := range notes {
+		note.Tags = grouped[note.ID]
+	}
+	return nil
+}
+
 func mapNotes(rows []dbgen.Note) []*Note {
 	notes := make([]*Note, 0, len(rows))
 	for _, row := range rows {
@@ -162,36 +377,37 @@ func mapNote(row dbgen.Note) *Note {
 	if len(row.Annotations) > 0 {
 		_ = json.Unmarshal(row.Annotations, &annotations)
 	}
-	var tags []string
-	if len(row.Tags) > 0 {
-		_ = json.Unmarshal(row.Tags, &tags)
+	var anchor *Anchor
+	if len(row.Anchor) > 0 {
+		anchor = &Anchor{}
+		_ = json.Unmarshal(row.Anchor, anchor)
 	}
 
 	return &Note{
 		ID:          db.UUID(row.ID),
 		UserID:      db.UUID(row.UserID),
-		SourceID:    uuidPtr(row.SourceID),
+		SourceID:    db.UUIDPtr(row.SourceID),
 		Content:     row.Content,
 		ContentType: ContentType(row.ContentType),
 		IsPublic:    db.Bool(row.IsPublic),
+		Anchor:      anchor,
 		Annotations: annotations,
-		Tags:        tags,
 		CreatedAt:   db.Time(row.CreatedAt),
 		UpdatedAt:   db.Time(row.UpdatedAt),
 	}
 }
 
-func uuidPtr(value pgtype.UUID) *uuid.UUID {
-	if !value.Valid {
-		return nil
+// marshalAnchor stores a missing anchor as NULL
+func marshalAnchor(anchor *Anchor) ([]byte, error) {
+	if anchor == nil {
+		return nil, nil
 	}
-	id := db.UUID(value)
-	return &id
+	return json.Marshal(anchor)
 }
 
-func pgUUIDPtr(value *uuid.UUID) pgtype.UUID {
-	if value == nil {
-		return pgtype.UUID{}
+func contentTypeText(contentType *ContentType) pgtype.Text {
+	if contentType == nil {
+		return pgtype.Text{}
 	}
-	return db.PGUUID(*value)
+	return db.PGTextString(string(*contentType))
 }
@@ -3,15 +3,19 @@ package notes
 import (
 	"context"
 	"errors"
+	"fmt"
 	"log/slog"
 
 	"github.com/gofrs/uuid/v5"
+	"github.com/zizouhuweidi/maktaba/internal/echox"
+	"github.com/zizouhuweidi/maktaba/internal/tags"
 )
 
 var (
 	ErrNoteNotFound   = errors.New("note not found")
 	ErrInvalidNote    = errors.New("invalid note data")
 	ErrSourceNotFound = errors.New("source not found")
+	ErrInvalidAnchor  = errors.New("invalid note anchor")
 )
 
 // Service provides business logic for notes
@@ -33,6 +37,14 @@ func (s *Service) Create(ctx context.Context, params CreateNoteParams) (*Note, e
 	if params.Content == "" {
 		return nil, ErrInvalidNote
 	}
+	cleaned, err := tags.Clean(params.Tags)
+	if err != nil {
+		return nil, ErrInvalidNote
+	}
+	anchor, err := anchorFor(params.SourceID, params.Anchor)
+	if err != nil {
+		return nil, err
+	}
 
 	note := &Note{
 		UserID:      params.UserID,
@@ -40,8 +52,9 @@ func (s *Service) Create(ctx context.Context, params CreateNoteParams) (*Note, e
 		Content:     params.Content,
 		ContentType: params.ContentType,
 		IsPublic:    params.IsPublic,
+		Anchor:      anchor,
 		Annotations: params.Annotations,
-		Tags:        params.Tags,
+		Tags:        cleaned,
 	}
 
 	created, err := s.repo.Create(ctx, note)
@@ -93,7 +106,13 @@ func (s *Service) ListBySource(ctx context.Context, sourceID uuid.UUID, limit, o
 	return s.repo.ListBySource(ctx, sourceID, limit, offset)
 }
 
-func (s *Service) ListPublicByUser(ctx context.Context, userID uuid.UUID, limit, offset int) ([]*Note, error) {
+// ListByLocation returns the notes on one source in reading order: by page,
+// then time, then position in the text. Notes without a location follow,
+// oldest first.
+func (s *Service) ListByLocation(ctx context.Context, filter ListFilter, limit, offset int) ([]*Note, error) {
+	if filter.SourceID == nil {
+		return nil, ErrInvalidNote
+	}
 	if limit <= 0 {
 		limit = 100
 	}
@@ -101,30 +120,42 @@ func (s *Service) ListPublicByUser(ctx context.Context, userID uuid.UUID, limit,
 		offset = 0
 	}
 
-	return s.repo.ListPublicByUser(ctx, userID, limit, offset)
+	return s.repo.ListByLocation(ctx, filter, limit, offset)
 }
 
-func (s *Service) ListPublicBySource(ctx context.Context, sourceID uuid.UUID, limit, offset int) ([]*Note, error) {
-	if limit <= 0 {
-		limit = 100
-	}
-	if offset < 0 {
-		offset = 0
+// List returns one page of the notes matching the filter along with how many
+// match in total
+func (s *Service) List(ctx context.Context, filter ListFilter, page echox.PageRequest) (*echox.Page[*Note], error) {
+	if filter.Tag != nil {
+		slug := tags.Slug(*filter.Tag)
+		filter.Tag = &slug
 	}
+	page = page.Normalize()
 
-	return s.repo.ListPublicBySource(ctx, sourceID, limit, offset)
+	notes, err := s.repo.List(ctx, filter, page)
+	if err != nil {
+		return nil, err
+	}
+	total, err := page.Total(func() (int64, error) { return s.repo.Count(ctx, filter) })
+	if err != nil {
+		return nil, err
+	}
+	return echox.NewPage(notes, page, total, func(note *Note) echox.Cursor {
+		return echox.Cursor{CreatedAt: note.CreatedAt, ID: note.ID}
+	}), nil
 }
 
-// ListPublic retrieves public notes
-func (s *Service) ListPublic(ctx context.Context, limit, offset int) ([]*Note, error) {
+// ListSimilar returns the user's notes closest in meaning to the given note.
+// Notes that have not been embedded yet have no neighbours.
+func (s *Service) ListSimilar(ctx context.Context, noteID, userID uuid.UUID, limit int) ([]*SimilarNote, error) {
 	if limit <= 0 {
-		limit = 100
+		limit = 10
 	}
-	if offset < 0 {
-		offset = 0
+	if limit > 50 {
+		limit = 50
 	}
 
-	return s.repo.ListPublic(ctx, limit, offset)
+	return s.repo.ListSimilar(ctx, noteID, userID, limit)
 }
 
 // Update updates an existing note
@@ -147,11 +178,22 @@ func (s *Service) Update(ctx context.Context, id uuid.UUID, params UpdateNotePar
 	if params.IsPublic != nil {
 		existing.IsPublic = *params.IsPublic
 	}
+	if params.Anchor != nil {
+		anchor, err := anchorFor(existing.SourceID, params.Anchor)
+		if err != nil {
+			return nil, err
+		}
+		existing.Anchor = anchor
+	}
 	if params.Annotations != nil {
 		existing.Annotations = params.Annotations
 	}
 	if params.Tags != nil {
-		existing.Tags = params.Tags
+		cleaned, err := tags.Clean(params.Tags)
+		if err != nil {
+			return nil, ErrInvalidNote
+		}
+		existing.Tags = cleaned
 	}
 
 	updated, err := s.repo.Update(ctx, existing)
@@ -174,3 +216,16 @@ func (s *Service) Delete(ctx context.Context, id uuid.UUID) error {
 	s.logger.Info("note deleted", "id", id)
 	return nil
 }
+
+// anchorFor checks an anchor and that the note it places has a source to
+// place it in
+func anchorFor(sourceID *uuid.UUID, anchor *Anchor) (*Anchor, error) {
+	anchor, err := normalizeAnchor(anchor)
+	if err != nil {
+		return nil, err
+	}
+	if anchor != nil && sourceID == nil {
+		return nil, fmt.Errorf("%w: a note without a source cannot be anchored", ErrInvalidAnchor)
+	}
+	return anchor, nil
+}
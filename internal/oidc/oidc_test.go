@@ -0,0 +1,254 @@
+package oidc
+
+import (
+	"context"
+	"crypto/rand"
+	"crypto/rsa"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"math/big"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/golang-jwt/jwt/v5"
+)
+
+// fakeIssuer is an in-process OpenID provider. authorize stands in for the
+// user signing in at the provider and returns the code the browser would
+// bring back.
+type fakeIssuer struct {
+	t        *testing.T
+	server   *httptest.Server
+	clientID string
+	secret   string
+
+	mu     sync.Mutex
+	key    *rsa.PrivateKey
+	keyID  string
+	grants map[string]fakeGrant
+	// claims adjusts the next ID tokens before they are signed.
+	claims func(jwt.MapClaims)
+}
+
+type fakeGrant struct {
+	challenge   string
+	nonce       string
+	redirectURI string
+}
+
+func newFakeIssuer(t *testing.T) *fakeIssuer {
+	t.Helper()
+	issuer := &fakeIssuer{t: t, clientID: "maktaba", secret: "s3cret", grants: map[string]fakeGrant{}}
+	issuer.rotateKey()
+
+	mux := http.NewServeMux()
+	mux.HandleFunc("GET /.well-known/openid-configuration", func(w http.ResponseWriter, r *http.Request) {
+		writeJSON(w, http.StatusOK, map[string]string{
+			"issuer":                 issuer.server.URL,
+			"authorization_endpoint": issuer.server.URL + "/authorize",
+			"token_endpoint":         issuer.server.URL + "/token",
+			"jwks_uri":               issuer.server.URL + "/jwks",
+		})
+	})
+	mux.HandleFunc("GET /jwks", func(w http.ResponseWriter, r *http.Request) {
+		issuer.mu.Lock()
+		defer issuer.mu.Unlock()
+		public := issuer.key.PublicKey
+		writeJSON(w, http.StatusOK, map[string]any{"keys": []map[string]string{{
+			"kty": "RSA",
+			"kid": issuer.keyID,
+			"use": "sig",
+			"alg": "RS256",
+			"n":   base64.RawURLEncoding.EncodeToString(public.N.Bytes()),
+			"e":   base64.RawURLEncoding.EncodeToString(big.NewInt(int64(public.E)).Bytes()),
+		}}})
+	})
+	mux.HandleFunc("POST /token", issuer.token)
+	issuer.server = httptest.NewServer(mux)
+	t.Cleanup(issuer.server.Close)
+	return issuer
+}
+
+func (f *fakeIssuer) provider() *Provider {
+	return NewProvider(Config{
+		Issuer:       f.server.URL,
+		ClientID:     f.clientID,
+		ClientSecret: f.secret,
+		RedirectURL:  "https://api.example/auth/oidc/fake/callback",
+	}, f.server.Client())
+}
+
+func (f *fakeIssuer) rotateKey() {
+	key, err := rsa.GenerateKey(rand.Reader, 2048)
+	if err != nil {
+		f.t.Fatalf("generate key: %v", err)
+	}
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	f.key = key
+	f.keyID = base64.RawURLEncoding.EncodeToString(key.N.Bytes()[:8])
+}
+
+func (f *fakeIssuer) authorize(authURL string) string {
+	f.t.Helper()
+	parsed, err := url.Parse(authURL)
+	if err != nil {
+		f.t.Fatalf("parse auth URL: %v", err)
+	}
+	query := parsed.Query()
+	if parsed.Path != "/authorize" || query.Get("client_id") != f.clientID || query.Get("response_type") != "code" ||
+		query.Get("code_challenge_method") != "S256" || query.Get("scope") != "openid email profile" {
+		f.t.Fatalf("auth URL = %s", authURL)
+	}
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	code := "code-" + query.Get("state")
+	f.grants[code] = fakeGrant{challenge: query.Get("code_challenge"), nonce: query.Get("nonce"), redirectURI: query.Get("redirect_uri")}
+	return code
+}
+
+func (f *fakeIssuer) token(w http.ResponseWriter, r *http.Request) {
+	clientID, secret, ok := r.BasicAuth()
+	if !ok || clientID != f.clientID || secret != f.secret {
+		writeJSON(w, http.StatusUnauthorized, map[string]string{"error": "invalid_client"})
+		return
+	}
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	grant, ok := f.grants[r.PostFormValue("code")]
+	delete(f.grants, r.PostFormValue("code"))
+	if !ok || r.PostFormValue("grant_type") != "authorization_code" || r.PostFormValue("redirect_uri") != grant.redirectURI ||
+		CodeChallenge(r.PostFormValue("code_verifier")) != grant.challenge {
+		writeJSON(w, http.StatusBadRequest, map[string]string{"error": "invalid_grant"})
+		return
+	}
+
+	now := time.Now()
+	claims := jwt.MapClaims{
+		"iss":                f.server.URL,
+		"sub":                "user-1",
+		"aud":                f.clientID,
+		"exp":                now.Add(5 * time.Minute).Unix(),
+		"iat":                now.Unix(),
+		"nonce":              grant.nonce,
+		"email":              "reader@example.com",
+		"email_verified":     true,
+		"name":               "Ibn Battuta",
+		"preferred_username": "battuta",
+	}
+	if f.claims != nil {
+		f.claims(claims)
+	}
+	token := jwt.NewWithClaims(jwt.SigningMethodRS256, claims)
+	token.Header["kid"] = f.keyID
+	signed, err := token.SignedString(f.key)
+	if err != nil {
+		f.t.Errorf("sign ID token: %v", err)
+	}
+	writeJSON(w, http.StatusOK, map[string]string{"access_token": "at", "token_type": "Bearer", "id_token": signed})
+}
+
+func writeJSON(w http.ResponseWriter, status int, v any) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	_ = json.NewEncoder(w).Encode(v)
+}
+
+// signIn runs the whole flow and returns what Exchange does.
+func signIn(t *testing.T, issuer *fakeIssuer, provider *Provider, verifierForExchange func(string) string) (*Identity, error) {
+	t.Helper()
+	ctx := context.Background()
+	authURL, err := provider.AuthCodeURL(ctx, "state-1", "nonce-1", "verifier-0123456789-0123456789-0123456789")
+	if err != nil {
+		t.Fatalf("AuthCodeURL() error = %v", err)
+	}
+	code := issuer.authorize(authURL)
+	verifier := "verifier-0123456789-0123456789-0123456789"
+	if verifierForExchange != nil {
+		verifier = verifierForExchange(verifier)
+	}
+	return provider.Exchange(ctx, code, verifier, "nonce-1")
+}
+
+func TestAuthorizationCodeFlow(t *testing.T) {
+	issuer := newFakeIssuer(t)
+
+	identity, err := signIn(t, issuer, issuer.provider(), nil)
+	if err != nil {
+		t.Fatalf("Exchange() error = %v", err)
+	}
+	want := Identity{Subject: "user-1", Email: "reader@example.com", EmailVerified: true, Name: "Ibn Battuta", PreferredUsername: "battuta"}
+	if *identity != want {
+		t.Fatalf("identity = %+v, want %+v", identity, want)
+	}
+}
+
+func TestExchangeNeedsCodeVerifier(t *testing.T) {
+	issuer := newFakeIssuer(t)
+
+	_, err := signIn(t, issuer, issuer.provider(), func(string) string { return "someone-elses-verifier-0123456789-0123456789" })
+	if err == nil {
+		t.Fatal("expected the token request to fail")
+	}
+}
+
+func TestExchangeRejectsBadIDTokens(t *testing.T) {
+	for name, adjust := range map[string]func(jwt.MapClaims){
+		"nonce":    func(c jwt.MapClaims) { c["nonce"] = "replayed" },
+		"audience": func(c jwt.MapClaims) { c["aud"] = "another-client" },
+		"issuer":   func(c jwt.MapClaims) { c["iss"] = "https://evil.example" },
+		"expired":  func(c jwt.MapClaims) { c["exp"] = time.Now().Add(-time.Hour).Unix() },
+		"subject":  func(c jwt.MapClaims) { delete(c, "sub") },
+	} {
+		t.Run(name, func(t *testing.T) {
+			issuer := newFakeIssuer(t)
+			issuer.claims = adjust
+
+			if _, err := signIn(t, issuer, issuer.provider(), nil); !errors.Is(err, ErrInvalidIDToken) {
+				t.Fatalf("error = %v, want %v", err, ErrInvalidIDToken)
+			}
+		})
+	}
+}
+
+func TestExchangeFollowsKeyRotation(t *testing.T) {
+	issuer := newFakeIssuer(t)
+	provider := issuer.provider()
+	clock := time.Now()
+	provider.now = func() time.Time { return clock }
+
+	if _, err := signIn(t, issuer, provider, nil); err != nil {
+		t.Fatalf("Exchange() error = %v", err)
+	}
+
+	issuer.rotateKey()
+	if _, err := signIn(t, issuer, provider, nil); !errors.Is(err, ErrInvalidIDToken) {
+		t.Fatalf("error right after rotation = %v, want %v", err, ErrInvalidIDToken)
+	}
+	clock = clock.Add(keyRefreshInterval)
+	if _, err := signIn(t, issuer, provider, nil); err != nil {
+		t.Fatalf("Exchange() after the refresh interval error = %v", err)
+	}
+}
+
+func TestDiscoveryChecksIssuer(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		writeJSON(w, http.StatusOK, map[string]string{
+			"issuer":                 "https://accounts.example",
+			"authorization_endpoint": "https://accounts.example/authorize",
+			"token_endpoint":         "https://accounts.example/token",
+			"jwks_uri":               "https://accounts.example/jwks",
+		})
+	}))
+	t.Cleanup(server.Close)
+	provider := NewProvider(Config{Issuer: server.URL, ClientID: "maktaba"}, server.Client())
+
+	if _, err := provider.AuthCodeURL(context.Background(), "s", "n", "v"); err == nil {
+		t.Fatal("expected discovery to fail when the metadata names another issuer")
+	}
+}
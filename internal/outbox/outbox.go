@@ -0,0 +1,69 @@
+// Package outbox implements the transactional outbox used to publish domain
+// events. Repositories append events in the same transaction as the change
+// they describe, and a Relay later drains them to the configured sinks.
+package outbox
+
+import (
+	"context"
+	"encoding/json"
+	"time"
+
+	"github.com/gofrs/uuid/v5"
+	"github.com/zizouhuweidi/maktaba/internal/db"
+	"github.com/zizouhuweidi/maktaba/internal/db/dbgen"
+)
+
+// Event is a domain event stored in the outbox table.
+type Event struct {
+	ID            uuid.UUID       `json:"id"`
+	AggregateType string          `json:"aggregate_type"`
+	AggregateID   uuid.UUID       `json:"aggregate_id"`
+	EventType     string          `json:"event_type"`
+	Payload       json.RawMessage `json:"payload"`
+	CreatedAt     time.Time       `json:"created_at"`
+	// Attempts counts the earlier deliveries of this event that failed.
+	Attempts int `json:"-"`
+}
+
+// Sink receives events drained from the outbox. Delivery is at least once,
+// so sinks must tolerate duplicates.
+type Sink interface {
+	Publish(ctx context.Context, event Event) error
+}
+
+// DeletedPayload is the payload recorded for delete events.
+type DeletedPayload struct {
+	ID uuid.UUID `json:"id"`
+}
+
+// Record appends an event to the outbox. q must be bound to the transaction
+// that performs the change being announced so both commit or roll back together.
+func Record(ctx context.Context, q *dbgen.Queries, aggregateType string, aggregateID uuid.UUID, eventType string, payload any) error {
+	id, err := uuid.NewV7()
+	if err != nil {
+		return err
+	}
+	encoded, err := json.Marshal(payload)
+	if err != nil {
+		return err
+	}
+	return q.InsertOutboxEvent(ctx, dbgen.InsertOutboxEventParams{
+		ID:            db.PGUUID(id),
+		AggregateType: aggregateType,
+		AggregateID:   db.PGUUID(aggregateID),
+		EventType:     eventType,
+		Payload:       encoded,
+	})
+}
+
+func mapEvent(row dbgen.ClaimOutboxEventsRow) Event {
+	return Event{
+		ID:            db.UUID(row.ID),
+		AggregateType: row.AggregateType,
+		AggregateID:   db.UUID(row.AggregateID),
+		EventType:     row.EventType,
+		Payload:       json.RawMessage(row.Payload),
+		CreatedAt:     db.Time(row.CreatedAt),
+		Attempts:      int(row.Attempts),
+	}
+}
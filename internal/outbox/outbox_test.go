@@ -0,0 +1,224 @@
+package outbox
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"io"
+	"log/slog"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/gofrs/uuid/v5"
+)
+
+type fakeStore struct {
+	pending   []Event
+	published []Event
+	dead      []Event
+	retryAt   map[uuid.UUID]time.Time
+}
+
+func (s *fakeStore) ProcessBatch(ctx context.Context, limit int, handle func(context.Context, Event) Delivery) (int, error) {
+	batch := s.pending
+	if len(batch) > limit {
+		batch = batch[:limit]
+	}
+	var retained []Event
+	published := 0
+	for _, event := range batch {
+		delivery := handle(ctx, event)
+		switch {
+		case delivery.Err == nil:
+			s.published = append(s.published, event)
+			published++
+		case delivery.Dead:
+			s.dead = append(s.dead, event)
+		default:
+			if s.retryAt == nil {
+				s.retryAt = make(map[uuid.UUID]time.Time)
+			}
+			s.retryAt[event.ID] = delivery.RetryAt
+			event.Attempts++
+			retained = append(retained, event)
+		}
+	}
+	s.pending = append(retained, s.pending[len(batch):]...)
+	return published, nil
+}
+
+type recordingSink struct {
+	events []Event
+	failOn string
+}
+
+func (s *recordingSink) Publish(ctx context.Context, event Event) error {
+	if event.EventType == s.failOn {
+		return errors.New("sink failed")
+	}
+	s.events = append(s.events, event)
+	return nil
+}
+
+func testEvent(eventType string) Event {
+	return Event{
+		ID:            uuid.Must(uuid.NewV7()),
+		AggregateType: "source",
+		AggregateID:   uuid.Must(uuid.NewV7()),
+		EventType:     eventType,
+		Payload:       json.RawMessage(`{"title":"Muqaddimah"}`),
+		CreatedAt:     time.Now(),
+	}
+}
+
+func testLogger() *slog.Logger {
+	return slog.New(slog.NewTextHandler(io.Discard, nil))
+}
+
+func TestRelayProcessBatchPublishesInOrder(t *testing.T) {
+	store := &fakeStore{pending: []Event{testEvent("source.created"), testEvent("source.updated"), testEvent("source.deleted")}}
+	sink := &recordingSink{}
+	relay := NewRelay(store, sink, 2, time.Second, 3, testLogger())
+
+	published, err := relay.ProcessBatch(context.Background())
+	if err != nil {
+		t.Fatalf("ProcessBatch() error = %v", err)
+	}
+	if published != 2 {
+		t.Fatalf("published = %d, want 2", published)
+	}
+	if len(sink.events) != 2 || sink.events[0].EventType != "source.created" || sink.events[1].EventType != "source.updated" {
+		t.Fatalf("sink events = %+v", sink.events)
+	}
+	if len(store.pending) != 1 {
+		t.Fatalf("pending = %d, want 1", len(store.pending))
+	}
+}
+
+func TestRelayProcessBatchRetriesPoisonEventWithoutBlocking(t *testing.T) {
+	store := &fakeStore{pending: []Event{testEvent("note.created"), testEvent("note.updated"), testEvent("note.deleted")}}
+	sink := &recordingSink{failOn: "note.updated"}
+	relay := NewRelay(store, sink, 10, time.Second, 3, testLogger())
+	now := time.Date(2026, 1, 2, 3, 4, 5, 0, time.UTC)
+	relay.now = func() time.Time { return now }
+
+	published, err := relay.ProcessBatch(context.Background())
+	if err != nil {
+		t.Fatalf("ProcessBatch() error = %v", err)
+	}
+	if published != 2 {
+		t.Fatalf("published = %d, want 2", published)
+	}
+	if len(sink.events) != 2 || sink.events[0].EventType != "note.created" || sink.events[1].EventType != "note.deleted" {
+		t.Fatalf("sink events = %+v, want the events around the failure", sink.events)
+	}
+	if len(store.pending) != 1 || store.pending[0].EventType != "note.updated" {
+		t.Fatalf("pending = %+v, want only the failed event", store.pending)
+	}
+	if got, want := store.retryAt[store.pending[0].ID], now.Add(retryBaseDelay); !got.Equal(want) {
+		t.Fatalf("retry at = %v, want %v", got, want)
+	}
+
+	for range 2 {
+		if _, err := relay.ProcessBatch(context.Background()); err != nil {
+			t.Fatalf("ProcessBatch() error = %v", err)
+		}
+	}
+	if len(store.pending) != 0 || len(store.dead) != 1 || store.dead[0].EventType != "note.updated" {
+		t.Fatalf("pending = %+v, dead = %+v, want the event dead-lettered after 3 attempts", store.pending, store.dead)
+	}
+}
+
+func TestRetryDelayBacksOff(t *testing.T) {
+	tests := []struct {
+		attempts int
+		want     time.Duration
+	}{
+		{attempts: 1, want: retryBaseDelay},
+		{attempts: 2, want: 2 * retryBaseDelay},
+		{attempts: 4, want: 8 * retryBaseDelay},
+		{attempts: 50, want: maxRetryDelay},
+	}
+	for _, tt := range tests {
+		if got := retryDelay(tt.attempts); got != tt.want {
+			t.Fatalf("retryDelay(%d) = %v, want %v", tt.attempts, got, tt.want)
+		}
+	}
+}
+
+func TestWebhookSinkSignsBody(t *testing.T) {
+	secret := "shh"
+	var received Event
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		body, err := io.ReadAll(r.Body)
+		if err != nil {
+			t.Errorf("read body: %v", err)
+			return
+		}
+		if got, want := r.Header.Get(SignatureHeader), Sign([]byte(secret), body); got != want {
+			t.Errorf("signature = %q, want %q", got, want)
+		}
+		if err := json.Unmarshal(body, &received); err != nil {
+			t.Errorf("decode body: %v", err)
+		}
+		w.WriteHeader(http.StatusAccepted)
+	}))
+	defer server.Close()
+
+	event := testEvent("review.created")
+	if err := NewWebhookSink(server.URL, secret, time.Second).Publish(context.Background(), event); err != nil {
+		t.Fatalf("Publish() error = %v", err)
+	}
+	if received.ID != event.ID || received.EventType != event.EventType {
+		t.Fatalf("received = %+v, want %+v", received, event)
+	}
+}
+
+func TestWebhookSinkRejectsErrorStatus(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusInternalServerError)
+	}))
+	defer server.Close()
+
+	if err := NewWebhookSink(server.URL, "", time.Second).Publish(context.Background(), testEvent("review.created")); err == nil {
+		t.Fatal("Publish() error = nil, want status error")
+	}
+}
+
+func TestSubscribersDispatchByEventType(t *testing.T) {
+	subscribers := NewSubscribers()
+	var created, deleted int
+	subscribers.Subscribe("collection.created", func(ctx context.Context, event Event) error {
+		created++
+		return nil
+	})
+	subscribers.Subscribe("collection.deleted", func(ctx context.Context, event Event) error {
+		deleted++
+		return nil
+	})
+
+	for _, eventType := range []string{"collection.created", "collection.created", "collection.updated"} {
+		if err := subscribers.Publish(context.Background(), testEvent(eventType)); err != nil {
+			t.Fatalf("Publish() error = %v", err)
+		}
+	}
+	if created != 2 || deleted != 0 {
+		t.Fatalf("created = %d, deleted = %d", created, deleted)
+	}
+}
+
+func TestMultiSinkPublishesToAll(t *testing.T) {
+	first := &recordingSink{}
+	second := &recordingSink{failOn: "source.created"}
+	third := &recordingSink{}
+
+	err := MultiSink{first, second, third}.Publish(context.Background(), testEvent("source.created"))
+	if err == nil {
+		t.Fatal("Publish() error = nil, want joined error")
+	}
+	if len(first.events) != 1 || len(third.events) != 1 {
+		t.Fatalf("first = %d, third = %d, want 1 each", len(first.events), len(third.events))
+	}
+}
@@ -0,0 +1,106 @@
+package outbox
+
+import (
+	"context"
+	"log/slog"
+	"time"
+)
+
+// Failed deliveries are retried after retryBaseDelay, doubling with every
+// further failure up to maxRetryDelay.
+const (
+	retryBaseDelay = 5 * time.Second
+	maxRetryDelay  = time.Hour
+)
+
+// Relay periodically drains the outbox into a sink.
+type Relay struct {
+	store       Store
+	sink        Sink
+	batchSize   int
+	interval    time.Duration
+	maxAttempts int
+	logger      *slog.Logger
+	now         func() time.Time
+}
+
+// NewRelay builds a relay. An event that fails maxAttempts deliveries is
+// dead-lettered and no longer retried.
+func NewRelay(store Store, sink Sink, batchSize int, interval time.Duration, maxAttempts int, logger *slog.Logger) *Relay {
+	if batchSize <= 0 {
+		batchSize = 100
+	}
+	if interval <= 0 {
+		interval = time.Second
+	}
+	if maxAttempts <= 0 {
+		maxAttempts = 10
+	}
+	return &Relay{store: store, sink: sink, batchSize: batchSize, interval: interval, maxAttempts: maxAttempts, logger: logger, now: time.Now}
+}
+
+// Run polls the outbox until ctx is cancelled. Full batches are drained
+// immediately; otherwise the relay waits for the next poll interval.
+func (r *Relay) Run(ctx context.Context) {
+	r.logger.Info("outbox relay started", "interval", r.interval, "batch_size", r.batchSize)
+	ticker := time.NewTicker(r.interval)
+	defer ticker.Stop()
+
+	for {
+		for {
+			published, err := r.ProcessBatch(ctx)
+			if err != nil {
+				if ctx.Err() == nil {
+					r.logger.Error("outbox relay batch failed", "error", err)
+				}
+				break
+			}
+			if published < r.batchSize {
+				break
+			}
+		}
+
+		select {
+		case <-ctx.Done():
+			r.logger.Info("outbox relay stopped")
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+// ProcessBatch publishes a single batch and returns how many events were
+// marked as published.
+func (r *Relay) ProcessBatch(ctx context.Context) (int, error) {
+	return r.store.ProcessBatch(ctx, r.batchSize, r.deliver)
+}
+
+// deliver publishes event and, when the sink fails, schedules the next
+// attempt or gives up once the event has failed maxAttempts times.
+func (r *Relay) deliver(ctx context.Context, event Event) Delivery {
+	err := r.sink.Publish(ctx, event)
+	if err == nil {
+		return Delivery{}
+	}
+
+	attempts := event.Attempts + 1
+	now := r.now()
+	if attempts >= r.maxAttempts {
+		r.logger.Error("outbox event dead-lettered", "id", event.ID, "event_type", event.EventType, "attempts", attempts, "error", err)
+		return Delivery{Err: err, RetryAt: now, Dead: true}
+	}
+	retryAt := now.Add(retryDelay(attempts))
+	if ctx.Err() == nil {
+		r.logger.Warn("outbox event delivery failed", "id", event.ID, "event_type", event.EventType, "attempts", attempts, "retry_at", retryAt, "error", err)
+	}
+	return Delivery{Err: err, RetryAt: retryAt}
+}
+
+// retryDelay is the backoff after the given number of failed attempts.
+func retryDelay(attempts int) time.Duration {
+	delay := retryBaseDelay
+	for i := 1; i < attempts && delay < maxRetryDelay; i++ {
+		delay *= 2
+	}
+	return min(delay, maxRetryDelay)
+}
@@ -0,0 +1,137 @@
+package outbox
+
+import (
+	"bytes"
+	"context"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log/slog"
+	"net/http"
+	"sync"
+	"time"
+)
+
+// SignatureHeader carries the hex-encoded HMAC-SHA256 of the webhook body.
+const SignatureHeader = "X-Maktaba-Signature"
+
+// LogSink writes every event to a logger.
+type LogSink struct {
+	logger *slog.Logger
+}
+
+func NewLogSink(logger *slog.Logger) *LogSink {
+	return &LogSink{logger: logger}
+}
+
+func (s *LogSink) Publish(_ context.Context, event Event) error {
+	s.logger.Info("outbox event",
+		"id", event.ID,
+		"event_type", event.EventType,
+		"aggregate_type", event.AggregateType,
+		"aggregate_id", event.AggregateID,
+	)
+	return nil
+}
+
+// WebhookSink POSTs each event as JSON to a URL. When a secret is configured
+// the body is signed and the signature sent in SignatureHeader.
+type WebhookSink struct {
+	url    string
+	secret []byte
+	client *http.Client
+}
+
+func NewWebhookSink(url, secret string, timeout time.Duration) *WebhookSink {
+	if timeout <= 0 {
+		timeout = 10 * time.Second
+	}
+	return &WebhookSink{
+		url:    url,
+		secret: []byte(secret),
+		client: &http.Client{Timeout: timeout},
+	}
+}
+
+func (s *WebhookSink) Publish(ctx context.Context, event Event) error {
+	body, err := json.Marshal(event)
+	if err != nil {
+		return err
+	}
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, s.url, bytes.NewReader(body))
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Content-Type", "application/json")
+	if len(s.secret) > 0 {
+		req.Header.Set(SignatureHeader, Sign(s.secret, body))
+	}
+
+	resp, err := s.client.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode < 200 || resp.StatusCode >= 300 {
+		return fmt.Errorf("webhook responded with status %d", resp.StatusCode)
+	}
+	return nil
+}
+
+// Sign returns the hex-encoded HMAC-SHA256 of body using secret.
+func Sign(secret, body []byte) string {
+	mac := hmac.New(sha256.New, secret)
+	mac.Write(body)
+	return hex.EncodeToString(mac.Sum(nil))
+}
+
+// Handler processes a single event for an in-process subscriber.
+type Handler func(ctx context.Context, event Event) error
+
+// Subscribers dispatches events to in-process handlers by event type.
+type Subscribers struct {
+	mu       sync.RWMutex
+	handlers map[string][]Handler
+}
+
+func NewSubscribers() *Subscribers {
+	return &Subscribers{handlers: make(map[string][]Handler)}
+}
+
+// Subscribe registers handler for eventType.
+func (s *Subscribers) Subscribe(eventType string, handler Handler) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.handlers[eventType] = append(s.handlers[eventType], handler)
+}
+
+func (s *Subscribers) Publish(ctx context.Context, event Event) error {
+	s.mu.RLock()
+	handlers := s.handlers[event.EventType]
+	s.mu.RUnlock()
+
+	for _, handler := range handlers {
+		if err := handler(ctx, event); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// MultiSink publishes each event to every sink in order.
+type MultiSink []Sink
+
+func (m MultiSink) Publish(ctx context.Context, event Event) error {
+	var errs []error
+	for _, sink := range m {
+		if err := sink.Publish(ctx, event); err != nil {
+			errs = append(errs, err)
+		}
+	}
+	return errors.Join(errs...)
+}
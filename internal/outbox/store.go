@@ -0,0 +1,81 @@
+package outbox
+
+import (
+	"context"
+	"time"
+
+	"github.com/jackc/pgx/v5/pgtype"
+	"github.com/zizouhuweidi/maktaba/internal/db"
+	"github.com/zizouhuweidi/maktaba/internal/db/dbgen"
+)
+
+// Delivery is the outcome of handling one event. The zero value means the
+// event was published.
+type Delivery struct {
+	// Err is why the event could not be delivered.
+	Err error
+	// RetryAt is when a failed event is next claimed.
+	RetryAt time.Time
+	// Dead sets a failed event aside for good instead of retrying it.
+	Dead bool
+}
+
+// Store claims unpublished events for delivery.
+type Store interface {
+	// ProcessBatch locks up to limit events that are due, calls handle for
+	// each in order, and records the outcome: delivered events are marked
+	// published and failed ones are rescheduled or dead-lettered. A failure
+	// does not stop the batch, so one poison event cannot hold back the
+	// events behind it. It returns how many events were published.
+	ProcessBatch(ctx context.Context, limit int, handle func(context.Context, Event) Delivery) (int, error)
+}
+
+type postgresStore struct {
+	db      *db.DB
+	queries *dbgen.Queries
+}
+
+func NewPostgresStore(d *db.DB) Store {
+	return &postgresStore{db: d, queries: dbgen.New(d.Pool)}
+}
+
+func (s *postgresStore) ProcessBatch(ctx context.Context, limit int, handle func(context.Context, Event) Delivery) (int, error) {
+	tx, err := s.db.Begin(ctx)
+	if err != nil {
+		return 0, err
+	}
+	defer tx.Rollback(ctx)
+	qtx := s.queries.WithTx(tx)
+
+	rows, err := qtx.ClaimOutboxEvents(ctx, int32(limit))
+	if err != nil {
+		return 0, err
+	}
+
+	published := make([]pgtype.UUID, 0, len(rows))
+	for _, row := range rows {
+		delivery := handle(ctx, mapEvent(row))
+		if delivery.Err == nil {
+			published = append(published, row.ID)
+			continue
+		}
+		if err := qtx.MarkOutboxEventFailed(ctx, dbgen.MarkOutboxEventFailedParams{
+			LastError:     db.PGTextString(delivery.Err.Error()),
+			NextAttemptAt: db.PGTimestamptz(delivery.RetryAt),
+			Dead:          delivery.Dead,
+			ID:            row.ID,
+		}); err != nil {
+			return 0, err
+		}
+	}
+
+	if len(published) > 0 {
+		if err := qtx.MarkOutboxEventsPublished(ctx, published); err != nil {
+			return 0, err
+		}
+	}
+	if err := tx.Commit(ctx); err != nil {
+		return 0, err
+	}
+	return len(published), nil
+}
@@ -20,9 +20,10 @@ func NewHandler(service *Service, logger *slog.Logger) *Handler {
 }
 
 type UpdateRequest struct {
-	DisplayName   *string `json:"display_name,omitempty"`
-	Bio           *string `json:"bio,omitempty"`
-	PublicProfile *bool   `json:"public_profile,omitempty"`
+	DisplayName        *string `json:"display_name,omitempty"`
+	Bio                *string `json:"bio,omitempty"`
+	PublicProfile      *bool   `json:"public_profile,omitempty"`
+	PublicYearInReview *bool   `json:"public_year_in_review,omitempty"`
 }
 
 func (h *Handler) RegisterPublicRoutes(e *echo.Echo) {
@@ -75,10 +76,11 @@ func (h *Handler) Update(c *echo.Context) error {
 	}
 
 	profile, err := h.service.Update(c.Request().Context(), UpdateProfileParams{
-		UserID:        userID,
-		DisplayName:   req.DisplayName,
-		Bio:           req.Bio,
-		PublicProfile: req.PublicProfile,
+		UserID:             userID,
+		DisplayName:        req.DisplayName,
+		Bio:                req.Bio,
+		PublicProfile:      req.PublicProfile,
+		PublicYearInReview: req.PublicYearInReview,
 	})
 	if errors.Is(err, ErrInvalidProfile) {
 		return echo.NewHTTPError(http.StatusBadRequest, "invalid profile")
@@ -8,14 +8,15 @@ import (
 )
 
 type Profile struct {
-	ID            uuid.UUID `json:"id"`
-	UserID        uuid.UUID `json:"user_id"`
-	Username      string    `json:"username,omitempty"`
-	DisplayName   *string   `json:"display_name,omitempty"`
-	Bio           *string   `json:"bio,omitempty"`
-	PublicProfile bool      `json:"public_profile"`
-	CreatedAt     time.Time `json:"created_at"`
-	UpdatedAt     time.Time `json:"updated_at"`
+	ID                 uuid.UUID `json:"id"`
+	UserID             uuid.UUID `json:"user_id"`
+	Username           string    `json:"username,omitempty"`
+	DisplayName        *string   `json:"display_name,omitempty"`
+	Bio                *string   `json:"bio,omitempty"`
+	PublicProfile      bool      `json:"public_profile"`
+	PublicYearInReview bool      `json:"public_year_in_review"`
+	CreatedAt          time.Time `json:"created_at"`
+	UpdatedAt          time.Time `json:"updated_at"`
 }
 
 type Repository interface {
@@ -25,8 +26,9 @@ type Repository interface {
 }
 
 type UpdateProfileParams struct {
-	UserID        uuid.UUID
-	DisplayName   *string
-	Bio           *string
-	PublicProfile *bool
+	UserID             uuid.UUID
+	DisplayName        *string
+	Bio                *string
+	PublicProfile      *bool
+	PublicYearInReview *bool
 }
@@ -39,14 +39,15 @@ func (r *postgresRepository) GetPublicByUsername(ctx context.Context, username s
 		return nil, err
 	}
 	return &Profile{
-		ID:            db.UUID(row.ID),
-		UserID:        db.UUID(row.UserID),
-		Username:      row.Username,
-		DisplayName:   db.StringPtr(row.DisplayName),
-		Bio:           db.StringPtr(row.Bio),
-		PublicProfile: db.Bool(row.PublicProfile),
-		CreatedAt:     db.Time(row.CreatedAt),
-		UpdatedAt:     db.Time(row.UpdatedAt),
+		ID:                 db.UUID(row.ID),
+		UserID:             db.UUID(row.UserID),
+		Username:           row.Username,
+		DisplayName:        db.StringPtr(row.DisplayName),
+		Bio:                db.StringPtr(row.Bio),
+		PublicProfile:      db.Bool(row.PublicProfile),
+		PublicYearInReview: row.PublicYearInReview,
+		CreatedAt:          db.Time(row.CreatedAt),
+		UpdatedAt:          db.Time(row.UpdatedAt),
 	}, nil
 }
 
@@ -61,35 +62,38 @@ func (r *postgresRepository) Upsert(ctx context.Context, profile *Profile) (*Pro
 	}
 
 	row, err := r.queries.UpsertProfile(ctx, dbgen.UpsertProfileParams{
-		ID:            db.PGUUID(id),
-		UserID:        db.PGUUID(profile.UserID),
-		DisplayName:   db.PGText(profile.DisplayName),
-		Bio:           db.PGText(profile.Bio),
-		PublicProfile: db.PGBool(profile.PublicProfile),
+		ID:                 db.PGUUID(id),
+		UserID:             db.PGUUID(profile.UserID),
+		DisplayName:        db.PGText(profile.DisplayName),
+		Bio:                db.PGText(profile.Bio),
+		PublicProfile:      db.PGBool(profile.PublicProfile),
+		PublicYearInReview: profile.PublicYearInReview,
 	})
 	if err != nil {
 		return nil, err
 	}
 	return &Profile{
-		ID:            db.UUID(row.ID),
-		UserID:        db.UUID(row.UserID),
-		Username:      row.Username,
-		DisplayName:   db.StringPtr(row.DisplayName),
-		Bio:           db.StringPtr(row.Bio),
-		PublicProfile: db.Bool(row.PublicProfile),
-		CreatedAt:     db.Time(row.CreatedAt),
-		UpdatedAt:     db.Time(row.UpdatedAt),
+		ID:                 db.UUID(row.ID),
+		UserID:             db.UUID(row.UserID),
+		Username:           row.Username,
+		DisplayName:        db.StringPtr(row.DisplayName),
+		Bio:                db.StringPtr(row.Bio),
+		PublicProfile:      db.Bool(row.PublicProfile),
+		PublicYearInReview: row.PublicYearInReview,
+		CreatedAt:          db.Time(row.CreatedAt),
+		UpdatedAt:          db.Time(row.UpdatedAt),
 	}, nil
 }
 
 func mapProfile(row dbgen.Profile) *Profile {
 	return &Profile{
-		ID:            db.UUID(row.ID),
-		UserID:        db.UUID(row.UserID),
-		DisplayName:   db.StringPtr(row.DisplayName),
-		Bio:           db.StringPtr(row.Bio),
-		PublicProfile: db.Bool(row.PublicProfile),
-		CreatedAt:     db.Time(row.CreatedAt),
-		UpdatedAt:     db.Time(row.UpdatedAt),
+		ID:                 db.UUID(row.ID),
+		UserID:             db.UUID(row.UserID),
+		DisplayName:        db.StringPtr(row.DisplayName),
+		Bio:                db.StringPtr(row.Bio),
+		PublicProfile:      db.Bool(row.PublicProfile),
+		PublicYearInReview: row.PublicYearInReview,
+		CreatedAt:          db.Time(row.CreatedAt),
+		UpdatedAt:          db.Time(row.UpdatedAt),
 	}
 }
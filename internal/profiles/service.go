@@ -71,6 +71,9 @@ func (s *Service) Update(ctx context.Context, params UpdateProfileParams) (*Prof
 	if params.PublicProfile != nil {
 		existing.PublicProfile = *params.PublicProfile
 	}
+	if params.PublicYearInReview != nil {
+		existing.PublicYearInReview = *params.PublicYearInReview
+	}
 
 	updated, err := s.repo.Upsert(ctx, existing)
 	if err != nil {
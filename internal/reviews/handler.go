@@ -101,32 +101,21 @@ func (h *Handler) GetByID(c *echo.Context) error {
 }
 
 func (h *Handler) List(c *echo.Context) error {
-	limit, offset := echox.Pagination(c)
-	userIDStr := c.QueryParam("user_id")
-	sourceIDStr := c.QueryParam("source_id")
-
-	var result []*Review
-	var err error
-	if userIDStr != "" {
-		userID, parseErr := uuid.FromString(userIDStr)
-		if parseErr != nil {
-			return echo.NewHTTPError(http.StatusBadRequest, "invalid user_id")
-		}
-		result, err = h.service.ListPublicByUser(c.Request().Context(), userID, limit, offset)
-	} else if sourceIDStr != "" {
-		sourceID, parseErr := uuid.FromString(sourceIDStr)
-		if parseErr != nil {
-			return echo.NewHTTPError(http.StatusBadRequest, "invalid source_id")
-		}
-		result, err = h.service.ListPublicBySource(c.Request().Context(), sourceID, limit, offset)
-	} else {
-		return echo.NewHTTPError(http.StatusBadRequest, "user_id or source_id required")
+	page, err := echox.PageParams(c)
+	if err != nil {
+		return err
 	}
+	filter, err := listFilter(c)
 	if err != nil {
-		return echo.NewHTTPError(http.StatusInternalServerError, "failed to list reviews")
+		return err
+	}
+	if filter.UserID == nil && filter.SourceID == nil {
+		return echo.NewHTTPError(http.StatusBadRequest, "user_id or source_id required")
 	}
+	filter.PublicOnly = true
 
-	return c.JSON(http.StatusOK, result)
+	result, err := h.service.List(c.Request().Context(), filter, page)
+	return h.listResult(c, result, err)
 }
 
 func (h *Handler) ListOwn(c *echo.Context) error {
@@ -134,14 +123,52 @@ func (h *Handler) ListOwn(c *echo.Context) error {
 	if !ok {
 		return echo.NewHTTPError(http.StatusUnauthorized, "authentication required")
 	}
-	limit, offset := echox.Pagination(c)
-	reviews, err := h.service.ListByUser(c.Request().Context(), userID, limit, offset)
+	page, err := echox.PageParams(c)
+	if err != nil {
+		return err
+	}
+	filter, err := listFilter(c)
+	if err != nil {
+		return err
+	}
+	filter.UserID = &userID
+
+	reviews, err := h.service.List(c.Request().Context(), filter, page)
+	return h.listResult(c, reviews, err)
+}
+
+func (h *Handler) listResult(c *echo.Context, reviews *echox.Page[*Review], err error) error {
+	if errors.Is(err, ErrInvalidFilter) {
+		return echo.NewHTTPError(http.StatusBadRequest, "invalid rating range")
+	}
 	if err != nil {
 		return echo.NewHTTPError(http.StatusInternalServerError, "failed to list reviews")
 	}
 	return c.JSON(http.StatusOK, reviews)
 }
 
+// listFilter reads the user_id, source_id, min_rating and max_rating query
+// parameters
+func listFilter(c *echo.Context) (ListFilter, error) {
+	userID, err := echox.OptionalUUID(echox.QueryString(c, "user_id"), "user_id")
+	if err != nil {
+		return ListFilter{}, err
+	}
+	sourceID, err := echox.OptionalUUID(echox.QueryString(c, "source_id"), "source_id")
+	if err != nil {
+		return ListFilter{}, err
+	}
+	minRating, err := echox.QueryInt(c, "min_rating", "min_rating")
+	if err != nil {
+		return ListFilter{}, err
+	}
+	maxRating, err := echox.QueryInt(c, "max_rating", "max_rating")
+	if err != nil {
+		return ListFilter{}, err
+	}
+	return ListFilter{UserID: userID, SourceID: sourceID, MinRating: minRating, MaxRating: maxRating}, nil
+}
+
 func (h *Handler) Update(c *echo.Context) error {
 	userID, ok := auth.UserID(c)
 	if !ok {
@@ -10,6 +10,7 @@ import (
 	"github.com/gofrs/uuid/v5"
 	"github.com/labstack/echo/v5"
 	"github.com/zizouhuweidi/maktaba/internal/auth"
+	"github.com/zizouhuweidi/maktaba/internal/echox"
 )
 
 func TestHandlerGetByIDRejectsPrivateReview(t *testing.T) {
@@ -55,6 +56,20 @@ func TestHandlerDeleteRejectsAnotherUsersReview(t *testing.T) {
 	}
 }
 
+func TestHandlerListRejectsInvalidRatingRange(t *testing.T) {
+	handler := NewHandler(NewService(&fakeReviewsRepository{}, slog.Default()), slog.Default())
+
+	for _, query := range []string{"min_rating=4&max_rating=2", "min_rating=0", "max_rating=6"} {
+		c := testContext(http.MethodGet, "/reviews?user_id="+mustTestUUID(t).String()+"&"+query, "", "")
+
+		err := handler.List(c)
+
+		if code := statusCode(t, err); code != http.StatusBadRequest {
+			t.Fatalf("%s: expected status %d, got %d", query, http.StatusBadRequest, code)
+		}
+	}
+}
+
 func testContext(method, target, paramName, paramValue string) *echo.Context {
 	e := echo.New()
 	req := httptest.NewRequest(method, target, nil)
@@ -100,11 +115,11 @@ func (r *fakeReviewsRepository) ListBySource(context.Context, uuid.UUID, int, in
 	panic("not implemented")
 }
 
-func (r *fakeReviewsRepository) ListPublicByUser(context.Context, uuid.UUID, int, int) ([]*Review, error) {
+func (r *fakeReviewsRepository) List(context.Context, ListFilter, echox.PageRequest) ([]*Review, error) {
 	panic("not implemented")
 }
 
-func (r *fakeReviewsRepository) ListPublicBySource(context.Context, uuid.UUID, int, int) ([]*Review, error) {
+func (r *fakeReviewsRepository) Count(context.Context, ListFilter) (int64, error) {
 	panic("not implemented")
 }
 
@@ -10,14 +10,17 @@ import (
 	"github.com/jackc/pgx/v5/pgtype"
 	"github.com/zizouhuweidi/maktaba/internal/db"
 	"github.com/zizouhuweidi/maktaba/internal/db/dbgen"
+	"github.com/zizouhuweidi/maktaba/internal/echox"
+	"github.com/zizouhuweidi/maktaba/internal/outbox"
 )
 
 type postgresRepository struct {
+	db      *db.DB
 	queries *dbgen.Queries
 }
 
 func NewPostgresRepository(d *db.DB) Repository {
-	return &postgresRepository{queries: dbgen.New(d.Pool)}
+	return &postgresRepository{db: d, queries: dbgen.New(d.Pool)}
 }
 
 func (r *postgresRepository) Create(ctx context.Context, review *Review) (*Review, error) {
@@ -26,7 +29,14 @@ func (r *postgresRepository) Create(ctx context.Context, review *Review) (*Revie
 		return nil, err
 	}
 
-	row, err := r.queries.CreateReview(ctx, dbgen.CreateReviewParams{
+	tx, err := r.db.Begin(ctx)
+	if err != nil {
+		return nil, err
+	}
+	defer tx.Rollback(ctx)
+	qtx := r.queries.WithTx(tx)
+
+	row, err := qtx.CreateReview(ctx, dbgen.CreateReviewParams{
 		ID:       db.PGUUID(id),
 		UserID:   db.PGUUID(review.UserID),
 		SourceID: db.PGUUID(review.SourceID),
@@ -37,7 +47,15 @@ func (r *postgresRepository) Create(ctx context.Context, review *Review) (*Revie
 	if err != nil {
 		return nil, mapCreateError(err)
 	}
-	return mapReview(row), nil
+	created := mapReview(row)
+	if err := outbox.Record(ctx, qtx, AggregateReview, created.ID, EventReviewCreated, created); err != nil {
+		return nil, err
+	}
+
+	if err := tx.Commit(ctx); err != nil {
+		return nil, err
+	}
+	return created, nil
 }
 
 func mapCreateError(err error) error {
@@ -85,35 +103,85 @@ func (r *postgresRepository) ListBySource(ctx context.Context, sourceID uuid.UUI
 	return mapReviews(rows), nil
 }
 
-func (r *postgresRepository) ListPublicByUser(ctx context.Context, userID uuid.UUID, limit, offset int) ([]*Review, error) {
-	rows, err := r.queries.ListPublicReviewsByUser(ctx, dbgen.ListPublicReviewsByUserParams{UserID: db.PGUUID(userID), Limit: int32(limit), Offset: int32(offset)})
+// List fetches one row past the page limit so the caller can tell whether
+// another page follows
+func (r *postgresRepository) List(ctx context.Context, filter ListFilter, page echox.PageRequest) ([]*Review, error) {
+	params := dbgen.ListReviewsNewestParams{
+		UserID:     db.PGUUIDPtr(filter.UserID),
+		SourceID:   db.PGUUIDPtr(filter.SourceID),
+		PublicOnly: filter.PublicOnly,
+		MinRating:  db.PGInt4Ptr(filter.MinRating),
+		MaxRating:  db.PGInt4Ptr(filter.MaxRating),
+		Limit:      int32(page.Limit + 1),
+	}
+	params.AfterCreatedAt, params.AfterID = db.Keyset(page.After.CreatedAt, page.After.ID, page.Oldest)
+	var rows []dbgen.Review
+	var err error
+	if page.Oldest {
+		rows, err = r.queries.ListReviewsOldest(ctx, dbgen.ListReviewsOldestParams(params))
+	} else {
+		rows, err = r.queries.ListReviewsNewest(ctx, params)
+	}
 	if err != nil {
 		return nil, err
 	}
 	return mapReviews(rows), nil
 }
 
-func (r *postgresRepository) ListPublicBySource(ctx context.Context, sourceID uuid.UUID, limit, offset int) ([]*Review, error) {
-	rows, err := r.queries.ListPublicReviewsBySource(ctx, dbgen.ListPublicReviewsBySourceParams{SourceID: db.PGUUID(sourceID), Limit: int32(limit), Offset: int32(offset)})
+func (r *postgresRepository) Count(ctx context.Context, filter ListFilter) (int64, error) {
+	return r.queries.CountReviews(ctx, dbgen.CountReviewsParams{
+		UserID:     db.PGUUIDPtr(filter.UserID),
+		SourceID:   db.PGUUIDPtr(filter.SourceID),
+		PublicOnly: filter.PublicOnly,
+		MinRating:  db.PGInt4Ptr(filter.MinRating),
+		MaxRating:  db.PGInt4Ptr(filter.MaxRating),
+	})
+}
+
+func (r *postgresRepository) Update(ctx context.Context, review *Review) (*Review, error) {
+	tx, err := r.db.Begin(ctx)
 	if err != nil {
 		return nil, err
 	}
-	return mapReviews(rows), nil
-}
+	defer tx.Rollback(ctx)
+	qtx := r.queries.WithTx(tx)
 
-func (r *postgresRepository) Update(ctx context.Context, review *Review) (*Review, error) {
-	row, err := r.queries.UpdateReview(ctx, dbgen.UpdateReviewParams{ID: db.PGUUID(review.ID), Rating: pgtype.Int4{Int32: int32(review.Rating), Valid: true}, Content: db.PGText(review.Content), IsPublic: db.PGBool(review.IsPublic)})
+	row, err := qtx.UpdateReview(ctx, dbgen.UpdateReviewParams{ID: db.PGUUID(review.ID), Rating: pgtype.Int4{Int32: int32(review.Rating), Valid: true}, Content: db.PGText(review.Content), IsPublic: db.PGBool(review.IsPublic)})
 	if errors.Is(err, pgx.ErrNoRows) {
 		return nil, nil
 	}
 	if err != nil {
 		return nil, err
 	}
-	return mapReview(row), nil
+	updated := mapReview(row)
+	if err := outbox.Record(ctx, qtx, AggregateReview, updated.ID, EventReviewUpdated, updated); err != nil {
+		return nil, err
+	}
+
+	if err := tx.Commit(ctx); err != nil {
+		return nil, err
+	}
+	return updated, nil
 }
 
 func (r *postgresRepository) Delete(ctx context.Context, id uuid.UUID) error {
-	return r.queries.DeleteReview(ctx, db.PGUUID(id))
+	tx, err := r.db.Begin(ctx)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback(ctx)
+	qtx := r.queries.WithTx(tx)
+
+	deleted, err := qtx.DeleteReview(ctx, db.PGUUID(id))
+	if err != nil {
+		return err
+	}
+	if deleted > 0 {
+		if err := outbox.Record(ctx, qtx, AggregateReview, id, EventReviewDeleted, outbox.DeletedPayload{ID: id}); err != nil {
+			return err
+		}
+	}
+	return tx.Commit(ctx)
 }
 
 func mapReviews(rows []dbgen.Review) []*Review {
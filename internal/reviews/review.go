@@ -5,6 +5,15 @@ import (
 	"time"
 
 	"github.com/gofrs/uuid/v5"
+	"github.com/zizouhuweidi/maktaba/internal/echox"
+)
+
+// Outbox aggregate and event types published by this context.
+const (
+	AggregateReview    = "review"
+	EventReviewCreated = "review.created"
+	EventReviewUpdated = "review.updated"
+	EventReviewDeleted = "review.deleted"
 )
 
 type Review struct {
@@ -23,12 +32,21 @@ type Repository interface {
 	GetByID(ctx context.Context, id uuid.UUID) (*Review, error)
 	ListByUser(ctx context.Context, userID uuid.UUID, limit, offset int) ([]*Review, error)
 	ListBySource(ctx context.Context, sourceID uuid.UUID, limit, offset int) ([]*Review, error)
-	ListPublicByUser(ctx context.Context, userID uuid.UUID, limit, offset int) ([]*Review, error)
-	ListPublicBySource(ctx context.Context, sourceID uuid.UUID, limit, offset int) ([]*Review, error)
+	List(ctx context.Context, filter ListFilter, page echox.PageRequest) ([]*Review, error)
+	Count(ctx context.Context, filter ListFilter) (int64, error)
 	Update(ctx context.Context, review *Review) (*Review, error)
 	Delete(ctx context.Context, id uuid.UUID) error
 }
 
+// ListFilter narrows a review list; the rating bounds are inclusive
+type ListFilter struct {
+	UserID     *uuid.UUID
+	SourceID   *uuid.UUID
+	PublicOnly bool
+	MinRating  *int
+	MaxRating  *int
+}
+
 type CreateReviewParams struct {
 	UserID   uuid.UUID
 	SourceID uuid.UUID
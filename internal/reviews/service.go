@@ -6,6 +6,7 @@ import (
 	"log/slog"
 
 	"github.com/gofrs/uuid/v5"
+	"github.com/zizouhuweidi/maktaba/internal/echox"
 )
 
 var (
@@ -14,6 +15,7 @@ var (
 	ErrReviewExists   = errors.New("review already exists")
 	ErrSourceNotFound = errors.New("source not found")
 	ErrReviewConflict = errors.New("review conflict")
+	ErrInvalidFilter  = errors.New("invalid list filter")
 )
 
 type Service struct {
@@ -59,14 +61,23 @@ func (s *Service) GetByID(ctx context.Context, id uuid.UUID) (*Review, error) {
 	return review, nil
 }
 
-func (s *Service) ListPublicByUser(ctx context.Context, userID uuid.UUID, limit, offset int) ([]*Review, error) {
-	limit, offset = normalizePagination(limit, offset)
-	return s.repo.ListPublicByUser(ctx, userID, limit, offset)
-}
+func (s *Service) List(ctx context.Context, filter ListFilter, page echox.PageRequest) (*echox.Page[*Review], error) {
+	if !validRatingRange(filter.MinRating, filter.MaxRating) {
+		return nil, ErrInvalidFilter
+	}
+	page = page.Normalize()
 
-func (s *Service) ListPublicBySource(ctx context.Context, sourceID uuid.UUID, limit, offset int) ([]*Review, error) {
-	limit, offset = normalizePagination(limit, offset)
-	return s.repo.ListPublicBySource(ctx, sourceID, limit, offset)
+	reviews, err := s.repo.List(ctx, filter, page)
+	if err != nil {
+		return nil, err
+	}
+	total, err := page.Total(func() (int64, error) { return s.repo.Count(ctx, filter) })
+	if err != nil {
+		return nil, err
+	}
+	return echox.NewPage(reviews, page, total, func(review *Review) echox.Cursor {
+		return echox.Cursor{CreatedAt: review.CreatedAt, ID: review.ID}
+	}), nil
 }
 
 func (s *Service) ListByUser(ctx context.Context, userID uuid.UUID, limit, offset int) ([]*Review, error) {
@@ -125,6 +136,13 @@ func validRating(rating int) bool {
 	return rating >= 1 && rating <= 5
 }
 
+func validRatingRange(min, max *int) bool {
+	if min != nil && !validRating(*min) || max != nil && !validRating(*max) {
+		return false
+	}
+	return min == nil || max == nil || *min <= *max
+}
+
 func normalizePagination(limit, offset int) (int, int) {
 	if limit <= 0 {
 		limit = 100
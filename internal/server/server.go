@@ -1,30 +1,68 @@
 package server
 
 import (
+	"context"
 	"errors"
+	"fmt"
 	"log/slog"
 	"net/http"
+	"strings"
 
 	"github.com/labstack/echo/v5"
 	"github.com/labstack/echo/v5/middleware"
+	"github.com/zizouhuweidi/maktaba/internal/account"
 	"github.com/zizouhuweidi/maktaba/internal/auth"
 	"github.com/zizouhuweidi/maktaba/internal/collections"
 	"github.com/zizouhuweidi/maktaba/internal/config"
 	"github.com/zizouhuweidi/maktaba/internal/db"
 	"github.com/zizouhuweidi/maktaba/internal/echox"
+	"github.com/zizouhuweidi/maktaba/internal/goals"
 	"github.com/zizouhuweidi/maktaba/internal/health"
+	"github.com/zizouhuweidi/maktaba/internal/importer"
 	"github.com/zizouhuweidi/maktaba/internal/library"
+	"github.com/zizouhuweidi/maktaba/internal/mail"
+	"github.com/zizouhuweidi/maktaba/internal/metadata"
 	"github.com/zizouhuweidi/maktaba/internal/notes"
+	"github.com/zizouhuweidi/maktaba/internal/oidc"
 	"github.com/zizouhuweidi/maktaba/internal/profiles"
 	"github.com/zizouhuweidi/maktaba/internal/reviews"
 	"github.com/zizouhuweidi/maktaba/internal/sources"
+	"github.com/zizouhuweidi/maktaba/internal/stats"
+	"github.com/zizouhuweidi/maktaba/internal/tags"
 )
 
-func New(cfg *config.Config, database *db.DB, logger *slog.Logger) (*http.Server, error) {
+// Server is the HTTP server together with the services whose background work
+// must finish before the process exits.
+type Server struct {
+	HTTP *http.Server
+	auth *auth.Service
+}
+
+// Wait blocks until the work requests left running in the background, such
+// as sending mail, has finished or ctx is done. Call it after HTTP has shut
+// down.
+func (s *Server) Wait(ctx context.Context) error {
+	return s.auth.Wait(ctx)
+}
+
+func New(cfg *config.Config, database *db.DB, logger *slog.Logger) (*Server, error) {
 	tokenManager, err := auth.NewTokenManager(cfg.Auth.Issuer, cfg.Auth.Audience, cfg.Auth.Ed25519PrivateKey, cfg.Auth.AccessTokenLifetime)
 	if err != nil {
 		return nil, err
 	}
+	secretBox, err := auth.NewSecretBox(cfg.Auth.TOTPEncryptionKey)
+	if err != nil {
+		return nil, err
+	}
+	metadataLookup, err := buildMetadataLookup(cfg.Metadata, logger)
+	if err != nil {
+		return nil, err
+	}
+	mailer, err := buildMailer(cfg.Mail, logger)
+	if err != nil {
+		return nil, err
+	}
+	identityProviders := buildIdentityProviders(cfg.Auth)
 
 	authRepo := auth.NewPostgresRepository(database)
 	collectionRepo := collections.NewPostgresRepository(database)
@@ -33,22 +71,39 @@ func New(cfg *config.Config, database *db.DB, logger *slog.Logger) (*http.Server
 	noteRepo := notes.NewPostgresRepository(database)
 	profileRepo := profiles.NewPostgresRepository(database)
 	reviewRepo := reviews.NewPostgresRepository(database)
+	statsRepo := stats.NewPostgresRepository(database)
+	goalRepo := goals.NewPostgresRepository(database)
+	tagRepo := tags.NewPostgresRepository(database)
 
-	authSvc := auth.NewService(authRepo, tokenManager, cfg.Auth.RefreshTokenLifetime, logger)
+	authSvc := auth.NewService(authRepo, tokenManager, secretBox, mailer, identityProviders, cfg.Auth.RefreshTokenLifetime, auth.EmailSettings{
+		AppURL:           cfg.Auth.AppURL,
+		VerifyEmailTTL:   cfg.Auth.VerifyEmailTokenLifetime,
+		ResetPasswordTTL: cfg.Auth.ResetPasswordTokenLifetime,
+	}, logger)
 	collectionSvc := collections.NewService(collectionRepo, logger)
 	librarySvc := library.NewService(libraryRepo, logger)
-	sourceSvc := sources.NewService(sourceRepo, logger)
+	sourceSvc := sources.NewService(sourceRepo, metadataLookup, logger)
 	noteSvc := notes.NewService(noteRepo, logger)
 	profileSvc := profiles.NewService(profileRepo, logger)
 	reviewSvc := reviews.NewService(reviewRepo, logger)
+	statsSvc := stats.NewService(statsRepo, logger)
+	goalSvc := goals.NewService(goalRepo, logger)
+	tagSvc := tags.NewService(tagRepo, logger)
+	importSvc := importer.NewService(sourceSvc, librarySvc, reviewSvc, logger)
+	accountSvc := account.NewService(authSvc, profileSvc, librarySvc, noteSvc, reviewSvc, collectionSvc, goalSvc, logger)
 
-	authHndlr := auth.NewHandler(authSvc, cfg.Auth.CookieSecure, logger)
+	authHndlr := auth.NewHandler(authSvc, cfg.Auth.CookieSecure, cfg.Auth.AppURL, logger)
 	collectionHndlr := collections.NewHandler(collectionSvc, logger)
 	libraryHndlr := library.NewHandler(librarySvc, logger)
 	sourceHndlr := sources.NewHandler(sourceSvc, logger)
 	noteHndlr := notes.NewHandler(noteSvc, logger)
 	profileHndlr := profiles.NewHandler(profileSvc, logger)
 	reviewHndlr := reviews.NewHandler(reviewSvc, logger)
+	statsHndlr := stats.NewHandler(statsSvc, logger)
+	goalHndlr := goals.NewHandler(goalSvc, logger)
+	tagHndlr := tags.NewHandler(tagSvc, logger)
+	importHndlr := importer.NewHandler(importSvc, logger)
+	accountHndlr := account.NewHandler(accountSvc, logger)
 
 	e := echo.New()
 	e.Logger = logger
@@ -86,25 +141,87 @@ func New(cfg *config.Config, database *db.DB, logger *slog.Logger) (*http.Server
 	noteHndlr.RegisterPublicRoutes(e)
 	profileHndlr.RegisterPublicRoutes(e)
 	reviewHndlr.RegisterPublicRoutes(e)
+	statsHndlr.RegisterPublicRoutes(e)
+	tagHndlr.RegisterPublicRoutes(e)
 
+	// Personal access tokens reach each group only with a scope for its
+	// resource, and never the account's credentials.
 	protected := e.Group("/api")
 	protected.Use(authHndlr.Middleware)
-	authHndlr.RegisterProtectedRoutes(protected)
-	collectionHndlr.RegisterProtectedRoutes(protected)
-	libraryHndlr.RegisterProtectedRoutes(protected)
-	sourceHndlr.RegisterProtectedRoutes(protected)
-	noteHndlr.RegisterProtectedRoutes(protected)
-	profileHndlr.RegisterProtectedRoutes(protected)
-	reviewHndlr.RegisterProtectedRoutes(protected)
-
-	return &http.Server{
-		Addr:         ":" + cfg.Server.Port,
-		Handler:      e,
-		ReadTimeout:  cfg.Server.ReadTimeout,
-		WriteTimeout: cfg.Server.WriteTimeout,
+	authHndlr.RegisterProtectedRoutes(protected.Group("", auth.RequireSession))
+	collectionHndlr.RegisterProtectedRoutes(protected.Group("", auth.RequireScope(auth.ResourceCollections)))
+	libraryHndlr.RegisterProtectedRoutes(protected.Group("", auth.RequireScope(auth.ResourceLibrary)))
+	sourceHndlr.RegisterProtectedRoutes(protected.Group("", auth.RequireScope(auth.ResourceSources)))
+	noteHndlr.RegisterProtectedRoutes(protected.Group("", auth.RequireScope(auth.ResourceNotes)))
+	profileHndlr.RegisterProtectedRoutes(protected.Group("", auth.RequireScope(auth.ResourceProfile)))
+	reviewHndlr.RegisterProtectedRoutes(protected.Group("", auth.RequireScope(auth.ResourceReviews)))
+	statsHndlr.RegisterProtectedRoutes(protected.Group("", auth.RequireScope(auth.ResourceLibrary)))
+	goalHndlr.RegisterProtectedRoutes(protected.Group("", auth.RequireScope(auth.ResourceGoals)))
+	tagHndlr.RegisterProtectedRoutes(protected.Group("", auth.RequireScope(auth.ResourceTags)))
+	importHndlr.RegisterProtectedRoutes(protected.Group("", auth.RequireScope(auth.ResourceLibrary)))
+	accountHndlr.RegisterProtectedRoutes(protected.Group("", auth.RequireScope(auth.ResourceAccount)))
+
+	return &Server{
+		HTTP: &http.Server{
+			Addr:         ":" + cfg.Server.Port,
+			Handler:      e,
+			ReadTimeout:  cfg.Server.ReadTimeout,
+			WriteTimeout: cfg.Server.WriteTimeout,
+		},
+		auth: authSvc,
 	}, nil
 }
 
+func buildMetadataLookup(cfg config.MetadataConfig, logger *slog.Logger) (*metadata.Lookup, error) {
+	client := &http.Client{Timeout: cfg.Timeout}
+	providers := make([]metadata.Provider, 0, len(cfg.Providers))
+	for _, name := range cfg.Providers {
+		switch name {
+		case "openlibrary":
+			providers = append(providers, metadata.NewOpenLibrary(client, ""))
+		case "googlebooks":
+			providers = append(providers, metadata.NewGoogleBooks(client, "", cfg.GoogleBooksAPIKey))
+		case "crossref":
+			providers = append(providers, metadata.NewCrossref(client, "", cfg.CrossrefMailto))
+		default:
+			return nil, fmt.Errorf("unknown metadata provider %q", name)
+		}
+	}
+	return metadata.NewLookup(providers, logger), nil
+}
+
+func buildMailer(cfg config.MailConfig, logger *slog.Logger) (mail.Mailer, error) {
+	switch cfg.Provider {
+	case "log":
+		return mail.NewLogMailer(logger), nil
+	case "file":
+		return mail.NewFileMailer(cfg.Dir, cfg.From), nil
+	case "smtp":
+		if cfg.SMTPHost == "" {
+			return nil, errors.New("SMTP_HOST is required for the smtp mail provider")
+		}
+		return mail.NewSMTPMailer(cfg.SMTPHost, cfg.SMTPPort, cfg.SMTPUsername, cfg.SMTPPassword, cfg.From, cfg.SMTPTimeout), nil
+	default:
+		return nil, fmt.Errorf("unknown mail provider %q", cfg.Provider)
+	}
+}
+
+// buildIdentityProviders sets up each OpenID Connect provider to redirect
+// back to its callback on this server.
+func buildIdentityProviders(cfg config.AuthConfig) map[string]auth.IdentityProvider {
+	providers := make(map[string]auth.IdentityProvider, len(cfg.OIDCProviders))
+	for _, provider := range cfg.OIDCProviders {
+		providers[provider.Name] = oidc.NewProvider(oidc.Config{
+			Issuer:       provider.Issuer,
+			ClientID:     provider.ClientID,
+			ClientSecret: provider.ClientSecret,
+			RedirectURL:  strings.TrimRight(cfg.APIURL, "/") + "/auth/oidc/" + provider.Name + "/callback",
+			Scopes:       provider.Scopes,
+		}, nil)
+	}
+	return providers
+}
+
 func echoErrorHandler(logger *slog.Logger) echo.HTTPErrorHandler {
 	return func(c *echo.Context, err error) {
 		if response, ok := c.Response().(*echo.Response); ok && response.Committed {
@@ -0,0 +1,99 @@
+package sources
+
+import (
+	"bytes"
+	"slices"
+
+	"github.com/gofrs/uuid/v5"
+)
+
+// DuplicateReason names the signal that matched two sources
+type DuplicateReason string
+
+const (
+	DuplicateReasonISBN  DuplicateReason = "isbn"
+	DuplicateReasonDOI   DuplicateReason = "doi"
+	DuplicateReasonTitle DuplicateReason = "title"
+)
+
+// DuplicatePair is two sources that look like the same work. Score is 1 for
+// identifier matches and the title similarity otherwise.
+type DuplicatePair struct {
+	SourceID    uuid.UUID
+	DuplicateID uuid.UUID
+	Reason      DuplicateReason
+	Score       float32
+}
+
+// DuplicateCluster groups sources that are probably the same work. Sources
+// are ordered oldest first, so the first is the suggested survivor.
+type DuplicateCluster struct {
+	Sources []*Source         `json:"sources"`
+	Reasons []DuplicateReason `json:"reasons"`
+	Score   float32           `json:"score"`
+}
+
+// MergeResult reports what a merge moved onto the surviving source
+type MergeResult struct {
+	Survivor     *Source   `json:"survivor"`
+	MergedID     uuid.UUID `json:"merged_id"`
+	LibraryItems int64     `json:"library_items"`
+	Reviews      int64     `json:"reviews"`
+	Notes        int64     `json:"notes"`
+	Collections  int64     `json:"collections"`
+}
+
+type duplicateGroup struct {
+	ids     []uuid.UUID
+	reasons []DuplicateReason
+	score   float32
+}
+
+// clusterPairs joins overlapping pairs into groups, so A~B and B~C become one
+// group of three. Groups keep the order in which their first pair appeared.
+func clusterPairs(pairs []DuplicatePair) []*duplicateGroup {
+	parent := map[uuid.UUID]uuid.UUID{}
+	var find func(id uuid.UUID) uuid.UUID
+	find = func(id uuid.UUID) uuid.UUID {
+		p, ok := parent[id]
+		if !ok {
+			parent[id] = id
+			return id
+		}
+		if p == id {
+			return id
+		}
+		root := find(p)
+		parent[id] = root
+		return root
+	}
+	for _, pair := range pairs {
+		if a, b := find(pair.SourceID), find(pair.DuplicateID); a != b {
+			parent[b] = a
+		}
+	}
+
+	groups := map[uuid.UUID]*duplicateGroup{}
+	var ordered []*duplicateGroup
+	for _, pair := range pairs {
+		root := find(pair.SourceID)
+		group, ok := groups[root]
+		if !ok {
+			group = &duplicateGroup{}
+			groups[root] = group
+			ordered = append(ordered, group)
+		}
+		if !slices.Contains(group.reasons, pair.Reason) {
+			group.reasons = append(group.reasons, pair.Reason)
+		}
+		group.score = max(group.score, pair.Score)
+	}
+	for id := range parent {
+		group := groups[find(id)]
+		group.ids = append(group.ids, id)
+	}
+	for _, group := range ordered {
+		slices.SortFunc(group.ids, func(a, b uuid.UUID) int { return bytes.Compare(a.Bytes(), b.Bytes()) })
+	}
+	return ordered
+}
@@ -0,0 +1,121 @@
+package sources
+
+import (
+	"github.com/zizouhuweidi/maktaba/internal/metadata"
+	"github.com/zizouhuweidi/maktaba/internal/tags"
+)
+
+// RefreshResult reports what a metadata refresh filled in on a source
+type RefreshResult struct {
+	Source    *Source       `json:"source"`
+	Changes   []FieldChange `json:"changes"`
+	Providers []string      `json:"providers"`
+}
+
+// bookParamsFromRecord prefills a new book from a looked-up record. Subjects
+// that make valid tag names become tags.
+func bookParamsFromRecord(record *metadata.Record) CreateBookParams {
+	return CreateBookParams{
+		Title:        record.Title,
+		Subtitle:     record.Subtitle,
+		Description:  record.Description,
+		DOI:          record.DOI,
+		URL:          record.URL,
+		Tags:         subjectTags(record.Subjects),
+		PublishedAt:  record.PublishedAt,
+		ISBN10:       record.ISBN10,
+		ISBN13:       record.ISBN13,
+		Publisher:    record.Publisher,
+		PageCount:    record.PageCount,
+		Language:     record.Language,
+		CoverURL:     record.CoverURL,
+		Contributors: contributorsFromRecord(record),
+	}
+}
+
+// fillSnapshot copies record fields into the blanks of snapshot and never
+// overwrites anything already set. Book metadata is only added to books.
+func fillSnapshot(snapshot Snapshot, record *metadata.Record) Snapshot {
+	if snapshot.Title == "" {
+		snapshot.Title = record.Title
+	}
+	fillBlank(&snapshot.Subtitle, record.Subtitle)
+	fillBlank(&snapshot.Description, record.Description)
+	fillBlank(&snapshot.Publisher, record.Publisher)
+	fillBlank(&snapshot.ISBN, record.ISBN13)
+	fillBlank(&snapshot.DOI, record.DOI)
+	fillBlank(&snapshot.URL, record.URL)
+	if snapshot.PublishedAt == nil {
+		snapshot.PublishedAt = record.PublishedAt
+	}
+	if len(snapshot.Tags) == 0 {
+		snapshot.Tags = subjectTags(record.Subjects)
+	}
+	if len(snapshot.Contributors) == 0 {
+		snapshot.Contributors = contributorsFromRecord(record)
+	}
+
+	if snapshot.Type == SourceTypeBook {
+		book := SnapshotMetadata{}
+		if snapshot.Metadata != nil {
+			book = *snapshot.Metadata
+		}
+		fillBlank(&book.ISBN10, record.ISBN10)
+		fillBlank(&book.ISBN13, record.ISBN13)
+		fillBlank(&book.Publisher, record.Publisher)
+		fillBlank(&book.Language, record.Language)
+		fillBlank(&book.CoverURL, record.CoverURL)
+		if book.PageCount == nil {
+			book.PageCount = record.PageCount
+		}
+		if book != (SnapshotMetadata{}) {
+			snapshot.Metadata = &book
+		}
+	}
+	return snapshot
+}
+
+// snapshotIdentifiers picks the ISBN and DOI a source can be looked up by,
+// preferring the book metadata ISBNs over the one on the source
+func snapshotIdentifiers(snapshot Snapshot) (isbn, doi string) {
+	candidates := []*string{snapshot.ISBN}
+	if snapshot.Metadata != nil {
+		candidates = []*string{snapshot.Metadata.ISBN13, snapshot.Metadata.ISBN10, snapshot.ISBN}
+	}
+	for _, candidate := range candidates {
+		if candidate != nil && *candidate != "" {
+			isbn = *candidate
+			break
+		}
+	}
+	if snapshot.DOI != nil {
+		doi = *snapshot.DOI
+	}
+	return isbn, doi
+}
+
+func contributorsFromRecord(record *metadata.Record) []ContributorInput {
+	var contributors []ContributorInput
+	for _, contributor := range record.Contributors {
+		contributors = append(contributors, ContributorInput{Name: contributor.Name, Role: contributor.Role})
+	}
+	return contributors
+}
+
+// subjectTags keeps the catalogue subjects that can be saved as tags, dropping
+// the long descriptive headings some providers return
+func subjectTags(subjects []string) []string {
+	var names []string
+	for _, subject := range subjects {
+		if tags.Valid(subject) {
+			names = append(names, subject)
+		}
+	}
+	return names
+}
+
+func fillBlank(dst **string, src *string) {
+	if (*dst == nil || **dst == "") && src != nil {
+		*dst = src
+	}
+}
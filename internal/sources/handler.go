@@ -1,12 +1,17 @@
 package sources
 
 import (
+	"context"
 	"errors"
 	"log/slog"
 	"net/http"
+	"time"
 
+	"github.com/gofrs/uuid/v5"
 	"github.com/labstack/echo/v5"
+	"github.com/zizouhuweidi/maktaba/internal/auth"
 	"github.com/zizouhuweidi/maktaba/internal/echox"
+	"github.com/zizouhuweidi/maktaba/internal/metadata"
 )
 
 type Handler struct {
@@ -48,9 +53,11 @@ type CreateBookRequest struct {
 	Title        string             `json:"title" validate:"required"`
 	Subtitle     *string            `json:"subtitle,omitempty"`
 	Description  *string            `json:"description,omitempty"`
+	DOI          *string            `json:"doi,omitempty"`
 	URL          *string            `json:"url,omitempty"`
 	ExternalID   *string            `json:"external_id,omitempty"`
 	Tags         []string           `json:"tags,omitempty"`
+	PublishedAt  *time.Time         `json:"published_at,omitempty"`
 	ISBN10       *string            `json:"isbn_10,omitempty"`
 	ISBN13       *string            `json:"isbn_13,omitempty"`
 	Publisher    *string            `json:"publisher,omitempty"`
@@ -60,21 +67,94 @@ type CreateBookRequest struct {
 	Contributors []ContributorInput `json:"contributors,omitempty"`
 }
 
+// TypedSourceRequest holds the fields shared by papers, podcasts, videos and
+// articles
+type TypedSourceRequest struct {
+	Title        string             `json:"title" validate:"required"`
+	Subtitle     *string            `json:"subtitle,omitempty"`
+	Description  *string            `json:"description,omitempty"`
+	Publisher    *string            `json:"publisher,omitempty"`
+	DOI          *string            `json:"doi,omitempty"`
+	URL          *string            `json:"url,omitempty"`
+	ExternalID   *string            `json:"external_id,omitempty"`
+	Tags         []string           `json:"tags,omitempty"`
+	PublishedAt  *time.Time         `json:"published_at,omitempty"`
+	Contributors []ContributorInput `json:"contributors,omitempty"`
+}
+
+type CreatePaperRequest struct {
+	TypedSourceRequest
+	Venue   *string `json:"venue,omitempty"`
+	ArxivID *string `json:"arxiv_id,omitempty"`
+}
+
+type CreatePodcastRequest struct {
+	TypedSourceRequest
+	Show            string  `json:"show" validate:"required"`
+	EpisodeNumber   *int    `json:"episode_number,omitempty"`
+	DurationSeconds *int    `json:"duration_seconds,omitempty"`
+	FeedURL         *string `json:"feed_url,omitempty"`
+}
+
+type CreateVideoRequest struct {
+	TypedSourceRequest
+	Channel         *string `json:"channel,omitempty"`
+	DurationSeconds *int    `json:"duration_seconds,omitempty"`
+	Platform        *string `json:"platform,omitempty"`
+	PlatformID      *string `json:"platform_id,omitempty"`
+}
+
+type CreateArticleRequest struct {
+	TypedSourceRequest
+	Site      *string `json:"site,omitempty"`
+	WordCount *int    `json:"word_count,omitempty"`
+}
+
+type MergeRequest struct {
+	DuplicateID string `json:"duplicate_id" validate:"required"`
+}
+
+type ReviewProposalRequest struct {
+	Note *string `json:"note,omitempty"`
+}
+
 func (h *Handler) RegisterPublicRoutes(e *echo.Echo) {
 	e.GET("/sources", h.List)
 	e.GET("/sources/search", h.Search)
 	e.GET("/sources/books/:id", h.GetBookByID)
 	e.GET("/sources/:id", h.GetByID)
+	e.GET("/sources/:id/related", h.ListRelated)
+	e.GET("/sources/:id/revisions", h.ListRevisions)
+	e.GET("/sources/:id/revisions/:revision", h.GetRevision)
+	e.GET("/sources/:id/diff", h.CompareRevisions)
+	e.GET("/tags/:slug/sources", h.ListByTag)
 }
 
 func (h *Handler) RegisterProtectedRoutes(g *echo.Group) {
 	g.POST("/sources", h.Create)
 	g.POST("/sources/books", h.CreateBook)
+	g.POST("/sources/papers", h.CreatePaper)
+	g.POST("/sources/podcasts", h.CreatePodcast)
+	g.POST("/sources/videos", h.CreateVideo)
+	g.POST("/sources/articles", h.CreateArticle)
+	g.GET("/sources/lookup", h.Lookup)
 	g.PUT("/sources/:id", h.Update)
 	g.DELETE("/sources/:id", h.Delete)
+	g.POST("/sources/:id/refresh", h.Refresh)
+	g.GET("/sources/proposals", h.ListProposals)
+	g.POST("/sources/proposals/:id/approve", h.ApproveProposal, auth.RequireRole(auth.RoleEditor, auth.RoleAdmin))
+	g.POST("/sources/proposals/:id/reject", h.RejectProposal, auth.RequireRole(auth.RoleEditor, auth.RoleAdmin))
+	g.POST("/sources/:id/revisions/:revision/revert", h.Revert, auth.RequireRole(auth.RoleAdmin))
+	g.GET("/sources/duplicates", h.ListDuplicates, auth.RequireRole(auth.RoleEditor, auth.RoleAdmin))
+	g.POST("/sources/:id/merge", h.Merge, auth.RequireRole(auth.RoleEditor, auth.RoleAdmin))
 }
 
 func (h *Handler) Create(c *echo.Context) error {
+	userID, ok := auth.UserID(c)
+	if !ok {
+		return echo.NewHTTPError(http.StatusUnauthorized, "authentication required")
+	}
+
 	var req CreateRequest
 	if err := echox.BindAndValidate(c, &req); err != nil {
 		return err
@@ -91,6 +171,7 @@ func (h *Handler) Create(c *echo.Context) error {
 		URL:         req.URL,
 		ExternalID:  req.ExternalID,
 		Tags:        req.Tags,
+		CreatedBy:   userID,
 	})
 	if errors.Is(err, ErrInvalidSource) {
 		return echo.NewHTTPError(http.StatusBadRequest, "invalid source")
@@ -103,6 +184,11 @@ func (h *Handler) Create(c *echo.Context) error {
 }
 
 func (h *Handler) CreateBook(c *echo.Context) error {
+	userID, ok := auth.UserID(c)
+	if !ok {
+		return echo.NewHTTPError(http.StatusUnauthorized, "authentication required")
+	}
+
 	var req CreateBookRequest
 	if err := echox.BindAndValidate(c, &req); err != nil {
 		return err
@@ -112,9 +198,11 @@ func (h *Handler) CreateBook(c *echo.Context) error {
 		Title:        req.Title,
 		Subtitle:     req.Subtitle,
 		Description:  req.Description,
+		DOI:          req.DOI,
 		URL:          req.URL,
 		ExternalID:   req.ExternalID,
 		Tags:         req.Tags,
+		PublishedAt:  req.PublishedAt,
 		ISBN10:       req.ISBN10,
 		ISBN13:       req.ISBN13,
 		Publisher:    req.Publisher,
@@ -122,10 +210,14 @@ func (h *Handler) CreateBook(c *echo.Context) error {
 		Language:     req.Language,
 		CoverURL:     req.CoverURL,
 		Contributors: req.Contributors,
+		CreatedBy:    userID,
 	})
 	if errors.Is(err, ErrInvalidSource) {
 		return echo.NewHTTPError(http.StatusBadRequest, "invalid book")
 	}
+	if errors.Is(err, ErrDuplicateSource) {
+		return echo.NewHTTPError(http.StatusConflict, "a book with this ISBN already exists")
+	}
 	if err != nil {
 		return echo.NewHTTPError(http.StatusInternalServerError, "failed to create book")
 	}
@@ -133,24 +225,105 @@ func (h *Handler) CreateBook(c *echo.Context) error {
 	return c.JSON(http.StatusCreated, book)
 }
 
+func (h *Handler) CreatePaper(c *echo.Context) error {
+	var req CreatePaperRequest
+	if err := echox.BindAndValidate(c, &req); err != nil {
+		return err
+	}
+	typed := TypedMetadata{}
+	if req.Venue != nil || req.ArxivID != nil {
+		typed.Paper = &PaperMetadata{Venue: req.Venue, ArxivID: req.ArxivID}
+	}
+	return h.createTyped(c, SourceTypePaper, req.TypedSourceRequest, typed)
+}
+
+func (h *Handler) CreatePodcast(c *echo.Context) error {
+	var req CreatePodcastRequest
+	if err := echox.BindAndValidate(c, &req); err != nil {
+		return err
+	}
+	return h.createTyped(c, SourceTypePodcast, req.TypedSourceRequest, TypedMetadata{Podcast: &PodcastMetadata{
+		Show:            req.Show,
+		EpisodeNumber:   req.EpisodeNumber,
+		DurationSeconds: req.DurationSeconds,
+		FeedURL:         req.FeedURL,
+	}})
+}
+
+func (h *Handler) CreateVideo(c *echo.Context) error {
+	var req CreateVideoRequest
+	if err := echox.BindAndValidate(c, &req); err != nil {
+		return err
+	}
+	typed := TypedMetadata{}
+	if req.Channel != nil || req.DurationSeconds != nil || req.Platform != nil || req.PlatformID != nil {
+		typed.Video = &VideoMetadata{Channel: req.Channel, DurationSeconds: req.DurationSeconds, Platform: req.Platform, PlatformID: req.PlatformID}
+	}
+	return h.createTyped(c, SourceTypeVideo, req.TypedSourceRequest, typed)
+}
+
+func (h *Handler) CreateArticle(c *echo.Context) error {
+	var req CreateArticleRequest
+	if err := echox.BindAndValidate(c, &req); err != nil {
+		return err
+	}
+	typed := TypedMetadata{}
+	if req.Site != nil || req.WordCount != nil {
+		typed.Article = &ArticleMetadata{Site: req.Site, WordCount: req.WordCount}
+	}
+	return h.createTyped(c, SourceTypeArticle, req.TypedSourceRequest, typed)
+}
+
+func (h *Handler) createTyped(c *echo.Context, sourceType SourceType, req TypedSourceRequest, typed TypedMetadata) error {
+	userID, ok := auth.UserID(c)
+	if !ok {
+		return echo.NewHTTPError(http.StatusUnauthorized, "authentication required")
+	}
+
+	detail, err := h.service.CreateTyped(c.Request().Context(), CreateTypedParams{
+		Source: CreateSourceParams{
+			Title:       req.Title,
+			Subtitle:    req.Subtitle,
+			Type:        sourceType,
+			Description: req.Description,
+			Publisher:   req.Publisher,
+			DOI:         req.DOI,
+			URL:         req.URL,
+			ExternalID:  req.ExternalID,
+			Tags:        req.Tags,
+			PublishedAt: req.PublishedAt,
+			CreatedBy:   userID,
+		},
+		Typed:        typed,
+		Contributors: req.Contributors,
+	})
+	if errors.Is(err, ErrInvalidSource) {
+		return echo.NewHTTPError(http.StatusBadRequest, "invalid "+string(sourceType))
+	}
+	if err != nil {
+		return echo.NewHTTPError(http.StatusInternalServerError, "failed to create "+string(sourceType))
+	}
+
+	return c.JSON(http.StatusCreated, detail)
+}
+
+// GetByID returns a source with its contributors and the metadata for its
+// type
 func (h *Handler) GetByID(c *echo.Context) error {
 	id, err := echox.ParamUUID(c, "id", "source ID")
 	if err != nil {
 		return err
 	}
 
-	source, err := h.service.GetByID(c.Request().Context(), id)
+	detail, err := h.service.GetDetail(c.Request().Context(), id)
 	if errors.Is(err, ErrSourceNotFound) {
 		return echo.NewHTTPError(http.StatusNotFound, "source not found")
 	}
-	if errors.Is(err, ErrInvalidSource) {
-		return echo.NewHTTPError(http.StatusBadRequest, "invalid source")
-	}
 	if err != nil {
 		return echo.NewHTTPError(http.StatusInternalServerError, "failed to get source")
 	}
 
-	return c.JSON(http.StatusOK, source)
+	return c.JSON(http.StatusOK, detail)
 }
 
 func (h *Handler) GetBookByID(c *echo.Context) error {
@@ -171,16 +344,36 @@ func (h *Handler) GetBookByID(c *echo.Context) error {
 }
 
 func (h *Handler) List(c *echo.Context) error {
-	limit, offset := echox.Pagination(c)
-	sourceType := c.QueryParam("type")
+	page, err := echox.PageParams(c)
+	if err != nil {
+		return err
+	}
+	filter := ListFilter{Tag: echox.QueryString(c, "tag")}
+	if value := echox.QueryString(c, "type"); value != nil {
+		sourceType := SourceType(*value)
+		filter.Type = &sourceType
+	}
+
+	sources, err := h.service.List(c.Request().Context(), filter, page)
+	if errors.Is(err, ErrInvalidFilter) {
+		return echo.NewHTTPError(http.StatusBadRequest, "invalid source type")
+	}
+	if err != nil {
+		return echo.NewHTTPError(http.StatusInternalServerError, "failed to list sources")
+	}
 
-	var sources []*Source
-	var err error
-	if sourceType != "" {
-		sources, err = h.service.ListByType(c.Request().Context(), SourceType(sourceType), limit, offset)
-	} else {
-		sources, err = h.service.List(c.Request().Context(), limit, offset)
+	return c.JSON(http.StatusOK, sources)
+}
+
+// ListByTag pages through the sources carrying the tag in the path
+func (h *Handler) ListByTag(c *echo.Context) error {
+	page, err := echox.PageParams(c)
+	if err != nil {
+		return err
 	}
+	tag := c.Param("slug")
+
+	sources, err := h.service.List(c.Request().Context(), ListFilter{Tag: &tag}, page)
 	if err != nil {
 		return echo.NewHTTPError(http.StatusInternalServerError, "failed to list sources")
 	}
@@ -189,21 +382,65 @@ func (h *Handler) List(c *echo.Context) error {
 }
 
 func (h *Handler) Search(c *echo.Context) error {
-	query := c.QueryParam("q")
-	if query == "" {
+	query := echox.QueryString(c, "q")
+	if query == nil {
 		return echo.NewHTTPError(http.StatusBadRequest, "search query required")
 	}
+	year, err := echox.QueryInt(c, "year", "year")
+	if err != nil {
+		return err
+	}
+	var sourceType *SourceType
+	if value := echox.QueryString(c, "type"); value != nil {
+		st := SourceType(*value)
+		sourceType = &st
+	}
 
 	limit, offset := echox.Pagination(c)
-	sources, err := h.service.Search(c.Request().Context(), query, limit, offset)
+	results, err := h.service.Search(c.Request().Context(), SearchParams{
+		Query:    *query,
+		Type:     sourceType,
+		Tag:      echox.QueryString(c, "tag"),
+		Language: echox.QueryString(c, "language"),
+		Year:     year,
+		Limit:    limit,
+		Offset:   offset,
+	})
+	if errors.Is(err, ErrInvalidSearch) {
+		return echo.NewHTTPError(http.StatusBadRequest, "invalid search filters")
+	}
 	if err != nil {
 		return echo.NewHTTPError(http.StatusInternalServerError, "failed to search sources")
 	}
 
+	return c.JSON(http.StatusOK, results)
+}
+
+func (h *Handler) ListRelated(c *echo.Context) error {
+	id, err := echox.ParamUUID(c, "id", "source ID")
+	if err != nil {
+		return err
+	}
+
+	limit, _ := echox.Pagination(c)
+	sources, err := h.service.ListRelated(c.Request().Context(), id, limit)
+	if errors.Is(err, ErrSourceNotFound) {
+		return echo.NewHTTPError(http.StatusNotFound, "source not found")
+	}
+	if err != nil {
+		return echo.NewHTTPError(http.StatusInternalServerError, "failed to find related sources")
+	}
+
 	return c.JSON(http.StatusOK, sources)
 }
 
+// Update edits a source. Edits by anyone other than the creator or a
+// moderator are stored as a proposal and answered with 202 Accepted.
 func (h *Handler) Update(c *echo.Context) error {
+	actor, err := currentActor(c)
+	if err != nil {
+		return err
+	}
 	id, err := echox.ParamUUID(c, "id", "source ID")
 	if err != nil {
 		return err
@@ -220,7 +457,7 @@ func (h *Handler) Update(c *echo.Context) error {
 		sourceType = &st
 	}
 
-	source, err := h.service.Update(c.Request().Context(), id, UpdateSourceParams{
+	source, proposal, err := h.service.Update(c.Request().Context(), id, actor, UpdateSourceParams{
 		Title:       req.Title,
 		Subtitle:    req.Subtitle,
 		Type:        sourceType,
@@ -235,20 +472,340 @@ func (h *Handler) Update(c *echo.Context) error {
 	if errors.Is(err, ErrSourceNotFound) {
 		return echo.NewHTTPError(http.StatusNotFound, "source not found")
 	}
+	if errors.Is(err, ErrInvalidSource) {
+		return echo.NewHTTPError(http.StatusBadRequest, "invalid source")
+	}
 	if err != nil {
 		return echo.NewHTTPError(http.StatusInternalServerError, "failed to update source")
 	}
+	if proposal != nil {
+		return c.JSON(http.StatusAccepted, proposal)
+	}
 
 	return c.JSON(http.StatusOK, source)
 }
 
 func (h *Handler) Delete(c *echo.Context) error {
+	actor, err := currentActor(c)
+	if err != nil {
+		return err
+	}
 	id, err := echox.ParamUUID(c, "id", "source ID")
 	if err != nil {
 		return err
 	}
-	if err := h.service.Delete(c.Request().Context(), id); err != nil {
+
+	err = h.service.Delete(c.Request().Context(), id, actor)
+	if errors.Is(err, ErrSourceNotFound) {
+		return echo.NewHTTPError(http.StatusNotFound, "source not found")
+	}
+	if errors.Is(err, ErrSourceForbidden) {
+		return echo.NewHTTPError(http.StatusForbidden, "only the creator or an editor can delete this source")
+	}
+	if errors.Is(err, ErrSourceInUse) {
+		return echo.NewHTTPError(http.StatusConflict, "source is used by other readers; ask an editor to delete it")
+	}
+	if err != nil {
 		return echo.NewHTTPError(http.StatusInternalServerError, "failed to delete source")
 	}
 	return c.NoContent(http.StatusNoContent)
 }
+
+func (h *Handler) ListProposals(c *echo.Context) error {
+	actor, err := currentActor(c)
+	if err != nil {
+		return err
+	}
+
+	params := ListProposalsParams{}
+	params.Limit, params.Offset = echox.Pagination(c)
+	if value := echox.QueryString(c, "status"); value != nil {
+		status := ProposalStatus(*value)
+		params.Status = &status
+	}
+	if params.SourceID, err = echox.OptionalUUID(echox.QueryString(c, "source_id"), "source ID"); err != nil {
+		return err
+	}
+
+	proposals, err := h.service.ListProposals(c.Request().Context(), actor, params)
+	if errors.Is(err, ErrInvalidSource) {
+		return echo.NewHTTPError(http.StatusBadRequest, "status must be pending, approved, or rejected")
+	}
+	if err != nil {
+		return echo.NewHTTPError(http.StatusInternalServerError, "failed to list proposals")
+	}
+
+	return c.JSON(http.StatusOK, proposals)
+}
+
+func (h *Handler) ApproveProposal(c *echo.Context) error {
+	return h.reviewProposal(c, h.service.ApproveProposal)
+}
+
+func (h *Handler) RejectProposal(c *echo.Context) error {
+	return h.reviewProposal(c, h.service.RejectProposal)
+}
+
+func (h *Handler) reviewProposal(c *echo.Context, review func(context.Context, uuid.UUID, Actor, *string) (*Proposal, error)) error {
+	actor, err := currentActor(c)
+	if err != nil {
+		return err
+	}
+	id, err := echox.ParamUUID(c, "id", "proposal ID")
+	if err != nil {
+		return err
+	}
+
+	var req ReviewProposalRequest
+	if err := echox.BindAndValidate(c, &req); err != nil {
+		return err
+	}
+
+	proposal, err := review(c.Request().Context(), id, actor, req.Note)
+	if errors.Is(err, ErrProposalNotFound) || errors.Is(err, ErrSourceNotFound) {
+		return echo.NewHTTPError(http.StatusNotFound, "proposal not found")
+	}
+	if errors.Is(err, ErrSourceForbidden) {
+		return echo.NewHTTPError(http.StatusForbidden, "insufficient role")
+	}
+	if errors.Is(err, ErrProposalReviewed) {
+		return echo.NewHTTPError(http.StatusConflict, "proposal already reviewed")
+	}
+	if errors.Is(err, ErrInvalidSource) {
+		return echo.NewHTTPError(http.StatusUnprocessableEntity, "proposal no longer applies cleanly")
+	}
+	if err != nil {
+		return echo.NewHTTPError(http.StatusInternalServerError, "failed to review proposal")
+	}
+
+	return c.JSON(http.StatusOK, proposal)
+}
+
+func (h *Handler) ListRevisions(c *echo.Context) error {
+	id, err := echox.ParamUUID(c, "id", "source ID")
+	if err != nil {
+		return err
+	}
+
+	limit, offset := echox.Pagination(c)
+	revisions, err := h.service.ListRevisions(c.Request().Context(), id, limit, offset)
+	if errors.Is(err, ErrSourceNotFound) {
+		return echo.NewHTTPError(http.StatusNotFound, "source not found")
+	}
+	if err != nil {
+		return echo.NewHTTPError(http.StatusInternalServerError, "failed to list revisions")
+	}
+
+	return c.JSON(http.StatusOK, revisions)
+}
+
+func (h *Handler) GetRevision(c *echo.Context) error {
+	id, err := echox.ParamUUID(c, "id", "source ID")
+	if err != nil {
+		return err
+	}
+	revision, err := echox.ParamInt(c, "revision", "revision")
+	if err != nil {
+		return err
+	}
+
+	found, err := h.service.GetRevision(c.Request().Context(), id, revision)
+	if errors.Is(err, ErrRevisionNotFound) {
+		return echo.NewHTTPError(http.StatusNotFound, "revision not found")
+	}
+	if err != nil {
+		return echo.NewHTTPError(http.StatusInternalServerError, "failed to get revision")
+	}
+
+	return c.JSON(http.StatusOK, found)
+}
+
+// CompareRevisions diffs two revisions given as the from and to query
+// parameters
+func (h *Handler) CompareRevisions(c *echo.Context) error {
+	id, err := echox.ParamUUID(c, "id", "source ID")
+	if err != nil {
+		return err
+	}
+	from, err := echox.QueryInt(c, "from", "from revision")
+	if err != nil {
+		return err
+	}
+	to, err := echox.QueryInt(c, "to", "to revision")
+	if err != nil {
+		return err
+	}
+	if from == nil || to == nil {
+		return echo.NewHTTPError(http.StatusBadRequest, "from and to revisions required")
+	}
+
+	changes, err := h.service.CompareRevisions(c.Request().Context(), id, *from, *to)
+	if errors.Is(err, ErrRevisionNotFound) {
+		return echo.NewHTTPError(http.StatusNotFound, "revision not found")
+	}
+	if err != nil {
+		return echo.NewHTTPError(http.StatusInternalServerError, "failed to compare revisions")
+	}
+
+	return c.JSON(http.StatusOK, changes)
+}
+
+func (h *Handler) Revert(c *echo.Context) error {
+	actor, err := currentActor(c)
+	if err != nil {
+		return err
+	}
+	id, err := echox.ParamUUID(c, "id", "source ID")
+	if err != nil {
+		return err
+	}
+	revision, err := echox.ParamInt(c, "revision", "revision")
+	if err != nil {
+		return err
+	}
+
+	source, err := h.service.Revert(c.Request().Context(), id, revision, actor)
+	if errors.Is(err, ErrSourceNotFound) || errors.Is(err, ErrRevisionNotFound) {
+		return echo.NewHTTPError(http.StatusNotFound, "revision not found")
+	}
+	if errors.Is(err, ErrSourceForbidden) {
+		return echo.NewHTTPError(http.StatusForbidden, "insufficient role")
+	}
+	if err != nil {
+		return echo.NewHTTPError(http.StatusInternalServerError, "failed to revert source")
+	}
+
+	return c.JSON(http.StatusOK, source)
+}
+
+func (h *Handler) ListDuplicates(c *echo.Context) error {
+	actor, err := currentActor(c)
+	if err != nil {
+		return err
+	}
+
+	limit, _ := echox.Pagination(c)
+	clusters, err := h.service.FindDuplicates(c.Request().Context(), actor, limit)
+	if errors.Is(err, ErrSourceForbidden) {
+		return echo.NewHTTPError(http.StatusForbidden, "insufficient role")
+	}
+	if err != nil {
+		return echo.NewHTTPError(http.StatusInternalServerError, "failed to find duplicates")
+	}
+
+	return c.JSON(http.StatusOK, clusters)
+}
+
+// Merge folds the source named in the body into the source in the path
+func (h *Handler) Merge(c *echo.Context) error {
+	actor, err := currentActor(c)
+	if err != nil {
+		return err
+	}
+	id, err := echox.ParamUUID(c, "id", "source ID")
+	if err != nil {
+		return err
+	}
+
+	var req MergeRequest
+	if err := echox.BindAndValidate(c, &req); err != nil {
+		return err
+	}
+	duplicateID, err := uuid.FromString(req.DuplicateID)
+	if err != nil {
+		return echo.NewHTTPError(http.StatusBadRequest, "invalid duplicate_id")
+	}
+
+	result, err := h.service.Merge(c.Request().Context(), id, duplicateID, actor)
+	if errors.Is(err, ErrSourceNotFound) {
+		return echo.NewHTTPError(http.StatusNotFound, "source not found")
+	}
+	if errors.Is(err, ErrSourceForbidden) {
+		return echo.NewHTTPError(http.StatusForbidden, "insufficient role")
+	}
+	if errors.Is(err, ErrInvalidMerge) {
+		return echo.NewHTTPError(http.StatusBadRequest, "sources must be different and of the same type")
+	}
+	if err != nil {
+		return echo.NewHTTPError(http.StatusInternalServerError, "failed to merge sources")
+	}
+
+	return c.JSON(http.StatusOK, result)
+}
+
+// Lookup answers with a book prefilled from external catalogues, shaped so it
+// can be edited and posted back to create the book
+func (h *Handler) Lookup(c *echo.Context) error {
+	params, err := h.service.Lookup(c.Request().Context(), c.QueryParam("isbn"), c.QueryParam("doi"))
+	if err != nil {
+		return metadataError(err, "failed to look up metadata")
+	}
+
+	return c.JSON(http.StatusOK, CreateBookRequest{
+		Title:        params.Title,
+		Subtitle:     params.Subtitle,
+		Description:  params.Description,
+		DOI:          params.DOI,
+		URL:          params.URL,
+		Tags:         params.Tags,
+		PublishedAt:  params.PublishedAt,
+		ISBN10:       params.ISBN10,
+		ISBN13:       params.ISBN13,
+		Publisher:    params.Publisher,
+		PageCount:    params.PageCount,
+		Language:     params.Language,
+		CoverURL:     params.CoverURL,
+		Contributors: params.Contributors,
+	})
+}
+
+func (h *Handler) Refresh(c *echo.Context) error {
+	actor, err := currentActor(c)
+	if err != nil {
+		return err
+	}
+	id, err := echox.ParamUUID(c, "id", "source ID")
+	if err != nil {
+		return err
+	}
+
+	result, err := h.service.Refresh(c.Request().Context(), id, actor)
+	if errors.Is(err, ErrSourceNotFound) {
+		return echo.NewHTTPError(http.StatusNotFound, "source not found")
+	}
+	if errors.Is(err, ErrSourceForbidden) {
+		return echo.NewHTTPError(http.StatusForbidden, "only the creator or an editor can refresh this source")
+	}
+	if err != nil {
+		return metadataError(err, "failed to refresh source")
+	}
+
+	return c.JSON(http.StatusOK, result)
+}
+
+// metadataError maps lookup failures to responses
+func metadataError(err error, message string) error {
+	switch {
+	case errors.Is(err, ErrNoIdentifier):
+		return echo.NewHTTPError(http.StatusBadRequest, "an ISBN or a DOI is required")
+	case errors.Is(err, metadata.ErrInvalidISBN):
+		return echo.NewHTTPError(http.StatusBadRequest, "invalid ISBN")
+	case errors.Is(err, metadata.ErrInvalidDOI):
+		return echo.NewHTTPError(http.StatusBadRequest, "invalid DOI")
+	case errors.Is(err, metadata.ErrNotFound):
+		return echo.NewHTTPError(http.StatusNotFound, "no metadata found")
+	case errors.Is(err, ErrLookupFailed):
+		return echo.NewHTTPError(http.StatusBadGateway, "metadata providers unavailable")
+	default:
+		return echo.NewHTTPError(http.StatusInternalServerError, message)
+	}
+}
+
+func currentActor(c *echo.Context) (Actor, error) {
+	userID, ok := auth.UserID(c)
+	if !ok {
+		return Actor{}, echo.NewHTTPError(http.StatusUnauthorized, "authentication required")
+	}
+	role := auth.UserRole(c)
+	return Actor{UserID: userID, Moderator: role.CanModerate(), Admin: role == auth.RoleAdmin}, nil
+}
@@ -4,12 +4,16 @@ import (
 	"context"
 	"encoding/json"
 	"errors"
+	"strconv"
 
 	"github.com/gofrs/uuid/v5"
 	"github.com/jackc/pgx/v5"
 	"github.com/jackc/pgx/v5/pgtype"
 	"github.com/zizouhuweidi/maktaba/internal/db"
 	"github.com/zizouhuweidi/maktaba/internal/db/dbgen"
+	"github.com/zizouhuweidi/maktaba/internal/echox"
+	"github.com/zizouhuweidi/maktaba/internal/outbox"
+	"github.com/zizouhuweidi/maktaba/internal/tags"
 )
 
 type postgresRepository struct {
@@ -22,36 +26,33 @@ func NewPostgresRepository(d *db.DB) Repository {
 }
 
 func (r *postgresRepository) Create(ctx context.Context, s *Source) (*Source, error) {
-	id, err := uuid.NewV7()
+	tx, err := r.db.Begin(ctx)
 	if err != nil {
 		return nil, err
 	}
-	tags, err := json.Marshal(s.Tags)
+	defer tx.Rollback(ctx)
+	qtx := r.queries.WithTx(tx)
+
+	created, err := insertSource(ctx, qtx, s)
 	if err != nil {
 		return nil, err
 	}
+	if _, err := recordRevision(ctx, qtx, nil, created.ID, revisionEdit{Action: RevisionActionCreate, EditedBy: created.CreatedBy}); err != nil {
+		return nil, err
+	}
+	if err := outbox.Record(ctx, qtx, AggregateSource, created.ID, EventSourceCreated, created); err != nil {
+		return nil, err
+	}
 
-	row, err := r.queries.CreateSource(ctx, dbgen.CreateSourceParams{
-		ID:          db.PGUUID(id),
-		Title:       s.Title,
-		Subtitle:    db.PGText(s.Subtitle),
-		Type:        string(s.Type),
-		Description: db.PGText(s.Description),
-		Publisher:   db.PGText(s.Publisher),
-		Isbn:        db.PGText(s.ISBN),
-		Doi:         db.PGText(s.DOI),
-		Url:         db.PGText(s.URL),
-		ExternalID:  db.PGText(s.ExternalID),
-		Tags:        tags,
-		PublishedAt: db.PGTimestamptzPtr(s.PublishedAt),
-	})
-	if err != nil {
+	if err := tx.Commit(ctx); err != nil {
 		return nil, err
 	}
-	return mapSource(row), nil
+	return created, nil
 }
 
-func (r *postgresRepository) CreateBook(ctx context.Context, params CreateBookParams) (*Book, error) {
+// CreateTyped creates a paper, podcast, video or article together with its
+// typed metadata and contributors
+func (r *postgresRepository) CreateTyped(ctx context.Context, s *Source, typed TypedMetadata, inputs []ContributorInput) (*SourceDetail, error) {
 	tx, err := r.db.Begin(ctx)
 	if err != nil {
 		return nil, err
@@ -59,11 +60,41 @@ func (r *postgresRepository) CreateBook(ctx context.Context, params CreateBookPa
 	defer tx.Rollback(ctx)
 	qtx := r.queries.WithTx(tx)
 
-	sourceID, err := uuid.NewV7()
+	created, err := insertSource(ctx, qtx, s)
+	if err != nil {
+		return nil, err
+	}
+	if err := saveTypedMetadata(ctx, qtx, created.ID, typed); err != nil {
+		return nil, err
+	}
+	contributors, err := insertContributors(ctx, qtx, created.ID, inputs)
+	if err != nil {
+		return nil, err
+	}
+
+	detail := &SourceDetail{Source: created, Metadata: typed.of(created.Type), Contributors: contributors}
+	if _, err := recordRevision(ctx, qtx, nil, created.ID, revisionEdit{Action: RevisionActionCreate, EditedBy: created.CreatedBy}); err != nil {
+		return nil, err
+	}
+	if err := outbox.Record(ctx, qtx, AggregateSource, created.ID, EventSourceCreated, detail); err != nil {
+		return nil, err
+	}
+
+	if err := tx.Commit(ctx); err != nil {
+		return nil, err
+	}
+	return detail, nil
+}
+
+func (r *postgresRepository) CreateBook(ctx context.Context, params CreateBookParams) (*Book, error) {
+	tx, err := r.db.Begin(ctx)
 	if err != nil {
 		return nil, err
 	}
-	tags, err := json.Marshal(params.Tags)
+	defer tx.Rollback(ctx)
+	qtx := r.queries.WithTx(tx)
+
+	sourceID, err := uuid.NewV7()
 	if err != nil {
 		return nil, err
 	}
@@ -76,14 +107,19 @@ func (r *postgresRepository) CreateBook(ctx context.Context, params CreateBookPa
 		Publisher:   db.PGText(params.Publisher),
 		Column6:     db.PGText(params.ISBN13),
 		Column7:     db.PGText(params.ISBN10),
+		Doi:         db.PGText(params.DOI),
 		Url:         db.PGText(params.URL),
 		ExternalID:  db.PGText(params.ExternalID),
-		Tags:        tags,
 		PublishedAt: db.PGTimestamptzPtr(params.PublishedAt),
+		CreatedBy:   db.PGUUID(params.CreatedBy),
 	})
 	if err != nil {
 		return nil, err
 	}
+	source := mapSource(sourceRow)
+	if err := saveTags(ctx, qtx, source, params.Tags); err != nil {
+		return nil, err
+	}
 
 	metadataRow, err := qtx.InsertBookMetadata(ctx, dbgen.InsertBookMetadataParams{
 		SourceID:  db.PGUUID(sourceID),
@@ -98,37 +134,23 @@ func (r *postgresRepository) CreateBook(ctx context.Context, params CreateBookPa
 		return nil, err
 	}
 
-	contributors := make([]*Contributor, 0, len(params.Contributors))
-	for position, contributor := range params.Contributors {
-		role := contributor.Role
-		if role == "" {
-			role = "author"
-		}
-		contributorID, err := uuid.NewV7()
-		if err != nil {
-			return nil, err
-		}
-		existingID, err := qtx.UpsertContributor(ctx, dbgen.UpsertContributorParams{ID: db.PGUUID(contributorID), Name: contributor.Name})
-		if err != nil {
-			return nil, err
-		}
-		row, err := qtx.InsertSourceContributor(ctx, dbgen.InsertSourceContributorParams{
-			SourceID:        db.PGUUID(sourceID),
-			ContributorID:   existingID,
-			Role:            role,
-			Position:        int32(position),
-			ContributorName: contributor.Name,
-		})
-		if err != nil {
-			return nil, err
-		}
-		contributors = append(contributors, mapInsertedContributor(row))
+	contributors, err := insertContributors(ctx, qtx, sourceID, params.Contributors)
+	if err != nil {
+		return nil, err
+	}
+
+	book := &Book{Source: source, Metadata: mapBookMetadata(metadataRow), Contributors: contributors}
+	if _, err := recordRevision(ctx, qtx, nil, sourceID, revisionEdit{Action: RevisionActionCreate, EditedBy: book.Source.CreatedBy}); err != nil {
+		return nil, err
+	}
+	if err := outbox.Record(ctx, qtx, AggregateSource, sourceID, EventSourceCreated, book); err != nil {
+		return nil, err
 	}
 
 	if err := tx.Commit(ctx); err != nil {
 		return nil, err
 	}
-	return &Book{Source: mapSource(sourceRow), Metadata: mapBookMetadata(metadataRow), Contributors: contributors}, nil
+	return book, nil
 }
 
 func (r *postgresRepository) GetByID(ctx context.Context, id uuid.UUID) (*Source, error) {
@@ -139,7 +161,11 @@ func (r *postgresRepository) GetByID(ctx context.Context, id uuid.UUID) (*Source
 	if err != nil {
 		return nil, err
 	}
-	return mapSource(row), nil
+	source := mapSource(row)
+	if err := attachTags(ctx, r.queries, source); err != nil {
+		return nil, err
+	}
+	return source, nil
 }
 
 func (r *postgresRepository) GetBookByID(ctx context.Context, id uuid.UUID) (*Book, error) {
@@ -150,10 +176,14 @@ func (r *postgresRepository) GetBookByID(ctx context.Context, id uuid.UUID) (*Bo
 	if err != nil {
 		return nil, err
 	}
+	source := mapSource(sourceRow)
+	if err := attachTags(ctx, r.queries, source); err != nil {
+		return nil, err
+	}
 
 	metadataRow, err := r.queries.GetBookMetadata(ctx, db.PGUUID(id))
 	if errors.Is(err, pgx.ErrNoRows) {
-		return &Book{Source: mapSource(sourceRow)}, nil
+		return &Book{Source: source}, nil
 	}
 	if err != nil {
 		return nil, err
@@ -162,109 +192,1093 @@ func (r *postgresRepository) GetBookByID(ctx context.Context, id uuid.UUID) (*Bo
 	if err != nil {
 		return nil, err
 	}
-	return &Book{Source: mapSource(sourceRow), Metadata: mapBookMetadata(metadataRow), Contributors: contributors}, nil
+	return &Book{Source: source, Metadata: mapBookMetadata(metadataRow), Contributors: contributors}, nil
 }
 
-func (r *postgresRepository) List(ctx context.Context, limit, offset int) ([]*Source, error) {
-	rows, err := r.queries.ListSources(ctx, dbgen.ListSourcesParams{Limit: int32(limit), Offset: int32(offset)})
+// GetDetail retrieves a source with its contributors and the metadata for its
+// type. It returns nil when the source does not exist.
+func (r *postgresRepository) GetDetail(ctx context.Context, id uuid.UUID) (*SourceDetail, error) {
+	row, err := r.queries.GetSourceByID(ctx, db.PGUUID(id))
+	if errors.Is(err, pgx.ErrNoRows) {
+		return nil, nil
+	}
 	if err != nil {
 		return nil, err
 	}
-	return mapSources(rows), nil
+	detail := &SourceDetail{Source: mapSource(row)}
+	if err := attachTags(ctx, r.queries, detail.Source); err != nil {
+		return nil, err
+	}
+
+	if detail.Type == SourceTypeBook {
+		metadataRow, err := r.queries.GetBookMetadata(ctx, db.PGUUID(id))
+		if err == nil {
+			detail.Metadata = mapBookMetadata(metadataRow)
+		} else if !errors.Is(err, pgx.ErrNoRows) {
+			return nil, err
+		}
+	} else {
+		typed, err := loadTypedMetadata(ctx, r.queries, id, detail.Type)
+		if err != nil {
+			return nil, err
+		}
+		detail.Metadata = typed.of(detail.Type)
+	}
+
+	if detail.Contributors, err = r.listContributors(ctx, id); err != nil {
+		return nil, err
+	}
+	return detail, nil
 }
 
-func (r *postgresRepository) ListByType(ctx context.Context, sourceType SourceType, limit, offset int) ([]*Source, error) {
-	rows, err := r.queries.ListSourcesByType(ctx, dbgen.ListSourcesByTypeParams{Type: string(sourceType), Limit: int32(limit), Offset: int32(offset)})
+func (r *postgresRepository) FindBookByISBN(ctx context.Context, isbn10, isbn13 *string) (*Source, error) {
+	row, err := r.queries.FindBookByISBN(ctx, dbgen.FindBookByISBNParams{Isbn13: db.PGText(isbn13), Isbn10: db.PGText(isbn10)})
+	if errors.Is(err, pgx.ErrNoRows) {
+		return nil, nil
+	}
 	if err != nil {
 		return nil, err
 	}
-	return mapSources(rows), nil
+	source := mapSource(row)
+	if err := attachTags(ctx, r.queries, source); err != nil {
+		return nil, err
+	}
+	return source, nil
 }
 
-func (r *postgresRepository) Update(ctx context.Context, s *Source) (*Source, error) {
-	tags, err := json.Marshal(s.Tags)
+// List fetches one row past the page limit so the caller can tell whether
+// another page follows
+func (r *postgresRepository) List(ctx context.Context, filter ListFilter, page echox.PageRequest) ([]*Source, error) {
+	params := dbgen.ListSourcesNewestParams{
+		Type:  db.PGText((*string)(filter.Type)),
+		Tag:   db.PGText(filter.Tag),
+		Limit: int32(page.Limit + 1),
+	}
+	params.AfterCreatedAt, params.AfterID = db.Keyset(page.After.CreatedAt, page.After.ID, page.Oldest)
+	var rows []dbgen.Source
+	var err error
+	if page.Oldest {
+		rows, err = r.queries.ListSourcesOldest(ctx, dbgen.ListSourcesOldestParams(params))
+	} else {
+		rows, err = r.queries.ListSourcesNewest(ctx, params)
+	}
 	if err != nil {
 		return nil, err
 	}
-	row, err := r.queries.UpdateSource(ctx, dbgen.UpdateSourceParams{
-		ID:          db.PGUUID(s.ID),
-		Title:       s.Title,
-		Subtitle:    db.PGText(s.Subtitle),
-		Type:        string(s.Type),
-		Description: db.PGText(s.Description),
-		Publisher:   db.PGText(s.Publisher),
-		Isbn:        db.PGText(s.ISBN),
-		Doi:         db.PGText(s.DOI),
-		Url:         db.PGText(s.URL),
-		ExternalID:  db.PGText(s.ExternalID),
-		Tags:        tags,
-		PublishedAt: db.PGTimestamptzPtr(s.PublishedAt),
-	})
-	if errors.Is(err, pgx.ErrNoRows) {
-		return nil, nil
+	sources := mapSources(rows)
+	if err := attachTags(ctx, r.queries, sources...); err != nil {
+		return nil, err
+	}
+	return sources, nil
+}
+
+// Update saves the source and appends a revision crediting the editor in the
+// same transaction. It returns nil when the source does not exist.
+func (r *postgresRepository) Update(ctx context.Context, s *Source, editorID uuid.UUID) (*Source, error) {
+	tx, err := r.db.Begin(ctx)
+	if err != nil {
+		return nil, err
+	}
+	defer tx.Rollback(ctx)
+	qtx := r.queries.WithTx(tx)
+
+	previous, err := beginRevision(ctx, qtx, s.ID)
+	if previous == nil || err != nil {
+		return nil, err
 	}
+	row, err := qtx.UpdateSource(ctx, updateSourceParams(s))
 	if err != nil {
 		return nil, err
 	}
-	return mapSource(row), nil
+	updated := mapSource(row)
+	if err := saveTags(ctx, qtx, updated, s.Tags); err != nil {
+		return nil, err
+	}
+	if _, err := recordRevision(ctx, qtx, previous, updated.ID, revisionEdit{Action: RevisionActionUpdate, EditedBy: &editorID}); err != nil {
+		return nil, err
+	}
+	if err := outbox.Record(ctx, qtx, AggregateSource, updated.ID, EventSourceUpdated, updated); err != nil {
+		return nil, err
+	}
+
+	if err := tx.Commit(ctx); err != nil {
+		return nil, err
+	}
+	return updated, nil
 }
 
 func (r *postgresRepository) Delete(ctx context.Context, id uuid.UUID) error {
-	return r.queries.DeleteSource(ctx, db.PGUUID(id))
+	tx, err := r.db.Begin(ctx)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback(ctx)
+	qtx := r.queries.WithTx(tx)
+
+	deleted, err := qtx.DeleteSource(ctx, db.PGUUID(id))
+	if err != nil {
+		return err
+	}
+	if deleted > 0 {
+		if err := outbox.Record(ctx, qtx, AggregateSource, id, EventSourceDeleted, outbox.DeletedPayload{ID: id}); err != nil {
+			return err
+		}
+	}
+	return tx.Commit(ctx)
 }
 
-func (r *postgresRepository) Search(ctx context.Context, query string, limit, offset int) ([]*Source, error) {
-	rows, err := r.queries.SearchSources(ctx, dbgen.SearchSourcesParams{Column1: pgtype.Text{String: query, Valid: true}, Limit: int32(limit), Offset: int32(offset)})
+func (r *postgresRepository) Search(ctx context.Context, params SearchParams) ([]*SearchResult, error) {
+	var sourceType *string
+	if params.Type != nil {
+		value := string(*params.Type)
+		sourceType = &value
+	}
+	rows, err := r.queries.SearchSources(ctx, dbgen.SearchSourcesParams{
+		Query:    params.Query,
+		Type:     db.PGText(sourceType),
+		Tag:      db.PGText(params.Tag),
+		Language: db.PGText(params.Language),
+		Year:     db.PGInt4Ptr(params.Year),
+		Limit:    int32(params.Limit),
+		Offset:   int32(params.Offset),
+	})
 	if err != nil {
 		return nil, err
 	}
-	return mapSources(rows), nil
+	results := make([]*SearchResult, 0, len(rows))
+	sources := make([]*Source, 0, len(rows))
+	for _, row := range rows {
+		result := mapSearchResult(row)
+		results = append(results, result)
+		sources = append(sources, result.Source)
+	}
+	if err := attachTags(ctx, r.queries, sources...); err != nil {
+		return nil, err
+	}
+	return results, nil
 }
 
-func (r *postgresRepository) Count(ctx context.Context) (int64, error) {
-	return r.queries.CountSources(ctx)
+func (r *postgresRepository) Count(ctx context.Context, filter ListFilter) (int64, error) {
+	return r.queries.CountSources(ctx, dbgen.CountSourcesParams{Type: db.PGText((*string)(filter.Type)), Tag: db.PGText(filter.Tag)})
 }
 
-func (r *postgresRepository) listContributors(ctx context.Context, sourceID uuid.UUID) ([]*Contributor, error) {
-	rows, err := r.queries.ListContributorsBySource(ctx, db.PGUUID(sourceID))
+// defaultEfSearch is pgvector's own hnsw.ef_search, which the related search
+// never narrows below.
+const defaultEfSearch = 40
+
+// ListRelated reads the source's embedding first so the nearest-neighbour
+// search orders by distance to a constant vector, which the HNSW index can
+// serve. The search is widened by one to make room for the source itself.
+func (r *postgresRepository) ListRelated(ctx context.Context, id uuid.UUID, limit int) ([]*RelatedSource, error) {
+	tx, err := r.db.Begin(ctx)
 	if err != nil {
 		return nil, err
 	}
-	contributors := make([]*Contributor, 0, len(rows))
+	defer tx.Rollback(ctx)
+	qtx := r.queries.WithTx(tx)
+
+	target, err := qtx.GetSourceEmbedding(ctx, db.PGUUID(id))
+	if errors.Is(err, pgx.ErrNoRows) {
+		return []*RelatedSource{}, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	candidates := limit + 1
+	if err := qtx.SetHNSWEfSearch(ctx, strconv.Itoa(max(candidates, defaultEfSearch))); err != nil {
+		return nil, err
+	}
+	rows, err := qtx.ListRelatedSources(ctx, dbgen.ListRelatedSourcesParams{
+		Embedding:  target.Embedding,
+		Model:      target.Model,
+		Candidates: int32(candidates),
+		SourceID:   db.PGUUID(id),
+		Limit:      int32(limit),
+	})
+	if err != nil {
+		return nil, err
+	}
+	if err := tx.Commit(ctx); err != nil {
+		return nil, err
+	}
+	related := make([]*RelatedSource, 0, len(rows))
+	sources := make([]*Source, 0, len(rows))
 	for _, row := range rows {
-		contributors = append(contributors, mapContributor(row))
+		source := mapSource(dbgen.Source{
+			ID:          row.ID,
+			Title:       row.Title,
+			Subtitle:    row.Subtitle,
+			Type:        row.Type,
+			Description: row.Description,
+			Publisher:   row.Publisher,
+			Isbn:        row.Isbn,
+			Doi:         row.Doi,
+			Url:         row.Url,
+			ExternalID:  row.ExternalID,
+			PublishedAt: row.PublishedAt,
+			CreatedAt:   row.CreatedAt,
+			UpdatedAt:   row.UpdatedAt,
+			CreatedBy:   row.CreatedBy,
+		})
+		related = append(related, &RelatedSource{Source: source, Similarity: row.Similarity})
+		sources = append(sources, source)
 	}
-	return contributors, nil
+	if err := attachTags(ctx, r.queries, sources...); err != nil {
+		return nil, err
+	}
+	return related, nil
 }
 
-func mapSources(rows []dbgen.Source) []*Source {
-	sources := make([]*Source, 0, len(rows))
+func (r *postgresRepository) CountReferencesByOthers(ctx context.Context, id, userID uuid.UUID) (int64, error) {
+	return r.queries.CountSourceReferencesByOthers(ctx, dbgen.CountSourceReferencesByOthersParams{SourceID: db.PGUUID(id), UserID: db.PGUUID(userID)})
+}
+
+func (r *postgresRepository) CreateProposal(ctx context.Context, proposal *Proposal) (*Proposal, error) {
+	id, err := uuid.NewV7()
+	if err != nil {
+		return nil, err
+	}
+	changes, err := json.Marshal(proposal.Changes)
+	if err != nil {
+		return nil, err
+	}
+
+	tx, err := r.db.Begin(ctx)
+	if err != nil {
+		return nil, err
+	}
+	defer tx.Rollback(ctx)
+	qtx := r.queries.WithTx(tx)
+
+	row, err := qtx.CreateSourceProposal(ctx, dbgen.CreateSourceProposalParams{
+		ID:         db.PGUUID(id),
+		SourceID:   db.PGUUID(proposal.SourceID),
+		ProposedBy: db.PGUUIDPtr(proposal.ProposedBy),
+		Changes:    changes,
+	})
+	if err != nil {
+		return nil, err
+	}
+	created := mapProposal(row)
+	if err := outbox.Record(ctx, qtx, AggregateSourceProposal, created.ID, EventSourceProposalCreated, created); err != nil {
+		return nil, err
+	}
+
+	if err := tx.Commit(ctx); err != nil {
+		return nil, err
+	}
+	return created, nil
+}
+
+func (r *postgresRepository) GetProposal(ctx context.Context, id uuid.UUID) (*Proposal, error) {
+	row, err := r.queries.GetSourceProposal(ctx, db.PGUUID(id))
+	if errors.Is(err, pgx.ErrNoRows) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	return mapProposal(row), nil
+}
+
+func (r *postgresRepository) ListProposals(ctx context.Context, params ListProposalsParams) ([]*Proposal, error) {
+	var status *string
+	if params.Status != nil {
+		value := string(*params.Status)
+		status = &value
+	}
+	rows, err := r.queries.ListSourceProposals(ctx, dbgen.ListSourceProposalsParams{
+		Status:     db.PGText(status),
+		SourceID:   db.PGUUIDPtr(params.SourceID),
+		ProposedBy: db.PGUUIDPtr(params.ProposedBy),
+		Limit:      int32(params.Limit),
+		Offset:     int32(params.Offset),
+	})
+	if err != nil {
+		return nil, err
+	}
+	proposals := make([]*Proposal, 0, len(rows))
 	for _, row := range rows {
-		sources = append(sources, mapSource(row))
+		proposals = append(proposals, mapProposal(row))
 	}
-	return sources
+	return proposals, nil
 }
 
-func mapSource(row dbgen.Source) *Source {
-	var tags []string
-	if len(row.Tags) > 0 {
-		_ = json.Unmarshal(row.Tags, &tags)
+// ApproveProposal marks the proposal approved and saves the updated source in
+// one transaction. It returns nil when the proposal is no longer pending.
+func (r *postgresRepository) ApproveProposal(ctx context.Context, review ProposalReview, s *Source) (*Proposal, error) {
+	tx, err := r.db.Begin(ctx)
+	if err != nil {
+		return nil, err
 	}
-	return &Source{
-		ID:          db.UUID(row.ID),
-		Title:       row.Title,
-		Subtitle:    db.StringPtr(row.Subtitle),
-		Type:        SourceType(row.Type),
-		Description: db.StringPtr(row.Description),
-		Publisher:   db.StringPtr(row.Publisher),
-		ISBN:        db.StringPtr(row.Isbn),
-		DOI:         db.StringPtr(row.Doi),
-		URL:         db.StringPtr(row.Url),
-		ExternalID:  db.StringPtr(row.ExternalID),
-		Tags:        tags,
-		PublishedAt: db.TimePtr(row.PublishedAt),
-		CreatedAt:   db.Time(row.CreatedAt),
-		UpdatedAt:   db.Time(row.UpdatedAt),
+	defer tx.Rollback(ctx)
+	qtx := r.queries.WithTx(tx)
+
+	proposal, err := reviewProposal(ctx, qtx, review, ProposalStatusApproved)
+	if proposal == nil || err != nil {
+		return nil, err
+	}
+
+	previous, err := beginRevision(ctx, qtx, s.ID)
+	if previous == nil || err != nil {
+		return nil, err
+	}
+	row, err := qtx.UpdateSource(ctx, updateSourceParams(s))
+	if err != nil {
+		return nil, err
+	}
+	updated := mapSource(row)
+	if err := saveTags(ctx, qtx, updated, s.Tags); err != nil {
+		return nil, err
+	}
+	edit := revisionEdit{Action: RevisionActionProposal, EditedBy: proposal.ProposedBy, ProposalID: &proposal.ID}
+	if _, err := recordRevision(ctx, qtx, previous, updated.ID, edit); err != nil {
+		return nil, err
+	}
+	if err := outbox.Record(ctx, qtx, AggregateSource, updated.ID, EventSourceUpdated, updated); err != nil {
+		return nil, err
+	}
+	if err := outbox.Record(ctx, qtx, AggregateSourceProposal, proposal.ID, EventSourceProposalApproved, proposal); err != nil {
+		return nil, err
+	}
+
+	if err := tx.Commit(ctx); err != nil {
+		return nil, err
+	}
+	return proposal, nil
+}
+
+// RejectProposal marks the proposal rejected. It returns nil when the proposal
+// is no longer pending.
+func (r *postgresRepository) RejectProposal(ctx context.Context, review ProposalReview) (*Proposal, error) {
+	tx, err := r.db.Begin(ctx)
+	if err != nil {
+		return nil, err
+	}
+	defer tx.Rollback(ctx)
+	qtx := r.queries.WithTx(tx)
+
+	proposal, err := reviewProposal(ctx, qtx, review, ProposalStatusRejected)
+	if proposal == nil || err != nil {
+		return nil, err
+	}
+	if err := outbox.Record(ctx, qtx, AggregateSourceProposal, proposal.ID, EventSourceProposalRejected, proposal); err != nil {
+		return nil, err
+	}
+
+	if err := tx.Commit(ctx); err != nil {
+		return nil, err
+	}
+	return proposal, nil
+}
+
+func reviewProposal(ctx context.Context, qtx *dbgen.Queries, review ProposalReview, status ProposalStatus) (*Proposal, error) {
+	row, err := qtx.ReviewSourceProposal(ctx, dbgen.ReviewSourceProposalParams{
+		ID:         db.PGUUID(review.ProposalID),
+		Status:     string(status),
+		ReviewedBy: db.PGUUID(review.ReviewerID),
+		ReviewNote: db.PGText(review.Note),
+	})
+	if errors.Is(err, pgx.ErrNoRows) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	return mapProposal(row), nil
+}
+
+func (r *postgresRepository) ListRevisions(ctx context.Context, sourceID uuid.UUID, limit, offset int) ([]*Revision, error) {
+	rows, err := r.queries.ListSourceRevisions(ctx, dbgen.ListSourceRevisionsParams{SourceID: db.PGUUID(sourceID), Limit: int32(limit), Offset: int32(offset)})
+	if err != nil {
+		return nil, err
+	}
+	revisions := make([]*Revision, 0, len(rows))
+	for _, row := range rows {
+		revisions = append(revisions, mapRevision(row))
+	}
+	return revisions, nil
+}
+
+func (r *postgresRepository) GetRevision(ctx context.Context, sourceID uuid.UUID, revision int) (*Revision, error) {
+	row, err := r.queries.GetSourceRevision(ctx, dbgen.GetSourceRevisionParams{SourceID: db.PGUUID(sourceID), Revision: int32(revision)})
+	if errors.Is(err, pgx.ErrNoRows) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	return mapRevision(row), nil
+}
+
+// GetSnapshot reads the current state of a source in revision form. It
+// returns nil when the source does not exist.
+func (r *postgresRepository) GetSnapshot(ctx context.Context, sourceID uuid.UUID) (*Snapshot, error) {
+	snapshot, err := loadSnapshot(ctx, r.queries, sourceID)
+	if errors.Is(err, pgx.ErrNoRows) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	return &snapshot, nil
+}
+
+// Revert restores the source, its book metadata and its contributors to the
+// target snapshot and records that as a new revision. It returns nil when the
+// source does not exist.
+func (r *postgresRepository) Revert(ctx context.Context, sourceID uuid.UUID, target *Revision, editorID uuid.UUID) (*Source, error) {
+	return r.saveSnapshot(ctx, sourceID, target.Snapshot, revisionEdit{Action: RevisionActionRevert, EditedBy: &editorID, RevertedTo: &target.Revision})
+}
+
+// Refresh saves a snapshot completed from external metadata as an ordinary
+// update. It returns nil when the source does not exist.
+func (r *postgresRepository) Refresh(ctx context.Context, sourceID uuid.UUID, snapshot Snapshot, editorID uuid.UUID) (*Source, error) {
+	return r.saveSnapshot(ctx, sourceID, snapshot, revisionEdit{Action: RevisionActionUpdate, EditedBy: &editorID})
+}
+
+// saveSnapshot overwrites the source, its book metadata and its contributors
+// with snapshot and records the edit as a revision
+func (r *postgresRepository) saveSnapshot(ctx context.Context, sourceID uuid.UUID, snapshot Snapshot, edit revisionEdit) (*Source, error) {
+	tx, err := r.db.Begin(ctx)
+	if err != nil {
+		return nil, err
+	}
+	defer tx.Rollback(ctx)
+	qtx := r.queries.WithTx(tx)
+
+	previous, err := beginRevision(ctx, qtx, sourceID)
+	if previous == nil || err != nil {
+		return nil, err
+	}
+	row, err := qtx.UpdateSource(ctx, updateSourceParams(sourceFromSnapshot(sourceID, snapshot)))
+	if err != nil {
+		return nil, err
+	}
+	saved := mapSource(row)
+	if err := saveTags(ctx, qtx, saved, snapshot.Tags); err != nil {
+		return nil, err
+	}
+
+	if metadata := snapshot.Metadata; metadata != nil {
+		err = qtx.UpsertBookMetadata(ctx, dbgen.UpsertBookMetadataParams{
+			SourceID:  db.PGUUID(sourceID),
+			Isbn10:    db.PGText(metadata.ISBN10),
+			Isbn13:    db.PGText(metadata.ISBN13),
+			Publisher: db.PGText(metadata.Publisher),
+			PageCount: db.PGInt4Ptr(metadata.PageCount),
+			Language:  db.PGText(metadata.Language),
+			CoverUrl:  db.PGText(metadata.CoverURL),
+		})
+	} else {
+		err = qtx.DeleteBookMetadata(ctx, db.PGUUID(sourceID))
+	}
+	if err != nil {
+		return nil, err
+	}
+	if err := saveTypedMetadata(ctx, qtx, sourceID, snapshot.TypedMetadata); err != nil {
+		return nil, err
+	}
+	if err := qtx.DeleteSourceContributors(ctx, db.PGUUID(sourceID)); err != nil {
+		return nil, err
+	}
+	if _, err := insertContributors(ctx, qtx, sourceID, snapshot.Contributors); err != nil {
+		return nil, err
+	}
+
+	if _, err := recordRevision(ctx, qtx, previous, sourceID, edit); err != nil {
+		return nil, err
+	}
+	if err := outbox.Record(ctx, qtx, AggregateSource, sourceID, EventSourceUpdated, saved); err != nil {
+		return nil, err
+	}
+
+	if err := tx.Commit(ctx); err != nil {
+		return nil, err
+	}
+	return saved, nil
+}
+
+func (r *postgresRepository) ListByIDs(ctx context.Context, ids []uuid.UUID) ([]*Source, error) {
+	pgIDs := make([]pgtype.UUID, 0, len(ids))
+	for _, id := range ids {
+		pgIDs = append(pgIDs, db.PGUUID(id))
+	}
+	rows, err := r.queries.ListSourcesByIDs(ctx, pgIDs)
+	if err != nil {
+		return nil, err
+	}
+	sources := mapSources(rows)
+	if err := attachTags(ctx, r.queries, sources...); err != nil {
+		return nil, err
+	}
+	return sources, nil
+}
+
+// ListDuplicatePairs finds pairs of sources sharing a normalized ISBN or DOI,
+// or with closely matching titles and contributors, strongest matches first.
+// ISBN-10s and 978-prefixed ISBN-13s are compared on their shared nine-digit
+// core so both forms of the same edition match.
+func (r *postgresRepository) ListDuplicatePairs(ctx context.Context, limit int) ([]DuplicatePair, error) {
+	rows, err := r.queries.ListDuplicateSourcePairs(ctx, int32(limit))
+	if err != nil {
+		return nil, err
+	}
+	pairs := make([]DuplicatePair, 0, len(rows))
+	for _, row := range rows {
+		pairs = append(pairs, DuplicatePair{
+			SourceID:    db.UUID(row.SourceID),
+			DuplicateID: db.UUID(row.DuplicateID),
+			Reason:      DuplicateReason(row.Reason),
+			Score:       row.Score,
+		})
+	}
+	return pairs, nil
+}
+
+// Merge moves everything that points at the duplicate onto the survivor and
+// deletes the duplicate in one transaction. A reader who has both sources in
+// their library, or reviewed both, keeps whichever entry they updated last,
+// and a collection listing both keeps the earlier one. Contributors, tags and
+// missing book metadata are copied over, and the survivor gets a merge
+// revision. It returns nil when either source does not exist.
+func (r *postgresRepository) Merge(ctx context.Context, survivorID, duplicateID, editorID uuid.UUID) (*MergeResult, error) {
+	tx, err := r.db.Begin(ctx)
+	if err != nil {
+		return nil, err
+	}
+	defer tx.Rollback(ctx)
+	qtx := r.queries.WithTx(tx)
+
+	previous, err := beginRevision(ctx, qtx, survivorID)
+	if previous == nil || err != nil {
+		return nil, err
+	}
+	if _, err := qtx.LockSource(ctx, db.PGUUID(duplicateID)); errors.Is(err, pgx.ErrNoRows) {
+		return nil, nil
+	} else if err != nil {
+		return nil, err
+	}
+
+	survivor, duplicate := db.PGUUID(survivorID), db.PGUUID(duplicateID)
+	result := &MergeResult{MergedID: duplicateID}
+	if _, err := qtx.MoveSupersededReadingSessions(ctx, dbgen.MoveSupersededReadingSessionsParams{DuplicateID: duplicate, SurvivorID: survivor}); err != nil {
+		return nil, err
+	}
+	if _, err := qtx.MoveSupersededLibraryItemCompletions(ctx, dbgen.MoveSupersededLibraryItemCompletionsParams{DuplicateID: duplicate, SurvivorID: survivor}); err != nil {
+		return nil, err
+	}
+	if _, err := qtx.DeleteSupersededLibraryItems(ctx, dbgen.DeleteSupersededLibraryItemsParams{DuplicateID: duplicate, SurvivorID: survivor}); err != nil {
+		return nil, err
+	}
+	if result.LibraryItems, err = qtx.MoveLibraryItemsToSource(ctx, dbgen.MoveLibraryItemsToSourceParams{SurvivorID: survivor, DuplicateID: duplicate}); err != nil {
+		return nil, err
+	}
+	if _, err := qtx.DeleteSupersededReviews(ctx, dbgen.DeleteSupersededReviewsParams{DuplicateID: duplicate, SurvivorID: survivor}); err != nil {
+		return nil, err
+	}
+	if result.Reviews, err = qtx.MoveReviewsToSource(ctx, dbgen.MoveReviewsToSourceParams{SurvivorID: survivor, DuplicateID: duplicate}); err != nil {
+		return nil, err
+	}
+	if result.Notes, err = qtx.MoveNotesToSource(ctx, dbgen.MoveNotesToSourceParams{SurvivorID: survivor, DuplicateID: duplicate}); err != nil {
+		return nil, err
+	}
+	superseded, err := qtx.DeleteSupersededCollectionItems(ctx, dbgen.DeleteSupersededCollectionItemsParams{DuplicateID: duplicate, SurvivorID: survivor})
+	if err != nil {
+		return nil, err
+	}
+	moved, err := qtx.MoveCollectionItemsToSource(ctx, dbgen.MoveCollectionItemsToSourceParams{SurvivorID: survivor, DuplicateID: duplicate})
+	if err != nil {
+		return nil, err
+	}
+	result.Collections = superseded + moved
+	if err := qtx.CopySourceContributors(ctx, dbgen.CopySourceContributorsParams{SurvivorID: survivor, DuplicateID: duplicate}); err != nil {
+		return nil, err
+	}
+	if err := qtx.CopySourceTags(ctx, dbgen.CopySourceTagsParams{SurvivorID: survivor, DuplicateID: duplicate}); err != nil {
+		return nil, err
+	}
+	if err := qtx.CopyBookMetadata(ctx, dbgen.CopyBookMetadataParams{SurvivorID: survivor, DuplicateID: duplicate}); err != nil {
+		return nil, err
+	}
+	if err := adoptTypedMetadata(ctx, qtx, survivorID, duplicateID); err != nil {
+		return nil, err
+	}
+	if _, err := qtx.DeleteSource(ctx, duplicate); err != nil {
+		return nil, err
+	}
+
+	edit := revisionEdit{Action: RevisionActionMerge, EditedBy: &editorID, MergedFrom: &duplicateID}
+	if _, err := recordRevision(ctx, qtx, previous, survivorID, edit); err != nil {
+		return nil, err
+	}
+	row, err := qtx.GetSourceByID(ctx, survivor)
+	if err != nil {
+		return nil, err
+	}
+	result.Survivor = mapSource(row)
+	if err := attachTags(ctx, qtx, result.Survivor); err != nil {
+		return nil, err
+	}
+	if err := outbox.Record(ctx, qtx, AggregateSource, duplicateID, EventSourceDeleted, outbox.DeletedPayload{ID: duplicateID}); err != nil {
+		return nil, err
+	}
+	if err := outbox.Record(ctx, qtx, AggregateSource, survivorID, EventSourceMerged, result); err != nil {
+		return nil, err
+	}
+
+	if err := tx.Commit(ctx); err != nil {
+		return nil, err
+	}
+	return result, nil
+}
+
+// revisionEdit describes who made an edit and why, for recordRevision
+type revisionEdit struct {
+	Action     RevisionAction
+	EditedBy   *uuid.UUID
+	ProposalID *uuid.UUID
+	RevertedTo *int
+	MergedFrom *uuid.UUID
+}
+
+// beginRevision locks the source for the rest of the transaction and returns
+// its latest revision. Sources created before revisions were tracked get a
+// baseline revision of their current state first, so the edit that follows
+// has something to diff against and revert to. It returns nil when the source
+// does not exist.
+func beginRevision(ctx context.Context, qtx *dbgen.Queries, sourceID uuid.UUID) (*Revision, error) {
+	if _, err := qtx.LockSource(ctx, db.PGUUID(sourceID)); errors.Is(err, pgx.ErrNoRows) {
+		return nil, nil
+	} else if err != nil {
+		return nil, err
+	}
+
+	row, err := qtx.GetLatestSourceRevision(ctx, db.PGUUID(sourceID))
+	if errors.Is(err, pgx.ErrNoRows) {
+		return recordRevision(ctx, qtx, nil, sourceID, revisionEdit{Action: RevisionActionBaseline})
+	}
+	if err != nil {
+		return nil, err
+	}
+	return mapRevision(row), nil
+}
+
+// recordRevision appends a revision holding the current state of the source
+// and its diff against previous, or against an empty snapshot for the first
+// revision. Baselines record no changes.
+func recordRevision(ctx context.Context, qtx *dbgen.Queries, previous *Revision, sourceID uuid.UUID, edit revisionEdit) (*Revision, error) {
+	snapshot, err := loadSnapshot(ctx, qtx, sourceID)
+	if err != nil {
+		return nil, err
+	}
+
+	number := 1
+	changes := []FieldChange{}
+	if previous != nil {
+		number = previous.Revision + 1
+		changes = diffSnapshots(previous.Snapshot, snapshot)
+	} else if edit.Action != RevisionActionBaseline {
+		changes = diffSnapshots(Snapshot{}, snapshot)
+	}
+
+	id, err := uuid.NewV7()
+	if err != nil {
+		return nil, err
+	}
+	snapshotJSON, err := json.Marshal(snapshot)
+	if err != nil {
+		return nil, err
+	}
+	changesJSON, err := json.Marshal(changes)
+	if err != nil {
+		return nil, err
+	}
+	row, err := qtx.CreateSourceRevision(ctx, dbgen.CreateSourceRevisionParams{
+		ID:         db.PGUUID(id),
+		SourceID:   db.PGUUID(sourceID),
+		Revision:   int32(number),
+		Action:     string(edit.Action),
+		EditedBy:   db.PGUUIDPtr(edit.EditedBy),
+		ProposalID: db.PGUUIDPtr(edit.ProposalID),
+		RevertedTo: db.PGInt4Ptr(edit.RevertedTo),
+		Snapshot:   snapshotJSON,
+		Changes:    changesJSON,
+		MergedFrom: db.PGUUIDPtr(edit.MergedFrom),
+	})
+	if err != nil {
+		return nil, err
+	}
+	return mapRevision(row), nil
+}
+
+func loadSnapshot(ctx context.Context, qtx *dbgen.Queries, sourceID uuid.UUID) (Snapshot, error) {
+	sourceRow, err := qtx.GetSourceByID(ctx, db.PGUUID(sourceID))
+	if err != nil {
+		return Snapshot{}, err
+	}
+
+	var metadata *BookMetadata
+	metadataRow, err := qtx.GetBookMetadata(ctx, db.PGUUID(sourceID))
+	if err == nil {
+		metadata = mapBookMetadata(metadataRow)
+	} else if !errors.Is(err, pgx.ErrNoRows) {
+		return Snapshot{}, err
+	}
+	typed, err := loadTypedMetadata(ctx, qtx, sourceID, SourceType(sourceRow.Type))
+	if err != nil {
+		return Snapshot{}, err
+	}
+
+	contributorRows, err := qtx.ListContributorsBySource(ctx, db.PGUUID(sourceID))
+	if err != nil {
+		return Snapshot{}, err
+	}
+	contributors := make([]*Contributor, 0, len(contributorRows))
+	for _, row := range contributorRows {
+		contributors = append(contributors, mapContributor(row))
+	}
+
+	source := mapSource(sourceRow)
+	if err := attachTags(ctx, qtx, source); err != nil {
+		return Snapshot{}, err
+	}
+	return snapshotOf(source, metadata, typed, contributors), nil
+}
+
+// loadTypedMetadata reads the metadata table for sourceType. Rows left in the
+// tables of other types are ignored.
+func loadTypedMetadata(ctx context.Context, qtx *dbgen.Queries, sourceID uuid.UUID, sourceType SourceType) (TypedMetadata, error) {
+	typed := TypedMetadata{}
+	id := db.PGUUID(sourceID)
+	var err error
+	switch sourceType {
+	case SourceTypePaper:
+		var row dbgen.PaperMetadatum
+		if row, err = qtx.GetPaperMetadata(ctx, id); err == nil {
+			typed.Paper = &PaperMetadata{Venue: db.StringPtr(row.Venue), ArxivID: db.StringPtr(row.ArxivID)}
+		}
+	case SourceTypePodcast:
+		var row dbgen.PodcastMetadatum
+		if row, err = qtx.GetPodcastMetadata(ctx, id); err == nil {
+			typed.Podcast = &PodcastMetadata{
+				Show:            row.Show,
+				EpisodeNumber:   db.IntPtr(row.EpisodeNumber),
+				DurationSeconds: db.IntPtr(row.DurationSeconds),
+				FeedURL:         db.StringPtr(row.FeedUrl),
+			}
+		}
+	case SourceTypeVideo:
+		var row dbgen.VideoMetadatum
+		if row, err = qtx.GetVideoMetadata(ctx, id); err == nil {
+			typed.Video = &VideoMetadata{
+				Channel:         db.StringPtr(row.Channel),
+				DurationSeconds: db.IntPtr(row.DurationSeconds),
+				Platform:        db.StringPtr(row.Platform),
+				PlatformID:      db.StringPtr(row.PlatformID),
+			}
+		}
+	case SourceTypeArticle:
+		var row dbgen.ArticleMetadatum
+		if row, err = qtx.GetArticleMetadata(ctx, id); err == nil {
+			typed.Article = &ArticleMetadata{Site: db.StringPtr(row.Site), WordCount: db.IntPtr(row.WordCount)}
+		}
+	}
+	if err != nil && !errors.Is(err, pgx.ErrNoRows) {
+		return TypedMetadata{}, err
+	}
+	return typed, nil
+}
+
+// saveTypedMetadata writes the set fields of typed and deletes the metadata
+// of every other type, so a source never keeps rows for a type it left
+func saveTypedMetadata(ctx context.Context, qtx *dbgen.Queries, sourceID uuid.UUID, typed TypedMetadata) error {
+	id := db.PGUUID(sourceID)
+	var err error
+	if paper := typed.Paper; paper != nil {
+		err = qtx.UpsertPaperMetadata(ctx, dbgen.UpsertPaperMetadataParams{SourceID: id, Venue: db.PGText(paper.Venue), ArxivID: db.PGText(paper.ArxivID)})
+	} else {
+		err = qtx.DeletePaperMetadata(ctx, id)
+	}
+	if err != nil {
+		return err
+	}
+
+	if podcast := typed.Podcast; podcast != nil {
+		err = qtx.UpsertPodcastMetadata(ctx, dbgen.UpsertPodcastMetadataParams{
+			SourceID:        id,
+			Show:            podcast.Show,
+			EpisodeNumber:   db.PGInt4Ptr(podcast.EpisodeNumber),
+			DurationSeconds: db.PGInt4Ptr(podcast.DurationSeconds),
+			FeedUrl:         db.PGText(podcast.FeedURL),
+		})
+	} else {
+		err = qtx.DeletePodcastMetadata(ctx, id)
+	}
+	if err != nil {
+		return err
+	}
+
+	if video := typed.Video; video != nil {
+		err = qtx.UpsertVideoMetadata(ctx, dbgen.UpsertVideoMetadataParams{
+			SourceID:        id,
+			Channel:         db.PGText(video.Channel),
+			DurationSeconds: db.PGInt4Ptr(video.DurationSeconds),
+			Platform:        db.PGText(video.Platform),
+			PlatformID:      db.PGText(video.PlatformID),
+		})
+	} else {
+		err = qtx.DeleteVideoMetadata(ctx, id)
+	}
+	if err != nil {
+		return err
+	}
+
+	if article := typed.Article; article != nil {
+		return qtx.UpsertArticleMetadata(ctx, dbgen.UpsertArticleMetadataParams{SourceID: id, Site: db.PGText(article.Site), WordCount: db.PGInt4Ptr(article.WordCount)})
+	}
+	return qtx.DeleteArticleMetadata(ctx, id)
+}
+
+// adoptTypedMetadata gives the survivor of a merge the duplicate's typed
+// metadata when it has none of its own. Both sources share a type.
+func adoptTypedMetadata(ctx context.Context, qtx *dbgen.Queries, survivorID, duplicateID uuid.UUID) error {
+	row, err := qtx.GetSourceByID(ctx, db.PGUUID(survivorID))
+	if err != nil {
+		return err
+	}
+	sourceType := SourceType(row.Type)
+	if sourceType == SourceTypeBook {
+		return nil
+	}
+
+	survivor, err := loadTypedMetadata(ctx, qtx, survivorID, sourceType)
+	if err != nil || survivor.of(sourceType) != nil {
+		return err
+	}
+	duplicate, err := loadTypedMetadata(ctx, qtx, duplicateID, sourceType)
+	if err != nil || duplicate.of(sourceType) == nil {
+		return err
+	}
+	return saveTypedMetadata(ctx, qtx, survivorID, duplicate)
+}
+
+func insertContributors(ctx context.Context, qtx *dbgen.Queries, sourceID uuid.UUID, inputs []ContributorInput) ([]*Contributor, error) {
+	contributors := make([]*Contributor, 0, len(inputs))
+	for position, contributor := range inputs {
+		role := contributor.Role
+		if role == "" {
+			role = "author"
+		}
+		contributorID, err := uuid.NewV7()
+		if err != nil {
+			return nil, err
+		}
+		existingID, err := qtx.UpsertContributor(ctx, dbgen.UpsertContributorParams{ID: db.PGUUID(contributorID), Name: contributor.Name})
+		if err != nil {
+			return nil, err
+		}
+		row, err := qtx.InsertSourceContributor(ctx, dbgen.InsertSourceContributorParams{
+			SourceID:        db.PGUUID(sourceID),
+			ContributorID:   existingID,
+			Role:            role,
+			Position:        int32(position),
+			ContributorName: contributor.Name,
+		})
+		if err != nil {
+			return nil, err
+		}
+		contributors = append(contributors, mapInsertedContributor(row))
+	}
+	return contributors, nil
+}
+
+func insertSource(ctx context.Context, qtx *dbgen.Queries, s *Source) (*Source, error) {
+	id, err := uuid.NewV7()
+	if err != nil {
+		return nil, err
+	}
+
+	row, err := qtx.CreateSource(ctx, dbgen.CreateSourceParams{
+		ID:          db.PGUUID(id),
+		Title:       s.Title,
+		Subtitle:    db.PGText(s.Subtitle),
+		Type:        string(s.Type),
+		Description: db.PGText(s.Description),
+		Publisher:   db.PGText(s.Publisher),
+		Isbn:        db.PGText(s.ISBN),
+		Doi:         db.PGText(s.DOI),
+		Url:         db.PGText(s.URL),
+		ExternalID:  db.PGText(s.ExternalID),
+		PublishedAt: db.PGTimestamptzPtr(s.PublishedAt),
+		CreatedBy:   db.PGUUIDPtr(s.CreatedBy),
+	})
+	if err != nil {
+		return nil, err
+	}
+	created := mapSource(row)
+	if err := saveTags(ctx, qtx, created, s.Tags); err != nil {
+		return nil, err
+	}
+	return created, nil
+}
+
+// saveTags replaces the tags of source with names, creating any tag that does
+// not exist yet, and reads back the names they are stored under
+func saveTags(ctx context.Context, qtx *dbgen.Queries, source *Source, names []string) error {
+	if err := qtx.DeleteSourceTags(ctx, db.PGUUID(source.ID)); err != nil {
+		return err
+	}
+	ids, err := tags.Upsert(ctx, qtx, names)
+	if err != nil {
+		return err
+	}
+	if len(ids) > 0 {
+		if err := qtx.InsertSourceTags(ctx, dbgen.InsertSourceTagsParams{SourceID: db.PGUUID(source.ID), TagIds: ids}); err != nil {
+			return err
+		}
+	}
+	return attachTags(ctx, qtx, source)
+}
+
+// attachTags loads the tags of a batch of sources in one query
+func attachTags(ctx context.Context, q *dbgen.Queries, sources ...*Source) error {
+	if len(sources) == 0 {
+		return nil
+	}
+	ids := make([]pgtype.UUID, 0, len(sources))
+	for _, source := range sources {
+		ids = append(ids, db.PGUUID(source.ID))
+	}
+	rows, err := q.ListTagsBySources(ctx, ids)
+	if err != nil {
+		return err
+	}
+	grouped := tags.Group(rows, func(row dbgen.ListTagsBySourcesRow) (pgtype.UUID, string) { return row.SourceID, row.Name })
+	for _, source := range sources {
+		source.Tags = grouped[source.ID]
+	}
+	return nil
+}
+
+func updateSourceParams(s *Source) dbgen.UpdateSourceParams {
+	return dbgen.UpdateSourceParams{
+		ID:          db.PGUUID(s.ID),
+		Title:       s.Title,
+		Subtitle:    db.PGText(s.Subtitle),
+		Type:        string(s.Type),
+		Description: db.PGText(s.Description),
+		Publisher:   db.PGText(s.Publisher),
+		Isbn:        db.PGText(s.ISBN),
+		Doi:         db.PGText(s.DOI),
+		Url:         db.PGText(s.URL),
+		ExternalID:  db.PGText(s.ExternalID),
+		PublishedAt: db.PGTimestamptzPtr(s.PublishedAt),
+	}
+}
+
+func (r *postgresRepository) listContributors(ctx context.Context, sourceID uuid.UUID) ([]*Contributor, error) {
+	rows, err := r.queries.ListContributorsBySource(ctx, db.PGUUID(sourceID))
+	if err != nil {
+		return nil, err
+	}
+	contributors := make([]*Contributor, 0, len(rows))
+	for _, row := range rows {
+		contributors = append(contributors, mapContributor(row))
+	}
+	return contributors, nil
+}
+
+func mapSources(rows []dbgen.Source) []*Source {
+	sources := make([]*Source, 0, len(rows))
+	for _, row := range rows {
+		sources = append(sources, mapSource(row))
+	}
+	return sources
+}
+
+func mapSource(row dbgen.Source) *Source {
+	return &Source{
+		ID:          db.UUID(row.ID),
+		Title:       row.Title,
+		Subtitle:    db.StringPtr(row.Subtitle),
+		Type:        SourceType(row.Type),
+		Description: db.StringPtr(row.Description),
+		Publisher:   db.StringPtr(row.Publisher),
+		ISBN:        db.StringPtr(row.Isbn),
+		DOI:         db.StringPtr(row.Doi),
+		URL:         db.StringPtr(row.Url),
+		ExternalID:  db.StringPtr(row.ExternalID),
+		PublishedAt: db.TimePtr(row.PublishedAt),
+		CreatedAt:   db.Time(row.CreatedAt),
+		UpdatedAt:   db.Time(row.UpdatedAt),
+		CreatedBy:   db.UUIDPtr(row.CreatedBy),
+	}
+}
+
+func mapSearchResult(row dbgen.SearchSourcesRow) *SearchResult {
+	return &SearchResult{
+		Source: mapSource(dbgen.Source{
+			ID:          row.ID,
+			Title:       row.Title,
+			Subtitle:    row.Subtitle,
+			Type:        row.Type,
+			Description: row.Description,
+			Publisher:   row.Publisher,
+			Isbn:        row.Isbn,
+			Doi:         row.Doi,
+			Url:         row.Url,
+			ExternalID:  row.ExternalID,
+			PublishedAt: row.PublishedAt,
+			CreatedAt:   row.CreatedAt,
+			UpdatedAt:   row.UpdatedAt,
+			CreatedBy:   row.CreatedBy,
+		}),
+		Rank:    row.Rank,
+		Snippet: row.Snippet,
+	}
+}
+
+func mapProposal(row dbgen.SourceProposal) *Proposal {
+	var changes UpdateSourceParams
+	if len(row.Changes) > 0 {
+		_ = json.Unmarshal(row.Changes, &changes)
+	}
+	return &Proposal{
+		ID:         db.UUID(row.ID),
+		SourceID:   db.UUID(row.SourceID),
+		ProposedBy: db.UUIDPtr(row.ProposedBy),
+		Changes:    changes,
+		Status:     ProposalStatus(row.Status),
+		ReviewedBy: db.UUIDPtr(row.ReviewedBy),
+		ReviewNote: db.StringPtr(row.ReviewNote),
+		ReviewedAt: db.TimePtr(row.ReviewedAt),
+		CreatedAt:  db.Time(row.CreatedAt),
+		UpdatedAt:  db.Time(row.UpdatedAt),
+	}
+}
+
+func mapRevision(row dbgen.SourceRevision) *Revision {
+	var snapshot Snapshot
+	if len(row.Snapshot) > 0 {
+		_ = json.Unmarshal(row.Snapshot, &snapshot)
+	}
+	changes := []FieldChange{}
+	if len(row.Changes) > 0 {
+		_ = json.Unmarshal(row.Changes, &changes)
+	}
+	return &Revision{
+		ID:         db.UUID(row.ID),
+		SourceID:   db.UUID(row.SourceID),
+		Revision:   int(row.Revision),
+		Action:     RevisionAction(row.Action),
+		EditedBy:   db.UUIDPtr(row.EditedBy),
+		ProposalID: db.UUIDPtr(row.ProposalID),
+		RevertedTo: db.IntPtr(row.RevertedTo),
+		MergedFrom: db.UUIDPtr(row.MergedFrom),
+		Snapshot:   snapshot,
+		Changes:    changes,
+		CreatedAt:  db.Time(row.CreatedAt),
 	}
 }
 
@@ -0,0 +1,220 @@
+package sources
+
+import (
+	"reflect"
+	"time"
+
+	"github.com/gofrs/uuid/v5"
+)
+
+// RevisionAction records which kind of edit produced a revision
+type RevisionAction string
+
+const (
+	// RevisionActionBaseline captures a source that existed before revisions
+	// were tracked, written just before its first recorded edit.
+	RevisionActionBaseline RevisionAction = "baseline"
+	RevisionActionCreate   RevisionAction = "create"
+	RevisionActionUpdate   RevisionAction = "update"
+	RevisionActionProposal RevisionAction = "proposal"
+	RevisionActionRevert   RevisionAction = "revert"
+	RevisionActionMerge    RevisionAction = "merge"
+)
+
+// Revision is an immutable record of a source after one edit, together with
+// the fields that edit changed.
+type Revision struct {
+	ID         uuid.UUID      `json:"id"`
+	SourceID   uuid.UUID      `json:"source_id"`
+	Revision   int            `json:"revision"`
+	Action     RevisionAction `json:"action"`
+	EditedBy   *uuid.UUID     `json:"edited_by,omitempty"`
+	ProposalID *uuid.UUID     `json:"proposal_id,omitempty"`
+	RevertedTo *int           `json:"reverted_to,omitempty"`
+	MergedFrom *uuid.UUID     `json:"merged_from,omitempty"`
+	Snapshot   Snapshot       `json:"snapshot"`
+	Changes    []FieldChange  `json:"changes"`
+	CreatedAt  time.Time      `json:"created_at"`
+}
+
+// Snapshot is the editable state of a source, its book or typed metadata and
+// its contributors at one revision.
+type Snapshot struct {
+	Title        string             `json:"title"`
+	Subtitle     *string            `json:"subtitle,omitempty"`
+	Type         SourceType         `json:"type"`
+	Description  *string            `json:"description,omitempty"`
+	Publisher    *string            `json:"publisher,omitempty"`
+	ISBN         *string            `json:"isbn,omitempty"`
+	DOI          *string            `json:"doi,omitempty"`
+	URL          *string            `json:"url,omitempty"`
+	ExternalID   *string            `json:"external_id,omitempty"`
+	Tags         []string           `json:"tags,omitempty"`
+	PublishedAt  *time.Time         `json:"published_at,omitempty"`
+	Metadata     *SnapshotMetadata  `json:"metadata,omitempty"`
+	Contributors []ContributorInput `json:"contributors,omitempty"`
+	TypedMetadata
+}
+
+// SnapshotMetadata is the book metadata part of a snapshot
+type SnapshotMetadata struct {
+	ISBN10    *string `json:"isbn_10,omitempty"`
+	ISBN13    *string `json:"isbn_13,omitempty"`
+	Publisher *string `json:"publisher,omitempty"`
+	PageCount *int    `json:"page_count,omitempty"`
+	Language  *string `json:"language,omitempty"`
+	CoverURL  *string `json:"cover_url,omitempty"`
+}
+
+// FieldChange is one field that differs between two snapshots. Old and New
+// are nil when the field is unset on that side.
+type FieldChange struct {
+	Field string `json:"field"`
+	Old   any    `json:"old"`
+	New   any    `json:"new"`
+}
+
+type snapshotField struct {
+	name  string
+	value any
+}
+
+func snapshotOf(source *Source, metadata *BookMetadata, typed TypedMetadata, contributors []*Contributor) Snapshot {
+	snapshot := Snapshot{
+		Title:       source.Title,
+		Subtitle:    source.Subtitle,
+		Type:        source.Type,
+		Description: source.Description,
+		Publisher:   source.Publisher,
+		ISBN:        source.ISBN,
+		DOI:         source.DOI,
+		URL:         source.URL,
+		ExternalID:  source.ExternalID,
+		Tags:        source.Tags,
+		PublishedAt: source.PublishedAt,
+	}
+	snapshot.TypedMetadata = typed
+	if metadata != nil {
+		snapshot.Metadata = &SnapshotMetadata{
+			ISBN10:    metadata.ISBN10,
+			ISBN13:    metadata.ISBN13,
+			Publisher: metadata.Publisher,
+			PageCount: metadata.PageCount,
+			Language:  metadata.Language,
+			CoverURL:  metadata.CoverURL,
+		}
+	}
+	for _, contributor := range contributors {
+		snapshot.Contributors = append(snapshot.Contributors, ContributorInput{Name: contributor.Name, Role: contributor.Role})
+	}
+	return snapshot
+}
+
+func sourceFromSnapshot(id uuid.UUID, snapshot Snapshot) *Source {
+	return &Source{
+		ID:          id,
+		Title:       snapshot.Title,
+		Subtitle:    snapshot.Subtitle,
+		Type:        snapshot.Type,
+		Description: snapshot.Description,
+		Publisher:   snapshot.Publisher,
+		ISBN:        snapshot.ISBN,
+		DOI:         snapshot.DOI,
+		URL:         snapshot.URL,
+		ExternalID:  snapshot.ExternalID,
+		Tags:        snapshot.Tags,
+		PublishedAt: snapshot.PublishedAt,
+	}
+}
+
+// diffSnapshots lists the fields that differ between two snapshots in a
+// stable order. Contributors are compared as a whole, including their order.
+func diffSnapshots(before, after Snapshot) []FieldChange {
+	oldFields, newFields := before.fields(), after.fields()
+	changes := []FieldChange{}
+	for i, field := range newFields {
+		if !reflect.DeepEqual(oldFields[i].value, field.value) {
+			changes = append(changes, FieldChange{Field: field.name, Old: oldFields[i].value, New: field.value})
+		}
+	}
+	return changes
+}
+
+func (s Snapshot) fields() []snapshotField {
+	var publishedAt any
+	if s.PublishedAt != nil {
+		publishedAt = s.PublishedAt.UTC().Format(time.RFC3339)
+	}
+	var tags any
+	if len(s.Tags) > 0 {
+		tags = s.Tags
+	}
+	var contributors any
+	if len(s.Contributors) > 0 {
+		contributors = s.Contributors
+	}
+	metadata := SnapshotMetadata{}
+	if s.Metadata != nil {
+		metadata = *s.Metadata
+	}
+	paper, podcast, video, article := PaperMetadata{}, PodcastMetadata{}, VideoMetadata{}, ArticleMetadata{}
+	if s.Paper != nil {
+		paper = *s.Paper
+	}
+	if s.Podcast != nil {
+		podcast = *s.Podcast
+	}
+	if s.Video != nil {
+		video = *s.Video
+	}
+	if s.Article != nil {
+		article = *s.Article
+	}
+
+	return []snapshotField{
+		{"title", nonEmpty(s.Title)},
+		{"subtitle", deref(s.Subtitle)},
+		{"type", nonEmpty(string(s.Type))},
+		{"description", deref(s.Description)},
+		{"publisher", deref(s.Publisher)},
+		{"isbn", deref(s.ISBN)},
+		{"doi", deref(s.DOI)},
+		{"url", deref(s.URL)},
+		{"external_id", deref(s.ExternalID)},
+		{"tags", tags},
+		{"published_at", publishedAt},
+		{"metadata.isbn_10", deref(metadata.ISBN10)},
+		{"metadata.isbn_13", deref(metadata.ISBN13)},
+		{"metadata.publisher", deref(metadata.Publisher)},
+		{"metadata.page_count", deref(metadata.PageCount)},
+		{"metadata.language", deref(metadata.Language)},
+		{"metadata.cover_url", deref(metadata.CoverURL)},
+		{"paper.venue", deref(paper.Venue)},
+		{"paper.arxiv_id", deref(paper.ArxivID)},
+		{"podcast.show", nonEmpty(podcast.Show)},
+		{"podcast.episode_number", deref(podcast.EpisodeNumber)},
+		{"podcast.duration_seconds", deref(podcast.DurationSeconds)},
+		{"podcast.feed_url", deref(podcast.FeedURL)},
+		{"video.channel", deref(video.Channel)},
+		{"video.duration_seconds", deref(video.DurationSeconds)},
+		{"video.platform", deref(video.Platform)},
+		{"video.platform_id", deref(video.PlatformID)},
+		{"article.site", deref(article.Site)},
+		{"article.word_count", deref(article.WordCount)},
+		{"contributors", contributors},
+	}
+}
+
+func nonEmpty(value string) any {
+	if value == "" {
+		return nil
+	}
+	return value
+}
+
+func deref[T any](value *T) any {
+	if value == nil {
+		return nil
+	}
+	return *value
+}
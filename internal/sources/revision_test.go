@@ -0,0 +1,66 @@
+package sources
+
+import (
+	"reflect"
+	"testing"
+	"time"
+)
+
+func TestDiffSnapshots(t *testing.T) {
+	subtitle := "An Introduction to History"
+	pages := 512
+	published := time.Date(1377, time.January, 1, 0, 0, 0, 0, time.UTC)
+	sameInstant := published.In(time.FixedZone("AST", 3*60*60))
+	before := Snapshot{
+		Title:        "Muqaddimah",
+		Type:         SourceTypeBook,
+		Tags:         []string{"history"},
+		PublishedAt:  &published,
+		Contributors: []ContributorInput{{Name: "Ibn Khaldun", Role: "author"}},
+	}
+	after := before
+	after.Subtitle = &subtitle
+	after.Tags = []string{"history", "sociology"}
+	after.PublishedAt = &sameInstant
+	after.Metadata = &SnapshotMetadata{PageCount: &pages}
+	after.Contributors = []ContributorInput{{Name: "Ibn Khaldun", Role: "author"}, {Name: "Franz Rosenthal", Role: "translator"}}
+
+	want := []FieldChange{
+		{Field: "subtitle", Old: nil, New: subtitle},
+		{Field: "tags", Old: []string{"history"}, New: []string{"history", "sociology"}},
+		{Field: "metadata.page_count", Old: nil, New: pages},
+		{Field: "contributors", Old: before.Contributors, New: after.Contributors},
+	}
+	if got := diffSnapshots(before, after); !reflect.DeepEqual(got, want) {
+		t.Fatalf("diffSnapshots() = %+v, want %+v", got, want)
+	}
+	if got := diffSnapshots(after, after); len(got) != 0 {
+		t.Fatalf("diffSnapshots() of identical snapshots = %+v, want none", got)
+	}
+}
+
+func TestDiffSnapshotsFromEmpty(t *testing.T) {
+	got := diffSnapshots(Snapshot{}, Snapshot{Title: "Muqaddimah", Type: SourceTypeBook})
+	want := []FieldChange{
+		{Field: "title", Old: nil, New: "Muqaddimah"},
+		{Field: "type", Old: nil, New: "book"},
+	}
+	if !reflect.DeepEqual(got, want) {
+		t.Fatalf("diffSnapshots() = %+v, want %+v", got, want)
+	}
+}
+
+func TestDiffSnapshotsTypedMetadata(t *testing.T) {
+	episode := 12
+	before := Snapshot{Title: "Ibn Khaldun", Type: SourceTypePodcast, TypedMetadata: TypedMetadata{Podcast: &PodcastMetadata{Show: "History of Philosophy"}}}
+	after := before
+	after.Podcast = &PodcastMetadata{Show: "History of Philosophy without any Gaps", EpisodeNumber: &episode}
+
+	want := []FieldChange{
+		{Field: "podcast.show", Old: "History of Philosophy", New: "History of Philosophy without any Gaps"},
+		{Field: "podcast.episode_number", Old: nil, New: episode},
+	}
+	if got := diffSnapshots(before, after); !reflect.DeepEqual(got, want) {
+		t.Fatalf("diffSnapshots() = %+v, want %+v", got, want)
+	}
+}
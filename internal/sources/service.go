@@ -4,25 +4,46 @@ import (
 	"context"
 	"errors"
 	"log/slog"
+	"net/url"
+	"regexp"
+	"slices"
+	"strings"
 
 	"github.com/gofrs/uuid/v5"
+	"github.com/zizouhuweidi/maktaba/internal/echox"
+	"github.com/zizouhuweidi/maktaba/internal/metadata"
+	"github.com/zizouhuweidi/maktaba/internal/tags"
 )
 
 var (
 	ErrSourceNotFound = errors.New("source not found")
 	ErrInvalidSource  = errors.New("invalid source data")
+	ErrInvalidSearch  = errors.New("invalid search query")
+	ErrInvalidFilter  = errors.New("invalid list filter")
+
+	ErrSourceForbidden  = errors.New("not allowed to modify source")
+	ErrSourceInUse      = errors.New("source is referenced by other users")
+	ErrProposalNotFound = errors.New("proposal not found")
+	ErrProposalReviewed = errors.New("proposal already reviewed")
+	ErrRevisionNotFound = errors.New("revision not found")
+	ErrDuplicateSource  = errors.New("source already exists")
+	ErrInvalidMerge     = errors.New("sources cannot be merged")
+	ErrNoIdentifier     = errors.New("source has no ISBN or DOI")
+	ErrLookupFailed     = errors.New("metadata providers unavailable")
 )
 
 // Service provides business logic for sources
 type Service struct {
 	repo   Repository
+	lookup MetadataLookup
 	logger *slog.Logger
 }
 
 // NewService creates a new source service
-func NewService(repo Repository, logger *slog.Logger) *Service {
+func NewService(repo Repository, lookup MetadataLookup, logger *slog.Logger) *Service {
 	return &Service{
 		repo:   repo,
+		lookup: lookup,
 		logger: logger,
 	}
 }
@@ -32,6 +53,10 @@ func (s *Service) Create(ctx context.Context, params CreateSourceParams) (*Sourc
 	if params.Title == "" || !validSourceType(params.Type) {
 		return nil, ErrInvalidSource
 	}
+	cleaned, err := tags.Clean(params.Tags)
+	if err != nil {
+		return nil, ErrInvalidSource
+	}
 
 	source := &Source{
 		Title:       params.Title,
@@ -43,9 +68,12 @@ func (s *Service) Create(ctx context.Context, params CreateSourceParams) (*Sourc
 		DOI:         params.DOI,
 		URL:         params.URL,
 		ExternalID:  params.ExternalID,
-		Tags:        params.Tags,
+		Tags:        cleaned,
 		PublishedAt: params.PublishedAt,
 	}
+	if params.CreatedBy != uuid.Nil {
+		source.CreatedBy = &params.CreatedBy
+	}
 
 	created, err := s.repo.Create(ctx, source)
 	if err != nil {
@@ -61,11 +89,25 @@ func (s *Service) CreateBook(ctx context.Context, params CreateBookParams) (*Boo
 	if params.Title == "" {
 		return nil, ErrInvalidSource
 	}
+	cleaned, err := tags.Clean(params.Tags)
+	if err != nil {
+		return nil, ErrInvalidSource
+	}
+	params.Tags = cleaned
 	for _, contributor := range params.Contributors {
 		if contributor.Name == "" {
 			return nil, ErrInvalidSource
 		}
 	}
+	if params.ISBN10 != nil || params.ISBN13 != nil {
+		existing, err := s.repo.FindBookByISBN(ctx, params.ISBN10, params.ISBN13)
+		if err != nil {
+			return nil, err
+		}
+		if existing != nil {
+			return nil, ErrDuplicateSource
+		}
+	}
 
 	book, err := s.repo.CreateBook(ctx, params)
 	if err != nil {
@@ -77,6 +119,64 @@ func (s *Service) CreateBook(ctx context.Context, params CreateBookParams) (*Boo
 	return book, nil
 }
 
+// FindBookByISBN returns the book with either ISBN, or nil if there is none.
+func (s *Service) FindBookByISBN(ctx context.Context, isbn10, isbn13 *string) (*Source, error) {
+	return s.repo.FindBookByISBN(ctx, isbn10, isbn13)
+}
+
+// CreateTyped creates a paper, podcast, video or article. The typed metadata
+// must match the source type, though it may be left out for anything but a
+// podcast, whose show is required.
+func (s *Service) CreateTyped(ctx context.Context, params CreateTypedParams) (*SourceDetail, error) {
+	if params.Source.Title == "" {
+		return nil, ErrInvalidSource
+	}
+	cleaned, err := tags.Clean(params.Source.Tags)
+	if err != nil {
+		return nil, ErrInvalidSource
+	}
+	for _, contributor := range params.Contributors {
+		if contributor.Name == "" {
+			return nil, ErrInvalidSource
+		}
+	}
+	if params.Source.DOI != nil {
+		doi, err := metadata.NormalizeDOI(*params.Source.DOI)
+		if err != nil {
+			return nil, ErrInvalidSource
+		}
+		params.Source.DOI = &doi
+	}
+	if err := validateTypedMetadata(params.Source.Type, params.Typed); err != nil {
+		return nil, err
+	}
+
+	source := &Source{
+		Title:       params.Source.Title,
+		Subtitle:    params.Source.Subtitle,
+		Type:        params.Source.Type,
+		Description: params.Source.Description,
+		Publisher:   params.Source.Publisher,
+		DOI:         params.Source.DOI,
+		URL:         params.Source.URL,
+		ExternalID:  params.Source.ExternalID,
+		Tags:        cleaned,
+		PublishedAt: params.Source.PublishedAt,
+	}
+	if params.Source.CreatedBy != uuid.Nil {
+		source.CreatedBy = &params.Source.CreatedBy
+	}
+
+	detail, err := s.repo.CreateTyped(ctx, source, params.Typed, params.Contributors)
+	if err != nil {
+		s.logger.Error("failed to create source", "error", err, "type", params.Source.Type)
+		return nil, err
+	}
+
+	s.logger.Info("source created", "id", detail.ID, "type", detail.Type, "title", detail.Title)
+	return detail, nil
+}
+
 // GetByID retrieves a source by ID
 func (s *Service) GetByID(ctx context.Context, id uuid.UUID) (*Source, error) {
 	source, err := s.repo.GetByID(ctx, id)
@@ -92,6 +192,20 @@ func (s *Service) GetByID(ctx context.Context, id uuid.UUID) (*Source, error) {
 	return source, nil
 }
 
+// GetDetail retrieves a source with its contributors and typed metadata
+func (s *Service) GetDetail(ctx context.Context, id uuid.UUID) (*SourceDetail, error) {
+	detail, err := s.repo.GetDetail(ctx, id)
+	if err != nil {
+		s.logger.Error("failed to get source", "error", err, "id", id)
+		return nil, err
+	}
+	if detail == nil {
+		return nil, ErrSourceNotFound
+	}
+
+	return detail, nil
+}
+
 func (s *Service) GetBookByID(ctx context.Context, id uuid.UUID) (*Book, error) {
 	book, err := s.repo.GetBookByID(ctx, id)
 	if err != nil {
@@ -105,8 +219,191 @@ func (s *Service) GetBookByID(ctx context.Context, id uuid.UUID) (*Book, error)
 	return book, nil
 }
 
-// List retrieves a paginated list of sources
-func (s *Service) List(ctx context.Context, limit, offset int) ([]*Source, error) {
+// List returns one page of sources matching the filter, newest first unless
+// the page asks for the oldest
+func (s *Service) List(ctx context.Context, filter ListFilter, page echox.PageRequest) (*echox.Page[*Source], error) {
+	if filter.Type != nil && !validSourceType(*filter.Type) {
+		return nil, ErrInvalidFilter
+	}
+	filter.Tag = tagSlug(filter.Tag)
+	page = page.Normalize()
+
+	sources, err := s.repo.List(ctx, filter, page)
+	if err != nil {
+		return nil, err
+	}
+	total, err := page.Total(func() (int64, error) { return s.repo.Count(ctx, filter) })
+	if err != nil {
+		return nil, err
+	}
+	return echox.NewPage(sources, page, total, func(source *Source) echox.Cursor {
+		return echox.Cursor{CreatedAt: source.CreatedAt, ID: source.ID}
+	}), nil
+}
+
+// Update applies changes directly when the actor created the source or is a
+// moderator. Anyone else gets a pending proposal instead, so exactly one of the
+// returned source and proposal is non-nil on success.
+func (s *Service) Update(ctx context.Context, id uuid.UUID, actor Actor, params UpdateSourceParams) (*Source, *Proposal, error) {
+	existing, err := s.repo.GetByID(ctx, id)
+	if err != nil {
+		return nil, nil, err
+	}
+	if existing == nil {
+		return nil, nil, ErrSourceNotFound
+	}
+
+	if err := applyUpdate(existing, params); err != nil {
+		return nil, nil, err
+	}
+
+	if !actor.Moderator && !ownsSource(existing, actor.UserID) {
+		proposal, err := s.repo.CreateProposal(ctx, &Proposal{SourceID: id, ProposedBy: &actor.UserID, Changes: params})
+		if err != nil {
+			s.logger.Error("failed to create source proposal", "error", err, "id", id)
+			return nil, nil, err
+		}
+		s.logger.Info("source change proposed", "id", id, "proposal_id", proposal.ID, "user_id", actor.UserID)
+		return nil, proposal, nil
+	}
+
+	updated, err := s.repo.Update(ctx, existing, actor.UserID)
+	if err != nil {
+		s.logger.Error("failed to update source", "error", err, "id", id)
+		return nil, nil, err
+	}
+	if updated == nil {
+		return nil, nil, ErrSourceNotFound
+	}
+
+	s.logger.Info("source updated", "id", id)
+	return updated, nil, nil
+}
+
+// Delete removes a source. Moderators may delete anything; creators may only
+// delete sources nobody else has in their library, notes, reviews or
+// collections.
+func (s *Service) Delete(ctx context.Context, id uuid.UUID, actor Actor) error {
+	existing, err := s.repo.GetByID(ctx, id)
+	if err != nil {
+		return err
+	}
+	if existing == nil {
+		return ErrSourceNotFound
+	}
+
+	if !actor.Moderator {
+		if !ownsSource(existing, actor.UserID) {
+			return ErrSourceForbidden
+		}
+		references, err := s.repo.CountReferencesByOthers(ctx, id, actor.UserID)
+		if err != nil {
+			return err
+		}
+		if references > 0 {
+			return ErrSourceInUse
+		}
+	}
+
+	if err := s.repo.Delete(ctx, id); err != nil {
+		s.logger.Error("failed to delete source", "error", err, "id", id)
+		return err
+	}
+
+	s.logger.Info("source deleted", "id", id, "user_id", actor.UserID)
+	return nil
+}
+
+// ListProposals lists change proposals. Non-moderators only see their own.
+func (s *Service) ListProposals(ctx context.Context, actor Actor, params ListProposalsParams) ([]*Proposal, error) {
+	if params.Status != nil && !validProposalStatus(*params.Status) {
+		return nil, ErrInvalidSource
+	}
+	if !actor.Moderator {
+		params.ProposedBy = &actor.UserID
+	}
+	if params.Limit <= 0 {
+		params.Limit = 100
+	}
+	if params.Offset < 0 {
+		params.Offset = 0
+	}
+
+	return s.repo.ListProposals(ctx, params)
+}
+
+// ApproveProposal applies a pending proposal to its source
+func (s *Service) ApproveProposal(ctx context.Context, id uuid.UUID, actor Actor, note *string) (*Proposal, error) {
+	proposal, err := s.pendingProposal(ctx, id, actor)
+	if err != nil {
+		return nil, err
+	}
+
+	source, err := s.repo.GetByID(ctx, proposal.SourceID)
+	if err != nil {
+		return nil, err
+	}
+	if source == nil {
+		return nil, ErrSourceNotFound
+	}
+	if err := applyUpdate(source, proposal.Changes); err != nil {
+		return nil, err
+	}
+
+	approved, err := s.repo.ApproveProposal(ctx, ProposalReview{ProposalID: id, ReviewerID: actor.UserID, Note: note}, source)
+	if err != nil {
+		s.logger.Error("failed to approve source proposal", "error", err, "proposal_id", id)
+		return nil, err
+	}
+	if approved == nil {
+		return nil, ErrProposalReviewed
+	}
+
+	s.logger.Info("source proposal approved", "proposal_id", id, "source_id", proposal.SourceID, "reviewer_id", actor.UserID)
+	return approved, nil
+}
+
+// RejectProposal closes a pending proposal without changing the source
+func (s *Service) RejectProposal(ctx context.Context, id uuid.UUID, actor Actor, note *string) (*Proposal, error) {
+	if _, err := s.pendingProposal(ctx, id, actor); err != nil {
+		return nil, err
+	}
+
+	rejected, err := s.repo.RejectProposal(ctx, ProposalReview{ProposalID: id, ReviewerID: actor.UserID, Note: note})
+	if err != nil {
+		s.logger.Error("failed to reject source proposal", "error", err, "proposal_id", id)
+		return nil, err
+	}
+	if rejected == nil {
+		return nil, ErrProposalReviewed
+	}
+
+	s.logger.Info("source proposal rejected", "proposal_id", id, "reviewer_id", actor.UserID)
+	return rejected, nil
+}
+
+func (s *Service) pendingProposal(ctx context.Context, id uuid.UUID, actor Actor) (*Proposal, error) {
+	if !actor.Moderator {
+		return nil, ErrSourceForbidden
+	}
+	proposal, err := s.repo.GetProposal(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+	if proposal == nil {
+		return nil, ErrProposalNotFound
+	}
+	if proposal.Status != ProposalStatusPending {
+		return nil, ErrProposalReviewed
+	}
+	return proposal, nil
+}
+
+// ListRevisions lists the edit history of a source, newest first
+func (s *Service) ListRevisions(ctx context.Context, sourceID uuid.UUID, limit, offset int) ([]*Revision, error) {
+	if _, err := s.GetByID(ctx, sourceID); err != nil {
+		return nil, err
+	}
 	if limit <= 0 {
 		limit = 100
 	}
@@ -114,100 +411,373 @@ func (s *Service) List(ctx context.Context, limit, offset int) ([]*Source, error
 		offset = 0
 	}
 
-	return s.repo.List(ctx, limit, offset)
+	return s.repo.ListRevisions(ctx, sourceID, limit, offset)
+}
+
+// GetRevision retrieves one revision of a source, including the changes it
+// made relative to the revision before it
+func (s *Service) GetRevision(ctx context.Context, sourceID uuid.UUID, revision int) (*Revision, error) {
+	found, err := s.repo.GetRevision(ctx, sourceID, revision)
+	if err != nil {
+		s.logger.Error("failed to get source revision", "error", err, "id", sourceID, "revision", revision)
+		return nil, err
+	}
+	if found == nil {
+		return nil, ErrRevisionNotFound
+	}
+	return found, nil
+}
+
+// CompareRevisions lists the fields that differ between two revisions of a
+// source
+func (s *Service) CompareRevisions(ctx context.Context, sourceID uuid.UUID, from, to int) ([]FieldChange, error) {
+	before, err := s.GetRevision(ctx, sourceID, from)
+	if err != nil {
+		return nil, err
+	}
+	after, err := s.GetRevision(ctx, sourceID, to)
+	if err != nil {
+		return nil, err
+	}
+	return diffSnapshots(before.Snapshot, after.Snapshot), nil
 }
 
-// ListByType retrieves sources filtered by type
-func (s *Service) ListByType(ctx context.Context, sourceType SourceType, limit, offset int) ([]*Source, error) {
+// Revert restores a source to the state recorded in one of its revisions.
+// The revert is itself recorded as a new revision, so it can be undone the
+// same way. Only admins may revert.
+func (s *Service) Revert(ctx context.Context, sourceID uuid.UUID, revision int, actor Actor) (*Source, error) {
+	if !actor.Admin {
+		return nil, ErrSourceForbidden
+	}
+	target, err := s.GetRevision(ctx, sourceID, revision)
+	if err != nil {
+		return nil, err
+	}
+
+	reverted, err := s.repo.Revert(ctx, sourceID, target, actor.UserID)
+	if err != nil {
+		s.logger.Error("failed to revert source", "error", err, "id", sourceID, "revision", revision)
+		return nil, err
+	}
+	if reverted == nil {
+		return nil, ErrSourceNotFound
+	}
+
+	s.logger.Info("source reverted", "id", sourceID, "revision", revision, "user_id", actor.UserID)
+	return reverted, nil
+}
+
+// FindDuplicates groups sources that are probably the same work, strongest
+// matches first. Only moderators may look for duplicates.
+func (s *Service) FindDuplicates(ctx context.Context, actor Actor, limit int) ([]*DuplicateCluster, error) {
+	if !actor.Moderator {
+		return nil, ErrSourceForbidden
+	}
 	if limit <= 0 {
 		limit = 100
 	}
-	if offset < 0 {
-		offset = 0
+
+	pairs, err := s.repo.ListDuplicatePairs(ctx, limit)
+	if err != nil {
+		s.logger.Error("failed to find duplicate sources", "error", err)
+		return nil, err
 	}
+	groups := clusterPairs(pairs)
 
-	return s.repo.ListByType(ctx, sourceType, limit, offset)
+	var ids []uuid.UUID
+	for _, group := range groups {
+		ids = append(ids, group.ids...)
+	}
+	found, err := s.repo.ListByIDs(ctx, ids)
+	if err != nil {
+		return nil, err
+	}
+	byID := make(map[uuid.UUID]*Source, len(found))
+	for _, source := range found {
+		byID[source.ID] = source
+	}
+
+	clusters := make([]*DuplicateCluster, 0, len(groups))
+	for _, group := range groups {
+		cluster := &DuplicateCluster{Reasons: group.reasons, Score: group.score}
+		for _, id := range group.ids {
+			if source, ok := byID[id]; ok {
+				cluster.Sources = append(cluster.Sources, source)
+			}
+		}
+		if len(cluster.Sources) < 2 {
+			continue
+		}
+		slices.SortStableFunc(cluster.Sources, func(a, b *Source) int { return a.CreatedAt.Compare(b.CreatedAt) })
+		clusters = append(clusters, cluster)
+	}
+	return clusters, nil
 }
 
-// Update updates an existing source
-func (s *Service) Update(ctx context.Context, id uuid.UUID, params UpdateSourceParams) (*Source, error) {
-	existing, err := s.repo.GetByID(ctx, id)
+// Merge folds a duplicate source into the survivor and deletes the duplicate.
+// Both must be of the same type, and only moderators may merge.
+func (s *Service) Merge(ctx context.Context, survivorID, duplicateID uuid.UUID, actor Actor) (*MergeResult, error) {
+	if !actor.Moderator {
+		return nil, ErrSourceForbidden
+	}
+	if survivorID == duplicateID {
+		return nil, ErrInvalidMerge
+	}
+	survivor, err := s.GetByID(ctx, survivorID)
 	if err != nil {
 		return nil, err
 	}
-	if existing == nil {
+	duplicate, err := s.GetByID(ctx, duplicateID)
+	if err != nil {
+		return nil, err
+	}
+	if survivor.Type != duplicate.Type {
+		return nil, ErrInvalidMerge
+	}
+
+	result, err := s.repo.Merge(ctx, survivorID, duplicateID, actor.UserID)
+	if err != nil {
+		s.logger.Error("failed to merge sources", "error", err, "survivor_id", survivorID, "duplicate_id", duplicateID)
+		return nil, err
+	}
+	if result == nil {
 		return nil, ErrSourceNotFound
 	}
 
-	// Apply updates
+	s.logger.Info("sources merged", "survivor_id", survivorID, "duplicate_id", duplicateID, "user_id", actor.UserID,
+		"library_items", result.LibraryItems, "reviews", result.Reviews, "notes", result.Notes, "collections", result.Collections)
+	return result, nil
+}
+
+// Lookup prefills a new book from external catalogues by ISBN or DOI.
+// Exactly one of the two must be given.
+func (s *Service) Lookup(ctx context.Context, isbn, doi string) (*CreateBookParams, error) {
+	isbn, doi = strings.TrimSpace(isbn), strings.TrimSpace(doi)
+	if (isbn == "") == (doi == "") {
+		return nil, ErrNoIdentifier
+	}
+
+	record, err := s.lookupRecord(ctx, isbn, doi)
+	if err != nil {
+		return nil, err
+	}
+	params := bookParamsFromRecord(record)
+	return &params, nil
+}
+
+// Refresh fills the blank fields of a source from external catalogues, looked
+// up by its ISBN or else its DOI. Fields that are already set are never
+// overwritten, and nothing is written when there is nothing to fill. Only the
+// creator or a moderator may refresh a source.
+func (s *Service) Refresh(ctx context.Context, id uuid.UUID, actor Actor) (*RefreshResult, error) {
+	existing, err := s.GetByID(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+	if !actor.Moderator && !ownsSource(existing, actor.UserID) {
+		return nil, ErrSourceForbidden
+	}
+	snapshot, err := s.repo.GetSnapshot(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+	if snapshot == nil {
+		return nil, ErrSourceNotFound
+	}
+
+	isbn, doi := snapshotIdentifiers(*snapshot)
+	if isbn == "" && doi == "" {
+		return nil, ErrNoIdentifier
+	}
+	record, err := s.lookupRecord(ctx, isbn, doi)
+	if err != nil {
+		return nil, err
+	}
+
+	filled := fillSnapshot(*snapshot, record)
+	changes := diffSnapshots(*snapshot, filled)
+	result := &RefreshResult{Source: existing, Changes: changes, Providers: record.Providers}
+	if len(changes) == 0 {
+		return result, nil
+	}
+
+	result.Source, err = s.repo.Refresh(ctx, id, filled, actor.UserID)
+	if err != nil {
+		s.logger.Error("failed to refresh source", "error", err, "id", id)
+		return nil, err
+	}
+	if result.Source == nil {
+		return nil, ErrSourceNotFound
+	}
+
+	s.logger.Info("source refreshed", "id", id, "user_id", actor.UserID, "changes", len(changes), "providers", record.Providers)
+	return result, nil
+}
+
+// lookupRecord asks the catalogues by ISBN when one is given, else by DOI
+func (s *Service) lookupRecord(ctx context.Context, isbn, doi string) (*metadata.Record, error) {
+	var record *metadata.Record
+	var err error
+	if isbn != "" {
+		record, err = s.lookup.ISBN(ctx, isbn)
+	} else {
+		record, err = s.lookup.DOI(ctx, doi)
+	}
+	if err != nil && !errors.Is(err, metadata.ErrNotFound) && !errors.Is(err, metadata.ErrInvalidISBN) && !errors.Is(err, metadata.ErrInvalidDOI) {
+		s.logger.Error("metadata lookup failed", "error", err, "isbn", isbn, "doi", doi)
+		return nil, ErrLookupFailed
+	}
+	return record, err
+}
+
+// Search ranks sources against a full-text query with typo tolerance
+func (s *Service) Search(ctx context.Context, params SearchParams) ([]*SearchResult, error) {
+	params.Query = strings.TrimSpace(params.Query)
+	if params.Query == "" {
+		return nil, ErrInvalidSearch
+	}
+	if params.Type != nil && !validSourceType(*params.Type) {
+		return nil, ErrInvalidSearch
+	}
+	params.Tag = tagSlug(params.Tag)
+	if params.Limit <= 0 {
+		params.Limit = 100
+	}
+	if params.Offset < 0 {
+		params.Offset = 0
+	}
+
+	return s.repo.Search(ctx, params)
+}
+
+// ListRelated returns the sources closest in meaning to the given source
+func (s *Service) ListRelated(ctx context.Context, id uuid.UUID, limit int) ([]*RelatedSource, error) {
+	source, err := s.repo.GetByID(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+	if source == nil {
+		return nil, ErrSourceNotFound
+	}
+	if limit <= 0 {
+		limit = 10
+	}
+	if limit > 50 {
+		limit = 50
+	}
+
+	return s.repo.ListRelated(ctx, id, limit)
+}
+
+// applyUpdate copies the set fields of params onto source
+func applyUpdate(source *Source, params UpdateSourceParams) error {
 	if params.Title != nil {
-		existing.Title = *params.Title
+		if *params.Title == "" {
+			return ErrInvalidSource
+		}
+		source.Title = *params.Title
 	}
 	if params.Subtitle != nil {
-		existing.Subtitle = params.Subtitle
+		source.Subtitle = params.Subtitle
 	}
 	if params.Type != nil {
 		if !validSourceType(*params.Type) {
-			return nil, ErrInvalidSource
+			return ErrInvalidSource
 		}
-		existing.Type = *params.Type
+		source.Type = *params.Type
 	}
 	if params.Description != nil {
-		existing.Description = params.Description
+		source.Description = params.Description
 	}
 	if params.Publisher != nil {
-		existing.Publisher = params.Publisher
+		source.Publisher = params.Publisher
 	}
 	if params.ISBN != nil {
-		existing.ISBN = params.ISBN
+		source.ISBN = params.ISBN
 	}
 	if params.DOI != nil {
-		existing.DOI = params.DOI
+		source.DOI = params.DOI
 	}
 	if params.URL != nil {
-		existing.URL = params.URL
+		source.URL = params.URL
 	}
 	if params.ExternalID != nil {
-		existing.ExternalID = params.ExternalID
+		source.ExternalID = params.ExternalID
 	}
 	if params.Tags != nil {
-		existing.Tags = params.Tags
+		cleaned, err := tags.Clean(params.Tags)
+		if err != nil {
+			return ErrInvalidSource
+		}
+		source.Tags = cleaned
 	}
 	if params.PublishedAt != nil {
-		existing.PublishedAt = params.PublishedAt
+		source.PublishedAt = params.PublishedAt
 	}
+	return nil
+}
 
-	updated, err := s.repo.Update(ctx, existing)
-	if err != nil {
-		s.logger.Error("failed to update source", "error", err, "id", id)
-		return nil, err
+// tagSlug canonicalizes a tag filter so any spelling of the tag matches
+func tagSlug(tag *string) *string {
+	if tag == nil {
+		return nil
 	}
+	slug := tags.Slug(*tag)
+	return &slug
+}
 
-	s.logger.Info("source updated", "id", id)
-	return updated, nil
+func ownsSource(source *Source, userID uuid.UUID) bool {
+	return source.CreatedBy != nil && *source.CreatedBy == userID
 }
 
-// Delete removes a source
-func (s *Service) Delete(ctx context.Context, id uuid.UUID) error {
-	if err := s.repo.Delete(ctx, id); err != nil {
-		s.logger.Error("failed to delete source", "error", err, "id", id)
-		return err
+func validProposalStatus(status ProposalStatus) bool {
+	switch status {
+	case ProposalStatusPending, ProposalStatusApproved, ProposalStatusRejected:
+		return true
+	default:
+		return false
 	}
+}
 
-	s.logger.Info("source deleted", "id", id)
+var arxivID = regexp.MustCompile(`^(\d{4}\.\d{4,5}|[a-z-]+(\.[A-Z]{2})?/\d{7})(v\d+)?$`)
+
+// validateTypedMetadata checks that only the metadata for sourceType is set
+// and that its values are in range
+func validateTypedMetadata(sourceType SourceType, typed TypedMetadata) error {
+	valid := false
+	switch sourceType {
+	case SourceTypePaper:
+		paper := typed.Paper
+		valid = typed == TypedMetadata{Paper: paper} &&
+			(paper == nil || paper.ArxivID == nil || arxivID.MatchString(*paper.ArxivID))
+	case SourceTypePodcast:
+		podcast := typed.Podcast
+		valid = typed == TypedMetadata{Podcast: podcast} && podcast != nil &&
+			strings.TrimSpace(podcast.Show) != "" &&
+			(podcast.EpisodeNumber == nil || *podcast.EpisodeNumber > 0) &&
+			!negative(podcast.DurationSeconds) &&
+			(podcast.FeedURL == nil || validWebURL(*podcast.FeedURL))
+	case SourceTypeVideo:
+		video := typed.Video
+		valid = typed == TypedMetadata{Video: video} &&
+			(video == nil || !negative(video.DurationSeconds) && (video.PlatformID == nil || video.Platform != nil))
+	case SourceTypeArticle:
+		article := typed.Article
+		valid = typed == TypedMetadata{Article: article} && (article == nil || !negative(article.WordCount))
+	}
+	if !valid {
+		return ErrInvalidSource
+	}
 	return nil
 }
 
-// Search searches sources by title
-func (s *Service) Search(ctx context.Context, query string, limit, offset int) ([]*Source, error) {
-	if limit <= 0 {
-		limit = 100
-	}
-	if offset < 0 {
-		offset = 0
-	}
+func negative(value *int) bool {
+	return value != nil && *value < 0
+}
 
-	return s.repo.Search(ctx, query, limit, offset)
+func validWebURL(value string) bool {
+	parsed, err := url.Parse(value)
+	return err == nil && (parsed.Scheme == "http" || parsed.Scheme == "https") && parsed.Host != ""
 }
 
 func validSourceType(sourceType SourceType) bool {
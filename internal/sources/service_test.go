@@ -5,14 +5,34 @@ import (
 	"errors"
 	"io"
 	"log/slog"
+	"slices"
+	"strings"
 	"testing"
+	"time"
 
 	"github.com/gofrs/uuid/v5"
+	"github.com/zizouhuweidi/maktaba/internal/echox"
+	"github.com/zizouhuweidi/maktaba/internal/metadata"
 )
 
 type fakeSourceRepo struct {
 	createCalled bool
 	existing     *Source
+	searchParams *SearchParams
+	updated      *Source
+	deleted      bool
+	references   int64
+	proposals    []*Proposal
+	reviewed     *ProposalReview
+	editor       uuid.UUID
+	revisions    []*Revision
+	revertedTo   *Revision
+	catalog      map[uuid.UUID]*Source
+	pairs        []DuplicatePair
+	merged       bool
+	snapshot     *Snapshot
+	refreshed    *Snapshot
+	relatedLimit int
 }
 
 func (r *fakeSourceRepo) Create(ctx context.Context, source *Source) (*Source, error) {
@@ -24,7 +44,19 @@ func (r *fakeSourceRepo) CreateBook(ctx context.Context, params CreateBookParams
 	return nil, nil
 }
 
+func (r *fakeSourceRepo) CreateTyped(ctx context.Context, source *Source, typed TypedMetadata, contributors []ContributorInput) (*SourceDetail, error) {
+	r.createCalled = true
+	return &SourceDetail{Source: source, Metadata: typed.of(source.Type)}, nil
+}
+
+func (r *fakeSourceRepo) GetDetail(ctx context.Context, id uuid.UUID) (*SourceDetail, error) {
+	panic("not implemented")
+}
+
 func (r *fakeSourceRepo) GetByID(ctx context.Context, id uuid.UUID) (*Source, error) {
+	if r.catalog != nil {
+		return r.catalog[id], nil
+	}
 	return r.existing, nil
 }
 
@@ -32,33 +64,42 @@ func (r *fakeSourceRepo) GetBookByID(ctx context.Context, id uuid.UUID) (*Book,
 	return nil, nil
 }
 
-func (r *fakeSourceRepo) List(ctx context.Context, limit, offset int) ([]*Source, error) {
-	return nil, nil
+func (r *fakeSourceRepo) FindBookByISBN(ctx context.Context, isbn10, isbn13 *string) (*Source, error) {
+	return r.existing, nil
 }
 
-func (r *fakeSourceRepo) ListByType(ctx context.Context, sourceType SourceType, limit, offset int) ([]*Source, error) {
+func (r *fakeSourceRepo) List(ctx context.Context, filter ListFilter, page echox.PageRequest) ([]*Source, error) {
 	return nil, nil
 }
 
-func (r *fakeSourceRepo) Update(ctx context.Context, source *Source) (*Source, error) {
+func (r *fakeSourceRepo) Update(ctx context.Context, source *Source, editorID uuid.UUID) (*Source, error) {
+	r.updated = source
+	r.editor = editorID
 	return source, nil
 }
 
 func (r *fakeSourceRepo) Delete(ctx context.Context, id uuid.UUID) error {
+	r.deleted = true
 	return nil
 }
 
-func (r *fakeSourceRepo) Search(ctx context.Context, query string, limit, offset int) ([]*Source, error) {
+func (r *fakeSourceRepo) Search(ctx context.Context, params SearchParams) ([]*SearchResult, error) {
+	r.searchParams = &params
 	return nil, nil
 }
 
-func (r *fakeSourceRepo) Count(ctx context.Context) (int64, error) {
+func (r *fakeSourceRepo) Count(ctx context.Context, filter ListFilter) (int64, error) {
 	return 0, nil
 }
 
+func (r *fakeSourceRepo) ListRelated(ctx context.Context, id uuid.UUID, limit int) ([]*RelatedSource, error) {
+	r.relatedLimit = limit
+	return nil, nil
+}
+
 func TestCreateRejectsInvalidSourceType(t *testing.T) {
 	repo := &fakeSourceRepo{}
-	service := NewService(repo, slog.New(slog.NewTextHandler(io.Discard, nil)))
+	service := NewService(repo, nil, slog.New(slog.NewTextHandler(io.Discard, nil)))
 
 	_, err := service.Create(context.Background(), CreateSourceParams{Title: "Invalid", Type: SourceType("unknown")})
 	if !errors.Is(err, ErrInvalidSource) {
@@ -71,11 +112,545 @@ func TestCreateRejectsInvalidSourceType(t *testing.T) {
 
 func TestUpdateRejectsInvalidSourceType(t *testing.T) {
 	repo := &fakeSourceRepo{existing: &Source{ID: uuid.Must(uuid.NewV7()), Title: "Book", Type: SourceTypeBook}}
-	service := NewService(repo, slog.New(slog.NewTextHandler(io.Discard, nil)))
+	service := NewService(repo, nil, slog.New(slog.NewTextHandler(io.Discard, nil)))
 	invalid := SourceType("unknown")
 
-	_, err := service.Update(context.Background(), repo.existing.ID, UpdateSourceParams{Type: &invalid})
+	_, _, err := service.Update(context.Background(), repo.existing.ID, Actor{Moderator: true}, UpdateSourceParams{Type: &invalid})
 	if !errors.Is(err, ErrInvalidSource) {
 		t.Fatalf("error = %v, want %v", err, ErrInvalidSource)
 	}
 }
+
+func TestUpdateCleansTags(t *testing.T) {
+	repo := &fakeSourceRepo{existing: &Source{ID: uuid.Must(uuid.NewV7()), Title: "Muqaddimah", Type: SourceTypeBook, Tags: []string{"history"}}}
+	service := NewService(repo, nil, slog.New(slog.NewTextHandler(io.Discard, nil)))
+
+	updated, _, err := service.Update(context.Background(), repo.existing.ID, Actor{Moderator: true}, UpdateSourceParams{Tags: []string{" Tārīkh ", "tarikh", "التاريخ", "التّاريخ"}})
+	if err != nil {
+		t.Fatalf("Update() error = %v", err)
+	}
+	if !slices.Equal(updated.Tags, []string{"Tārīkh", "التاريخ"}) {
+		t.Fatalf("tags = %q", updated.Tags)
+	}
+
+	_, _, err = service.Update(context.Background(), repo.existing.ID, Actor{Moderator: true}, UpdateSourceParams{Tags: []string{strings.Repeat("t", 101)}})
+	if !errors.Is(err, ErrInvalidSource) {
+		t.Fatalf("error = %v, want %v", err, ErrInvalidSource)
+	}
+}
+
+func TestSearchRejectsInvalidFilters(t *testing.T) {
+	invalid := SourceType("unknown")
+	tests := []struct {
+		name   string
+		params SearchParams
+	}{
+		{name: "blank query", params: SearchParams{Query: "   "}},
+		{name: "unknown type", params: SearchParams{Query: "khaldun", Type: &invalid}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			repo := &fakeSourceRepo{}
+			service := NewService(repo, nil, slog.New(slog.NewTextHandler(io.Discard, nil)))
+
+			_, err := service.Search(context.Background(), tt.params)
+			if !errors.Is(err, ErrInvalidSearch) {
+				t.Fatalf("error = %v, want %v", err, ErrInvalidSearch)
+			}
+			if repo.searchParams != nil {
+				t.Fatal("repository Search should not be called for invalid params")
+			}
+		})
+	}
+}
+
+func TestListRelatedClampsLimit(t *testing.T) {
+	tests := []struct {
+		limit int
+		want  int
+	}{
+		{limit: 0, want: 10},
+		{limit: -3, want: 10},
+		{limit: 25, want: 25},
+		{limit: 500, want: 50},
+	}
+	for _, tt := range tests {
+		repo := &fakeSourceRepo{existing: &Source{ID: uuid.Must(uuid.NewV7())}}
+		service := NewService(repo, nil, slog.New(slog.NewTextHandler(io.Discard, nil)))
+
+		if _, err := service.ListRelated(context.Background(), repo.existing.ID, tt.limit); err != nil {
+			t.Fatalf("ListRelated(%d) error = %v", tt.limit, err)
+		}
+		if repo.relatedLimit != tt.want {
+			t.Fatalf("ListRelated(%d) limit = %d, want %d", tt.limit, repo.relatedLimit, tt.want)
+		}
+	}
+}
+
+func TestSearchNormalizesParams(t *testing.T) {
+	repo := &fakeSourceRepo{}
+	service := NewService(repo, nil, slog.New(slog.NewTextHandler(io.Discard, nil)))
+
+	if _, err := service.Search(context.Background(), SearchParams{Query: "  ibn khaldun ", Offset: -5}); err != nil {
+		t.Fatalf("Search() error = %v", err)
+	}
+	if repo.searchParams.Query != "ibn khaldun" || repo.searchParams.Limit != 100 || repo.searchParams.Offset != 0 {
+		t.Fatalf("params = %+v", repo.searchParams)
+	}
+
+	tag := "Al-Tārīkh"
+	if _, err := service.Search(context.Background(), SearchParams{Query: "khaldun", Tag: &tag}); err != nil {
+		t.Fatalf("Search() error = %v", err)
+	}
+	if repo.searchParams.Tag == nil || *repo.searchParams.Tag != "al-tarikh" {
+		t.Fatalf("tag = %v, want slug", repo.searchParams.Tag)
+	}
+}
+
+func (r *fakeSourceRepo) CountReferencesByOthers(ctx context.Context, id, userID uuid.UUID) (int64, error) {
+	return r.references, nil
+}
+
+func (r *fakeSourceRepo) CreateProposal(ctx context.Context, proposal *Proposal) (*Proposal, error) {
+	proposal.ID = uuid.Must(uuid.NewV7())
+	proposal.Status = ProposalStatusPending
+	r.proposals = append(r.proposals, proposal)
+	return proposal, nil
+}
+
+func (r *fakeSourceRepo) GetProposal(ctx context.Context, id uuid.UUID) (*Proposal, error) {
+	for _, proposal := range r.proposals {
+		if proposal.ID == id {
+			return proposal, nil
+		}
+	}
+	return nil, nil
+}
+
+func (r *fakeSourceRepo) ListProposals(ctx context.Context, params ListProposalsParams) ([]*Proposal, error) {
+	panic("not implemented")
+}
+
+func (r *fakeSourceRepo) ApproveProposal(ctx context.Context, review ProposalReview, source *Source) (*Proposal, error) {
+	r.reviewed = &review
+	r.updated = source
+	proposal, _ := r.GetProposal(ctx, review.ProposalID)
+	proposal.Status = ProposalStatusApproved
+	return proposal, nil
+}
+
+func (r *fakeSourceRepo) RejectProposal(ctx context.Context, review ProposalReview) (*Proposal, error) {
+	panic("not implemented")
+}
+
+func (r *fakeSourceRepo) ListRevisions(ctx context.Context, sourceID uuid.UUID, limit, offset int) ([]*Revision, error) {
+	panic("not implemented")
+}
+
+func (r *fakeSourceRepo) GetRevision(ctx context.Context, sourceID uuid.UUID, revision int) (*Revision, error) {
+	for _, found := range r.revisions {
+		if found.SourceID == sourceID && found.Revision == revision {
+			return found, nil
+		}
+	}
+	return nil, nil
+}
+
+func (r *fakeSourceRepo) ListByIDs(ctx context.Context, ids []uuid.UUID) ([]*Source, error) {
+	var found []*Source
+	for _, id := range ids {
+		if source, ok := r.catalog[id]; ok {
+			found = append(found, source)
+		}
+	}
+	return found, nil
+}
+
+func (r *fakeSourceRepo) ListDuplicatePairs(ctx context.Context, limit int) ([]DuplicatePair, error) {
+	return r.pairs, nil
+}
+
+func (r *fakeSourceRepo) Merge(ctx context.Context, survivorID, duplicateID, editorID uuid.UUID) (*MergeResult, error) {
+	r.merged = true
+	return &MergeResult{Survivor: r.catalog[survivorID], MergedID: duplicateID}, nil
+}
+
+func (r *fakeSourceRepo) Revert(ctx context.Context, sourceID uuid.UUID, target *Revision, editorID uuid.UUID) (*Source, error) {
+	r.revertedTo = target
+	r.editor = editorID
+	return sourceFromSnapshot(sourceID, target.Snapshot), nil
+}
+
+func (r *fakeSourceRepo) GetSnapshot(ctx context.Context, sourceID uuid.UUID) (*Snapshot, error) {
+	return r.snapshot, nil
+}
+
+func (r *fakeSourceRepo) Refresh(ctx context.Context, sourceID uuid.UUID, snapshot Snapshot, editorID uuid.UUID) (*Source, error) {
+	r.refreshed = &snapshot
+	r.editor = editorID
+	return sourceFromSnapshot(sourceID, snapshot), nil
+}
+
+type fakeLookup struct {
+	record *metadata.Record
+	isbn   string
+	doi    string
+}
+
+func (l *fakeLookup) ISBN(ctx context.Context, isbn string) (*metadata.Record, error) {
+	l.isbn = isbn
+	return l.record, nil
+}
+
+func (l *fakeLookup) DOI(ctx context.Context, doi string) (*metadata.Record, error) {
+	l.doi = doi
+	return l.record, nil
+}
+
+func ownedSource(owner uuid.UUID) *Source {
+	return &Source{ID: uuid.Must(uuid.NewV7()), Title: "Muqaddimah", Type: SourceTypeBook, CreatedBy: &owner}
+}
+
+func TestUpdateByCreatorAppliesDirectly(t *testing.T) {
+	owner := uuid.Must(uuid.NewV7())
+	repo := &fakeSourceRepo{existing: ownedSource(owner)}
+	service := NewService(repo, nil, slog.New(slog.NewTextHandler(io.Discard, nil)))
+	title := "The Muqaddimah"
+
+	source, proposal, err := service.Update(context.Background(), repo.existing.ID, Actor{UserID: owner}, UpdateSourceParams{Title: &title})
+	if err != nil {
+		t.Fatalf("Update() error = %v", err)
+	}
+	if proposal != nil || source == nil || source.Title != title || repo.updated == nil || repo.editor != owner {
+		t.Fatalf("source = %+v, proposal = %+v", source, proposal)
+	}
+}
+
+func TestUpdateByOtherUserCreatesProposal(t *testing.T) {
+	repo := &fakeSourceRepo{existing: ownedSource(uuid.Must(uuid.NewV7()))}
+	service := NewService(repo, nil, slog.New(slog.NewTextHandler(io.Discard, nil)))
+	other := uuid.Must(uuid.NewV7())
+	title := "Vandalised"
+
+	source, proposal, err := service.Update(context.Background(), repo.existing.ID, Actor{UserID: other}, UpdateSourceParams{Title: &title})
+	if err != nil {
+		t.Fatalf("Update() error = %v", err)
+	}
+	if source != nil || proposal == nil || *proposal.ProposedBy != other || *proposal.Changes.Title != title {
+		t.Fatalf("source = %+v, proposal = %+v", source, proposal)
+	}
+	if repo.updated != nil {
+		t.Fatal("repository Update should not be called for a proposal")
+	}
+}
+
+func TestUpdateByModeratorAppliesToLegacySource(t *testing.T) {
+	repo := &fakeSourceRepo{existing: &Source{ID: uuid.Must(uuid.NewV7()), Title: "Legacy", Type: SourceTypeBook}}
+	service := NewService(repo, nil, slog.New(slog.NewTextHandler(io.Discard, nil)))
+	title := "Corrected"
+
+	source, proposal, err := service.Update(context.Background(), repo.existing.ID, Actor{UserID: uuid.Must(uuid.NewV7()), Moderator: true}, UpdateSourceParams{Title: &title})
+	if err != nil || proposal != nil || source.Title != title {
+		t.Fatalf("source = %+v, proposal = %+v, err = %v", source, proposal, err)
+	}
+}
+
+func TestDeleteChecksOwnershipAndReferences(t *testing.T) {
+	owner := uuid.Must(uuid.NewV7())
+	tests := []struct {
+		name       string
+		actor      Actor
+		references int64
+		wantErr    error
+	}{
+		{name: "other user", actor: Actor{UserID: uuid.Must(uuid.NewV7())}, wantErr: ErrSourceForbidden},
+		{name: "creator with other readers", actor: Actor{UserID: owner}, references: 2, wantErr: ErrSourceInUse},
+		{name: "creator alone", actor: Actor{UserID: owner}},
+		{name: "moderator", actor: Actor{UserID: uuid.Must(uuid.NewV7()), Moderator: true}, references: 5},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			repo := &fakeSourceRepo{existing: ownedSource(owner), references: tt.references}
+			service := NewService(repo, nil, slog.New(slog.NewTextHandler(io.Discard, nil)))
+
+			err := service.Delete(context.Background(), repo.existing.ID, tt.actor)
+			if !errors.Is(err, tt.wantErr) {
+				t.Fatalf("error = %v, want %v", err, tt.wantErr)
+			}
+			if repo.deleted != (tt.wantErr == nil) {
+				t.Fatalf("deleted = %v", repo.deleted)
+			}
+		})
+	}
+}
+
+func TestApproveProposalAppliesChanges(t *testing.T) {
+	repo := &fakeSourceRepo{existing: ownedSource(uuid.Must(uuid.NewV7()))}
+	service := NewService(repo, nil, slog.New(slog.NewTextHandler(io.Discard, nil)))
+	title := "Kitab al-Ibar"
+	_, proposal, err := service.Update(context.Background(), repo.existing.ID, Actor{UserID: uuid.Must(uuid.NewV7())}, UpdateSourceParams{Title: &title})
+	if err != nil {
+		t.Fatalf("Update() error = %v", err)
+	}
+
+	if _, err := service.ApproveProposal(context.Background(), proposal.ID, Actor{UserID: uuid.Must(uuid.NewV7())}, nil); !errors.Is(err, ErrSourceForbidden) {
+		t.Fatalf("ApproveProposal() by user error = %v, want %v", err, ErrSourceForbidden)
+	}
+
+	editor := uuid.Must(uuid.NewV7())
+	approved, err := service.ApproveProposal(context.Background(), proposal.ID, Actor{UserID: editor, Moderator: true}, nil)
+	if err != nil {
+		t.Fatalf("ApproveProposal() error = %v", err)
+	}
+	if approved.Status != ProposalStatusApproved || repo.reviewed.ReviewerID != editor || repo.updated.Title != title {
+		t.Fatalf("approved = %+v, updated = %+v", approved, repo.updated)
+	}
+
+	if _, err := service.ApproveProposal(context.Background(), proposal.ID, Actor{UserID: editor, Moderator: true}, nil); !errors.Is(err, ErrProposalReviewed) {
+		t.Fatalf("second ApproveProposal() error = %v, want %v", err, ErrProposalReviewed)
+	}
+}
+
+func TestRevertRequiresAdmin(t *testing.T) {
+	source := ownedSource(uuid.Must(uuid.NewV7()))
+	repo := &fakeSourceRepo{existing: source, revisions: []*Revision{
+		{SourceID: source.ID, Revision: 1, Action: RevisionActionCreate, Snapshot: Snapshot{Title: "Muqaddimah", Type: SourceTypeBook}},
+	}}
+	service := NewService(repo, nil, slog.New(slog.NewTextHandler(io.Discard, nil)))
+
+	if _, err := service.Revert(context.Background(), source.ID, 1, Actor{UserID: uuid.Must(uuid.NewV7()), Moderator: true}); !errors.Is(err, ErrSourceForbidden) {
+		t.Fatalf("Revert() by editor error = %v, want %v", err, ErrSourceForbidden)
+	}
+	if _, err := service.Revert(context.Background(), source.ID, 7, Actor{UserID: uuid.Must(uuid.NewV7()), Admin: true}); !errors.Is(err, ErrRevisionNotFound) {
+		t.Fatalf("Revert() to missing revision error = %v, want %v", err, ErrRevisionNotFound)
+	}
+
+	admin := uuid.Must(uuid.NewV7())
+	reverted, err := service.Revert(context.Background(), source.ID, 1, Actor{UserID: admin, Moderator: true, Admin: true})
+	if err != nil {
+		t.Fatalf("Revert() error = %v", err)
+	}
+	if reverted.Title != "Muqaddimah" || repo.revertedTo.Revision != 1 || repo.editor != admin {
+		t.Fatalf("reverted = %+v, target = %+v", reverted, repo.revertedTo)
+	}
+}
+
+func TestCreateBookRejectsDuplicateISBN(t *testing.T) {
+	repo := &fakeSourceRepo{existing: ownedSource(uuid.Must(uuid.NewV7()))}
+	service := NewService(repo, nil, slog.New(slog.NewTextHandler(io.Discard, nil)))
+	isbn := "9780691120546"
+
+	if _, err := service.CreateBook(context.Background(), CreateBookParams{Title: "The Muqaddimah", ISBN13: &isbn}); !errors.Is(err, ErrDuplicateSource) {
+		t.Fatalf("CreateBook() error = %v, want %v", err, ErrDuplicateSource)
+	}
+}
+
+func TestFindDuplicatesClustersPairs(t *testing.T) {
+	now := time.Now()
+	oldest := &Source{ID: uuid.Must(uuid.NewV7()), Title: "Muqaddimah", Type: SourceTypeBook, CreatedAt: now.Add(-time.Hour)}
+	isbnTwin := &Source{ID: uuid.Must(uuid.NewV7()), Title: "The Muqaddimah", Type: SourceTypeBook, CreatedAt: now}
+	titleTwin := &Source{ID: uuid.Must(uuid.NewV7()), Title: "Al-Muqaddimah", Type: SourceTypeBook, CreatedAt: now.Add(-time.Minute)}
+	paperA := &Source{ID: uuid.Must(uuid.NewV7()), Title: "Attention", Type: SourceTypePaper, CreatedAt: now}
+	paperB := &Source{ID: uuid.Must(uuid.NewV7()), Title: "Attention Is All You Need", Type: SourceTypePaper, CreatedAt: now}
+	repo := &fakeSourceRepo{
+		catalog: map[uuid.UUID]*Source{oldest.ID: oldest, isbnTwin.ID: isbnTwin, titleTwin.ID: titleTwin, paperA.ID: paperA, paperB.ID: paperB},
+		pairs: []DuplicatePair{
+			{SourceID: isbnTwin.ID, DuplicateID: oldest.ID, Reason: DuplicateReasonISBN, Score: 1},
+			{SourceID: paperA.ID, DuplicateID: paperB.ID, Reason: DuplicateReasonDOI, Score: 1},
+			{SourceID: titleTwin.ID, DuplicateID: isbnTwin.ID, Reason: DuplicateReasonTitle, Score: 0.8},
+		},
+	}
+	service := NewService(repo, nil, slog.New(slog.NewTextHandler(io.Discard, nil)))
+
+	if _, err := service.FindDuplicates(context.Background(), Actor{UserID: uuid.Must(uuid.NewV7())}, 0); !errors.Is(err, ErrSourceForbidden) {
+		t.Fatalf("FindDuplicates() by user error = %v, want %v", err, ErrSourceForbidden)
+	}
+
+	clusters, err := service.FindDuplicates(context.Background(), Actor{Moderator: true}, 0)
+	if err != nil {
+		t.Fatalf("FindDuplicates() error = %v", err)
+	}
+	if len(clusters) != 2 {
+		t.Fatalf("clusters = %d, want 2", len(clusters))
+	}
+	books := clusters[0]
+	if len(books.Sources) != 3 || books.Sources[0] != oldest || books.Sources[1] != titleTwin || books.Sources[2] != isbnTwin {
+		t.Fatalf("book cluster sources = %+v, want oldest first", books.Sources)
+	}
+	if !slices.Equal(books.Reasons, []DuplicateReason{DuplicateReasonISBN, DuplicateReasonTitle}) || books.Score != 1 {
+		t.Fatalf("book cluster = %+v", books)
+	}
+	if len(clusters[1].Sources) != 2 || clusters[1].Reasons[0] != DuplicateReasonDOI {
+		t.Fatalf("paper cluster = %+v", clusters[1])
+	}
+}
+
+func TestMergeValidatesSources(t *testing.T) {
+	book := ownedSource(uuid.Must(uuid.NewV7()))
+	twin := ownedSource(uuid.Must(uuid.NewV7()))
+	paper := &Source{ID: uuid.Must(uuid.NewV7()), Title: "Muqaddimah", Type: SourceTypePaper}
+	repo := &fakeSourceRepo{catalog: map[uuid.UUID]*Source{book.ID: book, twin.ID: twin, paper.ID: paper}}
+	service := NewService(repo, nil, slog.New(slog.NewTextHandler(io.Discard, nil)))
+	editor := Actor{UserID: uuid.Must(uuid.NewV7()), Moderator: true}
+
+	tests := []struct {
+		name      string
+		survivor  uuid.UUID
+		duplicate uuid.UUID
+		actor     Actor
+		want      error
+	}{
+		{name: "not a moderator", survivor: book.ID, duplicate: twin.ID, actor: Actor{UserID: *book.CreatedBy}, want: ErrSourceForbidden},
+		{name: "same source", survivor: book.ID, duplicate: book.ID, actor: editor, want: ErrInvalidMerge},
+		{name: "different types", survivor: book.ID, duplicate: paper.ID, actor: editor, want: ErrInvalidMerge},
+		{name: "missing duplicate", survivor: book.ID, duplicate: uuid.Must(uuid.NewV7()), actor: editor, want: ErrSourceNotFound},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if _, err := service.Merge(context.Background(), tt.survivor, tt.duplicate, tt.actor); !errors.Is(err, tt.want) {
+				t.Fatalf("Merge() error = %v, want %v", err, tt.want)
+			}
+		})
+	}
+	if repo.merged {
+		t.Fatal("repository Merge should not be called for invalid merges")
+	}
+
+	result, err := service.Merge(context.Background(), book.ID, twin.ID, editor)
+	if err != nil {
+		t.Fatalf("Merge() error = %v", err)
+	}
+	if !repo.merged || result.Survivor != book || result.MergedID != twin.ID {
+		t.Fatalf("result = %+v", result)
+	}
+}
+
+func TestLookupPrefillsBook(t *testing.T) {
+	publisher := "Princeton University Press"
+	lookup := &fakeLookup{record: &metadata.Record{
+		Title:        "The Muqaddimah",
+		Publisher:    &publisher,
+		Contributors: []metadata.Contributor{{Name: "Ibn Khaldun", Role: "author"}},
+		Subjects:     []string{"History"},
+	}}
+	service := NewService(&fakeSourceRepo{}, lookup, slog.New(slog.NewTextHandler(io.Discard, nil)))
+
+	if _, err := service.Lookup(context.Background(), "9780691120546", "10.1000/182"); !errors.Is(err, ErrNoIdentifier) {
+		t.Fatalf("Lookup() with both identifiers error = %v, want %v", err, ErrNoIdentifier)
+	}
+	params, err := service.Lookup(context.Background(), " 9780691120546 ", "")
+	if err != nil {
+		t.Fatalf("Lookup() error = %v", err)
+	}
+	if lookup.isbn != "9780691120546" || params.Title != "The Muqaddimah" || *params.Publisher != publisher {
+		t.Fatalf("params = %+v", params)
+	}
+	if len(params.Contributors) != 1 || params.Contributors[0].Name != "Ibn Khaldun" || !slices.Equal(params.Tags, []string{"History"}) {
+		t.Fatalf("params = %+v", params)
+	}
+}
+
+func TestRefreshFillsOnlyBlankFields(t *testing.T) {
+	owner := uuid.Must(uuid.NewV7())
+	source := ownedSource(owner)
+	isbn := "9780691120546"
+	description := "Our own description"
+	pages := 465
+	otherDescription := "A provider description"
+	publisher := "Princeton University Press"
+	repo := &fakeSourceRepo{existing: source, snapshot: &Snapshot{
+		Title:       source.Title,
+		Type:        SourceTypeBook,
+		Description: &description,
+		Metadata:    &SnapshotMetadata{ISBN13: &isbn},
+	}}
+	lookup := &fakeLookup{record: &metadata.Record{
+		Title:        "The Muqaddimah",
+		Description:  &otherDescription,
+		Publisher:    &publisher,
+		PageCount:    &pages,
+		Contributors: []metadata.Contributor{{Name: "Ibn Khaldun", Role: "author"}},
+		Providers:    []string{"openlibrary"},
+	}}
+	service := NewService(repo, lookup, slog.New(slog.NewTextHandler(io.Discard, nil)))
+
+	if _, err := service.Refresh(context.Background(), source.ID, Actor{UserID: uuid.Must(uuid.NewV7())}); !errors.Is(err, ErrSourceForbidden) {
+		t.Fatalf("Refresh() by other user error = %v, want %v", err, ErrSourceForbidden)
+	}
+
+	result, err := service.Refresh(context.Background(), source.ID, Actor{UserID: owner})
+	if err != nil {
+		t.Fatalf("Refresh() error = %v", err)
+	}
+	if lookup.isbn != isbn || repo.refreshed == nil || repo.editor != owner {
+		t.Fatalf("looked up %q, refreshed = %+v", lookup.isbn, repo.refreshed)
+	}
+	refreshed := repo.refreshed
+	if refreshed.Title != "Muqaddimah" || *refreshed.Description != description || *refreshed.Publisher != publisher {
+		t.Fatalf("refreshed = %+v", refreshed)
+	}
+	if *refreshed.Metadata.PageCount != pages || *refreshed.Metadata.Publisher != publisher || len(refreshed.Contributors) != 1 {
+		t.Fatalf("refreshed metadata = %+v, contributors = %+v", refreshed.Metadata, refreshed.Contributors)
+	}
+	if len(result.Changes) != 4 || !slices.Equal(result.Providers, []string{"openlibrary"}) {
+		t.Fatalf("changes = %+v, providers = %v", result.Changes, result.Providers)
+	}
+
+	repo.snapshot, repo.refreshed = refreshed, nil
+	result, err = service.Refresh(context.Background(), source.ID, Actor{UserID: owner})
+	if err != nil {
+		t.Fatalf("second Refresh() error = %v", err)
+	}
+	if len(result.Changes) != 0 || repo.refreshed != nil {
+		t.Fatalf("second refresh changes = %+v, refreshed = %+v", result.Changes, repo.refreshed)
+	}
+}
+
+func TestCreateTypedValidatesMetadata(t *testing.T) {
+	venue := "NeurIPS"
+	badArxiv := "attention"
+	arxiv := "1706.03762v7"
+	feed := "https://example.com/feed.xml"
+	badFeed := "feed.xml"
+	episode := 0
+	minutes := -60
+	platformID := "dQw4w9WgXcQ"
+	badDOI := "not-a-doi"
+	tests := []struct {
+		name   string
+		params CreateTypedParams
+		valid  bool
+	}{
+		{name: "paper", params: CreateTypedParams{Source: CreateSourceParams{Title: "Attention", Type: SourceTypePaper}, Typed: TypedMetadata{Paper: &PaperMetadata{Venue: &venue, ArxivID: &arxiv}}}, valid: true},
+		{name: "paper without metadata", params: CreateTypedParams{Source: CreateSourceParams{Title: "Attention", Type: SourceTypePaper}}, valid: true},
+		{name: "paper with bad arXiv ID", params: CreateTypedParams{Source: CreateSourceParams{Title: "Attention", Type: SourceTypePaper}, Typed: TypedMetadata{Paper: &PaperMetadata{ArxivID: &badArxiv}}}},
+		{name: "paper with bad DOI", params: CreateTypedParams{Source: CreateSourceParams{Title: "Attention", Type: SourceTypePaper, DOI: &badDOI}}},
+		{name: "podcast", params: CreateTypedParams{Source: CreateSourceParams{Title: "Episode", Type: SourceTypePodcast}, Typed: TypedMetadata{Podcast: &PodcastMetadata{Show: "Philosophize This", FeedURL: &feed}}}, valid: true},
+		{name: "podcast without show", params: CreateTypedParams{Source: CreateSourceParams{Title: "Episode", Type: SourceTypePodcast}}},
+		{name: "podcast with episode zero", params: CreateTypedParams{Source: CreateSourceParams{Title: "Episode", Type: SourceTypePodcast}, Typed: TypedMetadata{Podcast: &PodcastMetadata{Show: "Show", EpisodeNumber: &episode}}}},
+		{name: "podcast with relative feed", params: CreateTypedParams{Source: CreateSourceParams{Title: "Episode", Type: SourceTypePodcast}, Typed: TypedMetadata{Podcast: &PodcastMetadata{Show: "Show", FeedURL: &badFeed}}}},
+		{name: "video with negative duration", params: CreateTypedParams{Source: CreateSourceParams{Title: "Talk", Type: SourceTypeVideo}, Typed: TypedMetadata{Video: &VideoMetadata{DurationSeconds: &minutes}}}},
+		{name: "video ID without platform", params: CreateTypedParams{Source: CreateSourceParams{Title: "Talk", Type: SourceTypeVideo}, Typed: TypedMetadata{Video: &VideoMetadata{PlatformID: &platformID}}}},
+		{name: "article with paper metadata", params: CreateTypedParams{Source: CreateSourceParams{Title: "Essay", Type: SourceTypeArticle}, Typed: TypedMetadata{Paper: &PaperMetadata{Venue: &venue}}}},
+		{name: "book", params: CreateTypedParams{Source: CreateSourceParams{Title: "Muqaddimah", Type: SourceTypeBook}}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			repo := &fakeSourceRepo{}
+			service := NewService(repo, nil, slog.New(slog.NewTextHandler(io.Discard, nil)))
+
+			detail, err := service.CreateTyped(context.Background(), tt.params)
+			if tt.valid {
+				if err != nil || !repo.createCalled || detail.Type != tt.params.Source.Type {
+					t.Fatalf("CreateTyped() = %+v, %v", detail, err)
+				}
+				return
+			}
+			if !errors.Is(err, ErrInvalidSource) || repo.createCalled {
+				t.Fatalf("CreateTyped() error = %v, created = %v", err, repo.createCalled)
+			}
+		})
+	}
+}
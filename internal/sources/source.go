@@ -7,6 +7,8 @@ import (
 	"time"
 
 	"github.com/gofrs/uuid/v5"
+	"github.com/zizouhuweidi/maktaba/internal/echox"
+	"github.com/zizouhuweidi/maktaba/internal/metadata"
 )
 
 // SourceType represents the type of knowledge source
@@ -21,6 +23,29 @@ const (
 	SourceTypeEssay   SourceType = "essay"
 )
 
+// Outbox aggregate and event types published by this context.
+const (
+	AggregateSource    = "source"
+	EventSourceCreated = "source.created"
+	EventSourceUpdated = "source.updated"
+	EventSourceDeleted = "source.deleted"
+	EventSourceMerged  = "source.merged"
+
+	AggregateSourceProposal     = "source_proposal"
+	EventSourceProposalCreated  = "source_proposal.created"
+	EventSourceProposalApproved = "source_proposal.approved"
+	EventSourceProposalRejected = "source_proposal.rejected"
+)
+
+// ProposalStatus tracks a change proposal through moderation
+type ProposalStatus string
+
+const (
+	ProposalStatusPending  ProposalStatus = "pending"
+	ProposalStatusApproved ProposalStatus = "approved"
+	ProposalStatusRejected ProposalStatus = "rejected"
+)
+
 // Source represents a knowledge source entity
 type Source struct {
 	ID          uuid.UUID  `json:"id"`
@@ -37,6 +62,30 @@ type Source struct {
 	PublishedAt *time.Time `json:"published_at,omitempty" db:"published_at"`
 	CreatedAt   time.Time  `json:"created_at" db:"created_at"`
 	UpdatedAt   time.Time  `json:"updated_at" db:"updated_at"`
+	CreatedBy   *uuid.UUID `json:"created_by,omitempty" db:"created_by"`
+}
+
+// Proposal is an edit to a source by someone other than its creator, held
+// until an editor approves or rejects it.
+type Proposal struct {
+	ID         uuid.UUID          `json:"id"`
+	SourceID   uuid.UUID          `json:"source_id"`
+	ProposedBy *uuid.UUID         `json:"proposed_by,omitempty"`
+	Changes    UpdateSourceParams `json:"changes"`
+	Status     ProposalStatus     `json:"status"`
+	ReviewedBy *uuid.UUID         `json:"reviewed_by,omitempty"`
+	ReviewNote *string            `json:"review_note,omitempty"`
+	ReviewedAt *time.Time         `json:"reviewed_at,omitempty"`
+	CreatedAt  time.Time          `json:"created_at"`
+	UpdatedAt  time.Time          `json:"updated_at"`
+}
+
+// Actor identifies who is changing the catalog and whether they may
+// moderate it or, as an admin, revert it.
+type Actor struct {
+	UserID    uuid.UUID
+	Moderator bool
+	Admin     bool
 }
 
 type Contributor struct {
@@ -60,37 +109,164 @@ type BookMetadata struct {
 	UpdatedAt time.Time `json:"updated_at"`
 }
 
+// PaperMetadata describes a paper. Its DOI is kept on the source and its
+// authors are contributors.
+type PaperMetadata struct {
+	Venue   *string `json:"venue,omitempty"`
+	ArxivID *string `json:"arxiv_id,omitempty"`
+}
+
+type PodcastMetadata struct {
+	Show            string  `json:"show"`
+	EpisodeNumber   *int    `json:"episode_number,omitempty"`
+	DurationSeconds *int    `json:"duration_seconds,omitempty"`
+	FeedURL         *string `json:"feed_url,omitempty"`
+}
+
+// VideoMetadata describes a video. PlatformID is the video's ID on Platform,
+// such as a YouTube video ID.
+type VideoMetadata struct {
+	Channel         *string `json:"channel,omitempty"`
+	DurationSeconds *int    `json:"duration_seconds,omitempty"`
+	Platform        *string `json:"platform,omitempty"`
+	PlatformID      *string `json:"platform_id,omitempty"`
+}
+
+// ArticleMetadata describes an article. Its author is a contributor.
+type ArticleMetadata struct {
+	Site      *string `json:"site,omitempty"`
+	WordCount *int    `json:"word_count,omitempty"`
+}
+
+// TypedMetadata holds the metadata of a paper, podcast, video or article.
+// Only the field matching the source type is set.
+type TypedMetadata struct {
+	Paper   *PaperMetadata   `json:"paper,omitempty"`
+	Podcast *PodcastMetadata `json:"podcast,omitempty"`
+	Video   *VideoMetadata   `json:"video,omitempty"`
+	Article *ArticleMetadata `json:"article,omitempty"`
+}
+
+// of returns the metadata for sourceType, or nil when there is none
+func (t TypedMetadata) of(sourceType SourceType) any {
+	switch {
+	case sourceType == SourceTypePaper && t.Paper != nil:
+		return t.Paper
+	case sourceType == SourceTypePodcast && t.Podcast != nil:
+		return t.Podcast
+	case sourceType == SourceTypeVideo && t.Video != nil:
+		return t.Video
+	case sourceType == SourceTypeArticle && t.Article != nil:
+		return t.Article
+	default:
+		return nil
+	}
+}
+
+// SourceDetail is a source with its contributors and the metadata for its
+// type: a *BookMetadata, *PaperMetadata, *PodcastMetadata, *VideoMetadata or
+// *ArticleMetadata, told apart by the source's type.
+type SourceDetail struct {
+	*Source
+	Metadata     any            `json:"metadata,omitempty"`
+	Contributors []*Contributor `json:"contributors,omitempty"`
+}
+
 type Book struct {
 	Source       *Source        `json:"source"`
 	Metadata     *BookMetadata  `json:"metadata"`
 	Contributors []*Contributor `json:"contributors,omitempty"`
 }
 
-// Tag represents a taxonomy tag
-type Tag struct {
-	ID        uuid.UUID `json:"id"`
-	Name      string    `json:"name"`
-	CreatedAt time.Time `json:"created_at"`
+// SearchResult is a source matched by a search query together with its
+// relevance rank and a highlighted snippet.
+type SearchResult struct {
+	*Source
+	Rank    float32 `json:"rank"`
+	Snippet string  `json:"snippet"`
+}
+
+// RelatedSource is a source ranked by semantic similarity to another source.
+type RelatedSource struct {
+	*Source
+	Similarity float32 `json:"similarity"`
 }
 
 // Repository defines the interface for source data access
 type Repository interface {
 	Create(ctx context.Context, source *Source) (*Source, error)
 	CreateBook(ctx context.Context, params CreateBookParams) (*Book, error)
+	CreateTyped(ctx context.Context, source *Source, typed TypedMetadata, contributors []ContributorInput) (*SourceDetail, error)
 	GetByID(ctx context.Context, id uuid.UUID) (*Source, error)
+	GetDetail(ctx context.Context, id uuid.UUID) (*SourceDetail, error)
 	GetBookByID(ctx context.Context, id uuid.UUID) (*Book, error)
-	List(ctx context.Context, limit, offset int) ([]*Source, error)
-	ListByType(ctx context.Context, sourceType SourceType, limit, offset int) ([]*Source, error)
-	Update(ctx context.Context, source *Source) (*Source, error)
+	FindBookByISBN(ctx context.Context, isbn10, isbn13 *string) (*Source, error)
+	List(ctx context.Context, filter ListFilter, page echox.PageRequest) ([]*Source, error)
+	Update(ctx context.Context, source *Source, editorID uuid.UUID) (*Source, error)
 	Delete(ctx context.Context, id uuid.UUID) error
-	Search(ctx context.Context, query string, limit, offset int) ([]*Source, error)
-	Count(ctx context.Context) (int64, error)
+	Search(ctx context.Context, params SearchParams) ([]*SearchResult, error)
+	Count(ctx context.Context, filter ListFilter) (int64, error)
+	ListRelated(ctx context.Context, id uuid.UUID, limit int) ([]*RelatedSource, error)
+	CountReferencesByOthers(ctx context.Context, id, userID uuid.UUID) (int64, error)
+	CreateProposal(ctx context.Context, proposal *Proposal) (*Proposal, error)
+	GetProposal(ctx context.Context, id uuid.UUID) (*Proposal, error)
+	ListProposals(ctx context.Context, params ListProposalsParams) ([]*Proposal, error)
+	ApproveProposal(ctx context.Context, review ProposalReview, source *Source) (*Proposal, error)
+	RejectProposal(ctx context.Context, review ProposalReview) (*Proposal, error)
+	ListRevisions(ctx context.Context, sourceID uuid.UUID, limit, offset int) ([]*Revision, error)
+	GetRevision(ctx context.Context, sourceID uuid.UUID, revision int) (*Revision, error)
+	Revert(ctx context.Context, sourceID uuid.UUID, target *Revision, editorID uuid.UUID) (*Source, error)
+	GetSnapshot(ctx context.Context, sourceID uuid.UUID) (*Snapshot, error)
+	Refresh(ctx context.Context, sourceID uuid.UUID, snapshot Snapshot, editorID uuid.UUID) (*Source, error)
+	ListByIDs(ctx context.Context, ids []uuid.UUID) ([]*Source, error)
+	ListDuplicatePairs(ctx context.Context, limit int) ([]DuplicatePair, error)
+	Merge(ctx context.Context, survivorID, duplicateID, editorID uuid.UUID) (*MergeResult, error)
+}
+
+// MetadataLookup finds bibliographic records in external catalogues
+type MetadataLookup interface {
+	ISBN(ctx context.Context, isbn string) (*metadata.Record, error)
+	DOI(ctx context.Context, doi string) (*metadata.Record, error)
+}
+
+// ListFilter narrows the source list; nil fields match everything
+type ListFilter struct {
+	Type *SourceType
+	Tag  *string
+}
+
+// ListProposalsParams filters change proposals
+type ListProposalsParams struct {
+	Status     *ProposalStatus
+	SourceID   *uuid.UUID
+	ProposedBy *uuid.UUID
+	Limit      int
+	Offset     int
+}
+
+// ProposalReview records an editor's decision on a pending proposal
+type ProposalReview struct {
+	ProposalID uuid.UUID
+	ReviewerID uuid.UUID
+	Note       *string
+}
+
+// SearchParams contains the query and optional filters for searching sources
+type SearchParams struct {
+	Query    string
+	Type     *SourceType
+	Tag      *string
+	Language *string
+	Year     *int
+	Limit    int
+	Offset   int
 }
 
 type CreateBookParams struct {
 	Title        string
 	Subtitle     *string
 	Description  *string
+	DOI          *string
 	URL          *string
 	ExternalID   *string
 	Tags         []string
@@ -102,11 +278,12 @@ type CreateBookParams struct {
 	Language     *string
 	CoverURL     *string
 	Contributors []ContributorInput
+	CreatedBy    uuid.UUID
 }
 
 type ContributorInput struct {
-	Name string
-	Role string
+	Name string `json:"name"`
+	Role string `json:"role,omitempty"`
 }
 
 // CreateSourceParams contains parameters for creating a source
@@ -122,19 +299,29 @@ type CreateSourceParams struct {
 	ExternalID  *string
 	Tags        []string
 	PublishedAt *time.Time
+	CreatedBy   uuid.UUID
+}
+
+// CreateTypedParams contains parameters for creating a paper, podcast, video
+// or article together with its typed metadata
+type CreateTypedParams struct {
+	Source       CreateSourceParams
+	Typed        TypedMetadata
+	Contributors []ContributorInput
 }
 
-// UpdateSourceParams contains parameters for updating a source
+// UpdateSourceParams contains parameters for updating a source. It is also
+// stored as the changes of a proposal, hence the JSON tags.
 type UpdateSourceParams struct {
-	Title       *string
-	Subtitle    *string
-	Type        *SourceType
-	Description *string
-	Publisher   *string
-	ISBN        *string
-	DOI         *string
-	URL         *string
-	ExternalID  *string
-	Tags        []string
-	PublishedAt *time.Time
+	Title       *string     `json:"title,omitempty"`
+	Subtitle    *string     `json:"subtitle,omitempty"`
+	Type        *SourceType `json:"type,omitempty"`
+	Description *string     `json:"description,omitempty"`
+	Publisher   *string     `json:"publisher,omitempty"`
+	ISBN        *string     `json:"isbn,omitempty"`
+	DOI         *string     `json:"doi,omitempty"`
+	URL         *string     `json:"url,omitempty"`
+	ExternalID  *string     `json:"external_id,omitempty"`
+	Tags        []string    `json:"tags,omitempty"`
+	PublishedAt *time.Time  `json:"published_at,omitempty"`
 }
@@ -0,0 +1,67 @@
+package stats
+
+import (
+	"errors"
+	"log/slog"
+	"net/http"
+
+	"github.com/labstack/echo/v5"
+	"github.com/zizouhuweidi/maktaba/internal/auth"
+	"github.com/zizouhuweidi/maktaba/internal/echox"
+)
+
+type Handler struct {
+	service *Service
+	logger  *slog.Logger
+}
+
+func NewHandler(service *Service, logger *slog.Logger) *Handler {
+	return &Handler{service: service, logger: logger}
+}
+
+func (h *Handler) RegisterPublicRoutes(e *echo.Echo) {
+	e.GET("/users/:username/year-in-review", h.YearInReview)
+}
+
+func (h *Handler) RegisterProtectedRoutes(g *echo.Group) {
+	g.GET("/me/stats", h.Mine)
+}
+
+func (h *Handler) Mine(c *echo.Context) error {
+	userID, ok := auth.UserID(c)
+	if !ok {
+		return echo.NewHTTPError(http.StatusUnauthorized, "authentication required")
+	}
+	year, err := echox.QueryInt(c, "year", "year")
+	if err != nil {
+		return err
+	}
+
+	stats, err := h.service.ForUser(c.Request().Context(), userID, year)
+	if errors.Is(err, ErrInvalidYear) {
+		return echo.NewHTTPError(http.StatusBadRequest, "invalid year")
+	}
+	if err != nil {
+		return echo.NewHTTPError(http.StatusInternalServerError, "failed to get stats")
+	}
+	return c.JSON(http.StatusOK, stats)
+}
+
+func (h *Handler) YearInReview(c *echo.Context) error {
+	year, err := echox.QueryInt(c, "year", "year")
+	if err != nil {
+		return err
+	}
+
+	stats, err := h.service.YearInReview(c.Request().Context(), c.Param("username"), year)
+	if errors.Is(err, ErrInvalidYear) {
+		return echo.NewHTTPError(http.StatusBadRequest, "invalid year")
+	}
+	if errors.Is(err, ErrNotShared) {
+		return echo.NewHTTPError(http.StatusNotFound, "year in review not found")
+	}
+	if err != nil {
+		return echo.NewHTTPError(http.StatusInternalServerError, "failed to get year in review")
+	}
+	return c.JSON(http.StatusOK, stats)
+}
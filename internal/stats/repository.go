@@ -0,0 +1,100 @@
+package stats
+
+import (
+	"context"
+	"errors"
+
+	"github.com/gofrs/uuid/v5"
+	"github.com/jackc/pgx/v5"
+	"github.com/zizouhuweidi/maktaba/internal/db"
+	"github.com/zizouhuweidi/maktaba/internal/db/dbgen"
+)
+
+type postgresRepository struct {
+	queries *dbgen.Queries
+}
+
+func NewPostgresRepository(d *db.DB) Repository {
+	return &postgresRepository{queries: dbgen.New(d.Pool)}
+}
+
+func (r *postgresRepository) Aggregate(ctx context.Context, filter Filter) (*Aggregates, error) {
+	userID, from, to := db.PGUUID(filter.UserID), db.PGTimestamptz(filter.From), db.PGTimestamptz(filter.To)
+	aggregates := &Aggregates{CompletedByMonth: map[int]int{}}
+
+	months, err := r.queries.ListCompletedByMonth(ctx, dbgen.ListCompletedByMonthParams{UserID: userID, YearStart: from, YearEnd: to, PublicOnly: filter.PublicOnly})
+	if err != nil {
+		return nil, err
+	}
+	for _, row := range months {
+		aggregates.CompletedByMonth[int(row.Month)] = int(row.Completed)
+	}
+
+	types, err := r.queries.ListCompletedBySourceType(ctx, dbgen.ListCompletedBySourceTypeParams{UserID: userID, YearStart: from, YearEnd: to, PublicOnly: filter.PublicOnly})
+	if err != nil {
+		return nil, err
+	}
+	aggregates.CompletedByType = make([]TypeCount, 0, len(types))
+	for _, row := range types {
+		aggregates.CompletedByType = append(aggregates.CompletedByType, TypeCount{Type: row.Type, Completed: int(row.Completed)})
+	}
+
+	contributors, err := r.queries.ListTopCompletedContributors(ctx, dbgen.ListTopCompletedContributorsParams{UserID: userID, YearStart: from, YearEnd: to, PublicOnly: filter.PublicOnly, MaxRows: int32(filter.Top)})
+	if err != nil {
+		return nil, err
+	}
+	aggregates.TopContributors = make([]ContributorCount, 0, len(contributors))
+	for _, row := range contributors {
+		aggregates.TopContributors = append(aggregates.TopContributors, ContributorCount{ID: db.UUID(row.ID), Name: row.Name, Completed: int(row.Completed)})
+	}
+
+	tags, err := r.queries.ListTopCompletedTags(ctx, dbgen.ListTopCompletedTagsParams{UserID: userID, YearStart: from, YearEnd: to, PublicOnly: filter.PublicOnly, MaxRows: int32(filter.Top)})
+	if err != nil {
+		return nil, err
+	}
+	aggregates.TopTags = make([]TagCount, 0, len(tags))
+	for _, row := range tags {
+		aggregates.TopTags = append(aggregates.TopTags, TagCount{Tag: row.Tag, Completed: int(row.Completed)})
+	}
+
+	consumed, err := r.queries.GetConsumedTotals(ctx, dbgen.GetConsumedTotalsParams{UserID: userID, YearStart: from, YearEnd: to, PublicOnly: filter.PublicOnly})
+	if err != nil {
+		return nil, err
+	}
+	aggregates.PagesRead, aggregates.MinutesConsumed = consumed.Pages, consumed.Minutes
+
+	reviews, err := r.queries.GetReviewStats(ctx, dbgen.GetReviewStatsParams{UserID: userID, YearStart: from, YearEnd: to, PublicOnly: filter.PublicOnly})
+	if err != nil {
+		return nil, err
+	}
+	aggregates.Reviews, aggregates.AverageRating = int(reviews.Reviews), reviews.AverageRating
+
+	notes, err := r.queries.CountNotesInYear(ctx, dbgen.CountNotesInYearParams{UserID: userID, YearStart: from, YearEnd: to, PublicOnly: filter.PublicOnly})
+	if err != nil {
+		return nil, err
+	}
+	aggregates.Notes = int(notes)
+
+	days, err := r.queries.ListActivityDays(ctx, dbgen.ListActivityDaysParams{UserID: userID, YearStart: from, YearEnd: to, PublicOnly: filter.PublicOnly})
+	if err != nil {
+		return nil, err
+	}
+	for _, day := range days {
+		if day.Valid {
+			aggregates.ActivityDays = append(aggregates.ActivityDays, day.Time)
+		}
+	}
+	return aggregates, nil
+}
+
+func (r *postgresRepository) YearInReviewUserID(ctx context.Context, username string) (*uuid.UUID, error) {
+	row, err := r.queries.GetYearInReviewUserID(ctx, username)
+	if errors.Is(err, pgx.ErrNoRows) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	id := db.UUID(row)
+	return &id, nil
+}
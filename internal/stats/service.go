@@ -0,0 +1,124 @@
+package stats
+
+import (
+	"context"
+	"errors"
+	"log/slog"
+	"time"
+
+	"github.com/gofrs/uuid/v5"
+)
+
+var (
+	ErrInvalidYear = errors.New("invalid year")
+	ErrNotShared   = errors.New("year in review not shared")
+)
+
+// topCount is how many contributors and tags a summary lists
+const topCount = 5
+
+type Service struct {
+	repo   Repository
+	logger *slog.Logger
+}
+
+func NewService(repo Repository, logger *slog.Logger) *Service {
+	return &Service{repo: repo, logger: logger}
+}
+
+// ForUser summarises everything the user did in a year. A nil year means
+// the current one.
+func (s *Service) ForUser(ctx context.Context, userID uuid.UUID, year *int) (*Stats, error) {
+	return s.summarise(ctx, userID, year, false)
+}
+
+// YearInReview summarises a user's public activity, if they share it on
+// their public profile.
+func (s *Service) YearInReview(ctx context.Context, username string, year *int) (*Stats, error) {
+	if username == "" {
+		return nil, ErrNotShared
+	}
+	userID, err := s.repo.YearInReviewUserID(ctx, username)
+	if err != nil {
+		return nil, err
+	}
+	if userID == nil {
+		return nil, ErrNotShared
+	}
+	return s.summarise(ctx, *userID, year, true)
+}
+
+func (s *Service) summarise(ctx context.Context, userID uuid.UUID, year *int, publicOnly bool) (*Stats, error) {
+	now := time.Now().UTC()
+	selected := now.Year()
+	if year != nil {
+		selected = *year
+	}
+	if selected < 1000 || selected > now.Year()+1 {
+		return nil, ErrInvalidYear
+	}
+
+	from := time.Date(selected, time.January, 1, 0, 0, 0, 0, time.UTC)
+	aggregates, err := s.repo.Aggregate(ctx, Filter{
+		UserID:     userID,
+		From:       from,
+		To:         from.AddDate(1, 0, 0),
+		PublicOnly: publicOnly,
+		Top:        topCount,
+	})
+	if err != nil {
+		s.logger.Error("failed to aggregate stats", "error", err, "user_id", userID, "year", selected)
+		return nil, err
+	}
+	return build(selected, aggregates, now), nil
+}
+
+func build(year int, aggregates *Aggregates, now time.Time) *Stats {
+	stats := &Stats{
+		Year:             year,
+		CompletedByMonth: make([]MonthCount, 0, 12),
+		CompletedByType:  aggregates.CompletedByType,
+		PagesRead:        aggregates.PagesRead,
+		MinutesConsumed:  aggregates.MinutesConsumed,
+		Reviews:          aggregates.Reviews,
+		Notes:            aggregates.Notes,
+		TopContributors:  aggregates.TopContributors,
+		TopTags:          aggregates.TopTags,
+	}
+	for month := 1; month <= 12; month++ {
+		completed := aggregates.CompletedByMonth[month]
+		stats.CompletedByMonth = append(stats.CompletedByMonth, MonthCount{Month: month, Completed: completed})
+		stats.Completed += completed
+	}
+	if aggregates.Reviews > 0 {
+		average := aggregates.AverageRating
+		stats.AverageRating = &average
+	}
+	stats.LongestStreak, stats.CurrentStreak = streaks(aggregates.ActivityDays, now)
+	return stats
+}
+
+// streaks returns the longest run of consecutive active days and the run
+// that is still going: one that ends today or yesterday. days must be
+// sorted and distinct.
+func streaks(days []time.Time, today time.Time) (longest, current int) {
+	run := 0
+	var previous time.Time
+	for i, day := range days {
+		if i > 0 && day.Sub(previous) == 24*time.Hour {
+			run++
+		} else {
+			run = 1
+		}
+		longest = max(longest, run)
+		previous = day
+	}
+	if len(days) == 0 {
+		return 0, 0
+	}
+	today = time.Date(today.Year(), today.Month(), today.Day(), 0, 0, 0, 0, time.UTC)
+	if gap := today.Sub(previous); gap == 0 || gap == 24*time.Hour {
+		current = run
+	}
+	return longest, current
+}
@@ -0,0 +1,110 @@
+package stats
+
+import (
+	"context"
+	"errors"
+	"io"
+	"log/slog"
+	"testing"
+	"time"
+
+	"github.com/gofrs/uuid/v5"
+)
+
+type fakeStatsRepo struct {
+	aggregates *Aggregates
+	filter     Filter
+	sharedID   *uuid.UUID
+}
+
+func (r *fakeStatsRepo) Aggregate(ctx context.Context, filter Filter) (*Aggregates, error) {
+	r.filter = filter
+	return r.aggregates, nil
+}
+
+func (r *fakeStatsRepo) YearInReviewUserID(ctx context.Context, username string) (*uuid.UUID, error) {
+	return r.sharedID, nil
+}
+
+func testService(repo Repository) *Service {
+	return NewService(repo, slog.New(slog.NewTextHandler(io.Discard, nil)))
+}
+
+func TestForUserBuildsYearSummary(t *testing.T) {
+	repo := &fakeStatsRepo{aggregates: &Aggregates{
+		CompletedByMonth: map[int]int{1: 2, 6: 3},
+		Reviews:          4,
+		AverageRating:    3.75,
+	}}
+	year := 2025
+
+	stats, err := testService(repo).ForUser(context.Background(), uuid.Must(uuid.NewV7()), &year)
+	if err != nil {
+		t.Fatalf("ForUser returned error: %v", err)
+	}
+	if want := time.Date(2025, 1, 1, 0, 0, 0, 0, time.UTC); !repo.filter.From.Equal(want) || !repo.filter.To.Equal(want.AddDate(1, 0, 0)) {
+		t.Fatalf("filter = %v..%v, want the 2025 calendar year", repo.filter.From, repo.filter.To)
+	}
+	if repo.filter.PublicOnly {
+		t.Fatal("own stats should include private activity")
+	}
+	if stats.Completed != 5 || len(stats.CompletedByMonth) != 12 || stats.CompletedByMonth[5].Completed != 3 {
+		t.Fatalf("completed = %d by month %+v, want 5 with 3 in June", stats.Completed, stats.CompletedByMonth)
+	}
+	if stats.AverageRating == nil || *stats.AverageRating != 3.75 {
+		t.Fatalf("AverageRating = %v, want 3.75", stats.AverageRating)
+	}
+}
+
+func TestForUserRejectsInvalidYear(t *testing.T) {
+	year := time.Now().Year() + 2
+	if _, err := testService(&fakeStatsRepo{}).ForUser(context.Background(), uuid.Must(uuid.NewV7()), &year); !errors.Is(err, ErrInvalidYear) {
+		t.Fatalf("error = %v, want %v", err, ErrInvalidYear)
+	}
+}
+
+func TestYearInReviewRequiresSharing(t *testing.T) {
+	if _, err := testService(&fakeStatsRepo{}).YearInReview(context.Background(), "reader", nil); !errors.Is(err, ErrNotShared) {
+		t.Fatalf("error = %v, want %v", err, ErrNotShared)
+	}
+
+	userID := uuid.Must(uuid.NewV7())
+	repo := &fakeStatsRepo{aggregates: &Aggregates{}, sharedID: &userID}
+	stats, err := testService(repo).YearInReview(context.Background(), "reader", nil)
+	if err != nil {
+		t.Fatalf("YearInReview returned error: %v", err)
+	}
+	if !repo.filter.PublicOnly || repo.filter.UserID != userID {
+		t.Fatalf("filter = %+v, want public activity of %s", repo.filter, userID)
+	}
+	if stats.AverageRating != nil {
+		t.Fatalf("AverageRating = %v, want nil without reviews", *stats.AverageRating)
+	}
+}
+
+func TestStreaks(t *testing.T) {
+	day := func(month time.Month, d int) time.Time { return time.Date(2026, month, d, 0, 0, 0, 0, time.UTC) }
+	days := []time.Time{day(1, 1), day(1, 2), day(1, 3), day(1, 10), day(2, 27), day(2, 28), day(3, 1)}
+
+	tests := []struct {
+		name        string
+		today       time.Time
+		wantLongest int
+		wantCurrent int
+	}{
+		{"run ends today", day(3, 1).Add(15 * time.Hour), 3, 3},
+		{"run ended yesterday", day(3, 2), 3, 3},
+		{"run broken", day(3, 5), 3, 0},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			longest, current := streaks(days, tt.today)
+			if longest != tt.wantLongest || current != tt.wantCurrent {
+				t.Fatalf("streaks = %d, %d, want %d, %d", longest, current, tt.wantLongest, tt.wantCurrent)
+			}
+		})
+	}
+	if longest, current := streaks(nil, day(3, 1)); longest != 0 || current != 0 {
+		t.Fatalf("streaks(nil) = %d, %d, want 0, 0", longest, current)
+	}
+}
@@ -0,0 +1,77 @@
+// Package stats aggregates a user's library, reviews, notes and reading
+// sessions into yearly reading statistics.
+package stats
+
+import (
+	"context"
+	"time"
+
+	"github.com/gofrs/uuid/v5"
+)
+
+// Stats summarises one calendar year (UTC) of a user's activity
+type Stats struct {
+	Year             int                `json:"year"`
+	Completed        int                `json:"completed"`
+	CompletedByMonth []MonthCount       `json:"completed_by_month"`
+	CompletedByType  []TypeCount        `json:"completed_by_type"`
+	PagesRead        int64              `json:"pages_read"`
+	MinutesConsumed  int64              `json:"minutes_consumed"`
+	Reviews          int                `json:"reviews"`
+	AverageRating    *float64           `json:"average_rating,omitempty"`
+	Notes            int                `json:"notes"`
+	TopContributors  []ContributorCount `json:"top_contributors"`
+	TopTags          []TagCount         `json:"top_tags"`
+	LongestStreak    int                `json:"longest_streak"`
+	CurrentStreak    int                `json:"current_streak"`
+}
+
+type MonthCount struct {
+	Month     int `json:"month"`
+	Completed int `json:"completed"`
+}
+
+type TypeCount struct {
+	Type      string `json:"type"`
+	Completed int    `json:"completed"`
+}
+
+type ContributorCount struct {
+	ID        uuid.UUID `json:"id"`
+	Name      string    `json:"name"`
+	Completed int       `json:"completed"`
+}
+
+type TagCount struct {
+	Tag       string `json:"tag"`
+	Completed int    `json:"completed"`
+}
+
+// Filter selects the activity an aggregate covers. PublicOnly restricts it
+// to public library items, reviews and notes.
+type Filter struct {
+	UserID     uuid.UUID
+	From       time.Time
+	To         time.Time
+	PublicOnly bool
+	Top        int
+}
+
+// Aggregates are the raw totals the database returns for a filter
+type Aggregates struct {
+	CompletedByMonth map[int]int
+	CompletedByType  []TypeCount
+	TopContributors  []ContributorCount
+	TopTags          []TagCount
+	PagesRead        int64
+	MinutesConsumed  int64
+	Reviews          int
+	AverageRating    float64
+	Notes            int
+	ActivityDays     []time.Time
+}
+
+type Repository interface {
+	Aggregate(ctx context.Context, filter Filter) (*Aggregates, error)
+	YearInReviewUserID(ctx context.Context, username string) (*uuid.UUID, error)
+}
@@ -0,0 +1,135 @@
+package tags
+
+import (
+	"errors"
+	"log/slog"
+	"net/http"
+
+	"github.com/gofrs/uuid/v5"
+	"github.com/labstack/echo/v5"
+	"github.com/zizouhuweidi/maktaba/internal/auth"
+	"github.com/zizouhuweidi/maktaba/internal/echox"
+)
+
+type Handler struct {
+	service *Service
+	logger  *slog.Logger
+}
+
+func NewHandler(service *Service, logger *slog.Logger) *Handler {
+	return &Handler{service: service, logger: logger}
+}
+
+type RenameRequest struct {
+	Name string `json:"name" validate:"required"`
+}
+
+type MergeRequest struct {
+	IntoID string `json:"into_id" validate:"required"`
+}
+
+func (h *Handler) RegisterPublicRoutes(e *echo.Echo) {
+	e.GET("/tags", h.List)
+	e.GET("/tags/:slug", h.GetBySlug)
+}
+
+func (h *Handler) RegisterProtectedRoutes(g *echo.Group) {
+	g.GET("/me/tags", h.ListMine)
+	g.PUT("/tags/:id", h.Rename, auth.RequireRole(auth.RoleEditor, auth.RoleAdmin))
+	g.POST("/tags/:id/merge", h.Merge, auth.RequireRole(auth.RoleEditor, auth.RoleAdmin))
+}
+
+// List answers tag autocomplete and browsing, narrowed by the q parameter
+func (h *Handler) List(c *echo.Context) error {
+	limit, offset := echox.Pagination(c)
+	tags, err := h.service.List(c.Request().Context(), echox.QueryString(c, "q"), limit, offset)
+	if err != nil {
+		return echo.NewHTTPError(http.StatusInternalServerError, "failed to list tags")
+	}
+
+	return c.JSON(http.StatusOK, tags)
+}
+
+func (h *Handler) GetBySlug(c *echo.Context) error {
+	tag, err := h.service.GetBySlug(c.Request().Context(), c.Param("slug"))
+	if errors.Is(err, ErrTagNotFound) {
+		return echo.NewHTTPError(http.StatusNotFound, "tag not found")
+	}
+	if err != nil {
+		return echo.NewHTTPError(http.StatusInternalServerError, "failed to get tag")
+	}
+
+	return c.JSON(http.StatusOK, tag)
+}
+
+func (h *Handler) ListMine(c *echo.Context) error {
+	userID, ok := auth.UserID(c)
+	if !ok {
+		return echo.NewHTTPError(http.StatusUnauthorized, "authentication required")
+	}
+
+	tags, err := h.service.ListByUser(c.Request().Context(), userID, echox.QueryString(c, "q"))
+	if err != nil {
+		return echo.NewHTTPError(http.StatusInternalServerError, "failed to list tags")
+	}
+
+	return c.JSON(http.StatusOK, tags)
+}
+
+func (h *Handler) Rename(c *echo.Context) error {
+	id, err := echox.ParamUUID(c, "id", "tag ID")
+	if err != nil {
+		return err
+	}
+
+	var req RenameRequest
+	if err := echox.BindAndValidate(c, &req); err != nil {
+		return err
+	}
+
+	tag, err := h.service.Rename(c.Request().Context(), id, req.Name)
+	if errors.Is(err, ErrInvalidTag) {
+		return echo.NewHTTPError(http.StatusBadRequest, "invalid tag name")
+	}
+	if errors.Is(err, ErrTagNotFound) {
+		return echo.NewHTTPError(http.StatusNotFound, "tag not found")
+	}
+	if errors.Is(err, ErrTagExists) {
+		return echo.NewHTTPError(http.StatusConflict, "another tag has that name")
+	}
+	if err != nil {
+		return echo.NewHTTPError(http.StatusInternalServerError, "failed to rename tag")
+	}
+
+	return c.JSON(http.StatusOK, tag)
+}
+
+// Merge folds the tag in the path into the tag named in the body
+func (h *Handler) Merge(c *echo.Context) error {
+	id, err := echox.ParamUUID(c, "id", "tag ID")
+	if err != nil {
+		return err
+	}
+
+	var req MergeRequest
+	if err := echox.BindAndValidate(c, &req); err != nil {
+		return err
+	}
+	intoID, err := uuid.FromString(req.IntoID)
+	if err != nil {
+		return echo.NewHTTPError(http.StatusBadRequest, "invalid into_id")
+	}
+
+	result, err := h.service.Merge(c.Request().Context(), id, intoID)
+	if errors.Is(err, ErrInvalidMerge) {
+		return echo.NewHTTPError(http.StatusBadRequest, "a tag cannot be merged into itself")
+	}
+	if errors.Is(err, ErrTagNotFound) {
+		return echo.NewHTTPError(http.StatusNotFound, "tag not found")
+	}
+	if err != nil {
+		return echo.NewHTTPError(http.StatusInternalServerError, "failed to merge tags")
+	}
+
+	return c.JSON(http.StatusOK, result)
+}
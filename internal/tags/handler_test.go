@@ -0,0 +1,89 @@
+package tags
+
+import (
+	"context"
+	"log/slog"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/gofrs/uuid/v5"
+	"github.com/labstack/echo/v5"
+)
+
+func TestHandlerGetBySlugHidesPrivateTags(t *testing.T) {
+	repo := &fakeTagsRepository{tag: &Tag{ID: mustTestUUID(t), Name: "Café", Slug: "cafe"}}
+	handler := NewHandler(NewService(repo, slog.Default()), slog.Default())
+
+	c := testContext(http.MethodGet, "/tags/CAF%C3%89", "slug", "CAFÉ")
+
+	err := handler.GetBySlug(c)
+
+	if code := statusCode(t, err); code != http.StatusNotFound {
+		t.Fatalf("expected status %d, got %d", http.StatusNotFound, code)
+	}
+	if repo.slug != "cafe" {
+		t.Fatalf("looked up slug %q, want %q", repo.slug, "cafe")
+	}
+}
+
+func testContext(method, target, paramName, paramValue string) *echo.Context {
+	e := echo.New()
+	req := httptest.NewRequest(method, target, nil)
+	rec := httptest.NewRecorder()
+	c := e.NewContext(req, rec)
+	c.SetPathValues(echo.PathValues{{Name: paramName, Value: paramValue}})
+	return c
+}
+
+func statusCode(t *testing.T, err error) int {
+	t.Helper()
+	if err == nil {
+		t.Fatal("expected error")
+	}
+	httpErr, ok := err.(*echo.HTTPError)
+	if !ok {
+		t.Fatalf("expected echo HTTPError, got %T", err)
+	}
+	return httpErr.Code
+}
+
+type fakeTagsRepository struct {
+	tag       *Tag
+	slug      string
+	renameErr error
+}
+
+func (r *fakeTagsRepository) GetByID(context.Context, uuid.UUID) (*Tag, error) {
+	panic("not implemented")
+}
+
+func (r *fakeTagsRepository) GetBySlug(_ context.Context, slug string) (*Tag, error) {
+	r.slug = slug
+	return r.tag, nil
+}
+
+func (r *fakeTagsRepository) List(context.Context, *string, int, int) ([]*Tag, error) {
+	panic("not implemented")
+}
+
+func (r *fakeTagsRepository) ListByUser(context.Context, uuid.UUID, *string) ([]*UserTag, error) {
+	panic("not implemented")
+}
+
+func (r *fakeTagsRepository) Rename(context.Context, uuid.UUID, string, string) (*Tag, error) {
+	return nil, r.renameErr
+}
+
+func (r *fakeTagsRepository) Merge(context.Context, uuid.UUID, uuid.UUID) (*MergeResult, error) {
+	panic("not implemented")
+}
+
+func mustTestUUID(t *testing.T) uuid.UUID {
+	t.Helper()
+	id, err := uuid.NewV7()
+	if err != nil {
+		t.Fatalf("new uuid: %v", err)
+	}
+	return id
+}
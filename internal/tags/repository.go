@@ -0,0 +1,177 @@
+package tags
+
+import (
+	"context"
+	"errors"
+
+	"github.com/gofrs/uuid/v5"
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgconn"
+	"github.com/zizouhuweidi/maktaba/internal/db"
+	"github.com/zizouhuweidi/maktaba/internal/db/dbgen"
+	"github.com/zizouhuweidi/maktaba/internal/outbox"
+)
+
+type postgresRepository struct {
+	db      *db.DB
+	queries *dbgen.Queries
+}
+
+func NewPostgresRepository(d *db.DB) Repository {
+	return &postgresRepository{db: d, queries: dbgen.New(d.Pool)}
+}
+
+func (r *postgresRepository) GetByID(ctx context.Context, id uuid.UUID) (*Tag, error) {
+	row, err := r.queries.GetTagByID(ctx, db.PGUUID(id))
+	if errors.Is(err, pgx.ErrNoRows) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	return mapTag(row), nil
+}
+
+func (r *postgresRepository) GetBySlug(ctx context.Context, slug string) (*Tag, error) {
+	row, err := r.queries.GetTagBySlug(ctx, slug)
+	if errors.Is(err, pgx.ErrNoRows) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	return mapCountedTag(dbgen.ListTagsRow(row)), nil
+}
+
+// List returns tags used by at least one source or public note, most used
+// first. A prefix matches the start of the slug or of any word in it.
+func (r *postgresRepository) List(ctx context.Context, prefix *string, limit, offset int) ([]*Tag, error) {
+	rows, err := r.queries.ListTags(ctx, dbgen.ListTagsParams{Prefix: db.PGText(prefix), Limit: int32(limit), Offset: int32(offset)})
+	if err != nil {
+		return nil, err
+	}
+	tags := make([]*Tag, 0, len(rows))
+	for _, row := range rows {
+		tags = append(tags, mapCountedTag(row))
+	}
+	return tags, nil
+}
+
+func (r *postgresRepository) ListByUser(ctx context.Context, userID uuid.UUID, prefix *string) ([]*UserTag, error) {
+	rows, err := r.queries.ListUserTags(ctx, dbgen.ListUserTagsParams{UserID: db.PGUUID(userID), Prefix: db.PGText(prefix)})
+	if err != nil {
+		return nil, err
+	}
+	tags := make([]*UserTag, 0, len(rows))
+	for _, row := range rows {
+		tags = append(tags, &UserTag{ID: db.UUID(row.ID), Name: row.Name, Slug: row.Slug, ItemCount: row.ItemCount})
+	}
+	return tags, nil
+}
+
+// Rename sets the name and slug of a tag. It returns nil when the tag does
+// not exist and ErrTagExists when another tag already has the slug.
+func (r *postgresRepository) Rename(ctx context.Context, id uuid.UUID, name, slug string) (*Tag, error) {
+	tx, err := r.db.Begin(ctx)
+	if err != nil {
+		return nil, err
+	}
+	defer tx.Rollback(ctx)
+	qtx := r.queries.WithTx(tx)
+
+	row, err := qtx.RenameTag(ctx, dbgen.RenameTagParams{ID: db.PGUUID(id), Name: name, Slug: slug})
+	if errors.Is(err, pgx.ErrNoRows) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, mapRenameError(err)
+	}
+	renamed := mapTag(row)
+	if err := outbox.Record(ctx, qtx, AggregateTag, renamed.ID, EventTagRenamed, renamed); err != nil {
+		return nil, err
+	}
+
+	if err := tx.Commit(ctx); err != nil {
+		return nil, err
+	}
+	return renamed, nil
+}
+
+// Merge moves every source, note and library item tagged fromID onto intoID
+// and deletes fromID in one transaction. Positions are kept, and anything
+// already carrying both keeps its place for intoID. It returns nil when
+// either tag does not exist.
+func (r *postgresRepository) Merge(ctx context.Context, fromID, intoID uuid.UUID) (*MergeResult, error) {
+	tx, err := r.db.Begin(ctx)
+	if err != nil {
+		return nil, err
+	}
+	defer tx.Rollback(ctx)
+	qtx := r.queries.WithTx(tx)
+
+	into, err := qtx.GetTagByID(ctx, db.PGUUID(intoID))
+	if errors.Is(err, pgx.ErrNoRows) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	from := db.PGUUID(fromID)
+	if err := qtx.MoveSourceTags(ctx, dbgen.MoveSourceTagsParams{IntoID: into.ID, FromID: from}); err != nil {
+		return nil, err
+	}
+	if err := qtx.MoveNoteTags(ctx, dbgen.MoveNoteTagsParams{IntoID: into.ID, FromID: from}); err != nil {
+		return nil, err
+	}
+	if err := qtx.MoveLibraryItemTags(ctx, dbgen.MoveLibraryItemTagsParams{IntoID: into.ID, FromID: from}); err != nil {
+		return nil, err
+	}
+	deleted, err := qtx.DeleteTag(ctx, from)
+	if err != nil {
+		return nil, err
+	}
+	if deleted == 0 {
+		return nil, nil
+	}
+
+	result := &MergeResult{Tag: mapTag(into), MergedID: fromID}
+	if err := outbox.Record(ctx, qtx, AggregateTag, fromID, EventTagMerged, result); err != nil {
+		return nil, err
+	}
+
+	if err := tx.Commit(ctx); err != nil {
+		return nil, err
+	}
+	return result, nil
+}
+
+func mapRenameError(err error) error {
+	var pgErr *pgconn.PgError
+	if errors.As(err, &pgErr) && pgErr.Code == "23505" {
+		return ErrTagExists
+	}
+	return err
+}
+
+func mapTag(row dbgen.Tag) *Tag {
+	return &Tag{
+		ID:        db.UUID(row.ID),
+		Name:      row.Name,
+		Slug:      row.Slug,
+		CreatedAt: db.Time(row.CreatedAt),
+		UpdatedAt: db.Time(row.UpdatedAt),
+	}
+}
+
+func mapCountedTag(row dbgen.ListTagsRow) *Tag {
+	return &Tag{
+		ID:          db.UUID(row.ID),
+		Name:        row.Name,
+		Slug:        row.Slug,
+		SourceCount: row.SourceCount,
+		NoteCount:   row.NoteCount,
+		CreatedAt:   db.Time(row.CreatedAt),
+		UpdatedAt:   db.Time(row.UpdatedAt),
+	}
+}
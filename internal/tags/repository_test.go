@@ -0,0 +1,28 @@
+package tags
+
+import (
+	"errors"
+	"testing"
+
+	"github.com/jackc/pgx/v5/pgconn"
+)
+
+func TestMapRenameError(t *testing.T) {
+	notNull := &pgconn.PgError{Code: "23502"}
+	tests := []struct {
+		name string
+		err  error
+		want error
+	}{
+		{name: "duplicate slug", err: &pgconn.PgError{Code: "23505"}, want: ErrTagExists},
+		{name: "other", err: notNull, want: notNull},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if err := mapRenameError(tt.err); !errors.Is(err, tt.want) {
+				t.Fatalf("error = %v, want %v", err, tt.want)
+			}
+		})
+	}
+}
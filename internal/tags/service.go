@@ -0,0 +1,110 @@
+package tags
+
+import (
+	"context"
+	"errors"
+	"log/slog"
+	"strings"
+
+	"github.com/gofrs/uuid/v5"
+)
+
+var (
+	ErrTagNotFound  = errors.New("tag not found")
+	ErrTagExists    = errors.New("another tag has that name")
+	ErrInvalidMerge = errors.New("a tag cannot be merged into itself")
+)
+
+type Service struct {
+	repo   Repository
+	logger *slog.Logger
+}
+
+func NewService(repo Repository, logger *slog.Logger) *Service {
+	return &Service{repo: repo, logger: logger}
+}
+
+// List returns the tags in use, most used first. A query narrows them to
+// tags with a word starting with it, compared on slugs, so it suits
+// autocomplete in either script.
+func (s *Service) List(ctx context.Context, query *string, limit, offset int) ([]*Tag, error) {
+	return s.repo.List(ctx, searchPrefix(query), limit, offset)
+}
+
+// GetBySlug finds a tag by any spelling that shares its slug. Tags only used
+// privately are reported as not found.
+func (s *Service) GetBySlug(ctx context.Context, name string) (*Tag, error) {
+	slug := Slug(name)
+	if slug == "" {
+		return nil, ErrTagNotFound
+	}
+	tag, err := s.repo.GetBySlug(ctx, slug)
+	if err != nil {
+		s.logger.Error("failed to get tag", "error", err, "slug", slug)
+		return nil, err
+	}
+	if tag == nil || tag.SourceCount+tag.NoteCount == 0 {
+		return nil, ErrTagNotFound
+	}
+	return tag, nil
+}
+
+// ListByUser returns the tags a reader put on their library items.
+func (s *Service) ListByUser(ctx context.Context, userID uuid.UUID, query *string) ([]*UserTag, error) {
+	return s.repo.ListByUser(ctx, userID, searchPrefix(query))
+}
+
+// Rename changes the name of a tag everywhere it is used. Renaming to a
+// spelling of another tag fails with ErrTagExists; merge the two instead.
+func (s *Service) Rename(ctx context.Context, id uuid.UUID, name string) (*Tag, error) {
+	cleaned, err := Clean([]string{name})
+	if err != nil || len(cleaned) == 0 {
+		return nil, ErrInvalidTag
+	}
+	name = cleaned[0]
+
+	tag, err := s.repo.Rename(ctx, id, name, Slug(name))
+	if errors.Is(err, ErrTagExists) {
+		return nil, ErrTagExists
+	}
+	if err != nil {
+		s.logger.Error("failed to rename tag", "error", err, "id", id)
+		return nil, err
+	}
+	if tag == nil {
+		return nil, ErrTagNotFound
+	}
+
+	s.logger.Info("tag renamed", "id", id, "name", name)
+	return tag, nil
+}
+
+// Merge folds the tag fromID into intoID, which keeps its name.
+func (s *Service) Merge(ctx context.Context, fromID, intoID uuid.UUID) (*MergeResult, error) {
+	if fromID == intoID {
+		return nil, ErrInvalidMerge
+	}
+	result, err := s.repo.Merge(ctx, fromID, intoID)
+	if err != nil {
+		s.logger.Error("failed to merge tags", "error", err, "from_id", fromID, "into_id", intoID)
+		return nil, err
+	}
+	if result == nil {
+		return nil, ErrTagNotFound
+	}
+
+	s.logger.Info("tags merged", "from_id", fromID, "into_id", intoID)
+	return result, nil
+}
+
+func searchPrefix(query *string) *string {
+	if query == nil {
+		return nil
+	}
+	slug := Slug(strings.TrimSpace(*query))
+	if slug == "" {
+		return nil
+	}
+	slug = strings.NewReplacer(`\`, `\\`, "%", `\%`, "_", `\_`).Replace(slug)
+	return &slug
+}
@@ -0,0 +1,49 @@
+package tags
+
+import (
+	"context"
+	"errors"
+	"log/slog"
+	"strings"
+	"testing"
+)
+
+func TestServiceRenamePassesTakenName(t *testing.T) {
+	service := NewService(&fakeTagsRepository{renameErr: ErrTagExists}, slog.Default())
+
+	_, err := service.Rename(context.Background(), mustTestUUID(t), "Philosophy")
+
+	if !errors.Is(err, ErrTagExists) {
+		t.Fatalf("error = %v, want %v", err, ErrTagExists)
+	}
+}
+
+func TestServiceRenameRejectsInvalidName(t *testing.T) {
+	service := NewService(&fakeTagsRepository{}, slog.Default())
+
+	for _, name := range []string{"   ", strings.Repeat("x", MaxNameLength+1)} {
+		if _, err := service.Rename(context.Background(), mustTestUUID(t), name); !errors.Is(err, ErrInvalidTag) {
+			t.Fatalf("Rename(%q) error = %v, want %v", name, err, ErrInvalidTag)
+		}
+	}
+}
+
+func TestServiceMergeRejectsSameTag(t *testing.T) {
+	service := NewService(&fakeTagsRepository{}, slog.Default())
+	id := mustTestUUID(t)
+
+	if _, err := service.Merge(context.Background(), id, id); !errors.Is(err, ErrInvalidMerge) {
+		t.Fatalf("error = %v, want %v", err, ErrInvalidMerge)
+	}
+}
+
+func TestSearchPrefixEscapesWildcards(t *testing.T) {
+	query := " Fal_sa%fa "
+	if got := searchPrefix(&query); got == nil || *got != `fal\_sa\%fa` {
+		t.Fatalf("searchPrefix(%q) = %v", query, got)
+	}
+	blank := " ّ "
+	if got := searchPrefix(&blank); got != nil {
+		t.Fatalf("searchPrefix(%q) = %q, want nil", blank, *got)
+	}
+}
@@ -0,0 +1,173 @@
+// Package tags keeps the shared tag taxonomy. Sources and notes are tagged
+// from one list of tags, and readers tag their library items privately from
+// the same list. Tags are matched on a slug that ignores case, diacritics and
+// the Arabic letter variants readers type interchangeably, so "Café", "cafe"
+// and "CAFÉ" are one tag, as are "الفلسفة" and "الفَلسفة".
+package tags
+
+import (
+	"context"
+	"errors"
+	"strings"
+	"time"
+	"unicode/utf8"
+
+	"github.com/gofrs/uuid/v5"
+	"github.com/jackc/pgx/v5/pgtype"
+	"github.com/zizouhuweidi/maktaba/internal/db"
+	"github.com/zizouhuweidi/maktaba/internal/db/dbgen"
+	"golang.org/x/text/unicode/norm"
+)
+
+// Outbox aggregate and event types published by this context.
+const (
+	AggregateTag    = "tag"
+	EventTagRenamed = "tag.renamed"
+	EventTagMerged  = "tag.merged"
+)
+
+// MaxNameLength is the longest tag name accepted, in characters.
+const MaxNameLength = 100
+
+// ErrInvalidTag is returned for a tag name that is blank once cleaned, or
+// whose name or slug is longer than MaxNameLength.
+var ErrInvalidTag = errors.New("invalid tag")
+
+// Tag is a shared tag with the number of sources and public notes using it.
+type Tag struct {
+	ID          uuid.UUID `json:"id"`
+	Name        string    `json:"name"`
+	Slug        string    `json:"slug"`
+	SourceCount int64     `json:"source_count"`
+	NoteCount   int64     `json:"note_count"`
+	CreatedAt   time.Time `json:"created_at"`
+	UpdatedAt   time.Time `json:"updated_at"`
+}
+
+// UserTag is a tag on a reader's own library items, with how many carry it.
+type UserTag struct {
+	ID        uuid.UUID `json:"id"`
+	Name      string    `json:"name"`
+	Slug      string    `json:"slug"`
+	ItemCount int64     `json:"item_count"`
+}
+
+// MergeResult reports a tag folded into another.
+type MergeResult struct {
+	Tag      *Tag      `json:"tag"`
+	MergedID uuid.UUID `json:"merged_id"`
+}
+
+type Repository interface {
+	GetByID(ctx context.Context, id uuid.UUID) (*Tag, error)
+	GetBySlug(ctx context.Context, slug string) (*Tag, error)
+	List(ctx context.Context, prefix *string, limit, offset int) ([]*Tag, error)
+	ListByUser(ctx context.Context, userID uuid.UUID, prefix *string) ([]*UserTag, error)
+	Rename(ctx context.Context, id uuid.UUID, name, slug string) (*Tag, error)
+	Merge(ctx context.Context, fromID, intoID uuid.UUID) (*MergeResult, error)
+}
+
+// arabicVariants folds Arabic letters that readers use interchangeably onto
+// one form: alef wasla onto alef, alef maksura onto yeh and teh marbuta onto
+// heh.
+var arabicVariants = strings.NewReplacer("ٱ", "ا", "ى", "ي", "ة", "ه")
+
+// Slug returns the canonical form tags are matched on. It decomposes the
+// name, drops combining marks, Arabic harakat and tatweel, folds Arabic
+// letter variants, lowercases it and collapses whitespace.
+func Slug(name string) string {
+	stripped := strings.Map(func(r rune) rune {
+		if ignored(r) {
+			return -1
+		}
+		return r
+	}, norm.NFKD.String(name))
+	return strings.Join(strings.Fields(strings.ToLower(arabicVariants.Replace(stripped))), " ")
+}
+
+// ignored reports whether r is dropped from slugs: combining diacritics,
+// Arabic harakat, superscript alef and tatweel
+func ignored(r rune) bool {
+	return (r >= 0x0300 && r <= 0x036f) || (r >= 0x064b && r <= 0x065f) || r == 0x0670 || r == 0x0640
+}
+
+// Clean trims each name and collapses its whitespace, then drops blanks and
+// names whose slug repeats an earlier one. It returns ErrInvalidTag when a
+// name is too long or has nothing left once its marks are removed.
+func Clean(names []string) ([]string, error) {
+	if names == nil {
+		return nil, nil
+	}
+	cleaned := make([]string, 0, len(names))
+	seen := make(map[string]bool, len(names))
+	for _, name := range names {
+		name = strings.Join(strings.Fields(name), " ")
+		if name == "" {
+			continue
+		}
+		if !Valid(name) {
+			return nil, ErrInvalidTag
+		}
+		slug := Slug(name)
+		if seen[slug] {
+			continue
+		}
+		seen[slug] = true
+		cleaned = append(cleaned, name)
+	}
+	return cleaned, nil
+}
+
+// Valid reports whether name can be saved as a tag. The slug is checked as
+// well as the name, since decomposing ligatures and compatibility characters
+// can make it the longer of the two.
+func Valid(name string) bool {
+	slug := Slug(name)
+	return utf8.RuneCountInString(name) <= MaxNameLength && slug != "" && utf8.RuneCountInString(slug) <= MaxNameLength
+}
+
+// Upsert makes sure a tag exists for each name and returns their IDs in the
+// order given. A name whose slug is already taken resolves to that tag and
+// leaves its name alone. q must be bound to the transaction that links the
+// tags so both commit or roll back together.
+func Upsert(ctx context.Context, q *dbgen.Queries, names []string) ([]pgtype.UUID, error) {
+	names, err := Clean(names)
+	if err != nil || len(names) == 0 {
+		return nil, err
+	}
+	ids := make([]pgtype.UUID, 0, len(names))
+	slugs := make([]string, 0, len(names))
+	for _, name := range names {
+		id, err := uuid.NewV7()
+		if err != nil {
+			return nil, err
+		}
+		ids = append(ids, db.PGUUID(id))
+		slugs = append(slugs, Slug(name))
+	}
+
+	rows, err := q.UpsertTags(ctx, dbgen.UpsertTagsParams{Ids: ids, Names: names, Slugs: slugs})
+	if err != nil {
+		return nil, err
+	}
+	bySlug := make(map[string]pgtype.UUID, len(rows))
+	for _, row := range rows {
+		bySlug[row.Slug] = row.ID
+	}
+	ordered := make([]pgtype.UUID, 0, len(slugs))
+	for _, slug := range slugs {
+		ordered = append(ordered, bySlug[slug])
+	}
+	return ordered, nil
+}
+
+// Group collects the tag names of a batch of rows under the ID of the row
+// they belong to, keeping their order.
+func Group[Row any](rows []Row, key func(Row) (pgtype.UUID, string)) map[uuid.UUID][]string {
+	grouped := make(map[uuid.UUID][]string)
+	for _, row := range rows {
+		id, name := key(row)
+		grouped[db.UUID(id)] = append(grouped[db.UUID(id)], name)
+	}
+	return grouped
+}
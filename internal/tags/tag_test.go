@@ -0,0 +1,58 @@
+package tags
+
+import (
+	"errors"
+	"slices"
+	"strings"
+	"testing"
+)
+
+func TestSlug(t *testing.T) {
+	tests := []struct {
+		name string
+		in   string
+		want string
+	}{
+		{name: "case", in: "Philosophy", want: "philosophy"},
+		{name: "latin diacritics", in: "Café Société", want: "cafe societe"},
+		{name: "whitespace", in: "  history \t of   science ", want: "history of science"},
+		{name: "harakat", in: "الفَلْسَفَة", want: "الفلسفه"},
+		{name: "tatweel", in: "الفلســـفة", want: "الفلسفه"},
+		{name: "alef with hamza", in: "أدب", want: "ادب"},
+		{name: "alef maksura", in: "موسيقى", want: "موسيقي"},
+		{name: "teh marbuta", in: "رواية", want: "روايه"},
+		{name: "mixed scripts", in: "Ibn Rushd ابن رُشد", want: "ibn rushd ابن رشد"},
+		{name: "marks only", in: "َّ", want: ""},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := Slug(tt.in); got != tt.want {
+				t.Fatalf("Slug(%q) = %q, want %q", tt.in, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestCleanDropsRepeatedSlugs(t *testing.T) {
+	got, err := Clean([]string{" Café ", "cafe", "", "CAFÉ", "الفلسفة", "الفَلسفة", "history  of science"})
+	if err != nil {
+		t.Fatalf("Clean: %v", err)
+	}
+
+	want := []string{"Café", "الفلسفة", "history of science"}
+	if !slices.Equal(got, want) {
+		t.Fatalf("Clean = %q, want %q", got, want)
+	}
+}
+
+func TestCleanRejectsInvalidNames(t *testing.T) {
+	// "ﬃ" is one character that decomposes to "ffi", so this name fits but
+	// its slug does not.
+	expanding := strings.Repeat("ﬃ", MaxNameLength/2)
+	for _, name := range []string{strings.Repeat("ب", MaxNameLength+1), "َ", expanding} {
+		if _, err := Clean([]string{"history", name}); !errors.Is(err, ErrInvalidTag) {
+			t.Fatalf("Clean(%q) error = %v, want %v", name, err, ErrInvalidTag)
+		}
+	}
+}
@@ -0,0 +1,99 @@
+-- +goose Up
+CREATE EXTENSION IF NOT EXISTS pg_trgm;
+
+CREATE TABLE IF NOT EXISTS source_search (
+    source_id UUID PRIMARY KEY REFERENCES sources(id) ON DELETE CASCADE,
+    document TSVECTOR NOT NULL,
+    search_text TEXT NOT NULL,
+    updated_at TIMESTAMP WITH TIME ZONE DEFAULT NOW()
+);
+
+CREATE INDEX IF NOT EXISTS idx_source_search_document ON source_search USING GIN(document);
+CREATE INDEX IF NOT EXISTS idx_source_search_text_trgm ON source_search USING GIN(search_text gin_trgm_ops);
+CREATE INDEX IF NOT EXISTS idx_book_metadata_language ON book_metadata(lower(language));
+
+-- +goose StatementBegin
+CREATE OR REPLACE FUNCTION refresh_source_search(target_source_id UUID)
+RETURNS VOID AS $$
+BEGIN
+    INSERT INTO source_search (source_id, document, search_text, updated_at)
+    SELECT s.id,
+           setweight(to_tsvector('simple', COALESCE(s.title, '')), 'A') ||
+           setweight(to_tsvector('simple', COALESCE(s.subtitle, '')), 'B') ||
+           setweight(to_tsvector('simple', COALESCE(c.names, '')), 'B') ||
+           setweight(to_tsvector('simple', concat_ws(' ', t.names, s.isbn, s.doi, bm.isbn_10, bm.isbn_13, s.publisher)), 'C') ||
+           setweight(to_tsvector('simple', COALESCE(s.description, '')), 'D'),
+           lower(concat_ws(' ', s.title, s.subtitle, c.names)),
+           NOW()
+    FROM sources s
+    LEFT JOIN book_metadata bm ON bm.source_id = s.id
+    LEFT JOIN LATERAL (
+        SELECT string_agg(ct.name, ' ' ORDER BY sc.position) AS names
+        FROM source_contributors sc
+        JOIN contributors ct ON ct.id = sc.contributor_id
+        WHERE sc.source_id = s.id
+    ) c ON TRUE
+    LEFT JOIN LATERAL (
+        SELECT string_agg(tag, ' ') AS names
+        FROM jsonb_array_elements_text(COALESCE(s.tags, '[]'::jsonb)) AS tag
+    ) t ON TRUE
+    WHERE s.id = target_source_id
+    ON CONFLICT (source_id) DO UPDATE
+    SET document = EXCLUDED.document,
+        search_text = EXCLUDED.search_text,
+        updated_at = EXCLUDED.updated_at;
+END;
+$$ language 'plpgsql';
+-- +goose StatementEnd
+
+-- +goose StatementBegin
+CREATE OR REPLACE FUNCTION refresh_source_search_from_row()
+RETURNS TRIGGER AS $$
+BEGIN
+    IF TG_TABLE_NAME = 'sources' THEN
+        PERFORM refresh_source_search(NEW.id);
+    ELSIF TG_TABLE_NAME = 'contributors' THEN
+        PERFORM refresh_source_search(sc.source_id)
+        FROM source_contributors sc
+        WHERE sc.contributor_id = NEW.id;
+    ELSIF TG_OP = 'DELETE' THEN
+        PERFORM refresh_source_search(OLD.source_id);
+    ELSE
+        PERFORM refresh_source_search(NEW.source_id);
+    END IF;
+    RETURN NULL;
+END;
+$$ language 'plpgsql';
+-- +goose StatementEnd
+
+CREATE TRIGGER refresh_source_search_on_sources
+    AFTER INSERT OR UPDATE ON sources
+    FOR EACH ROW
+    EXECUTE FUNCTION refresh_source_search_from_row();
+
+CREATE TRIGGER refresh_source_search_on_source_contributors
+    AFTER INSERT OR UPDATE OR DELETE ON source_contributors
+    FOR EACH ROW
+    EXECUTE FUNCTION refresh_source_search_from_row();
+
+CREATE TRIGGER refresh_source_search_on_book_metadata
+    AFTER INSERT OR UPDATE ON book_metadata
+    FOR EACH ROW
+    EXECUTE FUNCTION refresh_source_search_from_row();
+
+CREATE TRIGGER refresh_source_search_on_contributors
+    AFTER UPDATE OF name ON contributors
+    FOR EACH ROW
+    EXECUTE FUNCTION refresh_source_search_from_row();
+
+SELECT refresh_source_search(id) FROM sources;
+
+-- +goose Down
+DROP TRIGGER IF EXISTS refresh_source_search_on_contributors ON contributors;
+DROP TRIGGER IF EXISTS refresh_source_search_on_book_metadata ON book_metadata;
+DROP TRIGGER IF EXISTS refresh_source_search_on_source_contributors ON source_contributors;
+DROP TRIGGER IF EXISTS refresh_source_search_on_sources ON sources;
+DROP FUNCTION IF EXISTS refresh_source_search_from_row();
+DROP FUNCTION IF EXISTS refresh_source_search(UUID);
+DROP INDEX IF EXISTS idx_book_metadata_language;
+DROP TABLE IF EXISTS source_search;
@@ -0,0 +1,37 @@
+-- +goose Up
+CREATE TABLE IF NOT EXISTS note_embeddings (
+    note_id UUID PRIMARY KEY REFERENCES notes(id) ON DELETE CASCADE,
+    model VARCHAR(100) NOT NULL,
+    embedding VECTOR(384) NOT NULL,
+    content_hash BYTEA NOT NULL,
+    created_at TIMESTAMP WITH TIME ZONE DEFAULT NOW(),
+    updated_at TIMESTAMP WITH TIME ZONE DEFAULT NOW()
+);
+
+CREATE TABLE IF NOT EXISTS source_embeddings (
+    source_id UUID PRIMARY KEY REFERENCES sources(id) ON DELETE CASCADE,
+    model VARCHAR(100) NOT NULL,
+    embedding VECTOR(384) NOT NULL,
+    content_hash BYTEA NOT NULL,
+    created_at TIMESTAMP WITH TIME ZONE DEFAULT NOW(),
+    updated_at TIMESTAMP WITH TIME ZONE DEFAULT NOW()
+);
+
+CREATE INDEX IF NOT EXISTS idx_note_embeddings_embedding ON note_embeddings USING hnsw (embedding vector_cosine_ops);
+CREATE INDEX IF NOT EXISTS idx_source_embeddings_embedding ON source_embeddings USING hnsw (embedding vector_cosine_ops);
+
+CREATE TRIGGER update_note_embeddings_updated_at
+    BEFORE UPDATE ON note_embeddings
+    FOR EACH ROW
+    EXECUTE FUNCTION update_updated_at_column();
+
+CREATE TRIGGER update_source_embeddings_updated_at
+    BEFORE UPDATE ON source_embeddings
+    FOR EACH ROW
+    EXECUTE FUNCTION update_updated_at_column();
+
+-- +goose Down
+DROP TRIGGER IF EXISTS update_source_embeddings_updated_at ON source_embeddings;
+DROP TRIGGER IF EXISTS update_note_embeddings_updated_at ON note_embeddings;
+DROP TABLE IF EXISTS source_embeddings;
+DROP TABLE IF EXISTS note_embeddings;
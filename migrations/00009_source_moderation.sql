@@ -0,0 +1,29 @@
+-- +goose Up
+ALTER TABLE users ADD COLUMN IF NOT EXISTS role VARCHAR(20) NOT NULL DEFAULT 'user'
+    CHECK (role IN ('user', 'editor', 'admin'));
+
+ALTER TABLE sources ADD COLUMN IF NOT EXISTS created_by UUID REFERENCES users(id) ON DELETE SET NULL;
+CREATE INDEX IF NOT EXISTS idx_sources_created_by ON sources(created_by);
+
+CREATE TABLE IF NOT EXISTS source_proposals (
+    id UUID PRIMARY KEY,
+    source_id UUID NOT NULL REFERENCES sources(id) ON DELETE CASCADE,
+    proposed_by UUID REFERENCES users(id) ON DELETE SET NULL,
+    changes JSONB NOT NULL,
+    status VARCHAR(20) NOT NULL DEFAULT 'pending' CHECK (status IN ('pending', 'approved', 'rejected')),
+    reviewed_by UUID REFERENCES users(id) ON DELETE SET NULL,
+    review_note TEXT,
+    reviewed_at TIMESTAMP WITH TIME ZONE,
+    created_at TIMESTAMP WITH TIME ZONE DEFAULT NOW(),
+    updated_at TIMESTAMP WITH TIME ZONE DEFAULT NOW()
+);
+
+CREATE INDEX IF NOT EXISTS idx_source_proposals_status ON source_proposals(status, created_at);
+CREATE INDEX IF NOT EXISTS idx_source_proposals_source_id ON source_proposals(source_id);
+CREATE INDEX IF NOT EXISTS idx_source_proposals_proposed_by ON source_proposals(proposed_by);
+
+-- +goose Down
+DROP TABLE IF EXISTS source_proposals;
+DROP INDEX IF EXISTS idx_sources_created_by;
+ALTER TABLE sources DROP COLUMN IF EXISTS created_by;
+ALTER TABLE users DROP COLUMN IF EXISTS role;
@@ -0,0 +1,46 @@
+-- +goose Up
+CREATE TABLE IF NOT EXISTS source_revisions (
+    id UUID PRIMARY KEY,
+    source_id UUID NOT NULL REFERENCES sources(id) ON DELETE CASCADE,
+    revision INTEGER NOT NULL CHECK (revision > 0),
+    action VARCHAR(20) NOT NULL CHECK (action IN ('baseline', 'create', 'update', 'proposal', 'revert')),
+    edited_by UUID REFERENCES users(id) ON DELETE SET NULL,
+    proposal_id UUID REFERENCES source_proposals(id) ON DELETE SET NULL,
+    reverted_to INTEGER,
+    snapshot JSONB NOT NULL,
+    changes JSONB NOT NULL DEFAULT '[]'::jsonb,
+    created_at TIMESTAMP WITH TIME ZONE DEFAULT NOW(),
+    UNIQUE (source_id, revision)
+);
+
+CREATE INDEX IF NOT EXISTS idx_source_revisions_edited_by ON source_revisions(edited_by);
+
+-- Revisions are an audit trail: rows are only ever inserted, or removed
+-- together with their source.
+-- +goose StatementBegin
+CREATE OR REPLACE FUNCTION prevent_source_revision_update()
+RETURNS TRIGGER AS $$
+BEGIN
+    IF NEW.source_id IS DISTINCT FROM OLD.source_id
+        OR NEW.revision IS DISTINCT FROM OLD.revision
+        OR NEW.action IS DISTINCT FROM OLD.action
+        OR NEW.snapshot IS DISTINCT FROM OLD.snapshot
+        OR NEW.changes IS DISTINCT FROM OLD.changes
+        OR NEW.reverted_to IS DISTINCT FROM OLD.reverted_to
+        OR NEW.created_at IS DISTINCT FROM OLD.created_at THEN
+        RAISE EXCEPTION 'source revisions are immutable';
+    END IF;
+    RETURN NEW;
+END;
+$$ LANGUAGE plpgsql;
+-- +goose StatementEnd
+
+CREATE TRIGGER source_revisions_immutable
+    BEFORE UPDATE ON source_revisions
+    FOR EACH ROW
+    EXECUTE FUNCTION prevent_source_revision_update();
+
+-- +goose Down
+DROP TRIGGER IF EXISTS source_revisions_immutable ON source_revisions;
+DROP FUNCTION IF EXISTS prevent_source_revision_update();
+DROP TABLE IF EXISTS source_revisions;
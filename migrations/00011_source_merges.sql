@@ -0,0 +1,49 @@
+-- +goose Up
+ALTER TABLE source_revisions ADD COLUMN IF NOT EXISTS merged_from UUID;
+ALTER TABLE source_revisions DROP CONSTRAINT IF EXISTS source_revisions_action_check;
+ALTER TABLE source_revisions ADD CONSTRAINT source_revisions_action_check
+    CHECK (action IN ('baseline', 'create', 'update', 'proposal', 'revert', 'merge'));
+
+-- +goose StatementBegin
+CREATE OR REPLACE FUNCTION prevent_source_revision_update()
+RETURNS TRIGGER AS $$
+BEGIN
+    IF NEW.source_id IS DISTINCT FROM OLD.source_id
+        OR NEW.revision IS DISTINCT FROM OLD.revision
+        OR NEW.action IS DISTINCT FROM OLD.action
+        OR NEW.snapshot IS DISTINCT FROM OLD.snapshot
+        OR NEW.changes IS DISTINCT FROM OLD.changes
+        OR NEW.reverted_to IS DISTINCT FROM OLD.reverted_to
+        OR NEW.merged_from IS DISTINCT FROM OLD.merged_from
+        OR NEW.created_at IS DISTINCT FROM OLD.created_at THEN
+        RAISE EXCEPTION 'source revisions are immutable';
+    END IF;
+    RETURN NEW;
+END;
+$$ LANGUAGE plpgsql;
+-- +goose StatementEnd
+
+-- +goose Down
+-- +goose StatementBegin
+CREATE OR REPLACE FUNCTION prevent_source_revision_update()
+RETURNS TRIGGER AS $$
+BEGIN
+    IF NEW.source_id IS DISTINCT FROM OLD.source_id
+        OR NEW.revision IS DISTINCT FROM OLD.revision
+        OR NEW.action IS DISTINCT FROM OLD.action
+        OR NEW.snapshot IS DISTINCT FROM OLD.snapshot
+        OR NEW.changes IS DISTINCT FROM OLD.changes
+        OR NEW.reverted_to IS DISTINCT FROM OLD.reverted_to
+        OR NEW.created_at IS DISTINCT FROM OLD.created_at THEN
+        RAISE EXCEPTION 'source revisions are immutable';
+    END IF;
+    RETURN NEW;
+END;
+$$ LANGUAGE plpgsql;
+-- +goose StatementEnd
+
+DELETE FROM source_revisions WHERE action = 'merge';
+ALTER TABLE source_revisions DROP CONSTRAINT IF EXISTS source_revisions_action_check;
+ALTER TABLE source_revisions ADD CONSTRAINT source_revisions_action_check
+    CHECK (action IN ('baseline', 'create', 'update', 'proposal', 'revert'));
+ALTER TABLE source_revisions DROP COLUMN IF EXISTS merged_from;
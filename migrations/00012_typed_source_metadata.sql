@@ -0,0 +1,70 @@
+-- +goose Up
+CREATE TABLE IF NOT EXISTS paper_metadata (
+    source_id UUID PRIMARY KEY REFERENCES sources(id) ON DELETE CASCADE,
+    venue VARCHAR(255),
+    arxiv_id VARCHAR(64),
+    created_at TIMESTAMP WITH TIME ZONE DEFAULT NOW(),
+    updated_at TIMESTAMP WITH TIME ZONE DEFAULT NOW()
+);
+
+CREATE TABLE IF NOT EXISTS podcast_metadata (
+    source_id UUID PRIMARY KEY REFERENCES sources(id) ON DELETE CASCADE,
+    show VARCHAR(255) NOT NULL,
+    episode_number INTEGER CHECK (episode_number IS NULL OR episode_number > 0),
+    duration_seconds INTEGER CHECK (duration_seconds IS NULL OR duration_seconds >= 0),
+    feed_url TEXT,
+    created_at TIMESTAMP WITH TIME ZONE DEFAULT NOW(),
+    updated_at TIMESTAMP WITH TIME ZONE DEFAULT NOW()
+);
+
+CREATE TABLE IF NOT EXISTS video_metadata (
+    source_id UUID PRIMARY KEY REFERENCES sources(id) ON DELETE CASCADE,
+    channel VARCHAR(255),
+    duration_seconds INTEGER CHECK (duration_seconds IS NULL OR duration_seconds >= 0),
+    platform VARCHAR(64),
+    platform_id VARCHAR(255),
+    created_at TIMESTAMP WITH TIME ZONE DEFAULT NOW(),
+    updated_at TIMESTAMP WITH TIME ZONE DEFAULT NOW()
+);
+
+CREATE TABLE IF NOT EXISTS article_metadata (
+    source_id UUID PRIMARY KEY REFERENCES sources(id) ON DELETE CASCADE,
+    site VARCHAR(255),
+    word_count INTEGER CHECK (word_count IS NULL OR word_count >= 0),
+    created_at TIMESTAMP WITH TIME ZONE DEFAULT NOW(),
+    updated_at TIMESTAMP WITH TIME ZONE DEFAULT NOW()
+);
+
+CREATE INDEX IF NOT EXISTS idx_paper_metadata_arxiv_id ON paper_metadata(arxiv_id);
+CREATE INDEX IF NOT EXISTS idx_podcast_metadata_show ON podcast_metadata(show);
+CREATE INDEX IF NOT EXISTS idx_video_metadata_platform_id ON video_metadata(platform, platform_id);
+
+CREATE TRIGGER update_paper_metadata_updated_at
+    BEFORE UPDATE ON paper_metadata
+    FOR EACH ROW
+    EXECUTE FUNCTION update_updated_at_column();
+
+CREATE TRIGGER update_podcast_metadata_updated_at
+    BEFORE UPDATE ON podcast_metadata
+    FOR EACH ROW
+    EXECUTE FUNCTION update_updated_at_column();
+
+CREATE TRIGGER update_video_metadata_updated_at
+    BEFORE UPDATE ON video_metadata
+    FOR EACH ROW
+    EXECUTE FUNCTION update_updated_at_column();
+
+CREATE TRIGGER update_article_metadata_updated_at
+    BEFORE UPDATE ON article_metadata
+    FOR EACH ROW
+    EXECUTE FUNCTION update_updated_at_column();
+
+-- +goose Down
+DROP TRIGGER IF EXISTS update_article_metadata_updated_at ON article_metadata;
+DROP TRIGGER IF EXISTS update_video_metadata_updated_at ON video_metadata;
+DROP TRIGGER IF EXISTS update_podcast_metadata_updated_at ON podcast_metadata;
+DROP TRIGGER IF EXISTS update_paper_metadata_updated_at ON paper_metadata;
+DROP TABLE IF EXISTS article_metadata;
+DROP TABLE IF EXISTS video_metadata;
+DROP TABLE IF EXISTS podcast_metadata;
+DROP TABLE IF EXISTS paper_metadata;
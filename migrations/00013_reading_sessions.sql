@@ -0,0 +1,19 @@
+-- +goose Up
+CREATE TABLE IF NOT EXISTS reading_sessions (
+    id UUID PRIMARY KEY,
+    library_item_id UUID NOT NULL REFERENCES user_library_items(id) ON DELETE CASCADE,
+    user_id UUID NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+    started_at TIMESTAMP WITH TIME ZONE NOT NULL,
+    ended_at TIMESTAMP WITH TIME ZONE CHECK (ended_at IS NULL OR ended_at >= started_at),
+    progress_from INTEGER CHECK (progress_from IS NULL OR progress_from >= 0),
+    progress_to INTEGER CHECK (progress_to IS NULL OR progress_to >= 0),
+    progress_unit VARCHAR(50) CHECK (progress_unit IS NULL OR progress_unit IN ('page', 'percent', 'minute', 'second', 'episode')),
+    device VARCHAR(100),
+    created_at TIMESTAMP WITH TIME ZONE DEFAULT NOW()
+);
+
+CREATE INDEX IF NOT EXISTS idx_reading_sessions_library_item_id ON reading_sessions(library_item_id, started_at DESC);
+CREATE INDEX IF NOT EXISTS idx_reading_sessions_user_id ON reading_sessions(user_id, started_at DESC);
+
+-- +goose Down
+DROP TABLE IF EXISTS reading_sessions;
@@ -0,0 +1,8 @@
+-- +goose Up
+ALTER TABLE profiles ADD COLUMN IF NOT EXISTS public_year_in_review BOOLEAN NOT NULL DEFAULT false;
+
+CREATE INDEX IF NOT EXISTS idx_user_library_items_completed_at ON user_library_items(user_id, completed_at) WHERE status = 'completed';
+
+-- +goose Down
+DROP INDEX IF EXISTS idx_user_library_items_completed_at;
+ALTER TABLE profiles DROP COLUMN IF EXISTS public_year_in_review;
@@ -0,0 +1,45 @@
+-- +goose Up
+CREATE TABLE IF NOT EXISTS goals (
+    id UUID PRIMARY KEY,
+    owner_id UUID NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+    title VARCHAR(255) NOT NULL,
+    target INTEGER NOT NULL CHECK (target > 0),
+    period VARCHAR(20) NOT NULL CHECK (period IN ('total', 'monthly')),
+    source_type VARCHAR(50) CHECK (source_type IS NULL OR source_type IN ('book', 'paper', 'podcast', 'video', 'article', 'essay')),
+    starts_on DATE NOT NULL,
+    ends_on DATE NOT NULL,
+    shared BOOLEAN NOT NULL DEFAULT false,
+    created_at TIMESTAMP WITH TIME ZONE DEFAULT NOW(),
+    updated_at TIMESTAMP WITH TIME ZONE DEFAULT NOW(),
+    CHECK (ends_on >= starts_on)
+);
+
+CREATE TABLE IF NOT EXISTS goal_participants (
+    goal_id UUID NOT NULL REFERENCES goals(id) ON DELETE CASCADE,
+    user_id UUID NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+    joined_at TIMESTAMP WITH TIME ZONE NOT NULL DEFAULT NOW(),
+    PRIMARY KEY (goal_id, user_id)
+);
+
+CREATE TABLE IF NOT EXISTS goal_achievements (
+    goal_id UUID NOT NULL,
+    user_id UUID NOT NULL,
+    period_start DATE NOT NULL,
+    achieved_at TIMESTAMP WITH TIME ZONE NOT NULL DEFAULT NOW(),
+    PRIMARY KEY (goal_id, user_id, period_start),
+    FOREIGN KEY (goal_id, user_id) REFERENCES goal_participants(goal_id, user_id) ON DELETE CASCADE
+);
+
+CREATE INDEX IF NOT EXISTS idx_goals_owner_id ON goals(owner_id);
+CREATE INDEX IF NOT EXISTS idx_goal_participants_user_id ON goal_participants(user_id);
+
+CREATE TRIGGER update_goals_updated_at
+    BEFORE UPDATE ON goals
+    FOR EACH ROW
+    EXECUTE FUNCTION update_updated_at_column();
+
+-- +goose Down
+DROP TRIGGER IF EXISTS update_goals_updated_at ON goals;
+DROP TABLE IF EXISTS goal_achievements;
+DROP TABLE IF EXISTS goal_participants;
+DROP TABLE IF EXISTS goals;
@@ -0,0 +1,32 @@
+-- +goose Up
+ALTER TABLE user_library_items
+    ADD COLUMN IF NOT EXISTS reread_count INTEGER NOT NULL DEFAULT 0 CHECK (reread_count >= 0);
+
+UPDATE user_library_items SET completed_at = NULL WHERE status <> 'completed' AND completed_at IS NOT NULL;
+UPDATE user_library_items SET started_at = NULL WHERE status = 'to_consume' AND started_at IS NOT NULL;
+UPDATE user_library_items SET started_at = completed_at WHERE completed_at < started_at;
+
+ALTER TABLE user_library_items
+    ADD CONSTRAINT user_library_items_completed_at_status CHECK (completed_at IS NULL OR status = 'completed'),
+    ADD CONSTRAINT user_library_items_started_at_status CHECK (started_at IS NULL OR status <> 'to_consume'),
+    ADD CONSTRAINT user_library_items_dates_ordered CHECK (started_at IS NULL OR completed_at IS NULL OR completed_at >= started_at);
+
+CREATE TABLE IF NOT EXISTS library_item_completions (
+    id UUID PRIMARY KEY,
+    library_item_id UUID NOT NULL REFERENCES user_library_items(id) ON DELETE CASCADE,
+    user_id UUID NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+    started_at TIMESTAMP WITH TIME ZONE,
+    completed_at TIMESTAMP WITH TIME ZONE NOT NULL CHECK (started_at IS NULL OR completed_at >= started_at),
+    created_at TIMESTAMP WITH TIME ZONE DEFAULT NOW()
+);
+
+CREATE INDEX IF NOT EXISTS idx_library_item_completions_library_item_id ON library_item_completions(library_item_id, completed_at DESC);
+CREATE INDEX IF NOT EXISTS idx_library_item_completions_user_id ON library_item_completions(user_id, completed_at DESC);
+
+-- +goose Down
+DROP TABLE IF EXISTS library_item_completions;
+ALTER TABLE user_library_items
+    DROP CONSTRAINT IF EXISTS user_library_items_dates_ordered,
+    DROP CONSTRAINT IF EXISTS user_library_items_started_at_status,
+    DROP CONSTRAINT IF EXISTS user_library_items_completed_at_status;
+ALTER TABLE user_library_items DROP COLUMN IF EXISTS reread_count;
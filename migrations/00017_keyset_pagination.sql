@@ -0,0 +1,13 @@
+-- +goose Up
+CREATE INDEX IF NOT EXISTS idx_sources_created_at_id ON sources(created_at DESC, id DESC);
+CREATE INDEX IF NOT EXISTS idx_notes_user_id_created_at_id ON notes(user_id, created_at DESC, id DESC);
+CREATE INDEX IF NOT EXISTS idx_reviews_user_id_created_at_id ON reviews(user_id, created_at DESC, id DESC);
+CREATE INDEX IF NOT EXISTS idx_collections_user_id_created_at_id ON collections(user_id, created_at DESC, id DESC);
+CREATE INDEX IF NOT EXISTS idx_user_library_items_user_id_created_at_id ON user_library_items(user_id, created_at DESC, id DESC);
+
+-- +goose Down
+DROP INDEX IF EXISTS idx_user_library_items_user_id_created_at_id;
+DROP INDEX IF EXISTS idx_collections_user_id_created_at_id;
+DROP INDEX IF EXISTS idx_reviews_user_id_created_at_id;
+DROP INDEX IF EXISTS idx_notes_user_id_created_at_id;
+DROP INDEX IF EXISTS idx_sources_created_at_id;
@@ -0,0 +1,247 @@
+-- +goose Up
+-- +goose StatementBegin
+CREATE OR REPLACE FUNCTION tag_name(raw TEXT)
+RETURNS TEXT AS $$
+    SELECT left(btrim(regexp_replace(raw, '\s+', ' ', 'g')), 100);
+$$ LANGUAGE sql IMMUTABLE;
+-- +goose StatementEnd
+
+-- +goose StatementBegin
+CREATE OR REPLACE FUNCTION tag_slug(name TEXT)
+RETURNS TEXT AS $$
+    SELECT btrim(regexp_replace(lower(translate(
+        regexp_replace(normalize(name, NFKD), '[\u0300-\u036f\u0640\u064b-\u065f\u0670]', '', 'g'),
+        'ٱىة', 'ايه')), '\s+', ' ', 'g'));
+$$ LANGUAGE sql IMMUTABLE;
+-- +goose StatementEnd
+
+ALTER TABLE tags ADD COLUMN IF NOT EXISTS slug VARCHAR(100);
+UPDATE tags SET name = tag_name(name), slug = tag_slug(tag_name(name));
+DELETE FROM tags t
+USING tags kept
+WHERE t.slug = kept.slug AND t.id > kept.id;
+DELETE FROM tags WHERE slug = '';
+ALTER TABLE tags ALTER COLUMN slug SET NOT NULL;
+ALTER TABLE tags ADD CONSTRAINT tags_slug_key UNIQUE (slug);
+
+CREATE TABLE IF NOT EXISTS source_tags (
+    source_id UUID NOT NULL REFERENCES sources(id) ON DELETE CASCADE,
+    tag_id UUID NOT NULL REFERENCES tags(id) ON DELETE CASCADE,
+    position INTEGER NOT NULL DEFAULT 0,
+    PRIMARY KEY (source_id, tag_id)
+);
+
+CREATE TABLE IF NOT EXISTS note_tags (
+    note_id UUID NOT NULL REFERENCES notes(id) ON DELETE CASCADE,
+    tag_id UUID NOT NULL REFERENCES tags(id) ON DELETE CASCADE,
+    position INTEGER NOT NULL DEFAULT 0,
+    PRIMARY KEY (note_id, tag_id)
+);
+
+CREATE TABLE IF NOT EXISTS library_item_tags (
+    library_item_id UUID NOT NULL REFERENCES user_library_items(id) ON DELETE CASCADE,
+    tag_id UUID NOT NULL REFERENCES tags(id) ON DELETE CASCADE,
+    position INTEGER NOT NULL DEFAULT 0,
+    PRIMARY KEY (library_item_id, tag_id)
+);
+
+CREATE INDEX IF NOT EXISTS idx_source_tags_tag_id ON source_tags(tag_id);
+CREATE INDEX IF NOT EXISTS idx_note_tags_tag_id ON note_tags(tag_id);
+CREATE INDEX IF NOT EXISTS idx_library_item_tags_tag_id ON library_item_tags(tag_id);
+
+WITH raw AS (
+    SELECT tag_name(name) AS name
+    FROM sources, jsonb_array_elements_text(CASE WHEN jsonb_typeof(tags) = 'array' THEN tags ELSE '[]'::jsonb END) AS name
+    UNION ALL
+    SELECT tag_name(name)
+    FROM notes, jsonb_array_elements_text(CASE WHEN jsonb_typeof(tags) = 'array' THEN tags ELSE '[]'::jsonb END) AS name
+)
+INSERT INTO tags (id, name, slug)
+SELECT DISTINCT ON (tag_slug(name)) uuidv7(), name, tag_slug(name)
+FROM raw
+WHERE tag_slug(name) <> ''
+ORDER BY tag_slug(name), name
+ON CONFLICT (slug) DO NOTHING;
+
+INSERT INTO source_tags (source_id, tag_id, position)
+SELECT s.id, t.id, MIN(raw.position) - 1
+FROM sources s
+CROSS JOIN LATERAL jsonb_array_elements_text(CASE WHEN jsonb_typeof(s.tags) = 'array' THEN s.tags ELSE '[]'::jsonb END)
+    WITH ORDINALITY AS raw(name, position)
+JOIN tags t ON t.slug = tag_slug(tag_name(raw.name))
+GROUP BY s.id, t.id;
+
+INSERT INTO note_tags (note_id, tag_id, position)
+SELECT n.id, t.id, MIN(raw.position) - 1
+FROM notes n
+CROSS JOIN LATERAL jsonb_array_elements_text(CASE WHEN jsonb_typeof(n.tags) = 'array' THEN n.tags ELSE '[]'::jsonb END)
+    WITH ORDINALITY AS raw(name, position)
+JOIN tags t ON t.slug = tag_slug(tag_name(raw.name))
+GROUP BY n.id, t.id;
+
+DROP INDEX IF EXISTS idx_sources_tags;
+DROP INDEX IF EXISTS idx_notes_tags;
+ALTER TABLE sources DROP COLUMN IF EXISTS tags;
+ALTER TABLE notes DROP COLUMN IF EXISTS tags;
+DROP FUNCTION IF EXISTS tag_slug(TEXT);
+DROP FUNCTION IF EXISTS tag_name(TEXT);
+
+-- +goose StatementBegin
+CREATE OR REPLACE FUNCTION refresh_source_search(target_source_id UUID)
+RETURNS VOID AS $$
+BEGIN
+    INSERT INTO source_search (source_id, document, search_text, updated_at)
+    SELECT s.id,
+           setweight(to_tsvector('simple', COALESCE(s.title, '')), 'A') ||
+           setweight(to_tsvector('simple', COALESCE(s.subtitle, '')), 'B') ||
+           setweight(to_tsvector('simple', COALESCE(c.names, '')), 'B') ||
+           setweight(to_tsvector('simple', concat_ws(' ', t.names, s.isbn, s.doi, bm.isbn_10, bm.isbn_13, s.publisher)), 'C') ||
+           setweight(to_tsvector('simple', COALESCE(s.description, '')), 'D'),
+           lower(concat_ws(' ', s.title, s.subtitle, c.names)),
+           NOW()
+    FROM sources s
+    LEFT JOIN book_metadata bm ON bm.source_id = s.id
+    LEFT JOIN LATERAL (
+        SELECT string_agg(ct.name, ' ' ORDER BY sc.position) AS names
+        FROM source_contributors sc
+        JOIN contributors ct ON ct.id = sc.contributor_id
+        WHERE sc.source_id = s.id
+    ) c ON TRUE
+    LEFT JOIN LATERAL (
+        SELECT string_agg(tg.name || ' ' || tg.slug, ' ' ORDER BY st.position) AS names
+        FROM source_tags st
+        JOIN tags tg ON tg.id = st.tag_id
+        WHERE st.source_id = s.id
+    ) t ON TRUE
+    WHERE s.id = target_source_id
+    ON CONFLICT (source_id) DO UPDATE
+    SET document = EXCLUDED.document,
+        search_text = EXCLUDED.search_text,
+        updated_at = EXCLUDED.updated_at;
+END;
+$$ language 'plpgsql';
+-- +goose StatementEnd
+
+-- +goose StatementBegin
+CREATE OR REPLACE FUNCTION refresh_source_search_from_row()
+RETURNS TRIGGER AS $$
+BEGIN
+    IF TG_TABLE_NAME = 'sources' THEN
+        PERFORM refresh_source_search(NEW.id);
+    ELSIF TG_TABLE_NAME = 'contributors' THEN
+        PERFORM refresh_source_search(sc.source_id)
+        FROM source_contributors sc
+        WHERE sc.contributor_id = NEW.id;
+    ELSIF TG_TABLE_NAME = 'tags' THEN
+        PERFORM refresh_source_search(st.source_id)
+        FROM source_tags st
+        WHERE st.tag_id = NEW.id;
+    ELSIF TG_OP = 'DELETE' THEN
+        PERFORM refresh_source_search(OLD.source_id);
+    ELSE
+        PERFORM refresh_source_search(NEW.source_id);
+    END IF;
+    RETURN NULL;
+END;
+$$ language 'plpgsql';
+-- +goose StatementEnd
+
+CREATE TRIGGER refresh_source_search_on_source_tags
+    AFTER INSERT OR UPDATE OR DELETE ON source_tags
+    FOR EACH ROW
+    EXECUTE FUNCTION refresh_source_search_from_row();
+
+CREATE TRIGGER refresh_source_search_on_tags
+    AFTER UPDATE OF name ON tags
+    FOR EACH ROW
+    EXECUTE FUNCTION refresh_source_search_from_row();
+
+SELECT refresh_source_search(id) FROM sources;
+
+-- +goose Down
+DROP TRIGGER IF EXISTS refresh_source_search_on_tags ON tags;
+DROP TRIGGER IF EXISTS refresh_source_search_on_source_tags ON source_tags;
+
+ALTER TABLE sources ADD COLUMN IF NOT EXISTS tags JSONB DEFAULT '[]'::jsonb;
+ALTER TABLE notes ADD COLUMN IF NOT EXISTS tags JSONB DEFAULT '[]'::jsonb;
+
+UPDATE sources s
+SET tags = (
+    SELECT COALESCE(jsonb_agg(t.name ORDER BY st.position), '[]'::jsonb)
+    FROM source_tags st
+    JOIN tags t ON t.id = st.tag_id
+    WHERE st.source_id = s.id
+);
+
+UPDATE notes n
+SET tags = (
+    SELECT COALESCE(jsonb_agg(t.name ORDER BY nt.position), '[]'::jsonb)
+    FROM note_tags nt
+    JOIN tags t ON t.id = nt.tag_id
+    WHERE nt.note_id = n.id
+);
+
+CREATE INDEX IF NOT EXISTS idx_sources_tags ON sources USING GIN(tags);
+CREATE INDEX IF NOT EXISTS idx_notes_tags ON notes USING GIN(tags);
+
+-- +goose StatementBegin
+CREATE OR REPLACE FUNCTION refresh_source_search(target_source_id UUID)
+RETURNS VOID AS $$
+BEGIN
+    INSERT INTO source_search (source_id, document, search_text, updated_at)
+    SELECT s.id,
+           setweight(to_tsvector('simple', COALESCE(s.title, '')), 'A') ||
+           setweight(to_tsvector('simple', COALESCE(s.subtitle, '')), 'B') ||
+           setweight(to_tsvector('simple', COALESCE(c.names, '')), 'B') ||
+           setweight(to_tsvector('simple', concat_ws(' ', t.names, s.isbn, s.doi, bm.isbn_10, bm.isbn_13, s.publisher)), 'C') ||
+           setweight(to_tsvector('simple', COALESCE(s.description, '')), 'D'),
+           lower(concat_ws(' ', s.title, s.subtitle, c.names)),
+           NOW()
+    FROM sources s
+    LEFT JOIN book_metadata bm ON bm.source_id = s.id
+    LEFT JOIN LATERAL (
+        SELECT string_agg(ct.name, ' ' ORDER BY sc.position) AS names
+        FROM source_contributors sc
+        JOIN contributors ct ON ct.id = sc.contributor_id
+        WHERE sc.source_id = s.id
+    ) c ON TRUE
+    LEFT JOIN LATERAL (
+        SELECT string_agg(tag, ' ') AS names
+        FROM jsonb_array_elements_text(COALESCE(s.tags, '[]'::jsonb)) AS tag
+    ) t ON TRUE
+    WHERE s.id = target_source_id
+    ON CONFLICT (source_id) DO UPDATE
+    SET document = EXCLUDED.document,
+        search_text = EXCLUDED.search_text,
+        updated_at = EXCLUDED.updated_at;
+END;
+$$ language 'plpgsql';
+-- +goose StatementEnd
+
+-- +goose StatementBegin
+CREATE OR REPLACE FUNCTION refresh_source_search_from_row()
+RETURNS TRIGGER AS $$
+BEGIN
+    IF TG_TABLE_NAME = 'sources' THEN
+        PERFORM refresh_source_search(NEW.id);
+    ELSIF TG_TABLE_NAME = 'contributors' THEN
+        PERFORM refresh_source_search(sc.source_id)
+        FROM source_contributors sc
+        WHERE sc.contributor_id = NEW.id;
+    ELSIF TG_OP = 'DELETE' THEN
+        PERFORM refresh_source_search(OLD.source_id);
+    ELSE
+        PERFORM refresh_source_search(NEW.source_id);
+    END IF;
+    RETURN NULL;
+END;
+$$ language 'plpgsql';
+-- +goose StatementEnd
+
+DROP TABLE IF EXISTS library_item_tags;
+DROP TABLE IF EXISTS note_tags;
+DROP TABLE IF EXISTS source_tags;
+ALTER TABLE tags DROP CONSTRAINT IF EXISTS tags_slug_key;
+ALTER TABLE tags DROP COLUMN IF EXISTS slug;
+
+SELECT refresh_source_search(id) FROM sources;
@@ -0,0 +1,45 @@
+-- +goose Up
+CREATE TABLE IF NOT EXISTS collection_items (
+    collection_id UUID NOT NULL REFERENCES collections(id) ON DELETE CASCADE,
+    source_id UUID NOT NULL REFERENCES sources(id) ON DELETE CASCADE,
+    position INTEGER NOT NULL,
+    note TEXT,
+    added_at TIMESTAMPTZ NOT NULL DEFAULT NOW(),
+    PRIMARY KEY (collection_id, source_id)
+);
+
+CREATE INDEX IF NOT EXISTS idx_collection_items_collection_id_position ON collection_items(collection_id, position);
+CREATE INDEX IF NOT EXISTS idx_collection_items_source_id ON collection_items(source_id);
+
+-- IDs that no longer match a source are dropped; the rest keep their first
+-- position, renumbered without gaps.
+INSERT INTO collection_items (collection_id, source_id, position, added_at)
+SELECT kept.collection_id, kept.source_id,
+       (ROW_NUMBER() OVER (PARTITION BY kept.collection_id ORDER BY kept.position) - 1)::int,
+       kept.created_at
+FROM (
+    SELECT c.id AS collection_id, s.id AS source_id, MIN(e.position) AS position, c.created_at
+    FROM collections c
+    CROSS JOIN LATERAL jsonb_array_elements_text(CASE WHEN jsonb_typeof(c.source_ids) = 'array' THEN c.source_ids ELSE '[]'::jsonb END)
+        WITH ORDINALITY AS e(value, position)
+    JOIN sources s ON s.id::text = e.value
+    GROUP BY c.id, s.id, c.created_at
+) kept
+ON CONFLICT DO NOTHING;
+
+DROP INDEX IF EXISTS idx_collections_source_ids;
+ALTER TABLE collections DROP COLUMN IF EXISTS source_ids;
+
+-- +goose Down
+ALTER TABLE collections ADD COLUMN IF NOT EXISTS source_ids JSONB DEFAULT '[]'::jsonb;
+
+UPDATE collections c
+SET source_ids = (
+    SELECT COALESCE(jsonb_agg(ci.source_id ORDER BY ci.position, ci.added_at), '[]'::jsonb)
+    FROM collection_items ci
+    WHERE ci.collection_id = c.id
+);
+
+CREATE INDEX IF NOT EXISTS idx_collections_source_ids ON collections USING GIN(source_ids);
+
+DROP TABLE IF EXISTS collection_items;
@@ -0,0 +1,51 @@
+-- +goose Up
+CREATE TABLE IF NOT EXISTS collection_members (
+    collection_id UUID NOT NULL REFERENCES collections(id) ON DELETE CASCADE,
+    user_id UUID NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+    role VARCHAR(20) NOT NULL CHECK (role IN ('owner', 'editor', 'viewer')),
+    invited_by UUID REFERENCES users(id) ON DELETE SET NULL,
+    joined_at TIMESTAMP WITH TIME ZONE NOT NULL DEFAULT NOW(),
+    PRIMARY KEY (collection_id, user_id)
+);
+
+CREATE UNIQUE INDEX IF NOT EXISTS idx_collection_members_one_owner ON collection_members(collection_id) WHERE role = 'owner';
+CREATE INDEX IF NOT EXISTS idx_collection_members_user_id ON collection_members(user_id);
+
+INSERT INTO collection_members (collection_id, user_id, role, joined_at)
+SELECT id, user_id, 'owner', COALESCE(created_at, NOW())
+FROM collections
+ON CONFLICT DO NOTHING;
+
+CREATE TABLE IF NOT EXISTS collection_invitations (
+    id UUID PRIMARY KEY,
+    collection_id UUID NOT NULL REFERENCES collections(id) ON DELETE CASCADE,
+    user_id UUID NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+    role VARCHAR(20) NOT NULL CHECK (role IN ('editor', 'viewer')),
+    invited_by UUID NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+    created_at TIMESTAMP WITH TIME ZONE NOT NULL DEFAULT NOW(),
+    UNIQUE (collection_id, user_id)
+);
+
+CREATE INDEX IF NOT EXISTS idx_collection_invitations_user_id ON collection_invitations(user_id);
+
+CREATE TABLE IF NOT EXISTS collection_activity (
+    id UUID PRIMARY KEY,
+    collection_id UUID NOT NULL REFERENCES collections(id) ON DELETE CASCADE,
+    actor_id UUID REFERENCES users(id) ON DELETE SET NULL,
+    action VARCHAR(50) NOT NULL,
+    source_id UUID REFERENCES sources(id) ON DELETE SET NULL,
+    member_id UUID REFERENCES users(id) ON DELETE SET NULL,
+    role VARCHAR(20),
+    created_at TIMESTAMP WITH TIME ZONE NOT NULL DEFAULT NOW()
+);
+
+CREATE INDEX IF NOT EXISTS idx_collection_activity_collection_id_created_at_id ON collection_activity(collection_id, created_at DESC, id DESC);
+
+ALTER TABLE collection_items ADD COLUMN IF NOT EXISTS added_by UUID REFERENCES users(id) ON DELETE SET NULL;
+UPDATE collection_items ci SET added_by = c.user_id FROM collections c WHERE c.id = ci.collection_id;
+
+-- +goose Down
+ALTER TABLE collection_items DROP COLUMN IF EXISTS added_by;
+DROP TABLE IF EXISTS collection_activity;
+DROP TABLE IF EXISTS collection_invitations;
+DROP TABLE IF EXISTS collection_members;
@@ -0,0 +1,8 @@
+-- +goose Up
+-- A collection with rules is smart: its sources are whatever the rules
+-- select when it is read, and it has no collection_items of its own.
+ALTER TABLE collections ADD COLUMN IF NOT EXISTS rules JSONB;
+
+-- +goose Down
+DELETE FROM collections WHERE rules IS NOT NULL;
+ALTER TABLE collections DROP COLUMN IF EXISTS rules;
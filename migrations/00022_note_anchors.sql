@@ -0,0 +1,15 @@
+-- +goose Up
+-- anchor holds where in the source a note points: pages, a time, a chapter
+-- and W3C Web Annotation selectors. location_key is derived from it so notes
+-- on one source sort in reading order.
+ALTER TABLE notes
+    ADD COLUMN IF NOT EXISTS anchor JSONB,
+    ADD COLUMN IF NOT EXISTS location_key TEXT;
+
+CREATE INDEX IF NOT EXISTS idx_notes_source_id_location_key ON notes(source_id, location_key, created_at);
+
+-- +goose Down
+DROP INDEX IF EXISTS idx_notes_source_id_location_key;
+ALTER TABLE notes
+    DROP COLUMN IF EXISTS location_key,
+    DROP COLUMN IF EXISTS anchor;
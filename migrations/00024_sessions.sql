@@ -0,0 +1,22 @@
+-- +goose Up
+-- A session is one sign-in: the refresh token family started by a login or
+-- registration, with the device that last used it.
+CREATE TABLE IF NOT EXISTS sessions (
+    family_id UUID PRIMARY KEY,
+    user_id UUID NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+    user_agent TEXT,
+    ip_address TEXT,
+    created_at TIMESTAMP WITH TIME ZONE NOT NULL DEFAULT NOW(),
+    last_used_at TIMESTAMP WITH TIME ZONE NOT NULL DEFAULT NOW()
+);
+
+CREATE INDEX IF NOT EXISTS idx_sessions_user_id ON sessions(user_id);
+
+INSERT INTO sessions (family_id, user_id, created_at, last_used_at)
+SELECT family_id, user_id, COALESCE(MIN(created_at), NOW()), COALESCE(MAX(created_at), NOW())
+FROM refresh_tokens
+GROUP BY family_id, user_id
+ON CONFLICT DO NOTHING;
+
+-- +goose Down
+DROP TABLE IF EXISTS sessions;
@@ -0,0 +1,33 @@
+-- +goose Up
+-- Accounts at OpenID Connect providers linked to users. The subject is the
+-- provider's stable ID for the account; the email is the latest one it shared.
+CREATE TABLE IF NOT EXISTS user_identities (
+    id UUID PRIMARY KEY,
+    user_id UUID NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+    provider VARCHAR(50) NOT NULL,
+    subject TEXT NOT NULL,
+    email TEXT,
+    created_at TIMESTAMP WITH TIME ZONE NOT NULL DEFAULT NOW(),
+    last_used_at TIMESTAMP WITH TIME ZONE NOT NULL DEFAULT NOW(),
+    UNIQUE (provider, subject),
+    UNIQUE (user_id, provider)
+);
+
+-- Sign-ins in progress at a provider, keyed by a hash of the OAuth state.
+-- user_id is set when a signed-in user is linking a provider to their
+-- account rather than signing in.
+CREATE TABLE IF NOT EXISTS oidc_logins (
+    state_hash BYTEA PRIMARY KEY,
+    provider VARCHAR(50) NOT NULL,
+    nonce TEXT NOT NULL,
+    code_verifier TEXT NOT NULL,
+    user_id UUID REFERENCES users(id) ON DELETE CASCADE,
+    expires_at TIMESTAMP WITH TIME ZONE NOT NULL,
+    created_at TIMESTAMP WITH TIME ZONE NOT NULL DEFAULT NOW()
+);
+
+CREATE INDEX IF NOT EXISTS idx_oidc_logins_expires_at ON oidc_logins(expires_at);
+
+-- +goose Down
+DROP TABLE IF EXISTS oidc_logins;
+DROP TABLE IF EXISTS user_identities;
@@ -0,0 +1,15 @@
+-- +goose Up
+-- Every time a reader finished a library item: the current completion of a
+-- completed item, and the earlier ones a re-read moved into
+-- library_item_completions. Goals and stats count from here so re-reading a
+-- book does not take its earlier finish away.
+CREATE OR REPLACE VIEW library_completions AS
+SELECT id AS library_item_id, user_id, completed_at
+FROM user_library_items
+WHERE status = 'completed' AND completed_at IS NOT NULL
+UNION ALL
+SELECT library_item_id, user_id, completed_at
+FROM library_item_completions;
+
+-- +goose Down
+DROP VIEW IF EXISTS library_completions;
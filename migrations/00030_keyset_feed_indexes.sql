@@ -0,0 +1,19 @@
+-- +goose Up
+CREATE INDEX IF NOT EXISTS idx_notes_created_at_id ON notes(created_at DESC, id DESC);
+CREATE INDEX IF NOT EXISTS idx_reviews_created_at_id ON reviews(created_at DESC, id DESC);
+CREATE INDEX IF NOT EXISTS idx_collections_created_at_id ON collections(created_at DESC, id DESC);
+CREATE INDEX IF NOT EXISTS idx_user_library_items_created_at_id ON user_library_items(created_at DESC, id DESC);
+DROP INDEX IF EXISTS idx_notes_created_at;
+DROP INDEX IF EXISTS idx_reviews_created_at;
+DROP INDEX IF EXISTS idx_collections_created_at;
+DROP INDEX IF EXISTS idx_user_library_items_created_at;
+
+-- +goose Down
+CREATE INDEX IF NOT EXISTS idx_user_library_items_created_at ON user_library_items(created_at DESC);
+CREATE INDEX IF NOT EXISTS idx_collections_created_at ON collections(created_at DESC);
+CREATE INDEX IF NOT EXISTS idx_reviews_created_at ON reviews(created_at DESC);
+CREATE INDEX IF NOT EXISTS idx_notes_created_at ON notes(created_at DESC);
+DROP INDEX IF EXISTS idx_user_library_items_created_at_id;
+DROP INDEX IF EXISTS idx_collections_created_at_id;
+DROP INDEX IF EXISTS idx_reviews_created_at_id;
+DROP INDEX IF EXISTS idx_notes_created_at_id;
@@ -0,0 +1,14 @@
+-- +goose Up
+ALTER TABLE oidc_logins ADD COLUMN IF NOT EXISTS reauthenticate BOOLEAN NOT NULL DEFAULT FALSE;
+
+ALTER TABLE user_tokens DROP CONSTRAINT IF EXISTS user_tokens_purpose_check;
+ALTER TABLE user_tokens ADD CONSTRAINT user_tokens_purpose_check
+    CHECK (purpose IN ('verify_email', 'reset_password', 'mfa_login', 'reauthenticate'));
+
+-- +goose Down
+DELETE FROM user_tokens WHERE purpose = 'reauthenticate';
+ALTER TABLE user_tokens DROP CONSTRAINT IF EXISTS user_tokens_purpose_check;
+ALTER TABLE user_tokens ADD CONSTRAINT user_tokens_purpose_check
+    CHECK (purpose IN ('verify_email', 'reset_password', 'mfa_login'));
+
+ALTER TABLE oidc_logins DROP COLUMN IF EXISTS reauthenticate;